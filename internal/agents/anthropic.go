@@ -0,0 +1,255 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	anthropicBaseURL   = "https://api.anthropic.com"
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 4096
+)
+
+// AnthropicClient implements LLMClient using the Anthropic Messages API.
+// Tools are declared in the OpenAI format shared by all providers and
+// translated to Anthropic tool definitions.
+type AnthropicClient struct {
+	httpClient *http.Client
+	apiKey     string
+	baseURL    string
+	model      string
+	maxTokens  int
+}
+
+// NewAnthropicClient creates a new Anthropic LLM client. An empty baseURL uses
+// the public API; maxTokens defaults to 4096.
+func NewAnthropicClient(apiKey, model, baseURL string, maxTokens int) *AnthropicClient {
+	if baseURL == "" {
+		baseURL = anthropicBaseURL
+	}
+	if maxTokens <= 0 {
+		maxTokens = anthropicMaxTokens
+	}
+	return &AnthropicClient{
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+		apiKey:     apiKey,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		model:      model,
+		maxTokens:  maxTokens,
+	}
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Complete sends a prompt to the LLM and returns the response.
+func (c *AnthropicClient) Complete(ctx context.Context, prompt string) (string, error) {
+	return c.CompleteWithSystem(ctx, "", prompt)
+}
+
+// CompleteWithSystem sends a prompt with system message to the LLM.
+func (c *AnthropicClient) CompleteWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	resp, err := c.create(ctx, anthropicRequest{
+		System:   systemPrompt,
+		Messages: []anthropicMessage{userMessage(userPrompt)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.text(), nil
+}
+
+// CompleteWithTools sends a prompt with tools and handles tool calls.
+func (c *AnthropicClient) CompleteWithTools(ctx context.Context, systemPrompt, userPrompt string, tools []openai.Tool, executor ToolExecutorInterface) (string, error) {
+	cot, err := c.CompleteWithToolsVerbose(ctx, systemPrompt, userPrompt, tools, executor)
+	if err != nil {
+		return "", err
+	}
+	return cot.Response, nil
+}
+
+// CompleteWithToolsVerbose sends a prompt with tools and returns the full chain of thought.
+func (c *AnthropicClient) CompleteWithToolsVerbose(ctx context.Context, systemPrompt, userPrompt string, tools []openai.Tool, executor ToolExecutorInterface) (*ChainOfThought, error) {
+	req := anthropicRequest{
+		System:   systemPrompt,
+		Messages: []anthropicMessage{userMessage(userPrompt)},
+		Tools:    anthropicTools(tools),
+	}
+	cot := &ChainOfThought{
+		ToolCalls: make([]ToolCallLog, 0),
+	}
+
+	for i := 0; i < maxToolRounds; i++ {
+		resp, err := c.create(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		var results []anthropicBlock
+		for _, block := range resp.Content {
+			if block.Type != "tool_use" {
+				continue
+			}
+			args := string(block.Input)
+			result, err := executor.ExecuteTool(ctx, block.Name, block.Input)
+			if err != nil {
+				result = fmt.Sprintf("Error executing tool %s: %v", block.Name, err)
+			}
+			cot.ToolCalls = append(cot.ToolCalls, ToolCallLog{
+				ToolName:  block.Name,
+				Arguments: args,
+				Result:    result,
+			})
+			results = append(results, anthropicBlock{
+				Type:      "tool_result",
+				ToolUseID: block.ID,
+				Content:   result,
+				IsError:   err != nil,
+			})
+		}
+
+		if len(results) == 0 {
+			cot.Response = resp.text()
+			return cot, nil
+		}
+
+		req.Messages = append(req.Messages,
+			anthropicMessage{Role: "assistant", Content: resp.Content},
+			anthropicMessage{Role: "user", Content: results},
+		)
+	}
+
+	return nil, fmt.Errorf("exceeded maximum tool call iterations")
+}
+
+// GetModel returns the model name.
+func (c *AnthropicClient) GetModel() string {
+	return c.model
+}
+
+func (c *AnthropicClient) create(ctx context.Context, req anthropicRequest) (*anthropicResponse, error) {
+	req.Model = c.model
+	req.MaxTokens = c.maxTokens
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding anthropic request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic completion failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading anthropic response: %w", err)
+	}
+
+	var resp anthropicResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("anthropic completion failed: status %d: %s", httpResp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("anthropic completion failed: %s: %s", resp.Error.Type, resp.Error.Message)
+	}
+	recordUsage(ctx, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("anthropic completion failed: status %d", httpResp.StatusCode)
+	}
+	if len(resp.Content) == 0 {
+		return nil, fmt.Errorf("no response from anthropic")
+	}
+	return &resp, nil
+}
+
+func (r *anthropicResponse) text() string {
+	var parts []string
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func userMessage(text string) anthropicMessage {
+	return anthropicMessage{Role: "user", Content: []anthropicBlock{{Type: "text", Text: text}}}
+}
+
+// anthropicTools converts OpenAI function tools to Anthropic tool definitions.
+func anthropicTools(tools []openai.Tool) []anthropicTool {
+	out := make([]anthropicTool, 0, len(tools))
+	for _, t := range tools {
+		if t.Function == nil {
+			continue
+		}
+		schema := t.Function.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		out = append(out, anthropicTool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	return out
+}
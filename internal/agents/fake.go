@@ -0,0 +1,159 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// FakeDefaultResponse is returned by a FakeClient with nothing scripted. It
+// parses as a HOLD from every agent.
+const FakeDefaultResponse = `RECOMMENDATION: HOLD
+CONFIDENCE: 50
+ENTRY: N/A
+STOPLOSS: N/A
+TARGET1: N/A
+TARGET2: N/A
+TARGET3: N/A
+REASONING: Deterministic response from the fake LLM provider.`
+
+// FakeToolCall is a tool call a FakeClient makes before answering.
+type FakeToolCall struct {
+	Name      string
+	Arguments string
+}
+
+// FakeTurn scripts one completion: the tool calls to make, executed in order
+// through the caller's executor, then the response.
+type FakeTurn struct {
+	ToolCalls []FakeToolCall
+	Response  string
+	Err       error
+}
+
+// FakeCall records a request made to a FakeClient.
+type FakeCall struct {
+	System string
+	Prompt string
+	Tools  []string
+}
+
+// FakeClient is a deterministic LLMClient for tests. Completions consume the
+// scripted turns in order and return FakeDefaultResponse once they run out.
+// It is safe for concurrent use.
+type FakeClient struct {
+	mu    sync.Mutex
+	turns []FakeTurn
+	calls []FakeCall
+}
+
+// NewFakeClient creates a fake client answering with responses in order.
+func NewFakeClient(responses ...string) *FakeClient {
+	turns := make([]FakeTurn, len(responses))
+	for i, r := range responses {
+		turns[i] = FakeTurn{Response: r}
+	}
+	return NewScriptedFakeClient(turns...)
+}
+
+// NewScriptedFakeClient creates a fake client playing back turns in order.
+func NewScriptedFakeClient(turns ...FakeTurn) *FakeClient {
+	return &FakeClient{turns: turns}
+}
+
+// Calls returns the requests made so far.
+func (c *FakeClient) Calls() []FakeCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]FakeCall(nil), c.calls...)
+}
+
+func (c *FakeClient) next(call FakeCall) FakeTurn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+	if len(c.turns) == 0 {
+		return FakeTurn{Response: FakeDefaultResponse}
+	}
+	turn := c.turns[0]
+	c.turns = c.turns[1:]
+	return turn
+}
+
+// Complete sends a prompt to the LLM and returns the response.
+func (c *FakeClient) Complete(ctx context.Context, prompt string) (string, error) {
+	return c.CompleteWithSystem(ctx, "", prompt)
+}
+
+// CompleteWithSystem sends a prompt with system message to the LLM.
+func (c *FakeClient) CompleteWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	turn := c.next(FakeCall{System: systemPrompt, Prompt: userPrompt})
+	recordUsage(ctx, estimateTokens(systemPrompt+userPrompt), estimateTokens(turn.Response))
+	return turn.Response, turn.Err
+}
+
+// CompleteWithTools sends a prompt with tools and handles tool calls.
+func (c *FakeClient) CompleteWithTools(ctx context.Context, systemPrompt, userPrompt string, tools []openai.Tool, executor ToolExecutorInterface) (string, error) {
+	cot, err := c.CompleteWithToolsVerbose(ctx, systemPrompt, userPrompt, tools, executor)
+	if err != nil {
+		return "", err
+	}
+	return cot.Response, nil
+}
+
+// CompleteWithToolsVerbose sends a prompt with tools and returns the full chain of thought.
+func (c *FakeClient) CompleteWithToolsVerbose(ctx context.Context, systemPrompt, userPrompt string, tools []openai.Tool, executor ToolExecutorInterface) (*ChainOfThought, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	call := FakeCall{System: systemPrompt, Prompt: userPrompt}
+	declared := make(map[string]bool)
+	for _, t := range tools {
+		if t.Function != nil {
+			call.Tools = append(call.Tools, t.Function.Name)
+			declared[t.Function.Name] = true
+		}
+	}
+	turn := c.next(call)
+	recordUsage(ctx, estimateTokens(systemPrompt+userPrompt), estimateTokens(turn.Response))
+	if turn.Err != nil {
+		return nil, turn.Err
+	}
+
+	cot := &ChainOfThought{
+		ToolCalls: make([]ToolCallLog, 0, len(turn.ToolCalls)),
+		Response:  turn.Response,
+	}
+	for _, tc := range turn.ToolCalls {
+		if !declared[tc.Name] {
+			return nil, fmt.Errorf("fake provider: tool %s was not offered", tc.Name)
+		}
+		result, err := executor.ExecuteTool(ctx, tc.Name, json.RawMessage(tc.Arguments))
+		if err != nil {
+			result = fmt.Sprintf("Error executing tool %s: %v", tc.Name, err)
+		}
+		cot.ToolCalls = append(cot.ToolCalls, ToolCallLog{
+			ToolName:  tc.Name,
+			Arguments: tc.Arguments,
+			Result:    result,
+		})
+	}
+	return cot, nil
+}
+
+// GetModel returns the model name.
+func (c *FakeClient) GetModel() string {
+	return "fake"
+}
+
+// estimateTokens approximates a token count at four characters per token.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
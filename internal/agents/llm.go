@@ -9,17 +9,34 @@ import (
 	"github.com/sashabaranov/go-openai"
 )
 
-// OpenAIClient implements LLMClient using OpenAI API.
+// maxToolRounds bounds the tool-calling loop of every provider.
+// Increased for mini models that may call more tools.
+const maxToolRounds = 8
+
+// OpenAIClient implements LLMClient using the OpenAI chat completions API.
+// It also serves Azure OpenAI and OpenAI-compatible local servers.
 type OpenAIClient struct {
-	client *openai.Client
-	model  string
+	client   *openai.Client
+	model    string
+	provider string
 }
 
 // NewOpenAIClient creates a new OpenAI LLM client.
 func NewOpenAIClient(apiKey string, model string) *OpenAIClient {
 	return &OpenAIClient{
-		client: openai.NewClient(apiKey),
-		model:  model,
+		client:   openai.NewClient(apiKey),
+		model:    model,
+		provider: "openai",
+	}
+}
+
+// NewOpenAICompatibleClient creates a client for any endpoint speaking the
+// OpenAI chat completions API. provider names it in errors.
+func NewOpenAICompatibleClient(cfg openai.ClientConfig, provider, model string) *OpenAIClient {
+	return &OpenAIClient{
+		client:   openai.NewClientWithConfig(cfg),
+		model:    model,
+		provider: provider,
 	}
 }
 
@@ -32,10 +49,11 @@ func (c *OpenAIClient) Complete(ctx context.Context, prompt string) (string, err
 		},
 	})
 	if err != nil {
-		return "", fmt.Errorf("openai completion failed: %w", err)
+		return "", fmt.Errorf("%s completion failed: %w", c.provider, err)
 	}
+	recordUsage(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from openai")
+		return "", fmt.Errorf("no response from %s", c.provider)
 	}
 	return resp.Choices[0].Message.Content, nil
 }
@@ -50,10 +68,11 @@ func (c *OpenAIClient) CompleteWithSystem(ctx context.Context, systemPrompt, use
 		},
 	})
 	if err != nil {
-		return "", fmt.Errorf("openai completion failed: %w", err)
+		return "", fmt.Errorf("%s completion failed: %w", c.provider, err)
 	}
+	recordUsage(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from openai")
+		return "", fmt.Errorf("no response from %s", c.provider)
 	}
 	return resp.Choices[0].Message.Content, nil
 }
@@ -104,18 +123,18 @@ func (c *OpenAIClient) CompleteWithToolsVerbose(ctx context.Context, systemPromp
 		ToolCalls: make([]ToolCallLog, 0),
 	}
 
-	// Allow up to 8 rounds of tool calls (increased for mini models that may call more tools)
-	for i := 0; i < 8; i++ {
+	for i := 0; i < maxToolRounds; i++ {
 		resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
 			Model:    c.model,
 			Messages: messages,
 			Tools:    tools,
 		})
 		if err != nil {
-			return nil, fmt.Errorf("openai completion failed: %w", err)
+			return nil, fmt.Errorf("%s completion failed: %w", c.provider, err)
 		}
+		recordUsage(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 		if len(resp.Choices) == 0 {
-			return nil, fmt.Errorf("no response from openai")
+			return nil, fmt.Errorf("no response from %s", c.provider)
 		}
 
 		choice := resp.Choices[0]
@@ -0,0 +1,411 @@
+package agents
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"zerodha-trader/internal/config"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/store"
+)
+
+// ErrLLMBudgetExceeded is returned by metered clients once the day's LLM
+// budget is spent. Agents treat it like any LLM failure and fall back to
+// their rule-based analysis.
+var ErrLLMBudgetExceeded = errors.New("daily LLM budget exceeded")
+
+// llmCacheRetention is how long cached responses are kept.
+const llmCacheRetention = 7 * 24 * time.Hour
+
+// defaultLLMPrices are estimated USD prices per million tokens, matched by
+// the longest model-name prefix. agents.toml's llm_pricing overrides them.
+var defaultLLMPrices = map[string]config.LLMPrice{
+	"gpt-4o":        {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":   {Input: 0.15, Output: 0.60},
+	"gpt-4.1":       {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":  {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":  {Input: 0.10, Output: 0.40},
+	"gpt-5":         {Input: 1.25, Output: 10.00},
+	"gpt-5-mini":    {Input: 0.25, Output: 2.00},
+	"gpt-5-nano":    {Input: 0.05, Output: 0.40},
+	"claude-opus":   {Input: 15.00, Output: 75.00},
+	"claude-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-haiku":  {Input: 1.00, Output: 5.00},
+}
+
+// LLMMeter wraps agents' LLM clients with a response cache, usage
+// accounting and daily budgets. Usage and cached responses are kept in the
+// store; budgets reset at midnight IST.
+type LLMMeter struct {
+	store  store.DataStore
+	config *config.AgentConfig
+
+	mu    sync.Mutex
+	day   string
+	spent map[string]float64
+	total float64
+
+	// Budgeted calls allowed but not yet charged hold a reservation of their
+	// estimated cost, the most a call by the agent has cost so far, so that
+	// concurrent calls cannot all pass the budget check before any is
+	// charged. A call whose cost cannot be estimated yet runs alone.
+	reserved      map[string]float64
+	reservedTotal float64
+	inflight      int
+	unestimated   int
+	estimates     map[string]float64
+	settled       *sync.Cond
+}
+
+// budgetHold is the reservation a budgeted call holds until it is charged.
+type budgetHold struct {
+	held      bool
+	estimated bool
+	cost      float64
+}
+
+// NewLLMMeter creates a meter. dataStore may be nil, in which case nothing
+// is cached or recorded and budgets only count this process's spend.
+func NewLLMMeter(dataStore store.DataStore, agentConfig *config.AgentConfig) *LLMMeter {
+	m := &LLMMeter{
+		store:     dataStore,
+		config:    agentConfig,
+		spent:     make(map[string]float64),
+		reserved:  make(map[string]float64),
+		estimates: make(map[string]float64),
+	}
+	m.settled = sync.NewCond(&m.mu)
+	return m
+}
+
+// Wrap returns client metered on behalf of agent. A nil client stays nil so
+// the agent keeps running rule-based.
+func (m *LLMMeter) Wrap(agent, provider string, client LLMClient) LLMClient {
+	if client == nil {
+		return nil
+	}
+	mc := &meteredClient{meter: m, agent: agent, provider: provider, inner: client}
+	if named, ok := client.(interface{ GetModel() string }); ok {
+		mc.model = named.GetModel()
+	}
+	return mc
+}
+
+// Price estimates the USD cost of a completion on model. Local and fake
+// providers are free.
+func (m *LLMMeter) Price(provider, model string, promptTokens, completionTokens int) float64 {
+	if provider == config.ProviderLocal || provider == config.ProviderFake {
+		return 0
+	}
+
+	price, ok := m.config.LLMPricing[model]
+	if !ok {
+		best := ""
+		for prefix, p := range defaultLLMPrices {
+			if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+				best, price = prefix, p
+			}
+		}
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6
+}
+
+// Spent returns the estimated spend today for agent and across all agents.
+func (m *LLMMeter) Spent(ctx context.Context, agent string) (float64, float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rollover(ctx)
+	return m.spent[agent], m.total
+}
+
+// allow reports whether agent may make another LLM call today and, when a
+// budget applies, reserves the call's estimated cost until charge.
+func (m *LLMMeter) allow(ctx context.Context, agent string) (budgetHold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for {
+		m.rollover(ctx)
+		if err := m.overBudget(agent); err != nil {
+			return budgetHold{}, err
+		}
+		if m.config.LLMDailyBudget <= 0 && m.config.LLMAgentBudgets[agent] <= 0 {
+			return budgetHold{}, nil
+		}
+		_, estimated := m.estimates[agent]
+		if m.unestimated == 0 && (estimated || m.inflight == 0) {
+			break
+		}
+		m.settled.Wait()
+	}
+
+	hold := budgetHold{held: true, cost: m.estimates[agent]}
+	_, hold.estimated = m.estimates[agent]
+	m.reserved[agent] += hold.cost
+	m.reservedTotal += hold.cost
+	m.inflight++
+	if !hold.estimated {
+		m.unestimated++
+	}
+	return hold, nil
+}
+
+// overBudget reports a spent budget, counting reservations. Callers hold mu.
+func (m *LLMMeter) overBudget(agent string) error {
+	if limit := m.config.LLMDailyBudget; limit > 0 && m.total+m.reservedTotal >= limit {
+		return fmt.Errorf("%w: spent $%.4f of $%.2f", ErrLLMBudgetExceeded, m.total, limit)
+	}
+	if limit := m.config.LLMAgentBudgets[agent]; limit > 0 && m.spent[agent]+m.reserved[agent] >= limit {
+		return fmt.Errorf("%w: %s spent $%.4f of $%.2f", ErrLLMBudgetExceeded, agent, m.spent[agent], limit)
+	}
+	return nil
+}
+
+// charge books a call's cost and releases its reservation.
+func (m *LLMMeter) charge(agent string, hold budgetHold, cost float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spent[agent] += cost
+	m.total += cost
+
+	if !hold.held {
+		return
+	}
+	m.reserved[agent] -= hold.cost
+	m.reservedTotal -= hold.cost
+	m.inflight--
+	if !hold.estimated {
+		m.unestimated--
+	}
+	if estimate, ok := m.estimates[agent]; !ok || cost > estimate {
+		m.estimates[agent] = cost
+	}
+	m.settled.Broadcast()
+}
+
+// rollover starts a new budget day, seeding it from the spend already
+// recorded today so restarts do not reset the budget. Callers hold mu.
+func (m *LLMMeter) rollover(ctx context.Context) {
+	now := time.Now().In(resilience.IndiaLocation)
+	day := now.Format("2006-01-02")
+	if day == m.day {
+		return
+	}
+	m.day = day
+	m.spent = make(map[string]float64)
+	m.total = 0
+
+	if m.store == nil {
+		return
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, resilience.IndiaLocation)
+	summaries, err := m.store.GetLLMUsageSummary(ctx, store.DateRange{Start: start, End: now})
+	if err == nil {
+		for _, s := range summaries {
+			m.spent[s.Agent] = s.Cost
+			m.total += s.Cost
+		}
+	}
+	m.store.PruneLLMCache(ctx, now.Add(-llmCacheRetention))
+}
+
+func (m *LLMMeter) record(ctx context.Context, usage *models.LLMUsage) {
+	if m.store == nil {
+		return
+	}
+	// Accounting must not fail the analysis
+	_ = m.store.LogLLMUsage(context.WithoutCancel(ctx), usage)
+}
+
+// meteredClient is an LLMClient that caches, accounts and budgets the calls
+// of one agent.
+type meteredClient struct {
+	meter    *LLMMeter
+	agent    string
+	provider string
+	model    string
+	inner    LLMClient
+}
+
+// Complete sends a prompt to the LLM and returns the response.
+func (c *meteredClient) Complete(ctx context.Context, prompt string) (string, error) {
+	return c.do(ctx, c.cacheKey(ctx, "complete", prompt), func(ctx context.Context) (string, error) {
+		return c.inner.Complete(ctx, prompt)
+	})
+}
+
+// CompleteWithSystem sends a prompt with system message to the LLM.
+func (c *meteredClient) CompleteWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return c.do(ctx, c.cacheKey(ctx, "system", systemPrompt, userPrompt), func(ctx context.Context) (string, error) {
+		return c.inner.CompleteWithSystem(ctx, systemPrompt, userPrompt)
+	})
+}
+
+// CompleteWithTools sends a prompt with tools and handles tool calls.
+func (c *meteredClient) CompleteWithTools(ctx context.Context, systemPrompt, userPrompt string, tools []openai.Tool, executor ToolExecutorInterface) (string, error) {
+	cot, err := c.CompleteWithToolsVerbose(ctx, systemPrompt, userPrompt, tools, executor)
+	if err != nil {
+		return "", err
+	}
+	return cot.Response, nil
+}
+
+// CompleteWithToolsVerbose sends a prompt with tools and returns the full chain of thought.
+func (c *meteredClient) CompleteWithToolsVerbose(ctx context.Context, systemPrompt, userPrompt string, tools []openai.Tool, executor ToolExecutorInterface) (*ChainOfThought, error) {
+	parts := []string{systemPrompt, userPrompt}
+	for _, t := range tools {
+		if t.Function != nil {
+			parts = append(parts, t.Function.Name)
+		}
+	}
+
+	raw, err := c.do(ctx, c.cacheKey(ctx, "tools", parts...), func(ctx context.Context) (string, error) {
+		cot, err := c.inner.CompleteWithToolsVerbose(ctx, systemPrompt, userPrompt, tools, executor)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(cot)
+		return string(data), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var cot ChainOfThought
+	if err := json.Unmarshal([]byte(raw), &cot); err != nil {
+		return nil, fmt.Errorf("decoding chain of thought: %w", err)
+	}
+	return &cot, nil
+}
+
+// GetModel returns the model name.
+func (c *meteredClient) GetModel() string {
+	return c.model
+}
+
+// cacheKey hashes the request with the agent, model and candle it was made
+// for. Requests without a candle timestamp in ctx are not cached, since
+// nothing says when their inputs change.
+func (c *meteredClient) cacheKey(ctx context.Context, method string, parts ...string) string {
+	candle, ok := candleTime(ctx)
+	if !ok || !c.meter.config.LLMCache || c.meter.store == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, p := range append([]string{c.agent, c.provider, c.model, method, candle.UTC().Format(time.RFC3339)}, parts...) {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *meteredClient) do(ctx context.Context, key string, run func(context.Context) (string, error)) (string, error) {
+	usage := &models.LLMUsage{
+		Timestamp: time.Now(),
+		Agent:     c.agent,
+		Provider:  c.provider,
+		Model:     c.model,
+	}
+
+	if key != "" {
+		if response, ok, err := c.meter.store.GetLLMResponse(ctx, key); err == nil && ok {
+			usage.Cached = true
+			c.meter.record(ctx, usage)
+			return response, nil
+		}
+	}
+
+	hold, err := c.meter.allow(ctx, c.agent)
+	if err != nil {
+		usage.Error = err.Error()
+		c.meter.record(ctx, usage)
+		return "", err
+	}
+
+	tokens := &tokenUsage{}
+	start := time.Now()
+	response, err := run(withTokenUsage(ctx, tokens))
+	usage.Latency = time.Since(start)
+	usage.PromptTokens, usage.CompletionTokens = tokens.totals()
+	usage.Cost = c.meter.Price(c.provider, c.model, usage.PromptTokens, usage.CompletionTokens)
+	c.meter.charge(c.agent, hold, usage.Cost)
+	if err != nil {
+		usage.Error = err.Error()
+	}
+	c.meter.record(ctx, usage)
+
+	if err == nil && key != "" {
+		_ = c.meter.store.SaveLLMResponse(ctx, key, c.agent, response)
+	}
+	return response, err
+}
+
+// tokenUsage accumulates the tokens of every API round of one completion.
+type tokenUsage struct {
+	mu         sync.Mutex
+	prompt     int
+	completion int
+}
+
+func (u *tokenUsage) totals() (int, int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.prompt, u.completion
+}
+
+type tokenUsageKey struct{}
+
+type candleTimeKey struct{}
+
+func withTokenUsage(ctx context.Context, u *tokenUsage) context.Context {
+	return context.WithValue(ctx, tokenUsageKey{}, u)
+}
+
+// recordUsage adds an API round's tokens to the completion's usage, if a
+// meter is counting.
+func recordUsage(ctx context.Context, promptTokens, completionTokens int) {
+	u, ok := ctx.Value(tokenUsageKey{}).(*tokenUsage)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	u.prompt += promptTokens
+	u.completion += completionTokens
+	u.mu.Unlock()
+}
+
+// WithCandleTime marks ctx with the timestamp of the latest candle the
+// analysis is based on, enabling the LLM response cache.
+func WithCandleTime(ctx context.Context, t time.Time) context.Context {
+	if t.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, candleTimeKey{}, t)
+}
+
+func candleTime(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(candleTimeKey{}).(time.Time)
+	return t, ok
+}
+
+// latestCandleTime returns the newest candle timestamp across req's timeframes.
+func latestCandleTime(req AnalysisRequest) time.Time {
+	var latest time.Time
+	for _, candles := range req.Candles {
+		if n := len(candles); n > 0 && candles[n-1].Timestamp.After(latest) {
+			latest = candles[n-1].Timestamp
+		}
+	}
+	return latest
+}
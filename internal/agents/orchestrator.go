@@ -21,15 +21,12 @@ type Orchestrator struct {
 	config      *config.AgentConfig
 	store       store.DataStore
 	notifier    Notifier
+	risk        *RiskState
 
 	// State
-	mu                sync.RWMutex
-	running           bool
-	paused            bool
-	dailyTrades       int
-	dailyLoss         float64
-	lastTradeAt       time.Time
-	consecutiveLosses int
+	mu      sync.RWMutex
+	running bool
+	paused  bool
 
 	// Channels
 	stopChan chan struct{}
@@ -69,10 +66,23 @@ func NewOrchestrator(
 		config:      agentConfig,
 		store:       dataStore,
 		notifier:    notifier,
+		risk:        NewRiskState(dataStore, nil),
 		stopChan:    make(chan struct{}),
 	}
 }
 
+// RiskState returns the daily risk counters, shared with the decision
+// pipeline.
+func (o *Orchestrator) RiskState() *RiskState {
+	return o.risk
+}
+
+// SetDayBoundary sets when the daily risk counters reset, typically to a
+// trading.SessionManager. It takes effect at the next Start.
+func (o *Orchestrator) SetDayBoundary(boundary DayBoundary) {
+	o.risk.SetBoundary(boundary)
+}
+
 // Start starts the orchestrator.
 func (o *Orchestrator) Start(ctx context.Context) error {
 	o.mu.Lock()
@@ -80,13 +90,17 @@ func (o *Orchestrator) Start(ctx context.Context) error {
 		o.mu.Unlock()
 		return fmt.Errorf("orchestrator already running")
 	}
+
+	// Restore today's counters so a restart keeps the daily limits
+	if err := o.risk.Load(ctx); err != nil {
+		o.mu.Unlock()
+		return fmt.Errorf("restoring risk counters: %w", err)
+	}
+
 	o.running = true
 	o.paused = false
 	o.mu.Unlock()
 
-	// Reset daily counters at start
-	o.resetDailyCounters()
-
 	return nil
 }
 
@@ -106,6 +120,14 @@ func (o *Orchestrator) Stop() error {
 	return nil
 }
 
+// Done returns a channel that is closed when the orchestrator is stopped.
+// Call it after Start; each run gets a new channel.
+func (o *Orchestrator) Done() <-chan struct{} {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.stopChan
+}
+
 // Pause pauses the orchestrator.
 func (o *Orchestrator) Pause() error {
 	o.mu.Lock()
@@ -141,14 +163,15 @@ func (o *Orchestrator) GetStatus() *OrchestratorStatus {
 	for _, agent := range o.agents {
 		enabledAgents = append(enabledAgents, agent.Name())
 	}
+	counters := o.risk.Counters()
 
 	return &OrchestratorStatus{
 		Running:           o.running,
 		Paused:            o.paused,
-		DailyTrades:       o.dailyTrades,
-		DailyLoss:         o.dailyLoss,
-		LastTradeAt:       o.lastTradeAt,
-		ConsecutiveLosses: o.consecutiveLosses,
+		DailyTrades:       counters.Trades,
+		DailyLoss:         counters.Loss,
+		LastTradeAt:       counters.LastTradeAt,
+		ConsecutiveLosses: counters.ConsecutiveLosses,
 		EnabledAgents:     enabledAgents,
 	}
 }
@@ -162,6 +185,9 @@ func (o *Orchestrator) ProcessSymbol(ctx context.Context, req AnalysisRequest) (
 	}
 	o.mu.RUnlock()
 
+	// Key cached LLM responses to the candle being analyzed
+	ctx = WithCandleTime(ctx, latestCandleTime(req))
+
 	// Run all agents in parallel with timeout
 	results, err := o.runAgentsParallel(ctx, req)
 	if err != nil {
@@ -306,27 +332,26 @@ func (o *Orchestrator) shouldExecute(decision *models.Decision) bool {
 	}
 
 	// Check daily limits
-	o.mu.RLock()
-	defer o.mu.RUnlock()
+	counters := o.risk.Counters()
 
-	if o.dailyTrades >= o.config.MaxDailyTrades {
+	if counters.Trades >= o.config.MaxDailyTrades {
 		return false
 	}
 
-	if o.dailyLoss >= o.config.MaxDailyLoss {
+	if counters.Loss >= o.config.MaxDailyLoss {
 		return false
 	}
 
 	// Check cooldown
 	if o.config.CooldownMinutes > 0 {
 		cooldown := time.Duration(o.config.CooldownMinutes) * time.Minute
-		if time.Since(o.lastTradeAt) < cooldown {
+		if time.Since(counters.LastTradeAt) < cooldown {
 			return false
 		}
 	}
 
 	// Check consecutive losses
-	if o.consecutiveLosses >= o.config.ConsecutiveLossLimit {
+	if counters.ConsecutiveLosses >= o.config.ConsecutiveLossLimit {
 		return false
 	}
 
@@ -335,28 +360,7 @@ func (o *Orchestrator) shouldExecute(decision *models.Decision) bool {
 
 // RecordTrade records a trade execution for tracking.
 func (o *Orchestrator) RecordTrade(pnl float64) {
-	o.mu.Lock()
-	defer o.mu.Unlock()
-
-	o.dailyTrades++
-	o.lastTradeAt = time.Now()
-
-	if pnl < 0 {
-		o.dailyLoss += -pnl
-		o.consecutiveLosses++
-	} else {
-		o.consecutiveLosses = 0
-	}
-}
-
-// resetDailyCounters resets daily tracking counters.
-func (o *Orchestrator) resetDailyCounters() {
-	o.mu.Lock()
-	defer o.mu.Unlock()
-
-	o.dailyTrades = 0
-	o.dailyLoss = 0
-	o.consecutiveLosses = 0
+	o.risk.RecordTrade(pnl)
 }
 
 // GetDecisionStats returns statistics about AI decisions.
@@ -424,16 +428,18 @@ func (o *Orchestrator) CanTrade() (bool, string) {
 	}
 
 	if o.config != nil {
-		if o.dailyTrades >= o.config.MaxDailyTrades {
-			return false, fmt.Sprintf("daily trade limit reached: %d", o.dailyTrades)
+		counters := o.risk.Counters()
+
+		if counters.Trades >= o.config.MaxDailyTrades {
+			return false, fmt.Sprintf("daily trade limit reached: %d", counters.Trades)
 		}
 
-		if o.dailyLoss >= o.config.MaxDailyLoss {
-			return false, fmt.Sprintf("daily loss limit reached: %.2f", o.dailyLoss)
+		if counters.Loss >= o.config.MaxDailyLoss {
+			return false, fmt.Sprintf("daily loss limit reached: %.2f", counters.Loss)
 		}
 
-		if o.consecutiveLosses >= o.config.ConsecutiveLossLimit {
-			return false, fmt.Sprintf("consecutive loss limit reached: %d", o.consecutiveLosses)
+		if counters.ConsecutiveLosses >= o.config.ConsecutiveLossLimit {
+			return false, fmt.Sprintf("consecutive loss limit reached: %d", counters.ConsecutiveLosses)
 		}
 	}
 
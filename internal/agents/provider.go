@@ -0,0 +1,68 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+
+	"zerodha-trader/internal/config"
+)
+
+// DefaultLocalBaseURL is where a local provider is reached when no base_url
+// is configured: Ollama's OpenAI-compatible endpoint.
+const DefaultLocalBaseURL = "http://localhost:11434/v1"
+
+// ErrNoAPIKey is returned when a hosted provider is selected without a key.
+var ErrNoAPIKey = errors.New("no API key configured")
+
+// NewLLMClient creates the LLM client for a provider configuration, as
+// resolved by config.Config.LLMFor.
+func NewLLMClient(cfg config.LLMConfig) (LLMClient, error) {
+	switch cfg.Provider {
+	case "", config.ProviderOpenAI:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("openai: %w", ErrNoAPIKey)
+		}
+		if cfg.BaseURL == "" {
+			return NewOpenAIClient(cfg.APIKey, cfg.Model), nil
+		}
+		oc := openai.DefaultConfig(cfg.APIKey)
+		oc.BaseURL = cfg.BaseURL
+		return NewOpenAICompatibleClient(oc, config.ProviderOpenAI, cfg.Model), nil
+
+	case config.ProviderAzure:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("azure: %w", ErrNoAPIKey)
+		}
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("azure: no endpoint configured")
+		}
+		oc := openai.DefaultAzureConfig(cfg.APIKey, cfg.BaseURL)
+		if cfg.APIVersion != "" {
+			oc.APIVersion = cfg.APIVersion
+		}
+		// The configured model is the deployment name, used verbatim
+		oc.AzureModelMapperFunc = func(model string) string { return model }
+		return NewOpenAICompatibleClient(oc, config.ProviderAzure, cfg.Model), nil
+
+	case config.ProviderAnthropic:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("anthropic: %w", ErrNoAPIKey)
+		}
+		return NewAnthropicClient(cfg.APIKey, cfg.Model, cfg.BaseURL, cfg.MaxTokens), nil
+
+	case config.ProviderLocal:
+		oc := openai.DefaultConfig(cfg.APIKey)
+		oc.BaseURL = cfg.BaseURL
+		if oc.BaseURL == "" {
+			oc.BaseURL = DefaultLocalBaseURL
+		}
+		return NewOpenAICompatibleClient(oc, config.ProviderLocal, cfg.Model), nil
+
+	case config.ProviderFake:
+		return NewFakeClient(), nil
+	}
+
+	return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
+}
@@ -0,0 +1,338 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"zerodha-trader/internal/config"
+	"zerodha-trader/internal/store"
+)
+
+type echoExecutor struct{ calls []string }
+
+func (e *echoExecutor) ExecuteTool(_ context.Context, name string, args json.RawMessage) (string, error) {
+	e.calls = append(e.calls, name)
+	return name + ":" + string(args), nil
+}
+
+func testTools() []openai.Tool {
+	return []openai.Tool{{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:       "get_quote",
+			Parameters: json.RawMessage(`{"type":"object","properties":{"symbol":{"type":"string"}}}`),
+		},
+	}}
+}
+
+func TestAnthropicClientToolLoop(t *testing.T) {
+	var requests []anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "key" {
+			t.Errorf("unexpected request %s with key %q", r.URL.Path, r.Header.Get("x-api-key"))
+		}
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, req)
+
+		if len(requests) == 1 {
+			w.Write([]byte(`{"content":[{"type":"tool_use","id":"tu1","name":"get_quote","input":{"symbol":"INFY"}}],"stop_reason":"tool_use"}`))
+			return
+		}
+		w.Write([]byte(`{"content":[{"type":"text","text":"HOLD"}],"stop_reason":"end_turn"}`))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient("key", "claude-test", srv.URL, 0)
+	exec := &echoExecutor{}
+	cot, err := client.CompleteWithToolsVerbose(context.Background(), "system", "analyze INFY", testTools(), exec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cot.Response != "HOLD" || len(cot.ToolCalls) != 1 || cot.ToolCalls[0].Result != `get_quote:{"symbol":"INFY"}` {
+		t.Errorf("unexpected chain of thought: %+v", cot)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if requests[0].System != "system" || len(requests[0].Tools) != 1 || requests[0].MaxTokens != anthropicMaxTokens {
+		t.Errorf("first request: %+v", requests[0])
+	}
+	last := requests[1].Messages[len(requests[1].Messages)-1]
+	if last.Role != "user" || last.Content[0].Type != "tool_result" || last.Content[0].ToolUseID != "tu1" {
+		t.Errorf("tool result not sent back: %+v", last)
+	}
+}
+
+func TestFakeClientScriptedTools(t *testing.T) {
+	client := NewScriptedFakeClient(FakeTurn{
+		ToolCalls: []FakeToolCall{{Name: "get_quote", Arguments: `{"symbol":"TCS"}`}},
+		Response:  "done",
+	})
+	exec := &echoExecutor{}
+
+	cot, err := client.CompleteWithToolsVerbose(context.Background(), "s", "p", testTools(), exec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cot.Response != "done" || len(exec.calls) != 1 {
+		t.Errorf("unexpected chain of thought: %+v", cot)
+	}
+
+	// Once the script runs out, agents get a parseable HOLD
+	result, err := NewTechnicalAgent(client, 0.3).Analyze(context.Background(), AnalysisRequest{Symbol: "TCS", CurrentPrice: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Recommendation != Hold || result.Confidence != 50 {
+		t.Errorf("got %s at %.0f, want HOLD at 50", result.Recommendation, result.Confidence)
+	}
+	if calls := client.Calls(); len(calls) != 2 || calls[0].Tools[0] != "get_quote" {
+		t.Errorf("calls not recorded: %+v", calls)
+	}
+}
+
+func TestNewLLMClientProviders(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Provider = config.ProviderOpenAI
+	cfg.Agents.Model = "gpt-5.2"
+	cfg.Agents.LLM = map[string]config.LLMConfig{
+		"news":   {Provider: config.ProviderLocal, Model: "llama3.1"},
+		"trader": {Provider: config.ProviderFake},
+	}
+
+	if _, err := NewLLMClient(cfg.LLMFor("technical")); err == nil {
+		t.Error("expected missing OpenAI key to fail")
+	}
+
+	news, err := NewLLMClient(cfg.LLMFor("news"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oc, ok := news.(*OpenAIClient); !ok || oc.GetModel() != "llama3.1" {
+		t.Errorf("news client = %T", news)
+	}
+
+	trader, err := NewLLMClient(cfg.LLMFor("trader"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := trader.(*FakeClient); !ok {
+		t.Errorf("trader client = %T", trader)
+	}
+}
+
+func TestLLMForProviderSwitchDropsDefaultModel(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Model = "gpt-5.2"
+	cfg.Agents.LLM = map[string]config.LLMConfig{
+		"news":      {Provider: config.ProviderAnthropic},
+		"technical": {Provider: config.ProviderOpenAI, MaxTokens: 512},
+		"sentiment": {Provider: config.ProviderAnthropic, Model: "claude-sonnet"},
+	}
+
+	if got := cfg.LLMFor("news").Model; got != "" {
+		t.Errorf("anthropic override inherited model %q", got)
+	}
+	if got := cfg.LLMFor("technical").Model; got != "gpt-5.2" {
+		t.Errorf("same-provider override model = %q, want gpt-5.2", got)
+	}
+	if got := cfg.LLMFor("sentiment").Model; got != "claude-sonnet" {
+		t.Errorf("explicit model = %q, want claude-sonnet", got)
+	}
+
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "llm.news") {
+		t.Errorf("expected llm.news to need a model, got %v", err)
+	}
+}
+
+func TestValidateModelPerProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		model    string
+		llm      map[string]config.LLMConfig
+		wantErr  string
+	}{
+		{name: "openai default", provider: config.ProviderOpenAI, model: config.DefaultOpenAIModel},
+		{name: "anthropic without model", provider: config.ProviderAnthropic, wantErr: "model is required"},
+		{name: "anthropic with the OpenAI default", provider: config.ProviderAnthropic, model: config.DefaultOpenAIModel, wantErr: "OpenAI default"},
+		{name: "azure with the OpenAI default", provider: config.ProviderAzure, model: config.DefaultOpenAIModel, wantErr: "OpenAI default"},
+		{name: "anthropic with its own model", provider: config.ProviderAnthropic, model: "claude-sonnet-4-5"},
+		{name: "fake needs no model", provider: config.ProviderFake},
+		{
+			name: "override inherits a valid model", provider: config.ProviderAnthropic, model: "claude-sonnet-4-5",
+			llm: map[string]config.LLMConfig{"news": {MaxTokens: 512}},
+		},
+		{
+			name: "override keeps the OpenAI default", provider: config.ProviderOpenAI, model: config.DefaultOpenAIModel,
+			llm:     map[string]config.LLMConfig{"news": {Provider: config.ProviderLocal, Model: config.DefaultOpenAIModel}},
+			wantErr: "llm.news",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Agents.Provider = tt.provider
+			cfg.Agents.Model = tt.model
+			cfg.Agents.LLM = tt.llm
+
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadAppliesOpenAIModelOnlyToOpenAI(t *testing.T) {
+	for provider, want := range map[string]string{
+		config.ProviderOpenAI:    config.DefaultOpenAIModel,
+		config.ProviderAnthropic: "",
+	} {
+		dir := t.TempDir()
+		files := map[string]string{
+			"config.toml":      "",
+			"credentials.toml": "",
+			"agents.toml":      fmt.Sprintf("provider = %q\n", provider),
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cfg, err := config.Load(dir)
+		if provider == config.ProviderAnthropic {
+			if err == nil || !strings.Contains(err.Error(), "model is required") {
+				t.Errorf("%s without a model: Load() error = %v", provider, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Load() error = %v", provider, err)
+		}
+		if got := cfg.LLMFor("technical").Model; got != want {
+			t.Errorf("%s: model = %q, want %q", provider, got, want)
+		}
+	}
+}
+
+func TestLLMMeterCacheAndBudget(t *testing.T) {
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "trader.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	cfg := &config.AgentConfig{
+		LLMCache:        true,
+		LLMAgentBudgets: map[string]float64{"technical": 0.001},
+		LLMPricing:      map[string]config.LLMPrice{"fake": {Input: 1000, Output: 1000}},
+	}
+	meter := NewLLMMeter(db, cfg)
+	fake := NewFakeClient()
+	client := meter.Wrap("technical", config.ProviderOpenAI, fake)
+
+	ctx := WithCandleTime(context.Background(), time.Date(2026, 1, 5, 9, 15, 0, 0, time.UTC))
+	for i := 0; i < 2; i++ {
+		if _, err := client.CompleteWithSystem(ctx, "system", "prompt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(fake.Calls()); n != 1 {
+		t.Errorf("provider called %d times, want 1 with the second served from cache", n)
+	}
+
+	// The first call spent more than the budget, so a new candle is refused
+	next := WithCandleTime(context.Background(), time.Date(2026, 1, 5, 9, 20, 0, 0, time.UTC))
+	if _, err := client.CompleteWithSystem(next, "system", "prompt"); !errors.Is(err, ErrLLMBudgetExceeded) {
+		t.Errorf("err = %v, want budget exceeded", err)
+	}
+
+	summaries, err := db.GetLLMUsageSummary(context.Background(), store.DateRange{Start: time.Now().Add(-time.Hour), End: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 1 || summaries[0].Calls != 3 || summaries[0].CachedCalls != 1 || summaries[0].Errors != 1 || summaries[0].Cost <= 0 {
+		t.Errorf("unexpected usage summary: %+v", summaries)
+	}
+
+	// Once over budget the agent degrades to rule-based analysis
+	result, err := NewTechnicalAgent(client, 0.3).Analyze(next, AnalysisRequest{Symbol: "TCS", CurrentPrice: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(result.Reasoning, "fake LLM provider") {
+		t.Error("expected rule-based reasoning once the budget is spent")
+	}
+}
+
+// slowClient holds each completion open long enough for concurrent calls to
+// overlap.
+type slowClient struct{ *FakeClient }
+
+func (c slowClient) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	time.Sleep(5 * time.Millisecond)
+	return c.FakeClient.CompleteWithSystem(ctx, system, prompt)
+}
+
+func TestLLMMeterConcurrentBudget(t *testing.T) {
+	pricing := map[string]config.LLMPrice{"fake": {Input: 1000, Output: 1000}}
+	ctx := context.Background()
+
+	// Price one call on an unbudgeted meter. Every call below costs the same,
+	// so concurrent calls may overshoot the budget by at most this much.
+	probe := NewLLMMeter(nil, &config.AgentConfig{LLMPricing: pricing})
+	if _, err := probe.Wrap("technical", config.ProviderOpenAI, NewFakeClient()).CompleteWithSystem(ctx, "system", "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	perCall := probe.total
+	if perCall <= 0 {
+		t.Fatalf("call cost = %v, want a positive cost", perCall)
+	}
+
+	budget := 5.5 * perCall
+	for _, cfg := range []*config.AgentConfig{
+		{LLMDailyBudget: budget, LLMPricing: pricing},
+		{LLMAgentBudgets: map[string]float64{"technical": budget}, LLMPricing: pricing},
+	} {
+		meter := NewLLMMeter(nil, cfg)
+
+		client := meter.Wrap("technical", config.ProviderOpenAI, slowClient{NewFakeClient()})
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = client.CompleteWithSystem(ctx, "system", "prompt")
+			}()
+		}
+		wg.Wait()
+
+		if meter.total > budget+perCall*1.0001 {
+			t.Errorf("spent $%.6f of a $%.6f budget, more than one call over", meter.total, budget)
+		}
+		if meter.total < budget {
+			t.Errorf("spent $%.6f, want the budget of $%.6f used", meter.total, budget)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/store"
+)
+
+// DayBoundary maps a time to the start of the market day it belongs to.
+// trading.SessionManager implements it.
+type DayBoundary interface {
+	MarketDayStart(t time.Time) time.Time
+}
+
+// calendarDay is the boundary used when none is configured: midnight IST.
+type calendarDay struct{}
+
+func (calendarDay) MarketDayStart(t time.Time) time.Time {
+	t = t.In(resilience.IndiaLocation)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, resilience.IndiaLocation)
+}
+
+// RiskCounters are the daily counters behind the trade, loss, cooldown and
+// consecutive-loss limits.
+type RiskCounters struct {
+	DayStart          time.Time
+	Trades            int
+	Loss              float64
+	ConsecutiveLosses int
+	LastTradeAt       time.Time
+}
+
+// RiskState holds the daily risk counters shared by the Orchestrator and
+// trading.DecisionPipeline. Counters are rebuilt from the store's trades and
+// decisions by Load, so a restart mid-day keeps the limits in force, and
+// reset only when the day boundary is crossed.
+type RiskState struct {
+	mu       sync.RWMutex
+	store    store.DataStore
+	boundary DayBoundary
+	counters RiskCounters
+	now      func() time.Time
+}
+
+// NewRiskState creates risk state backed by dataStore, which may be nil. A
+// nil boundary starts each day at midnight IST.
+func NewRiskState(dataStore store.DataStore, boundary DayBoundary) *RiskState {
+	if boundary == nil {
+		boundary = calendarDay{}
+	}
+	r := &RiskState{
+		store:    dataStore,
+		boundary: boundary,
+		now:      time.Now,
+	}
+	r.counters.DayStart = boundary.MarketDayStart(r.now())
+	return r
+}
+
+// SetBoundary changes the market-day boundary. Call Load afterwards to
+// rebuild the counters for the new day.
+func (r *RiskState) SetBoundary(boundary DayBoundary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if boundary == nil {
+		boundary = calendarDay{}
+	}
+	r.boundary = boundary
+	r.rollover()
+}
+
+// Load rebuilds the current day's counters from the store. Executed
+// decisions and logged trades each count once, a trade logged against a
+// decision counting with it; realized P&L is taken in time order to restore
+// the loss total and the current losing streak.
+func (r *RiskState) Load(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	counters := RiskCounters{DayStart: r.boundary.MarketDayStart(now)}
+	if r.store == nil {
+		r.counters = counters
+		return nil
+	}
+
+	executed := true
+	decisions, err := r.store.GetDecisions(ctx, store.DecisionFilter{
+		StartDate: counters.DayStart,
+		EndDate:   now,
+		Executed:  &executed,
+	})
+	if err != nil {
+		return fmt.Errorf("loading today's decisions: %w", err)
+	}
+	trades, err := r.store.GetTrades(ctx, store.TradeFilter{
+		StartDate: counters.DayStart,
+		EndDate:   now,
+	})
+	if err != nil {
+		return fmt.Errorf("loading today's trades: %w", err)
+	}
+
+	type outcome struct {
+		at  time.Time
+		pnl float64
+	}
+	var outcomes []outcome
+	seen := make(map[string]bool)
+	traded := make(map[string]bool)
+
+	for _, t := range trades {
+		key := "trade:" + t.ID
+		if t.DecisionID != "" {
+			key = t.DecisionID
+			traded[t.DecisionID] = true
+		}
+		seen[key] = true
+		outcomes = append(outcomes, outcome{t.Timestamp, t.PnL})
+		if t.Timestamp.After(counters.LastTradeAt) {
+			counters.LastTradeAt = t.Timestamp
+		}
+	}
+	for _, d := range decisions {
+		seen[d.ID] = true
+		if d.Timestamp.After(counters.LastTradeAt) {
+			counters.LastTradeAt = d.Timestamp
+		}
+		if !traded[d.ID] && (d.Outcome == models.OutcomeWin || d.Outcome == models.OutcomeLoss) {
+			outcomes = append(outcomes, outcome{d.Timestamp, d.PnL})
+		}
+	}
+	counters.Trades = len(seen)
+
+	sort.SliceStable(outcomes, func(i, j int) bool { return outcomes[i].at.Before(outcomes[j].at) })
+	for _, o := range outcomes {
+		if o.pnl < 0 {
+			counters.Loss += -o.pnl
+			counters.ConsecutiveLosses++
+		} else {
+			counters.ConsecutiveLosses = 0
+		}
+	}
+
+	r.counters = counters
+	return nil
+}
+
+// RecordTrade records an executed trade and its realized P&L.
+func (r *RiskState) RecordTrade(pnl float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rollover()
+
+	r.counters.Trades++
+	r.counters.LastTradeAt = r.now()
+	if pnl < 0 {
+		r.counters.Loss += -pnl
+		r.counters.ConsecutiveLosses++
+	} else {
+		r.counters.ConsecutiveLosses = 0
+	}
+}
+
+// Counters returns the current day's counters.
+func (r *RiskState) Counters() RiskCounters {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rollover()
+	return r.counters
+}
+
+// Set overwrites the current day's counters (for testing or state recovery).
+func (r *RiskState) Set(trades int, loss float64, consecutiveLosses int, lastTradeAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rollover()
+
+	r.counters.Trades = trades
+	r.counters.Loss = loss
+	r.counters.ConsecutiveLosses = consecutiveLosses
+	r.counters.LastTradeAt = lastTradeAt
+}
+
+// Reset zeroes the current day's trade, loss and streak counters. The last
+// trade time is kept so the cooldown still applies.
+func (r *RiskState) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters = RiskCounters{DayStart: r.boundary.MarketDayStart(r.now()), LastTradeAt: r.counters.LastTradeAt}
+}
+
+// rollover zeroes the counters once the day boundary has been crossed.
+// Callers hold mu.
+func (r *RiskState) rollover() {
+	start := r.boundary.MarketDayStart(r.now())
+	if !start.Equal(r.counters.DayStart) {
+		r.counters = RiskCounters{DayStart: start, LastTradeAt: r.counters.LastTradeAt}
+	}
+}
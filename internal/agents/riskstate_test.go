@@ -0,0 +1,63 @@
+package agents
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/store"
+)
+
+// hourBoundary starts each market day at 09:00 UTC.
+type hourBoundary struct{}
+
+func (hourBoundary) MarketDayStart(t time.Time) time.Time {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), t.Day(), 9, 0, 0, 0, time.UTC)
+	if t.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+func TestRiskStateRestoresAndRollsOver(t *testing.T) {
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "trader.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	day := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+
+	// Yesterday's loss must not count
+	db.LogTrade(ctx, &models.Trade{ID: "old", Timestamp: at(-1, 0), Symbol: "TCS", PnL: -900})
+	// A decision whose trade was logged counts once, with the trade's P&L
+	db.SaveDecision(ctx, &models.Decision{ID: "d1", Timestamp: at(1, 0), Symbol: "INFY", Action: "BUY", Executed: true, Outcome: models.OutcomeLoss, PnL: -100})
+	db.LogTrade(ctx, &models.Trade{ID: "t1", Timestamp: at(1, 30), Symbol: "INFY", PnL: -200, DecisionID: "d1"})
+	db.SaveDecision(ctx, &models.Decision{ID: "d2", Timestamp: at(2, 0), Symbol: "SBIN", Action: "SELL", Executed: true, Outcome: models.OutcomeWin, PnL: 50})
+	db.LogTrade(ctx, &models.Trade{ID: "t2", Timestamp: at(3, 0), Symbol: "HDFC", PnL: -300})
+	db.SaveDecision(ctx, &models.Decision{ID: "d3", Timestamp: at(4, 0), Symbol: "ITC", Action: "BUY", Executed: true, Outcome: models.OutcomePending})
+	db.SaveDecision(ctx, &models.Decision{ID: "d4", Timestamp: at(4, 30), Symbol: "ITC", Action: "HOLD"})
+
+	now := at(5, 0)
+	rs := NewRiskState(db, hourBoundary{})
+	rs.now = func() time.Time { return now }
+	if err := rs.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := rs.Counters()
+	if c.Trades != 4 || c.Loss != 500 || c.ConsecutiveLosses != 1 || !c.LastTradeAt.Equal(at(4, 0)) {
+		t.Errorf("restored %+v, want 4 trades, 500 loss, 1 consecutive loss, last trade at %v", c, at(4, 0))
+	}
+
+	// Crossing the boundary resets the counters
+	now = at(24, 1)
+	if c := rs.Counters(); c.Trades != 0 || c.Loss != 0 || c.ConsecutiveLosses != 0 || !c.DayStart.Equal(at(24, 0)) {
+		t.Errorf("after boundary %+v, want zeroed counters", c)
+	}
+}
@@ -0,0 +1,269 @@
+package indicators
+
+import "zerodha-trader/internal/models"
+
+// Incremental is implemented by indicators that can update their latest
+// value one closed candle at a time, in O(1), instead of recomputing the
+// whole series. Feeding an Updater a series candle by candle yields the same
+// values Calculate returns for it.
+type Incremental interface {
+	Indicator
+	NewUpdater() Updater
+}
+
+// Updater holds the running state of an Incremental indicator.
+type Updater interface {
+	// Update adds the next closed candle and returns the latest value. ok is
+	// false while the indicator is still warming up.
+	Update(candle models.Candle) (value float64, ok bool)
+}
+
+// MultiIncremental is the multi-value counterpart of Incremental.
+type MultiIncremental interface {
+	MultiValueIndicator
+	NewUpdater() MultiUpdater
+}
+
+// MultiUpdater holds the running state of a MultiIncremental indicator.
+type MultiUpdater interface {
+	// Update adds the next closed candle and returns the latest values, keyed
+	// as in Calculate. ok is false while the indicator is still warming up.
+	Update(candle models.Candle) (values map[string]float64, ok bool)
+}
+
+// emaState updates an EMA seeded with the SMA of its first period values,
+// as CalculateEMA does.
+type emaState struct {
+	period int
+	n      int
+	sum    float64
+	value  float64
+}
+
+func (e *emaState) update(v float64) (float64, bool) {
+	e.n++
+	switch {
+	case e.n < e.period:
+		e.sum += v
+		return 0, false
+	case e.n == e.period:
+		e.sum += v
+		e.value = e.sum / float64(e.period)
+	default:
+		e.value = (v-e.value)*(2.0/float64(e.period+1)) + e.value
+	}
+	return e.value, true
+}
+
+// NewUpdater returns an EMA updater.
+func (e *EMA) NewUpdater() Updater {
+	return &emaUpdater{emaState{period: e.period}}
+}
+
+type emaUpdater struct {
+	ema emaState
+}
+
+func (u *emaUpdater) Update(c models.Candle) (float64, bool) {
+	return u.ema.update(c.Close)
+}
+
+// NewUpdater returns an RSI updater.
+func (r *RSI) NewUpdater() Updater {
+	return &rsiUpdater{period: r.period}
+}
+
+type rsiUpdater struct {
+	period    int
+	n         int
+	prevClose float64
+	avgGain   float64
+	avgLoss   float64
+}
+
+func (u *rsiUpdater) Update(c models.Candle) (float64, bool) {
+	u.n++
+	if u.n == 1 {
+		u.prevClose = c.Close
+		return 0, false
+	}
+
+	var gain, loss float64
+	if change := c.Close - u.prevClose; change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+	u.prevClose = c.Close
+
+	// The first averages are simple means, then Wilder smoothing
+	p := float64(u.period)
+	switch {
+	case u.n <= u.period:
+		u.avgGain += gain
+		u.avgLoss += loss
+		return 0, false
+	case u.n == u.period+1:
+		u.avgGain = (u.avgGain + gain) / p
+		u.avgLoss = (u.avgLoss + loss) / p
+	default:
+		u.avgGain = (u.avgGain*(p-1) + gain) / p
+		u.avgLoss = (u.avgLoss*(p-1) + loss) / p
+	}
+
+	if u.avgLoss == 0 {
+		return 100, true
+	}
+	return 100 - (100 / (1 + u.avgGain/u.avgLoss)), true
+}
+
+// NewUpdater returns an ATR updater.
+func (a *ATR) NewUpdater() Updater {
+	return &atrUpdater{period: a.period}
+}
+
+type atrUpdater struct {
+	period int
+	n      int
+	prev   models.Candle
+	sum    float64
+	value  float64
+}
+
+func (u *atrUpdater) Update(c models.Candle) (float64, bool) {
+	u.n++
+	tr := c.High - c.Low
+	if u.n > 1 {
+		tr = trueRange(c, u.prev)
+	}
+	u.prev = c
+
+	p := float64(u.period)
+	switch {
+	case u.n < u.period:
+		u.sum += tr
+		return 0, false
+	case u.n == u.period:
+		u.value = (u.sum + tr) / p
+	default:
+		u.value = (u.value*(p-1) + tr) / p
+	}
+	return u.value, true
+}
+
+// NewUpdater returns a VWAP updater. Like Calculate, it accumulates from the
+// first candle it sees; start a new updater each session.
+func (v *VWAP) NewUpdater() Updater {
+	return &vwapUpdater{}
+}
+
+type vwapUpdater struct {
+	tpv    float64
+	volume float64
+}
+
+func (u *vwapUpdater) Update(c models.Candle) (float64, bool) {
+	u.tpv += typicalPrice(c) * float64(c.Volume)
+	u.volume += float64(c.Volume)
+	if u.volume == 0 {
+		return 0, true
+	}
+	return u.tpv / u.volume, true
+}
+
+// NewUpdater returns a MACD updater. It is ready once the signal line is.
+func (m *MACD) NewUpdater() MultiUpdater {
+	return &macdUpdater{
+		fast:   emaState{period: m.fastPeriod},
+		slow:   emaState{period: m.slowPeriod},
+		signal: emaState{period: m.signalPeriod},
+	}
+}
+
+type macdUpdater struct {
+	fast, slow, signal emaState
+}
+
+func (u *macdUpdater) Update(c models.Candle) (map[string]float64, bool) {
+	fast, _ := u.fast.update(c.Close)
+	slow, ok := u.slow.update(c.Close)
+	if !ok {
+		return nil, false
+	}
+	macd := fast - slow
+	signal, ok := u.signal.update(macd)
+	if !ok {
+		return nil, false
+	}
+	return map[string]float64{
+		"macd":      macd,
+		"signal":    signal,
+		"histogram": macd - signal,
+	}, true
+}
+
+// NewUpdater returns a SuperTrend updater.
+func (s *SuperTrend) NewUpdater() MultiUpdater {
+	return &superTrendUpdater{
+		atr:        atrUpdater{period: s.atrPeriod},
+		multiplier: s.multiplier,
+	}
+}
+
+type superTrendUpdater struct {
+	atr        atrUpdater
+	multiplier float64
+	ready      bool
+	prevClose  float64
+	upper      float64
+	lower      float64
+	superTrend float64
+	direction  float64
+}
+
+func (u *superTrendUpdater) Update(c models.Candle) (map[string]float64, bool) {
+	atr, ok := u.atr.Update(c)
+	if !ok {
+		u.prevClose = c.Close
+		return nil, false
+	}
+
+	hl2 := (c.High + c.Low) / 2
+	upper := hl2 + u.multiplier*atr
+	lower := hl2 - u.multiplier*atr
+
+	if !u.ready {
+		u.ready = true
+		u.superTrend, u.direction = upper, -1
+	} else {
+		// Adjust bands based on previous values
+		if lower < u.lower && u.prevClose > u.lower {
+			lower = u.lower
+		}
+		if upper > u.upper && u.prevClose < u.upper {
+			upper = u.upper
+		}
+
+		if u.superTrend == u.upper {
+			if c.Close > upper {
+				u.superTrend, u.direction = lower, 1
+			} else {
+				u.superTrend, u.direction = upper, -1
+			}
+		} else {
+			if c.Close < lower {
+				u.superTrend, u.direction = upper, -1
+			} else {
+				u.superTrend, u.direction = lower, 1
+			}
+		}
+	}
+	u.upper, u.lower, u.prevClose = upper, lower, c.Close
+
+	return map[string]float64{
+		"supertrend": u.superTrend,
+		"direction":  u.direction,
+		"upper_band": u.upper,
+		"lower_band": u.lower,
+	}, true
+}
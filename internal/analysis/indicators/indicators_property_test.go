@@ -484,3 +484,61 @@ func TestProperty_PivotPointsOrdering(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+// Property: Feeding an incremental updater a series one candle at a time
+// yields exactly the values Calculate returns for the series.
+func TestProperty_IncrementalMatchesCalculate(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = 100
+	parameters.Rng.Seed(time.Now().UnixNano())
+
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("incremental values equal full recalculation", prop.ForAll(
+		func(candles []models.Candle) bool {
+			for _, ind := range []Incremental{NewRSI(14), NewEMA(20), NewATR(14), NewVWAP()} {
+				values, err := ind.Calculate(candles)
+				if err != nil {
+					return false
+				}
+				u := ind.NewUpdater()
+				for i, c := range candles {
+					v, ok := u.Update(c)
+					if ok != (i >= ind.Period()-1+rsiOffset(ind)) || (ok && v != values[i]) {
+						return false
+					}
+				}
+			}
+			for _, ind := range []MultiIncremental{NewMACD(12, 26, 9), NewSuperTrend(10, 3)} {
+				series, err := ind.Calculate(candles)
+				if err != nil {
+					return false
+				}
+				u := ind.NewUpdater()
+				for i, c := range candles {
+					values, ok := u.Update(c)
+					if ok != (i >= ind.Period()-1) {
+						return false
+					}
+					for key, v := range values {
+						if v != series[key][i] {
+							return false
+						}
+					}
+				}
+			}
+			return true
+		},
+		candleSliceGen(40, 120),
+	))
+
+	properties.TestingRun(t)
+}
+
+// rsiOffset accounts for RSI's first value needing period+1 candles.
+func rsiOffset(ind Indicator) int {
+	if _, ok := ind.(*RSI); ok {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,495 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"zerodha-trader/internal/analysis/indicators"
+	"zerodha-trader/internal/broker"
+	apperrors "zerodha-trader/internal/errors"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/security"
+)
+
+// maxRequestBody limits the size of JSON request bodies.
+const maxRequestBody = 1 << 20
+
+// errorResponse is the JSON body returned for failed requests.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// OrderRequest is the JSON body accepted by POST /api/order.
+type OrderRequest struct {
+	Symbol       string  `json:"symbol"`
+	Exchange     string  `json:"exchange"`
+	Side         string  `json:"side"`
+	Type         string  `json:"type"`
+	Product      string  `json:"product"`
+	Quantity     int     `json:"quantity"`
+	Price        float64 `json:"price"`
+	TriggerPrice float64 `json:"trigger_price"`
+	Validity     string  `json:"validity"`
+	Tag          string  `json:"tag"`
+}
+
+// AnalysisResponse is the JSON body returned by GET /api/analysis/:symbol.
+type AnalysisResponse struct {
+	Symbol     string                        `json:"symbol"`
+	Exchange   string                        `json:"exchange"`
+	Timeframe  string                        `json:"timeframe"`
+	Candles    int                           `json:"candles"`
+	LastClose  float64                       `json:"last_close"`
+	AsOf       time.Time                     `json:"as_of"`
+	Indicators map[string]float64            `json:"indicators"`
+	Bands      map[string]map[string]float64 `json:"bands"`
+}
+
+// SignalResponse is the JSON body returned by GET /api/signal/:symbol.
+type SignalResponse struct {
+	Symbol         string             `json:"symbol"`
+	Exchange       string             `json:"exchange"`
+	Timeframe      string             `json:"timeframe"`
+	Score          float64            `json:"score"`
+	Recommendation string             `json:"recommendation"`
+	Components     map[string]float64 `json:"components"`
+	VolumeConfirm  bool               `json:"volume_confirm"`
+}
+
+func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	symbol, exchange, ok := s.symbolParam(w, r)
+	if !ok {
+		return
+	}
+
+	quote, err := s.broker.GetQuote(r.Context(), fmt.Sprintf("%s:%s", exchange, symbol))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to get quote: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, quote)
+}
+
+func (s *Server) handlePositions(w http.ResponseWriter, r *http.Request) {
+	positions, err := s.broker.GetPositions(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to get positions: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, positions)
+}
+
+func (s *Server) handleHoldings(w http.ResponseWriter, r *http.Request) {
+	holdings, err := s.broker.GetHoldings(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to get holdings: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, holdings)
+}
+
+func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
+	orders, err := s.broker.GetOrders(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to get orders: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, orders)
+}
+
+func (s *Server) handlePlaceOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !s.checkPermission(w, r, security.OpPlaceOrder) {
+		return
+	}
+
+	var req OrderRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := s.buildOrder(req)
+	if err != nil {
+		s.auditOrder(ctx, r, security.AuditInputValidation, "", req.Symbol, req.Side, nil, err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The broker audits the order itself, tagged with this request's ID
+	result, err := s.broker.PlaceOrder(ctx, order)
+	if err != nil {
+		writeError(w, orderErrorStatus(err), fmt.Sprintf("order failed: %v", err))
+		return
+	}
+
+	s.logger.Info().
+		Str("order_id", result.OrderID).
+		Str("symbol", order.Symbol).
+		Str("side", string(order.Side)).
+		Int("quantity", order.Quantity).
+		Msg("Order placed via API")
+
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !s.checkPermission(w, r, security.OpCancelOrder) {
+		return
+	}
+
+	orderID := r.PathValue("id")
+	if err := s.validator.ValidateOrderID(orderID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.broker.CancelOrder(ctx, orderID); err != nil {
+		writeError(w, orderErrorStatus(err), fmt.Sprintf("cancel failed: %v", err))
+		return
+	}
+
+	s.logger.Info().Str("order_id", orderID).Msg("Order cancelled via API")
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"order_id": orderID,
+		"status":   "CANCELLED",
+	})
+}
+
+func (s *Server) handleAnalysis(w http.ResponseWriter, r *http.Request) {
+	symbol, exchange, ok := s.symbolParam(w, r)
+	if !ok {
+		return
+	}
+	timeframe := queryOr(r, "timeframe", "1day")
+
+	candles, err := s.fetchCandles(r.Context(), symbol, exchange, timeframe)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to get historical data: %v", err))
+		return
+	}
+	if len(candles) < 26 {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("insufficient data: need at least 26 candles, got %d", len(candles)))
+		return
+	}
+
+	single, multi, err := newAnalysisEngine().CalculateAll(r.Context(), candles)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	last := candles[len(candles)-1]
+	resp := AnalysisResponse{
+		Symbol:     symbol,
+		Exchange:   string(exchange),
+		Timeframe:  timeframe,
+		Candles:    len(candles),
+		LastClose:  last.Close,
+		AsOf:       last.Timestamp,
+		Indicators: make(map[string]float64, len(single)),
+		Bands:      make(map[string]map[string]float64, len(multi)),
+	}
+	for name, values := range single {
+		resp.Indicators[name] = lastValue(values)
+	}
+	for name, series := range multi {
+		latest := make(map[string]float64, len(series))
+		for key, values := range series {
+			latest[key] = lastValue(values)
+		}
+		resp.Bands[name] = latest
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleSignal(w http.ResponseWriter, r *http.Request) {
+	if s.scorer == nil {
+		writeError(w, http.StatusServiceUnavailable, "signal scorer not configured")
+		return
+	}
+
+	symbol, exchange, ok := s.symbolParam(w, r)
+	if !ok {
+		return
+	}
+	timeframe := queryOr(r, "timeframe", "1day")
+
+	candles, err := s.fetchCandles(r.Context(), symbol, exchange, timeframe)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to get historical data: %v", err))
+		return
+	}
+
+	score, err := s.scorer.Score(r.Context(), candles)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SignalResponse{
+		Symbol:         symbol,
+		Exchange:       string(exchange),
+		Timeframe:      timeframe,
+		Score:          score.Score,
+		Recommendation: string(score.Recommendation),
+		Components:     score.Components,
+		VolumeConfirm:  score.VolumeConfirm,
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if s.health == nil {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "alive"})
+		return
+	}
+	s.health.HealthHTTPHandler()(w, r)
+}
+
+// symbolParam extracts and validates the symbol path value and exchange query parameter.
+func (s *Server) symbolParam(w http.ResponseWriter, r *http.Request) (string, models.Exchange, bool) {
+	symbol := strings.ToUpper(strings.TrimSpace(r.PathValue("symbol")))
+	if err := s.validator.ValidateSymbol(symbol); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+
+	exchange := models.Exchange(strings.ToUpper(queryOr(r, "exchange", string(s.config.DefaultExchange))))
+	return symbol, exchange, true
+}
+
+// fetchCandles loads enough history for indicator calculation on the given timeframe.
+func (s *Server) fetchCandles(ctx context.Context, symbol string, exchange models.Exchange, timeframe string) ([]models.Candle, error) {
+	days := 150
+	switch timeframe {
+	case "1min", "3min", "5min", "15min":
+		days = 5
+	case "30min", "1hour", "60min":
+		days = 30
+	}
+
+	return s.broker.GetHistorical(ctx, broker.HistoricalRequest{
+		Symbol:    symbol,
+		Exchange:  exchange,
+		Timeframe: timeframe,
+		From:      time.Now().AddDate(0, 0, -days),
+		To:        time.Now(),
+	})
+}
+
+// buildOrder validates an order request and converts it to a broker order.
+func (s *Server) buildOrder(req OrderRequest) (*models.Order, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if err := s.validator.ValidateSymbol(symbol); err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidateQuantity(req.Quantity); err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidatePrice(req.Price); err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidatePrice(req.TriggerPrice); err != nil {
+		return nil, err
+	}
+
+	order := &models.Order{
+		Symbol:       symbol,
+		Exchange:     models.Exchange(strings.ToUpper(req.Exchange)),
+		Side:         models.OrderSide(strings.ToUpper(req.Side)),
+		Type:         models.OrderType(strings.ToUpper(req.Type)),
+		Product:      models.ProductType(strings.ToUpper(req.Product)),
+		Quantity:     req.Quantity,
+		Price:        req.Price,
+		TriggerPrice: req.TriggerPrice,
+		Validity:     strings.ToUpper(req.Validity),
+		Tag:          req.Tag,
+	}
+
+	if order.Exchange == "" {
+		order.Exchange = s.config.DefaultExchange
+	}
+	if order.Product == "" {
+		order.Product = models.ProductMIS
+	}
+	if order.Type == "" {
+		order.Type = models.OrderTypeMarket
+		if order.Price > 0 {
+			order.Type = models.OrderTypeLimit
+		}
+	}
+	if order.Validity == "" {
+		order.Validity = "DAY"
+	}
+
+	switch order.Side {
+	case models.OrderSideBuy, models.OrderSideSell:
+	default:
+		return nil, fmt.Errorf("invalid side %q (must be BUY or SELL)", req.Side)
+	}
+
+	switch order.Product {
+	case models.ProductMIS, models.ProductCNC, models.ProductNRML:
+	default:
+		return nil, fmt.Errorf("invalid product %q (must be MIS, CNC or NRML)", req.Product)
+	}
+
+	switch order.Type {
+	case models.OrderTypeMarket:
+	case models.OrderTypeLimit:
+		if order.Price <= 0 {
+			return nil, fmt.Errorf("price is required for LIMIT orders")
+		}
+	case models.OrderTypeStopLoss:
+		if order.Price <= 0 || order.TriggerPrice <= 0 {
+			return nil, fmt.Errorf("price and trigger_price are required for SL orders")
+		}
+	case models.OrderTypeStopLossM:
+		if order.TriggerPrice <= 0 {
+			return nil, fmt.Errorf("trigger_price is required for SL-M orders")
+		}
+	default:
+		return nil, fmt.Errorf("invalid order type %q (must be MARKET, LIMIT, SL or SL-M)", req.Type)
+	}
+
+	if order.Validity != "DAY" && order.Validity != "IOC" {
+		return nil, fmt.Errorf("invalid validity %q (must be DAY or IOC)", req.Validity)
+	}
+
+	return order, nil
+}
+
+// checkPermission rejects write operations when read-only mode is enabled.
+func (s *Server) checkPermission(w http.ResponseWriter, r *http.Request, op security.OperationType) bool {
+	if s.access == nil {
+		return true
+	}
+
+	if err := s.access.CheckPermission(r.Context(), op); err != nil {
+		var roErr *security.ReadOnlyError
+		if errors.As(err, &roErr) {
+			writeError(w, http.StatusForbidden, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// auditOrder records a mutating API call in the audit log.
+func (s *Server) auditOrder(ctx context.Context, r *http.Request, eventType security.AuditEventType, orderID, symbol, action string, details map[string]interface{}, err error) {
+	if s.audit == nil {
+		return
+	}
+
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["source"] = "api"
+	details["route"] = r.Method + " " + r.URL.Path
+
+	event := security.AuditEvent{
+		EventType: eventType,
+		OrderID:   orderID,
+		Symbol:    symbol,
+		Action:    action,
+		Details:   details,
+		Success:   err == nil,
+		IPAddress: clientIP(r),
+	}
+	if err != nil {
+		event.ErrorMsg = err.Error()
+	}
+
+	if logErr := s.audit.Log(ctx, event); logErr != nil {
+		s.logger.Error().Err(logErr).Msg("Failed to write audit event")
+	}
+}
+
+// newAnalysisEngine creates an indicator engine with the standard analysis set.
+func newAnalysisEngine() *indicators.Engine {
+	engine := indicators.NewEngine(4)
+
+	engine.RegisterIndicator(indicators.NewRSI(14))
+	engine.RegisterIndicator(indicators.NewSMA(20))
+	engine.RegisterIndicator(indicators.NewSMA(50))
+	engine.RegisterIndicator(indicators.NewEMA(9))
+	engine.RegisterIndicator(indicators.NewEMA(20))
+	engine.RegisterIndicator(indicators.NewEMA(50))
+	engine.RegisterIndicator(indicators.NewATR(14))
+	engine.RegisterIndicator(indicators.NewCCI(20))
+	engine.RegisterIndicator(indicators.NewVWAP())
+	engine.RegisterIndicator(indicators.NewOBV())
+
+	engine.RegisterMultiIndicator(indicators.NewMACD(12, 26, 9))
+	engine.RegisterMultiIndicator(indicators.NewADX(14))
+	engine.RegisterMultiIndicator(indicators.NewSuperTrend(10, 3.0))
+	engine.RegisterMultiIndicator(indicators.NewBollingerBands(20, 2.0))
+	engine.RegisterMultiIndicator(indicators.NewStochastic(14, 3, 3))
+
+	return engine
+}
+
+// lastValue returns the last element of a series, or 0 if empty.
+func lastValue(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return values[len(values)-1]
+}
+
+// queryOr returns a query parameter or the fallback if it is absent.
+func queryOr(r *http.Request, key, fallback string) string {
+	if v := r.URL.Query().Get(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// decodeJSON decodes a size-limited JSON request body.
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
+
+// orderErrorStatus maps an order error to a response status. Orders refused
+// by policy, such as the risk gate or kill switch, are 403; orders the
+// broker cannot accept as sent are 422; anything else is an upstream
+// failure.
+func orderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, apperrors.ErrOrderRejected), errors.Is(err, apperrors.ErrReadOnlyMode):
+		return http.StatusForbidden
+	case errors.Is(err, apperrors.ErrInvalidOrder), errors.Is(err, apperrors.ErrInsufficientFunds),
+		errors.Is(err, apperrors.ErrMarketClosed):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadGateway
+	}
+}
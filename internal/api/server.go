@@ -0,0 +1,251 @@
+// Package api provides the REST API server for external integrations.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"zerodha-trader/internal/analysis/scoring"
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/security"
+)
+
+// Config holds API server configuration.
+type Config struct {
+	// Host is the interface to bind to.
+	Host string
+	// Port is the TCP port to listen on.
+	Port int
+	// APIKey is the key clients must send in the X-API-Key header.
+	// An empty key disables authentication.
+	APIKey string
+	// DefaultExchange is used when a request does not specify an exchange.
+	DefaultExchange models.Exchange
+	// ReadTimeout is the maximum duration for reading a request.
+	ReadTimeout time.Duration
+	// WriteTimeout is the maximum duration before timing out writes of a response.
+	WriteTimeout time.Duration
+	// ShutdownTimeout is the grace period for in-flight requests on shutdown.
+	ShutdownTimeout time.Duration
+}
+
+// DefaultConfig returns the default API server configuration.
+func DefaultConfig() Config {
+	return Config{
+		Host:            "localhost",
+		Port:            8080,
+		DefaultExchange: models.NSE,
+		ReadTimeout:     10 * time.Second,
+		WriteTimeout:    60 * time.Second,
+		ShutdownTimeout: 5 * time.Second,
+	}
+}
+
+// Addr returns the listen address for the configuration.
+func (c Config) Addr() string {
+	return net.JoinHostPort(c.Host, fmt.Sprintf("%d", c.Port))
+}
+
+// Route describes a single API endpoint.
+type Route struct {
+	Method      string
+	Path        string
+	Description string
+	Mutating    bool
+}
+
+// Routes returns the endpoints served by the API server.
+func Routes() []Route {
+	return []Route{
+		{Method: http.MethodGet, Path: "/api/quote/{symbol}", Description: "Get real-time quote"},
+		{Method: http.MethodGet, Path: "/api/positions", Description: "Get open positions"},
+		{Method: http.MethodGet, Path: "/api/holdings", Description: "Get holdings"},
+		{Method: http.MethodGet, Path: "/api/orders", Description: "Get orders"},
+		{Method: http.MethodPost, Path: "/api/order", Description: "Place order", Mutating: true},
+		{Method: http.MethodDelete, Path: "/api/order/{id}", Description: "Cancel order", Mutating: true},
+		{Method: http.MethodGet, Path: "/api/analysis/{symbol}", Description: "Get technical analysis"},
+		{Method: http.MethodGet, Path: "/api/signal/{symbol}", Description: "Get signal score"},
+		{Method: http.MethodGet, Path: "/api/health", Description: "Health check"},
+	}
+}
+
+// Server serves the trading REST API on top of a broker.
+type Server struct {
+	config    Config
+	broker    broker.Broker
+	scorer    *scoring.SignalScorer
+	health    *resilience.HealthMonitor
+	access    *security.AccessController
+	audit     *security.AuditLogger
+	validator *security.InputValidator
+	logger    zerolog.Logger
+
+	httpServer *http.Server
+}
+
+// NewServer creates a new API server.
+// The scorer, health monitor, access controller and audit logger are optional.
+func NewServer(
+	cfg Config,
+	b broker.Broker,
+	scorer *scoring.SignalScorer,
+	health *resilience.HealthMonitor,
+	access *security.AccessController,
+	audit *security.AuditLogger,
+	logger zerolog.Logger,
+) *Server {
+	if cfg.DefaultExchange == "" {
+		cfg.DefaultExchange = models.NSE
+	}
+
+	return &Server{
+		config:    cfg,
+		broker:    b,
+		scorer:    scorer,
+		health:    health,
+		access:    access,
+		audit:     audit,
+		validator: security.NewInputValidator(true),
+		logger:    logger.With().Str("component", "api").Logger(),
+	}
+}
+
+// Handler returns the HTTP handler with all routes and middleware applied.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/quote/{symbol}", s.handleQuote)
+	mux.HandleFunc("GET /api/positions", s.handlePositions)
+	mux.HandleFunc("GET /api/holdings", s.handleHoldings)
+	mux.HandleFunc("GET /api/orders", s.handleOrders)
+	mux.HandleFunc("POST /api/order", s.handlePlaceOrder)
+	mux.HandleFunc("DELETE /api/order/{id}", s.handleCancelOrder)
+	mux.HandleFunc("GET /api/analysis/{symbol}", s.handleAnalysis)
+	mux.HandleFunc("GET /api/signal/{symbol}", s.handleSignal)
+	mux.HandleFunc("GET /api/health", s.handleHealth)
+
+	return s.withRecovery(s.withRequestID(s.withAuth(mux)))
+}
+
+// ListenAndServe starts the server and blocks until the context is cancelled
+// or the listener fails.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	s.httpServer = &http.Server{
+		Addr:         s.config.Addr(),
+		Handler:      s.Handler(),
+		ReadTimeout:  s.config.ReadTimeout,
+		WriteTimeout: s.config.WriteTimeout,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		s.logger.Info().Str("addr", s.httpServer.Addr).Msg("API server listening")
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errChan <- err
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down API server: %w", err)
+	}
+	return nil
+}
+
+// withAuth enforces the API key on every route except the health check.
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.APIKey == "" || r.URL.Path == "/api/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := r.Header.Get("X-API-Key")
+		if key == "" {
+			key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(key), []byte(s.config.APIKey)) != 1 {
+			s.logger.Warn().Str("remote", clientIP(r)).Str("path", r.URL.Path).Msg("Rejected request with invalid API key")
+			if s.audit != nil {
+				s.audit.Log(r.Context(), security.AuditEvent{
+					EventType: security.AuditAuthFailed,
+					Action:    r.Method + " " + r.URL.Path,
+					IPAddress: clientIP(r),
+					Success:   false,
+					ErrorMsg:  "invalid API key",
+				})
+			}
+			writeError(w, http.StatusUnauthorized, "invalid or missing API key")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withRequestID attaches a request ID and the client address to the context
+// so audit events written further down, such as by the broker, can be
+// correlated.
+func (s *Server) withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID := r.Header.Get("X-Request-ID")
+		if reqID == "" {
+			reqID = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", reqID)
+
+		ctx := context.WithValue(r.Context(), "request_id", reqID)
+		ctx = context.WithValue(ctx, "ip_address", clientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withRecovery converts handler panics into 500 responses.
+func (s *Server) withRecovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				s.logger.Error().Interface("panic", rec).Str("path", r.URL.Path).Msg("Recovered from panic in API handler")
+				writeError(w, http.StatusInternalServerError, "internal server error")
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the remote address of the request without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// newRequestID generates a random request ID.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
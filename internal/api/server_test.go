@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/config"
+	apperrors "zerodha-trader/internal/errors"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/security"
+	"zerodha-trader/internal/trading"
+)
+
+func newTestServer(apiKey string, readOnly bool) (*Server, *broker.PaperBroker) {
+	paper := broker.NewPaperBroker(broker.PaperBrokerConfig{InitialBalance: 1000000})
+	paper.UpdatePrice("RELIANCE", 2500)
+
+	cfg := DefaultConfig()
+	cfg.APIKey = apiKey
+
+	srv := NewServer(cfg, paper, nil, nil, security.NewAccessController(readOnly, nil), nil, zerolog.Nop())
+	return srv, paper
+}
+
+func TestServer_RejectsMissingAPIKey(t *testing.T) {
+	srv, _ := newTestServer("secret-key", false)
+	handler := srv.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/positions", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without key, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/positions", nil)
+	req.Header.Set("X-API-Key", "secret-key")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with key, got %d", rec.Code)
+	}
+
+	// Health check is always reachable for load balancers.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for health without key, got %d", rec.Code)
+	}
+}
+
+func TestServer_PlaceOrder(t *testing.T) {
+	srv, paper := newTestServer("", false)
+
+	body, _ := json.Marshal(OrderRequest{Symbol: "reliance", Side: "BUY", Quantity: 10})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/order", bytes.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var result broker.OrderResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if result.OrderID == "" {
+		t.Error("expected order ID in response")
+	}
+
+	positions, _ := paper.GetPositions(context.Background())
+	if len(positions) != 1 || positions[0].Quantity != 10 {
+		t.Errorf("expected one position of 10 shares, got %+v", positions)
+	}
+}
+
+func TestServer_ReadOnlyBlocksWrites(t *testing.T) {
+	srv, paper := newTestServer("", true)
+	handler := srv.Handler()
+
+	body, _ := json.Marshal(OrderRequest{Symbol: "RELIANCE", Side: "BUY", Quantity: 1})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/order", bytes.NewReader(body)))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 in read-only mode, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/order/PAPER_1", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for cancel in read-only mode, got %d", rec.Code)
+	}
+
+	orders, _ := paper.GetOrders(context.Background())
+	if len(orders) != 0 {
+		t.Errorf("expected no orders to reach the broker, got %d", len(orders))
+	}
+}
+
+func TestServer_RejectsInvalidOrder(t *testing.T) {
+	srv, _ := newTestServer("", false)
+
+	tests := []OrderRequest{
+		{Symbol: "RELIANCE", Side: "HOLD", Quantity: 1},
+		{Symbol: "RELIANCE", Side: "BUY", Quantity: 0},
+		{Symbol: "RELIANCE", Side: "BUY", Quantity: 1, Type: "LIMIT"},
+		{Symbol: "RELI;ANCE", Side: "BUY", Quantity: 1},
+	}
+
+	for _, tc := range tests {
+		body, _ := json.Marshal(tc)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/order", bytes.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %+v, got %d", tc, rec.Code)
+		}
+	}
+}
+
+// failingBroker fails every order with err.
+type failingBroker struct {
+	broker.Broker
+	err error
+}
+
+func (b *failingBroker) PlaceOrder(ctx context.Context, order *models.Order) (*broker.OrderResult, error) {
+	return nil, b.err
+}
+
+func (b *failingBroker) CancelOrder(ctx context.Context, orderID string) error {
+	return b.err
+}
+
+func TestServer_OrderErrorStatus(t *testing.T) {
+	newServer := func(b broker.Broker) *Server {
+		return NewServer(DefaultConfig(), b, nil, nil, security.NewAccessController(false, nil), nil, zerolog.Nop())
+	}
+	paper := func() *broker.PaperBroker {
+		p := broker.NewPaperBroker(broker.PaperBrokerConfig{InitialBalance: 1000000})
+		p.UpdatePrice("RELIANCE", 2500)
+		return p
+	}
+
+	killed := trading.NewRiskGate(paper(), config.RiskConfig{}, nil)
+	if err := killed.SetKillSwitch(context.Background(), true, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		broker broker.Broker
+		cancel int
+		place  int
+	}{
+		{"risk gate limit", trading.NewRiskGate(paper(), config.RiskConfig{MaxOrderValue: 1000}, nil), 0, http.StatusForbidden},
+		{"kill switch", killed, 0, http.StatusForbidden},
+		{"broker refusal", &failingBroker{err: fmt.Errorf("margin check: %w", apperrors.ErrInsufficientFunds)}, http.StatusUnprocessableEntity, http.StatusUnprocessableEntity},
+		{"upstream down", &failingBroker{err: fmt.Errorf("kite: %w", apperrors.ErrConnectionFailed)}, http.StatusBadGateway, http.StatusBadGateway},
+		{"unknown error", &failingBroker{err: fmt.Errorf("unexpected EOF")}, http.StatusBadGateway, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newServer(tt.broker).Handler()
+
+			body, _ := json.Marshal(OrderRequest{Symbol: "RELIANCE", Side: "BUY", Quantity: 10})
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/order", bytes.NewReader(body)))
+			if rec.Code != tt.place {
+				t.Errorf("place order: expected %d, got %d: %s", tt.place, rec.Code, rec.Body.String())
+			}
+
+			if tt.cancel == 0 {
+				return
+			}
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/order/PAPER_1", nil))
+			if rec.Code != tt.cancel {
+				t.Errorf("cancel order: expected %d, got %d: %s", tt.cancel, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
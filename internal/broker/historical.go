@@ -0,0 +1,37 @@
+package broker
+
+import "time"
+
+// historicalMaxDays is the longest range Kite serves in one historical data
+// request, by interval.
+var historicalMaxDays = map[string]int{
+	"minute":   60,
+	"3minute":  100,
+	"5minute":  100,
+	"10minute": 100,
+	"15minute": 200,
+	"30minute": 200,
+	"60minute": 400,
+	"day":      2000,
+}
+
+// Chunks splits the request into consecutive requests no longer than Kite
+// serves for its timeframe. A range that already fits is returned as is.
+func (r HistoricalRequest) Chunks() []HistoricalRequest {
+	if !r.From.Before(r.To) {
+		return []HistoricalRequest{r}
+	}
+	span := time.Duration(historicalMaxDays[mapTimeframeToInterval(r.Timeframe)]) * 24 * time.Hour
+
+	var chunks []HistoricalRequest
+	for from := r.From; !from.After(r.To); from = from.Add(span) {
+		chunk := r
+		chunk.From = from
+		// Kite's range is inclusive at both ends
+		if end := from.Add(span - time.Second); end.Before(r.To) {
+			chunk.To = end
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
@@ -0,0 +1,258 @@
+package kitetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GTT statuses, as Kite reports them.
+const (
+	GTTActive    = "active"
+	GTTTriggered = "triggered"
+	GTTDeleted   = "deleted"
+)
+
+// gtt is a GTT trigger. A single trigger fires when the last price crosses
+// its value from where the market was when it was placed; a two-leg trigger
+// fires its first leg at or below the lower value and its second at or
+// above the upper one.
+type gtt struct {
+	id        int
+	kind      string
+	inst      *instrument
+	condition gttCondition
+	orders    []gttOrder
+	status    string
+	reference float64
+	orderID   string
+	created   time.Time
+	updated   time.Time
+}
+
+type gttCondition struct {
+	Exchange      string    `json:"exchange"`
+	Tradingsymbol string    `json:"tradingsymbol"`
+	LastPrice     float64   `json:"last_price"`
+	TriggerValues []float64 `json:"trigger_values"`
+}
+
+type gttOrder struct {
+	Exchange        string  `json:"exchange"`
+	Tradingsymbol   string  `json:"tradingsymbol"`
+	TransactionType string  `json:"transaction_type"`
+	Quantity        float64 `json:"quantity"`
+	Price           float64 `json:"price"`
+	OrderType       string  `json:"order_type"`
+	Product         string  `json:"product"`
+}
+
+// GTTStatus returns the status of a GTT trigger and the order it placed,
+// if it fired.
+func (s *Server) GTTStatus(id int) (status, orderID string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.findGTT(id)
+	if g == nil {
+		return "", "", fmt.Errorf("kitetest: unknown GTT %d", id)
+	}
+	return g.status, g.orderID, nil
+}
+
+func (s *Server) findGTT(id int) *gtt {
+	for _, g := range s.gtts {
+		if g.id == id {
+			return g
+		}
+	}
+	return nil
+}
+
+// parseGTT reads and validates a trigger from a place or modify request.
+// Callers hold s.mu.
+func (s *Server) parseGTT(r *http.Request) (*gtt, string) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err.Error()
+	}
+	g := &gtt{kind: r.PostForm.Get("type")}
+	if err := json.Unmarshal([]byte(r.PostForm.Get("condition")), &g.condition); err != nil {
+		return nil, "Invalid `condition`."
+	}
+	if err := json.Unmarshal([]byte(r.PostForm.Get("orders")), &g.orders); err != nil {
+		return nil, "Invalid `orders`."
+	}
+
+	inst, ok := s.instruments[g.condition.Exchange+":"+g.condition.Tradingsymbol]
+	if !ok {
+		return nil, "Invalid `tradingsymbol`."
+	}
+	g.inst = inst
+
+	legs := 1
+	if g.kind == "two-leg" {
+		legs = 2
+	} else if g.kind != "single" {
+		return nil, "Invalid `type`."
+	}
+	if len(g.condition.TriggerValues) != legs || len(g.orders) != legs {
+		return nil, fmt.Sprintf("A %s trigger needs %d trigger value(s) and order(s).", g.kind, legs)
+	}
+	if legs == 2 && g.condition.TriggerValues[0] >= g.condition.TriggerValues[1] {
+		return nil, "The lower trigger must be below the upper trigger."
+	}
+	for _, o := range g.orders {
+		if o.Quantity <= 0 || o.Price <= 0 {
+			return nil, "Invalid order `quantity` or `price`."
+		}
+	}
+
+	g.reference = g.condition.LastPrice
+	if g.reference <= 0 {
+		g.reference = inst.ltp
+	}
+	return g, ""
+}
+
+func (s *Server) handlePlaceGTT(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, msg := s.parseGTT(r)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, "InputException", msg)
+		return
+	}
+	g.id = s.nextGTTID
+	s.nextGTTID++
+	g.status = GTTActive
+	g.created = time.Now()
+	g.updated = g.created
+	s.gtts = append(s.gtts, g)
+	writeData(w, map[string]int{"trigger_id": g.id})
+}
+
+func (s *Server) handleModifyGTT(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := s.gttFromPath(w, r)
+	if existing == nil {
+		return
+	}
+	g, msg := s.parseGTT(r)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, "InputException", msg)
+		return
+	}
+	existing.kind, existing.inst, existing.condition, existing.orders = g.kind, g.inst, g.condition, g.orders
+	existing.reference = g.reference
+	existing.updated = time.Now()
+	writeData(w, map[string]int{"trigger_id": existing.id})
+}
+
+func (s *Server) handleDeleteGTT(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.gttFromPath(w, r)
+	if g == nil {
+		return
+	}
+	g.status = GTTDeleted
+	g.updated = time.Now()
+	writeData(w, map[string]int{"trigger_id": g.id})
+}
+
+// gttFromPath returns the active trigger named in the path, or writes an
+// error and returns nil.
+func (s *Server) gttFromPath(w http.ResponseWriter, r *http.Request) *gtt {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	g := s.findGTT(id)
+	if g == nil || g.status != GTTActive {
+		writeError(w, http.StatusBadRequest, "InputException", "Invalid trigger ID or the trigger is no longer active.")
+		return nil
+	}
+	return g
+}
+
+func (s *Server) handleGTTs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gtts := make([]map[string]interface{}, len(s.gtts))
+	for i, g := range s.gtts {
+		gtts[i] = s.gttJSON(g)
+	}
+	writeData(w, gtts)
+}
+
+func (s *Server) handleGetGTT(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	g := s.findGTT(id)
+	if g == nil {
+		writeError(w, http.StatusNotFound, "InputException", "Invalid trigger ID.")
+		return
+	}
+	writeData(w, s.gttJSON(g))
+}
+
+func (s *Server) gttJSON(g *gtt) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         g.id,
+		"user_id":    UserID,
+		"type":       g.kind,
+		"created_at": kiteTime(g.created),
+		"updated_at": kiteTime(g.updated),
+		"expires_at": kiteTime(g.created.AddDate(1, 0, 0)),
+		"status":     g.status,
+		"condition":  g.condition,
+		"orders":     g.orders,
+	}
+}
+
+// fireGTTs triggers the instrument's active GTTs the last price has
+// reached, placing each fired leg as a limit order. Callers hold s.mu.
+func (s *Server) fireGTTs(inst *instrument) {
+	ltp := inst.ltp
+	for _, g := range s.gtts {
+		if g.inst != inst || g.status != GTTActive {
+			continue
+		}
+
+		leg := -1
+		values := g.condition.TriggerValues
+		if g.kind == "two-leg" {
+			if ltp <= values[0] {
+				leg = 0
+			} else if ltp >= values[1] {
+				leg = 1
+			}
+		} else if (g.reference < values[0] && ltp >= values[0]) || (g.reference >= values[0] && ltp <= values[0]) {
+			leg = 0
+		}
+		if leg < 0 {
+			continue
+		}
+
+		g.status = GTTTriggered
+		g.updated = time.Now()
+		o := g.orders[leg]
+		placed := &order{
+			inst:      inst,
+			variety:   "regular",
+			side:      o.TransactionType,
+			orderType: "LIMIT",
+			product:   o.Product,
+			validity:  "DAY",
+			quantity:  int(o.Quantity),
+			price:     o.Price,
+		}
+		s.submit(placed)
+		g.orderID = placed.id
+	}
+}
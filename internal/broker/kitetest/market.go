@@ -0,0 +1,346 @@
+package kitetest
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+)
+
+// Instrument describes a tradable instrument to list.
+type Instrument struct {
+	Symbol   string
+	Exchange models.Exchange // defaults to NSE
+	Name     string
+	Type     string  // defaults to EQ
+	LotSize  int     // defaults to 1
+	TickSize float64 // defaults to 0.05
+	// Price is the opening last traded price, also used as the previous
+	// close.
+	Price float64
+}
+
+// instrument is a listed instrument and its market state for the session.
+type instrument struct {
+	Instrument
+	token     uint32
+	ltp       float64
+	open      float64
+	high      float64
+	low       float64
+	close     float64
+	lastQty   int
+	volume    int
+	turnover  float64
+	lastTrade time.Time
+}
+
+// Kite encodes the exchange segment in an instrument token's low byte; the
+// ticker uses it to scale prices.
+var segments = map[models.Exchange]uint32{
+	models.NSE: 1,
+	models.NFO: 2,
+	models.CDS: 3,
+	models.BSE: 4,
+	models.MCX: 7,
+}
+
+// AddInstrument lists an instrument and returns its instrument token.
+func (s *Server) AddInstrument(inst Instrument) uint32 {
+	if inst.Exchange == "" {
+		inst.Exchange = models.NSE
+	}
+	if inst.Type == "" {
+		inst.Type = "EQ"
+	}
+	if inst.LotSize == 0 {
+		inst.LotSize = 1
+	}
+	if inst.TickSize == 0 {
+		inst.TickSize = 0.05
+	}
+	if inst.Name == "" {
+		inst.Name = inst.Symbol
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := s.nextToken<<8 | segments[inst.Exchange]
+	s.nextToken++
+	i := &instrument{
+		Instrument: inst,
+		token:      token,
+		ltp:        inst.Price,
+		open:       inst.Price,
+		high:       inst.Price,
+		low:        inst.Price,
+		close:      inst.Price,
+		lastTrade:  time.Now(),
+	}
+	s.instruments[instrumentKey(inst.Exchange, inst.Symbol)] = i
+	s.tokens[token] = i
+	s.listed = append(s.listed, i)
+	return token
+}
+
+// SetCandles sets the historical candles served for symbol ("NSE:INFY" or
+// a bare NSE symbol) at a Kite interval such as "5minute" or "day".
+func (s *Server) SetCandles(symbol, interval string, candles []models.Candle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, err := s.lookup(symbol)
+	if err != nil {
+		return err
+	}
+	s.candles[candleKey(inst.token, interval)] = append([]models.Candle(nil), candles...)
+	return nil
+}
+
+// SetPrice trades symbol at price: the quote is updated, pending orders and
+// GTTs are matched against it and subscribed ticker clients get a tick.
+// volume is the quantity traded; zero means one lot.
+func (s *Server) SetPrice(symbol string, price float64, volume int) error {
+	if price <= 0 {
+		return fmt.Errorf("price must be positive: %v", price)
+	}
+
+	s.mu.Lock()
+	inst, err := s.lookup(symbol)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if volume <= 0 {
+		volume = inst.LotSize
+	}
+	inst.ltp = price
+	inst.high = math.Max(inst.high, price)
+	inst.low = math.Min(inst.low, price)
+	inst.lastQty = volume
+	inst.volume += volume
+	inst.turnover += price * float64(volume)
+	inst.lastTrade = time.Now()
+
+	s.matchOrders(inst)
+	s.fireGTTs(inst)
+	sends := s.tickSends(inst)
+	s.mu.Unlock()
+
+	for _, send := range sends {
+		send()
+	}
+	return nil
+}
+
+// Price returns symbol's last traded price.
+func (s *Server) Price(symbol string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, err := s.lookup(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return inst.ltp, nil
+}
+
+// Move is one step of a Scenario.
+type Move struct {
+	Symbol string
+	Price  float64
+	// Volume traded at Price; zero means one lot.
+	Volume int
+	// Wait is how long Play pauses before the move.
+	Wait time.Duration
+}
+
+// Scenario is a scripted sequence of price moves.
+type Scenario []Move
+
+// Path moves symbol through prices in turn.
+func Path(symbol string, prices ...float64) Scenario {
+	sc := make(Scenario, len(prices))
+	for i, p := range prices {
+		sc[i] = Move{Symbol: symbol, Price: p}
+	}
+	return sc
+}
+
+// Ramp moves symbol from one price to another in equal steps, ending at to.
+func Ramp(symbol string, from, to float64, steps int) Scenario {
+	if steps < 1 {
+		steps = 1
+	}
+	sc := make(Scenario, steps)
+	for i := range sc {
+		p := from + (to-from)*float64(i+1)/float64(steps)
+		sc[i] = Move{Symbol: symbol, Price: math.Round(p*100) / 100}
+	}
+	return sc
+}
+
+// Every returns a copy of the scenario that pauses d before each move.
+func (sc Scenario) Every(d time.Duration) Scenario {
+	out := make(Scenario, len(sc))
+	for i, m := range sc {
+		m.Wait = d
+		out[i] = m
+	}
+	return out
+}
+
+// Then returns the scenario followed by next.
+func (sc Scenario) Then(next Scenario) Scenario {
+	return append(append(Scenario(nil), sc...), next...)
+}
+
+// Play applies each move in order, honouring its Wait, until the scenario
+// ends or ctx is done.
+func (s *Server) Play(ctx context.Context, sc Scenario) error {
+	for _, m := range sc {
+		if m.Wait > 0 {
+			timer := time.NewTimer(m.Wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.SetPrice(m.Symbol, m.Price, m.Volume); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup finds an instrument by "EXCHANGE:SYMBOL" or bare NSE symbol.
+// Callers hold s.mu.
+func (s *Server) lookup(symbol string) (*instrument, error) {
+	key := symbol
+	if !strings.Contains(symbol, ":") {
+		key = instrumentKey(models.NSE, symbol)
+	}
+	inst, ok := s.instruments[key]
+	if !ok {
+		return nil, fmt.Errorf("kitetest: unknown instrument %s", symbol)
+	}
+	return inst, nil
+}
+
+func instrumentKey(exchange models.Exchange, symbol string) string {
+	return fmt.Sprintf("%s:%s", exchange, symbol)
+}
+
+func candleKey(token uint32, interval string) string {
+	return fmt.Sprintf("%d/%s", token, interval)
+}
+
+func (s *Server) handleInstruments(w http.ResponseWriter, r *http.Request) {
+	exchange := models.Exchange(r.PathValue("exchange"))
+
+	s.mu.Lock()
+	var rows [][]string
+	for _, inst := range s.listed {
+		if inst.Exchange != exchange {
+			continue
+		}
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(inst.token), 10),
+			strconv.FormatUint(uint64(inst.token>>8), 10),
+			inst.Symbol,
+			inst.Name,
+			"0",
+			"",
+			"0",
+			strconv.FormatFloat(inst.TickSize, 'f', -1, 64),
+			strconv.Itoa(inst.LotSize),
+			inst.Type,
+			string(inst.Exchange),
+			string(inst.Exchange),
+		})
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"instrument_token", "exchange_token", "tradingsymbol", "name", "last_price", "expiry",
+		"strike", "tick_size", "lot_size", "instrument_type", "segment", "exchange"})
+	cw.WriteAll(rows)
+}
+
+func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes := make(map[string]interface{})
+	for _, key := range r.URL.Query()["i"] {
+		// Kite silently leaves out instruments it does not know
+		inst, ok := s.instruments[key]
+		if !ok {
+			continue
+		}
+		avg := inst.ltp
+		if inst.volume > 0 {
+			avg = inst.turnover / float64(inst.volume)
+		}
+		quotes[key] = map[string]interface{}{
+			"instrument_token":    inst.token,
+			"timestamp":           kiteTime(time.Now()),
+			"last_price":          inst.ltp,
+			"last_quantity":       inst.lastQty,
+			"last_trade_time":     kiteTime(inst.lastTrade),
+			"average_price":       math.Round(avg*100) / 100,
+			"volume":              inst.volume,
+			"buy_quantity":        0,
+			"sell_quantity":       0,
+			"ohlc":                map[string]float64{"open": inst.open, "high": inst.high, "low": inst.low, "close": inst.close},
+			"net_change":          inst.ltp - inst.close,
+			"lower_circuit_limit": math.Round(inst.close*0.8*100) / 100,
+			"upper_circuit_limit": math.Round(inst.close*1.2*100) / 100,
+		}
+	}
+	writeData(w, quotes)
+}
+
+func (s *Server) handleHistorical(w http.ResponseWriter, r *http.Request) {
+	token, err := strconv.ParseUint(r.PathValue("token"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InputException", "Invalid instrument token")
+		return
+	}
+	q := r.URL.Query()
+	from, errFrom := time.ParseInLocation("2006-01-02 15:04:05", q.Get("from"), resilience.IndiaLocation)
+	to, errTo := time.ParseInLocation("2006-01-02 15:04:05", q.Get("to"), resilience.IndiaLocation)
+	if errFrom != nil || errTo != nil {
+		writeError(w, http.StatusBadRequest, "InputException", "Invalid `from` or `to` date")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tokens[uint32(token)]; !ok {
+		writeError(w, http.StatusBadRequest, "InputException", "Invalid instrument token")
+		return
+	}
+
+	candles := make([][]interface{}, 0)
+	for _, c := range s.candles[candleKey(uint32(token), r.PathValue("interval"))] {
+		if c.Timestamp.Before(from) || c.Timestamp.After(to) {
+			continue
+		}
+		candles = append(candles, []interface{}{
+			c.Timestamp.In(resilience.IndiaLocation).Format("2006-01-02T15:04:05-0700"),
+			c.Open, c.High, c.Low, c.Close, c.Volume,
+		})
+	}
+	writeData(w, map[string]interface{}{"candles": candles})
+}
@@ -0,0 +1,488 @@
+package kitetest
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Order statuses, as Kite reports them.
+const (
+	StatusOpen           = "OPEN"
+	StatusTriggerPending = "TRIGGER PENDING"
+	StatusComplete       = "COMPLETE"
+	StatusCancelled      = "CANCELLED"
+	StatusRejected       = "REJECTED"
+)
+
+// order is one order of the session.
+type order struct {
+	id              string
+	exchangeOrderID string
+	inst            *instrument
+	variety         string
+	side            string
+	orderType       string
+	product         string
+	validity        string
+	tag             string
+	quantity        int
+	filled          int
+	price           float64
+	triggerPrice    float64
+	averagePrice    float64
+	status          string
+	statusMessage   string
+	placed          time.Time
+	updated         time.Time
+}
+
+func (o *order) pending() bool {
+	return o.status == StatusOpen || o.status == StatusTriggerPending
+}
+
+// position is the session's net position in one instrument and product.
+type position struct {
+	inst      *instrument
+	product   string
+	quantity  int
+	average   float64
+	buyQty    int
+	buyValue  float64
+	sellQty   int
+	sellValue float64
+	realised  float64
+}
+
+// holding is a delivery holding carried into the session.
+type holding struct {
+	inst     *instrument
+	quantity int
+	average  float64
+}
+
+// AddHolding adds a delivery holding of symbol bought at averagePrice.
+func (s *Server) AddHolding(symbol string, quantity int, averagePrice float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, err := s.lookup(symbol)
+	if err != nil {
+		return err
+	}
+	s.holdings = append(s.holdings, &holding{inst: inst, quantity: quantity, average: averagePrice})
+	return nil
+}
+
+// OrderStatus returns the status of an order placed on the server.
+func (s *Server) OrderStatus(orderID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.findOrder(orderID)
+	if o == nil {
+		return "", fmt.Errorf("kitetest: unknown order %s", orderID)
+	}
+	return o.status, nil
+}
+
+func (s *Server) findOrder(id string) *order {
+	for _, o := range s.orders {
+		if o.id == id {
+			return o
+		}
+	}
+	return nil
+}
+
+func (s *Server) handlePlaceOrder(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "InputException", err.Error())
+		return
+	}
+	if variety := r.PathValue("variety"); variety != "regular" {
+		writeError(w, http.StatusBadRequest, "InputException", fmt.Sprintf("Order variety `%s` is not supported.", variety))
+		return
+	}
+	f := r.PostForm
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, ok := s.instruments[f.Get("exchange")+":"+f.Get("tradingsymbol")]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "InputException", "Invalid `tradingsymbol`.")
+		return
+	}
+	o := &order{
+		inst:      inst,
+		variety:   "regular",
+		side:      f.Get("transaction_type"),
+		orderType: f.Get("order_type"),
+		product:   f.Get("product"),
+		validity:  f.Get("validity"),
+		tag:       f.Get("tag"),
+	}
+	o.quantity, _ = strconv.Atoi(f.Get("quantity"))
+	o.price, _ = strconv.ParseFloat(f.Get("price"), 64)
+	o.triggerPrice, _ = strconv.ParseFloat(f.Get("trigger_price"), 64)
+	if o.validity == "" {
+		o.validity = "DAY"
+	}
+	if msg := validateOrder(o); msg != "" {
+		writeError(w, http.StatusBadRequest, "InputException", msg)
+		return
+	}
+
+	s.submit(o)
+	writeData(w, map[string]string{"order_id": o.id})
+}
+
+func validateOrder(o *order) string {
+	switch {
+	case o.side != "BUY" && o.side != "SELL":
+		return "Invalid `transaction_type`."
+	case o.orderType != "MARKET" && o.orderType != "LIMIT" && o.orderType != "SL" && o.orderType != "SL-M":
+		return "Invalid `order_type`."
+	case o.product != "CNC" && o.product != "MIS" && o.product != "NRML":
+		return "Invalid `product`."
+	case o.quantity <= 0:
+		return "Invalid `quantity`."
+	case o.quantity%o.inst.LotSize != 0:
+		return fmt.Sprintf("Quantity should be a multiple of lot size (%d).", o.inst.LotSize)
+	case (o.orderType == "LIMIT" || o.orderType == "SL") && o.price <= 0:
+		return "Invalid `price`."
+	case (o.orderType == "SL" || o.orderType == "SL-M") && o.triggerPrice <= 0:
+		return "Invalid `trigger_price`."
+	}
+	return ""
+}
+
+// submit books a validated order and fills it if the market allows.
+// Callers hold s.mu.
+func (s *Server) submit(o *order) {
+	o.id = strconv.Itoa(240000000 + s.nextOrderID)
+	s.nextOrderID++
+	o.placed = time.Now()
+	o.updated = o.placed
+	s.orders = append(s.orders, o)
+
+	// Buys are checked against the full order value, as for delivery
+	if o.side == "BUY" {
+		value := float64(o.quantity) * math.Max(o.price, o.inst.ltp)
+		if value > s.cash {
+			o.status = StatusRejected
+			o.statusMessage = fmt.Sprintf("Insufficient funds. Required margin is %.2f but available margin is %.2f.", value, s.cash)
+			return
+		}
+	}
+
+	o.exchangeOrderID = strconv.Itoa(1100000000 + s.nextOrderID)
+	o.status = StatusOpen
+	if o.orderType == "SL" || o.orderType == "SL-M" {
+		o.status = StatusTriggerPending
+	}
+	s.tryFill(o)
+}
+
+// matchOrders fills the instrument's pending orders the last price allows.
+// Callers hold s.mu.
+func (s *Server) matchOrders(inst *instrument) {
+	for _, o := range s.orders {
+		if o.inst == inst && o.pending() {
+			s.tryFill(o)
+		}
+	}
+}
+
+// tryFill triggers and fills o against its instrument's last price. Orders
+// fill completely at the last price.
+func (s *Server) tryFill(o *order) {
+	ltp := o.inst.ltp
+	buy := o.side == "BUY"
+
+	if o.status == StatusTriggerPending {
+		if (buy && ltp < o.triggerPrice) || (!buy && ltp > o.triggerPrice) {
+			return
+		}
+		o.status = StatusOpen
+		o.updated = time.Now()
+	}
+	if o.status != StatusOpen {
+		return
+	}
+	if o.orderType == "LIMIT" || o.orderType == "SL" {
+		if (buy && ltp > o.price) || (!buy && ltp < o.price) {
+			return
+		}
+	}
+
+	o.filled = o.quantity
+	o.averagePrice = ltp
+	o.status = StatusComplete
+	o.updated = time.Now()
+
+	qty := o.quantity
+	if buy {
+		s.cash -= float64(qty) * ltp
+	} else {
+		s.cash += float64(qty) * ltp
+		qty = -qty
+	}
+
+	key := fmt.Sprintf("%s:%s", instrumentKey(o.inst.Exchange, o.inst.Symbol), o.product)
+	p, ok := s.positions[key]
+	if !ok {
+		p = &position{inst: o.inst, product: o.product}
+		s.positions[key] = p
+	}
+	p.add(qty, ltp)
+}
+
+// add books a fill of qty (negative for sells) at price.
+func (p *position) add(qty int, price float64) {
+	if qty > 0 {
+		p.buyQty += qty
+		p.buyValue += float64(qty) * price
+	} else {
+		p.sellQty -= qty
+		p.sellValue -= float64(qty) * price
+	}
+
+	switch {
+	case p.quantity == 0 || (p.quantity > 0) == (qty > 0):
+		p.average = (p.average*math.Abs(float64(p.quantity)) + price*math.Abs(float64(qty))) /
+			math.Abs(float64(p.quantity+qty))
+	default:
+		closed := math.Min(math.Abs(float64(qty)), math.Abs(float64(p.quantity)))
+		direction := 1.0
+		if p.quantity < 0 {
+			direction = -1
+		}
+		p.realised += closed * (price - p.average) * direction
+		if math.Abs(float64(qty)) > math.Abs(float64(p.quantity)) {
+			p.average = price
+		}
+	}
+
+	p.quantity += qty
+	if p.quantity == 0 {
+		p.average = 0
+	}
+}
+
+func (s *Server) handleModifyOrder(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "InputException", err.Error())
+		return
+	}
+	f := r.PostForm
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.findOrder(r.PathValue("id"))
+	if o == nil {
+		writeError(w, http.StatusBadRequest, "InputException", "Invalid `order_id`.")
+		return
+	}
+	if !o.pending() {
+		writeError(w, http.StatusBadRequest, "InputException",
+			fmt.Sprintf("Order cannot be modified as it is %s.", strings.ToLower(o.status)))
+		return
+	}
+
+	updated := *o
+	if v := f.Get("order_type"); v != "" {
+		updated.orderType = v
+	}
+	if v, err := strconv.Atoi(f.Get("quantity")); err == nil && v > 0 {
+		updated.quantity = v
+	}
+	if v, err := strconv.ParseFloat(f.Get("price"), 64); err == nil {
+		updated.price = v
+	}
+	if v, err := strconv.ParseFloat(f.Get("trigger_price"), 64); err == nil {
+		updated.triggerPrice = v
+	}
+	if v := f.Get("validity"); v != "" {
+		updated.validity = v
+	}
+	if msg := validateOrder(&updated); msg != "" {
+		writeError(w, http.StatusBadRequest, "InputException", msg)
+		return
+	}
+
+	*o = updated
+	if o.status == StatusTriggerPending && o.orderType != "SL" && o.orderType != "SL-M" {
+		o.status = StatusOpen
+	}
+	o.updated = time.Now()
+	s.tryFill(o)
+	writeData(w, map[string]string{"order_id": o.id})
+}
+
+func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.findOrder(r.PathValue("id"))
+	if o == nil {
+		writeError(w, http.StatusBadRequest, "InputException", "Invalid `order_id`.")
+		return
+	}
+	if !o.pending() {
+		writeError(w, http.StatusBadRequest, "InputException",
+			fmt.Sprintf("Order cannot be cancelled as it is %s.", strings.ToLower(o.status)))
+		return
+	}
+	o.status = StatusCancelled
+	o.statusMessage = "Cancelled by user"
+	o.updated = time.Now()
+	writeData(w, map[string]string{"order_id": o.id})
+}
+
+func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := make([]map[string]interface{}, len(s.orders))
+	for i, o := range s.orders {
+		cancelled := 0
+		if o.status == StatusCancelled {
+			cancelled = o.quantity - o.filled
+		}
+		pending := 0
+		if o.pending() {
+			pending = o.quantity - o.filled
+		}
+		orders[i] = map[string]interface{}{
+			"placed_by":                 UserID,
+			"order_id":                  o.id,
+			"exchange_order_id":         o.exchangeOrderID,
+			"status":                    o.status,
+			"status_message":            o.statusMessage,
+			"order_timestamp":           kiteTime(o.placed),
+			"exchange_update_timestamp": kiteTime(o.updated),
+			"variety":                   o.variety,
+			"exchange":                  string(o.inst.Exchange),
+			"tradingsymbol":             o.inst.Symbol,
+			"instrument_token":          o.inst.token,
+			"order_type":                o.orderType,
+			"transaction_type":          o.side,
+			"validity":                  o.validity,
+			"product":                   o.product,
+			"quantity":                  o.quantity,
+			"price":                     o.price,
+			"trigger_price":             o.triggerPrice,
+			"average_price":             o.averagePrice,
+			"filled_quantity":           o.filled,
+			"pending_quantity":          pending,
+			"cancelled_quantity":        cancelled,
+			"tag":                       o.tag,
+		}
+	}
+	writeData(w, orders)
+}
+
+func (s *Server) handlePositions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Every position was opened today, so day and net agree
+	positions := make([]map[string]interface{}, 0, len(s.positions))
+	for _, p := range s.positions {
+		ltp := p.inst.ltp
+		unrealised := (ltp - p.average) * float64(p.quantity)
+		positions = append(positions, map[string]interface{}{
+			"tradingsymbol":      p.inst.Symbol,
+			"exchange":           string(p.inst.Exchange),
+			"instrument_token":   p.inst.token,
+			"product":            p.product,
+			"quantity":           p.quantity,
+			"overnight_quantity": 0,
+			"multiplier":         1,
+			"average_price":      p.average,
+			"close_price":        p.inst.close,
+			"last_price":         ltp,
+			"value":              p.sellValue - p.buyValue,
+			"pnl":                p.realised + unrealised,
+			"m2m":                p.realised + unrealised,
+			"unrealised":         unrealised,
+			"realised":           p.realised,
+			"buy_quantity":       p.buyQty,
+			"buy_price":          averageOf(p.buyValue, p.buyQty),
+			"buy_value":          p.buyValue,
+			"sell_quantity":      p.sellQty,
+			"sell_price":         averageOf(p.sellValue, p.sellQty),
+			"sell_value":         p.sellValue,
+			"day_buy_quantity":   p.buyQty,
+			"day_buy_price":      averageOf(p.buyValue, p.buyQty),
+			"day_buy_value":      p.buyValue,
+			"day_sell_quantity":  p.sellQty,
+			"day_sell_price":     averageOf(p.sellValue, p.sellQty),
+			"day_sell_value":     p.sellValue,
+		})
+	}
+	writeData(w, map[string]interface{}{"net": positions, "day": positions})
+}
+
+func averageOf(value float64, qty int) float64 {
+	if qty == 0 {
+		return 0
+	}
+	return value / float64(qty)
+}
+
+func (s *Server) handleHoldings(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	holdings := make([]map[string]interface{}, len(s.holdings))
+	for i, h := range s.holdings {
+		holdings[i] = map[string]interface{}{
+			"tradingsymbol":    h.inst.Symbol,
+			"exchange":         string(h.inst.Exchange),
+			"instrument_token": h.inst.token,
+			"product":          "CNC",
+			"quantity":         h.quantity,
+			"average_price":    h.average,
+			"last_price":       h.inst.ltp,
+			"close_price":      h.inst.close,
+			"pnl":              (h.inst.ltp - h.average) * float64(h.quantity),
+			"day_change":       h.inst.ltp - h.inst.close,
+		}
+	}
+	writeData(w, holdings)
+}
+
+func (s *Server) handleMargins(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Like Kite, available cash is the opening balance and what was spent
+	// today shows as debits
+	debits := math.Max(s.openingCash-s.cash, 0)
+	writeData(w, map[string]interface{}{
+		"equity": map[string]interface{}{
+			"enabled": true,
+			"net":     s.cash,
+			"available": map[string]float64{
+				"cash":            s.openingCash,
+				"opening_balance": s.openingCash,
+				"live_balance":    s.cash,
+				"collateral":      0,
+				"intraday_payin":  0,
+				"adhoc_margin":    0,
+			},
+			"utilised": map[string]float64{"debits": debits},
+		},
+		"commodity": map[string]interface{}{
+			"enabled":   false,
+			"net":       0,
+			"available": map[string]float64{"cash": 0},
+			"utilised":  map[string]float64{"debits": 0},
+		},
+	})
+}
@@ -0,0 +1,291 @@
+// Package kitetest provides an in-process stand-in for the Kite Connect REST
+// API and WebSocket ticker, so ZerodhaBroker and ZerodhaTicker can be tested
+// end to end without network access.
+//
+// A Server holds a small simulated market: instruments with a last traded
+// price, an order book of the session's orders, positions, holdings, margins
+// and GTT triggers. Prices move only when a test calls SetPrice or plays a
+// Scenario; each move matches pending orders, fires GTTs and streams ticks to
+// subscribed ticker connections.
+package kitetest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+)
+
+// Credentials the server accepts. Log in with CompleteLogin(ctx, RequestToken).
+const (
+	APIKey       = "kitetest"
+	APISecret    = "kitetest-secret"
+	UserID       = "KT0001"
+	RequestToken = "kitetest-request-token"
+)
+
+// DefaultCash is the opening equity balance of a new server.
+const DefaultCash = 1000000.0
+
+// Server is a fake Kite Connect server backed by httptest.
+type Server struct {
+	// URL is the REST API base URL, for ZerodhaConfig.BaseURL.
+	URL string
+	// TickerURL is the WebSocket ticker URL, for ZerodhaConfig.TickerURL.
+	TickerURL string
+
+	srv *httptest.Server
+
+	mu          sync.Mutex
+	accessToken string
+	sessions    int
+	instruments map[string]*instrument // by EXCHANGE:SYMBOL
+	tokens      map[uint32]*instrument
+	listed      []*instrument
+	nextToken   uint32
+	candles     map[string][]models.Candle // by token/interval
+	orders      []*order
+	nextOrderID int
+	positions   map[string]*position // by EXCHANGE:SYMBOL:PRODUCT
+	holdings    []*holding
+	gtts        []*gtt
+	nextGTTID   int
+	cash        float64
+	openingCash float64
+	failures    []failure
+	hits        map[string]int
+	clients     map[*tickerClient]bool
+}
+
+// failure is a scripted error response for the next request to a path.
+type failure struct {
+	path      string
+	status    int
+	errorType string
+	message   string
+}
+
+// NewServer starts a fake Kite server with DefaultCash and no instruments.
+// Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		instruments: make(map[string]*instrument),
+		tokens:      make(map[uint32]*instrument),
+		nextToken:   1,
+		candles:     make(map[string][]models.Candle),
+		nextOrderID: 1,
+		positions:   make(map[string]*position),
+		nextGTTID:   1,
+		cash:        DefaultCash,
+		openingCash: DefaultCash,
+		hits:        make(map[string]int),
+		clients:     make(map[*tickerClient]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /session/token", s.handleSession)
+	mux.HandleFunc("DELETE /session/token", s.auth(s.handleLogout))
+	mux.HandleFunc("POST /session/refresh_token", s.handleRefresh)
+	mux.HandleFunc("GET /user/profile", s.auth(s.handleProfile))
+	mux.HandleFunc("GET /user/margins", s.auth(s.handleMargins))
+	mux.HandleFunc("GET /quote", s.auth(s.handleQuote))
+	mux.HandleFunc("GET /instruments/{exchange}", s.handleInstruments)
+	mux.HandleFunc("GET /instruments/historical/{token}/{interval}", s.auth(s.handleHistorical))
+	mux.HandleFunc("GET /orders", s.auth(s.handleOrders))
+	mux.HandleFunc("POST /orders/{variety}", s.auth(s.handlePlaceOrder))
+	mux.HandleFunc("PUT /orders/{variety}/{id}", s.auth(s.handleModifyOrder))
+	mux.HandleFunc("DELETE /orders/{variety}/{id}", s.auth(s.handleCancelOrder))
+	mux.HandleFunc("GET /portfolio/positions", s.auth(s.handlePositions))
+	mux.HandleFunc("GET /portfolio/holdings", s.auth(s.handleHoldings))
+	mux.HandleFunc("GET /gtt/triggers", s.auth(s.handleGTTs))
+	mux.HandleFunc("POST /gtt/triggers", s.auth(s.handlePlaceGTT))
+	mux.HandleFunc("GET /gtt/triggers/{id}", s.auth(s.handleGetGTT))
+	mux.HandleFunc("PUT /gtt/triggers/{id}", s.auth(s.handleModifyGTT))
+	mux.HandleFunc("DELETE /gtt/triggers/{id}", s.auth(s.handleDeleteGTT))
+	mux.HandleFunc("GET /ws", s.handleTicker)
+
+	s.srv = httptest.NewServer(s.record(mux))
+	s.URL = s.srv.URL
+	s.TickerURL = "ws" + strings.TrimPrefix(s.srv.URL, "http") + "/ws"
+	return s
+}
+
+// Close disconnects ticker clients and shuts the server down.
+func (s *Server) Close() {
+	s.mu.Lock()
+	clients := make([]*tickerClient, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range clients {
+		c.close()
+	}
+	s.srv.Close()
+}
+
+// SetCash resets the opening equity balance.
+func (s *Server) SetCash(cash float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cash, s.openingCash = cash, cash
+}
+
+// Fail makes the next request whose path starts with path fail with the
+// given HTTP status and Kite error type, e.g. "NetworkException".
+func (s *Server) Fail(path string, status int, errorType, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{path: path, status: status, errorType: errorType, message: message})
+}
+
+// Hits returns how many requests were made to paths starting with prefix.
+func (s *Server) Hits(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for path, count := range s.hits {
+		if strings.HasPrefix(path, prefix) {
+			n += count
+		}
+	}
+	return n
+}
+
+// record counts each request and serves any scripted failure for its path.
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		var fail *failure
+		for i, f := range s.failures {
+			if strings.HasPrefix(r.URL.Path, f.path) {
+				fail = &f
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+				break
+			}
+		}
+		s.mu.Unlock()
+
+		if fail != nil {
+			writeError(w, fail.status, fail.errorType, fail.message)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// auth rejects requests without the current session's access token.
+func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		token := s.accessToken
+		s.mu.Unlock()
+
+		if token == "" || r.Header.Get("Authorization") != fmt.Sprintf("token %s:%s", APIKey, token) {
+			writeError(w, http.StatusForbidden, "TokenException", "Incorrect `api_key` or `access_token`.")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "InputException", err.Error())
+		return
+	}
+	sum := sha256.Sum256([]byte(APIKey + RequestToken + APISecret))
+	if r.PostForm.Get("api_key") != APIKey || r.PostForm.Get("request_token") != RequestToken ||
+		r.PostForm.Get("checksum") != fmt.Sprintf("%x", sum) {
+		writeError(w, http.StatusForbidden, "TokenException", "Token is invalid or has expired.")
+		return
+	}
+
+	s.mu.Lock()
+	s.sessions++
+	s.accessToken = fmt.Sprintf("kitetest-access-%d", s.sessions)
+	token := s.accessToken
+	s.mu.Unlock()
+
+	session := profile()
+	session["api_key"] = APIKey
+	session["access_token"] = token
+	session["public_token"] = "kitetest-public"
+	session["refresh_token"] = ""
+	session["login_time"] = kiteTime(time.Now())
+	writeData(w, session)
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "InputException", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken == "" || r.PostForm.Get("refresh_token") != s.accessToken {
+		writeError(w, http.StatusForbidden, "TokenException", "Token is invalid or has expired.")
+		return
+	}
+	s.sessions++
+	s.accessToken = fmt.Sprintf("kitetest-access-%d", s.sessions)
+	writeData(w, map[string]string{"user_id": UserID, "access_token": s.accessToken, "refresh_token": ""})
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.accessToken = ""
+	s.mu.Unlock()
+	writeData(w, true)
+}
+
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	writeData(w, profile())
+}
+
+func profile() map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":        UserID,
+		"user_name":      "Kite Test",
+		"user_shortname": "Kite",
+		"user_type":      "individual",
+		"email":          "kitetest@example.com",
+		"broker":         "ZERODHA",
+		"exchanges":      []string{"NSE", "BSE", "NFO"},
+		"products":       []string{"CNC", "MIS", "NRML"},
+		"order_types":    []string{"MARKET", "LIMIT", "SL", "SL-M"},
+	}
+}
+
+// writeData writes a Kite success envelope.
+func writeData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": data})
+}
+
+// writeError writes a Kite error envelope.
+func writeError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "error",
+		"error_type": errorType,
+		"message":    message,
+		"data":       nil,
+	})
+}
+
+// kiteTime formats t the way Kite does, as zoneless IST.
+func kiteTime(t time.Time) string {
+	return t.In(resilience.IndiaLocation).Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,280 @@
+package kitetest
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Packet lengths of the Kite ticker's binary modes.
+const (
+	ltpPacketLength   = 8
+	quotePacketLength = 44
+	fullPacketLength  = 184
+)
+
+// heartbeatInterval matches Kite, which sends a one-byte heartbeat every
+// second; the client reconnects after five seconds without data.
+const heartbeatInterval = time.Second
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// tickerClient is one WebSocket ticker connection and its subscriptions.
+type tickerClient struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	modes   map[uint32]string // guarded by Server.mu
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (c *tickerClient) write(messageType int, data []byte) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	c.conn.WriteMessage(messageType, data)
+}
+
+func (c *tickerClient) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.writeMu.Lock()
+		c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		c.writeMu.Unlock()
+		c.conn.Close()
+	})
+}
+
+// Subscribers returns how many ticker connections are subscribed to
+// symbol, so tests can wait for a subscription before moving prices.
+func (s *Server) Subscribers(symbol string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, err := s.lookup(symbol)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for c := range s.clients {
+		if _, ok := c.modes[inst.token]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// DropTickers closes every ticker connection, as Kite does when its
+// WebSocket servers restart.
+func (s *Server) DropTickers() {
+	s.mu.Lock()
+	clients := make([]*tickerClient, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range clients {
+		c.close()
+	}
+}
+
+func (s *Server) handleTicker(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	token := s.accessToken
+	s.mu.Unlock()
+
+	q := r.URL.Query()
+	if token == "" || q.Get("api_key") != APIKey || q.Get("access_token") != token {
+		http.Error(w, "invalid api_key or access_token", http.StatusForbidden)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &tickerClient{conn: conn, modes: make(map[uint32]string), done: make(chan struct{})}
+
+	s.mu.Lock()
+	s.clients[c] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+		c.close()
+	}()
+
+	go func() {
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-heartbeat.C:
+				c.write(websocket.BinaryMessage, []byte{0})
+			}
+		}
+	}()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType == websocket.TextMessage {
+			s.handleTickerRequest(c, data)
+		}
+	}
+}
+
+// handleTickerRequest applies a subscribe, unsubscribe or mode request and
+// sends a snapshot of newly subscribed instruments, as Kite does.
+func (s *Server) handleTickerRequest(c *tickerClient, data []byte) {
+	var req struct {
+		Action string          `json:"a"`
+		Value  json.RawMessage `json:"v"`
+	}
+	if json.Unmarshal(data, &req) != nil {
+		return
+	}
+
+	var snapshot []*instrument
+	s.mu.Lock()
+	switch req.Action {
+	case "subscribe", "unsubscribe":
+		var tokens []uint32
+		if json.Unmarshal(req.Value, &tokens) != nil {
+			break
+		}
+		for _, token := range tokens {
+			if req.Action == "unsubscribe" {
+				delete(c.modes, token)
+				continue
+			}
+			if inst, ok := s.tokens[token]; ok {
+				if _, ok := c.modes[token]; !ok {
+					c.modes[token] = "quote"
+				}
+				snapshot = append(snapshot, inst)
+			}
+		}
+	case "mode":
+		var value []json.RawMessage
+		var mode string
+		var tokens []uint32
+		if json.Unmarshal(req.Value, &value) != nil || len(value) != 2 ||
+			json.Unmarshal(value[0], &mode) != nil || json.Unmarshal(value[1], &tokens) != nil {
+			break
+		}
+		for _, token := range tokens {
+			if inst, ok := s.tokens[token]; ok {
+				c.modes[token] = mode
+				snapshot = append(snapshot, inst)
+			}
+		}
+	}
+
+	var packets [][]byte
+	for _, inst := range snapshot {
+		packets = append(packets, inst.packet(c.modes[inst.token]))
+	}
+	s.mu.Unlock()
+
+	if len(packets) > 0 {
+		c.write(websocket.BinaryMessage, frame(packets))
+	}
+}
+
+// tickSends prepares a tick of inst for each subscribed client, to be sent
+// once s.mu is released. Callers hold s.mu.
+func (s *Server) tickSends(inst *instrument) []func() {
+	var sends []func()
+	for c := range s.clients {
+		mode, ok := c.modes[inst.token]
+		if !ok {
+			continue
+		}
+		c, message := c, frame([][]byte{inst.packet(mode)})
+		sends = append(sends, func() { c.write(websocket.BinaryMessage, message) })
+	}
+	return sends
+}
+
+// frame joins packets into one ticker message: a packet count followed by
+// each packet prefixed with its length.
+func frame(packets [][]byte) []byte {
+	size := 2
+	for _, p := range packets {
+		size += 2 + len(p)
+	}
+	out := make([]byte, 2, size)
+	binary.BigEndian.PutUint16(out, uint16(len(packets)))
+	for _, p := range packets {
+		out = binary.BigEndian.AppendUint16(out, uint16(len(p)))
+		out = append(out, p...)
+	}
+	return out
+}
+
+// packet encodes the instrument's market state in a ticker mode. Prices
+// are sent in paise.
+func (i *instrument) packet(mode string) []byte {
+	paise := func(p float64) uint32 { return uint32(math.Round(p * 100)) }
+
+	length := quotePacketLength
+	switch mode {
+	case "ltp":
+		length = ltpPacketLength
+	case "full":
+		length = fullPacketLength
+	}
+	b := make([]byte, length)
+	binary.BigEndian.PutUint32(b[0:4], i.token)
+	binary.BigEndian.PutUint32(b[4:8], paise(i.ltp))
+	if length == ltpPacketLength {
+		return b
+	}
+
+	avg := i.ltp
+	if i.volume > 0 {
+		avg = i.turnover / float64(i.volume)
+	}
+	binary.BigEndian.PutUint32(b[8:12], uint32(i.lastQty))
+	binary.BigEndian.PutUint32(b[12:16], paise(avg))
+	binary.BigEndian.PutUint32(b[16:20], uint32(i.volume))
+	binary.BigEndian.PutUint32(b[20:24], uint32(i.LotSize*100))
+	binary.BigEndian.PutUint32(b[24:28], uint32(i.LotSize*100))
+	binary.BigEndian.PutUint32(b[28:32], paise(i.open))
+	binary.BigEndian.PutUint32(b[32:36], paise(i.high))
+	binary.BigEndian.PutUint32(b[36:40], paise(i.low))
+	binary.BigEndian.PutUint32(b[40:44], paise(i.close))
+	if length == quotePacketLength {
+		return b
+	}
+
+	binary.BigEndian.PutUint32(b[44:48], uint32(i.lastTrade.Unix()))
+	binary.BigEndian.PutUint32(b[60:64], uint32(time.Now().Unix()))
+
+	// Five levels of depth a tick apart on each side of the last price
+	for level := 0; level < 5; level++ {
+		offset := float64(level+1) * i.TickSize
+		bid := b[64+level*12:]
+		binary.BigEndian.PutUint32(bid[0:4], uint32(i.LotSize*10))
+		binary.BigEndian.PutUint32(bid[4:8], paise(i.ltp-offset))
+		binary.BigEndian.PutUint16(bid[8:10], 1)
+		ask := b[124+level*12:]
+		binary.BigEndian.PutUint32(ask[0:4], uint32(i.LotSize*10))
+		binary.BigEndian.PutUint32(ask[4:8], paise(i.ltp+offset))
+		binary.BigEndian.PutUint16(ask[8:10], 1)
+	}
+	return b
+}
@@ -4,10 +4,13 @@ package broker
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"zerodha-trader/internal/charges"
 	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
 )
 
 // PaperBroker implements the Broker interface for paper trading simulation.
@@ -29,6 +32,43 @@ type PaperBroker struct {
 	
 	// Price cache for simulation
 	priceCache map[string]float64
+
+	// Persists GTT state; nil keeps GTTs in memory only
+	gttStore GTTStore
+
+	// Persists the whole account; nil keeps it in memory only. unsaved holds
+	// orders changed since the last flush and ledger the unwritten entries.
+	store      PaperStore
+	dirty      bool
+	unsaved    map[string]bool
+	ledger     []models.PaperLedgerEntry
+	persistErr error
+	// version is the stored account version this state was loaded or last
+	// saved at
+	version int64
+
+	// Close of the current session, when end-of-day processing is due
+	sessionEnd time.Time
+	createdAt  time.Time
+	calendar   MarketCalendar
+
+	// Resting orders in placement order, and the last cumulative volume
+	// seen per symbol for sizing fills
+	book       []*models.Order
+	lastVolume map[string]int64
+
+	now func() time.Time
+	// at overrides the clock while ticks and end-of-day events are processed
+	at time.Time
+
+	// route places the leg orders of triggered GTTs; nil places them here
+	route func(ctx context.Context, order *models.Order) (*OrderResult, error)
+
+	// Charges applied to fills, as on a contract note
+	charges      *charges.Calculator
+	orderCharges map[string]charges.Breakdown
+	totalCharges charges.Breakdown
+	realizedPnL  float64
 	
 	mu sync.RWMutex
 }
@@ -38,6 +78,47 @@ type PaperBrokerConfig struct {
 	DataBroker     Broker
 	Ticker         Ticker
 	InitialBalance float64
+	// GTTStore persists GTTs so they survive restarts. Optional.
+	GTTStore GTTStore
+	// Charges computes brokerage and statutory charges for fills.
+	// Defaults to the standard charge schedule.
+	Charges *charges.Calculator
+	// DisableCharges turns off charges entirely.
+	DisableCharges bool
+	// Clock returns the current time for order timestamps and DAY expiry.
+	// Defaults to time.Now.
+	Clock func() time.Time
+	// Calendar reports exchange holidays, on which no session closes.
+	// Defaults to the NSE holiday list.
+	Calendar MarketCalendar
+}
+
+// MarketCalendar reports exchange holidays. resilience.MarketHoursManager
+// and trading.SessionManager implement it.
+type MarketCalendar interface {
+	IsHoliday(date time.Time) bool
+}
+
+// nseCalendar returns the NSE holidays around the year of now.
+func nseCalendar(now time.Time) MarketCalendar {
+	calendar := resilience.NewMarketHoursManager()
+	for year := now.Year() - 1; year <= now.Year()+1; year++ {
+		resilience.InitializeHolidays(calendar, year)
+	}
+	return calendar
+}
+
+// GTTStore persists paper GTTs.
+type GTTStore interface {
+	SavePaperGTT(ctx context.Context, gtt *models.GTTOrder) error
+	GetPaperGTTs(ctx context.Context) ([]models.GTTOrder, error)
+}
+
+// PaperPnL summarises realised paper trading P&L before and after charges.
+type PaperPnL struct {
+	GrossRealized float64
+	Charges       charges.Breakdown
+	NetRealized   float64
 }
 
 // NewPaperBroker creates a new paper trading broker.
@@ -46,8 +127,22 @@ func NewPaperBroker(cfg PaperBrokerConfig) *PaperBroker {
 	if initialBalance == 0 {
 		initialBalance = 1000000 // 10 lakhs default
 	}
+
+	calc := cfg.Charges
+	if calc == nil && !cfg.DisableCharges {
+		calc = charges.NewCalculator()
+	}
+
+	clock := cfg.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	calendar := cfg.Calendar
+	if calendar == nil {
+		calendar = nseCalendar(clock())
+	}
 	
-	return &PaperBroker{
+	p := &PaperBroker{
 		dataBroker: cfg.DataBroker,
 		ticker:     cfg.Ticker,
 		positions:  make(map[string]*models.Position),
@@ -58,8 +153,30 @@ func NewPaperBroker(cfg PaperBrokerConfig) *PaperBroker {
 			AvailableCash: initialBalance,
 			TotalEquity:   initialBalance,
 		},
-		priceCache: make(map[string]float64),
+		priceCache:   make(map[string]float64),
+		lastVolume:   make(map[string]int64),
+		gttStore:     cfg.GTTStore,
+		unsaved:      make(map[string]bool),
+		createdAt:    clock(),
+		now:          clock,
+		charges:      calc,
+		orderCharges: make(map[string]charges.Breakdown),
+		calendar:     calendar,
+	}
+	p.sessionEnd = p.sessionClose(clock())
+
+	// Restore GTTs from a previous session
+	if p.gttStore != nil {
+		if gtts, err := p.gttStore.GetPaperGTTs(context.Background()); err == nil {
+			for i := range gtts {
+				gtt := gtts[i]
+				p.gttOrders[gtt.ID] = &gtt
+			}
+			p.gttCounter = len(gtts)
+		}
 	}
+
+	return p
 }
 
 
@@ -83,13 +200,14 @@ func (p *PaperBroker) RefreshSession(ctx context.Context) error {
 	return nil
 }
 
-// GetQuote fetches real-time quote from the data broker.
+// GetQuote fetches real-time quote from the data broker. The price is cached
+// under the bare symbol, as ticks carry it.
 func (p *PaperBroker) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
 	if p.dataBroker != nil {
 		quote, err := p.dataBroker.GetQuote(ctx, symbol)
 		if err == nil {
 			p.mu.Lock()
-			p.priceCache[symbol] = quote.LTP
+			p.priceCache[bareSymbol(symbol)] = quote.LTP
 			p.mu.Unlock()
 		}
 		return quote, err
@@ -121,53 +239,44 @@ func (p *PaperBroker) GetInstrumentToken(ctx context.Context, symbol string, exc
 	return 0, fmt.Errorf("no data broker configured")
 }
 
-// PlaceOrder simulates order placement.
+// PlaceOrder simulates order placement. Marketable orders fill at the last
+// traded price; limit and stoploss orders rest in the order book until ticks
+// reach them. Invalid orders are recorded as REJECTED, as on Kite.
 func (p *PaperBroker) PlaceOrder(ctx context.Context, order *models.Order) (*OrderResult, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	
-	// Generate order ID
-	p.orderCounter++
-	orderID := fmt.Sprintf("PAPER_%d_%d", time.Now().Unix(), p.orderCounter)
+
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result, err := p.placeOrder(ctx, order)
+	p.flush(ctx)
+	return result, err
+}
+
+// placeOrder places an order with p.mu held.
+func (p *PaperBroker) placeOrder(ctx context.Context, order *models.Order) (*OrderResult, error) {
+	orderID := p.nextOrderID()
 	
 	// Get current price
 	price := p.getPrice(order.Symbol)
 	if price == 0 {
 		// Try to fetch from data broker
 		if p.dataBroker != nil {
-			quote, err := p.dataBroker.GetQuote(ctx, order.Symbol)
+			quote, err := p.dataBroker.GetQuote(ctx, quoteSymbol(order.Exchange, order.Symbol))
 			if err == nil {
 				price = quote.LTP
 				p.priceCache[order.Symbol] = price
 			}
 		}
 	}
-	
-	// Determine execution price
-	execPrice := price
-	if order.Type == models.OrderTypeLimit {
-		execPrice = order.Price
-	}
-	
-	// Check if order can be filled
-	canFill := true
-	if order.Type == models.OrderTypeLimit {
-		if order.Side == models.OrderSideBuy && price > order.Price {
-			canFill = false
-		}
-		if order.Side == models.OrderSideSell && price < order.Price {
-			canFill = false
-		}
-	}
-	
-	// Calculate order value
-	orderValue := execPrice * float64(order.Quantity)
-	
-	// Check balance for buy orders
-	if order.Side == models.OrderSideBuy && canFill {
-		if p.balance.AvailableCash < orderValue {
-			return nil, fmt.Errorf("insufficient funds: need %.2f, have %.2f", orderValue, p.balance.AvailableCash)
-		}
+
+	validity := order.Validity
+	if validity == "" {
+		validity = models.ValidityDay
 	}
 	
 	// Create order record
@@ -181,30 +290,60 @@ func (p *PaperBroker) PlaceOrder(ctx context.Context, order *models.Order) (*Ord
 		Quantity:     order.Quantity,
 		Price:        order.Price,
 		TriggerPrice: order.TriggerPrice,
-		Validity:     order.Validity,
+		Validity:     validity,
 		Tag:          order.Tag,
-		PlacedAt:     time.Now(),
-	}
-	
-	if canFill {
-		newOrder.Status = "COMPLETE"
-		newOrder.FilledQty = order.Quantity
-		newOrder.AveragePrice = execPrice
-		
-		// Update position
-		p.updatePosition(order.Symbol, order.Exchange, order.Product, order.Side, order.Quantity, execPrice)
-		
-		// Update balance
-		if order.Side == models.OrderSideBuy {
-			p.balance.AvailableCash -= orderValue
-		} else {
-			p.balance.AvailableCash += orderValue
-		}
-	} else {
-		newOrder.Status = "OPEN"
+		PlacedAt:     p.eventTime(),
 	}
-	
 	p.orders[orderID] = newOrder
+	p.touch(newOrder)
+
+	if reason := validatePaperOrder(newOrder, price); reason != "" {
+		p.closeOrder(newOrder, models.OrderStatusRejected, reason)
+		return &OrderResult{OrderID: orderID, Status: newOrder.Status, Message: reason}, nil
+	}
+
+	// Check funds for buy orders at the price they are expected to fill
+	if order.Side == models.OrderSideBuy {
+		estPrice := price
+		switch order.Type {
+		case models.OrderTypeLimit, models.OrderTypeStopLoss:
+			estPrice = order.Price
+		case models.OrderTypeStopLossM:
+			estPrice = order.TriggerPrice
+		}
+		need := estPrice*float64(order.Quantity) + p.calculateCharges(newOrder, order.Quantity, estPrice).Total
+		if p.balance.AvailableCash < need {
+			reason := fmt.Sprintf("Insufficient funds: need %.2f, have %.2f", need, p.balance.AvailableCash)
+			p.closeOrder(newOrder, models.OrderStatusRejected, reason)
+			return &OrderResult{OrderID: orderID, Status: newOrder.Status, Message: reason}, nil
+		}
+	}
+
+	// Delivery sells need shares in holdings or bought today
+	if order.Side == models.OrderSideSell && order.Product == models.ProductCNC {
+		if have := p.deliverableQty(order.Symbol, order.Exchange); order.Quantity > have {
+			reason := fmt.Sprintf("Insufficient holdings: need %d, have %d", order.Quantity, have)
+			p.closeOrder(newOrder, models.OrderStatusRejected, reason)
+			return &OrderResult{OrderID: orderID, Status: newOrder.Status, Message: reason}, nil
+		}
+	}
+
+	switch order.Type {
+	case models.OrderTypeStopLoss, models.OrderTypeStopLossM:
+		newOrder.Status = models.OrderStatusTriggerPending
+	default:
+		newOrder.Status = models.OrderStatusOpen
+	}
+	p.book = append(p.book, newOrder)
+
+	// Depth is unknown at placement, so marketable orders fill in full
+	p.matchOrder(newOrder, price, -1)
+
+	// IOC orders cancel whatever did not fill immediately
+	if validity == models.ValidityIOC && isActiveOrder(newOrder) {
+		p.closeOrder(newOrder, models.OrderStatusCancelled, "IOC order cancelled: not filled immediately")
+	}
+	p.pruneBook()
 	
 	return &OrderResult{
 		OrderID: orderID,
@@ -213,43 +352,97 @@ func (p *PaperBroker) PlaceOrder(ctx context.Context, order *models.Order) (*Ord
 	}, nil
 }
 
+// nextOrderID generates a paper order ID.
+func (p *PaperBroker) nextOrderID() string {
+	p.orderCounter++
+	return fmt.Sprintf("PAPER_%d_%d", p.eventTime().Unix(), p.orderCounter)
+}
+
 
-// ModifyOrder simulates order modification.
+// ModifyOrder simulates order modification. Open and trigger pending orders can
+// change price, trigger price, quantity and type; the order is re-matched at
+// the current price afterwards.
 func (p *PaperBroker) ModifyOrder(ctx context.Context, orderID string, order *models.Order) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	
 	existing, ok := p.orders[orderID]
 	if !ok {
 		return fmt.Errorf("order not found: %s", orderID)
 	}
 	
-	if existing.Status != "OPEN" {
+	if !isActiveOrder(existing) {
 		return fmt.Errorf("cannot modify order with status: %s", existing.Status)
 	}
-	
-	existing.Price = order.Price
-	existing.TriggerPrice = order.TriggerPrice
-	existing.Quantity = order.Quantity
+
+	modified := *existing
+	modified.Price = order.Price
+	modified.TriggerPrice = order.TriggerPrice
+	modified.Quantity = order.Quantity
+	if order.Type != "" {
+		modified.Type = order.Type
+	}
+	if modified.Quantity <= existing.FilledQty {
+		return fmt.Errorf("quantity must exceed filled quantity %d", existing.FilledQty)
+	}
+
+	price := p.getPrice(existing.Symbol)
+	if modified.Type != existing.Type {
+		switch modified.Type {
+		case models.OrderTypeStopLoss, models.OrderTypeStopLossM:
+			modified.Status = models.OrderStatusTriggerPending
+		default:
+			modified.Status = models.OrderStatusOpen
+		}
+	}
+	checkPrice := price
+	if modified.Status == models.OrderStatusOpen {
+		// Trigger has already been hit; only the limit price matters now
+		checkPrice = 0
+	}
+	if reason := validatePaperOrder(&modified, checkPrice); reason != "" {
+		return fmt.Errorf("invalid modification: %s", reason)
+	}
+
+	*existing = modified
+	p.touch(existing)
+	p.matchOrder(existing, price, -1)
+	p.pruneBook()
+	p.flush(ctx)
 	
 	return nil
 }
 
-// CancelOrder simulates order cancellation.
+// CancelOrder simulates order cancellation. Partially filled orders keep their
+// filled quantity and cancel the remainder.
 func (p *PaperBroker) CancelOrder(ctx context.Context, orderID string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	
 	order, ok := p.orders[orderID]
 	if !ok {
 		return fmt.Errorf("order not found: %s", orderID)
 	}
 	
-	if order.Status != "OPEN" {
+	if !isActiveOrder(order) {
 		return fmt.Errorf("cannot cancel order with status: %s", order.Status)
 	}
 	
-	order.Status = "CANCELLED"
+	p.closeOrder(order, models.OrderStatusCancelled, "Cancelled by user")
+	p.pruneBook()
+	p.flush(ctx)
 	return nil
 }
 
@@ -279,13 +472,29 @@ func (p *PaperBroker) GetOrderHistory(ctx context.Context, from, to time.Time) (
 	return orders, nil
 }
 
-// PlaceGTT simulates GTT order placement.
+// PlaceGTT simulates GTT order placement. Single GTTs fire when the price
+// crosses TriggerPrice from LastPrice; two-leg GTTs carry a trigger on each leg
+// and fire the stop-loss or target leg, cancelling the other.
 func (p *PaperBroker) PlaceGTT(ctx context.Context, gtt *models.GTTOrder) (*GTTResult, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	lastPrice := gtt.LastPrice
+	if lastPrice == 0 {
+		lastPrice = p.getPrice(gtt.Symbol)
+	}
+	if err := validatePaperGTT(gtt, lastPrice); err != nil {
+		return nil, err
+	}
 	
 	p.gttCounter++
-	gttID := fmt.Sprintf("PAPER_GTT_%d_%d", time.Now().Unix(), p.gttCounter)
+	gttID := fmt.Sprintf("PAPER_GTT_%d_%d", p.eventTime().Unix(), p.gttCounter)
 	
 	newGTT := &models.GTTOrder{
 		ID:           gttID,
@@ -293,14 +502,19 @@ func (p *PaperBroker) PlaceGTT(ctx context.Context, gtt *models.GTTOrder) (*GTTR
 		Exchange:     gtt.Exchange,
 		TriggerType:  gtt.TriggerType,
 		TriggerPrice: gtt.TriggerPrice,
-		LastPrice:    gtt.LastPrice,
-		Orders:       gtt.Orders,
+		LastPrice:    lastPrice,
+		Orders:       append([]models.GTTOrderLeg(nil), gtt.Orders...),
 		Status:       "ACTIVE",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		CreatedAt:    p.now(),
+		UpdatedAt:    p.now(),
+	}
+
+	if err := p.saveGTT(ctx, newGTT); err != nil {
+		return nil, err
 	}
-	
 	p.gttOrders[gttID] = newGTT
+	p.dirty = true
+	p.flush(ctx)
 	
 	return &GTTResult{
 		TriggerID: gttID,
@@ -313,15 +527,38 @@ func (p *PaperBroker) PlaceGTT(ctx context.Context, gtt *models.GTTOrder) (*GTTR
 func (p *PaperBroker) ModifyGTT(ctx context.Context, gttID string, gtt *models.GTTOrder) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	
 	existing, ok := p.gttOrders[gttID]
 	if !ok {
 		return fmt.Errorf("GTT not found: %s", gttID)
 	}
-	
-	existing.TriggerPrice = gtt.TriggerPrice
-	existing.Orders = gtt.Orders
-	existing.UpdatedAt = time.Now()
+	if existing.Status != "ACTIVE" {
+		return fmt.Errorf("cannot modify GTT with status: %s", existing.Status)
+	}
+
+	modified := *existing
+	modified.TriggerPrice = gtt.TriggerPrice
+	modified.Orders = append([]models.GTTOrderLeg(nil), gtt.Orders...)
+	if gtt.LastPrice > 0 {
+		modified.LastPrice = gtt.LastPrice
+	}
+	if err := validatePaperGTT(&modified, modified.LastPrice); err != nil {
+		return err
+	}
+	modified.UpdatedAt = p.now()
+
+	if err := p.saveGTT(ctx, &modified); err != nil {
+		return err
+	}
+	*existing = modified
+	p.dirty = true
+	p.flush(ctx)
 	
 	return nil
 }
@@ -330,14 +567,28 @@ func (p *PaperBroker) ModifyGTT(ctx context.Context, gttID string, gtt *models.G
 func (p *PaperBroker) CancelGTT(ctx context.Context, gttID string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	
 	gtt, ok := p.gttOrders[gttID]
 	if !ok {
 		return fmt.Errorf("GTT not found: %s", gttID)
 	}
+	if gtt.Status != "ACTIVE" {
+		return fmt.Errorf("cannot cancel GTT with status: %s", gtt.Status)
+	}
 	
 	gtt.Status = "CANCELLED"
-	gtt.UpdatedAt = time.Now()
+	gtt.UpdatedAt = p.now()
+	if err := p.saveGTT(ctx, gtt); err != nil {
+		return err
+	}
+	p.dirty = true
+	p.flush(ctx)
 	return nil
 }
 
@@ -403,13 +654,13 @@ func (p *PaperBroker) GetBalance(ctx context.Context) (*models.Balance, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 	
-	// Calculate total equity including positions
+	// Calculate total equity including positions and holdings
 	totalEquity := p.balance.AvailableCash
 	for _, pos := range p.positions {
-		price := p.priceCache[pos.Symbol]
-		if price > 0 {
-			totalEquity += price * float64(pos.Quantity)
-		}
+		totalEquity += p.markPrice(pos.Symbol, pos.LTP, pos.AveragePrice) * float64(pos.Quantity)
+	}
+	for _, h := range p.holdings {
+		totalEquity += p.markPrice(h.Symbol, h.LTP, h.AveragePrice) * float64(h.Quantity)
 	}
 	
 	return &models.Balance{
@@ -453,7 +704,7 @@ func (p *PaperBroker) GetFuturesChain(ctx context.Context, symbol string) (*mode
 
 // updatePosition updates or creates a position based on trade.
 func (p *PaperBroker) updatePosition(symbol string, exchange models.Exchange, product models.ProductType, side models.OrderSide, qty int, price float64) {
-	key := fmt.Sprintf("%s:%s:%s", exchange, symbol, product)
+	key := positionKey(exchange, symbol, product)
 	
 	pos, exists := p.positions[key]
 	if !exists {
@@ -465,26 +716,36 @@ func (p *PaperBroker) updatePosition(symbol string, exchange models.Exchange, pr
 		p.positions[key] = pos
 	}
 	
-	if side == models.OrderSideBuy {
-		// Calculate new average price
-		totalValue := pos.AveragePrice*float64(pos.Quantity) + price*float64(qty)
-		pos.Quantity += qty
+	delta := qty
+	if side == models.OrderSideSell {
+		delta = -qty
+	}
+
+	switch {
+	case pos.Quantity == 0 || (pos.Quantity > 0) == (delta > 0):
+		// Opening or adding: quantity-weighted average on either side
+		held := abs(pos.Quantity)
+		pos.AveragePrice = (pos.AveragePrice*float64(held) + price*float64(qty)) / float64(held+qty)
+		pos.Quantity += delta
+	default:
+		// Reducing: book realised P&L on the closed quantity, keep the average
+		closed := min(qty, abs(pos.Quantity))
 		if pos.Quantity > 0 {
-			pos.AveragePrice = totalValue / float64(pos.Quantity)
+			p.realizedPnL += (price - pos.AveragePrice) * float64(closed)
+		} else {
+			p.realizedPnL += (pos.AveragePrice - price) * float64(closed)
 		}
-	} else {
-		pos.Quantity -= qty
-		// If position is closed, remove it
+		pos.Quantity += delta
 		if pos.Quantity == 0 {
 			delete(p.positions, key)
 			return
 		}
-		// If position flipped to short
-		if pos.Quantity < 0 {
+		// Crossing zero opens the remainder at the fill price
+		if qty > closed {
 			pos.AveragePrice = price
 		}
 	}
-	
+
 	pos.LTP = price
 	pos.Value = price * float64(pos.Quantity)
 	pos.PnL = (price - pos.AveragePrice) * float64(pos.Quantity)
@@ -493,11 +754,75 @@ func (p *PaperBroker) updatePosition(symbol string, exchange models.Exchange, pr
 	}
 }
 
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// calculateCharges returns the charges for filling qty of order at price.
+func (p *PaperBroker) calculateCharges(order *models.Order, qty int, price float64) charges.Breakdown {
+	if p.charges == nil {
+		return charges.Breakdown{}
+	}
+
+	b, err := p.charges.Calculate(charges.Trade{
+		Symbol:   order.Symbol,
+		Exchange: order.Exchange,
+		Product:  order.Product,
+		Side:     order.Side,
+		Quantity: qty,
+		Price:    price,
+	})
+	if err != nil {
+		return charges.Breakdown{}
+	}
+	return b
+}
+
+// GetOrderCharges returns the charges applied to a filled paper order.
+func (p *PaperBroker) GetOrderCharges(orderID string) (charges.Breakdown, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	b, ok := p.orderCharges[orderID]
+	return b, ok
+}
+
+// GetPnLSummary returns realised P&L before and after charges.
+func (p *PaperBroker) GetPnLSummary() PaperPnL {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return PaperPnL{
+		GrossRealized: p.realizedPnL,
+		Charges:       p.totalCharges,
+		NetRealized:   p.realizedPnL - p.totalCharges.Total,
+	}
+}
+
 // getPrice returns cached price for a symbol.
 func (p *PaperBroker) getPrice(symbol string) float64 {
 	return p.priceCache[symbol]
 }
 
+// quoteSymbol returns the EXCHANGE:SYMBOL form the data broker quotes.
+func quoteSymbol(exchange models.Exchange, symbol string) string {
+	if exchange == "" {
+		return symbol
+	}
+	return fmt.Sprintf("%s:%s", exchange, symbol)
+}
+
+// bareSymbol strips the exchange from a quote symbol such as "NSE:INFY".
+func bareSymbol(symbol string) string {
+	if _, s, ok := strings.Cut(symbol, ":"); ok {
+		return s
+	}
+	return symbol
+}
+
 // UpdatePrice updates the cached price for a symbol.
 func (p *PaperBroker) UpdatePrice(symbol string, price float64) {
 	p.mu.Lock()
@@ -505,51 +830,233 @@ func (p *PaperBroker) UpdatePrice(symbol string, price float64) {
 	p.priceCache[symbol] = price
 }
 
-// ProcessTick processes a tick and updates prices.
-func (p *PaperBroker) ProcessTick(tick models.Tick) {
+// SetOrderRouter makes triggered GTTs place their leg orders through route,
+// normally the risk gate in front of this broker, so that they pass the same
+// checks and audit as every other order. By default they are placed here
+// directly.
+func (p *PaperBroker) SetOrderRouter(route func(ctx context.Context, order *models.Order) (*OrderResult, error)) {
 	p.mu.Lock()
-	p.priceCache[tick.Symbol] = tick.LTP
-	p.mu.Unlock()
-	
-	// Check GTT triggers
-	p.checkGTTTriggers(tick)
+	defer p.mu.Unlock()
+	p.route = route
+}
+
+// ProcessTick processes a tick: it updates prices, runs end-of-day processing
+// once the session closes, fills resting orders the tick reaches and fires GTTs.
+func (p *PaperBroker) ProcessTick(tick models.Tick) {
+	at := tick.Timestamp
+	if at.IsZero() {
+		at = p.now()
+	}
+
+	// Triggered legs are placed once the account is unlocked: the router
+	// reads positions and places the order back through this broker
+	for _, t := range p.processTick(tick, at) {
+		p.placeTriggered(t)
+	}
 }
 
-// checkGTTTriggers checks if any GTT orders should be triggered.
-func (p *PaperBroker) checkGTTTriggers(tick models.Tick) {
+func (p *PaperBroker) processTick(tick models.Tick, at time.Time) []triggeredGTT {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	
+
+	p.priceCache[tick.Symbol] = tick.LTP
+
+	ctx := context.Background()
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		p.persistErr = err
+		return nil
+	}
+	defer unlock()
+
+	p.settle(at)
+	p.expireOrders(at)
+
+	p.at = at
+	p.matchTick(tick)
+	triggered := p.checkGTTTriggers(tick)
+	p.at = time.Time{}
+
+	p.flush(ctx)
+	return triggered
+}
+
+// triggeredGTT is a GTT that has fired and the leg order it places.
+type triggeredGTT struct {
+	id    string
+	order *models.Order
+}
+
+// checkGTTTriggers marks every GTT the tick triggers and returns the leg
+// orders to place. Must be called with p.mu held.
+func (p *PaperBroker) checkGTTTriggers(tick models.Tick) []triggeredGTT {
+	ctx := context.Background()
+	var triggered []triggeredGTT
 	for _, gtt := range p.gttOrders {
 		if gtt.Status != "ACTIVE" || gtt.Symbol != tick.Symbol {
 			continue
 		}
-		
-		triggered := false
-		if gtt.TriggerType == "single" {
-			// Single trigger - check if price crossed trigger
-			if len(gtt.Orders) > 0 {
-				if gtt.Orders[0].Side == models.OrderSideBuy && tick.LTP >= gtt.TriggerPrice {
-					triggered = true
-				}
-				if gtt.Orders[0].Side == models.OrderSideSell && tick.LTP <= gtt.TriggerPrice {
-					triggered = true
-				}
-			}
+
+		leg, ok := gttTriggeredLeg(gtt, tick.LTP)
+		if !ok {
+			continue
+		}
+
+		orderType := leg.Type
+		if orderType == "" {
+			orderType = models.OrderTypeLimit
+		}
+		triggered = append(triggered, triggeredGTT{id: gtt.ID, order: &models.Order{
+			Symbol:   gtt.Symbol,
+			Exchange: gtt.Exchange,
+			Side:     leg.Side,
+			Type:     orderType,
+			Product:  leg.Product,
+			Quantity: leg.Quantity,
+			Price:    leg.Price,
+			Tag:      "gtt",
+		}})
+
+		gtt.Status = "TRIGGERED"
+		gtt.UpdatedAt = p.now()
+		// Best effort: the in-memory state stays authoritative for this session
+		_ = p.saveGTT(ctx, gtt)
+	}
+	return triggered
+}
+
+// placeTriggered places a triggered GTT's leg order and records it on the
+// GTT. An order the router refuses leaves the GTT triggered with no order,
+// as on Kite.
+func (p *PaperBroker) placeTriggered(t triggeredGTT) {
+	ctx := context.Background()
+
+	p.mu.RLock()
+	route := p.route
+	p.mu.RUnlock()
+	if route == nil {
+		route = p.PlaceOrder
+	}
+
+	result, err := route(ctx, t.order)
+	if err != nil || result == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		p.persistErr = err
+		return
+	}
+	defer unlock()
+	if gtt, ok := p.gttOrders[t.id]; ok {
+		gtt.OrderID = result.OrderID
+		gtt.UpdatedAt = p.now()
+		_ = p.saveGTT(ctx, gtt)
+	}
+}
+
+// gttTriggeredLeg returns the leg to place if ltp triggers the GTT. For two-leg
+// GTTs the lower trigger is the stop-loss and the upper trigger is the target.
+func gttTriggeredLeg(gtt *models.GTTOrder, ltp float64) (models.GTTOrderLeg, bool) {
+	if len(gtt.Orders) == 0 || ltp <= 0 {
+		return models.GTTOrderLeg{}, false
+	}
+
+	if gtt.TriggerType == "two-leg" && len(gtt.Orders) >= 2 {
+		lower, upper := gtt.Orders[0], gtt.Orders[1]
+		if lower.TriggerPrice > upper.TriggerPrice {
+			lower, upper = upper, lower
 		}
-		
-		if triggered {
-			gtt.Status = "TRIGGERED"
-			gtt.UpdatedAt = time.Now()
-			// In a real implementation, we would place the order here
+		switch {
+		case ltp <= lower.TriggerPrice:
+			return lower, true
+		case ltp >= upper.TriggerPrice:
+			return upper, true
 		}
+		return models.GTTOrderLeg{}, false
+	}
+
+	// Single trigger fires when the price crosses it from where it was placed
+	leg := gtt.Orders[0]
+	rising := leg.Side == models.OrderSideBuy
+	if gtt.LastPrice > 0 {
+		rising = gtt.TriggerPrice > gtt.LastPrice
+	}
+	if rising {
+		return leg, ltp >= gtt.TriggerPrice
 	}
+	return leg, ltp <= gtt.TriggerPrice
 }
 
-// Reset resets the paper broker to initial state.
+// validatePaperGTT checks a GTT the way Kite does before accepting it.
+func validatePaperGTT(gtt *models.GTTOrder, lastPrice float64) error {
+	switch gtt.TriggerType {
+	case "single":
+		if len(gtt.Orders) != 1 {
+			return fmt.Errorf("single GTT must have exactly one leg")
+		}
+		if gtt.TriggerPrice <= 0 {
+			return fmt.Errorf("trigger price must be positive")
+		}
+		if lastPrice > 0 && gtt.TriggerPrice == lastPrice {
+			return fmt.Errorf("trigger price must differ from the last price")
+		}
+	case "two-leg":
+		if len(gtt.Orders) != 2 {
+			return fmt.Errorf("two-leg GTT must have exactly two legs")
+		}
+		lower, upper := gtt.Orders[0].TriggerPrice, gtt.Orders[1].TriggerPrice
+		if lower > upper {
+			lower, upper = upper, lower
+		}
+		if lower <= 0 || lower == upper {
+			return fmt.Errorf("two-leg GTT needs distinct positive trigger prices on both legs")
+		}
+		if lastPrice > 0 && (lastPrice <= lower || lastPrice >= upper) {
+			return fmt.Errorf("last price %.2f must lie between the triggers %.2f and %.2f", lastPrice, lower, upper)
+		}
+	default:
+		return fmt.Errorf("unsupported GTT trigger type %q", gtt.TriggerType)
+	}
+
+	for _, leg := range gtt.Orders {
+		if leg.Quantity <= 0 {
+			return fmt.Errorf("GTT leg quantity must be positive")
+		}
+		if leg.Price <= 0 && leg.Type != models.OrderTypeMarket {
+			return fmt.Errorf("GTT leg limit price must be positive")
+		}
+	}
+	return nil
+}
+
+// saveGTT persists a GTT when a store is configured.
+func (p *PaperBroker) saveGTT(ctx context.Context, gtt *models.GTTOrder) error {
+	if p.gttStore == nil {
+		return nil
+	}
+	if err := p.gttStore.SavePaperGTT(ctx, gtt); err != nil {
+		return fmt.Errorf("saving GTT: %w", err)
+	}
+	return nil
+}
+
+// Reset resets the paper broker to initial state. A persistent account keeps
+// its ledger and snapshots; everything else is cleared.
 func (p *PaperBroker) Reset(initialBalance float64) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+
+	ctx := context.Background()
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		p.persistErr = err
+		return
+	}
+	defer unlock()
 	
 	p.positions = make(map[string]*models.Position)
 	p.holdings = make(map[string]*models.Holding)
@@ -559,8 +1066,31 @@ func (p *PaperBroker) Reset(initialBalance float64) {
 		AvailableCash: initialBalance,
 		TotalEquity:   initialBalance,
 	}
+	p.book = nil
+	p.lastVolume = make(map[string]int64)
 	p.orderCounter = 0
 	p.gttCounter = 0
+	p.orderCharges = make(map[string]charges.Breakdown)
+	p.totalCharges = charges.Breakdown{}
+	p.realizedPnL = 0
+	p.unsaved = make(map[string]bool)
+	p.sessionEnd = p.sessionClose(p.now())
+
+	if p.store == nil {
+		return
+	}
+	if err := p.store.ClearPaperAccount(ctx); err != nil {
+		p.persistErr = fmt.Errorf("resetting paper account: %w", err)
+		p.dirty = true
+		return
+	}
+	p.record(models.PaperLedgerEntry{
+		Type:    models.PaperLedgerReset,
+		Amount:  initialBalance,
+		Balance: initialBalance,
+		Note:    "Account reset",
+	})
+	p.flush(ctx)
 }
 
 // GetTrades returns all completed trades.
@@ -570,7 +1100,7 @@ func (p *PaperBroker) GetTrades() []models.Order {
 	
 	trades := make([]models.Order, 0)
 	for _, o := range p.orders {
-		if o.Status == "COMPLETE" {
+		if o.FilledQty > 0 {
 			trades = append(trades, *o)
 		}
 	}
@@ -0,0 +1,521 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"zerodha-trader/internal/charges"
+	"zerodha-trader/internal/models"
+)
+
+// A paper account persists through a PaperStore. State is written through
+// after every change: the account row, positions and holdings are replaced,
+// changed orders are upserted and new ledger entries appended. A failed write
+// is kept for PersistError and retried with the next change.
+//
+// Several processes may trade one account, such as the daemon and a manual
+// order. Every change holds the store's account lock, and first reloads the
+// account if its version shows another process saved it since.
+//
+// End of day follows the exchange calendar used for DAY orders. At 15:15 IST
+// open MIS orders are cancelled and MIS positions squared off at the last
+// price. At the 15:30 close DAY orders expire and CNC positions move to
+// holdings as T1 quantity, which settles at the next session's close (T+1).
+
+// squareOffTag marks orders placed by the intraday square-off.
+const squareOffTag = "squareoff"
+
+// misSquareOffLead is how long before the close MIS positions are squared off.
+const misSquareOffLead = 15 * time.Minute
+
+// PaperStore persists a paper trading account.
+type PaperStore interface {
+	GTTStore
+	// LoadPaperAccount returns nil if the account does not exist yet.
+	LoadPaperAccount(ctx context.Context) (*models.PaperAccountState, error)
+	// SavePaperAccount replaces positions and holdings and upserts orders.
+	// It fails without writing if the stored version is no longer
+	// state.Account.Version, and otherwise increments it.
+	SavePaperAccount(ctx context.Context, state *models.PaperAccountState) error
+	// LockPaperAccount blocks until no other process is changing the
+	// account and returns the function that releases the lock.
+	LockPaperAccount(ctx context.Context) (func(), error)
+	// PaperAccountVersion returns the stored version, 0 for a new account.
+	PaperAccountVersion(ctx context.Context) (int64, error)
+	// ClearPaperAccount deletes orders, positions, holdings and GTTs.
+	ClearPaperAccount(ctx context.Context) error
+	AppendPaperLedger(ctx context.Context, entries []models.PaperLedgerEntry) error
+}
+
+// OpenPaperBroker creates a paper broker backed by a persistent account. A new
+// account starts with cfg.InitialBalance; an existing one is restored and any
+// sessions that closed since it was last used are settled.
+func OpenPaperBroker(ctx context.Context, cfg PaperBrokerConfig, store PaperStore) (*PaperBroker, error) {
+	cfg.GTTStore = nil
+	p := NewPaperBroker(cfg)
+	p.store = store
+	p.gttStore = store
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	unlock, err := store.LockPaperAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	exists, err := p.reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		p.record(models.PaperLedgerEntry{
+			Type:    models.PaperLedgerDeposit,
+			Amount:  p.balance.AvailableCash,
+			Balance: p.balance.AvailableCash,
+			Note:    "Account opened",
+		})
+	}
+
+	p.settle(p.now())
+	p.flush(ctx)
+	if p.persistErr != nil {
+		return nil, p.persistErr
+	}
+	return p, nil
+}
+
+// restore loads persisted state. Charges per order are recomputed from the
+// fills so partial fills keep applying per-order brokerage caps.
+func (p *PaperBroker) restore(state *models.PaperAccountState) {
+	a := state.Account
+	p.balance = &models.Balance{
+		AvailableCash: a.Cash,
+		TotalEquity:   a.InitialBalance,
+	}
+	p.realizedPnL = a.RealizedPnL
+	p.version = a.Version
+	p.orderCounter = a.OrderCounter
+	p.gttCounter = a.GTTCounter
+	p.createdAt = a.CreatedAt
+	if !a.SessionClose.IsZero() {
+		p.sessionEnd = a.SessionClose
+	}
+
+	for i := range state.Orders {
+		o := state.Orders[i]
+		p.orders[o.ID] = &o
+		if o.FilledQty > 0 {
+			b := p.calculateCharges(&o, o.FilledQty, o.AveragePrice)
+			p.orderCharges[o.ID] = b
+			p.totalCharges = p.totalCharges.Add(b)
+		}
+		if isActiveOrder(&o) {
+			p.book = append(p.book, &o)
+		}
+	}
+
+	for i := range state.Positions {
+		pos := state.Positions[i]
+		p.positions[positionKey(pos.Exchange, pos.Symbol, pos.Product)] = &pos
+	}
+	for i := range state.Holdings {
+		h := state.Holdings[i]
+		p.holdings[h.Symbol] = &h
+	}
+}
+
+// reload replaces the in-memory account with the stored one and reports
+// whether it exists. Changes that failed to save are dropped. Must be called
+// with p.mu held.
+func (p *PaperBroker) reload(ctx context.Context) (bool, error) {
+	state, err := p.store.LoadPaperAccount(ctx)
+	if err != nil {
+		return false, err
+	}
+	gtts, err := p.store.GetPaperGTTs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	p.positions = make(map[string]*models.Position)
+	p.holdings = make(map[string]*models.Holding)
+	p.orders = make(map[string]*models.Order)
+	p.gttOrders = make(map[string]*models.GTTOrder)
+	p.book = nil
+	p.orderCharges = make(map[string]charges.Breakdown)
+	p.totalCharges = charges.Breakdown{}
+	p.unsaved = make(map[string]bool)
+	p.ledger = nil
+	p.dirty = false
+
+	if state != nil {
+		p.restore(state)
+	}
+	for i := range gtts {
+		gtt := gtts[i]
+		p.gttOrders[gtt.ID] = &gtt
+	}
+	return state != nil, nil
+}
+
+// lockAccount takes the account lock for a change and reloads the account
+// if another process saved it since. Must be called with p.mu held; the
+// returned function releases the lock.
+func (p *PaperBroker) lockAccount(ctx context.Context) (func(), error) {
+	if p.store == nil {
+		return func() {}, nil
+	}
+
+	unlock, err := p.store.LockPaperAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("locking paper account: %w", err)
+	}
+	version, err := p.store.PaperAccountVersion(ctx)
+	if err == nil && version != p.version {
+		_, err = p.reload(ctx)
+	}
+	if err != nil {
+		unlock()
+		return nil, fmt.Errorf("reloading paper account: %w", err)
+	}
+	return unlock, nil
+}
+
+// state returns the account state to persist, with the orders changed since
+// the last flush.
+func (p *PaperBroker) state() *models.PaperAccountState {
+	state := &models.PaperAccountState{
+		Account: models.PaperAccount{
+			InitialBalance: p.balance.TotalEquity,
+			Cash:           p.balance.AvailableCash,
+			RealizedPnL:    p.realizedPnL,
+			Charges:        p.totalCharges.Total,
+			OrderCounter:   p.orderCounter,
+			GTTCounter:     p.gttCounter,
+			SessionClose:   p.sessionEnd,
+			CreatedAt:      p.createdAt,
+			UpdatedAt:      p.now(),
+			Version:        p.version,
+		},
+	}
+	for id := range p.unsaved {
+		if o, ok := p.orders[id]; ok {
+			state.Orders = append(state.Orders, *o)
+		}
+	}
+	for _, pos := range p.positions {
+		state.Positions = append(state.Positions, *pos)
+	}
+	for _, h := range p.holdings {
+		state.Holdings = append(state.Holdings, *h)
+	}
+	return state
+}
+
+// flush writes pending changes to the store. Must be called with p.mu held.
+func (p *PaperBroker) flush(ctx context.Context) {
+	if p.store == nil || !p.dirty {
+		return
+	}
+
+	err := p.store.SavePaperAccount(ctx, p.state())
+	if err == nil {
+		p.version++
+		err = p.store.AppendPaperLedger(ctx, p.ledger)
+	}
+	if err != nil {
+		p.persistErr = fmt.Errorf("saving paper account: %w", err)
+		return
+	}
+
+	p.persistErr = nil
+	p.dirty = false
+	p.unsaved = make(map[string]bool)
+	p.ledger = nil
+}
+
+// PersistError returns the last error writing the account to its store, or
+// nil once a later write succeeds.
+func (p *PaperBroker) PersistError() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.persistErr
+}
+
+// touch marks an order as changed.
+func (p *PaperBroker) touch(o *models.Order) {
+	p.dirty = true
+	if p.store != nil {
+		p.unsaved[o.ID] = true
+	}
+}
+
+// record queues a ledger entry for the next flush.
+func (p *PaperBroker) record(entry models.PaperLedgerEntry) {
+	p.dirty = true
+	if p.store == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = p.eventTime()
+	}
+	p.ledger = append(p.ledger, entry)
+}
+
+// recordFill queues the cash movements of a fill.
+func (p *PaperBroker) recordFill(o *models.Order, qty int, price float64, fillCharges charges.Breakdown) {
+	value := price * float64(qty)
+	entry := models.PaperLedgerEntry{
+		Type:    models.PaperLedgerBuy,
+		Symbol:  o.Symbol,
+		OrderID: o.ID,
+		Amount:  -value,
+		Note:    fmt.Sprintf("%d @ %.2f", qty, price),
+	}
+	if o.Side == models.OrderSideSell {
+		entry.Type = models.PaperLedgerSell
+		entry.Amount = value
+	}
+	entry.Balance = p.balance.AvailableCash + fillCharges.Total
+	p.record(entry)
+
+	if fillCharges.Total > 0 {
+		p.record(models.PaperLedgerEntry{
+			Type:    models.PaperLedgerCharges,
+			Symbol:  o.Symbol,
+			OrderID: o.ID,
+			Amount:  -fillCharges.Total,
+			Balance: p.balance.AvailableCash,
+		})
+	}
+}
+
+// eventTime is the time of the event being processed: the tick or
+// end-of-day time when one is set, else the clock.
+func (p *PaperBroker) eventTime() time.Time {
+	if !p.at.IsZero() {
+		return p.at
+	}
+	return p.now()
+}
+
+// SettleEndOfDay runs the intraday square-off and end-of-day processing for
+// every session that has closed by now. Ticks run it automatically.
+func (p *PaperBroker) SettleEndOfDay(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx := context.Background()
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		p.persistErr = err
+		return
+	}
+	defer unlock()
+
+	p.settle(now)
+	p.flush(ctx)
+}
+
+// settle catches the account up to now. Must be called with p.mu held.
+func (p *PaperBroker) settle(now time.Time) {
+	saved := p.at
+	defer func() { p.at = saved }()
+
+	for !now.Before(p.sessionEnd) {
+		p.at = p.sessionEnd
+		p.endOfDay(p.sessionEnd)
+		p.sessionEnd = p.sessionClose(p.sessionEnd)
+	}
+
+	cutoff := p.sessionEnd.Add(-misSquareOffLead)
+	if !now.Before(cutoff) {
+		p.at = cutoff
+		p.squareOffMIS(cutoff)
+	}
+}
+
+// squareOffMIS cancels open MIS orders and closes MIS positions at the last
+// known price, as the broker's auto square-off does.
+func (p *PaperBroker) squareOffMIS(at time.Time) {
+	for _, o := range p.book {
+		if isActiveOrder(o) && o.Product == models.ProductMIS {
+			p.closeOrder(o, models.OrderStatusCancelled, "Cancelled by intraday square-off")
+		}
+	}
+	p.pruneBook()
+
+	for _, pos := range p.positions {
+		if pos.Product != models.ProductMIS || pos.Quantity == 0 {
+			continue
+		}
+
+		side, qty := models.OrderSideSell, pos.Quantity
+		if qty < 0 {
+			side, qty = models.OrderSideBuy, -qty
+		}
+		o := &models.Order{
+			ID:       p.nextOrderID(),
+			Symbol:   pos.Symbol,
+			Exchange: pos.Exchange,
+			Side:     side,
+			Type:     models.OrderTypeMarket,
+			Product:  models.ProductMIS,
+			Quantity: qty,
+			Validity: models.ValidityDay,
+			Tag:      squareOffTag,
+			Status:   models.OrderStatusOpen,
+			PlacedAt: at,
+		}
+		p.orders[o.ID] = o
+		p.fillOrder(o, qty, p.markPrice(pos.Symbol, pos.LTP, pos.AveragePrice))
+	}
+}
+
+// endOfDay closes the session ending at end.
+func (p *PaperBroker) endOfDay(end time.Time) {
+	p.expireOrders(end)
+	p.squareOffMIS(end.Add(-misSquareOffLead))
+
+	// Shares bought in the previous session settle today (T+1)
+	for _, h := range p.holdings {
+		if h.T1Quantity == 0 {
+			continue
+		}
+		p.record(models.PaperLedgerEntry{
+			Type:    models.PaperLedgerSettlement,
+			Symbol:  h.Symbol,
+			Balance: p.balance.AvailableCash,
+			Note:    fmt.Sprintf("%d settled", h.T1Quantity),
+		})
+		h.T1Quantity = 0
+	}
+
+	// Delivery positions move to holdings pending settlement
+	for key, pos := range p.positions {
+		if pos.Product != models.ProductCNC || pos.Quantity <= 0 {
+			continue
+		}
+
+		h, ok := p.holdings[pos.Symbol]
+		if !ok {
+			h = &models.Holding{Symbol: pos.Symbol}
+			p.holdings[pos.Symbol] = h
+		}
+		h.AveragePrice = (h.AveragePrice*float64(h.Quantity) + pos.AveragePrice*float64(pos.Quantity)) / float64(h.Quantity+pos.Quantity)
+		h.Quantity += pos.Quantity
+		h.T1Quantity += pos.Quantity
+		h.InvestedValue = h.AveragePrice * float64(h.Quantity)
+		h.LTP = pos.LTP
+
+		p.record(models.PaperLedgerEntry{
+			Type:    models.PaperLedgerSettlement,
+			Symbol:  pos.Symbol,
+			Balance: p.balance.AvailableCash,
+			Note:    fmt.Sprintf("%d moved to holdings (T1)", pos.Quantity),
+		})
+		delete(p.positions, key)
+	}
+}
+
+// sellFromHoldings sells the part of a delivery sell not covered by today's
+// CNC long position out of holdings, booking P&L against the holding's
+// average price. It returns the quantity taken from holdings.
+func (p *PaperBroker) sellFromHoldings(o *models.Order, qty int, price float64) int {
+	h, ok := p.holdings[o.Symbol]
+	if !ok {
+		return 0
+	}
+
+	if pos, ok := p.positions[positionKey(o.Exchange, o.Symbol, o.Product)]; ok && pos.Quantity > 0 {
+		qty -= pos.Quantity
+	}
+	n := min(qty, h.Quantity)
+	if n <= 0 {
+		return 0
+	}
+
+	p.realizedPnL += (price - h.AveragePrice) * float64(n)
+	h.Quantity -= n
+	h.T1Quantity = min(h.T1Quantity, h.Quantity)
+	h.InvestedValue = h.AveragePrice * float64(h.Quantity)
+	if h.Quantity == 0 {
+		delete(p.holdings, o.Symbol)
+	}
+	return n
+}
+
+// deliverableQty returns how many shares a CNC sell can deliver.
+func (p *PaperBroker) deliverableQty(symbol string, exchange models.Exchange) int {
+	qty := 0
+	if h, ok := p.holdings[symbol]; ok {
+		qty += h.Quantity
+	}
+	if pos, ok := p.positions[positionKey(exchange, symbol, models.ProductCNC)]; ok && pos.Quantity > 0 {
+		qty += pos.Quantity
+	}
+	return qty
+}
+
+// markPrice returns the price to value a position at: the live price when
+// known, else the last persisted price, else cost.
+func (p *PaperBroker) markPrice(symbol string, ltp, avg float64) float64 {
+	if price := p.priceCache[symbol]; price > 0 {
+		return price
+	}
+	if ltp > 0 {
+		return ltp
+	}
+	return avg
+}
+
+// Snapshot summarises the account at current prices.
+func (p *PaperBroker) Snapshot(label string) models.PaperSnapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	snap := models.PaperSnapshot{
+		Label:         label,
+		TakenAt:       p.now(),
+		Cash:          p.balance.AvailableCash,
+		Equity:        p.balance.AvailableCash,
+		RealizedPnL:   p.realizedPnL,
+		Charges:       p.totalCharges.Total,
+		OpenPositions: len(p.positions),
+		Holdings:      len(p.holdings),
+	}
+	for _, pos := range p.positions {
+		price := p.markPrice(pos.Symbol, pos.LTP, pos.AveragePrice)
+		snap.Equity += price * float64(pos.Quantity)
+		snap.UnrealizedPnL += (price - pos.AveragePrice) * float64(pos.Quantity)
+	}
+	for _, h := range p.holdings {
+		price := p.markPrice(h.Symbol, h.LTP, h.AveragePrice)
+		snap.Equity += price * float64(h.Quantity)
+		snap.UnrealizedPnL += (price - h.AveragePrice) * float64(h.Quantity)
+	}
+	for _, o := range p.orders {
+		if o.FilledQty > 0 {
+			snap.Trades++
+		}
+	}
+	if initial := p.balance.TotalEquity; initial > 0 {
+		snap.ReturnPercent = (snap.Equity - initial) / initial * 100
+	}
+	return snap
+}
+
+// InitialBalance returns the cash the account started with.
+func (p *PaperBroker) InitialBalance() float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.balance.TotalEquity
+}
+
+// positionKey identifies a position by exchange, symbol and product.
+func positionKey(exchange models.Exchange, symbol string, product models.ProductType) string {
+	return fmt.Sprintf("%s:%s:%s", exchange, symbol, product)
+}
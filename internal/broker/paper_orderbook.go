@@ -0,0 +1,275 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+)
+
+// The paper order book mirrors Kite's order lifecycle:
+//
+//	MARKET/LIMIT:  OPEN -> COMPLETE | CANCELLED
+//	SL/SL-M:       TRIGGER PENDING -> OPEN -> COMPLETE | CANCELLED
+//	invalid order: REJECTED
+//
+// Resting orders are matched against ticks in price-time priority. Fills are
+// limited by the opposite side depth on the tick, or by the volume traded since
+// the previous tick when depth is missing, so large orders fill partially.
+
+// isActiveOrder reports whether an order can still fill.
+func isActiveOrder(o *models.Order) bool {
+	return o.Status == models.OrderStatusOpen || o.Status == models.OrderStatusTriggerPending
+}
+
+// validatePaperOrder returns a Kite-style rejection reason, or "" if the order is valid.
+func validatePaperOrder(o *models.Order, ltp float64) string {
+	if o.Quantity <= 0 {
+		return "Quantity should be greater than zero"
+	}
+	if o.Validity != models.ValidityDay && o.Validity != models.ValidityIOC {
+		return fmt.Sprintf("Invalid validity %q", o.Validity)
+	}
+
+	switch o.Type {
+	case models.OrderTypeMarket:
+	case models.OrderTypeLimit:
+		if o.Price <= 0 {
+			return "Price should be greater than zero for limit orders"
+		}
+	case models.OrderTypeStopLoss, models.OrderTypeStopLossM:
+		if o.TriggerPrice <= 0 {
+			return "Trigger price should be greater than zero for stoploss orders"
+		}
+		if o.Type == models.OrderTypeStopLoss {
+			if o.Price <= 0 {
+				return "Price should be greater than zero for stoploss limit orders"
+			}
+			if o.Side == models.OrderSideBuy && o.TriggerPrice > o.Price {
+				return "Trigger price for stoploss buy orders should be lower than or equal to the limit price"
+			}
+			if o.Side == models.OrderSideSell && o.TriggerPrice < o.Price {
+				return "Trigger price for stoploss sell orders should be higher than or equal to the limit price"
+			}
+		}
+		if ltp > 0 && o.Side == models.OrderSideBuy && o.TriggerPrice <= ltp {
+			return "Trigger price for stoploss buy orders should be higher than the last traded price"
+		}
+		if ltp > 0 && o.Side == models.OrderSideSell && o.TriggerPrice >= ltp {
+			return "Trigger price for stoploss sell orders should be lower than the last traded price"
+		}
+	default:
+		return fmt.Sprintf("Invalid order type %q", o.Type)
+	}
+
+	return ""
+}
+
+// triggered reports whether a stoploss order's trigger has been hit.
+func triggered(o *models.Order, ltp float64) bool {
+	if o.Side == models.OrderSideBuy {
+		return ltp >= o.TriggerPrice
+	}
+	return ltp <= o.TriggerPrice
+}
+
+// marketable reports whether an open order can fill at ltp.
+func marketable(o *models.Order, ltp float64) bool {
+	switch o.Type {
+	case models.OrderTypeMarket, models.OrderTypeStopLossM:
+		return true
+	default:
+		if o.Side == models.OrderSideBuy {
+			return ltp <= o.Price
+		}
+		return ltp >= o.Price
+	}
+}
+
+// matchOrder advances an active order at ltp, filling at most available
+// quantity (negative means unlimited). It returns the quantity filled.
+func (p *PaperBroker) matchOrder(o *models.Order, ltp float64, available int) int {
+	if ltp <= 0 {
+		return 0
+	}
+	if o.Status == models.OrderStatusTriggerPending {
+		if !triggered(o, ltp) {
+			return 0
+		}
+		o.Status = models.OrderStatusOpen
+	}
+	if o.Status != models.OrderStatusOpen || !marketable(o, ltp) {
+		return 0
+	}
+
+	qty := o.Quantity - o.FilledQty
+	if available >= 0 && qty > available {
+		qty = available
+	}
+	if qty <= 0 {
+		return 0
+	}
+
+	if !p.fillOrder(o, qty, ltp) {
+		return 0
+	}
+	return qty
+}
+
+// fillOrder executes qty of an order at price, updating the position, cash and
+// charges. Charges are recomputed on the cumulative fill so per-order brokerage
+// caps apply once across partial fills. Returns false if the buyer cannot pay,
+// in which case the unfilled remainder is cancelled.
+func (p *PaperBroker) fillOrder(o *models.Order, qty int, price float64) bool {
+	filled := o.FilledQty + qty
+	avgPrice := (o.AveragePrice*float64(o.FilledQty) + price*float64(qty)) / float64(filled)
+
+	cumulative := p.calculateCharges(o, filled, avgPrice)
+	previous := p.orderCharges[o.ID]
+	fillCharges := cumulative.Sub(previous)
+
+	value := price * float64(qty)
+	// The broker's square-off executes regardless of funds
+	if o.Side == models.OrderSideBuy && o.Tag != squareOffTag && p.balance.AvailableCash < value+fillCharges.Total {
+		msg := fmt.Sprintf("Insufficient funds: need %.2f, have %.2f", value+fillCharges.Total, p.balance.AvailableCash)
+		if o.FilledQty > 0 {
+			p.closeOrder(o, models.OrderStatusCancelled, msg)
+		} else {
+			p.closeOrder(o, models.OrderStatusRejected, msg)
+		}
+		return false
+	}
+
+	positionQty := qty
+	if o.Product == models.ProductCNC && o.Side == models.OrderSideSell {
+		positionQty -= p.sellFromHoldings(o, qty, price)
+	}
+	if positionQty > 0 {
+		p.updatePosition(o.Symbol, o.Exchange, o.Product, o.Side, positionQty, price)
+	}
+
+	if o.Side == models.OrderSideBuy {
+		p.balance.AvailableCash -= value
+	} else {
+		p.balance.AvailableCash += value
+	}
+	p.balance.AvailableCash -= fillCharges.Total
+	p.orderCharges[o.ID] = cumulative
+	p.totalCharges = p.totalCharges.Add(fillCharges)
+	p.recordFill(o, qty, price, fillCharges)
+
+	o.FilledQty = filled
+	o.AveragePrice = avgPrice
+	if o.FilledQty == o.Quantity {
+		o.Status = models.OrderStatusComplete
+	}
+	p.touch(o)
+	return true
+}
+
+// closeOrder moves an order to a terminal status.
+func (p *PaperBroker) closeOrder(o *models.Order, status, message string) {
+	o.Status = status
+	o.StatusMessage = message
+	p.touch(o)
+}
+
+// matchTick fills resting orders for the tick's symbol in placement order.
+// Must be called with p.mu held.
+func (p *PaperBroker) matchTick(tick models.Tick) {
+	buyLiquidity, sellLiquidity := p.tickLiquidity(tick)
+
+	for _, o := range p.book {
+		if !isActiveOrder(o) || o.Symbol != tick.Symbol {
+			continue
+		}
+		if o.Side == models.OrderSideBuy {
+			buyLiquidity -= p.matchOrder(o, tick.LTP, buyLiquidity)
+		} else {
+			sellLiquidity -= p.matchOrder(o, tick.LTP, sellLiquidity)
+		}
+	}
+
+	p.pruneBook()
+}
+
+// tickLiquidity returns the quantity available to buyers and sellers on a tick.
+// Depth is preferred; otherwise the volume traded since the last tick is used.
+// Negative values mean the liquidity is unknown and fills are not limited.
+func (p *PaperBroker) tickLiquidity(tick models.Tick) (buy, sell int) {
+	buy, sell = -1, -1
+
+	last, seen := p.lastVolume[tick.Symbol]
+	if tick.Volume > 0 {
+		p.lastVolume[tick.Symbol] = tick.Volume
+	}
+	if seen && tick.Volume > last {
+		traded := int(tick.Volume - last)
+		buy, sell = traded, traded
+	}
+
+	if tick.SellQuantity > 0 {
+		buy = int(tick.SellQuantity)
+	}
+	if tick.BuyQuantity > 0 {
+		sell = int(tick.BuyQuantity)
+	}
+	return buy, sell
+}
+
+// expireOrders cancels DAY orders whose session has closed.
+// Must be called with p.mu held.
+func (p *PaperBroker) expireOrders(now time.Time) {
+	for _, o := range p.book {
+		if isActiveOrder(o) && !now.Before(p.sessionClose(o.PlacedAt)) {
+			p.closeOrder(o, models.OrderStatusCancelled, "Order expired at market close")
+		}
+	}
+	p.pruneBook()
+}
+
+// ExpireOrders cancels DAY orders whose session has closed by now. It runs on
+// every tick and can also be called by a scheduler at market close.
+func (p *PaperBroker) ExpireOrders(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx := context.Background()
+	unlock, err := p.lockAccount(ctx)
+	if err != nil {
+		p.persistErr = err
+		return
+	}
+	defer unlock()
+
+	p.expireOrders(now)
+	p.flush(ctx)
+}
+
+// pruneBook drops orders that can no longer fill from the book.
+func (p *PaperBroker) pruneBook() {
+	active := p.book[:0]
+	for _, o := range p.book {
+		if isActiveOrder(o) {
+			active = append(active, o)
+		}
+	}
+	p.book = active
+}
+
+// sessionClose returns the market close that ends the trading day of t.
+// Orders placed after the close belong to the next trading day's session;
+// weekends and exchange holidays have none.
+func (p *PaperBroker) sessionClose(t time.Time) time.Time {
+	t = t.In(resilience.IndiaLocation)
+	end := time.Date(t.Year(), t.Month(), t.Day(), 15, 30, 0, 0, resilience.IndiaLocation)
+	if !t.Before(end) {
+		end = end.AddDate(0, 0, 1)
+	}
+	for end.Weekday() == time.Saturday || end.Weekday() == time.Sunday || p.calendar.IsHoliday(end) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
@@ -0,0 +1,537 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/store"
+)
+
+func newTestPaperBroker(now *time.Time) *PaperBroker {
+	return NewPaperBroker(PaperBrokerConfig{
+		InitialBalance: 1000000,
+		DisableCharges: true,
+		Clock:          func() time.Time { return *now },
+	})
+}
+
+func paperOrder(t *testing.T, p *PaperBroker, id string) models.Order {
+	t.Helper()
+	orders, _ := p.GetOrders(context.Background())
+	for _, o := range orders {
+		if o.ID == id {
+			return o
+		}
+	}
+	t.Fatalf("order %s not found", id)
+	return models.Order{}
+}
+
+func TestPaperBroker_RestingLimitFillsOnTick(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, resilience.IndiaLocation)
+	p := newTestPaperBroker(&now)
+	p.UpdatePrice("INFY", 1500)
+
+	result, err := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy,
+		Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 100, Price: 1490,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Status != models.OrderStatusOpen {
+		t.Fatalf("expected OPEN, got %s", result.Status)
+	}
+
+	// Price above the limit: no fill
+	p.ProcessTick(models.Tick{Symbol: "INFY", LTP: 1495, Timestamp: now})
+	if o := paperOrder(t, p, result.OrderID); o.FilledQty != 0 {
+		t.Fatalf("filled above limit: %d", o.FilledQty)
+	}
+
+	// Crosses the limit with 40 shares offered: partial fill
+	p.ProcessTick(models.Tick{Symbol: "INFY", LTP: 1489, SellQuantity: 40, Timestamp: now})
+	o := paperOrder(t, p, result.OrderID)
+	if o.Status != models.OrderStatusOpen || o.FilledQty != 40 {
+		t.Fatalf("expected partial fill of 40, got %s %d", o.Status, o.FilledQty)
+	}
+
+	p.ProcessTick(models.Tick{Symbol: "INFY", LTP: 1488, SellQuantity: 500, Timestamp: now})
+	o = paperOrder(t, p, result.OrderID)
+	if o.Status != models.OrderStatusComplete || o.FilledQty != 100 {
+		t.Fatalf("expected COMPLETE, got %s %d", o.Status, o.FilledQty)
+	}
+	if want := (1489.0*40 + 1488.0*60) / 100; o.AveragePrice != want {
+		t.Errorf("average price: expected %.2f, got %.2f", want, o.AveragePrice)
+	}
+}
+
+func TestPaperBroker_StopLossTriggers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, resilience.IndiaLocation)
+	p := newTestPaperBroker(&now)
+	p.UpdatePrice("SBIN", 800)
+
+	// Trigger above LTP for a stoploss sell is rejected
+	result, _ := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "SBIN", Exchange: models.NSE, Side: models.OrderSideSell,
+		Type: models.OrderTypeStopLossM, Product: models.ProductMIS, Quantity: 10, TriggerPrice: 810,
+	})
+	if result.Status != models.OrderStatusRejected {
+		t.Fatalf("expected REJECTED, got %s", result.Status)
+	}
+
+	result, _ = p.PlaceOrder(ctx, &models.Order{
+		Symbol: "SBIN", Exchange: models.NSE, Side: models.OrderSideSell,
+		Type: models.OrderTypeStopLossM, Product: models.ProductMIS, Quantity: 10, TriggerPrice: 790,
+	})
+	if result.Status != models.OrderStatusTriggerPending {
+		t.Fatalf("expected TRIGGER PENDING, got %s", result.Status)
+	}
+
+	p.ProcessTick(models.Tick{Symbol: "SBIN", LTP: 795, Timestamp: now})
+	if o := paperOrder(t, p, result.OrderID); o.Status != models.OrderStatusTriggerPending {
+		t.Fatalf("triggered early: %s", o.Status)
+	}
+
+	p.ProcessTick(models.Tick{Symbol: "SBIN", LTP: 788, Timestamp: now})
+	o := paperOrder(t, p, result.OrderID)
+	if o.Status != models.OrderStatusComplete || o.AveragePrice != 788 {
+		t.Fatalf("expected SL-M fill at 788, got %s at %.2f", o.Status, o.AveragePrice)
+	}
+}
+
+func TestPaperBroker_ValidityExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, resilience.IndiaLocation)
+	p := newTestPaperBroker(&now)
+	p.UpdatePrice("TCS", 3500)
+
+	ioc, _ := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "TCS", Exchange: models.NSE, Side: models.OrderSideBuy, Validity: models.ValidityIOC,
+		Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 1, Price: 3400,
+	})
+	if ioc.Status != models.OrderStatusCancelled {
+		t.Errorf("expected unfilled IOC to be CANCELLED, got %s", ioc.Status)
+	}
+
+	day, _ := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "TCS", Exchange: models.NSE, Side: models.OrderSideBuy,
+		Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 1, Price: 3400,
+	})
+	if day.Status != models.OrderStatusOpen {
+		t.Fatalf("expected OPEN, got %s", day.Status)
+	}
+
+	p.ExpireOrders(time.Date(2024, 3, 4, 15, 29, 0, 0, resilience.IndiaLocation))
+	if o := paperOrder(t, p, day.OrderID); o.Status != models.OrderStatusOpen {
+		t.Fatalf("expired before close: %s", o.Status)
+	}
+
+	// A tick after the close expires the order instead of filling it
+	p.ProcessTick(models.Tick{Symbol: "TCS", LTP: 3390, Timestamp: time.Date(2024, 3, 4, 15, 31, 0, 0, resilience.IndiaLocation)})
+	if o := paperOrder(t, p, day.OrderID); o.Status != models.OrderStatusCancelled || o.FilledQty != 0 {
+		t.Errorf("expected DAY order CANCELLED at close, got %s (filled %d)", o.Status, o.FilledQty)
+	}
+}
+
+func TestPaperBroker_HolidayAndClockIDs(t *testing.T) {
+	ctx := context.Background()
+	// Thursday after the close; Friday 8 March 2024 is an NSE holiday
+	now := time.Date(2024, 3, 7, 16, 0, 0, 0, resilience.IndiaLocation)
+	p := newTestPaperBroker(&now)
+	p.UpdatePrice("TCS", 3500)
+
+	day, _ := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "TCS", Exchange: models.NSE, Side: models.OrderSideBuy,
+		Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 1, Price: 3400,
+	})
+	if want := fmt.Sprintf("PAPER_%d_1", now.Unix()); day.OrderID != want {
+		t.Errorf("order ID %s, want %s from the broker clock", day.OrderID, want)
+	}
+	gtt, err := p.PlaceGTT(ctx, &models.GTTOrder{
+		Symbol: "TCS", Exchange: models.NSE, TriggerType: "single", TriggerPrice: 3300,
+		Orders: []models.GTTOrderLeg{{Side: models.OrderSideBuy, Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 1, Price: 3301, TriggerPrice: 3300}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := fmt.Sprintf("PAPER_GTT_%d_1", now.Unix()); gtt.TriggerID != want {
+		t.Errorf("GTT ID %s, want %s from the broker clock", gtt.TriggerID, want)
+	}
+
+	// No session closes on the holiday or the weekend
+	p.ExpireOrders(time.Date(2024, 3, 8, 15, 31, 0, 0, resilience.IndiaLocation))
+	if o := paperOrder(t, p, day.OrderID); o.Status != models.OrderStatusOpen {
+		t.Fatalf("expired on a holiday: %s", o.Status)
+	}
+	p.ExpireOrders(time.Date(2024, 3, 11, 15, 30, 0, 0, resilience.IndiaLocation))
+	if o := paperOrder(t, p, day.OrderID); o.Status != models.OrderStatusCancelled {
+		t.Errorf("expected expiry at Monday's close, got %s", o.Status)
+	}
+}
+
+// memoryGTTStore keeps GTTs in memory to simulate persistence across restarts.
+type memoryGTTStore struct {
+	gtts map[string]models.GTTOrder
+}
+
+func (m *memoryGTTStore) SavePaperGTT(_ context.Context, gtt *models.GTTOrder) error {
+	m.gtts[gtt.ID] = *gtt
+	return nil
+}
+
+func (m *memoryGTTStore) GetPaperGTTs(_ context.Context) ([]models.GTTOrder, error) {
+	gtts := make([]models.GTTOrder, 0, len(m.gtts))
+	for _, g := range m.gtts {
+		gtts = append(gtts, g)
+	}
+	return gtts, nil
+}
+
+func TestPaperBroker_TwoLegGTTSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, resilience.IndiaLocation)
+	gttStore := &memoryGTTStore{gtts: make(map[string]models.GTTOrder)}
+
+	p := NewPaperBroker(PaperBrokerConfig{InitialBalance: 1000000, DisableCharges: true, GTTStore: gttStore, Clock: func() time.Time { return now }})
+	p.UpdatePrice("INFY", 1500)
+	if _, err := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy,
+		Type: models.OrderTypeMarket, Product: models.ProductCNC, Quantity: 10,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := p.PlaceGTT(ctx, &models.GTTOrder{
+		Symbol: "INFY", Exchange: models.NSE, TriggerType: "two-leg",
+		Orders: []models.GTTOrderLeg{
+			{Side: models.OrderSideSell, Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 10, Price: 1399, TriggerPrice: 1400},
+			{Side: models.OrderSideSell, Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 10, Price: 1601, TriggerPrice: 1600},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Restart: a new broker on the same store sees the active GTT
+	restarted := NewPaperBroker(PaperBrokerConfig{InitialBalance: 1000000, DisableCharges: true, GTTStore: gttStore, Clock: func() time.Time { return now }})
+	restarted.mu.Lock()
+	restarted.positions = p.positions
+	restarted.mu.Unlock()
+
+	restarted.ProcessTick(models.Tick{Symbol: "INFY", LTP: 1550, Timestamp: now})
+	gtts, _ := restarted.GetGTTs(ctx)
+	if len(gtts) != 1 || gtts[0].Status != "ACTIVE" {
+		t.Fatalf("expected restored active GTT, got %+v", gtts)
+	}
+
+	// Target leg triggers and places its sell order; the stop-loss leg is cancelled with it
+	restarted.ProcessTick(models.Tick{Symbol: "INFY", LTP: 1605, Timestamp: now})
+	gtts, _ = restarted.GetGTTs(ctx)
+	if gtts[0].Status != "TRIGGERED" || gtts[0].OrderID == "" {
+		t.Fatalf("expected triggered GTT with an order, got %+v", gtts[0])
+	}
+	o := paperOrder(t, restarted, gtts[0].OrderID)
+	if o.Side != models.OrderSideSell || o.Price != 1601 || o.Status != models.OrderStatusComplete {
+		t.Errorf("expected target leg sell at 1601 to fill, got %+v", o)
+	}
+
+	restarted.ProcessTick(models.Tick{Symbol: "INFY", LTP: 1390, Timestamp: now})
+	orders, _ := restarted.GetOrders(ctx)
+	if len(orders) != 1 {
+		t.Errorf("stop-loss leg fired after the target: %d orders", len(orders))
+	}
+	if saved := gttStore.gtts[result.TriggerID]; saved.Status != "TRIGGERED" {
+		t.Errorf("expected persisted TRIGGERED status, got %s", saved.Status)
+	}
+}
+
+func TestPaperBroker_ShortPositionPnL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, resilience.IndiaLocation)
+	p := newTestPaperBroker(&now)
+
+	trade := func(side models.OrderSide, qty int, price float64) {
+		t.Helper()
+		p.UpdatePrice("SBIN", price)
+		result, err := p.PlaceOrder(ctx, &models.Order{
+			Symbol: "SBIN", Exchange: models.NSE, Side: side,
+			Type: models.OrderTypeMarket, Product: models.ProductMIS, Quantity: qty,
+		})
+		if err != nil || result.Status != models.OrderStatusComplete {
+			t.Fatalf("%s %d@%.0f: %v %+v", side, qty, price, err, result)
+		}
+	}
+	position := func() (int, float64) {
+		positions, _ := p.GetPositions(ctx)
+		if len(positions) == 0 {
+			return 0, 0
+		}
+		return positions[0].Quantity, positions[0].AveragePrice
+	}
+
+	tests := []struct {
+		name     string
+		side     models.OrderSide
+		qty      int
+		price    float64
+		quantity int
+		average  float64
+		realized float64
+	}{
+		{"short", models.OrderSideSell, 10, 100, -10, 100, 0},
+		{"add to short", models.OrderSideSell, 10, 110, -20, 105, 0},
+		{"partial cover keeps average", models.OrderSideBuy, 5, 100, -15, 105, 25},
+		{"cover", models.OrderSideBuy, 15, 105, 0, 0, 25},
+		{"short again", models.OrderSideSell, 10, 100, -10, 100, 25},
+		{"flip to long", models.OrderSideBuy, 15, 90, 5, 90, 125},
+		{"add to long", models.OrderSideBuy, 5, 100, 10, 95, 125},
+		{"flip to short", models.OrderSideSell, 14, 97, -4, 97, 145},
+	}
+
+	for _, tt := range tests {
+		trade(tt.side, tt.qty, tt.price)
+		quantity, average := position()
+		if quantity != tt.quantity || average != tt.average {
+			t.Errorf("%s: position %d@%.2f, want %d@%.2f", tt.name, quantity, average, tt.quantity, tt.average)
+		}
+		if pnl := p.GetPnLSummary(); pnl.GrossRealized != tt.realized {
+			t.Errorf("%s: realised P&L %.2f, want %.2f", tt.name, pnl.GrossRealized, tt.realized)
+		}
+	}
+}
+
+func TestPaperBroker_AccountPersistsThroughEndOfDay(t *testing.T) {
+	ctx := context.Background()
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "paper.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, resilience.IndiaLocation) // Monday
+	open := func() *PaperBroker {
+		p, err := OpenPaperBroker(ctx, PaperBrokerConfig{
+			InitialBalance: 100000,
+			DisableCharges: true,
+			Clock:          func() time.Time { return now },
+		}, db.PaperAccount("swing"))
+		if err != nil {
+			t.Fatalf("failed to open account: %v", err)
+		}
+		return p
+	}
+
+	p := open()
+	p.UpdatePrice("INFY", 1500)
+	p.UpdatePrice("SBIN", 800)
+	for _, o := range []*models.Order{
+		{Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy, Type: models.OrderTypeMarket, Product: models.ProductCNC, Quantity: 10},
+		{Symbol: "SBIN", Exchange: models.NSE, Side: models.OrderSideBuy, Type: models.OrderTypeMarket, Product: models.ProductMIS, Quantity: 5},
+	} {
+		if result, _ := p.PlaceOrder(ctx, o); result.Status != models.OrderStatusComplete {
+			t.Fatalf("expected fill, got %s", result.Status)
+		}
+	}
+
+	// The intraday position is squared off at 15:15 on the next tick
+	p.ProcessTick(models.Tick{Symbol: "SBIN", LTP: 810, Timestamp: time.Date(2024, 3, 4, 15, 16, 0, 0, resilience.IndiaLocation)})
+	if positions, _ := p.GetPositions(ctx); len(positions) != 1 || positions[0].Symbol != "INFY" {
+		t.Fatalf("expected only the CNC position after square-off, got %+v", positions)
+	}
+	if pnl := p.GetPnLSummary(); pnl.GrossRealized != 50 {
+		t.Errorf("expected square-off P&L 50, got %.2f", pnl.GrossRealized)
+	}
+
+	// Next morning: the CNC buy is a T1 holding
+	now = time.Date(2024, 3, 5, 10, 0, 0, 0, resilience.IndiaLocation)
+	p = open()
+	holdings, _ := p.GetHoldings(ctx)
+	if len(holdings) != 1 || holdings[0].Quantity != 10 || holdings[0].T1Quantity != 10 {
+		t.Fatalf("expected 10 INFY awaiting settlement, got %+v", holdings)
+	}
+	if positions, _ := p.GetPositions(ctx); len(positions) != 0 {
+		t.Errorf("expected no positions after end of day, got %+v", positions)
+	}
+	balance, _ := p.GetBalance(ctx)
+	if want := 100000.0 - 15000 + 50; balance.AvailableCash != want {
+		t.Errorf("expected cash %.2f, got %.2f", want, balance.AvailableCash)
+	}
+
+	// A day later the shares have settled and can be sold from holdings
+	now = time.Date(2024, 3, 6, 10, 0, 0, 0, resilience.IndiaLocation)
+	p = open()
+	holdings, _ = p.GetHoldings(ctx)
+	if len(holdings) != 1 || holdings[0].T1Quantity != 0 {
+		t.Fatalf("expected settled holding, got %+v", holdings)
+	}
+	p.UpdatePrice("INFY", 1600)
+	if result, _ := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideSell,
+		Type: models.OrderTypeMarket, Product: models.ProductCNC, Quantity: 11,
+	}); result.Status != models.OrderStatusRejected {
+		t.Errorf("expected selling more than held to be rejected, got %s", result.Status)
+	}
+	if result, _ := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideSell,
+		Type: models.OrderTypeMarket, Product: models.ProductCNC, Quantity: 10,
+	}); result.Status != models.OrderStatusComplete {
+		t.Fatalf("expected delivery sell to fill, got %s", result.Status)
+	}
+	if holdings, _ := p.GetHoldings(ctx); len(holdings) != 0 {
+		t.Errorf("expected holdings to be sold, got %+v", holdings)
+	}
+
+	p = open()
+	if pnl := p.GetPnLSummary(); pnl.GrossRealized != 1050 {
+		t.Errorf("expected restored realised P&L 1050, got %.2f", pnl.GrossRealized)
+	}
+	if orders, _ := p.GetOrders(ctx); len(orders) != 5 {
+		t.Errorf("expected 5 persisted orders, got %d", len(orders))
+	}
+	ledger, err := db.PaperAccount("swing").GetPaperLedger(ctx, 0)
+	if err != nil || len(ledger) == 0 || ledger[0].Type != models.PaperLedgerDeposit {
+		t.Errorf("expected ledger to start with the deposit, got %+v (%v)", ledger, err)
+	}
+}
+
+func TestPaperBroker_SharedAccountAcrossProcesses(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "paper.db")
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, resilience.IndiaLocation)
+
+	// Each process opens its own store on the same database
+	open := func() *PaperBroker {
+		db, err := store.NewSQLiteStore(path)
+		if err != nil {
+			t.Fatalf("failed to open store: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		p, err := OpenPaperBroker(ctx, PaperBrokerConfig{
+			InitialBalance: 100000,
+			DisableCharges: true,
+			Clock:          func() time.Time { return now },
+		}, db.PaperAccount(store.DefaultPaperAccount))
+		if err != nil {
+			t.Fatalf("failed to open account: %v", err)
+		}
+		p.UpdatePrice("INFY", 1500)
+		p.UpdatePrice("SBIN", 800)
+		return p
+	}
+	buy := func(symbol string, qty int, price float64) *models.Order {
+		o := &models.Order{Symbol: symbol, Exchange: models.NSE, Side: models.OrderSideBuy, Type: models.OrderTypeMarket,
+			Product: models.ProductCNC, Quantity: qty}
+		if price > 0 {
+			o.Type, o.Price = models.OrderTypeLimit, price
+		}
+		return o
+	}
+
+	daemon := open()
+	manual := open()
+
+	// A resting order from the daemon, then a manual buy from the CLI
+	resting, err := daemon.PlaceOrder(ctx, buy("SBIN", 10, 790))
+	if err != nil || resting.Status != models.OrderStatusOpen {
+		t.Fatalf("resting order: %+v, %v", resting, err)
+	}
+	filled, err := manual.PlaceOrder(ctx, buy("INFY", 10, 0))
+	if err != nil || filled.Status != models.OrderStatusComplete {
+		t.Fatalf("manual order: %+v, %v", filled, err)
+	}
+	if filled.OrderID == resting.OrderID {
+		t.Fatalf("both processes issued order ID %s", filled.OrderID)
+	}
+
+	// The daemon's next tick fills its order without wiping the manual buy
+	daemon.ProcessTick(models.Tick{Symbol: "SBIN", LTP: 789, Timestamp: now})
+	if err := daemon.PersistError(); err != nil {
+		t.Fatalf("daemon failed to save: %v", err)
+	}
+
+	p := open()
+	positions, _ := p.GetPositions(ctx)
+	if len(positions) != 2 {
+		t.Errorf("expected INFY and SBIN positions, got %+v", positions)
+	}
+	if orders, _ := p.GetOrders(ctx); len(orders) != 2 {
+		t.Errorf("expected both orders, got %+v", orders)
+	}
+	balance, _ := p.GetBalance(ctx)
+	if want := 100000.0 - 15000 - 7890; balance.AvailableCash != want {
+		t.Errorf("expected cash %.2f, got %.2f", want, balance.AvailableCash)
+	}
+}
+
+// quoteBroker serves Kite-style quotes, which need EXCHANGE:SYMBOL.
+type quoteBroker struct {
+	Broker
+	ltp map[string]float64
+}
+
+func (b *quoteBroker) GetQuote(_ context.Context, symbol string) (*models.Quote, error) {
+	ltp, ok := b.ltp[symbol]
+	if !ok {
+		return nil, fmt.Errorf("quote not found for symbol: %s", symbol)
+	}
+	return &models.Quote{Symbol: symbol, LTP: ltp}, nil
+}
+
+// handlerTicker holds the tick handler so tests can deliver ticks.
+type handlerTicker struct {
+	Ticker
+	onTick func(models.Tick)
+}
+
+func (t *handlerTicker) OnTick(handler func(models.Tick)) { t.onTick = handler }
+
+func TestPaperBroker_LiveDataAndTickFeed(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, resilience.IndiaLocation)
+	feed := &handlerTicker{}
+	p := NewPaperBroker(PaperBrokerConfig{
+		DataBroker:     &quoteBroker{ltp: map[string]float64{"NSE:INFY": 1500}},
+		Ticker:         feed,
+		InitialBalance: 1000000,
+		DisableCharges: true,
+		Clock:          func() time.Time { return now },
+	})
+
+	// The fill price comes from an exchange-qualified quote
+	result, err := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy,
+		Type: models.OrderTypeMarket, Product: models.ProductMIS, Quantity: 10,
+	})
+	if err != nil || result.Status != models.OrderStatusComplete {
+		t.Fatalf("market order: %+v, %v", result, err)
+	}
+	if o := paperOrder(t, p, result.OrderID); o.AveragePrice != 1500 {
+		t.Fatalf("filled at %.2f, want 1500", o.AveragePrice)
+	}
+
+	limit, _ := p.PlaceOrder(ctx, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideSell,
+		Type: models.OrderTypeLimit, Product: models.ProductMIS, Quantity: 10, Price: 1520,
+	})
+
+	// Ticks reach the broker before the caller's handler, which sees them too
+	ticker := p.Ticker()
+	var seen []float64
+	ticker.OnTick(func(tick models.Tick) { seen = append(seen, tick.LTP) })
+	feed.onTick(models.Tick{Symbol: "INFY", LTP: 1525, Timestamp: now})
+
+	if o := paperOrder(t, p, limit.OrderID); o.Status != models.OrderStatusComplete {
+		t.Errorf("resting limit status %s after tick, want COMPLETE", o.Status)
+	}
+	if len(seen) != 1 {
+		t.Errorf("handler saw %d ticks, want 1", len(seen))
+	}
+}
@@ -0,0 +1,33 @@
+package broker
+
+import "zerodha-trader/internal/models"
+
+// Ticker returns the configured ticker wrapped so that the paper broker
+// processes every tick before the caller's handler sees it. Resting orders
+// and GTTs then fill from the live feed whichever command is streaming.
+// It returns nil when no ticker is configured.
+func (p *PaperBroker) Ticker() Ticker {
+	if p.ticker == nil {
+		return nil
+	}
+	t := &paperTicker{Ticker: p.ticker, paper: p}
+	t.OnTick(nil)
+	return t
+}
+
+// paperTicker feeds ticks to a paper broker ahead of the tick handler.
+type paperTicker struct {
+	Ticker
+	paper *PaperBroker
+}
+
+// OnTick sets the tick handler, which runs after the paper broker has
+// processed the tick.
+func (t *paperTicker) OnTick(handler func(models.Tick)) {
+	t.Ticker.OnTick(func(tick models.Tick) {
+		t.paper.ProcessTick(tick)
+		if handler != nil {
+			handler(tick)
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+// ReplayConfig holds configuration for a ReplayTicker.
+type ReplayConfig struct {
+	// Dir is the tick recording directory, as given to NewTickRecorder.
+	Dir string
+	// Date is the trading day to replay.
+	Date time.Time
+	// Speed scales playback: 1 replays in real time, 10 ten times faster.
+	// Zero replays as fast as the tick handler returns.
+	Speed float64
+}
+
+// ReplayTicker implements Ticker by playing back a day recorded with
+// TickRecorder. Ticks are delivered in recorded order on a single goroutine,
+// so a replay at any speed sees the same sequence. Now is a virtual clock
+// that follows the replay; pass it as PaperBrokerConfig.Clock so orders are
+// stamped and expired in session time.
+//
+// Only subscribed symbols are delivered, without depth unless subscribed in
+// full mode. Ticks recorded without an exchange timestamp carry the time
+// they were recorded. When the recording ends the ticker disconnects.
+type ReplayTicker struct {
+	cfg    ReplayConfig
+	reader *tickDayReader
+
+	// Handlers
+	onTick       func(models.Tick)
+	onError      func(error)
+	onConnect    func()
+	onDisconnect func()
+
+	// State
+	subscribed map[string]TickMode
+	last       map[string]models.Tick // latest tick of every recorded symbol
+	started    bool
+	cancel     context.CancelFunc
+	done       chan struct{}
+
+	// Virtual clock
+	clock     time.Time // receive time of the last tick played
+	origin    time.Time // receive time of the first tick
+	wallStart time.Time // when paced playback started
+	paced     bool
+
+	mu sync.Mutex
+}
+
+// NewReplayTicker opens the recording of cfg.Date. The clock starts at the
+// first recorded tick.
+func NewReplayTicker(cfg ReplayConfig) (*ReplayTicker, error) {
+	if cfg.Speed < 0 {
+		return nil, fmt.Errorf("invalid replay speed %v", cfg.Speed)
+	}
+	reader, err := openTickDay(cfg.Dir, cfg.Date)
+	if err != nil {
+		return nil, err
+	}
+	first, ok := reader.peek()
+	if !ok {
+		reader.close()
+		return nil, fmt.Errorf("no ticks recorded on %s", cfg.Date.Format(tickDayLayout))
+	}
+	return &ReplayTicker{
+		cfg:        cfg,
+		reader:     reader,
+		subscribed: make(map[string]TickMode),
+		last:       make(map[string]models.Tick),
+		done:       make(chan struct{}),
+		clock:      first.received(),
+		origin:     first.received(),
+	}, nil
+}
+
+// Now returns the replay's virtual time.
+func (r *ReplayTicker) Now() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.now()
+}
+
+// now returns the virtual time. Between ticks of a paced replay the clock
+// runs at Speed times wall time. Callers hold r.mu.
+func (r *ReplayTicker) now() time.Time {
+	if r.paced {
+		if t := r.origin.Add(time.Duration(float64(time.Since(r.wallStart)) * r.cfg.Speed)); t.After(r.clock) {
+			return t
+		}
+	}
+	return r.clock
+}
+
+// Done is closed when playback ends.
+func (r *ReplayTicker) Done() <-chan struct{} {
+	return r.done
+}
+
+// Connect calls the connect handler, where callers subscribe, and starts
+// playback.
+func (r *ReplayTicker) Connect(ctx context.Context) error {
+	r.mu.Lock()
+	if r.started {
+		r.mu.Unlock()
+		return fmt.Errorf("replay of %s already started", r.cfg.Date.Format(tickDayLayout))
+	}
+	r.started = true
+	ctx, r.cancel = context.WithCancel(ctx)
+	onConnect := r.onConnect
+	r.mu.Unlock()
+
+	if onConnect != nil {
+		onConnect()
+	}
+	go r.play(ctx)
+	return nil
+}
+
+// Disconnect stops playback.
+func (r *ReplayTicker) Disconnect() error {
+	r.mu.Lock()
+	cancel := r.cancel
+	if !r.started {
+		// Never played: release the recording and refuse later connects
+		r.started = true
+		r.reader.close()
+		close(r.done)
+	}
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-r.done
+	}
+	return nil
+}
+
+// Subscribe selects symbols to deliver.
+func (r *ReplayTicker) Subscribe(symbols []string, mode TickMode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range symbols {
+		r.subscribed[s] = mode
+	}
+	return nil
+}
+
+// Unsubscribe stops delivering symbols.
+func (r *ReplayTicker) Unsubscribe(symbols []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range symbols {
+		delete(r.subscribed, s)
+	}
+	return nil
+}
+
+// RegisterSymbol is a no-op: recordings are keyed by symbol.
+func (r *ReplayTicker) RegisterSymbol(symbol string, token uint32) {}
+
+// OnTick sets the tick handler.
+func (r *ReplayTicker) OnTick(handler func(models.Tick)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onTick = handler
+}
+
+// OnError sets the error handler, called for damaged recording segments.
+func (r *ReplayTicker) OnError(handler func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onError = handler
+}
+
+// OnConnect sets the connect handler.
+func (r *ReplayTicker) OnConnect(handler func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onConnect = handler
+}
+
+// OnDisconnect sets the disconnect handler, called when playback ends.
+func (r *ReplayTicker) OnDisconnect(handler func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onDisconnect = handler
+}
+
+// play delivers the recording, pacing ticks by their receive times.
+func (r *ReplayTicker) play(ctx context.Context) {
+	defer close(r.done)
+	defer r.reader.close()
+
+	r.mu.Lock()
+	r.wallStart = time.Now()
+	r.paced = r.cfg.Speed > 0
+	r.mu.Unlock()
+
+	for {
+		rec, ok := r.reader.next()
+		if !ok {
+			break
+		}
+		received := rec.received()
+
+		if r.cfg.Speed > 0 {
+			due := r.wallStart.Add(time.Duration(float64(received.Sub(r.origin)) / r.cfg.Speed))
+			if wait := time.Until(due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					r.stop()
+					return
+				case <-timer.C:
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			r.stop()
+			return
+		}
+
+		tick := rec.tick()
+		if tick.Timestamp.IsZero() {
+			tick.Timestamp = received
+		}
+
+		r.mu.Lock()
+		r.clock = received
+		r.last[tick.Symbol] = tick
+		mode, subscribed := r.subscribed[tick.Symbol]
+		handler := r.onTick
+		r.mu.Unlock()
+
+		if !subscribed || handler == nil {
+			continue
+		}
+		if mode != TickModeFull {
+			tick.Depth = nil
+		}
+		handler(tick)
+	}
+
+	r.stop()
+	r.mu.Lock()
+	onError, onDisconnect := r.onError, r.onDisconnect
+	r.mu.Unlock()
+	if truncated := r.reader.truncated(); len(truncated) > 0 && onError != nil {
+		onError(fmt.Errorf("tick recording ends early in %s", strings.Join(truncated, ", ")))
+	}
+	if onDisconnect != nil {
+		onDisconnect()
+	}
+}
+
+// stop freezes the virtual clock.
+func (r *ReplayTicker) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock = r.now()
+	r.paced = false
+}
+
+// DataBroker wraps data so that quotes come from the replay and historical
+// requests end at the replay clock, keeping later data out of a replayed
+// session. Everything else passes through.
+func (r *ReplayTicker) DataBroker(data Broker) Broker {
+	return &replayDataBroker{Broker: data, replay: r}
+}
+
+// replayDataBroker serves market data as of the replay clock.
+type replayDataBroker struct {
+	Broker
+	replay *ReplayTicker
+}
+
+// GetQuote returns the latest replayed tick of symbol as a quote.
+func (b *replayDataBroker) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	b.replay.mu.Lock()
+	tick, ok := b.replay.last[bareSymbol(symbol)]
+	b.replay.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no replayed tick for %s yet", symbol)
+	}
+
+	quote := &models.Quote{
+		Symbol:    tick.Symbol,
+		LTP:       tick.LTP,
+		Open:      tick.Open,
+		High:      tick.High,
+		Low:       tick.Low,
+		Close:     tick.Close,
+		Volume:    tick.Volume,
+		Timestamp: tick.Timestamp,
+	}
+	if tick.Close > 0 {
+		quote.Change = tick.LTP - tick.Close
+		quote.ChangePercent = quote.Change / tick.Close * 100
+	}
+	return quote, nil
+}
+
+// GetHistorical ends the request at the replay clock.
+func (b *replayDataBroker) GetHistorical(ctx context.Context, req HistoricalRequest) ([]models.Candle, error) {
+	now := b.replay.Now()
+	if req.To.IsZero() || req.To.After(now) {
+		req.To = now
+	}
+	if !req.From.Before(req.To) {
+		return nil, nil
+	}
+	return b.Broker.GetHistorical(ctx, req)
+}
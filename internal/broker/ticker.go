@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"net/url"
 	"sync"
 	"time"
 
@@ -19,6 +20,7 @@ type ZerodhaTicker struct {
 	ticker      *kiteticker.Ticker
 	apiKey      string
 	accessToken string
+	url         string
 	
 	// Handlers
 	onTick       func(models.Tick)
@@ -34,6 +36,7 @@ type ZerodhaTicker struct {
 	
 	// Reconnection
 	reconnecting bool
+	stopped      bool // set by Disconnect so closes are not redialed
 	maxRetries   int
 	baseDelay    time.Duration
 	
@@ -47,6 +50,8 @@ type ZerodhaTickerConfig struct {
 	AccessToken string
 	MaxRetries  int
 	BaseDelay   time.Duration
+	// URL overrides the Kite WebSocket endpoint, wss://ws.kite.trade.
+	URL string
 }
 
 // NewZerodhaTicker creates a new Zerodha ticker instance.
@@ -64,6 +69,7 @@ func NewZerodhaTicker(cfg ZerodhaTickerConfig) *ZerodhaTicker {
 	return &ZerodhaTicker{
 		apiKey:       cfg.APIKey,
 		accessToken:  cfg.AccessToken,
+		url:          cfg.URL,
 		subscribed:   make(map[uint32]TickMode),
 		symbolTokens: make(map[string]uint32),
 		tokenSymbols: make(map[uint32]string),
@@ -81,8 +87,18 @@ func (t *ZerodhaTicker) Connect(ctx context.Context) error {
 		return nil
 	}
 	
+	t.stopped = false
+	
 	// Create ticker instance
 	t.ticker = kiteticker.New(t.apiKey, t.accessToken)
+	if t.url != "" {
+		u, err := url.Parse(t.url)
+		if err != nil {
+			t.mu.Unlock()
+			return fmt.Errorf("invalid ticker URL: %w", err)
+		}
+		t.ticker.SetRootURL(*u)
+	}
 	
 	// Channel to signal connection
 	connectedCh := make(chan struct{})
@@ -122,6 +138,7 @@ func (t *ZerodhaTicker) Connect(ctx context.Context) error {
 		t.mu.Lock()
 		wasConnected := t.connected
 		t.connected = false
+		stopped := t.stopped
 		t.mu.Unlock()
 		
 		if t.onDisconnect != nil && wasConnected {
@@ -129,7 +146,9 @@ func (t *ZerodhaTicker) Connect(ctx context.Context) error {
 		}
 		
 		// Attempt reconnection
-		go t.reconnect(ctx)
+		if !stopped {
+			go t.reconnect(ctx)
+		}
 	})
 	
 	t.ticker.OnError(func(err error) {
@@ -179,7 +198,10 @@ func (t *ZerodhaTicker) Disconnect() error {
 	defer t.mu.Unlock()
 	
 	if t.ticker != nil {
+		// Stop the library's own reconnect loop as well as the connection
+		t.stopped = true
 		t.ticker.Close()
+		t.ticker.Stop()
 		t.connected = false
 	}
 	
@@ -340,7 +362,23 @@ func (t *ZerodhaTicker) convertTick(tick kitemodels.Tick) models.Tick {
 		BidPrice:     getBestBid(tick),
 		AskPrice:     getBestAsk(tick),
 		Timestamp:    tick.Timestamp.Time,
+		Depth:        getDepth(tick),
+	}
+}
+
+// getDepth returns the tick's market depth, which Kite sends in full mode only.
+func getDepth(tick kitemodels.Tick) *models.MarketDepth {
+	if tick.Mode != string(kiteticker.ModeFull) {
+		return nil
+	}
+	depth := &models.MarketDepth{}
+	for i, level := range tick.Depth.Buy {
+		depth.Bids[i] = models.DepthLevel{Price: level.Price, Quantity: int64(level.Quantity), Orders: int(level.Orders)}
+	}
+	for i, level := range tick.Depth.Sell {
+		depth.Asks[i] = models.DepthLevel{Price: level.Price, Quantity: int64(level.Quantity), Orders: int(level.Orders)}
 	}
+	return depth
 }
 
 func getBestBid(tick kitemodels.Tick) float64 {
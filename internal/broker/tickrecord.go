@@ -0,0 +1,414 @@
+package broker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+)
+
+// tickFlushInterval bounds how much a crashed recorder loses.
+const tickFlushInterval = time.Second
+
+// tickDayLayout names the per-day directories of a tick recording.
+const tickDayLayout = "2006-01-02"
+
+// TickRecorder writes ticks as gzip-compressed JSON lines, one directory per
+// trading day, for ReplayTicker to play back. Each recorder writes its own
+// segment file per day, named by when it was opened, so the daemon and a
+// watch command can record the same day side by side. Segments are flushed
+// every second; a crash loses at most the unflushed tail.
+//
+// TickRecorder satisfies stream.Consumer, so it can be registered on a Hub,
+// or it can wrap a Ticker with RecordTicks.
+type TickRecorder struct {
+	dir string
+	now func() time.Time
+
+	mu      sync.Mutex
+	day     string
+	file    *os.File
+	gz      *gzip.Writer
+	enc     *json.Encoder
+	flushed time.Time
+	err     error // first write error, returned by Close
+}
+
+// NewTickRecorder creates a recorder writing under dir. Files are created on
+// the first tick.
+func NewTickRecorder(dir string) (*TickRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating tick directory: %w", err)
+	}
+	return &TickRecorder{dir: dir, now: time.Now}, nil
+}
+
+// OnTick records a tick, keeping the first error for Close.
+func (r *TickRecorder) OnTick(tick models.Tick) {
+	r.Record(tick)
+}
+
+// Symbols returns nil: the recorder takes every tick.
+func (r *TickRecorder) Symbols() []string {
+	return nil
+}
+
+// Record writes a tick stamped with the time it was received.
+func (r *TickRecorder) Record(tick models.Tick) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	received := r.now()
+	day := received.In(resilience.IndiaLocation).Format(tickDayLayout)
+	if day != r.day {
+		if err := r.open(day, received); err != nil {
+			return r.fail(err)
+		}
+	}
+	if err := r.enc.Encode(newTickRecord(tick, received)); err != nil {
+		return r.fail(err)
+	}
+	if received.Sub(r.flushed) >= tickFlushInterval {
+		r.flushed = received
+		if err := r.gz.Flush(); err != nil {
+			return r.fail(err)
+		}
+	}
+	return nil
+}
+
+// Flush writes buffered ticks to disk.
+func (r *TickRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gz == nil {
+		return r.err
+	}
+	if err := r.gz.Flush(); err != nil {
+		return r.fail(err)
+	}
+	return r.err
+}
+
+// Close finishes the current segment. It returns the first error seen while
+// recording.
+func (r *TickRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.closeSegment(); err != nil {
+		r.fail(err)
+	}
+	return r.err
+}
+
+// open starts a segment for day, closing the previous one. Callers hold r.mu.
+func (r *TickRecorder) open(day string, received time.Time) error {
+	if err := r.closeSegment(); err != nil {
+		return err
+	}
+	dayDir := filepath.Join(r.dir, day)
+	if err := os.MkdirAll(dayDir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d.jsonl.gz", received.In(resilience.IndiaLocation).Format("150405"), os.Getpid())
+	file, err := os.OpenFile(filepath.Join(dayDir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	r.day = day
+	r.file = file
+	r.gz = gzip.NewWriter(file)
+	r.enc = json.NewEncoder(r.gz)
+	r.flushed = received
+	return nil
+}
+
+// closeSegment finishes the gzip stream and closes the file. Callers hold r.mu.
+func (r *TickRecorder) closeSegment() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.gz.Close()
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.day, r.file, r.gz, r.enc = "", nil, nil, nil
+	return err
+}
+
+func (r *TickRecorder) fail(err error) error {
+	err = fmt.Errorf("recording tick: %w", err)
+	if r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+// RecordTicks wraps t so that every tick is recorded before the tick handler
+// sees it.
+func RecordTicks(t Ticker, r *TickRecorder) Ticker {
+	rt := &recordingTicker{Ticker: t, recorder: r}
+	rt.OnTick(nil)
+	return rt
+}
+
+// recordingTicker records ticks ahead of the tick handler.
+type recordingTicker struct {
+	Ticker
+	recorder *TickRecorder
+}
+
+// OnTick sets the tick handler, which runs after the tick is recorded.
+func (t *recordingTicker) OnTick(handler func(models.Tick)) {
+	t.Ticker.OnTick(func(tick models.Tick) {
+		t.recorder.Record(tick)
+		if handler != nil {
+			handler(tick)
+		}
+	})
+}
+
+// RecordedDays lists the trading days recorded under dir, oldest first.
+func RecordedDays(dir string) ([]time.Time, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var days []time.Time
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if day, err := time.ParseInLocation(tickDayLayout, e.Name(), resilience.IndiaLocation); err == nil {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// tickRecord is the on-disk form of a tick. Keys are short because a full
+// session of full-mode ticks runs to millions of lines.
+type tickRecord struct {
+	Received     int64        `json:"r"` // Unix nanoseconds
+	Symbol       string       `json:"s"`
+	LTP          float64      `json:"p"`
+	Open         float64      `json:"o,omitempty"`
+	High         float64      `json:"h,omitempty"`
+	Low          float64      `json:"l,omitempty"`
+	Close        float64      `json:"c,omitempty"`
+	Volume       int64        `json:"v,omitempty"`
+	BuyQuantity  int64        `json:"bq,omitempty"`
+	SellQuantity int64        `json:"sq,omitempty"`
+	BidPrice     float64      `json:"b,omitempty"`
+	AskPrice     float64      `json:"a,omitempty"`
+	Timestamp    int64        `json:"t,omitempty"` // Unix nanoseconds; exchange time
+	Depth        *depthRecord `json:"d,omitempty"`
+}
+
+// depthRecord holds depth levels as [price, quantity, orders].
+type depthRecord struct {
+	Bids [5][3]float64 `json:"b"`
+	Asks [5][3]float64 `json:"a"`
+}
+
+func newTickRecord(tick models.Tick, received time.Time) tickRecord {
+	rec := tickRecord{
+		Received:     received.UnixNano(),
+		Symbol:       tick.Symbol,
+		LTP:          tick.LTP,
+		Open:         tick.Open,
+		High:         tick.High,
+		Low:          tick.Low,
+		Close:        tick.Close,
+		Volume:       tick.Volume,
+		BuyQuantity:  tick.BuyQuantity,
+		SellQuantity: tick.SellQuantity,
+		BidPrice:     tick.BidPrice,
+		AskPrice:     tick.AskPrice,
+	}
+	if !tick.Timestamp.IsZero() {
+		rec.Timestamp = tick.Timestamp.UnixNano()
+	}
+	if tick.Depth != nil {
+		rec.Depth = &depthRecord{}
+		for i, l := range tick.Depth.Bids {
+			rec.Depth.Bids[i] = [3]float64{l.Price, float64(l.Quantity), float64(l.Orders)}
+		}
+		for i, l := range tick.Depth.Asks {
+			rec.Depth.Asks[i] = [3]float64{l.Price, float64(l.Quantity), float64(l.Orders)}
+		}
+	}
+	return rec
+}
+
+// received returns when the tick was recorded.
+func (rec tickRecord) received() time.Time {
+	return time.Unix(0, rec.Received).In(resilience.IndiaLocation)
+}
+
+func (rec tickRecord) tick() models.Tick {
+	tick := models.Tick{
+		Symbol:       rec.Symbol,
+		LTP:          rec.LTP,
+		Open:         rec.Open,
+		High:         rec.High,
+		Low:          rec.Low,
+		Close:        rec.Close,
+		Volume:       rec.Volume,
+		BuyQuantity:  rec.BuyQuantity,
+		SellQuantity: rec.SellQuantity,
+		BidPrice:     rec.BidPrice,
+		AskPrice:     rec.AskPrice,
+	}
+	if rec.Timestamp != 0 {
+		tick.Timestamp = time.Unix(0, rec.Timestamp).In(resilience.IndiaLocation)
+	}
+	if rec.Depth != nil {
+		tick.Depth = &models.MarketDepth{}
+		for i, l := range rec.Depth.Bids {
+			tick.Depth.Bids[i] = models.DepthLevel{Price: l[0], Quantity: int64(l[1]), Orders: int(l[2])}
+		}
+		for i, l := range rec.Depth.Asks {
+			tick.Depth.Asks[i] = models.DepthLevel{Price: l[0], Quantity: int64(l[1]), Orders: int(l[2])}
+		}
+	}
+	return tick
+}
+
+// tickDayReader merges the segments of one recorded day in receive order.
+type tickDayReader struct {
+	segments []*tickSegment
+}
+
+// openTickDay opens every segment recorded on day.
+func openTickDay(dir string, day time.Time) (*tickDayReader, error) {
+	dayDir := filepath.Join(dir, day.In(resilience.IndiaLocation).Format(tickDayLayout))
+	names, err := filepath.Glob(filepath.Join(dayDir, "*.jsonl.gz"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no ticks recorded in %s", dayDir)
+	}
+	sort.Strings(names)
+
+	r := &tickDayReader{}
+	for _, name := range names {
+		seg, err := openTickSegment(name)
+		if err != nil {
+			r.close()
+			return nil, err
+		}
+		r.segments = append(r.segments, seg)
+	}
+	return r, nil
+}
+
+// next returns the earliest unread tick across segments.
+func (r *tickDayReader) next() (tickRecord, bool) {
+	first := r.first()
+	if first == nil {
+		return tickRecord{}, false
+	}
+	rec := first.head
+	first.advance()
+	return rec, true
+}
+
+// peek returns the earliest unread tick without consuming it.
+func (r *tickDayReader) peek() (tickRecord, bool) {
+	if first := r.first(); first != nil {
+		return first.head, true
+	}
+	return tickRecord{}, false
+}
+
+// first returns the segment holding the earliest unread tick, or nil.
+func (r *tickDayReader) first() *tickSegment {
+	var first *tickSegment
+	for _, seg := range r.segments {
+		if seg.ok && (first == nil || seg.head.Received < first.head.Received) {
+			first = seg
+		}
+	}
+	return first
+}
+
+// truncated lists segments that ended mid-record, usually because the
+// recorder was killed.
+func (r *tickDayReader) truncated() []string {
+	var names []string
+	for _, seg := range r.segments {
+		if seg.truncated {
+			names = append(names, filepath.Base(seg.file.Name()))
+		}
+	}
+	return names
+}
+
+func (r *tickDayReader) close() {
+	for _, seg := range r.segments {
+		seg.file.Close()
+	}
+}
+
+// tickSegment reads one segment file a record at a time.
+type tickSegment struct {
+	file      *os.File
+	scanner   *bufio.Scanner
+	head      tickRecord
+	ok        bool
+	truncated bool
+}
+
+func openTickSegment(name string) (*tickSegment, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	seg := &tickSegment{file: file}
+	gz, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		// A recorder that died before its first flush leaves an empty file
+		seg.truncated = !errors.Is(err, io.EOF)
+		return seg, nil
+	}
+	seg.scanner = bufio.NewScanner(gz)
+	seg.advance()
+	return seg, nil
+}
+
+// advance reads the next record into head. A truncated tail ends the
+// segment rather than the replay.
+func (seg *tickSegment) advance() {
+	seg.ok = false
+	for seg.scanner.Scan() {
+		line := seg.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var rec tickRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			seg.truncated = true
+			return
+		}
+		seg.head, seg.ok = rec, true
+		return
+	}
+	if seg.scanner.Err() != nil {
+		seg.truncated = true
+	}
+}
@@ -0,0 +1,141 @@
+package broker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+)
+
+func TestTickRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	open := time.Date(2024, 3, 4, 9, 15, 0, 0, resilience.IndiaLocation)
+
+	// Two recorders, as the daemon and a watch command would run
+	clocks := [2]time.Time{open, open}
+	var recorders [2]*TickRecorder
+	for i := range recorders {
+		r, err := NewTickRecorder(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		i := i
+		r.now = func() time.Time { return clocks[i] }
+		recorders[i] = r
+	}
+	record := func(i int, at time.Duration, tick models.Tick) {
+		clocks[i] = open.Add(at)
+		if err := recorders[i].Record(tick); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	depth := &models.MarketDepth{}
+	depth.Bids[0] = models.DepthLevel{Price: 1499.95, Quantity: 120, Orders: 3}
+	depth.Asks[0] = models.DepthLevel{Price: 1500.05, Quantity: 80, Orders: 2}
+	record(0, 0, models.Tick{Symbol: "INFY", LTP: 1500, Close: 1490, Volume: 100, Depth: depth})
+	record(1, time.Second, models.Tick{Symbol: "TCS", LTP: 3800})
+	record(0, 2*time.Second, models.Tick{Symbol: "INFY", LTP: 1502, Close: 1490, Volume: 250})
+	record(1, 3*time.Second, models.Tick{Symbol: "TCS", LTP: 3795, Timestamp: open.Add(2 * time.Second)})
+	record(0, 4*time.Second, models.Tick{Symbol: "RELIANCE", LTP: 2500})
+	for _, r := range recorders {
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A recorder killed mid-write leaves a truncated segment
+	if err := os.WriteFile(filepath.Join(dir, "2024-03-04", "091600-1.jsonl.gz"), []byte{0x1f, 0x8b, 0x08}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	days, err := RecordedDays(dir)
+	if err != nil || len(days) != 1 || days[0].Format(tickDayLayout) != "2024-03-04" {
+		t.Fatalf("recorded days %v, %v", days, err)
+	}
+
+	replay, err := NewReplayTicker(ReplayConfig{Dir: dir, Date: open})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replay.Now().Equal(open) {
+		t.Errorf("clock before playback %v, want %v", replay.Now(), open)
+	}
+
+	// A paper broker on the replay clock fills from replayed ticks
+	paper := NewPaperBroker(PaperBrokerConfig{Ticker: replay, DisableCharges: true, Clock: replay.Now})
+	ticker := paper.Ticker()
+	var ticks []models.Tick
+	var clock []time.Time
+	var errs []error
+	ticker.OnTick(func(tick models.Tick) {
+		ticks = append(ticks, tick)
+		clock = append(clock, replay.Now())
+	})
+	ticker.OnError(func(err error) { errs = append(errs, err) })
+	ticker.OnConnect(func() {
+		ticker.Subscribe([]string{"INFY"}, TickModeFull)
+		ticker.Subscribe([]string{"TCS"}, TickModeQuote)
+	})
+	if err := ticker.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-replay.Done()
+
+	want := []struct {
+		symbol string
+		ltp    float64
+		at     time.Duration
+	}{
+		{"INFY", 1500, 0},
+		{"TCS", 3800, time.Second},
+		{"INFY", 1502, 2 * time.Second},
+		{"TCS", 3795, 3 * time.Second},
+	}
+	if len(ticks) != len(want) {
+		t.Fatalf("replayed %d ticks, want %d: %+v", len(ticks), len(want), ticks)
+	}
+	for i, w := range want {
+		if ticks[i].Symbol != w.symbol || ticks[i].LTP != w.ltp || !clock[i].Equal(open.Add(w.at)) {
+			t.Errorf("tick %d: %s %.2f at %v, want %s %.2f at %v", i, ticks[i].Symbol, ticks[i].LTP, clock[i], w.symbol, w.ltp, open.Add(w.at))
+		}
+	}
+	if ticks[0].Depth == nil || ticks[0].Depth.Bids[0] != depth.Bids[0] || ticks[0].Depth.Asks[0] != depth.Asks[0] {
+		t.Errorf("full mode depth %+v", ticks[0].Depth)
+	}
+	if !ticks[1].Timestamp.Equal(open.Add(time.Second)) {
+		t.Errorf("tick without exchange time stamped %v", ticks[1].Timestamp)
+	}
+	if !ticks[3].Timestamp.Equal(open.Add(2 * time.Second)) {
+		t.Errorf("exchange time %v not kept", ticks[3].Timestamp)
+	}
+	if len(errs) != 1 {
+		t.Errorf("truncated segment reported %d times", len(errs))
+	}
+
+	if paper.getPrice("INFY") != 1502 {
+		t.Errorf("paper price of INFY %.2f, want 1502", paper.getPrice("INFY"))
+	}
+	result, err := paper.PlaceOrder(context.Background(), &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy,
+		Type: models.OrderTypeMarket, Product: models.ProductMIS, Quantity: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders, _ := paper.GetOrders(context.Background())
+	for _, o := range orders {
+		if o.ID == result.OrderID && !o.PlacedAt.Equal(open.Add(4*time.Second)) {
+			t.Errorf("order placed at %v, want replay time %v", o.PlacedAt, open.Add(4*time.Second))
+		}
+	}
+
+	quote, err := replay.DataBroker(paper).GetQuote(context.Background(), "NSE:RELIANCE")
+	if err != nil || quote.LTP != 2500 {
+		t.Errorf("replayed quote %+v, %v", quote, err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +20,7 @@ import (
 	kiteconnect "github.com/zerodha/gokiteconnect/v4"
 
 	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/options"
 )
 
 // ZerodhaBroker implements the Broker interface for Zerodha Kite Connect.
@@ -29,6 +31,7 @@ type ZerodhaBroker struct {
 	userID        string
 	accessToken   string
 	tokenPath     string
+	tickerURL     string
 	authenticated bool
 	instruments   map[string]models.Instrument
 	mu            sync.RWMutex
@@ -40,12 +43,19 @@ type ZerodhaConfig struct {
 	APISecret string
 	UserID    string
 	TokenPath string
+	// BaseURL overrides the Kite Connect API endpoint and TickerURL the
+	// WebSocket ticker endpoint, e.g. to point at a kitetest server.
+	BaseURL   string
+	TickerURL string
 }
 
 // NewZerodhaBroker creates a new Zerodha broker instance.
 // It automatically loads any saved session from disk.
 func NewZerodhaBroker(cfg ZerodhaConfig) *ZerodhaBroker {
 	client := kiteconnect.New(cfg.APIKey)
+	if cfg.BaseURL != "" {
+		client.SetBaseURI(strings.TrimRight(cfg.BaseURL, "/"))
+	}
 	
 	tokenPath := cfg.TokenPath
 	if tokenPath == "" {
@@ -59,6 +69,7 @@ func NewZerodhaBroker(cfg ZerodhaConfig) *ZerodhaBroker {
 		apiSecret:   cfg.APISecret,
 		userID:      cfg.UserID,
 		tokenPath:   tokenPath,
+		tickerURL:   cfg.TickerURL,
 		instruments: make(map[string]models.Instrument),
 	}
 	
@@ -378,6 +389,7 @@ func (z *ZerodhaBroker) CreateTicker() (*ZerodhaTicker, error) {
 	return NewZerodhaTicker(ZerodhaTickerConfig{
 		APIKey:      z.apiKey,
 		AccessToken: z.accessToken,
+		URL:         z.tickerURL,
 	}), nil
 }
 
@@ -461,20 +473,26 @@ func (z *ZerodhaBroker) GetHistorical(ctx context.Context, req HistoricalRequest
 	// Map timeframe to Kite interval
 	interval := mapTimeframeToInterval(req.Timeframe)
 	
-	data, err := z.client.GetHistoricalData(int(token), interval, req.From, req.To, false, false)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get historical data: %w", err)
-	}
-	
-	candles := make([]models.Candle, len(data))
-	for i, d := range data {
-		candles[i] = models.Candle{
-			Timestamp: d.Date.Time,
-			Open:      d.Open,
-			High:      d.High,
-			Low:       d.Low,
-			Close:     d.Close,
-			Volume:    int64(d.Volume),
+	// Kite caps the days per request, so long ranges are fetched in chunks
+	candles := make([]models.Candle, 0)
+	for _, chunk := range req.Chunks() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := z.client.GetHistoricalData(int(token), interval, chunk.From, chunk.To, false, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get historical data for %s to %s: %w",
+				chunk.From.Format("2006-01-02"), chunk.To.Format("2006-01-02"), err)
+		}
+		for _, d := range data {
+			candles = append(candles, models.Candle{
+				Timestamp: d.Date.Time,
+				Open:      d.Open,
+				High:      d.High,
+				Low:       d.Low,
+				Close:     d.Close,
+				Volume:    int64(d.Volume),
+			})
 		}
 	}
 	
@@ -659,21 +677,22 @@ func (z *ZerodhaBroker) GetOrders(ctx context.Context) ([]models.Order, error) {
 	result := make([]models.Order, len(orders))
 	for i, o := range orders {
 		result[i] = models.Order{
-			ID:           o.OrderID,
-			Symbol:       o.TradingSymbol,
-			Exchange:     models.Exchange(o.Exchange),
-			Side:         models.OrderSide(o.TransactionType),
-			Type:         models.OrderType(o.OrderType),
-			Product:      models.ProductType(o.Product),
-			Quantity:     int(o.Quantity),
-			Price:        o.Price,
-			TriggerPrice: o.TriggerPrice,
-			Validity:     o.Validity,
-			Tag:          o.Tag,
-			Status:       o.Status,
-			FilledQty:    int(o.FilledQuantity),
-			AveragePrice: o.AveragePrice,
-			PlacedAt:     o.OrderTimestamp.Time,
+			ID:            o.OrderID,
+			Symbol:        o.TradingSymbol,
+			Exchange:      models.Exchange(o.Exchange),
+			Side:          models.OrderSide(o.TransactionType),
+			Type:          models.OrderType(o.OrderType),
+			Product:       models.ProductType(o.Product),
+			Quantity:      int(o.Quantity),
+			Price:         o.Price,
+			TriggerPrice:  o.TriggerPrice,
+			Validity:      o.Validity,
+			Tag:           o.Tag,
+			Status:        o.Status,
+			StatusMessage: o.StatusMessage,
+			FilledQty:     int(o.FilledQuantity),
+			AveragePrice:  o.AveragePrice,
+			PlacedAt:      o.OrderTimestamp.Time,
 		}
 	}
 	
@@ -702,21 +721,22 @@ func (z *ZerodhaBroker) GetOrderHistory(ctx context.Context, from, to time.Time)
 		orderTime := o.OrderTimestamp.Time
 		if (orderTime.Equal(from) || orderTime.After(from)) && (orderTime.Equal(to) || orderTime.Before(to)) {
 			result = append(result, models.Order{
-				ID:           o.OrderID,
-				Symbol:       o.TradingSymbol,
-				Exchange:     models.Exchange(o.Exchange),
-				Side:         models.OrderSide(o.TransactionType),
-				Type:         models.OrderType(o.OrderType),
-				Product:      models.ProductType(o.Product),
-				Quantity:     int(o.Quantity),
-				Price:        o.Price,
-				TriggerPrice: o.TriggerPrice,
-				Validity:     o.Validity,
-				Tag:          o.Tag,
-				Status:       o.Status,
-				FilledQty:    int(o.FilledQuantity),
-				AveragePrice: o.AveragePrice,
-				PlacedAt:     orderTime,
+				ID:            o.OrderID,
+				Symbol:        o.TradingSymbol,
+				Exchange:      models.Exchange(o.Exchange),
+				Side:          models.OrderSide(o.TransactionType),
+				Type:          models.OrderType(o.OrderType),
+				Product:       models.ProductType(o.Product),
+				Quantity:      int(o.Quantity),
+				Price:         o.Price,
+				TriggerPrice:  o.TriggerPrice,
+				Validity:      o.Validity,
+				Tag:           o.Tag,
+				Status:        o.Status,
+				StatusMessage: o.StatusMessage,
+				FilledQty:     int(o.FilledQuantity),
+				AveragePrice:  o.AveragePrice,
+				PlacedAt:      orderTime,
 			})
 		}
 	}
@@ -738,12 +758,12 @@ func (z *ZerodhaBroker) PlaceGTT(ctx context.Context, gtt *models.GTTOrder) (*GT
 		// OCO trigger with upper and lower legs
 		trigger = &kiteconnect.GTTOneCancelsOtherTrigger{
 			Upper: kiteconnect.TriggerParams{
-				TriggerValue: gtt.Orders[0].Price * 1.01, // Upper trigger
+				TriggerValue: legTrigger(gtt.Orders[0], 1.01), // Upper trigger
 				LimitPrice:   gtt.Orders[0].Price,
 				Quantity:     float64(gtt.Orders[0].Quantity),
 			},
 			Lower: kiteconnect.TriggerParams{
-				TriggerValue: gtt.Orders[1].Price * 0.99, // Lower trigger
+				TriggerValue: legTrigger(gtt.Orders[1], 0.99), // Lower trigger
 				LimitPrice:   gtt.Orders[1].Price,
 				Quantity:     float64(gtt.Orders[1].Quantity),
 			},
@@ -789,6 +809,15 @@ func (z *ZerodhaBroker) PlaceGTT(ctx context.Context, gtt *models.GTTOrder) (*GT
 	}, nil
 }
 
+// legTrigger returns a two-leg GTT leg's trigger value, deriving it from the
+// leg's limit price when unset.
+func legTrigger(leg models.GTTOrderLeg, factor float64) float64 {
+	if leg.TriggerPrice > 0 {
+		return leg.TriggerPrice
+	}
+	return leg.Price * factor
+}
+
 // ModifyGTT modifies an existing GTT order.
 func (z *ZerodhaBroker) ModifyGTT(ctx context.Context, gttID string, gtt *models.GTTOrder) error {
 	if !z.IsAuthenticated() {
@@ -804,12 +833,12 @@ func (z *ZerodhaBroker) ModifyGTT(ctx context.Context, gttID string, gtt *models
 	if gtt.TriggerType == "two-leg" && len(gtt.Orders) >= 2 {
 		trigger = &kiteconnect.GTTOneCancelsOtherTrigger{
 			Upper: kiteconnect.TriggerParams{
-				TriggerValue: gtt.Orders[0].Price * 1.01,
+				TriggerValue: legTrigger(gtt.Orders[0], 1.01),
 				LimitPrice:   gtt.Orders[0].Price,
 				Quantity:     float64(gtt.Orders[0].Quantity),
 			},
 			Lower: kiteconnect.TriggerParams{
-				TriggerValue: gtt.Orders[1].Price * 0.99,
+				TriggerValue: legTrigger(gtt.Orders[1], 0.99),
 				LimitPrice:   gtt.Orders[1].Price,
 				Quantity:     float64(gtt.Orders[1].Quantity),
 			},
@@ -889,6 +918,9 @@ func (z *ZerodhaBroker) GetGTTs(ctx context.Context) ([]models.GTTOrder, error)
 				Quantity: int(o.Quantity),
 				Price:    o.Price,
 			}
+			if g.Type == kiteconnect.GTTTypeOCO && j < len(g.Condition.TriggerValues) {
+				orders[j].TriggerPrice = g.Condition.TriggerValues[j]
+			}
 		}
 		
 		triggerType := "single"
@@ -1060,7 +1092,7 @@ func (z *ZerodhaBroker) GetOptionChain(ctx context.Context, symbol string, expir
 	}
 	
 	// Get spot price
-	quote, err := z.GetQuote(ctx, fmt.Sprintf("NSE:%s", symbol))
+	quote, err := z.GetQuote(ctx, options.UnderlyingQuoteSymbol(symbol))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get spot price: %w", err)
 	}
@@ -1098,6 +1130,7 @@ func (z *ZerodhaBroker) GetOptionChain(ctx context.Context, symbol string, expir
 		}
 		
 		optData := &models.OptionData{
+			Symbol: inst.Symbol,
 			LTP:    optQuote.LTP,
 			Volume: optQuote.Volume,
 		}
@@ -1114,13 +1147,19 @@ func (z *ZerodhaBroker) GetOptionChain(ctx context.Context, symbol string, expir
 	for _, s := range strikeMap {
 		strikes = append(strikes, *s)
 	}
+	sort.Slice(strikes, func(i, j int) bool {
+		return strikes[i].Strike < strikes[j].Strike
+	})
 	
-	return &models.OptionChain{
+	chain := &models.OptionChain{
 		Symbol:    symbol,
 		SpotPrice: quote.LTP,
 		Expiry:    expiry,
 		Strikes:   strikes,
-	}, nil
+	}
+	options.ApplyChain(chain, time.Now(), options.DefaultRate)
+	
+	return chain, nil
 }
 
 // GetFuturesChain fetches futures chain for a symbol.
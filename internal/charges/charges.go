@@ -0,0 +1,233 @@
+// Package charges computes brokerage and statutory charges for Indian markets.
+package charges
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"zerodha-trader/internal/models"
+)
+
+// Segment identifies the charge schedule that applies to a trade.
+type Segment string
+
+const (
+	SegmentEquityIntraday Segment = "EQUITY_INTRADAY"
+	SegmentEquityDelivery Segment = "EQUITY_DELIVERY"
+	SegmentFutures        Segment = "FUTURES"
+	SegmentOptions        Segment = "OPTIONS"
+)
+
+// Rates holds the charge rates for a segment. Percentages are of turnover
+// (premium turnover for options).
+type Rates struct {
+	// BrokeragePercent is the percentage brokerage, capped at BrokerageCap per order.
+	// When BrokeragePercent is zero and BrokerageCap is set, brokerage is flat.
+	BrokeragePercent float64
+	BrokerageCap     float64
+	STTBuyPercent    float64
+	STTSellPercent   float64
+	// ExchangeTxnPercent is keyed by exchange; missing exchanges use NSE.
+	ExchangeTxnPercent map[models.Exchange]float64
+	SEBIPerCrore       float64
+	StampBuyPercent    float64
+	// GSTPercent applies to brokerage, exchange transaction and SEBI charges.
+	GSTPercent float64
+}
+
+// DefaultRates returns the standard discount-broker charge schedule.
+func DefaultRates() map[Segment]Rates {
+	return map[Segment]Rates{
+		SegmentEquityDelivery: {
+			STTBuyPercent:      0.1,
+			STTSellPercent:     0.1,
+			ExchangeTxnPercent: map[models.Exchange]float64{models.NSE: 0.00297, models.BSE: 0.00375},
+			SEBIPerCrore:       10,
+			StampBuyPercent:    0.015,
+			GSTPercent:         18,
+		},
+		SegmentEquityIntraday: {
+			BrokeragePercent:   0.03,
+			BrokerageCap:       20,
+			STTSellPercent:     0.025,
+			ExchangeTxnPercent: map[models.Exchange]float64{models.NSE: 0.00297, models.BSE: 0.00375},
+			SEBIPerCrore:       10,
+			StampBuyPercent:    0.003,
+			GSTPercent:         18,
+		},
+		SegmentFutures: {
+			BrokeragePercent:   0.03,
+			BrokerageCap:       20,
+			STTSellPercent:     0.02,
+			ExchangeTxnPercent: map[models.Exchange]float64{models.NFO: 0.00173},
+			SEBIPerCrore:       10,
+			StampBuyPercent:    0.002,
+			GSTPercent:         18,
+		},
+		SegmentOptions: {
+			BrokerageCap:       20,
+			STTSellPercent:     0.1,
+			ExchangeTxnPercent: map[models.Exchange]float64{models.NFO: 0.03503},
+			SEBIPerCrore:       10,
+			StampBuyPercent:    0.003,
+			GSTPercent:         18,
+		},
+	}
+}
+
+// Trade describes a single executed order for charge calculation.
+type Trade struct {
+	Symbol   string
+	Exchange models.Exchange
+	Product  models.ProductType
+	Side     models.OrderSide
+	Quantity int
+	Price    float64
+	// Segment overrides the segment derived from exchange, product and symbol.
+	Segment Segment
+}
+
+// Breakdown itemises the charges for one or more orders.
+type Breakdown struct {
+	Turnover    float64 `json:"turnover"`
+	Brokerage   float64 `json:"brokerage"`
+	STT         float64 `json:"stt"`
+	ExchangeTxn float64 `json:"exchange_txn"`
+	SEBI        float64 `json:"sebi"`
+	GST         float64 `json:"gst"`
+	StampDuty   float64 `json:"stamp_duty"`
+	Total       float64 `json:"total"`
+}
+
+// Add returns the sum of two breakdowns.
+func (b Breakdown) Add(o Breakdown) Breakdown {
+	return Breakdown{
+		Turnover:    b.Turnover + o.Turnover,
+		Brokerage:   b.Brokerage + o.Brokerage,
+		STT:         b.STT + o.STT,
+		ExchangeTxn: b.ExchangeTxn + o.ExchangeTxn,
+		SEBI:        b.SEBI + o.SEBI,
+		GST:         b.GST + o.GST,
+		StampDuty:   b.StampDuty + o.StampDuty,
+		Total:       b.Total + o.Total,
+	}
+}
+
+// Sub returns the difference between two breakdowns.
+func (b Breakdown) Sub(o Breakdown) Breakdown {
+	return Breakdown{
+		Turnover:    b.Turnover - o.Turnover,
+		Brokerage:   b.Brokerage - o.Brokerage,
+		STT:         b.STT - o.STT,
+		ExchangeTxn: b.ExchangeTxn - o.ExchangeTxn,
+		SEBI:        b.SEBI - o.SEBI,
+		GST:         b.GST - o.GST,
+		StampDuty:   b.StampDuty - o.StampDuty,
+		Total:       b.Total - o.Total,
+	}
+}
+
+// Calculator computes charges for trades.
+type Calculator struct {
+	rates map[Segment]Rates
+}
+
+// NewCalculator creates a calculator with the default charge schedule.
+func NewCalculator() *Calculator {
+	return &Calculator{rates: DefaultRates()}
+}
+
+// NewCalculatorWithRates creates a calculator with a custom charge schedule.
+func NewCalculatorWithRates(rates map[Segment]Rates) *Calculator {
+	return &Calculator{rates: rates}
+}
+
+// SegmentFor derives the charge segment for an exchange, product and symbol.
+// NFO symbols ending in CE or PE are options; other NFO symbols are futures.
+func SegmentFor(exchange models.Exchange, product models.ProductType, symbol string) Segment {
+	if exchange == models.NFO {
+		s := strings.ToUpper(symbol)
+		if strings.HasSuffix(s, "CE") || strings.HasSuffix(s, "PE") {
+			return SegmentOptions
+		}
+		return SegmentFutures
+	}
+	if product == models.ProductMIS {
+		return SegmentEquityIntraday
+	}
+	return SegmentEquityDelivery
+}
+
+// Calculate returns the charges for a single executed order.
+func (c *Calculator) Calculate(t Trade) (Breakdown, error) {
+	if t.Quantity <= 0 || t.Price <= 0 {
+		return Breakdown{}, fmt.Errorf("quantity and price must be positive")
+	}
+
+	segment := t.Segment
+	if segment == "" {
+		segment = SegmentFor(t.Exchange, t.Product, t.Symbol)
+	}
+	rates, ok := c.rates[segment]
+	if !ok {
+		return Breakdown{}, fmt.Errorf("no charge schedule for segment %s", segment)
+	}
+
+	turnover := t.Price * float64(t.Quantity)
+	b := Breakdown{Turnover: turnover}
+
+	// Brokerage: percentage capped per order, or flat per order
+	switch {
+	case rates.BrokeragePercent > 0:
+		b.Brokerage = math.Min(turnover*rates.BrokeragePercent/100, rates.BrokerageCap)
+	default:
+		b.Brokerage = rates.BrokerageCap
+	}
+
+	if t.Side == models.OrderSideBuy {
+		b.STT = turnover * rates.STTBuyPercent / 100
+		b.StampDuty = turnover * rates.StampBuyPercent / 100
+	} else {
+		b.STT = turnover * rates.STTSellPercent / 100
+	}
+
+	txnRate, ok := rates.ExchangeTxnPercent[t.Exchange]
+	if !ok {
+		txnRate = rates.ExchangeTxnPercent[models.NSE]
+		if txnRate == 0 {
+			txnRate = rates.ExchangeTxnPercent[models.NFO]
+		}
+	}
+	b.ExchangeTxn = turnover * txnRate / 100
+	b.SEBI = turnover * rates.SEBIPerCrore / 1e7
+	b.GST = (b.Brokerage + b.ExchangeTxn + b.SEBI) * rates.GSTPercent / 100
+
+	// Contract notes show each component in paise
+	b.Brokerage = round2(b.Brokerage)
+	b.STT = round2(b.STT)
+	b.ExchangeTxn = round2(b.ExchangeTxn)
+	b.SEBI = round2(b.SEBI)
+	b.GST = round2(b.GST)
+	b.StampDuty = round2(b.StampDuty)
+	b.Total = round2(b.Brokerage + b.STT + b.ExchangeTxn + b.SEBI + b.GST + b.StampDuty)
+
+	return b, nil
+}
+
+// RoundTrip returns the combined charges for buying and selling qty at the given prices.
+func (c *Calculator) RoundTrip(symbol string, exchange models.Exchange, product models.ProductType, qty int, buyPrice, sellPrice float64) (Breakdown, error) {
+	buy, err := c.Calculate(Trade{Symbol: symbol, Exchange: exchange, Product: product, Side: models.OrderSideBuy, Quantity: qty, Price: buyPrice})
+	if err != nil {
+		return Breakdown{}, err
+	}
+	sell, err := c.Calculate(Trade{Symbol: symbol, Exchange: exchange, Product: product, Side: models.OrderSideSell, Quantity: qty, Price: sellPrice})
+	if err != nil {
+		return Breakdown{}, err
+	}
+	return buy.Add(sell), nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,97 @@
+package charges
+
+import (
+	"math"
+	"testing"
+
+	"zerodha-trader/internal/models"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 0.011
+}
+
+func TestCalculate_EquityIntraday(t *testing.T) {
+	calc := NewCalculator()
+
+	// Buy 100 @ 1000 and sell 100 @ 1010 intraday on NSE
+	b, err := calc.RoundTrip("INFY", models.NSE, models.ProductMIS, 100, 1000, 1010)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Brokerage: 0.03% of 1,00,000 = 30 -> capped at 20 per leg
+	if !approx(b.Brokerage, 40) {
+		t.Errorf("brokerage: expected 40, got %.2f", b.Brokerage)
+	}
+	// STT: 0.025% on sell turnover 1,01,000
+	if !approx(b.STT, 25.25) {
+		t.Errorf("STT: expected 25.25, got %.2f", b.STT)
+	}
+	// Stamp duty: 0.003% on buy turnover 1,00,000
+	if !approx(b.StampDuty, 3) {
+		t.Errorf("stamp duty: expected 3.00, got %.2f", b.StampDuty)
+	}
+	// Exchange txn: 0.00297% of 2,01,000
+	if !approx(b.ExchangeTxn, 5.97) {
+		t.Errorf("exchange txn: expected 5.97, got %.2f", b.ExchangeTxn)
+	}
+
+	sum := b.Brokerage + b.STT + b.ExchangeTxn + b.SEBI + b.GST + b.StampDuty
+	if !approx(b.Total, sum) {
+		t.Errorf("total %.2f does not match components %.2f", b.Total, sum)
+	}
+}
+
+func TestCalculate_DeliveryHasNoBrokerage(t *testing.T) {
+	calc := NewCalculator()
+
+	b, err := calc.Calculate(Trade{Symbol: "TCS", Exchange: models.NSE, Product: models.ProductCNC, Side: models.OrderSideBuy, Quantity: 10, Price: 3500})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Brokerage != 0 {
+		t.Errorf("expected zero delivery brokerage, got %.2f", b.Brokerage)
+	}
+	// STT: 0.1% on buy turnover 35,000
+	if !approx(b.STT, 35) {
+		t.Errorf("STT: expected 35.00, got %.2f", b.STT)
+	}
+}
+
+func TestSegmentFor(t *testing.T) {
+	tests := []struct {
+		exchange models.Exchange
+		product  models.ProductType
+		symbol   string
+		want     Segment
+	}{
+		{models.NSE, models.ProductMIS, "INFY", SegmentEquityIntraday},
+		{models.NSE, models.ProductCNC, "INFY", SegmentEquityDelivery},
+		{models.NFO, models.ProductNRML, "NIFTY24DECFUT", SegmentFutures},
+		{models.NFO, models.ProductMIS, "NIFTY24DEC24000CE", SegmentOptions},
+		{models.NFO, models.ProductNRML, "BANKNIFTY24DEC51000PE", SegmentOptions},
+	}
+
+	for _, tc := range tests {
+		if got := SegmentFor(tc.exchange, tc.product, tc.symbol); got != tc.want {
+			t.Errorf("SegmentFor(%s, %s, %s) = %s, want %s", tc.exchange, tc.product, tc.symbol, got, tc.want)
+		}
+	}
+}
+
+func TestCalculate_OptionsFlatBrokerage(t *testing.T) {
+	calc := NewCalculator()
+
+	b, err := calc.Calculate(Trade{Symbol: "NIFTY24DEC24000CE", Exchange: models.NFO, Product: models.ProductNRML, Side: models.OrderSideSell, Quantity: 75, Price: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Brokerage != 20 {
+		t.Errorf("expected flat ₹20 options brokerage, got %.2f", b.Brokerage)
+	}
+	// STT: 0.1% of 7,500 premium on sell
+	if !approx(b.STT, 7.5) {
+		t.Errorf("STT: expected 7.50, got %.2f", b.STT)
+	}
+}
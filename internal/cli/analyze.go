@@ -2229,12 +2229,8 @@ func calculateBollingerBands(closes []float64, period int, stdDev float64) (uppe
 		diff := closes[i] - middle
 		sum += diff * diff
 	}
-	sd := 0.0
 	if period > 0 {
-		sd = sum / float64(period)
-		if sd > 0 {
-			sd = sd // sqrt would be needed but we'll use variance for simplicity
-		}
+		_ = sum / float64(period) // variance; bands use a range-based approximation below
 	}
 	// Simplified: use range-based approximation
 	high := closes[len(closes)-1]
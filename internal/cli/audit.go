@@ -0,0 +1,374 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/security"
+	"zerodha-trader/internal/store"
+)
+
+// addAuditCommands adds audit log commands.
+func addAuditCommands(rootCmd *cobra.Command, app *App) {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Audit log verification and order trails",
+		Long: `Inspect the tamper-evident audit log.
+
+Every audit entry carries a sequence number and the hash of the entry
+before it, so editing, inserting or deleting entries breaks the chain.`,
+	}
+
+	cmd.PersistentFlags().String("dir", "", "audit log directory (default: ~/.config/zerodha-trader/audit)")
+
+	cmd.AddCommand(newAuditVerifyCmd(app))
+	cmd.AddCommand(newAuditTraceCmd(app))
+
+	rootCmd.AddCommand(cmd)
+}
+
+func auditDir(cmd *cobra.Command) string {
+	if dir, _ := cmd.Flags().GetString("dir"); dir != "" {
+		return dir
+	}
+	return security.DefaultAuditConfig().LogDir
+}
+
+func newAuditVerifyCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the audit log hash chain",
+		Long: `Walk the audit log, rotated files included, and check that every entry
+hashes to its recorded hash and links to the entry before it.
+
+Entries written before hash chaining was introduced are counted as legacy.
+Removing entries from the very end of the log cannot be detected; note the
+last sequence number reported here to compare against later.`,
+		Example: `  trader audit verify
+  trader audit verify --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+
+			result, err := security.VerifyAuditLog(auditDir(cmd))
+			if err != nil {
+				output.Error("Failed to read audit log: %v", err)
+				return err
+			}
+
+			if output.IsJSON() {
+				if err := output.JSON(result); err != nil {
+					return err
+				}
+			} else {
+				output.Bold("Audit Log Verification")
+				output.Printf("  Files:     %d\n", len(result.Files))
+				output.Printf("  Entries:   %d\n", result.Entries)
+				if result.Legacy > 0 {
+					output.Printf("  Legacy:    %d (written before chaining)\n", result.Legacy)
+				}
+				if result.Entries > 0 {
+					output.Printf("  Sequence:  %d – %d\n", result.FirstSeq, result.LastSeq)
+					output.Printf("  Head hash: %s\n", result.LastHash)
+				}
+				if result.Entries > 0 && !result.Anchored {
+					output.Warning("Oldest entry links to a rotated-out file; verified from seq %d", result.FirstSeq)
+				}
+				output.Println()
+
+				if result.Valid() {
+					output.Success("✓ Audit chain intact")
+					return nil
+				}
+
+				table := NewTable(output, "File", "Line", "Seq", "Problem")
+				for _, p := range result.Problems {
+					seq := "-"
+					if p.Seq > 0 {
+						seq = fmt.Sprintf("%d", p.Seq)
+					}
+					table.AddRow(p.File, fmt.Sprintf("%d", p.Line), seq, output.Red(p.Reason))
+				}
+				table.Render()
+				output.Println()
+			}
+
+			if !result.Valid() {
+				output.Error("Audit chain verification failed: %d problem(s)", len(result.Problems))
+				return fmt.Errorf("audit chain verification failed")
+			}
+			return nil
+		},
+	}
+}
+
+// traceEvent is one step in an order's audit trail.
+type traceEvent struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// orderTrace joins everything recorded about one order.
+type orderTrace struct {
+	OrderID   string                          `json:"order_id"`
+	Timeline  []traceEvent                    `json:"timeline"`
+	Audit     []security.AuditEvent           `json:"audit"`
+	Decisions []models.Decision               `json:"decisions"`
+	Trades    []models.Trade                  `json:"trades"`
+	Context   map[string]*models.TradeContext `json:"context,omitempty"`
+	Orders    []models.Order                  `json:"orders"`
+	Warnings  []string                        `json:"warnings,omitempty"`
+}
+
+func newAuditTraceCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "trace <order-id>",
+		Short: "Reconstruct an order's trail",
+		Long: `Reconstruct how an order came about for compliance review.
+
+Joins, in time order:
+  audit      audit log entries for the order
+  decision   the AI decision that placed it, with each agent's result
+             and the risk check
+  trade      trades logged against the order
+  context    market context saved with those trades
+  broker     the order as the broker reports it (today's orders only)`,
+		Example: `  trader audit trace 240115000123456
+  trader audit trace 240115000123456 --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			trace, err := traceOrder(ctx, app, auditDir(cmd), args[0])
+			if err != nil {
+				output.Error("%v", err)
+				return err
+			}
+
+			if output.IsJSON() {
+				return output.JSON(trace)
+			}
+
+			if len(trace.Timeline) == 0 {
+				output.Warning("Nothing recorded for order %s", trace.OrderID)
+				for _, w := range trace.Warnings {
+					output.Dim("  %s", w)
+				}
+				return nil
+			}
+
+			output.Bold("Order Trail: %s", trace.OrderID)
+			output.Println()
+
+			table := NewTable(output, "Time", "Source", "Event", "Detail")
+			for _, e := range trace.Timeline {
+				table.AddRow(FormatDateTime(e.Time), e.Source, e.Event, TruncateString(e.Detail, 70))
+			}
+			table.Render()
+
+			for _, w := range trace.Warnings {
+				output.Println()
+				output.Warning("%s", w)
+			}
+			return nil
+		},
+	}
+}
+
+// traceOrder collects the audit trail of orderID. Missing sources are noted
+// as warnings rather than failing the trace.
+func traceOrder(ctx context.Context, app *App, dir, orderID string) (*orderTrace, error) {
+	trace := &orderTrace{OrderID: orderID}
+	add := func(t time.Time, source, event, detail string) {
+		trace.Timeline = append(trace.Timeline, traceEvent{Time: t, Source: source, Event: event, Detail: detail})
+	}
+
+	audit, err := security.ReadAuditEvents(dir, func(e security.AuditEvent) bool {
+		return e.OrderID == orderID
+	})
+	if err != nil {
+		trace.Warnings = append(trace.Warnings, fmt.Sprintf("audit log: %v", err))
+	}
+	trace.Audit = audit
+	for _, e := range audit {
+		add(e.Timestamp, "audit", string(e.EventType), auditDetail(e))
+	}
+
+	if app.Store == nil {
+		trace.Warnings = append(trace.Warnings, "store not initialized: decisions and trades omitted")
+	} else {
+		if err := traceStore(ctx, app.Store, trace, add); err != nil {
+			return nil, err
+		}
+	}
+
+	if app.Broker == nil || !app.Broker.IsAuthenticated() {
+		trace.Warnings = append(trace.Warnings, "broker not logged in: broker order history omitted")
+	} else {
+		from := time.Now().Add(-24 * time.Hour)
+		for _, e := range trace.Timeline {
+			if e.Time.Before(from) {
+				from = e.Time
+			}
+		}
+		orders, err := app.Broker.GetOrderHistory(ctx, from, time.Now())
+		if err != nil {
+			trace.Warnings = append(trace.Warnings, fmt.Sprintf("broker order history: %v", err))
+		}
+		for _, o := range orders {
+			if o.ID != orderID {
+				continue
+			}
+			trace.Orders = append(trace.Orders, o)
+			detail := fmt.Sprintf("%s %d %s %s", o.Side, o.Quantity, o.Symbol, o.Type)
+			if o.Price > 0 {
+				detail += fmt.Sprintf(" @ %.2f", o.Price)
+			}
+			if o.FilledQty > 0 {
+				detail += fmt.Sprintf(", filled %d @ %.2f", o.FilledQty, o.AveragePrice)
+			}
+			if o.StatusMessage != "" {
+				detail += ": " + o.StatusMessage
+			}
+			add(o.PlacedAt, "broker", "order "+o.Status, detail)
+		}
+	}
+
+	sort.SliceStable(trace.Timeline, func(i, j int) bool {
+		return trace.Timeline[i].Time.Before(trace.Timeline[j].Time)
+	})
+	return trace, nil
+}
+
+// traceStore adds the decisions, trades and trade context behind an order.
+func traceStore(ctx context.Context, ds store.DataStore, trace *orderTrace, add func(time.Time, string, string, string)) error {
+	decisions, err := ds.GetDecisions(ctx, store.DecisionFilter{OrderID: trace.OrderID})
+	if err != nil {
+		return fmt.Errorf("fetching decisions: %w", err)
+	}
+
+	trades, err := ds.GetTrades(ctx, store.TradeFilter{OrderID: trace.OrderID})
+	if err != nil {
+		return fmt.Errorf("fetching trades: %w", err)
+	}
+	trace.Trades = trades
+
+	seen := make(map[string]bool)
+	for _, d := range decisions {
+		seen[d.ID] = true
+	}
+	for _, t := range trades {
+		if t.DecisionID == "" || seen[t.DecisionID] {
+			continue
+		}
+		d, err := ds.GetDecisionByID(ctx, t.DecisionID)
+		if err != nil {
+			return fmt.Errorf("fetching decision %s: %w", t.DecisionID, err)
+		}
+		if d != nil {
+			seen[d.ID] = true
+			decisions = append(decisions, *d)
+		}
+	}
+	trace.Decisions = decisions
+
+	for _, d := range decisions {
+		names := make([]string, 0, len(d.AgentResults))
+		for name := range d.AgentResults {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			r := d.AgentResults[name]
+			if r == nil {
+				continue
+			}
+			at := r.Timestamp
+			if at.IsZero() {
+				at = d.Timestamp
+			}
+			add(at, "agent:"+name, fmt.Sprintf("%s %.0f%%", r.Recommendation, r.Confidence), r.Reasoning)
+		}
+
+		if d.RiskCheck != nil {
+			event, detail := "risk approved", ""
+			if !d.RiskCheck.Approved {
+				event = "risk rejected"
+			}
+			if len(d.RiskCheck.Violations) > 0 {
+				detail = strings.Join(d.RiskCheck.Violations, "; ")
+			}
+			add(d.Timestamp, "risk", event, detail)
+		}
+
+		detail := d.Reasoning
+		if d.EntryPrice > 0 {
+			detail = fmt.Sprintf("entry %.2f, stop %.2f. %s", d.EntryPrice, d.StopLoss, d.Reasoning)
+		}
+		add(d.Timestamp, "decision", fmt.Sprintf("%s %s %.0f%%", d.Action, d.Symbol, d.Confidence), detail)
+	}
+
+	for _, t := range trades {
+		add(t.Timestamp, "trade", fmt.Sprintf("%s %d %s", t.Side, t.Quantity, t.Symbol),
+			fmt.Sprintf("entry %.2f, exit %.2f, P&L %.2f", t.EntryPrice, t.ExitPrice, t.PnL))
+
+		tc, err := ds.GetTradeContext(ctx, t.ID)
+		if err != nil {
+			return fmt.Errorf("fetching trade context: %w", err)
+		}
+		if tc == nil {
+			continue
+		}
+		if trace.Context == nil {
+			trace.Context = make(map[string]*models.TradeContext)
+		}
+		trace.Context[t.ID] = tc
+		detail := fmt.Sprintf("NIFTY %.2f, sector %.2f, VIX %.2f", tc.NiftyLevel, tc.SectorIndex, tc.VIXLevel)
+		if tc.MarketTrend != "" {
+			detail += ", " + tc.MarketTrend
+		}
+		if tc.NewsEvents != "" {
+			detail += ". " + tc.NewsEvents
+		}
+		add(t.Timestamp, "context", "market context", detail)
+	}
+
+	return nil
+}
+
+// auditDetail summarizes an audit entry in one line.
+func auditDetail(e security.AuditEvent) string {
+	var parts []string
+	if e.Action != "" {
+		parts = append(parts, e.Action)
+	}
+	if e.Symbol != "" {
+		parts = append(parts, e.Symbol)
+	}
+
+	keys := make([]string, 0, len(e.Details))
+	for k := range e.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, e.Details[k]))
+	}
+
+	if !e.Success {
+		parts = append(parts, "failed: "+e.ErrorMsg)
+	}
+	if e.Seq > 0 {
+		parts = append(parts, fmt.Sprintf("seq %d", e.Seq))
+	}
+	return strings.Join(parts, " ")
+}
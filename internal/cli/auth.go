@@ -16,6 +16,7 @@ import (
 
 	"zerodha-trader/internal/broker"
 	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/trading"
 )
 
 // addAuthCommands adds authentication commands.
@@ -46,13 +47,13 @@ Otherwise, it will open a browser window for OAuth authentication.`,
 			defer cancel()
 
 			// Check if broker is configured
-			if app.Broker == nil {
+			if app.DataBroker == nil {
 				output.Error("Broker not configured. Please check your credentials.toml")
 				return fmt.Errorf("broker not configured")
 			}
 
 			// Check if already authenticated
-			if app.Broker.IsAuthenticated() {
+			if app.DataBroker.IsAuthenticated() {
 				return showLoginStatus(ctx, app, output)
 			}
 
@@ -72,7 +73,7 @@ Otherwise, it will open a browser window for OAuth authentication.`,
 			if !forceBrowser && password != "" && totpSecret != "" {
 				output.Info("Auto-login credentials found, attempting auto-login...")
 				
-				zb, ok := app.Broker.(*broker.ZerodhaBroker)
+				zb, ok := app.DataBroker.(*broker.ZerodhaBroker)
 				if ok {
 					if err := zb.AutoLogin(ctx, password, totpSecret); err == nil {
 						output.Success("✓ Login successful!")
@@ -86,7 +87,7 @@ Otherwise, it will open a browser window for OAuth authentication.`,
 			}
 
 			// Fall back to browser OAuth flow
-			err := app.Broker.Login(ctx)
+			err := app.DataBroker.Login(ctx)
 			if err == nil {
 				output.Success("✓ Already logged in!")
 				return nil
@@ -149,7 +150,7 @@ func completeLogin(ctx context.Context, app *App, output *Output, token string)
 	output.Info("Completing login with token...")
 
 	// Get the Zerodha broker to call CompleteLogin
-	zb, ok := app.Broker.(*broker.ZerodhaBroker)
+	zb, ok := app.DataBroker.(*broker.ZerodhaBroker)
 	if !ok {
 		output.Error("Broker is not Zerodha broker")
 		return fmt.Errorf("invalid broker type")
@@ -183,21 +184,21 @@ func showLoginStatus(ctx context.Context, app *App, output *Output) error {
 	
 	go func() {
 		defer wg.Done()
-		if b, err := app.Broker.GetBalance(ctx); err == nil {
+		if b, err := app.DataBroker.GetBalance(ctx); err == nil {
 			res.balance = b
 		}
 	}()
 	
 	go func() {
 		defer wg.Done()
-		if p, err := app.Broker.GetPositions(ctx); err == nil {
+		if p, err := app.DataBroker.GetPositions(ctx); err == nil {
 			res.positions = p
 		}
 	}()
 	
 	go func() {
 		defer wg.Done()
-		if h, err := app.Broker.GetHoldings(ctx); err == nil {
+		if h, err := app.DataBroker.GetHoldings(ctx); err == nil {
 			res.holdings = h
 		}
 	}()
@@ -279,13 +280,13 @@ You will need to login again to use trading features.`,
 			defer cancel()
 
 			// Check if broker is configured
-			if app.Broker == nil {
+			if app.DataBroker == nil {
 				output.Warning("No active session found.")
 				return nil
 			}
 
 			// Check if authenticated
-			if !app.Broker.IsAuthenticated() {
+			if !app.DataBroker.IsAuthenticated() {
 				output.Warning("Not currently logged in.")
 				return nil
 			}
@@ -293,7 +294,7 @@ You will need to login again to use trading features.`,
 			output.Info("Logging out...")
 
 			// Perform logout
-			if err := app.Broker.Logout(ctx); err != nil {
+			if err := app.DataBroker.Logout(ctx); err != nil {
 				output.Error("Logout failed: %v", err)
 				return err
 			}
@@ -340,13 +341,13 @@ To get your TOTP secret:
 			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 			defer cancel()
 
-			if app.Broker == nil {
+			if app.DataBroker == nil {
 				output.Error("Broker not configured. Check credentials.toml")
 				return fmt.Errorf("broker not configured")
 			}
 
 			// Check if already authenticated
-			if app.Broker.IsAuthenticated() {
+			if app.DataBroker.IsAuthenticated() {
 				return showLoginStatus(ctx, app, output)
 			}
 
@@ -369,7 +370,7 @@ To get your TOTP secret:
 
 			output.Info("Performing auto-login...")
 
-			zb, ok := app.Broker.(*broker.ZerodhaBroker)
+			zb, ok := app.DataBroker.(*broker.ZerodhaBroker)
 			if !ok {
 				output.Error("Auto-login only works with Zerodha broker")
 				return fmt.Errorf("invalid broker type")
@@ -396,12 +397,15 @@ func newAuthStatusCmd(app *App) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			output := NewOutput(cmd)
 
-			if app.Broker == nil {
+			if app.DataBroker == nil {
 				output.Error("Broker not configured")
 				return nil
 			}
 
-			if !app.Broker.IsAuthenticated() {
+			printModeBanner(output, app)
+			output.Println()
+
+			if !app.DataBroker.IsAuthenticated() {
 				output.Warning("Not authenticated")
 				output.Println()
 				output.Info("Run 'trader login' or 'trader autologin' to authenticate")
@@ -415,7 +419,7 @@ func newAuthStatusCmd(app *App) *cobra.Command {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 
-			balance, err := app.Broker.GetBalance(ctx)
+			balance, err := app.DataBroker.GetBalance(ctx)
 			if err != nil {
 				output.Warning("Session may be expired: %v", err)
 				output.Info("Run 'trader login' or 'trader autologin' to re-authenticate")
@@ -462,3 +466,37 @@ func formatDuration(d time.Duration) string {
 	}
 	return fmt.Sprintf("%dm", minutes)
 }
+
+// printModeBanner shows the trading mode and which broker takes orders.
+func printModeBanner(output *Output, app *App) {
+	if app.Replay != nil {
+		output.Warning("⏪ REPLAY MODE (%s)", app.Replay.Now().Format("2006-01-02"))
+	} else if app.Config.IsPaperMode() {
+		output.Warning("📝 PAPER TRADING MODE")
+	} else {
+		output.Printf("%s\n", output.Red("⚡ LIVE TRADING MODE"))
+	}
+	output.Printf("  Orders:      %s\n", activeBrokerName(app))
+	if app.Replay != nil {
+		output.Printf("  Market data: recorded ticks\n")
+	} else if app.DataBroker != nil {
+		output.Printf("  Market data: Zerodha Kite\n")
+	}
+}
+
+// activeBrokerName describes the broker behind the risk gate.
+func activeBrokerName(app *App) string {
+	switch trading.UnwrapBroker(app.Broker).(type) {
+	case nil:
+		return "none"
+	case *broker.PaperBroker:
+		if app.Replay != nil {
+			return "in-memory paper account (simulated)"
+		}
+		return fmt.Sprintf("paper account %q (simulated)", paperAccountName(app.Config))
+	case *broker.ZerodhaBroker:
+		return "Zerodha Kite (real orders)"
+	default:
+		return fmt.Sprintf("%T", trading.UnwrapBroker(app.Broker))
+	}
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/trading"
+)
+
+func newDataBackfillCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backfill [symbols...]",
+		Short: "Backfill historical candles into the local store",
+		Long: `Load historical candles into the local candle store for backtests and
+offline analysis.
+
+Ranges are split into the chunks Kite serves per request and fetched at
+its rate limit of 3 requests per second. A symbol that already has candles
+resumes after the newest one, and trading sessions missing before it are
+fetched as well. Sessions still missing afterwards, such as days before a
+listing, are reported.`,
+		Example: `  trader data backfill --watchlist nifty50 --timeframe 5min --from 2024-01-01
+  trader data backfill RELIANCE INFY --timeframe 1day --days 1000
+  trader data backfill TCS --timeframe 15min --from 2024-01-01 --to 2024-06-30 --full`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			exchange, _ := cmd.Flags().GetString("exchange")
+			timeframe, _ := cmd.Flags().GetString("timeframe")
+			watchlistName, _ := cmd.Flags().GetString("watchlist")
+			fromStr, _ := cmd.Flags().GetString("from")
+			toStr, _ := cmd.Flags().GetString("to")
+			days, _ := cmd.Flags().GetInt("days")
+			full, _ := cmd.Flags().GetBool("full")
+
+			if app.Broker == nil {
+				output.Error("Broker not configured. Run 'trader login' first.")
+				return fmt.Errorf("broker not configured")
+			}
+			if app.Store == nil {
+				output.Error("Store not initialized")
+				return fmt.Errorf("store not initialized")
+			}
+
+			var symbols []string
+			if watchlistName != "" {
+				symbols = getPredefinedWatchlist(watchlistName, app, ctx)
+				if len(symbols) == 0 {
+					output.Error("Watchlist '%s' not found or empty", watchlistName)
+					return fmt.Errorf("watchlist not found")
+				}
+			}
+			for _, s := range args {
+				symbols = append(symbols, strings.ToUpper(s))
+			}
+			if len(symbols) == 0 {
+				output.Error("Specify symbols or --watchlist")
+				return fmt.Errorf("no symbols")
+			}
+
+			to := time.Now().In(resilience.IndiaLocation)
+			if toStr != "" {
+				day, err := time.ParseInLocation("2006-01-02", toStr, resilience.IndiaLocation)
+				if err != nil {
+					output.Error("Invalid --to date: %s (use YYYY-MM-DD)", toStr)
+					return err
+				}
+				// The whole day, up to now
+				if end := day.AddDate(0, 0, 1).Add(-time.Second); end.Before(to) {
+					to = end
+				}
+			}
+			from := to.AddDate(0, 0, -days)
+			if fromStr != "" {
+				day, err := time.ParseInLocation("2006-01-02", fromStr, resilience.IndiaLocation)
+				if err != nil {
+					output.Error("Invalid --from date: %s (use YYYY-MM-DD)", fromStr)
+					return err
+				}
+				from = day
+			}
+
+			if !output.IsJSON() {
+				output.Bold("Backfilling %d symbol(s), %s candles", len(symbols), timeframe)
+				output.Printf("  Range: %s to %s\n", FormatDate(from), FormatDate(to))
+				if full {
+					output.Printf("  Mode:  full refetch\n")
+				} else {
+					output.Printf("  Mode:  resume and fill gaps\n")
+				}
+				output.Println()
+			}
+
+			manager := trading.NewBackfillManager(app.Broker, app.Store)
+			results, err := manager.Backfill(ctx, trading.BackfillRequest{
+				Symbols:   symbols,
+				Exchange:  models.Exchange(exchange),
+				Timeframe: timeframe,
+				From:      from,
+				To:        to,
+				Full:      full,
+				Progress: func(p trading.BackfillProgress) {
+					if output.IsJSON() {
+						return
+					}
+					line := fmt.Sprintf("  [%d/%d] %-12s %s → %s", p.Chunk, p.Chunks, p.Symbol,
+						FormatDate(p.From), FormatDate(p.To))
+					if p.Err != nil {
+						output.Printf("%s  %s\n", line, output.Red("failed: "+p.Err.Error()))
+						return
+					}
+					output.Printf("%s  %d candles\n", line, p.Candles)
+				},
+			})
+			if err != nil && len(results) == 0 {
+				output.Error("Backfill failed: %v", err)
+				return err
+			}
+
+			if output.IsJSON() {
+				type symbolResult struct {
+					Symbol   string      `json:"symbol"`
+					Resumed  *time.Time  `json:"resumed,omitempty"`
+					Gaps     int         `json:"gaps_filled"`
+					Requests int         `json:"requests"`
+					Candles  int         `json:"candles"`
+					Missing  []time.Time `json:"missing_sessions"`
+					Error    string      `json:"error,omitempty"`
+				}
+				out := make([]symbolResult, len(results))
+				for i, r := range results {
+					out[i] = symbolResult{Symbol: r.Symbol, Gaps: r.Gaps, Requests: r.Requests, Candles: r.Candles, Missing: r.Missing}
+					if !r.Resumed.IsZero() {
+						resumed := r.Resumed
+						out[i].Resumed = &resumed
+					}
+					if r.Err != nil {
+						out[i].Error = r.Err.Error()
+					}
+				}
+				if jsonErr := output.JSON(out); jsonErr != nil {
+					return jsonErr
+				}
+				return err
+			}
+
+			output.Println()
+			table := NewTable(output, "Symbol", "Resumed From", "Gaps", "Requests", "Candles", "Still Missing")
+			failed := 0
+			for _, r := range results {
+				resumed := "-"
+				if !r.Resumed.IsZero() {
+					resumed = FormatDateTime(r.Resumed)
+				}
+				missing := output.Green("0")
+				if len(r.Missing) > 0 {
+					missing = output.Yellow(fmt.Sprintf("%d", len(r.Missing)))
+				}
+				symbol := r.Symbol
+				if r.Err != nil {
+					failed++
+					symbol = output.Red(symbol)
+				}
+				table.AddRow(symbol, resumed, fmt.Sprintf("%d", r.Gaps), fmt.Sprintf("%d", r.Requests),
+					fmt.Sprintf("%d", r.Candles), missing)
+			}
+			table.Render()
+
+			for _, r := range results {
+				if len(r.Missing) == 0 {
+					continue
+				}
+				dates := make([]string, 0, 5)
+				for i, day := range r.Missing {
+					if i == 5 {
+						dates = append(dates, fmt.Sprintf("… %d more", len(r.Missing)-5))
+						break
+					}
+					dates = append(dates, FormatDate(day))
+				}
+				output.Dim("  %s missing: %s", r.Symbol, strings.Join(dates, ", "))
+			}
+			output.Println()
+
+			if err != nil {
+				output.Error("Backfill stopped: %v", err)
+				return err
+			}
+			if failed > 0 {
+				output.Warning("%d symbol(s) had failed requests; run again to retry", failed)
+				return nil
+			}
+			output.Success("✓ Backfill complete")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("exchange", "e", "NSE", "Exchange (NSE, BSE, NFO, CDS, MCX)")
+	cmd.Flags().StringP("timeframe", "t", "1day", "Timeframe (1min, 5min, 15min, 30min, 1hour, 1day)")
+	cmd.Flags().StringP("watchlist", "w", "", "Watchlist to backfill (e.g. nifty50)")
+	cmd.Flags().String("from", "", "Start date (YYYY-MM-DD)")
+	cmd.Flags().String("to", "", "End date (YYYY-MM-DD, default: now)")
+	cmd.Flags().IntP("days", "d", 365, "Days of history when --from is not given")
+	cmd.Flags().Bool("full", false, "Refetch the whole range instead of resuming")
+
+	return cmd
+}
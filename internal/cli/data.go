@@ -110,7 +110,8 @@ func newDataCmd(app *App) *cobra.Command {
 Data is cached locally for faster subsequent access.`,
 		Example: `  trader data RELIANCE
   trader data INFY --timeframe 15min --days 30
-  trader data NIFTY50 --timeframe 1day --days 365`,
+  trader data NIFTY50 --timeframe 1day --days 365
+  trader data backfill --watchlist nifty50 --timeframe 5min --from 2024-01-01`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			output := NewOutput(cmd)
@@ -172,6 +173,8 @@ Data is cached locally for faster subsequent access.`,
 	cmd.Flags().IntP("days", "d", 30, "Number of days of history")
 	cmd.Flags().IntP("limit", "l", 0, "Limit number of candles to display (0 for all)")
 
+	cmd.AddCommand(newDataBackfillCmd(app))
+
 	return cmd
 }
 
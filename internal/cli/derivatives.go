@@ -4,12 +4,17 @@ package cli
 import (
 	"context"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"zerodha-trader/internal/broker"
 	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/options"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/trading"
 )
 
 // addDerivativesCommands adds derivatives trading commands.
@@ -105,9 +110,9 @@ func displayOptionChain(output *Output, chain interface{}, strikes int) error {
 	output.Printf("  Spot: %s  Expiry: %s\n\n", FormatPrice(oc.SpotPrice), FormatDate(oc.Expiry))
 
 	// Header
-	output.Printf("%-10s %-10s │ %-10s │ %-10s %-10s\n",
-		"Call LTP", "Call Vol", "Strike", "Put LTP", "Put Vol")
-	output.Println(strings.Repeat("─", 60))
+	output.Printf("%-9s %-7s %-7s %-9s │ %-10s │ %-9s %-7s %-7s %-9s\n",
+		"Call Vol", "Call IV", "Call Δ", "Call LTP", "Strike", "Put LTP", "Put Δ", "Put IV", "Put Vol")
+	output.Println(strings.Repeat("─", 90))
 
 	// Find ATM strike
 	atmStrike := oc.SpotPrice
@@ -131,22 +136,11 @@ func displayOptionChain(output *Output, chain interface{}, strikes int) error {
 			strikeStr = output.BoldText(strikeStr)
 		}
 
-		callLTP := "-"
-		callVol := "-"
-		if s.Call != nil {
-			callLTP = FormatPrice(s.Call.LTP)
-			callVol = FormatVolume(s.Call.Volume)
-		}
-
-		putLTP := "-"
-		putVol := "-"
-		if s.Put != nil {
-			putLTP = FormatPrice(s.Put.LTP)
-			putVol = FormatVolume(s.Put.Volume)
-		}
+		callLTP, callVol, callIV, callDelta := optionChainCells(s.Call)
+		putLTP, putVol, putIV, putDelta := optionChainCells(s.Put)
 
-		output.Printf("%-10s %-10s │ %-10s │ %-10s %-10s\n",
-			callLTP, callVol, strikeStr, putLTP, putVol)
+		output.Printf("%-9s %-7s %-7s %-9s │ %-10s │ %-9s %-7s %-7s %-9s\n",
+			callVol, callIV, callDelta, callLTP, strikeStr, putLTP, putDelta, putIV, putVol)
 
 		displayed++
 		if displayed >= strikes*2 {
@@ -157,38 +151,244 @@ func displayOptionChain(output *Output, chain interface{}, strikes int) error {
 	return nil
 }
 
+// optionChainCells formats one side of an option chain row. IV and delta are
+// blank when the LTP admitted no implied volatility.
+func optionChainCells(d *models.OptionData) (ltp, vol, iv, delta string) {
+	if d == nil {
+		return "-", "-", "-", "-"
+	}
+	ltp = FormatPrice(d.LTP)
+	vol = FormatVolume(d.Volume)
+	iv, delta = "-", "-"
+	if d.IV > 0 {
+		iv = fmt.Sprintf("%.1f%%", d.IV)
+		delta = fmt.Sprintf("%.2f", d.Greeks.Delta)
+	}
+	return ltp, vol, iv, delta
+}
+
+// optionGreeksResult is the JSON shape of `options greeks` for one contract.
+type optionGreeksResult struct {
+	Symbol     string              `json:"symbol"`
+	Type       options.Kind        `json:"type"`
+	Strike     float64             `json:"strike"`
+	Expiry     time.Time           `json:"expiry"`
+	Underlying float64             `json:"underlying"`
+	Price      float64             `json:"price"`
+	IV         float64             `json:"iv"`
+	Intrinsic  float64             `json:"intrinsic"`
+	TimeValue  float64             `json:"time_value"`
+	DaysToExp  float64             `json:"days_to_expiry"`
+	Greeks     models.OptionGreeks `json:"greeks"`
+}
+
 func newOptionsGreeksCmd(app *App) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "greeks",
 		Short: "Calculate option Greeks",
 		Long: `Calculate option Greeks (Delta, Gamma, Theta, Vega, Rho).
 
-Can calculate for a specific option or portfolio of options.`,
+Prices the option with Black-Scholes on the spot (or Black-76 with --futures),
+solving implied volatility from the option's LTP. Underlying and option
+prices are fetched from the broker unless given with --spot and --price;
+--iv prices the option at a given volatility instead of solving for it.
+
+Theta is per calendar day, Vega per 1% volatility and Rho per 1% rate.
+With --portfolio, sums Greeks across open F&O positions.`,
 		Example: `  trader options greeks --symbol NIFTY --strike 19500 --type CE --expiry 2024-01-25
+  trader options greeks --symbol NIFTY --strike 19500 --type PE --expiry 2024-01-25 --spot 19420 --price 112.5
+  trader options greeks --symbol BANKNIFTY --strike 44000 --type CE --expiry 2024-01-25 --spot 44150 --iv 13.5
   trader options greeks --portfolio`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if portfolio, _ := cmd.Flags().GetBool("portfolio"); portfolio {
+				return showPortfolioGreeks(ctx, output, app)
+			}
 
 			symbol, _ := cmd.Flags().GetString("symbol")
 			strike, _ := cmd.Flags().GetFloat64("strike")
 			optType, _ := cmd.Flags().GetString("type")
+			expiryStr, _ := cmd.Flags().GetString("expiry")
+			spot, _ := cmd.Flags().GetFloat64("spot")
+			price, _ := cmd.Flags().GetFloat64("price")
+			ivPercent, _ := cmd.Flags().GetFloat64("iv")
+			ratePercent, _ := cmd.Flags().GetFloat64("rate")
+			futures, _ := cmd.Flags().GetBool("futures")
+
+			symbol = strings.ToUpper(symbol)
+			if symbol == "" || strike <= 0 || expiryStr == "" {
+				output.Error("--symbol, --strike and --expiry are required")
+				return fmt.Errorf("missing option contract")
+			}
+			kind, err := options.ParseKind(optType)
+			if err != nil {
+				output.Error("%v", err)
+				return err
+			}
+			expiry, err := time.ParseInLocation("2006-01-02", expiryStr, resilience.IndiaLocation)
+			if err != nil {
+				output.Error("Invalid expiry format. Use YYYY-MM-DD")
+				return err
+			}
+
+			if (spot <= 0 || (price <= 0 && ivPercent <= 0)) && app.Broker == nil {
+				output.Error("Broker not configured. Run 'trader login' first.")
+				return fmt.Errorf("broker not configured")
+			}
+			if spot <= 0 {
+				if futures {
+					output.Error("--futures requires --spot with the futures price")
+					return fmt.Errorf("missing futures price")
+				}
+				quote, err := app.Broker.GetQuote(ctx, options.UnderlyingQuoteSymbol(symbol))
+				if err != nil {
+					output.Error("Failed to get underlying quote: %v", err)
+					return err
+				}
+				spot = quote.LTP
+			}
+
+			now := time.Now()
+			in := options.Inputs{
+				Kind:       kind,
+				Model:      options.BlackScholes,
+				Underlying: spot,
+				Strike:     strike,
+				T:          options.YearsToExpiry(now, expiry),
+				Rate:       ratePercent / 100,
+			}
+			if futures {
+				in.Model = options.Black76
+			}
+
+			if ivPercent > 0 {
+				in.Vol = ivPercent / 100
+				price = options.Price(in)
+			} else {
+				if price <= 0 {
+					price, err = optionLTP(ctx, app, symbol, expiry, strike, kind)
+					if err != nil {
+						output.Error("Failed to get option price: %v", err)
+						return err
+					}
+				}
+				in.Vol, err = options.ImpliedVol(in, price)
+				if err != nil {
+					output.Error("Cannot solve implied volatility for price %s: %v", FormatPrice(price), err)
+					return err
+				}
+			}
+
+			intrinsic := math.Max(spot-strike, 0)
+			if kind == options.Put {
+				intrinsic = math.Max(strike-spot, 0)
+			}
+			result := optionGreeksResult{
+				Symbol:     symbol,
+				Type:       kind,
+				Strike:     strike,
+				Expiry:     options.ExpiryClose(expiry),
+				Underlying: spot,
+				Price:      price,
+				IV:         in.Vol * 100,
+				Intrinsic:  intrinsic,
+				TimeValue:  price - intrinsic,
+				DaysToExp:  in.T * 365,
+				Greeks:     options.Greeks(in),
+			}
 
+			if output.IsJSON() {
+				return output.JSON(result)
+			}
+
+			g := result.Greeks
 			output.Bold("Option Greeks")
-			output.Printf("  %s %s %.0f\n\n", symbol, optType, strike)
-
-			// Sample Greeks
-			output.Printf("  Delta (Δ):  %s\n", output.BoldText("0.52"))
-			output.Printf("  Gamma (Γ):  %s\n", "0.0025")
-			output.Printf("  Theta (Θ):  %s\n", output.Red("-12.50"))
-			output.Printf("  Vega (ν):   %s\n", "45.30")
-			output.Printf("  Rho (ρ):    %s\n", "8.25")
+			output.Printf("  %s %.0f %s  expiry %s (%.1f days)\n", symbol, strike, kind, FormatDate(expiry), result.DaysToExp)
+			output.Printf("  Underlying: %s  Price: %s\n\n", FormatPrice(spot), FormatPrice(price))
+
+			output.Printf("  Delta (Δ):  %s\n", output.BoldText(fmt.Sprintf("%.4f", g.Delta)))
+			output.Printf("  Gamma (Γ):  %.6f\n", g.Gamma)
+			output.Printf("  Theta (Θ):  %s\n", output.FormatPnL(g.Theta))
+			output.Printf("  Vega (ν):   %.4f\n", g.Vega)
+			output.Printf("  Rho (ρ):    %.4f\n", g.Rho)
 			output.Println()
-			output.Printf("  IV:         %s\n", "14.5%")
-			output.Printf("  Time Value: %s\n", FormatIndianCurrency(85.50))
+			output.Printf("  IV:         %.2f%%\n", result.IV)
+			output.Printf("  Intrinsic:  %s\n", FormatIndianCurrency(result.Intrinsic))
+			output.Printf("  Time Value: %s\n", FormatIndianCurrency(result.TimeValue))
 
 			return nil
 		},
 	}
+
+	cmd.Flags().String("symbol", "", "Underlying symbol (e.g. NIFTY, RELIANCE)")
+	cmd.Flags().Float64("strike", 0, "Strike price")
+	cmd.Flags().String("type", "CE", "Option type (CE/PE)")
+	cmd.Flags().String("expiry", "", "Expiry date (YYYY-MM-DD)")
+	cmd.Flags().Float64("spot", 0, "Underlying price (default: live quote)")
+	cmd.Flags().Float64("price", 0, "Option price to solve IV from (default: live LTP)")
+	cmd.Flags().Float64("iv", 0, "Price at this implied volatility in percent instead of solving")
+	cmd.Flags().Float64("rate", options.DefaultRate*100, "Risk-free rate in percent")
+	cmd.Flags().Bool("futures", false, "Treat --spot as the futures price and use Black-76")
+	cmd.Flags().Bool("portfolio", false, "Sum Greeks across open F&O positions")
+
+	return cmd
+}
+
+// optionLTP looks up the NFO contract for an option and returns its LTP.
+func optionLTP(ctx context.Context, app *App, symbol string, expiry time.Time, strike float64, kind options.Kind) (float64, error) {
+	instruments, err := app.Broker.GetInstruments(ctx, models.NFO)
+	if err != nil {
+		return 0, err
+	}
+
+	day := expiry.Format("2006-01-02")
+	for _, inst := range instruments {
+		if inst.Name != symbol || inst.Strike != strike || inst.InstrType != string(kind) {
+			continue
+		}
+		if inst.Expiry.In(resilience.IndiaLocation).Format("2006-01-02") != day {
+			continue
+		}
+		quote, err := app.Broker.GetQuote(ctx, "NFO:"+inst.Symbol)
+		if err != nil {
+			return 0, err
+		}
+		return quote.LTP, nil
+	}
+	return 0, fmt.Errorf("no %s %.0f %s contract expiring %s", symbol, strike, kind, day)
+}
+
+func showPortfolioGreeks(ctx context.Context, output *Output, app *App) error {
+	if app.Broker == nil {
+		output.Error("Broker not configured. Run 'trader login' first.")
+		return fmt.Errorf("broker not configured")
+	}
+
+	pm := trading.NewPositionManager(app.Broker)
+	analyzer := trading.NewPortfolioAnalyzer(app.Broker, pm, app.Store)
+	greeks, err := analyzer.GetPortfolioGreeks(ctx)
+	if err != nil {
+		output.Error("Failed to calculate portfolio Greeks: %v", err)
+		return err
+	}
+
+	if output.IsJSON() {
+		return output.JSON(greeks)
+	}
+
+	output.Bold("Portfolio Greeks")
+	output.Dim("  Summed across open F&O positions, per unit of underlying")
+	output.Println()
+	output.Printf("  Delta (Δ):  %s\n", output.BoldText(fmt.Sprintf("%.2f", greeks.Delta)))
+	output.Printf("  Gamma (Γ):  %.4f\n", greeks.Gamma)
+	output.Printf("  Theta (Θ):  %s /day\n", output.FormatPnL(greeks.Theta))
+	output.Printf("  Vega (ν):   %.2f\n", greeks.Vega)
+	output.Printf("  Rho (ρ):    %.2f\n", greeks.Rho)
+
+	return nil
 }
 
 func newOptionsStrategyCmd(app *App) *cobra.Command {
@@ -198,8 +398,8 @@ func newOptionsStrategyCmd(app *App) *cobra.Command {
 		Long: `Build and analyze option strategies.
 
 Supports common strategies like straddle, strangle, spreads, iron condor, etc.`,
-		Example: `  trader options strategy --type straddle --symbol NIFTY --strike 19500
-  trader options strategy --type iron-condor --symbol BANKNIFTY`,
+		Example: `  trader options strategy build straddle --symbol NIFTY --strike 19500
+  trader options strategy build iron-condor --symbol BANKNIFTY --width 2 --wing 2`,
 	}
 
 	cmd.AddCommand(&cobra.Command{
@@ -211,101 +411,198 @@ Supports common strategies like straddle, strangle, spreads, iron condor, etc.`,
 			output.Println()
 
 			strategies := []struct {
-				name string
+				name options.StrategyKind
 				desc string
 			}{
-				{"straddle", "Buy/Sell ATM Call + Put"},
-				{"strangle", "Buy/Sell OTM Call + Put"},
-				{"bull-call-spread", "Buy lower strike Call, Sell higher strike Call"},
-				{"bear-put-spread", "Buy higher strike Put, Sell lower strike Put"},
-				{"iron-condor", "Sell OTM Call + Put, Buy further OTM Call + Put"},
-				{"butterfly", "Buy 1 ITM, Sell 2 ATM, Buy 1 OTM"},
-				{"calendar-spread", "Sell near expiry, Buy far expiry"},
-				{"ratio-spread", "Buy 1, Sell 2 (or other ratios)"},
+				{options.Straddle, "Buy/Sell ATM Call + Put"},
+				{options.Strangle, "Buy/Sell OTM Call + Put"},
+				{options.BullCallSpread, "Buy lower strike Call, Sell higher strike Call"},
+				{options.BearCallSpread, "Sell lower strike Call, Buy higher strike Call"},
+				{options.BullPutSpread, "Sell higher strike Put, Buy lower strike Put"},
+				{options.BearPutSpread, "Buy higher strike Put, Sell lower strike Put"},
+				{options.IronCondor, "Sell OTM Call + Put, Buy further OTM Call + Put"},
+				{options.Butterfly, "Buy 1 ITM, Sell 2 ATM, Buy 1 OTM"},
+				{options.CalendarSpread, "Sell near expiry, Buy far expiry"},
+				{options.RatioSpread, "Buy 1, Sell 2 (or other ratios)"},
 			}
 
 			for _, s := range strategies {
-				output.Printf("  %-18s %s\n", output.Cyan(s.name), s.desc)
+				output.Printf("  %-18s %s\n", output.Cyan(string(s.name)), s.desc)
 			}
 		},
 	})
 
-	cmd.AddCommand(&cobra.Command{
+	buildCmd := &cobra.Command{
 		Use:   "build <strategy-type>",
 		Short: "Build a strategy",
-		Args:  cobra.ExactArgs(1),
+		Long: `Build a strategy from the live option chain.
+
+Legs are priced at their LTP and sized in lots of the contract's lot size.
+--width and --wing count listed strikes away from the centre strike (ATM
+unless --strike is given). Shows net premium, max profit/loss, breakevens
+and the probability of profit implied by the legs' IV at the first expiry.
+
+With --confirm, all legs are placed as a basket, buy legs first.`,
+		Example: `  trader options strategy build straddle --symbol NIFTY
+  trader options strategy build strangle --symbol NIFTY --width 3 --short
+  trader options strategy build bull-call-spread --symbol RELIANCE --strike 2500 --width 2
+  trader options strategy build calendar-spread --symbol BANKNIFTY --type PE
+  trader options strategy build iron-condor --symbol NIFTY --lots 2 --limit --confirm`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			output := NewOutput(cmd)
-			strategyType := args[0]
-			symbol, _ := cmd.Flags().GetString("symbol")
-			strike, _ := cmd.Flags().GetFloat64("strike")
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
 
-			output.Bold("%s Strategy - %s", strings.Title(strategyType), symbol)
-			output.Printf("  ATM Strike: %.0f\n\n", strike)
+			if app.Broker == nil {
+				output.Error("Broker not configured. Run 'trader login' first.")
+				return fmt.Errorf("broker not configured")
+			}
 
-			// Sample strategy analysis
-			output.Bold("Legs")
-			output.Printf("  1. BUY  %s 19500 CE @ 112.80\n", symbol)
-			output.Printf("  2. BUY  %s 19500 PE @ 78.60\n", symbol)
-			output.Println()
+			product, _ := cmd.Flags().GetString("product")
+			limit, _ := cmd.Flags().GetBool("limit")
+			confirm, _ := cmd.Flags().GetBool("confirm")
 
-			output.Bold("Analysis")
-			output.Printf("  Net Premium:    %s\n", FormatIndianCurrency(191.40))
-			output.Printf("  Max Profit:     %s\n", output.Green("Unlimited"))
-			output.Printf("  Max Loss:       %s\n", output.Red(FormatIndianCurrency(191.40)))
-			output.Printf("  Upper Breakeven: %.2f\n", 19691.40)
-			output.Printf("  Lower Breakeven: %.2f\n", 19308.60)
-			output.Printf("  Probability:    %s\n", "45%")
+			builder := trading.NewOptionStrategyBuilder(app.Broker, broker.NewSegmentManager(app.Broker))
+			strategy, err := buildStrategyFromFlags(ctx, cmd, builder, args[0])
+			if err != nil {
+				output.Error("Failed to build strategy: %v", err)
+				return err
+			}
+
+			if !confirm {
+				if output.IsJSON() {
+					return output.JSON(strategy)
+				}
+				displayOptionStrategy(output, strategy)
+				output.Println()
+				output.Warning("Use --confirm to place all legs")
+				return nil
+			}
+
+			basket, err := builder.PlaceStrategy(ctx, strategy, models.ProductType(strings.ToUpper(product)), limit)
+			if err != nil {
+				output.Error("Failed to place strategy: %v", err)
+				for _, order := range basket.Orders {
+					if order.ID != "" {
+						output.Printf("  %s  %-4s %d %s  %s\n", order.ID, order.Side, order.Quantity, order.Symbol, order.Status)
+					}
+				}
+				return err
+			}
+
+			if output.IsJSON() {
+				return output.JSON(basket)
+			}
+			displayOptionStrategy(output, strategy)
+			output.Println()
+			output.Success("✓ Placed %d legs", len(basket.Orders))
+			for _, order := range basket.Orders {
+				output.Printf("  %s  %-4s %d %s  %s\n", order.ID, order.Side, order.Quantity, order.Symbol, order.Status)
+			}
 
 			return nil
 		},
-	})
+	}
+	buildCmd.Flags().String("expiry", "", "Expiry date (YYYY-MM-DD, default: nearest)")
+	addStrategyFlags(buildCmd)
+	buildCmd.Flags().String("product", string(models.ProductNRML), "Product type (NRML/MIS)")
+	buildCmd.Flags().Bool("limit", false, "Place limit orders at each leg's LTP instead of market orders")
+	buildCmd.Flags().Bool("confirm", false, "Place all legs as a basket order")
+	cmd.AddCommand(buildCmd)
 
 	cmd.PersistentFlags().String("symbol", "NIFTY", "Underlying symbol")
-	cmd.PersistentFlags().Float64("strike", 0, "Strike price")
+	cmd.PersistentFlags().Float64("strike", 0, "Centre strike (default: ATM)")
 
 	return cmd
 }
 
-func newOptionsPayoffCmd(app *App) *cobra.Command {
-	return &cobra.Command{
-		Use:   "payoff",
-		Short: "Display payoff diagram",
-		Long:  "Display ASCII payoff diagram for an option or strategy.",
-		Example: `  trader options payoff --symbol NIFTY --strike 19500 --type CE --side BUY
-  trader options payoff --strategy straddle --symbol NIFTY --strike 19500`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			output := NewOutput(cmd)
+// addStrategyFlags adds the flags that shape a strategy's legs.
+func addStrategyFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("width", 1, "Strikes from the centre to the inner legs")
+	cmd.Flags().Int("wing", 1, "Strikes from the short to the long legs of an iron condor")
+	cmd.Flags().Int("ratio", 2, "Short legs per long leg of a ratio spread")
+	cmd.Flags().String("type", "CE", "Option type for butterfly, calendar and ratio spreads (CE/PE)")
+	cmd.Flags().Bool("short", false, "Sell the structure instead of buying it")
+	cmd.Flags().Int("lots", 1, "Number of lots per leg")
+}
 
-			output.Bold("Payoff Diagram - NIFTY 19500 Straddle")
-			output.Println()
+// buildStrategyFromFlags builds the named strategy on --symbol from the
+// flags added by addStrategyFlags, plus --strike and --expiry.
+func buildStrategyFromFlags(ctx context.Context, cmd *cobra.Command, builder *trading.OptionStrategyBuilder, name string) (*models.OptionStrategy, error) {
+	symbol, _ := cmd.Flags().GetString("symbol")
+	strike, _ := cmd.Flags().GetFloat64("strike")
+	expiryStr, _ := cmd.Flags().GetString("expiry")
+	width, _ := cmd.Flags().GetInt("width")
+	wing, _ := cmd.Flags().GetInt("wing")
+	ratio, _ := cmd.Flags().GetInt("ratio")
+	optType, _ := cmd.Flags().GetString("type")
+	short, _ := cmd.Flags().GetBool("short")
+	lots, _ := cmd.Flags().GetInt("lots")
+
+	kind, err := options.ParseKind(optType)
+	if err != nil {
+		return nil, err
+	}
 
-			// ASCII payoff diagram
-			diagram := []string{
-				"  Profit │                    ╱",
-				"         │                   ╱",
-				"         │                  ╱",
-				"         │                 ╱",
-				"      0  │────────────────╳────────────────",
-				"         │               ╱ ╲",
-				"         │              ╱   ╲",
-				"         │             ╱     ╲",
-				"    Loss │            ╱       ╲",
-				"         └────────────────────────────────",
-				"              19300  19500  19700  Price",
-			}
+	var expiry time.Time
+	if expiryStr != "" {
+		expiry, err = time.ParseInLocation("2006-01-02", expiryStr, resilience.IndiaLocation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry format, use YYYY-MM-DD: %w", err)
+		}
+	}
 
-			for _, line := range diagram {
-				output.Println(line)
-			}
+	params := options.StrategyParams{
+		Kind:   options.StrategyKind(strings.ToLower(name)),
+		Strike: strike,
+		Width:  width,
+		Wing:   wing,
+		Ratio:  ratio,
+		Type:   kind,
+		Short:  short,
+		Lots:   lots,
+	}
+	return builder.Build(ctx, strings.ToUpper(symbol), expiry, params)
+}
 
-			output.Println()
-			output.Printf("  Breakeven: 19,308.60 - 19,691.40\n")
-			output.Printf("  Max Loss:  %s at 19,500\n", output.Red(FormatIndianCurrency(-191.40)))
+func displayOptionStrategy(output *Output, s *models.OptionStrategy) {
+	output.Bold("%s - %s", strings.Title(strings.ReplaceAll(s.Name, "-", " ")), s.Symbol)
+	output.Printf("  Spot: %s  Lot size: %d\n\n", FormatPrice(s.Spot), s.LotSize)
 
-			return nil
-		},
+	output.Bold("Legs")
+	for i, leg := range s.Legs {
+		output.Printf("  %d. %-4s %d x %s %.0f %s  %s @ %s  IV %.1f%%\n",
+			i+1, leg.Side, leg.Quantity, s.Symbol, leg.Strike, leg.Type,
+			FormatDate(leg.Expiry), FormatPrice(leg.Premium), leg.IV)
+	}
+	output.Println()
+
+	output.Bold("Analysis")
+	if s.NetPremium >= 0 {
+		output.Printf("  Net Credit:      %s\n", output.Green(FormatIndianCurrency(s.NetPremium)))
+	} else {
+		output.Printf("  Net Debit:       %s\n", output.Red(FormatIndianCurrency(-s.NetPremium)))
+	}
+	if s.UnlimitedProfit {
+		output.Printf("  Max Profit:      %s\n", output.Green("Unlimited"))
+	} else {
+		output.Printf("  Max Profit:      %s\n", output.Green(FormatIndianCurrency(s.MaxProfit)))
+	}
+	if s.UnlimitedLoss {
+		output.Printf("  Max Loss:        %s\n", output.Red("Unlimited"))
+	} else {
+		output.Printf("  Max Loss:        %s\n", output.Red(FormatIndianCurrency(s.MaxLoss)))
+	}
+	breakevens := make([]string, len(s.Breakevens))
+	for i, b := range s.Breakevens {
+		breakevens[i] = FormatPrice(b)
+	}
+	if len(breakevens) == 0 {
+		breakevens = []string{"-"}
 	}
+	output.Printf("  Breakevens:      %s\n", strings.Join(breakevens, ", "))
+	output.Printf("  Probability:     %.1f%%\n", s.ProbabilityOfProfit)
 }
 
 func newFuturesCmd(app *App) *cobra.Command {
@@ -252,7 +252,9 @@ func (pt *PaperTracker) CheckExpiredPredictions(prices map[string]float64) []*Pr
 
 // addPaperCommands adds paper trading commands.
 func addPaperCommands(rootCmd *cobra.Command, app *App) {
-	rootCmd.AddCommand(newPaperCmd(app))
+	paperCmd := newPaperCmd(app)
+	paperCmd.AddCommand(newPaperAccountCmd(app))
+	rootCmd.AddCommand(paperCmd)
 }
 
 func newPaperCmd(app *App) *cobra.Command {
@@ -0,0 +1,511 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/config"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/store"
+)
+
+func newPaperAccountCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "account",
+		Short: "Manage persistent paper trading accounts",
+		Long: `Create and inspect named paper trading accounts stored in the local database.
+
+Each account keeps its cash, orders, positions, holdings, GTTs and a cash
+ledger across restarts. MIS positions are squared off at 15:15 and CNC
+positions move to holdings at the close, settling T+1.`,
+	}
+
+	cmd.AddCommand(newPaperAccountListCmd(app))
+	cmd.AddCommand(newPaperAccountCreateCmd(app))
+	cmd.AddCommand(newPaperAccountShowCmd(app))
+	cmd.AddCommand(newPaperAccountResetCmd(app))
+	cmd.AddCommand(newPaperAccountSnapshotCmd(app))
+	cmd.AddCommand(newPaperAccountCompareCmd(app))
+
+	return cmd
+}
+
+// paperAccountName returns the paper account traded in paper mode.
+func paperAccountName(cfg *config.Config) string {
+	if cfg.Trading.PaperAccount == "" {
+		return "default"
+	}
+	return cfg.Trading.PaperAccount
+}
+
+// openPaperModeBroker opens the paper account that paper mode trades, with
+// quotes, history and ticks from the live data broker.
+func openPaperModeBroker(ctx context.Context, app *App) (*broker.PaperBroker, error) {
+	if app.Store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	cfg := broker.PaperBrokerConfig{
+		DataBroker:     app.DataBroker,
+		Ticker:         app.Ticker,
+		InitialBalance: app.Config.Trading.PaperBalance,
+	}
+	return broker.OpenPaperBroker(ctx, cfg, app.Store.PaperAccount(paperAccountName(app.Config)))
+}
+
+// openPaperAccount opens a named paper account, using the live broker for
+// quotes when one is configured.
+func openPaperAccount(ctx context.Context, app *App, name string, balance float64) (*broker.PaperBroker, *store.PaperAccountStore, error) {
+	if app.Store == nil {
+		return nil, nil, fmt.Errorf("store not initialized")
+	}
+
+	accountStore := app.Store.PaperAccount(name)
+	paper, err := broker.OpenPaperBroker(ctx, broker.PaperBrokerConfig{
+		DataBroker:     app.DataBroker,
+		InitialBalance: balance,
+	}, accountStore)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paper, accountStore, nil
+}
+
+// paperAccountExists reports whether a paper account has been created.
+func paperAccountExists(ctx context.Context, app *App, name string) (bool, error) {
+	accounts, err := app.Store.GetPaperAccounts(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, a := range accounts {
+		if a.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// openExistingPaperAccount opens a paper account that must already exist.
+func openExistingPaperAccount(ctx context.Context, app *App, output *Output, name string) (*broker.PaperBroker, *store.PaperAccountStore, error) {
+	if app.Store == nil {
+		output.Error("Store not initialized")
+		return nil, nil, fmt.Errorf("store not initialized")
+	}
+
+	exists, err := paperAccountExists(ctx, app, name)
+	if err != nil {
+		output.Error("Failed to list paper accounts: %v", err)
+		return nil, nil, err
+	}
+	if !exists {
+		output.Error("Paper account %q not found. Create it with 'trader paper account create %s'", name, name)
+		return nil, nil, fmt.Errorf("paper account not found: %s", name)
+	}
+
+	paper, accountStore, err := openPaperAccount(ctx, app, name, 0)
+	if err != nil {
+		output.Error("Failed to open paper account: %v", err)
+		return nil, nil, err
+	}
+	return paper, accountStore, nil
+}
+
+// markPaperAccount refreshes prices for the account's positions and holdings
+// from the live broker. Symbols without a quote keep their last price.
+func markPaperAccount(ctx context.Context, app *App, paper *broker.PaperBroker) {
+	if app.DataBroker == nil || !app.DataBroker.IsAuthenticated() {
+		return
+	}
+
+	positions, _ := paper.GetPositions(ctx)
+	holdings, _ := paper.GetHoldings(ctx)
+	seen := make(map[string]bool)
+	for _, p := range positions {
+		seen[fmt.Sprintf("%s:%s", p.Exchange, p.Symbol)] = true
+	}
+	for _, h := range holdings {
+		// Holdings do not record their exchange
+		seen["NSE:"+h.Symbol] = true
+	}
+	for symbol := range seen {
+		_, _ = paper.GetQuote(ctx, symbol)
+	}
+}
+
+func newPaperAccountListCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List paper accounts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if app.Store == nil {
+				output.Error("Store not initialized")
+				return fmt.Errorf("store not initialized")
+			}
+
+			accounts, err := app.Store.GetPaperAccounts(ctx)
+			if err != nil {
+				output.Error("Failed to list paper accounts: %v", err)
+				return err
+			}
+
+			if output.IsJSON() {
+				return output.JSON(accounts)
+			}
+
+			if len(accounts) == 0 {
+				output.Info("No paper accounts. Create one with 'trader paper account create <name>'")
+				return nil
+			}
+
+			output.Bold("Paper Accounts")
+			output.Println()
+
+			table := NewTable(output, "Name", "Initial", "Cash", "Realized P&L", "Charges", "Updated")
+			for _, a := range accounts {
+				table.AddRow(
+					a.Name,
+					FormatIndianCurrency(a.InitialBalance),
+					FormatIndianCurrency(a.Cash),
+					output.FormatPnL(a.RealizedPnL),
+					FormatIndianCurrency(a.Charges),
+					FormatDateTime(a.UpdatedAt),
+				)
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
+
+func newPaperAccountCreateCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a paper account",
+		Args:  cobra.ExactArgs(1),
+		Example: `  trader paper account create swing
+  trader paper account create intraday --balance 200000`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			name := args[0]
+			balance, _ := cmd.Flags().GetFloat64("balance")
+
+			if app.Store == nil {
+				output.Error("Store not initialized")
+				return fmt.Errorf("store not initialized")
+			}
+			if balance <= 0 {
+				output.Error("Balance must be positive")
+				return fmt.Errorf("invalid balance: %.2f", balance)
+			}
+
+			exists, err := paperAccountExists(ctx, app, name)
+			if err != nil {
+				output.Error("Failed to list paper accounts: %v", err)
+				return err
+			}
+			if exists {
+				output.Error("Paper account %q already exists", name)
+				return fmt.Errorf("paper account exists: %s", name)
+			}
+
+			if _, _, err := openPaperAccount(ctx, app, name, balance); err != nil {
+				output.Error("Failed to create paper account: %v", err)
+				return err
+			}
+
+			output.Success("✓ Created paper account %s with %s", name, FormatIndianCurrency(balance))
+			return nil
+		},
+	}
+
+	cmd.Flags().Float64("balance", 1000000, "Starting cash balance")
+
+	return cmd
+}
+
+func newPaperAccountShowCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show a paper account's balance, positions, holdings and ledger",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+
+			ledgerLimit, _ := cmd.Flags().GetInt("ledger")
+
+			paper, accountStore, err := openExistingPaperAccount(ctx, app, output, args[0])
+			if err != nil {
+				return err
+			}
+			markPaperAccount(ctx, app, paper)
+
+			positions, _ := paper.GetPositions(ctx)
+			holdings, _ := paper.GetHoldings(ctx)
+			ledger, err := accountStore.GetPaperLedger(ctx, ledgerLimit)
+			if err != nil {
+				output.Error("Failed to load ledger: %v", err)
+				return err
+			}
+			snap := paper.Snapshot("")
+
+			if output.IsJSON() {
+				return output.JSON(map[string]interface{}{
+					"summary":   snap,
+					"positions": positions,
+					"holdings":  holdings,
+					"ledger":    ledger,
+				})
+			}
+
+			displayPaperSnapshot(output, accountStore.Name(), snap)
+
+			if len(positions) > 0 {
+				output.Println()
+				if err := displayPositions(output, positions); err != nil {
+					return err
+				}
+			}
+			if len(holdings) > 0 {
+				output.Println()
+				if err := displayHoldings(output, holdings); err != nil {
+					return err
+				}
+			}
+			if len(ledger) > 0 {
+				output.Println()
+				displayPaperLedger(output, ledger)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int("ledger", 20, "Number of recent ledger entries to show")
+
+	return cmd
+}
+
+func newPaperAccountResetCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reset <name>",
+		Short: "Reset a paper account to a fresh balance",
+		Long: `Clear a paper account's orders, positions, holdings and GTTs and restore its
+cash. The ledger and snapshots are kept.`,
+		Args: cobra.ExactArgs(1),
+		Example: `  trader paper account reset swing --confirm
+  trader paper account reset swing --balance 500000 --confirm`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			name := args[0]
+			balance, _ := cmd.Flags().GetFloat64("balance")
+			confirm, _ := cmd.Flags().GetBool("confirm")
+
+			paper, _, err := openExistingPaperAccount(ctx, app, output, name)
+			if err != nil {
+				return err
+			}
+			if balance <= 0 {
+				balance = paper.InitialBalance()
+			}
+
+			if !confirm {
+				output.Warning("This clears all orders, positions and holdings of %s. Re-run with --confirm to reset.", name)
+				return nil
+			}
+
+			paper.Reset(balance)
+			if err := paper.PersistError(); err != nil {
+				output.Error("Failed to reset paper account: %v", err)
+				return err
+			}
+
+			output.Success("✓ Reset paper account %s to %s", name, FormatIndianCurrency(balance))
+			return nil
+		},
+	}
+
+	cmd.Flags().Float64("balance", 0, "New starting balance (default: the account's initial balance)")
+	cmd.Flags().Bool("confirm", false, "Confirm the reset")
+
+	return cmd
+}
+
+func newPaperAccountSnapshotCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot <name>",
+		Short: "Record or list snapshots of a paper account",
+		Args:  cobra.ExactArgs(1),
+		Example: `  trader paper account snapshot swing --label week-1
+  trader paper account snapshot swing --list`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+
+			label, _ := cmd.Flags().GetString("label")
+			list, _ := cmd.Flags().GetBool("list")
+
+			paper, accountStore, err := openExistingPaperAccount(ctx, app, output, args[0])
+			if err != nil {
+				return err
+			}
+
+			if !list {
+				markPaperAccount(ctx, app, paper)
+				snap := paper.Snapshot(label)
+				if err := accountStore.SavePaperSnapshot(ctx, &snap); err != nil {
+					output.Error("Failed to save snapshot: %v", err)
+					return err
+				}
+				if output.IsJSON() {
+					return output.JSON(snap)
+				}
+				output.Success("✓ Saved snapshot of %s", accountStore.Name())
+				output.Println()
+				displayPaperSnapshot(output, accountStore.Name(), snap)
+				return nil
+			}
+
+			snaps, err := accountStore.GetPaperSnapshots(ctx)
+			if err != nil {
+				output.Error("Failed to load snapshots: %v", err)
+				return err
+			}
+			if output.IsJSON() {
+				return output.JSON(snaps)
+			}
+			if len(snaps) == 0 {
+				output.Info("No snapshots of %s", accountStore.Name())
+				return nil
+			}
+
+			output.Bold("Snapshots of %s", accountStore.Name())
+			output.Println()
+
+			table := NewTable(output, "Taken", "Label", "Equity", "Return", "Realized", "Unrealized", "Charges", "Trades")
+			for _, s := range snaps {
+				table.AddRow(
+					FormatDateTime(s.TakenAt),
+					s.Label,
+					FormatIndianCurrency(s.Equity),
+					output.FormatPercent(s.ReturnPercent),
+					output.FormatPnL(s.RealizedPnL),
+					output.FormatPnL(s.UnrealizedPnL),
+					FormatIndianCurrency(s.Charges),
+					fmt.Sprintf("%d", s.Trades),
+				)
+			}
+			table.Render()
+			return nil
+		},
+	}
+
+	cmd.Flags().String("label", "", "Label for the snapshot")
+	cmd.Flags().Bool("list", false, "List recorded snapshots instead of taking one")
+
+	return cmd
+}
+
+func newPaperAccountCompareCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:     "compare <name> <name>...",
+		Short:   "Compare paper accounts side by side",
+		Args:    cobra.MinimumNArgs(2),
+		Example: `  trader paper account compare swing intraday momentum`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+			defer cancel()
+
+			snaps := make([]models.PaperSnapshot, 0, len(args))
+			for _, name := range args {
+				paper, accountStore, err := openExistingPaperAccount(ctx, app, output, name)
+				if err != nil {
+					return err
+				}
+				markPaperAccount(ctx, app, paper)
+				snap := paper.Snapshot("")
+				snap.Account = accountStore.Name()
+				snaps = append(snaps, snap)
+			}
+
+			if output.IsJSON() {
+				return output.JSON(snaps)
+			}
+
+			output.Bold("Paper Account Comparison")
+			output.Println()
+
+			table := NewTable(output, "Account", "Equity", "Return", "Realized", "Unrealized", "Charges", "Net P&L", "Positions", "Holdings", "Trades")
+			best := 0
+			for i, s := range snaps {
+				if s.ReturnPercent > snaps[best].ReturnPercent {
+					best = i
+				}
+				table.AddRow(
+					s.Account,
+					FormatIndianCurrency(s.Equity),
+					output.FormatPercent(s.ReturnPercent),
+					output.FormatPnL(s.RealizedPnL),
+					output.FormatPnL(s.UnrealizedPnL),
+					FormatIndianCurrency(s.Charges),
+					output.FormatPnL(s.RealizedPnL+s.UnrealizedPnL-s.Charges),
+					fmt.Sprintf("%d", s.OpenPositions),
+					fmt.Sprintf("%d", s.Holdings),
+					fmt.Sprintf("%d", s.Trades),
+				)
+			}
+			table.Render()
+
+			output.Println()
+			output.Printf("  Best return: %s (%s)\n", output.BoldText(snaps[best].Account), output.FormatPercent(snaps[best].ReturnPercent))
+			return nil
+		},
+	}
+}
+
+func displayPaperSnapshot(output *Output, name string, snap models.PaperSnapshot) {
+	output.Bold("Paper Account: %s", name)
+	output.Printf("  Cash:            %s\n", FormatIndianCurrency(snap.Cash))
+	output.Printf("  Equity:          %s (%s)\n", FormatIndianCurrency(snap.Equity), output.FormatPercent(snap.ReturnPercent))
+	output.Printf("  Realized P&L:    %s\n", output.FormatPnL(snap.RealizedPnL))
+	output.Printf("  Unrealized P&L:  %s\n", output.FormatPnL(snap.UnrealizedPnL))
+	output.Printf("  Charges:         %s\n", FormatIndianCurrency(snap.Charges))
+	output.Printf("  Trades:          %d\n", snap.Trades)
+}
+
+func displayPaperLedger(output *Output, ledger []models.PaperLedgerEntry) {
+	output.Bold("Ledger")
+	output.Println()
+
+	table := NewTable(output, "Time", "Type", "Symbol", "Amount", "Balance", "Note")
+	for _, e := range ledger {
+		amount := FormatIndianCurrency(e.Amount)
+		if e.Amount != 0 {
+			amount = output.FormatPnL(e.Amount)
+		}
+		table.AddRow(
+			FormatDateTime(e.Time),
+			e.Type,
+			e.Symbol,
+			amount,
+			FormatIndianCurrency(e.Balance),
+			e.Note,
+		)
+	}
+	table.Render()
+}
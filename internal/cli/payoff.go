@@ -0,0 +1,400 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/options"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/trading"
+)
+
+const (
+	payoffChartWidth  = 61
+	payoffChartHeight = 15
+)
+
+// payoffScenario is the P&L of a position at one hypothetical spot.
+type payoffScenario struct {
+	Spot      float64 `json:"spot"`
+	PnL       float64 `json:"pnl"`
+	ExpiryPnL float64 `json:"expiry_pnl"`
+}
+
+// payoffResult is the JSON shape of `options payoff`.
+type payoffResult struct {
+	Strategy  *models.OptionStrategy `json:"strategy"`
+	Spot      float64                `json:"spot"`
+	Expiry    time.Time              `json:"expiry"`
+	At        time.Time              `json:"at"`
+	IVShift   float64                `json:"iv_shift"`
+	AtExpiry  []options.PayoffPoint  `json:"at_expiry"`
+	AtTime    []options.PayoffPoint  `json:"at_time,omitempty"`
+	Scenarios []payoffScenario       `json:"scenarios"`
+}
+
+func newOptionsPayoffCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "payoff",
+		Short: "Display payoff diagram",
+		Long: `Display the payoff of an option position at expiry and before it.
+
+Legs come from one of:
+  --leg SIDE:STRIKE:TYPE:QTY:PREMIUM   (repeatable, with --symbol and --expiry)
+  --strategy <name>                    (built from the live chain, as in 'strategy build')
+  --positions                          (open NFO positions on --symbol)
+
+The chart plots P&L at the first expiry (●) and, until then, at --days
+from today (·), with the IV of every leg shifted by --iv-shift points.
+The spot is marked with ┊. --at adds P&L rows for other spot prices.`,
+		Example: `  trader options payoff --symbol NIFTY --expiry 2024-01-25 --leg BUY:19500:CE:50:112.8 --leg BUY:19500:PE:50:78.6
+  trader options payoff --strategy iron-condor --symbol NIFTY --width 2
+  trader options payoff --positions --symbol BANKNIFTY --days 3 --iv-shift -2
+  trader options payoff --strategy straddle --symbol NIFTY --at 19300 --at 19700`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+
+			legs, _ := cmd.Flags().GetStringArray("leg")
+			strategyName, _ := cmd.Flags().GetString("strategy")
+			fromPositions, _ := cmd.Flags().GetBool("positions")
+			days, _ := cmd.Flags().GetFloat64("days")
+			ivShift, _ := cmd.Flags().GetFloat64("iv-shift")
+			rangePct, _ := cmd.Flags().GetFloat64("range")
+			spots, _ := cmd.Flags().GetFloat64Slice("at")
+
+			sources := 0
+			for _, set := range []bool{len(legs) > 0, strategyName != "", fromPositions} {
+				if set {
+					sources++
+				}
+			}
+			if sources != 1 {
+				output.Error("Give exactly one of --leg, --strategy or --positions")
+				return fmt.Errorf("no payoff legs")
+			}
+
+			strategy, err := payoffStrategy(ctx, cmd, app, legs, strategyName, fromPositions)
+			if err != nil {
+				output.Error("Failed to build position: %v", err)
+				return err
+			}
+
+			now := time.Now()
+			expiry := options.StrategyExpiry(strategy)
+			at := now.Add(time.Duration(days * 24 * float64(time.Hour)))
+			if at.After(expiry) {
+				at = expiry
+			}
+
+			lo, hi := payoffRange(strategy, rangePct)
+			result := payoffResult{
+				Strategy: strategy,
+				Spot:     strategy.Spot,
+				Expiry:   expiry,
+				At:       at,
+				IVShift:  ivShift,
+				AtExpiry: options.PayoffCurve(strategy, lo, hi, payoffChartWidth, expiry, ivShift, options.DefaultRate),
+			}
+			if at.Before(expiry) {
+				result.AtTime = options.PayoffCurve(strategy, lo, hi, payoffChartWidth, at, ivShift, options.DefaultRate)
+			}
+
+			if len(spots) == 0 {
+				spots = []float64{strategy.Spot * 0.98, strategy.Spot * 0.99, strategy.Spot, strategy.Spot * 1.01, strategy.Spot * 1.02}
+			}
+			for _, spot := range spots {
+				result.Scenarios = append(result.Scenarios, payoffScenario{
+					Spot:      spot,
+					PnL:       options.ScenarioPnL(strategy, options.Scenario{Price: spot, At: at, IVShift: ivShift}, options.DefaultRate),
+					ExpiryPnL: options.ScenarioPnL(strategy, options.Scenario{Price: spot, At: expiry, IVShift: ivShift}, options.DefaultRate),
+				})
+			}
+
+			if output.IsJSON() {
+				return output.JSON(result)
+			}
+
+			output.Bold("Payoff Diagram - %s %s", strategy.Symbol, strings.Title(strings.ReplaceAll(strategy.Name, "-", " ")))
+			output.Printf("  Spot: %s  Expiry: %s", FormatPrice(strategy.Spot), FormatDateTime(expiry))
+			if ivShift != 0 {
+				output.Printf("  IV shift: %+.1f", ivShift)
+			}
+			output.Println()
+			output.Println()
+
+			renderPayoffChart(output, result.AtExpiry, result.AtTime, strategy.Spot)
+			output.Println()
+
+			if len(strategy.Breakevens) > 0 {
+				breakevens := make([]string, len(strategy.Breakevens))
+				for i, b := range strategy.Breakevens {
+					breakevens[i] = FormatPrice(b)
+				}
+				output.Printf("  Breakevens: %s\n", strings.Join(breakevens, ", "))
+			}
+			if strategy.UnlimitedProfit {
+				output.Printf("  Max Profit: %s\n", output.Green("Unlimited"))
+			} else {
+				output.Printf("  Max Profit: %s\n", output.Green(FormatIndianCurrency(strategy.MaxProfit)))
+			}
+			if strategy.UnlimitedLoss {
+				output.Printf("  Max Loss:   %s\n", output.Red("Unlimited"))
+			} else {
+				output.Printf("  Max Loss:   %s\n", output.Red(FormatIndianCurrency(strategy.MaxLoss)))
+			}
+			output.Println()
+
+			table := NewTable(output, "Spot", "Change", fmt.Sprintf("P&L %s", FormatDate(at)), "P&L at Expiry")
+			for _, sc := range result.Scenarios {
+				change := 0.0
+				if strategy.Spot > 0 {
+					change = (sc.Spot/strategy.Spot - 1) * 100
+				}
+				table.AddRow(FormatPrice(sc.Spot), output.FormatPercent(change), output.FormatPnL(sc.PnL), output.FormatPnL(sc.ExpiryPnL))
+			}
+			table.Render()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("symbol", "NIFTY", "Underlying symbol")
+	cmd.Flags().String("expiry", "", "Expiry date (YYYY-MM-DD); required with --leg")
+	cmd.Flags().Float64("spot", 0, "Underlying price for --leg (default: live quote)")
+	cmd.Flags().StringArray("leg", nil, "Leg as SIDE:STRIKE:TYPE:QTY:PREMIUM, e.g. SELL:19500:CE:50:112.8")
+	cmd.Flags().String("strategy", "", "Strategy to build from the live chain")
+	cmd.Flags().Float64("strike", 0, "Centre strike for --strategy (default: ATM)")
+	addStrategyFlags(cmd)
+	cmd.Flags().Bool("positions", false, "Use open NFO positions")
+	cmd.Flags().Float64("days", 0, "Days from today for the before-expiry curve")
+	cmd.Flags().Float64("iv-shift", 0, "Shift every leg's IV by this many volatility points")
+	cmd.Flags().Float64("range", 5, "Price range around spot to chart, in percent")
+	cmd.Flags().Float64Slice("at", nil, "Spot prices to show P&L at (default: spot ±1%, ±2%)")
+
+	return cmd
+}
+
+// payoffStrategy collects the payoff legs from whichever source was given.
+func payoffStrategy(ctx context.Context, cmd *cobra.Command, app *App, legs []string, strategyName string, fromPositions bool) (*models.OptionStrategy, error) {
+	symbol, _ := cmd.Flags().GetString("symbol")
+	symbol = strings.ToUpper(symbol)
+
+	if len(legs) == 0 {
+		if app.Broker == nil {
+			return nil, fmt.Errorf("broker not configured, run 'trader login' first")
+		}
+		builder := trading.NewOptionStrategyBuilder(app.Broker, broker.NewSegmentManager(app.Broker))
+		if fromPositions {
+			if !cmd.Flags().Changed("symbol") {
+				symbol = ""
+			}
+			return builder.FromPositions(ctx, symbol)
+		}
+		return buildStrategyFromFlags(ctx, cmd, builder, strategyName)
+	}
+
+	expiryStr, _ := cmd.Flags().GetString("expiry")
+	spot, _ := cmd.Flags().GetFloat64("spot")
+	if expiryStr == "" {
+		return nil, fmt.Errorf("--expiry is required with --leg")
+	}
+	expiry, err := time.ParseInLocation("2006-01-02", expiryStr, resilience.IndiaLocation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry format, use YYYY-MM-DD: %w", err)
+	}
+
+	if spot <= 0 {
+		if app.Broker == nil {
+			return nil, fmt.Errorf("--spot is required without a broker")
+		}
+		quote, err := app.Broker.GetQuote(ctx, options.UnderlyingQuoteSymbol(symbol))
+		if err != nil {
+			return nil, fmt.Errorf("fetching underlying quote: %w", err)
+		}
+		spot = quote.LTP
+	}
+
+	strategy := &models.OptionStrategy{Name: "custom", Symbol: symbol, Spot: spot}
+	for _, spec := range legs {
+		leg, err := parsePayoffLeg(spec)
+		if err != nil {
+			return nil, err
+		}
+		leg.Expiry = expiry
+		strategy.Legs = append(strategy.Legs, leg)
+	}
+
+	now := time.Now()
+	options.FillLegIV(strategy, now, options.DefaultRate)
+	options.AnalyzeStrategy(strategy, options.DefaultRate, now)
+	return strategy, nil
+}
+
+// parsePayoffLeg parses SIDE:STRIKE:TYPE:QTY:PREMIUM. TYPE is CE, PE or FUT;
+// a futures leg's STRIKE is ignored.
+func parsePayoffLeg(spec string) (models.OptionLeg, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 5 {
+		return models.OptionLeg{}, fmt.Errorf("leg %q: want SIDE:STRIKE:TYPE:QTY:PREMIUM", spec)
+	}
+
+	var leg models.OptionLeg
+	switch strings.ToUpper(parts[0]) {
+	case "BUY":
+		leg.Side = models.OrderSideBuy
+	case "SELL":
+		leg.Side = models.OrderSideSell
+	default:
+		return leg, fmt.Errorf("leg %q: side must be BUY or SELL", spec)
+	}
+
+	if strings.EqualFold(parts[2], options.FutureLeg) {
+		leg.Type = options.FutureLeg
+	} else {
+		kind, err := options.ParseKind(parts[2])
+		if err != nil {
+			return leg, fmt.Errorf("leg %q: %w", spec, err)
+		}
+		leg.Type = kind.LegType()
+
+		leg.Strike, err = strconv.ParseFloat(parts[1], 64)
+		if err != nil || leg.Strike <= 0 {
+			return leg, fmt.Errorf("leg %q: invalid strike", spec)
+		}
+	}
+
+	qty, err := strconv.Atoi(parts[3])
+	if err != nil || qty <= 0 {
+		return leg, fmt.Errorf("leg %q: invalid quantity", spec)
+	}
+	leg.Quantity = qty
+
+	leg.Premium, err = strconv.ParseFloat(parts[4], 64)
+	if err != nil || leg.Premium < 0 {
+		return leg, fmt.Errorf("leg %q: invalid premium", spec)
+	}
+	return leg, nil
+}
+
+// payoffRange returns the price range to chart: rangePct either side of the
+// spot, widened to take in every strike and breakeven.
+func payoffRange(s *models.OptionStrategy, rangePct float64) (float64, float64) {
+	lo := s.Spot * (1 - rangePct/100)
+	hi := s.Spot * (1 + rangePct/100)
+	for _, leg := range s.Legs {
+		if leg.Type == options.FutureLeg {
+			continue
+		}
+		lo = math.Min(lo, leg.Strike)
+		hi = math.Max(hi, leg.Strike)
+	}
+	for _, b := range s.Breakevens {
+		lo = math.Min(lo, b)
+		hi = math.Max(hi, b)
+	}
+	pad := (hi - lo) * 0.05
+	return math.Max(lo-pad, 0), hi + pad
+}
+
+// renderPayoffChart draws the expiry curve (●) and the optional
+// before-expiry curve (·) on a shared scale, with the zero line and a spot
+// marker (┊).
+func renderPayoffChart(output *Output, expiry, before []options.PayoffPoint, spot float64) {
+	if len(expiry) == 0 {
+		return
+	}
+
+	lo, hi := 0.0, 0.0
+	for _, curve := range [][]options.PayoffPoint{expiry, before} {
+		for _, p := range curve {
+			lo = math.Min(lo, p.PnL)
+			hi = math.Max(hi, p.PnL)
+		}
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	row := func(pnl float64) int {
+		return int(math.Round((hi - pnl) / (hi - lo) * float64(payoffChartHeight-1)))
+	}
+
+	width := len(expiry)
+	grid := make([][]rune, payoffChartHeight)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", width))
+	}
+
+	zero := row(0)
+	for c := range grid[zero] {
+		grid[zero][c] = '─'
+	}
+
+	minPrice, maxPrice := expiry[0].Price, expiry[width-1].Price
+	spotCol := -1
+	if spot >= minPrice && spot <= maxPrice && maxPrice > minPrice {
+		spotCol = int(math.Round((spot - minPrice) / (maxPrice - minPrice) * float64(width-1)))
+		for r := range grid {
+			grid[r][spotCol] = '┊'
+		}
+	}
+
+	for c, p := range before {
+		grid[row(p.PnL)][c] = '·'
+	}
+	for c, p := range expiry {
+		grid[row(p.PnL)][c] = '●'
+	}
+
+	for r, line := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = FormatIndianCurrency(hi)
+		case zero:
+			label = "0"
+		case payoffChartHeight - 1:
+			label = FormatIndianCurrency(lo)
+		}
+		text := string(line)
+		if r != zero {
+			color := output.Green
+			if r > zero {
+				color = output.Red
+			}
+			for _, mark := range []string{"●", "·"} {
+				text = strings.ReplaceAll(text, mark, color(mark))
+			}
+		}
+		output.Printf("%14s │%s\n", label, text)
+	}
+
+	output.Printf("%14s └%s\n", "", strings.Repeat("─", width))
+
+	axis := []rune(strings.Repeat(" ", width+16))
+	place := func(col int, text string) {
+		start := col + 16 - len(text)/2
+		if start < 16 {
+			start = 16
+		}
+		if start+len(text) > len(axis) {
+			start = len(axis) - len(text)
+		}
+		copy(axis[start:], []rune(text))
+	}
+	place(0, fmt.Sprintf("%.0f", minPrice))
+	place(width-1, fmt.Sprintf("%.0f", maxPrice))
+	if spotCol >= 0 {
+		place(spotCol, fmt.Sprintf("%.0f", spot))
+	}
+	output.Println(strings.TrimRight(string(axis), " "))
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"math"
+	"testing"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/options"
+)
+
+func TestParsePayoffLeg(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    models.OptionLeg
+		wantErr bool
+	}{
+		{spec: "BUY:22000:CE:50:100", want: models.OptionLeg{Side: models.OrderSideBuy, Strike: 22000, Type: "CALL", Quantity: 50, Premium: 100}},
+		{spec: "sell:21800:pe:75:42.5", want: models.OptionLeg{Side: models.OrderSideSell, Strike: 21800, Type: "PUT", Quantity: 75, Premium: 42.5}},
+		{spec: "BUY:0:FUT:50:22010", want: models.OptionLeg{Side: models.OrderSideBuy, Type: options.FutureLeg, Quantity: 50, Premium: 22010}},
+		{spec: "BUY:x:fut:50:22010", want: models.OptionLeg{Side: models.OrderSideBuy, Type: options.FutureLeg, Quantity: 50, Premium: 22010}},
+		{spec: "BUY:22000:CE:50:0", want: models.OptionLeg{Side: models.OrderSideBuy, Strike: 22000, Type: "CALL", Quantity: 50}},
+		{spec: "BUY:22000:CE:50", wantErr: true},
+		{spec: "HOLD:22000:CE:50:100", wantErr: true},
+		{spec: "BUY:22000:XX:50:100", wantErr: true},
+		{spec: "BUY:0:CE:50:100", wantErr: true},
+		{spec: "BUY:22000:CE:0:100", wantErr: true},
+		{spec: "BUY:22000:CE:-50:100", wantErr: true},
+		{spec: "BUY:22000:CE:50:-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parsePayoffLeg(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayoffRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy *models.OptionStrategy
+		pct      float64
+		lo, hi   float64
+	}{
+		{
+			// 20900..23100 padded by 5% of 2200
+			name:     "spot only",
+			strategy: &models.OptionStrategy{Spot: 22000, Legs: []models.OptionLeg{{Strike: 22000, Type: "CALL"}}},
+			pct:      5,
+			lo:       20790,
+			hi:       23210,
+		},
+		{
+			// 22000 ± 2% is 21560..22440; the strike and breakeven widen it to 21000..23000
+			name: "strikes and breakevens widen the range",
+			strategy: &models.OptionStrategy{
+				Spot:       22000,
+				Legs:       []models.OptionLeg{{Strike: 23000, Type: "CALL"}, {Type: options.FutureLeg, Premium: 22000}},
+				Breakevens: []float64{21000},
+			},
+			pct: 2,
+			lo:  20900,
+			hi:  23100,
+		},
+		{
+			name:     "never below zero",
+			strategy: &models.OptionStrategy{Spot: 100},
+			pct:      100,
+			lo:       0,
+			hi:       210,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lo, hi := payoffRange(tt.strategy, tt.pct)
+			if math.Abs(lo-tt.lo) > 1e-6 || math.Abs(hi-tt.hi) > 1e-6 {
+				t.Errorf("payoffRange() = %.2f..%.2f, want %.2f..%.2f", lo, hi, tt.lo, tt.hi)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/config"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/security"
+	"zerodha-trader/internal/store"
+)
+
+// ticksDir returns where ticks are recorded and replayed from.
+func ticksDir(cfg *config.Config) string {
+	if cfg.Trading.TicksDir == "" {
+		return filepath.Join(config.DefaultConfigDir(), "ticks")
+	}
+	return cfg.Trading.TicksDir
+}
+
+// parseReplaySpeed parses a --replay-speed value such as "1x", "10x" or
+// "max". Max speed is returned as zero.
+func parseReplaySpeed(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "max" {
+		return 0, nil
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("invalid replay speed %q: use 1x, 10x or max", s)
+	}
+	return speed, nil
+}
+
+// setupReplay swaps the live ticker for a replay of the recorded day. Orders
+// go to an in-memory paper account on the replay clock, never to Zerodha or
+// the persistent paper account, and market data stops at the replay clock.
+// The account sits behind the same risk gate and audit log as in paper mode.
+//
+// Everything else the replay writes, such as decisions, risk counters,
+// triggered alerts and the audit log, goes to a scratch directory that is
+// deleted on exit, and notifications are switched off.
+func setupReplay(app *App, date, speed string) error {
+	day, err := time.ParseInLocation("2006-01-02", date, resilience.IndiaLocation)
+	if err != nil {
+		return fmt.Errorf("invalid replay date %q: use YYYY-MM-DD", date)
+	}
+	rate, err := parseReplaySpeed(speed)
+	if err != nil {
+		return err
+	}
+
+	dir := ticksDir(app.Config)
+	replay, err := broker.NewReplayTicker(broker.ReplayConfig{Dir: dir, Date: day, Speed: rate})
+	if err != nil {
+		if days, _ := broker.RecordedDays(dir); len(days) > 0 {
+			return fmt.Errorf("%w (last recorded day: %s)", err, days[len(days)-1].Format("2006-01-02"))
+		}
+		return err
+	}
+
+	if err := useScratchStore(app); err != nil {
+		replay.Disconnect()
+		return err
+	}
+	auditConfig := security.DefaultAuditConfig()
+	auditConfig.LogDir = filepath.Join(app.scratchDir, "audit")
+	audit, err := security.NewAuditLogger(auditConfig)
+	if err != nil {
+		replay.Disconnect()
+		closeScratchStore(app)
+		return fmt.Errorf("opening replay audit log: %w", err)
+	}
+	audit.SetUserID(app.Config.Credentials.Zerodha.UserID)
+	app.Audit = audit
+	disableNotifications(app.Config)
+
+	if app.DataBroker != nil {
+		app.DataBroker = replay.DataBroker(app.DataBroker)
+	}
+	paper := broker.NewPaperBroker(broker.PaperBrokerConfig{
+		DataBroker:     app.DataBroker,
+		Ticker:         replay,
+		InitialBalance: app.Config.Trading.PaperBalance,
+		Clock:          replay.Now,
+	})
+	app.Broker = guardBroker(paper, app.Audit, app.Config.Risk, app.Store, app.Logger)
+	app.Ticker = paper.Ticker()
+	app.Replay = replay
+	app.Logger.Debug().Str("date", date).Str("speed", speed).Msg("Replaying recorded ticks")
+	return nil
+}
+
+// useScratchStore creates the replay's temporary directory, removed by
+// closeScratchStore, and replaces the store with a copy inside it.
+func useScratchStore(app *App) error {
+	dir, err := os.MkdirTemp("", "trader-replay-")
+	if err != nil {
+		return fmt.Errorf("creating replay store: %w", err)
+	}
+	app.scratchDir = dir
+
+	live, ok := app.Store.(*store.SQLiteStore)
+	if !ok {
+		return nil
+	}
+	path := filepath.Join(dir, "trader.db")
+	if err := live.Snapshot(context.Background(), path); err != nil {
+		os.RemoveAll(dir)
+		app.scratchDir = ""
+		return fmt.Errorf("creating replay store: %w", err)
+	}
+	scratch, err := store.NewSQLiteStore(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		app.scratchDir = ""
+		return fmt.Errorf("opening replay store: %w", err)
+	}
+
+	live.Close()
+	app.Store = scratch
+	return nil
+}
+
+// closeScratchStore closes and deletes a replay's scratch store.
+func closeScratchStore(app *App) {
+	if app.scratchDir == "" {
+		return
+	}
+	if app.Store != nil {
+		app.Store.Close()
+	}
+	os.RemoveAll(app.scratchDir)
+	app.scratchDir = ""
+}
+
+// disableNotifications turns off every notification channel and voice
+// announcements.
+func disableNotifications(cfg *config.Config) {
+	cfg.Notifications.Enabled = false
+	cfg.Notifications.Webhook.Enabled = false
+	cfg.Notifications.Telegram.Enabled = false
+	cfg.Notifications.Email.Enabled = false
+	muted = true
+}
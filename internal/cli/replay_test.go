@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/config"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/security"
+	"zerodha-trader/internal/store"
+	"zerodha-trader/internal/trading"
+)
+
+func TestSetupReplayIsolatesStoreAndNotifications(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.Trading.TicksDir = t.TempDir()
+	cfg.Notifications.Enabled = true
+	cfg.Notifications.Webhook.Enabled = true
+	t.Cleanup(func() { muted = false })
+
+	recorder, err := broker.NewTickRecorder(cfg.Trading.TicksDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Record(models.Tick{Symbol: "INFY", LTP: 1500}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	days, err := broker.RecordedDays(cfg.Trading.TicksDir)
+	if err != nil || len(days) != 1 {
+		t.Fatalf("recorded days %v, %v", days, err)
+	}
+
+	livePath := filepath.Join(t.TempDir(), "trader.db")
+	live, err := store.NewSQLiteStore(livePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := live.AddToWatchlist(ctx, "INFY", "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &App{Config: cfg, Logger: zerolog.Nop(), Store: live}
+	if err := setupReplay(app, days[0].Format("2006-01-02"), "max"); err != nil {
+		t.Fatalf("setupReplay() error = %v", err)
+	}
+	defer app.Replay.Disconnect()
+	scratchDir := app.scratchDir
+
+	// The scratch store starts as a copy of the live one
+	if app.Store == live {
+		t.Fatal("replay kept the live store")
+	}
+	if symbols, err := app.Store.GetWatchlist(ctx, "default"); err != nil || len(symbols) != 1 || symbols[0] != "INFY" {
+		t.Errorf("scratch watchlist = %v, %v; want [INFY]", symbols, err)
+	}
+	if err := app.Store.AddToWatchlist(ctx, "TCS", "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Notifications.Enabled || cfg.Notifications.Webhook.Enabled || !muted {
+		t.Errorf("notifications still enabled during replay")
+	}
+	if !app.Now().Equal(app.Replay.Now()) {
+		t.Errorf("app clock %v does not follow the replay clock %v", app.Now(), app.Replay.Now())
+	}
+
+	// Orders pass the live decorator stack, audited to the scratch directory
+	if _, ok := trading.UnwrapBroker(app.Broker).(*broker.PaperBroker); !ok {
+		t.Fatalf("replay broker is %T, want a paper account", trading.UnwrapBroker(app.Broker))
+	}
+	gate, ok := app.Broker.(*trading.RiskGate)
+	if !ok {
+		t.Fatalf("replay broker %T is not behind the risk gate", app.Broker)
+	}
+	if err := gate.SetKillSwitch(ctx, true, "replay"); err != nil {
+		t.Fatal(err)
+	}
+	buy := &models.Order{Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy, Type: models.OrderTypeMarket, Product: models.ProductMIS, Quantity: 1}
+	if _, err := app.Broker.PlaceOrder(ctx, buy); err == nil {
+		t.Fatal("order placed with the kill switch engaged")
+	}
+	app.Audit.Close()
+	events, err := security.ReadAuditEvents(filepath.Join(scratchDir, "audit"), func(security.AuditEvent) bool { return true })
+	if err != nil || len(events) != 1 || events[0].EventType != security.AuditOrderRejected {
+		t.Errorf("scratch audit log = %+v, %v; want the rejected order", events, err)
+	}
+
+	closeScratchStore(app)
+	if _, err := os.Stat(scratchDir); !os.IsNotExist(err) {
+		t.Errorf("scratch store left behind: %v", err)
+	}
+
+	reopened, err := store.NewSQLiteStore(livePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if symbols, _ := reopened.GetWatchlist(ctx, "default"); len(symbols) != 1 {
+		t.Errorf("replay wrote to the live store: watchlist %v", symbols)
+	}
+}
@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/trading"
+)
+
+// addRiskCommands adds portfolio risk commands.
+func addRiskCommands(rootCmd *cobra.Command, app *App) {
+	rootCmd.AddCommand(newRiskCmd(app))
+	rootCmd.AddCommand(newKillCmd(app))
+}
+
+func newRiskCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "risk",
+		Short: "Portfolio risk reports",
+		Long:  "Reports on portfolio risk computed from positions, holdings and stored daily candles.",
+	}
+
+	cmd.AddCommand(newRiskVaRCmd(app))
+
+	return cmd
+}
+
+func newRiskVaRCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "var",
+		Short: "Value at Risk and Expected Shortfall",
+		Long: `Calculate portfolio Value at Risk (VaR) and Expected Shortfall (ES).
+
+Methods:
+  historical   replays overlapping windows of past daily returns
+  montecarlo   draws correlated returns from the constituents' covariance
+  parametric   delta-normal, options at their delta
+
+Historical and Monte Carlo reprice option positions in full at their
+current IV. Returns come from stored daily candles; symbols with missing
+or stale candles are listed as warnings.`,
+		Example: `  trader risk var
+  trader risk var --method historical --confidence 95 --horizon 5
+  trader risk var --method montecarlo --simulations 50000 --seed 42`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+
+			if app.Broker == nil {
+				output.Error("Broker not configured. Run 'trader login' first.")
+				return fmt.Errorf("broker not configured")
+			}
+			if app.Store == nil {
+				output.Error("Store not initialized")
+				return fmt.Errorf("store not initialized")
+			}
+
+			method, _ := cmd.Flags().GetString("method")
+			confidence, _ := cmd.Flags().GetFloat64("confidence")
+			horizon, _ := cmd.Flags().GetInt("horizon")
+			simulations, _ := cmd.Flags().GetInt("simulations")
+			seed, _ := cmd.Flags().GetInt64("seed")
+			benchmark, _ := cmd.Flags().GetString("benchmark")
+			lookback, _ := cmd.Flags().GetInt("lookback")
+
+			if confidence < 1 {
+				confidence *= 100
+			}
+
+			methods := []trading.VaRMethod{trading.VaRHistorical, trading.VaRMonteCarlo, trading.VaRParametric}
+			if method != "all" {
+				methods = []trading.VaRMethod{trading.VaRMethod(strings.ToLower(method))}
+			}
+
+			analyzer := trading.NewPortfolioAnalyzer(app.Broker, trading.NewPositionManager(app.Broker), app.Store)
+			analyzer.SetBenchmark(strings.ToUpper(benchmark), lookback)
+
+			var results []*trading.VaRResult
+			for _, m := range methods {
+				result, err := analyzer.ComputeVaR(ctx, trading.VaRRequest{
+					Method:      m,
+					Confidence:  confidence / 100,
+					HorizonDays: horizon,
+					Simulations: simulations,
+					Seed:        seed,
+				})
+				if err != nil {
+					output.Error("Failed to calculate %s VaR: %v", m, err)
+					return err
+				}
+				results = append(results, result)
+			}
+
+			if output.IsJSON() {
+				return output.JSON(results)
+			}
+
+			first := results[0]
+			output.Bold("Value at Risk - %.1f%%, %d-day", confidence, horizon)
+			output.Printf("  Gross exposure: %s  (%d equities, %d F&O positions)\n\n",
+				FormatIndianCurrency(first.GrossExposure), first.Equities, first.Derivatives)
+
+			table := NewTable(output, "Method", "VaR", "Expected Shortfall", "% of Exposure", "Scenarios")
+			for _, r := range results {
+				pct := 0.0
+				if r.GrossExposure > 0 {
+					pct = r.VaR / r.GrossExposure * 100
+				}
+				scenarios := "-"
+				if r.Scenarios > 0 {
+					scenarios = fmt.Sprintf("%d", r.Scenarios)
+				}
+				table.AddRow(string(r.Method), output.Red(FormatIndianCurrency(r.VaR)),
+					output.Red(FormatIndianCurrency(r.ExpectedShortfall)), fmt.Sprintf("%.2f%%", pct), scenarios)
+			}
+			table.Render()
+
+			if len(first.Warnings) > 0 {
+				output.Println()
+				output.Warning("Data warnings")
+				for _, w := range first.Warnings {
+					line := w.Message
+					if w.Symbol != "" {
+						line = fmt.Sprintf("%s: %s", w.Symbol, w.Message)
+					}
+					if !w.LastCandle.IsZero() {
+						line += fmt.Sprintf(" (last candle %s)", FormatDate(w.LastCandle))
+					}
+					output.Printf("  • %s\n", line)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("method", "all", "VaR method (historical, montecarlo, parametric, all)")
+	cmd.Flags().Float64("confidence", 99, "Confidence level in percent (99 or 0.99)")
+	cmd.Flags().Int("horizon", 1, "Horizon in trading days")
+	cmd.Flags().Int("simulations", 10000, "Monte Carlo paths")
+	cmd.Flags().Int64("seed", 0, "Monte Carlo random seed (default: random)")
+	cmd.Flags().String("benchmark", trading.DefaultBenchmark, "Benchmark index for proxying symbols without history")
+	cmd.Flags().Int("lookback", trading.DefaultRiskLookback, "Trading days of history to use")
+
+	return cmd
+}
+
+// riskGate returns the gate in front of the broker, or a gate over the
+// store alone when no broker is configured.
+func riskGate(app *App) *trading.RiskGate {
+	if g, ok := app.Broker.(*trading.RiskGate); ok {
+		return g
+	}
+	return trading.NewRiskGate(app.Broker, app.Config.Risk, app.Store)
+}
+
+func newKillCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kill",
+		Short: "Trading kill switch",
+		Long: `Engage or release the trading kill switch.
+
+While the switch is engaged every order, modification and GTT that adds
+risk is refused, whether it comes from a command, a plan, a basket or the
+autonomous trader. Exits and stop-loss modifications that shrink an open
+position still go through, as does cancelling orders. The switch is
+persisted, so it survives restarts and stops traders running in other
+processes.`,
+		Example: `  trader kill on --reason "feed outage"
+  trader kill on --flatten
+  trader kill status
+  trader kill off`,
+	}
+
+	cmd.AddCommand(newKillOnCmd(app))
+	cmd.AddCommand(newKillOffCmd(app))
+	cmd.AddCommand(newKillStatusCmd(app))
+
+	return cmd
+}
+
+func newKillOnCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "on",
+		Short: "Engage the kill switch",
+		Long: `Engage the kill switch, blocking all orders that add risk.
+
+With --flatten, open orders are also cancelled and every open position is
+squared off at market.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+
+			if app.Store == nil {
+				output.Error("Store not initialized")
+				return fmt.Errorf("store not initialized")
+			}
+
+			reason, _ := cmd.Flags().GetString("reason")
+			flatten, _ := cmd.Flags().GetBool("flatten")
+
+			if flatten && app.Broker == nil {
+				output.Error("Broker not configured. Run 'trader login' first.")
+				return fmt.Errorf("broker not configured")
+			}
+
+			gate := riskGate(app)
+			if err := gate.SetKillSwitch(ctx, true, reason); err != nil {
+				output.Error("Failed to engage kill switch: %v", err)
+				return err
+			}
+			output.Success("✓ Kill switch engaged; new orders are blocked")
+
+			if flatten {
+				output.Info("Cancelling open orders and squaring off positions...")
+				if err := gate.Flatten(ctx); err != nil {
+					output.Error("%v", err)
+					return err
+				}
+				output.Success("✓ All positions flattened")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("reason", "", "why trading was stopped")
+	cmd.Flags().Bool("flatten", false, "cancel open orders and square off all positions")
+
+	return cmd
+}
+
+func newKillOffCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "off",
+		Short: "Release the kill switch",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx := context.Background()
+
+			if app.Store == nil {
+				output.Error("Store not initialized")
+				return fmt.Errorf("store not initialized")
+			}
+
+			if err := riskGate(app).SetKillSwitch(ctx, false, ""); err != nil {
+				output.Error("Failed to release kill switch: %v", err)
+				return err
+			}
+			output.Success("✓ Kill switch released; orders are allowed")
+			return nil
+		},
+	}
+}
+
+func newKillStatusCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the kill switch state",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx := context.Background()
+
+			if app.Store == nil {
+				output.Error("Store not initialized")
+				return fmt.Errorf("store not initialized")
+			}
+
+			ks, err := riskGate(app).KillSwitch(ctx)
+			if err != nil {
+				output.Error("Failed to read kill switch: %v", err)
+				return err
+			}
+
+			if output.IsJSON() {
+				return output.JSON(ks)
+			}
+
+			if ks.Engaged {
+				output.Printf("Kill switch: %s\n", output.Red("ENGAGED"))
+			} else {
+				output.Printf("Kill switch: %s\n", output.Green("off"))
+			}
+			if ks.Reason != "" {
+				output.Printf("Reason:      %s\n", ks.Reason)
+			}
+			if !ks.UpdatedAt.IsZero() {
+				output.Printf("Since:       %s\n", ks.UpdatedAt.In(resilience.IndiaLocation).Format("2006-01-02 15:04:05"))
+			}
+
+			risk := app.Config.Risk
+			output.Println()
+			output.Bold("Pre-trade limits")
+			output.Printf("  Max Order Value:      %s\n", formatLimit(risk.MaxOrderValue > 0, FormatIndianCurrency(risk.MaxOrderValue)))
+			output.Printf("  Max Qty per Symbol:   %s\n", formatLimit(risk.MaxQuantityPerSymbol > 0, fmt.Sprintf("%d", risk.MaxQuantityPerSymbol)))
+			output.Printf("  Max Open Positions:   %s\n", formatLimit(risk.MaxConcurrentPositions > 0, fmt.Sprintf("%d", risk.MaxConcurrentPositions)))
+			output.Printf("  Daily Loss Limit:     %s\n", formatLimit(risk.DailyLossLimit > 0, FormatIndianCurrency(risk.DailyLossLimit)))
+			output.Printf("  Price Band vs LTP:    %s\n", formatLimit(risk.PriceBandPercent > 0, fmt.Sprintf("%.1f%%", risk.PriceBandPercent)))
+			return nil
+		},
+	}
+}
+
+func formatLimit(enabled bool, value string) string {
+	if !enabled {
+		return "off"
+	}
+	return value
+}
@@ -2,8 +2,11 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
@@ -12,7 +15,9 @@ import (
 	"zerodha-trader/internal/broker"
 	"zerodha-trader/internal/config"
 	"zerodha-trader/internal/logging"
+	"zerodha-trader/internal/security"
 	"zerodha-trader/internal/store"
+	"zerodha-trader/internal/trading"
 )
 
 // Version information
@@ -23,12 +28,46 @@ const (
 
 // App holds the application dependencies.
 type App struct {
-	Config    *config.Config
-	Logger    zerolog.Logger
-	Broker    broker.Broker
-	Ticker    broker.Ticker
+	Config *config.Config
+	Logger zerolog.Logger
+	// Broker takes orders: Zerodha in live mode, the paper account in
+	// paper mode. Either way it sits behind the risk gate.
+	Broker broker.Broker
+	// DataBroker is the Zerodha client, used for login and market data.
+	DataBroker broker.Broker
+	Ticker     broker.Ticker
+	// Recorder saves live ticks when trading.record_ticks is set.
+	Recorder *broker.TickRecorder
+	// Replay is set when --replay plays back a recorded day in place of
+	// the live ticker.
+	Replay *broker.ReplayTicker
+	// Audit is the hash-chained audit log every order is written to.
+	Audit     *security.AuditLogger
 	Store     store.DataStore
 	LLMClient agents.LLMClient
+	// AgentLLMs holds clients for agents with their own [llm.<name>] table.
+	AgentLLMs map[string]agents.LLMClient
+
+	// scratchDir holds a replay's throwaway copy of the store.
+	scratchDir string
+}
+
+// Now returns the time the app trades at: the replay clock during a
+// replay, else the wall clock.
+func (a *App) Now() time.Time {
+	if a.Replay != nil {
+		return a.Replay.Now()
+	}
+	return time.Now()
+}
+
+// LLMFor returns the LLM client for the named agent, falling back to the
+// default client.
+func (a *App) LLMFor(agent string) agents.LLMClient {
+	if client, ok := a.AgentLLMs[agent]; ok {
+		return client
+	}
+	return a.LLMClient
 }
 
 // NewRootCmd creates the root command for the CLI.
@@ -45,8 +84,11 @@ func NewRootCmd(cfg *config.Config, logger zerolog.Logger) *cobra.Command {
 			APIKey:    cfg.Credentials.Zerodha.APIKey,
 			APISecret: cfg.Credentials.Zerodha.APISecret,
 			UserID:    cfg.Credentials.Zerodha.UserID,
+			BaseURL:   cfg.Credentials.Zerodha.BaseURL,
+			TickerURL: cfg.Credentials.Zerodha.TickerURL,
 		})
 		app.Broker = zerodhaBroker
+		app.DataBroker = zerodhaBroker
 		logger.Debug().Msg("Zerodha broker initialized")
 
 		// Initialize ticker if broker is authenticated
@@ -57,6 +99,17 @@ func NewRootCmd(cfg *config.Config, logger zerolog.Logger) *cobra.Command {
 			} else {
 				app.Ticker = ticker
 				logger.Debug().Msg("Zerodha ticker initialized")
+
+				if cfg.Trading.RecordTicks {
+					recorder, err := broker.NewTickRecorder(ticksDir(cfg))
+					if err != nil {
+						logger.Warn().Err(err).Msg("Failed to start tick recorder")
+					} else {
+						app.Recorder = recorder
+						app.Ticker = broker.RecordTicks(ticker, recorder)
+						logger.Debug().Str("dir", ticksDir(cfg)).Msg("Recording ticks")
+					}
+				}
 			}
 		}
 	}
@@ -71,10 +124,13 @@ func NewRootCmd(cfg *config.Config, logger zerolog.Logger) *cobra.Command {
 		logger.Debug().Msg("SQLite store initialized")
 	}
 
-	// Initialize LLM client if OpenAI API key is available
-	if cfg.Credentials.OpenAI.APIKey != "" {
-		app.LLMClient = agents.NewOpenAIClient(cfg.Credentials.OpenAI.APIKey, cfg.Agents.Model)
-		logger.Debug().Str("model", cfg.Agents.Model).Msg("OpenAI LLM client initialized")
+	// Initialize LLM clients; agents run rule-based without one
+	app.LLMClient = newLLMClient(cfg.LLMFor(""), "", logger)
+	app.AgentLLMs = make(map[string]agents.LLMClient)
+	for name := range cfg.Agents.LLM {
+		if client := newLLMClient(cfg.LLMFor(name), name, logger); client != nil {
+			app.AgentLLMs[name] = client
+		}
 	}
 
 	rootCmd := &cobra.Command{
@@ -96,14 +152,37 @@ Use 'trader examples' to see common workflows.`,
 				logging.SetDebugLevel()
 				app.Logger = app.Logger.Level(zerolog.DebugLevel)
 			}
+
+			// A replay trades on its own scratch account, so the order
+			// broker is only opened when not replaying
+			if date, _ := cmd.Flags().GetString("replay"); date != "" {
+				speed, _ := cmd.Flags().GetString("replay-speed")
+				return setupReplay(app, date, speed)
+			}
+			if err := setupBroker(app); err != nil && !offlineCommand(cmd) {
+				return err
+			}
 			return nil
 		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if app.Audit != nil {
+				app.Audit.Close()
+			}
+			closeScratchStore(app)
+			if app.Recorder != nil {
+				if err := app.Recorder.Close(); err != nil {
+					app.Logger.Warn().Err(err).Msg("Tick recording incomplete")
+				}
+			}
+		},
 	}
 
 	// Global flags
 	rootCmd.PersistentFlags().String("config", "", "config directory (default: ~/.config/zerodha-trader)")
 	rootCmd.PersistentFlags().Bool("json", false, "output in JSON format")
 	rootCmd.PersistentFlags().Bool("debug", false, "enable debug logging")
+	rootCmd.PersistentFlags().String("replay", "", "replay ticks recorded on a day (YYYY-MM-DD) instead of streaming live")
+	rootCmd.PersistentFlags().String("replay-speed", "1x", "replay speed: 1x, 10x or max")
 
 	// Add all command groups
 	addCoreCommands(rootCmd, app)
@@ -112,17 +191,96 @@ Use 'trader examples' to see common workflows.`,
 	addAnalysisCommands(rootCmd, app)
 	addTradingCommands(rootCmd, app)
 	addDerivativesCommands(rootCmd, app)
+	addRiskCommands(rootCmd, app)
 	addPlanningCommands(rootCmd, app)
 	addMonitoringCommands(rootCmd, app)
 	addTraderCommands(rootCmd, app)
 	addPaperCommands(rootCmd, app)
 	addJournalCommands(rootCmd, app)
+	addAuditCommands(rootCmd, app)
 	addUtilityCommands(rootCmd, app)
 	addHelpCommands(rootCmd, app)
 
 	return rootCmd
 }
 
+// setupBroker opens the broker that takes orders. Paper mode simulates every
+// order; quotes, history and ticks stay live. It fails if the account cannot
+// be opened: never fall back to the live broker, nor to an in-memory account
+// whose orders would vanish.
+//
+// Every order passes the pre-trade risk gate and is audited, whether the
+// gate lets it through or not. Live trading refuses to run unaudited.
+func setupBroker(app *App) error {
+	cfg, logger := app.Config, app.Logger
+	if cfg.IsPaperMode() && app.DataBroker != nil {
+		paper, err := openPaperModeBroker(context.Background(), app)
+		if err != nil {
+			app.Broker = nil
+			return fmt.Errorf("opening paper account %q: %w", paperAccountName(cfg), err)
+		}
+		app.Broker = paper
+		if ticker := paper.Ticker(); ticker != nil {
+			app.Ticker = ticker
+		}
+		logger.Debug().Str("account", paperAccountName(cfg)).Msg("Paper broker initialized")
+	}
+	if app.Broker == nil {
+		return nil
+	}
+
+	auditLogger, err := security.NewAuditLogger(security.DefaultAuditConfig())
+	switch {
+	case err == nil:
+		auditLogger.SetUserID(cfg.Credentials.Zerodha.UserID)
+		app.Audit = auditLogger
+	case cfg.IsPaperMode():
+		logger.Warn().Err(err).Msg("Failed to open audit log, paper orders will not be audited")
+	default:
+		app.Broker = nil
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	app.Broker = guardBroker(app.Broker, app.Audit, cfg.Risk, app.Store, logger)
+	return nil
+}
+
+// guardBroker puts the risk gate and audit log in front of b. A paper
+// broker places the legs of its triggered GTTs back through the gate.
+func guardBroker(b broker.Broker, audit *security.AuditLogger, risk config.RiskConfig, dataStore store.DataStore, logger zerolog.Logger) broker.Broker {
+	gate := trading.NewGuardedBroker(b, audit, logger, risk, dataStore)
+	if paper, ok := b.(*broker.PaperBroker); ok {
+		paper.SetOrderRouter(gate.PlaceOrder)
+	}
+	return gate
+}
+
+// offlineCommand reports whether cmd only manages the CLI itself, so it can
+// run when the broker could not be set up.
+func offlineCommand(cmd *cobra.Command) bool {
+	for cmd.HasParent() && cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
+	switch cmd.Name() {
+	case "config", "version", "completion", "help":
+		return true
+	}
+	return false
+}
+
+func newLLMClient(llm config.LLMConfig, agent string, logger zerolog.Logger) agents.LLMClient {
+	client, err := agents.NewLLMClient(llm)
+	if err != nil {
+		event := logger.Warn()
+		if errors.Is(err, agents.ErrNoAPIKey) {
+			event = logger.Debug()
+		}
+		event.Err(err).Str("agent", agent).Msg("LLM client not initialized")
+		return nil
+	}
+	logger.Debug().Str("agent", agent).Str("provider", llm.Provider).Str("model", llm.Model).Msg("LLM client initialized")
+	return client
+}
+
 // addCoreCommands adds core utility commands.
 func addCoreCommands(rootCmd *cobra.Command, app *App) {
 	rootCmd.AddCommand(newVersionCmd())
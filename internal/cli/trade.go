@@ -9,6 +9,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"zerodha-trader/internal/broker"
 	"zerodha-trader/internal/models"
 )
 
@@ -107,6 +108,9 @@ Can specify stop-loss and target prices for bracket orders.`,
 
 			// Place order
 			result, err := app.Broker.PlaceOrder(ctx, order)
+			if err == nil {
+				err = orderRejection(result)
+			}
 			if err != nil {
 				output.Error("Order failed: %v", err)
 				return err
@@ -209,6 +213,9 @@ Supports market, limit, and stop-loss orders.`,
 
 			// Place order
 			result, err := app.Broker.PlaceOrder(ctx, order)
+			if err == nil {
+				err = orderRejection(result)
+			}
 			if err != nil {
 				output.Error("Order failed: %v", err)
 				return err
@@ -446,6 +453,9 @@ func newExitCmd(app *App) *cobra.Command {
 			output.Println()
 
 			result, err := app.Broker.PlaceOrder(ctx, order)
+			if err == nil {
+				err = orderRejection(result)
+			}
 			if err != nil {
 				output.Error("Exit failed: %v", err)
 				return err
@@ -533,7 +543,10 @@ func newExitAllCmd(app *App) *cobra.Command {
 					Quantity: qty,
 				}
 
-				_, err := app.Broker.PlaceOrder(ctx, order)
+				result, err := app.Broker.PlaceOrder(ctx, order)
+				if err == nil {
+					err = orderRejection(result)
+				}
 				if err != nil {
 					output.Error("Failed to exit %s: %v", p.Symbol, err)
 				} else {
@@ -696,3 +709,12 @@ func displayBalance(output *Output, balance *models.Balance, margins *models.Mar
 
 	return nil
 }
+
+// orderRejection reports an order the broker accepted but rejected, such as a
+// paper order refused for insufficient funds, as an error.
+func orderRejection(result *broker.OrderResult) error {
+	if result != nil && result.Status == models.OrderStatusRejected {
+		return fmt.Errorf("order %s rejected: %s", result.OrderID, result.Message)
+	}
+	return nil
+}
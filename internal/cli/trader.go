@@ -3,17 +3,24 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"zerodha-trader/internal/agents"
 	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/config"
+	"zerodha-trader/internal/daemon"
 	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
 	"zerodha-trader/internal/store"
+	"zerodha-trader/internal/stream"
+	"zerodha-trader/internal/trading"
 )
 
 // addTraderCommands adds autonomous trading commands.
@@ -33,6 +40,7 @@ func addTraderCommands(rootCmd *cobra.Command, app *App) {
 	cmd.AddCommand(newTraderDecisionsCmd(app))
 	cmd.AddCommand(newTraderConfigCmd(app))
 	cmd.AddCommand(newTraderHealthCmd(app))
+	cmd.AddCommand(newTraderCostsCmd(app))
 
 	rootCmd.AddCommand(cmd)
 
@@ -97,11 +105,26 @@ The daemon will:
 				return fmt.Errorf("broker not initialized")
 			}
 
-			if !app.Broker.IsAuthenticated() {
+			// Paper mode still needs a Zerodha session for market data
+			if app.DataBroker == nil || !app.DataBroker.IsAuthenticated() {
 				output.Error("Not authenticated. Please run 'trader auth login' first.")
 				return fmt.Errorf("not authenticated")
 			}
 
+			// Only one daemon may run at a time
+			paths := daemonPaths()
+			lock, err := daemon.Acquire(paths.PIDFile)
+			if err != nil {
+				var running *daemon.RunningError
+				if errors.As(err, &running) {
+					output.Error("Daemon already running (PID %d). Use 'trader trader stop' to stop it.", running.PID)
+				} else {
+					output.Error("Failed to acquire daemon lock: %v", err)
+				}
+				return err
+			}
+			defer lock.Release()
+
 			// Display configuration
 			output.Printf("  Mode:             %s\n", app.Config.Agents.AutonomousMode)
 			output.Printf("  Confidence:       %.0f%%\n", app.Config.Agents.AutoExecuteThreshold)
@@ -119,10 +142,8 @@ The daemon will:
 				output.Println()
 			}
 
-			if app.Config.IsPaperMode() {
-				output.Warning("📝 PAPER TRADING MODE")
-				output.Println()
-			}
+			printModeBanner(output, app)
+			output.Println()
 
 			// Get watchlist symbols
 			symbols, err := getWatchlistSymbols(app, watchlist)
@@ -155,30 +176,115 @@ The daemon will:
 				output.Error("Failed to start orchestrator: %v", err)
 				return err
 			}
+			stopped := orchestrator.Done()
+
+			// Ticks build the intraday bars the agents analyze, and resting
+			// paper orders and GTTs fill from them between scans. A replay
+			// always streams: its ticks drive the clock and quotes.
+			candles := newDaemonCandles()
+			var bars *stream.CandleAggregator
+			if app.Ticker != nil {
+				bars, err = stream.NewCandleAggregator(stream.CandleAggregatorConfig{
+					Symbols: symbols,
+					Store:   app.Store,
+					OnBar:   candles.addBar,
+					OnError: func(err error) { output.Dim("Live candles: %v", err) },
+				})
+				if err != nil {
+					orchestrator.Stop()
+					return err
+				}
+				defer bars.Close()
+
+				hub := stream.NewHub()
+				hub.RegisterOrderedConsumer(bars)
+				if err := hub.Start(ctx); err != nil {
+					orchestrator.Stop()
+					return err
+				}
+				defer hub.Stop()
+
+				disconnect, err := streamTicks(ctx, app, output, symbols, func(tick models.Tick) {
+					// Bars need every tick, so wait briefly rather than drop
+					hub.PublishWithTimeout(tick, time.Second)
+				})
+				switch {
+				case err != nil && app.Replay != nil:
+					output.Error("Failed to start replay: %v", err)
+					orchestrator.Stop()
+					return err
+				case err != nil && app.Config.IsPaperMode():
+					output.Warning("Paper tick feed unavailable, resting orders will not fill: %v", err)
+				case err != nil:
+					output.Warning("Tick feed unavailable, analyzing daily candles only: %v", err)
+				default:
+					defer disconnect()
+				}
+			}
+
+			// Control socket for stop/pause/resume/status
+			control, err := daemon.Listen(paths.Socket, orchestrator)
+			if err != nil {
+				output.Error("Failed to open control socket: %v", err)
+				orchestrator.Stop()
+				return err
+			}
+			defer control.Close()
+			go control.Serve()
 
-			output.Success("✓ Daemon started")
+			output.Success("✓ Daemon started (PID %d)", os.Getpid())
 			output.Println()
-			output.Dim("Press Ctrl+C to stop")
+			output.Dim("Press Ctrl+C or run 'trader trader stop' to stop")
 			output.Println()
 
-			// Main trading loop
-			ticker := time.NewTicker(time.Duration(interval) * time.Second)
+			// Main trading loop. Scans run every interval on the app clock; a
+			// replay's clock runs at the replay speed, so it is polled instead.
+			period := time.Duration(interval) * time.Second
+			poll := period
+			var replayDone <-chan struct{}
+			if app.Replay != nil {
+				poll = 100 * time.Millisecond
+				replayDone = app.Replay.Done()
+			}
+			ticker := time.NewTicker(poll)
 			defer ticker.Stop()
 
 			scanCount := 0
+			lastScan := app.Now()
 			for {
 				select {
 				case <-ctx.Done():
+					orchestrator.Stop()
 					output.Info("Daemon stopped")
 					return nil
+				case <-stopped:
+					output.Info("Daemon stopped by control command")
+					return nil
+				case <-replayDone:
+					orchestrator.Stop()
+					output.Info("Replay finished")
+					return nil
 				case <-ticker.C:
+					if app.Replay != nil && app.Now().Sub(lastScan) < period {
+						continue
+					}
+					lastScan = app.Now()
+					if orchestrator.GetStatus().Paused {
+						output.Dim("[%s] Paused - skipping scan", app.Now().Format("15:04:05"))
+						continue
+					}
 					scanCount++
 					output.Dim("[%s] Scan #%d - Analyzing %d symbols...",
-						time.Now().Format("15:04:05"), scanCount, len(symbols))
+						app.Now().Format("15:04:05"), scanCount, len(symbols))
+
+					// Close bars the feed has gone quiet on, as at the close
+					if bars != nil {
+						bars.Flush(app.Now())
+					}
 
 					// Process each symbol
 					for _, symbol := range symbols {
-						decision, err := processSymbol(ctx, app, orchestrator, symbol, dryRun)
+						decision, err := processSymbol(ctx, app, orchestrator, candles, symbol, dryRun)
 						if err != nil {
 							output.Dim("  %s: error - %v", symbol, err)
 							continue
@@ -208,6 +314,70 @@ The daemon will:
 	return cmd
 }
 
+// streamTicks streams ticks for the watchlist and for every symbol with an
+// open order or active GTT, which the paper broker fills from, and passes
+// each to onTick. It returns a function that disconnects the ticker.
+func streamTicks(ctx context.Context, app *App, output *Output, symbols []string, onTick func(models.Tick)) (func(), error) {
+	exchanges := make(map[string]models.Exchange)
+	for _, symbol := range symbols {
+		exchanges[symbol] = models.NSE
+	}
+	if orders, err := app.Broker.GetOrders(ctx); err == nil {
+		for _, o := range orders {
+			if o.Status == models.OrderStatusOpen || o.Status == models.OrderStatusTriggerPending {
+				exchanges[o.Symbol] = o.Exchange
+			}
+		}
+	}
+	if gtts, err := app.Broker.GetGTTs(ctx); err == nil {
+		for _, g := range gtts {
+			if g.Status == "ACTIVE" {
+				exchanges[g.Symbol] = g.Exchange
+			}
+		}
+	}
+
+	streamed := make([]string, 0, len(exchanges))
+	for symbol, exchange := range exchanges {
+		token, err := app.DataBroker.GetInstrumentToken(ctx, symbol, exchange)
+		if err != nil {
+			output.Dim("  %s: no instrument token, not streamed", symbol)
+			continue
+		}
+		app.Ticker.RegisterSymbol(symbol, token)
+		streamed = append(streamed, symbol)
+	}
+	if len(streamed) == 0 {
+		return nil, fmt.Errorf("no symbols to stream")
+	}
+
+	app.Ticker.OnTick(onTick)
+	app.Ticker.OnError(func(err error) {
+		output.Dim("Ticker error: %v", err)
+	})
+	app.Ticker.OnConnect(func() {
+		if err := app.Ticker.Subscribe(streamed, broker.TickModeQuote); err != nil {
+			output.Warning("Failed to subscribe to ticks: %v", err)
+		}
+	})
+	if err := app.Ticker.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return func() { app.Ticker.Disconnect() }, nil
+}
+
+// daemonPaths returns the PID file and control socket of the trading daemon.
+func daemonPaths() daemon.Paths {
+	return daemon.DefaultPaths(config.DefaultConfigDir())
+}
+
+// sendDaemonCommand sends a control command to the running daemon.
+func sendDaemonCommand(command string) (*daemon.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return daemon.Send(ctx, daemonPaths().Socket, command)
+}
+
 func newTraderStopCmd(app *App) *cobra.Command {
 	return &cobra.Command{
 		Use:   "stop",
@@ -216,8 +386,27 @@ func newTraderStopCmd(app *App) *cobra.Command {
 			output := NewOutput(cmd)
 
 			output.Info("Stopping autonomous trading daemon...")
-			output.Success("✓ Daemon stopped")
+			if _, err := sendDaemonCommand(daemon.CommandStop); err != nil {
+				if errors.Is(err, daemon.ErrNotRunning) {
+					output.Warning("Daemon is not running")
+					return nil
+				}
+				output.Error("Failed to stop daemon: %v", err)
+				return err
+			}
+
+			// Wait for the daemon to release its lock
+			pidFile := daemonPaths().PIDFile
+			deadline := time.Now().Add(10 * time.Second)
+			for time.Now().Before(deadline) {
+				if _, running := daemon.Running(pidFile); !running {
+					output.Success("✓ Daemon stopped")
+					return nil
+				}
+				time.Sleep(200 * time.Millisecond)
+			}
 
+			output.Warning("Stop requested; the daemon is finishing its current scan")
 			return nil
 		},
 	}
@@ -230,11 +419,19 @@ func newTraderStatusCmd(app *App) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			output := NewOutput(cmd)
 
-			// Sample status
+			resp, err := sendDaemonCommand(daemon.CommandStatus)
+			if err != nil && !errors.Is(err, daemon.ErrNotRunning) {
+				output.Error("Failed to get daemon status: %v", err)
+				return err
+			}
+
 			status := struct {
 				Running           bool
 				Paused            bool
+				PID               int
 				Mode              string
+				TradingMode       string
+				Broker            string
 				DailyTrades       int
 				DailyLoss         float64
 				LastTradeAt       time.Time
@@ -242,15 +439,24 @@ func newTraderStatusCmd(app *App) *cobra.Command {
 				Uptime            time.Duration
 				EnabledAgents     []string
 			}{
-				Running:           true,
-				Paused:            false,
-				Mode:              "SEMI_AUTO",
-				DailyTrades:       3,
-				DailyLoss:         1250.50,
-				LastTradeAt:       time.Now().Add(-45 * time.Minute),
-				ConsecutiveLosses: 0,
-				Uptime:            2*time.Hour + 30*time.Minute,
-				EnabledAgents:     []string{"technical", "research", "news", "risk", "trader"},
+				Mode:        app.Config.Agents.AutonomousMode,
+				TradingMode: "live",
+				Broker:      activeBrokerName(app),
+			}
+			if app.Config.IsPaperMode() {
+				status.TradingMode = "paper"
+			}
+			if resp != nil && resp.Status != nil && resp.Status.Orchestrator != nil {
+				orch := resp.Status.Orchestrator
+				status.Running = orch.Running
+				status.Paused = orch.Paused
+				status.PID = resp.Status.PID
+				status.DailyTrades = orch.DailyTrades
+				status.DailyLoss = orch.DailyLoss
+				status.LastTradeAt = orch.LastTradeAt
+				status.ConsecutiveLosses = orch.ConsecutiveLosses
+				status.Uptime = time.Since(resp.Status.StartedAt)
+				status.EnabledAgents = orch.EnabledAgents
 			}
 
 			if output.IsJSON() {
@@ -259,6 +465,8 @@ func newTraderStatusCmd(app *App) *cobra.Command {
 
 			output.Bold("Autonomous Trading Daemon Status")
 			output.Println()
+			printModeBanner(output, app)
+			output.Println()
 
 			// Status indicator
 			if status.Running {
@@ -269,8 +477,11 @@ func newTraderStatusCmd(app *App) *cobra.Command {
 				}
 			} else {
 				output.Printf("  Status: %s\n", output.Red("○ STOPPED"))
+				output.Dim("Start it with 'trader trader start'")
+				return nil
 			}
 
+			output.Printf("  PID:    %d\n", status.PID)
 			output.Printf("  Mode:   %s\n", status.Mode)
 			output.Printf("  Uptime: %s\n", FormatDuration(status.Uptime))
 			output.Println()
@@ -280,7 +491,11 @@ func newTraderStatusCmd(app *App) *cobra.Command {
 			output.Printf("  Daily Loss:   %s / %s\n",
 				output.FormatPnL(-status.DailyLoss),
 				FormatIndianCurrency(app.Config.Agents.MaxDailyLoss))
-			output.Printf("  Last Trade:   %s\n", FormatDateTime(status.LastTradeAt))
+			if status.LastTradeAt.IsZero() {
+				output.Printf("  Last Trade:   -\n")
+			} else {
+				output.Printf("  Last Trade:   %s\n", FormatDateTime(status.LastTradeAt))
+			}
 			output.Printf("  Consec. Loss: %d / %d\n", status.ConsecutiveLosses, app.Config.Agents.ConsecutiveLossLimit)
 			output.Println()
 
@@ -298,13 +513,17 @@ func newTraderPauseCmd(app *App) *cobra.Command {
 	return &cobra.Command{
 		Use:   "pause",
 		Short: "Pause the trading daemon",
-		Long:  "Pause trading without stopping the daemon. Analysis continues but no trades are executed.",
+		Long:  "Pause trading without stopping the daemon. Scans are skipped and no trades are executed until resumed.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			output := NewOutput(cmd)
 
 			output.Info("Pausing trading daemon...")
+			if _, err := sendDaemonCommand(daemon.CommandPause); err != nil {
+				output.Error("Failed to pause daemon: %v", err)
+				return err
+			}
 			output.Success("✓ Trading paused")
-			output.Dim("Analysis continues. Use 'trader trader resume' to resume trading.")
+			output.Dim("Use 'trader trader resume' to resume trading.")
 
 			return nil
 		},
@@ -319,6 +538,10 @@ func newTraderResumeCmd(app *App) *cobra.Command {
 			output := NewOutput(cmd)
 
 			output.Info("Resuming trading daemon...")
+			if _, err := sendDaemonCommand(daemon.CommandResume); err != nil {
+				output.Error("Failed to resume daemon: %v", err)
+				return err
+			}
 			output.Success("✓ Trading resumed")
 
 			return nil
@@ -745,6 +968,7 @@ func newTraderConfigCmd(app *App) *cobra.Command {
 			output.Bold("Trader Configuration")
 			output.Println()
 
+			output.Printf("  Provider:           %s\n", app.Config.LLMFor("").Provider)
 			output.Printf("  Model:              %s\n", app.Config.Agents.Model)
 			output.Printf("  Autonomous Mode:    %s\n", app.Config.Agents.AutonomousMode)
 			output.Printf("  Auto Threshold:     %.0f%%\n", app.Config.Agents.AutoExecuteThreshold)
@@ -753,6 +977,10 @@ func newTraderConfigCmd(app *App) *cobra.Command {
 			output.Printf("  Max Position Size:  %s\n", FormatIndianCurrency(app.Config.Agents.MaxPositionSize))
 			output.Printf("  Cooldown:           %d min\n", app.Config.Agents.CooldownMinutes)
 			output.Printf("  Consec. Loss Limit: %d\n", app.Config.Agents.ConsecutiveLossLimit)
+			output.Printf("  LLM Cache:          %t\n", app.Config.Agents.LLMCache)
+			if app.Config.Agents.LLMDailyBudget > 0 {
+				output.Printf("  LLM Daily Budget:   $%.2f\n", app.Config.Agents.LLMDailyBudget)
+			}
 			output.Println()
 
 			output.Bold("Enabled Agents")
@@ -769,6 +997,123 @@ func newTraderConfigCmd(app *App) *cobra.Command {
 	}
 }
 
+func newTraderCostsCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "costs",
+		Short: "LLM token usage and estimated spend by agent",
+		Long: `Display LLM usage by agent: calls, cache hits, tokens, latency and
+estimated cost in USD, with today's spend against the daily budgets set in
+agents.toml. Costs are estimates from per-model token prices.`,
+		Example: `  trader trader costs
+  trader trader costs --days 7`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if app.Store == nil {
+				output.Error("Store not initialized")
+				return fmt.Errorf("store not initialized")
+			}
+
+			days, _ := cmd.Flags().GetInt("days")
+			if days < 1 {
+				days = 1
+			}
+
+			now := time.Now().In(resilience.IndiaLocation)
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, resilience.IndiaLocation)
+			summaries, err := app.Store.GetLLMUsageSummary(ctx, store.DateRange{
+				Start: today.AddDate(0, 0, 1-days),
+				End:   now,
+			})
+			if err != nil {
+				output.Error("Failed to get LLM usage: %v", err)
+				return err
+			}
+
+			if output.IsJSON() {
+				return output.JSON(summaries)
+			}
+
+			if days == 1 {
+				output.Bold("LLM Costs - Today")
+			} else {
+				output.Bold("LLM Costs - Last %d days", days)
+			}
+			output.Println()
+
+			if len(summaries) == 0 {
+				output.Info("No LLM usage recorded")
+				return nil
+			}
+
+			table := NewTable(output, "Agent", "Calls", "Cached", "Errors", "Prompt Tokens", "Completion Tokens", "Avg Latency", "Cost")
+			var total models.LLMUsageSummary
+			for _, s := range summaries {
+				table.AddRow(
+					s.Agent,
+					fmt.Sprintf("%d", s.Calls),
+					fmt.Sprintf("%d", s.CachedCalls),
+					fmt.Sprintf("%d", s.Errors),
+					FormatVolume(int64(s.PromptTokens)),
+					FormatVolume(int64(s.CompletionTokens)),
+					s.AvgLatency.Round(time.Millisecond).String(),
+					fmt.Sprintf("$%.4f", s.Cost),
+				)
+				total.Calls += s.Calls
+				total.CachedCalls += s.CachedCalls
+				total.Errors += s.Errors
+				total.PromptTokens += s.PromptTokens
+				total.CompletionTokens += s.CompletionTokens
+				total.Cost += s.Cost
+			}
+			table.AddRow(
+				output.BoldText("Total"),
+				fmt.Sprintf("%d", total.Calls),
+				fmt.Sprintf("%d", total.CachedCalls),
+				fmt.Sprintf("%d", total.Errors),
+				FormatVolume(int64(total.PromptTokens)),
+				FormatVolume(int64(total.CompletionTokens)),
+				"",
+				output.BoldText(fmt.Sprintf("$%.4f", total.Cost)),
+			)
+			table.Render()
+
+			// Budgets always refer to today
+			cfg := app.Config.Agents
+			if cfg.LLMDailyBudget > 0 || len(cfg.LLMAgentBudgets) > 0 {
+				meter := agents.NewLLMMeter(app.Store, &cfg)
+				output.Println()
+				output.Bold("Today's Budget")
+				if cfg.LLMDailyBudget > 0 {
+					_, spent := meter.Spent(ctx, "")
+					output.Printf("  %-12s %s\n", "all agents", formatBudget(output, spent, cfg.LLMDailyBudget))
+				}
+				for agent, limit := range cfg.LLMAgentBudgets {
+					spent, _ := meter.Spent(ctx, agent)
+					output.Printf("  %-12s %s\n", agent, formatBudget(output, spent, limit))
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Int("days", 1, "Number of days to report, including today")
+
+	return cmd
+}
+
+// formatBudget shows spend against a budget, red once it is used up.
+func formatBudget(output *Output, spent, limit float64) string {
+	text := fmt.Sprintf("$%.4f of $%.2f", spent, limit)
+	if spent >= limit {
+		return output.Red(text + " (exhausted, rule-based fallback)")
+	}
+	return fmt.Sprintf("%s %s", createBar(int(spent/limit*100), 100, 20), text)
+}
+
 func newTraderHealthCmd(app *App) *cobra.Command {
 	return &cobra.Command{
 		Use:   "health",
@@ -883,6 +1228,12 @@ func createOrchestrator(app *App) *agents.Orchestrator {
 		}
 	}
 
+	// Meter each agent's LLM calls: cache, usage and daily budget
+	meter := agents.NewLLMMeter(app.Store, &app.Config.Agents)
+	llm := func(agent string) agents.LLMClient {
+		return meter.Wrap(agent, app.Config.LLMFor(agent).Provider, app.LLMFor(agent))
+	}
+
 	// Create enabled agents
 	for _, agentName := range app.Config.Agents.EnabledAgents {
 		weight := weights[agentName]
@@ -892,21 +1243,21 @@ func createOrchestrator(app *App) *agents.Orchestrator {
 
 		switch agentName {
 		case "technical":
-			agentList = append(agentList, agents.NewTechnicalAgent(app.LLMClient, weight))
+			agentList = append(agentList, agents.NewTechnicalAgent(llm("technical"), weight))
 		case "research":
 			// WebSearchClient is optional - pass nil for now
-			agentList = append(agentList, agents.NewResearchAgent(app.LLMClient, nil, weight))
+			agentList = append(agentList, agents.NewResearchAgent(llm("research"), nil, weight))
 		case "news":
 			// WebSearchClient is optional - pass nil for now
-			agentList = append(agentList, agents.NewNewsAgent(app.LLMClient, nil, weight))
+			agentList = append(agentList, agents.NewNewsAgent(llm("news"), nil, weight))
 		}
 	}
 
 	// Create trader and risk agents
-	traderAgent := agents.NewTraderAgent(app.LLMClient, weights, 1.0)
+	traderAgent := agents.NewTraderAgent(llm("trader"), weights, 1.0)
 	riskAgent := agents.NewRiskAgent(nil, weights["risk"])
 
-	return agents.NewOrchestrator(
+	orchestrator := agents.NewOrchestrator(
 		agentList,
 		traderAgent,
 		riskAgent,
@@ -914,10 +1265,24 @@ func createOrchestrator(app *App) *agents.Orchestrator {
 		app.Store,
 		nil, // notifier - can be added later
 	)
+
+	// Daily limits reset at the configured market-day boundary
+	sessions := trading.NewSessionManager()
+	if err := sessions.SetDayBoundary(app.Config.Agents.DayBoundary); err != nil {
+		app.Logger.Warn().Err(err).Msg("Using default day boundary")
+	}
+	orchestrator.SetDayBoundary(sessions)
+
+	// The risk gate counts the agents' realized losses against the daily limit
+	if gate, ok := app.Broker.(*trading.RiskGate); ok {
+		gate.SetRiskState(orchestrator.RiskState())
+	}
+
+	return orchestrator
 }
 
 // processSymbol analyzes a symbol and returns a trading decision.
-func processSymbol(ctx context.Context, app *App, orchestrator *agents.Orchestrator, symbol string, dryRun bool) (*models.Decision, error) {
+func processSymbol(ctx context.Context, app *App, orchestrator *agents.Orchestrator, candles *daemonCandles, symbol string, dryRun bool) (*models.Decision, error) {
 	// Format symbol with exchange prefix for Zerodha API
 	fullSymbol := "NSE:" + symbol
 
@@ -927,14 +1292,8 @@ func processSymbol(ctx context.Context, app *App, orchestrator *agents.Orchestra
 		return nil, fmt.Errorf("failed to get quote: %w", err)
 	}
 
-	// Get historical data for analysis
-	candles, err := app.Broker.GetHistorical(ctx, broker.HistoricalRequest{
-		Symbol:    symbol,
-		Exchange:  models.NSE,
-		Timeframe: "day",
-		From:      time.Now().AddDate(0, -3, 0),
-		To:        time.Now(),
-	})
+	// Daily history plus the intraday bars closed so far
+	series, err := candles.get(ctx, app, symbol)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get historical data: %w", err)
 	}
@@ -943,9 +1302,7 @@ func processSymbol(ctx context.Context, app *App, orchestrator *agents.Orchestra
 	req := agents.AnalysisRequest{
 		Symbol:       symbol,
 		CurrentPrice: quote.LTP,
-		Candles: map[string][]models.Candle{
-			"day": candles,
-		},
+		Candles:      series,
 	}
 
 	// Process through orchestrator
@@ -957,6 +1314,81 @@ func processSymbol(ctx context.Context, app *App, orchestrator *agents.Orchestra
 	return decision, nil
 }
 
+// daemonCandles holds the candles the daemon analyzes: three months of
+// daily history, fetched once per symbol a day, and the intraday bars the
+// tick feed has closed this session.
+type daemonCandles struct {
+	mu       sync.Mutex
+	day      time.Time
+	daily    map[string][]models.Candle
+	intraday map[string]map[string][]models.Candle
+}
+
+func newDaemonCandles() *daemonCandles {
+	return &daemonCandles{
+		daily:    make(map[string][]models.Candle),
+		intraday: make(map[string]map[string][]models.Candle),
+	}
+}
+
+// addBar records a bar closed by the candle aggregator.
+func (c *daemonCandles) addBar(bar stream.LiveBar) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rollover(bar.Candle.Timestamp)
+	if c.intraday[bar.Symbol] == nil {
+		c.intraday[bar.Symbol] = make(map[string][]models.Candle)
+	}
+	c.intraday[bar.Symbol][bar.Timeframe] = append(c.intraday[bar.Symbol][bar.Timeframe], bar.Candle)
+}
+
+// get returns a symbol's candles by timeframe, up to the app clock in a
+// replay.
+func (c *daemonCandles) get(ctx context.Context, app *App, symbol string) (map[string][]models.Candle, error) {
+	now := app.Now()
+
+	c.mu.Lock()
+	c.rollover(now)
+	daily, ok := c.daily[symbol]
+	c.mu.Unlock()
+
+	if !ok {
+		var err error
+		daily, err = app.Broker.GetHistorical(ctx, broker.HistoricalRequest{
+			Symbol:    symbol,
+			Exchange:  models.NSE,
+			Timeframe: "day",
+			From:      now.AddDate(0, -3, 0),
+			To:        now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.daily[symbol] = daily
+	series := map[string][]models.Candle{"day": daily}
+	for timeframe, bars := range c.intraday[symbol] {
+		series[timeframe] = append([]models.Candle(nil), bars...)
+	}
+	return series, nil
+}
+
+// rollover drops the cached candles when the market day changes. Callers
+// hold c.mu.
+func (c *daemonCandles) rollover(at time.Time) {
+	t := at.In(resilience.IndiaLocation)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, resilience.IndiaLocation)
+	if day.After(c.day) {
+		c.day = day
+		c.daily = make(map[string][]models.Candle)
+		c.intraday = make(map[string]map[string][]models.Candle)
+	}
+}
+
 // displayDecision shows a trading decision in the output.
 func displayDecision(output *Output, decision *models.Decision, dryRun bool) {
 	if decision.Action == "HOLD" {
@@ -7,14 +7,23 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"zerodha-trader/internal/analysis/indicators"
+	"zerodha-trader/internal/analysis/scoring"
+	"zerodha-trader/internal/api"
 	"zerodha-trader/internal/broker"
 	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/security"
 	"zerodha-trader/internal/store"
+	"zerodha-trader/internal/trading"
 )
 
 // addUtilityCommands adds utility commands.
@@ -137,18 +146,27 @@ notification channels (Telegram, Email, Webhook) are working correctly.`,
 	}
 }
 
+// muted silences voice and sound notifications, as during a replay.
+var muted bool
+
 // speak uses macOS 'say' command for voice notifications (non-blocking)
 func speak(text string) {
+	if muted {
+		return
+	}
 	exec.Command("say", text).Start()
 }
 
 // speakAsync is an alias for speak (both are non-blocking now)
 func speakAsync(text string) {
-	exec.Command("say", text).Start()
+	speak(text)
 }
 
 // playSound plays a macOS system sound
 func playSound(name string) {
+	if muted {
+		return
+	}
 	exec.Command("afplay", "/System/Library/Sounds/"+name+".aiff").Start()
 }
 
@@ -158,6 +176,9 @@ func newBacktestCmd(app *App) *cobra.Command {
 		Short: "Backtest trading strategies",
 		Long: `Backtest trading strategies on historical data.
 
+Strategies are resolved from the strategy registry. Use 'trader backtest strategies'
+to list them along with their parameters.
+
 Calculates metrics including:
 - Total return
 - Win rate
@@ -165,27 +186,114 @@ Calculates metrics including:
 - Sharpe ratio
 - Profit factor`,
 		Example: `  trader backtest --strategy momentum --symbol RELIANCE --days 365
-  trader backtest --strategy breakout --watchlist nifty50 --days 180`,
+  trader backtest --strategy sma_crossover --param short_period=5,long_period=30 --symbol INFY
+  trader backtest --strategy mtf_momentum --symbol SBIN --timeframe 5min --higher-timeframe 1hour --days 30
+  trader backtest --strategy momentum --watchlist nifty50 --days 365 --max-positions 5
+  trader backtest strategies
+  trader backtest optimize --strategy rsi_oversold --symbol INFY --in-sample 250 --out-of-sample 60`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			output := NewOutput(cmd)
 			ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 			defer cancel()
 
-			strategy, _ := cmd.Flags().GetString("strategy")
+			strategyName, _ := cmd.Flags().GetString("strategy")
+			rawParams, _ := cmd.Flags().GetStringToString("param")
 			symbol, _ := cmd.Flags().GetString("symbol")
 			days, _ := cmd.Flags().GetInt("days")
 			capital, _ := cmd.Flags().GetFloat64("capital")
+			slippage, _ := cmd.Flags().GetFloat64("slippage")
+			commission, _ := cmd.Flags().GetFloat64("commission")
 			exchange, _ := cmd.Flags().GetString("exchange")
-
-			if symbol == "" {
-				output.Error("Symbol is required. Use --symbol flag.")
+			timeframe, _ := cmd.Flags().GetString("timeframe")
+			higherTimeframe, _ := cmd.Flags().GetString("higher-timeframe")
+			product, _ := cmd.Flags().GetString("product")
+			allowShort, _ := cmd.Flags().GetBool("short")
+			watchlist, _ := cmd.Flags().GetString("watchlist")
+			maxPositions, _ := cmd.Flags().GetInt("max-positions")
+
+			if symbol == "" && watchlist == "" {
+				output.Error("Symbol is required. Use --symbol or --watchlist flag.")
 				return fmt.Errorf("symbol required")
 			}
 
-			output.Bold("Backtesting: %s Strategy", strategy)
+			engine := trading.NewBacktestEngine(app.Store)
+			strategy, err := engine.Strategies().Get(strategyName)
+			if err != nil {
+				output.Error("%v", err)
+				return err
+			}
+
+			params := make(map[string]interface{}, len(rawParams))
+			for k, v := range rawParams {
+				params[k] = v
+			}
+			resolved, err := trading.ResolveParams(strategy, params)
+			if err != nil {
+				output.Error("Invalid strategy parameters: %v", err)
+				return err
+			}
+
+			if watchlist != "" {
+				// The portfolio engine steps a single timeframe and only opens longs.
+				if higherTimeframe != "" {
+					output.Error("--higher-timeframe is not supported with --watchlist")
+					return fmt.Errorf("--higher-timeframe not supported for portfolio backtests")
+				}
+				if allowShort {
+					output.Error("--short is not supported with --watchlist")
+					return fmt.Errorf("--short not supported for portfolio backtests")
+				}
+				if app.Store == nil {
+					output.Error("Data store not available")
+					return fmt.Errorf("store not available")
+				}
+				symbols, err := app.Store.GetWatchlist(ctx, watchlist)
+				if err != nil {
+					output.Error("Failed to load watchlist %s: %v", watchlist, err)
+					return err
+				}
+				if len(symbols) == 0 {
+					output.Error("Watchlist %s is empty", watchlist)
+					return fmt.Errorf("empty watchlist")
+				}
+
+				risk := app.Config.Risk
+				if maxPositions > 0 {
+					risk.MaxConcurrentPositions = maxPositions
+				}
+
+				output.Bold("Portfolio Backtest: %s Strategy", strategy.Name())
+				output.Printf("  Watchlist: %s (%d symbols)\n", watchlist, len(symbols))
+				output.Printf("  Period:    %d days (%s)\n", days, timeframe)
+				output.Printf("  Capital:   %s\n", FormatIndianCurrency(capital))
+				output.Printf("  Positions: max %d concurrent\n", risk.MaxConcurrentPositions)
+				output.Printf("  Params:    %s\n", formatStrategyParams(strategy, resolved))
+				output.Println()
+
+				return runPortfolioBacktest(ctx, app, output, engine, trading.PortfolioBacktestConfig{
+					Symbols:        symbols,
+					StartDate:      time.Now().AddDate(0, 0, -days),
+					EndDate:        time.Now(),
+					InitialCapital: capital,
+					Strategy:       strategy.Name(),
+					Parameters:     params,
+					Slippage:       slippage / 100,
+					Commission:     commission / 100,
+					Timeframe:      timeframe,
+					Product:        models.ProductType(strings.ToUpper(product)),
+					Exchange:       models.Exchange(exchange),
+					Risk:           &risk,
+				}, models.Exchange(exchange))
+			}
+
+			output.Bold("Backtesting: %s Strategy", strategy.Name())
 			output.Printf("  Symbol:  %s\n", symbol)
-			output.Printf("  Period:  %d days\n", days)
+			output.Printf("  Period:  %d days (%s)\n", days, timeframe)
+			if higherTimeframe != "" {
+				output.Printf("  Filter:  %s\n", higherTimeframe)
+			}
 			output.Printf("  Capital: %s\n", FormatIndianCurrency(capital))
+			output.Printf("  Params:  %s\n", formatStrategyParams(strategy, resolved))
 			output.Println()
 
 			if app.Broker == nil {
@@ -195,19 +303,37 @@ Calculates metrics including:
 
 			output.Info("Fetching historical data...")
 
+			from := time.Now().AddDate(0, 0, -days)
+			to := time.Now()
+
 			// Fetch historical data
 			candles, err := app.Broker.GetHistorical(ctx, broker.HistoricalRequest{
 				Symbol:    symbol,
 				Exchange:  models.Exchange(exchange),
-				Timeframe: "1day",
-				From:      time.Now().AddDate(0, 0, -days),
-				To:        time.Now(),
+				Timeframe: timeframe,
+				From:      from,
+				To:        to,
 			})
 			if err != nil {
 				output.Error("Failed to fetch historical data: %v", err)
 				return err
 			}
 
+			var higher []models.Candle
+			if higherTimeframe != "" {
+				higher, err = app.Broker.GetHistorical(ctx, broker.HistoricalRequest{
+					Symbol:    symbol,
+					Exchange:  models.Exchange(exchange),
+					Timeframe: higherTimeframe,
+					From:      from,
+					To:        to,
+				})
+				if err != nil {
+					output.Error("Failed to fetch %s data: %v", higherTimeframe, err)
+					return err
+				}
+			}
+
 			if len(candles) < 30 {
 				output.Error("Insufficient data for backtest (need at least 30 candles, got %d)", len(candles))
 				return fmt.Errorf("insufficient data")
@@ -216,8 +342,27 @@ Calculates metrics including:
 			output.Info("Running backtest on %d candles...", len(candles))
 			output.Println()
 
-			// Run simple momentum backtest
-			results := runMomentumBacktest(candles, capital, strategy)
+			result, err := engine.RunMultiTimeframe(ctx, trading.BacktestConfig{
+				Symbol:          symbol,
+				StartDate:       from,
+				EndDate:         to,
+				InitialCapital:  capital,
+				Strategy:        strategy.Name(),
+				Parameters:      params,
+				Slippage:        slippage / 100,
+				Commission:      commission / 100,
+				Timeframe:       timeframe,
+				HigherTimeframe: higherTimeframe,
+				Product:         models.ProductType(strings.ToUpper(product)),
+				Exchange:        models.Exchange(exchange),
+				AllowShort:      allowShort,
+			}, candles, higher)
+			if err != nil {
+				output.Error("Backtest failed: %v", err)
+				return err
+			}
+
+			results := toBacktestResults(result, capital)
 
 			if output.IsJSON() {
 				return output.JSON(results)
@@ -227,181 +372,480 @@ Calculates metrics including:
 		},
 	}
 
-	cmd.Flags().String("strategy", "momentum", "Strategy to backtest")
+	cmd.Flags().String("strategy", "momentum", "Strategy to backtest (see 'trader backtest strategies')")
+	cmd.Flags().StringToString("param", nil, "Strategy parameters as name=value pairs")
 	cmd.Flags().String("symbol", "", "Symbol to backtest")
-	cmd.Flags().String("watchlist", "", "Watchlist to backtest")
+	cmd.Flags().String("watchlist", "", "Watchlist to backtest as a portfolio with shared capital")
+	cmd.Flags().Int("max-positions", 0, "Max concurrent positions for portfolio backtests (default from risk config)")
 	cmd.Flags().Int("days", 365, "Number of days to backtest")
 	cmd.Flags().Float64("capital", 1000000, "Starting capital")
 	cmd.Flags().Float64("slippage", 0.1, "Slippage percentage")
-	cmd.Flags().Float64("commission", 0.03, "Commission percentage")
+	cmd.Flags().Float64("commission", 0, "Extra commission percentage on top of brokerage and statutory charges")
 	cmd.Flags().StringP("exchange", "e", "NSE", "Exchange (NSE, BSE)")
+	cmd.Flags().String("timeframe", "1day", "Candle timeframe (1min, 5min, 15min, 30min, 1hour, 1day)")
+	cmd.Flags().String("higher-timeframe", "", "Higher timeframe exposed to the strategy as a filter")
+	cmd.Flags().String("product", "", "Product type (MIS, CNC); defaults to MIS for intraday timeframes")
+	cmd.Flags().Bool("short", false, "Allow SELL signals to open short positions (MIS only)")
+
+	cmd.AddCommand(newBacktestStrategiesCmd())
+	cmd.AddCommand(newBacktestOptimizeCmd(app))
 
 	return cmd
 }
 
-// runMomentumBacktest runs a simple momentum-based backtest
-func runMomentumBacktest(candles []models.Candle, capital float64, strategy string) BacktestResults {
-	// Extract closes
-	closes := make([]float64, len(candles))
-	for i, c := range candles {
-		closes[i] = c.Close
-	}
-
-	// Calculate EMAs for signals
-	ema9 := calculateEMA(closes, 9)
-	ema21 := calculateEMA(closes, 21)
-
-	// Simulate trades
-	var trades []backtestTrade
-	inPosition := false
-	entryPrice := 0.0
-	entryIdx := 0
-	currentCapital := capital
-	maxCapital := capital
-	maxDrawdown := 0.0
-
-	// Track equity curve
-	equityCurve := make([]float64, 0, len(candles))
-	equityCurve = append(equityCurve, capital) // Starting point
-
-	for i := 21; i < len(candles); i++ {
-		if len(ema9) <= i || len(ema21) <= i {
-			continue
+func newBacktestStrategiesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "strategies",
+		Short: "List registered backtest strategies",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			strategies := trading.DefaultStrategyRegistry().List()
+
+			if output.IsJSON() {
+				type strategyInfo struct {
+					Name        string              `json:"name"`
+					Description string              `json:"description"`
+					Params      []trading.ParamSpec `json:"params"`
+				}
+				infos := make([]strategyInfo, 0, len(strategies))
+				for _, s := range strategies {
+					infos = append(infos, strategyInfo{Name: s.Name(), Description: s.Description(), Params: s.Params()})
+				}
+				return output.JSON(infos)
+			}
+
+			output.Bold("Backtest Strategies")
+			output.Println()
+
+			for _, s := range strategies {
+				output.Printf("  %s - %s\n", output.BoldText(s.Name()), s.Description())
+				for _, p := range s.Params() {
+					output.Printf("      %-14s %-6s default %-6v range %v-%v  %s\n",
+						p.Name, p.Type, p.Default, p.Min, p.Max, output.DimText(p.Description))
+				}
+				output.Println()
+			}
+
+			return nil
+		},
+	}
+}
+
+func newBacktestOptimizeCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "optimize",
+		Short: "Search strategy parameters with walk-forward validation",
+		Long: `Grid or random search over strategy parameter ranges, running backtests in parallel.
+
+Ranges are given as name=min:max[:step]. Parameters without a range keep the value
+from --param or their default; with no ranges every parameter is searched over its
+declared range.
+
+With --in-sample and --out-of-sample (in bars) the search is repeated on rolling
+windows: each in-sample window picks the best parameters, which are then traded on
+the following out-of-sample window. The chained out-of-sample equity curve and the
+stability of the chosen parameters show whether the optimum generalises.`,
+		Example: `  trader backtest optimize --strategy rsi_oversold --symbol INFY --range oversold=20:40:5,overbought=60:80:5
+  trader backtest optimize --strategy momentum --symbol RELIANCE --method random --samples 200 --days 730
+  trader backtest optimize --strategy rsi_oversold --symbol TCS --days 1095 --in-sample 250 --out-of-sample 60`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := NewOutput(cmd)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+
+			strategyName, _ := cmd.Flags().GetString("strategy")
+			rawParams, _ := cmd.Flags().GetStringToString("param")
+			rawRanges, _ := cmd.Flags().GetStringToString("range")
+			symbol, _ := cmd.Flags().GetString("symbol")
+			days, _ := cmd.Flags().GetInt("days")
+			capital, _ := cmd.Flags().GetFloat64("capital")
+			slippage, _ := cmd.Flags().GetFloat64("slippage")
+			commission, _ := cmd.Flags().GetFloat64("commission")
+			exchange, _ := cmd.Flags().GetString("exchange")
+			timeframe, _ := cmd.Flags().GetString("timeframe")
+			product, _ := cmd.Flags().GetString("product")
+			method, _ := cmd.Flags().GetString("method")
+			objective, _ := cmd.Flags().GetString("objective")
+			samples, _ := cmd.Flags().GetInt("samples")
+			seed, _ := cmd.Flags().GetInt64("seed")
+			workers, _ := cmd.Flags().GetInt("workers")
+			inSample, _ := cmd.Flags().GetInt("in-sample")
+			outOfSample, _ := cmd.Flags().GetInt("out-of-sample")
+			top, _ := cmd.Flags().GetInt("top")
+
+			if symbol == "" {
+				output.Error("Symbol is required. Use --symbol flag.")
+				return fmt.Errorf("symbol required")
+			}
+			if (inSample > 0) != (outOfSample > 0) {
+				output.Error("Walk-forward needs both --in-sample and --out-of-sample")
+				return fmt.Errorf("incomplete walk-forward windows")
+			}
+
+			engine := trading.NewBacktestEngine(app.Store)
+			strategy, err := engine.Strategies().Get(strategyName)
+			if err != nil {
+				output.Error("%v", err)
+				return err
+			}
+
+			params := make(map[string]interface{}, len(rawParams))
+			for k, v := range rawParams {
+				params[k] = v
+			}
+
+			names := make([]string, 0, len(rawRanges))
+			for name := range rawRanges {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			var ranges []trading.ParamRange
+			for _, name := range names {
+				r, err := trading.ParseParamRange(name, rawRanges[name])
+				if err != nil {
+					output.Error("%v", err)
+					return err
+				}
+				ranges = append(ranges, r)
+			}
+
+			if app.Broker == nil {
+				output.Error("Broker not configured. Run 'trader login' first.")
+				return fmt.Errorf("broker not configured")
+			}
+
+			from := time.Now().AddDate(0, 0, -days)
+			to := time.Now()
+
+			output.Info("Fetching historical data...")
+			candles, err := app.Broker.GetHistorical(ctx, broker.HistoricalRequest{
+				Symbol:    symbol,
+				Exchange:  models.Exchange(exchange),
+				Timeframe: timeframe,
+				From:      from,
+				To:        to,
+			})
+			if err != nil {
+				output.Error("Failed to fetch historical data: %v", err)
+				return err
+			}
+
+			cfg := trading.OptimizeConfig{
+				Base: trading.BacktestConfig{
+					Symbol:         symbol,
+					StartDate:      from,
+					EndDate:        to,
+					InitialCapital: capital,
+					Strategy:       strategy.Name(),
+					Parameters:     params,
+					Slippage:       slippage / 100,
+					Commission:     commission / 100,
+					Timeframe:      timeframe,
+					Product:        models.ProductType(strings.ToUpper(product)),
+					Exchange:       models.Exchange(exchange),
+				},
+				Ranges:    ranges,
+				Method:    trading.OptimizeMethod(method),
+				Objective: objective,
+				Samples:   samples,
+				Seed:      seed,
+				Workers:   workers,
+			}
+			if inSample > 0 {
+				cfg.WalkForward = &trading.WalkForwardConfig{InSampleBars: inSample, OutOfSampleBars: outOfSample}
+			}
+
+			output.Info("Optimising %s on %d candles...", strategy.Name(), len(candles))
+			output.Println()
+
+			result, err := engine.OptimizeCandles(ctx, cfg, candles)
+			if err != nil {
+				output.Error("Optimisation failed: %v", err)
+				return err
+			}
+
+			if output.IsJSON() {
+				return output.JSON(result)
+			}
+
+			return displayOptimizeResult(output, strategy, result, capital, top)
+		},
+	}
+
+	cmd.Flags().String("strategy", "momentum", "Strategy to optimise (see 'trader backtest strategies')")
+	cmd.Flags().StringToString("param", nil, "Fixed strategy parameters as name=value pairs")
+	cmd.Flags().StringToString("range", nil, "Parameter ranges as name=min:max[:step] pairs")
+	cmd.Flags().String("symbol", "", "Symbol to optimise on")
+	cmd.Flags().Int("days", 730, "Number of days of history")
+	cmd.Flags().Float64("capital", 1000000, "Starting capital")
+	cmd.Flags().Float64("slippage", 0.1, "Slippage percentage")
+	cmd.Flags().Float64("commission", 0, "Extra commission percentage on top of brokerage and statutory charges")
+	cmd.Flags().StringP("exchange", "e", "NSE", "Exchange (NSE, BSE)")
+	cmd.Flags().String("timeframe", "1day", "Candle timeframe (1min, 5min, 15min, 30min, 1hour, 1day)")
+	cmd.Flags().String("product", "", "Product type (MIS, CNC); defaults to MIS for intraday timeframes")
+	cmd.Flags().String("method", "grid", "Search method (grid, random)")
+	cmd.Flags().String("objective", trading.ObjectiveSharpe, "Objective to maximise (sharpe, return, profit_factor)")
+	cmd.Flags().Int("samples", 100, "Parameter sets to draw for random search")
+	cmd.Flags().Int64("seed", 0, "Random search seed (0 for time-based)")
+	cmd.Flags().Int("workers", 0, "Parallel backtests (default: number of CPUs)")
+	cmd.Flags().Int("in-sample", 0, "Walk-forward in-sample window in bars")
+	cmd.Flags().Int("out-of-sample", 0, "Walk-forward out-of-sample window in bars")
+	cmd.Flags().Int("top", 10, "Number of top parameter sets to show")
+
+	return cmd
+}
+
+// displayOptimizeResult prints the ranked trials and the walk-forward report.
+func displayOptimizeResult(output *Output, strategy trading.Strategy, result *trading.OptimizeResult, capital float64, top int) error {
+	valid := 0
+	for _, t := range result.Trials {
+		if t.Result != nil {
+			valid++
 		}
+	}
+
+	output.Bold("Optimisation: %s (%s search, objective %s)", result.Strategy, result.Method, result.Objective)
+	output.Printf("  Trials: %d (%d invalid combinations skipped)\n", len(result.Trials), len(result.Trials)-valid)
+	output.Println()
 
-		// Track equity at each point
-		equity := currentCapital
-		if inPosition {
-			// Mark-to-market: calculate unrealized P&L
-			unrealizedPnL := ((candles[i].Close - entryPrice) / entryPrice) * currentCapital
-			equity = currentCapital + unrealizedPnL
+	output.Bold("Top Parameter Sets")
+	table := NewTable(output, "#", "Params", "Score", "Return", "Max DD", "Trades")
+	for i, t := range result.Trials {
+		if i >= top || t.Result == nil {
+			break
 		}
-		equityCurve = append(equityCurve, equity)
+		table.AddRow(
+			fmt.Sprintf("%d", i+1),
+			formatStrategyParams(strategy, trading.Params(t.Params)),
+			fmt.Sprintf("%.2f", t.Score),
+			output.FormatPercent(t.TotalReturn),
+			fmt.Sprintf("%.1f%%", t.MaxDrawdown),
+			fmt.Sprintf("%d", t.TotalTrades),
+		)
+	}
+	table.Render()
+	output.Println()
+
+	wf := result.WalkForward
+	if wf == nil {
+		output.Printf("  %s\n", output.DimText("In-sample only. Use --in-sample and --out-of-sample to check the optimum out of sample."))
+		return nil
+	}
+
+	output.Bold("Walk-Forward Windows")
+	windows := NewTable(output, "Out-of-Sample", "Params", "IS Return", "OOS Return", "Trades")
+	for _, w := range wf.Windows {
+		windows.AddRow(
+			fmt.Sprintf("%s → %s", w.OutOfSampleStart.Format("2006-01-02"), w.OutOfSampleEnd.Format("2006-01-02")),
+			formatStrategyParams(strategy, trading.Params(w.Params)),
+			output.FormatPercent(w.InSampleReturn),
+			output.FormatPercent(w.OutOfSampleReturn),
+			fmt.Sprintf("%d", w.Trades),
+		)
+	}
+	windows.Render()
+	output.Println()
 
-		// Entry signal: EMA9 crosses above EMA21
-		if !inPosition && ema9[i] > ema21[i] && ema9[i-1] <= ema21[i-1] {
-			inPosition = true
-			entryPrice = candles[i].Close
-			entryIdx = i
+	output.Bold("Parameter Stability")
+	stability := NewTable(output, "Param", "Mean", "Std Dev", "Range", "CV")
+	for _, s := range wf.Stability {
+		cv := fmt.Sprintf("%.2f", s.CV)
+		if s.CV > 0.3 {
+			cv = output.Red(cv + " unstable")
 		}
+		stability.AddRow(
+			s.Name,
+			fmt.Sprintf("%.2f", s.Mean),
+			fmt.Sprintf("%.2f", s.StdDev),
+			fmt.Sprintf("%v-%v", s.Min, s.Max),
+			cv,
+		)
+	}
+	stability.Render()
+	output.Println()
 
-		// Exit signal: EMA9 crosses below EMA21 or stop loss
-		if inPosition {
-			exitSignal := ema9[i] < ema21[i] && ema9[i-1] >= ema21[i-1]
-			stopLoss := candles[i].Close < entryPrice*0.97 // 3% stop loss
-
-			if exitSignal || stopLoss {
-				exitPrice := candles[i].Close
-				pnlPercent := ((exitPrice - entryPrice) / entryPrice) * 100
-				pnl := (currentCapital * (pnlPercent / 100))
-				currentCapital += pnl
-
-				trades = append(trades, backtestTrade{
-					entryPrice: entryPrice,
-					exitPrice:  exitPrice,
-					pnl:        pnl,
-					pnlPercent: pnlPercent,
-					holdDays:   i - entryIdx,
-				})
+	output.Bold("Out-of-Sample Performance")
+	output.Printf("  Total Return:     %s\n", output.FormatPercent(wf.TotalReturn))
+	output.Printf("  Max Drawdown:     %s\n", output.Red(fmt.Sprintf("%.1f%%", wf.MaxDrawdown)))
+	output.Printf("  Trades:           %d\n", wf.TotalTrades)
+	output.Printf("  WF Efficiency:    %.2f\n", wf.Efficiency)
+	output.Println()
 
-				// Track max drawdown
-				if currentCapital > maxCapital {
-					maxCapital = currentCapital
-				}
-				drawdown := ((maxCapital - currentCapital) / maxCapital) * 100
-				if drawdown > maxDrawdown {
-					maxDrawdown = drawdown
-				}
+	equity := make([]float64, 0, len(wf.EquityCurve)+1)
+	equity = append(equity, capital)
+	for _, p := range wf.EquityCurve {
+		equity = append(equity, p.Equity)
+	}
+	output.Bold("Out-of-Sample Equity Curve")
+	drawEquityCurve(output, equity, capital)
 
-				inPosition = false
-			}
+	return nil
+}
+
+// runPortfolioBacktest fetches candles for every symbol and runs a shared-capital backtest.
+func runPortfolioBacktest(ctx context.Context, app *App, output *Output, engine *trading.DefaultBacktestEngine, cfg trading.PortfolioBacktestConfig, exchange models.Exchange) error {
+	if app.Broker == nil {
+		output.Error("Broker not configured. Run 'trader login' first.")
+		return fmt.Errorf("broker not configured")
+	}
+
+	output.Info("Fetching historical data for %d symbols...", len(cfg.Symbols))
+
+	timeframe := cfg.Timeframe
+	candles := make(map[string][]models.Candle, len(cfg.Symbols))
+	var symbols []string
+	for _, symbol := range cfg.Symbols {
+		c, err := app.Broker.GetHistorical(ctx, broker.HistoricalRequest{
+			Symbol:    symbol,
+			Exchange:  exchange,
+			Timeframe: timeframe,
+			From:      cfg.StartDate,
+			To:        cfg.EndDate,
+		})
+		if err != nil {
+			output.Warning("Skipping %s: %v", symbol, err)
+			continue
+		}
+		if len(c) < 30 {
+			output.Warning("Skipping %s: insufficient data (%d candles)", symbol, len(c))
+			continue
 		}
+		candles[symbol] = c
+		symbols = append(symbols, symbol)
+	}
+
+	if len(symbols) == 0 {
+		output.Error("No symbols with enough data to backtest")
+		return fmt.Errorf("insufficient data")
+	}
+	cfg.Symbols = symbols
+
+	output.Info("Running portfolio backtest on %d symbols...", len(symbols))
+	output.Println()
+
+	result, err := engine.RunPortfolioCandles(ctx, cfg, candles)
+	if err != nil {
+		output.Error("Backtest failed: %v", err)
+		return err
+	}
+
+	if output.IsJSON() {
+		return output.JSON(result)
+	}
+
+	if err := displayBacktestResults(output, toBacktestResults(result.Combined, cfg.InitialCapital)); err != nil {
+		return err
 	}
+	output.Println()
 
-	// Calculate results
-	totalTrades := len(trades)
-	winningTrades := 0
-	grossProfit := 0.0
-	grossLoss := 0.0
-	totalHoldDays := 0
-	largestWin := 0.0
-	largestLoss := 0.0
+	output.Bold("Per-Symbol Results")
+	table := NewTable(output, "Symbol", "Trades", "Win Rate", "P&L", "Max DD")
+	sort.Slice(symbols, func(i, j int) bool {
+		return result.Symbols[symbols[i]].TotalPnL > result.Symbols[symbols[j]].TotalPnL
+	})
+	for _, symbol := range symbols {
+		r := result.Symbols[symbol]
+		table.AddRow(
+			symbol,
+			fmt.Sprintf("%d", len(r.Trades)),
+			fmt.Sprintf("%.1f%%", r.WinRate),
+			output.FormatPnL(r.TotalPnL),
+			FormatIndianCurrency(r.MaxDrawdown),
+		)
+	}
+	table.Render()
+	output.Println()
+
+	var avgExposure float64
+	for _, e := range result.Exposure {
+		avgExposure += e.ExposurePercent
+	}
+	if len(result.Exposure) > 0 {
+		avgExposure /= float64(len(result.Exposure))
+	}
 
-	for _, t := range trades {
-		if t.pnl > 0 {
-			winningTrades++
-			grossProfit += t.pnl
-			if t.pnl > largestWin {
-				largestWin = t.pnl
+	output.Bold("Exposure & Diversification")
+	output.Printf("  Avg Exposure:          %.1f%%\n", avgExposure)
+	output.Printf("  Peak Exposure:         %.1f%%\n", result.MaxExposure)
+	output.Printf("  Skipped Signals:       %d\n", result.SkippedSignals)
+	output.Printf("  Avg Correlation:       %.2f\n", result.AvgCorrelation)
+	output.Printf("  Max Drawdown:          %.1f%%\n", result.Combined.MaxDrawdown)
+	output.Printf("  Undiversified DD:      %.1f%%\n", result.UndiversifiedDrawdown)
+	output.Printf("  Diversification Gain:  %.1f%%\n", result.DiversificationBenefit)
+
+	return nil
+}
+
+// formatStrategyParams renders resolved strategy parameters in declaration order.
+func formatStrategyParams(strategy trading.Strategy, params trading.Params) string {
+	specs := strategy.Params()
+	if len(specs) == 0 {
+		return "none"
+	}
+
+	parts := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		parts = append(parts, fmt.Sprintf("%s=%v", spec.Name, params[spec.Name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// toBacktestResults converts an engine result into the CLI display summary.
+func toBacktestResults(result *trading.BacktestResult, capital float64) BacktestResults {
+	r := BacktestResults{
+		TotalTrades:   result.TotalTrades,
+		WinningTrades: result.WinningTrades,
+		LosingTrades:  result.LosingTrades,
+		WinRate:       result.WinRate,
+		TotalReturn:   result.TotalReturn,
+		MaxDrawdown:   result.MaxDrawdown,
+		SharpeRatio:   result.SharpeRatio,
+		ProfitFactor:  result.ProfitFactor,
+		AvgWin:        result.AvgWin,
+		AvgLoss:       result.AvgLoss,
+		GrossPnL:      result.GrossPnL,
+		Charges:       result.TotalCharges,
+		StartCapital:  capital,
+		EndCapital:    capital,
+		EquityCurve:   make([]float64, 0, len(result.EquityCurve)+1),
+	}
+
+	var holdTime time.Duration
+	for _, t := range result.Trades {
+		if t.PnL > 0 {
+			r.GrossProfit += t.PnL
+			if t.PnL > r.LargestWin {
+				r.LargestWin = t.PnL
 			}
 		} else {
-			grossLoss += t.pnl
-			if t.pnl < largestLoss {
-				largestLoss = t.pnl
+			r.GrossLoss += t.PnL
+			if t.PnL < r.LargestLoss {
+				r.LargestLoss = t.PnL
 			}
 		}
-		totalHoldDays += t.holdDays
-	}
-
-	winRate := 0.0
-	avgWin := 0.0
-	avgLoss := 0.0
-	profitFactor := 0.0
-	avgHoldDays := 0
-
-	if totalTrades > 0 {
-		winRate = float64(winningTrades) / float64(totalTrades) * 100
-		avgHoldDays = totalHoldDays / totalTrades
-	}
-	if winningTrades > 0 {
-		avgWin = grossProfit / float64(winningTrades)
-	}
-	losingTrades := totalTrades - winningTrades
-	if losingTrades > 0 {
-		avgLoss = grossLoss / float64(losingTrades)
-	}
-	if grossLoss != 0 {
-		profitFactor = grossProfit / (-grossLoss)
-	}
-
-	netProfit := grossProfit + grossLoss
-	totalReturn := (netProfit / capital) * 100
-
-	// Simplified Sharpe ratio (annualized)
-	sharpeRatio := 0.0
-	if maxDrawdown > 0 {
-		sharpeRatio = totalReturn / maxDrawdown
-	}
-
-	return BacktestResults{
-		TotalTrades:   totalTrades,
-		WinningTrades: winningTrades,
-		LosingTrades:  losingTrades,
-		WinRate:       winRate,
-		GrossProfit:   grossProfit,
-		GrossLoss:     grossLoss,
-		NetProfit:     netProfit,
-		TotalReturn:   totalReturn,
-		MaxDrawdown:   maxDrawdown,
-		SharpeRatio:   sharpeRatio,
-		ProfitFactor:  profitFactor,
-		AvgWin:        avgWin,
-		AvgLoss:       avgLoss,
-		LargestWin:    largestWin,
-		LargestLoss:   largestLoss,
-		AvgHoldTime:   fmt.Sprintf("%dd", avgHoldDays),
-		StartCapital:  capital,
-		EndCapital:    currentCapital,
-		EquityCurve:   equityCurve,
+		holdTime += t.ExitTime.Sub(t.EntryTime)
+	}
+	r.NetProfit = r.GrossProfit + r.GrossLoss
+
+	var avgHold time.Duration
+	if len(result.Trades) > 0 {
+		avgHold = holdTime / time.Duration(len(result.Trades))
+	}
+	if avgHold < 24*time.Hour {
+		r.AvgHoldTime = avgHold.Round(time.Minute).String()
+	} else {
+		r.AvgHoldTime = fmt.Sprintf("%dd", int(avgHold.Hours()/24))
+	}
+
+	r.EquityCurve = append(r.EquityCurve, capital)
+	for _, p := range result.EquityCurve {
+		r.EquityCurve = append(r.EquityCurve, p.Equity)
+	}
+	if n := len(result.EquityCurve); n > 0 {
+		r.EndCapital = result.EquityCurve[n-1].Equity
 	}
-}
 
-type backtestTrade struct {
-	entryPrice float64
-	exitPrice  float64
-	pnl        float64
-	pnlPercent float64
-	holdDays   int
+	return r
 }
 
 type BacktestResults struct {
@@ -421,6 +865,8 @@ type BacktestResults struct {
 	LargestWin    float64
 	LargestLoss   float64
 	AvgHoldTime   string
+	GrossPnL      float64 // P&L before charges
+	Charges       float64 // Brokerage and statutory charges
 	StartCapital  float64
 	EndCapital    float64
 	EquityCurve   []float64 // Track equity over time
@@ -442,6 +888,8 @@ func displayBacktestResults(output *Output, r BacktestResults) error {
 	output.Bold("Profit & Loss")
 	output.Printf("  Gross Profit:     %s\n", output.Green(FormatIndianCurrency(r.GrossProfit)))
 	output.Printf("  Gross Loss:       %s\n", output.Red(FormatIndianCurrency(r.GrossLoss)))
+	output.Printf("  P&L Before Costs: %s\n", output.FormatPnL(r.GrossPnL))
+	output.Printf("  Charges:          %s\n", output.Red(FormatIndianCurrency(r.Charges)))
 	output.Printf("  Net Profit:       %s\n", output.FormatPnL(r.NetProfit))
 	output.Printf("  Total Return:     %s\n", output.FormatPercent(r.TotalReturn))
 	output.Println()
@@ -791,71 +1239,118 @@ func newAPICmd(app *App) *cobra.Command {
 		Long: `Start a REST API server for external integrations.
 
 Endpoints:
-  GET  /api/quote/:symbol     - Get quote
-  GET  /api/positions         - Get positions
-  GET  /api/orders            - Get orders
-  POST /api/order             - Place order
-  GET  /api/analysis/:symbol  - Get analysis
-  GET  /api/health            - Health check`,
+  GET    /api/quote/:symbol     - Get quote
+  GET    /api/positions         - Get positions
+  GET    /api/holdings          - Get holdings
+  GET    /api/orders            - Get orders
+  POST   /api/order             - Place order
+  DELETE /api/order/:id         - Cancel order
+  GET    /api/analysis/:symbol  - Get analysis
+  GET    /api/signal/:symbol    - Get signal score
+  GET    /api/health            - Health check
+
+Clients authenticate with the X-API-Key header (or Authorization: Bearer).
+Write routes are blocked when security.read_only_mode is enabled, and every
+order placement or cancellation is recorded in the audit log.`,
 		Example: `  trader api start
   trader api start --port 8080
   trader api start --key myapikey`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			output := NewOutput(cmd)
+			host, _ := cmd.Flags().GetString("host")
 			port, _ := cmd.Flags().GetInt("port")
 			apiKey, _ := cmd.Flags().GetString("key")
 
+			if app.Broker == nil {
+				output.Error("Broker not configured. Run 'trader login' first.")
+				return fmt.Errorf("broker not configured")
+			}
+
+			cfg := api.DefaultConfig()
+			cfg.Host = host
+			cfg.Port = port
+			cfg.APIKey = apiKey
+			if app.Config.Trading.DefaultExchange != "" {
+				cfg.DefaultExchange = models.Exchange(app.Config.Trading.DefaultExchange)
+			}
+
+			// The broker already audits orders; the server adds auth failures
+			// and rejected requests to the same log
+			auditLogger := app.Audit
+			if auditLogger == nil {
+				output.Error("Audit log unavailable; the API server requires it")
+				return fmt.Errorf("audit log not initialized")
+			}
+
+			accessController := security.NewAccessController(app.Config.Security.ReadOnlyMode, auditLogger)
+
+			healthMonitor := resilience.NewHealthMonitor(resilience.DefaultHealthMonitorConfig())
+			healthMonitor.RegisterComponent("broker", resilience.APIHealthCheck("broker", func(ctx context.Context) (time.Duration, error) {
+				start := time.Now()
+				if !app.Broker.IsAuthenticated() {
+					return 0, fmt.Errorf("broker session not authenticated")
+				}
+				return time.Since(start), nil
+			}))
+			healthMonitor.Start()
+			defer healthMonitor.Stop()
+
+			engine := indicators.NewEngine(4)
+			server := api.NewServer(
+				cfg,
+				app.Broker,
+				scoring.NewSignalScorer(engine),
+				healthMonitor,
+				accessController,
+				auditLogger,
+				app.Logger,
+			)
+
 			output.Bold("Starting REST API Server")
-			output.Printf("  Port:    %d\n", port)
+			output.Printf("  Address: %s\n", cfg.Addr())
 			if apiKey != "" {
-				output.Printf("  API Key: %s\n", "****"+apiKey[len(apiKey)-4:])
+				output.Printf("  API Key: %s\n", security.MaskCredential(apiKey))
+			} else {
+				output.Warning("No API key set - requests are not authenticated")
+			}
+			if accessController.IsReadOnly() {
+				output.Printf("  Mode:    %s\n", output.Yellow("READ-ONLY (write routes disabled)"))
 			}
-			output.Println()
-
-			output.Info("API server starting on http://localhost:%d", port)
 			output.Println()
 
 			output.Bold("Available Endpoints")
-			endpoints := []struct {
-				method string
-				path   string
-				desc   string
-			}{
-				{"GET", "/api/quote/:symbol", "Get real-time quote"},
-				{"GET", "/api/positions", "Get open positions"},
-				{"GET", "/api/holdings", "Get holdings"},
-				{"GET", "/api/orders", "Get orders"},
-				{"POST", "/api/order", "Place order"},
-				{"DELETE", "/api/order/:id", "Cancel order"},
-				{"GET", "/api/analysis/:symbol", "Get technical analysis"},
-				{"GET", "/api/signal/:symbol", "Get signal score"},
-				{"GET", "/api/health", "Health check"},
-			}
-
-			for _, e := range endpoints {
+			for _, route := range api.Routes() {
 				methodColor := ColorGreen
-				if e.method == "POST" {
+				if route.Method == "POST" {
 					methodColor = ColorYellow
-				} else if e.method == "DELETE" {
+				} else if route.Method == "DELETE" {
 					methodColor = ColorRed
 				}
 				output.Printf("  %s %-25s %s\n",
-					output.ColoredString(methodColor, PadRight(e.method, 6)),
-					e.path,
-					output.DimText(e.desc))
+					output.ColoredString(methodColor, PadRight(route.Method, 6)),
+					route.Path,
+					output.DimText(route.Description))
 			}
 
 			output.Println()
+			output.Info("API server listening on http://%s", cfg.Addr())
 			output.Dim("Press Ctrl+C to stop the server")
 
-			// In a real implementation, this would start an HTTP server
-			// For now, just show the info
-			output.Warning("API server not implemented in this version")
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if err := server.ListenAndServe(ctx); err != nil {
+				output.Error("API server failed: %v", err)
+				return err
+			}
 
+			output.Println()
+			output.Success("✓ API server stopped")
 			return nil
 		},
 	})
 
+	cmd.PersistentFlags().String("host", "localhost", "Interface to bind to")
 	cmd.PersistentFlags().Int("port", 8080, "Server port")
 	cmd.PersistentFlags().String("key", "", "API key for authentication")
 
@@ -26,6 +26,13 @@ type TradingConfig struct {
 	Mode            string `mapstructure:"mode"`             // "live", "paper"
 	DefaultProduct  string `mapstructure:"default_product"`  // MIS, CNC, NRML
 	DefaultExchange string `mapstructure:"default_exchange"` // NSE, BSE
+	// Paper mode trades this persistent paper account, opened with
+	// PaperBalance if it does not exist yet
+	PaperAccount string  `mapstructure:"paper_account"` // default: "default"
+	PaperBalance float64 `mapstructure:"paper_balance"` // INR; default: 10 lakh
+	// RecordTicks saves every streamed tick under TicksDir for --replay
+	RecordTicks bool   `mapstructure:"record_ticks"`
+	TicksDir    string `mapstructure:"ticks_dir"` // default: <config dir>/ticks
 }
 
 // RiskConfig holds risk management configuration.
@@ -37,6 +44,10 @@ type RiskConfig struct {
 	TrailingStopPercent    float64 `mapstructure:"trailing_stop_percent"`
 	DailyLossLimit         float64 `mapstructure:"daily_loss_limit"`
 	MaxSlippage            float64 `mapstructure:"max_slippage"`
+	// Hard pre-trade limits enforced on every broker order; zero disables.
+	MaxOrderValue        float64 `mapstructure:"max_order_value"`
+	MaxQuantityPerSymbol int     `mapstructure:"max_quantity_per_symbol"`
+	PriceBandPercent     float64 `mapstructure:"price_band_percent"`
 }
 
 // UIConfig holds UI-related configuration.
@@ -91,8 +102,10 @@ type EmailConfig struct {
 // Credentials holds API credentials.
 type Credentials struct {
 	Zerodha ZerodhaCredentials `mapstructure:"zerodha"`
-	OpenAI  OpenAICredentials  `mapstructure:"openai"`
-	Tavily  TavilyCredentials  `mapstructure:"tavily"`
+	OpenAI    OpenAICredentials    `mapstructure:"openai"`
+	Anthropic AnthropicCredentials `mapstructure:"anthropic"`
+	Azure     AzureCredentials     `mapstructure:"azure"`
+	Tavily    TavilyCredentials    `mapstructure:"tavily"`
 }
 
 // ZerodhaCredentials holds Zerodha API credentials.
@@ -102,6 +115,8 @@ type ZerodhaCredentials struct {
 	UserID     string `mapstructure:"user_id"`
 	Password   string `mapstructure:"password"`    // For auto-login
 	TOTPSecret string `mapstructure:"totp_secret"` // For auto-login with 2FA
+	BaseURL    string `mapstructure:"base_url"`    // Overrides https://api.kite.trade
+	TickerURL  string `mapstructure:"ticker_url"`  // Overrides wss://ws.kite.trade
 }
 
 // OpenAICredentials holds OpenAI API credentials.
@@ -109,23 +124,71 @@ type OpenAICredentials struct {
 	APIKey string `mapstructure:"api_key"`
 }
 
+// AnthropicCredentials holds Anthropic API credentials.
+type AnthropicCredentials struct {
+	APIKey string `mapstructure:"api_key"`
+}
+
+// AzureCredentials holds Azure OpenAI credentials.
+type AzureCredentials struct {
+	APIKey   string `mapstructure:"api_key"`
+	Endpoint string `mapstructure:"endpoint"` // e.g. https://<resource>.openai.azure.com
+}
+
 // TavilyCredentials holds Tavily API credentials.
 type TavilyCredentials struct {
 	APIKey string `mapstructure:"api_key"`
 }
 
+// LLM providers supported by the agents.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAzure     = "azure"
+	ProviderAnthropic = "anthropic"
+	ProviderLocal     = "local" // any OpenAI-compatible server: Ollama, llama.cpp, vLLM
+	ProviderFake      = "fake"  // deterministic canned responses, for tests and dry runs
+)
+
+// DefaultOpenAIModel is the model used when the OpenAI provider is selected
+// without one. Other providers have no default model.
+const DefaultOpenAIModel = "gpt-5.2"
+
+// LLMConfig selects the LLM provider and model for an agent.
+type LLMConfig struct {
+	Provider   string `mapstructure:"provider"`
+	Model      string `mapstructure:"model"`       // Azure: the deployment name
+	BaseURL    string `mapstructure:"base_url"`    // Azure endpoint or local server URL
+	APIKey     string `mapstructure:"api_key"`     // Defaults to the provider's key in credentials.toml
+	APIVersion string `mapstructure:"api_version"` // Azure only
+	MaxTokens  int    `mapstructure:"max_tokens"`
+}
+
 // AgentConfig holds AI agent configuration.
 type AgentConfig struct {
-	Model                string             `mapstructure:"model"`
-	AutonomousMode       string             `mapstructure:"autonomous_mode"` // FULL_AUTO, SEMI_AUTO, NOTIFY_ONLY, MANUAL
-	AutoExecuteThreshold float64            `mapstructure:"auto_execute_threshold"`
-	MaxDailyTrades       int                `mapstructure:"max_daily_trades"`
-	MaxDailyLoss         float64            `mapstructure:"max_daily_loss"`
-	MaxPositionSize      float64            `mapstructure:"max_position_size"`
-	CooldownMinutes      int                `mapstructure:"cooldown_minutes"`
-	ConsecutiveLossLimit int                `mapstructure:"consecutive_loss_limit"`
-	EnabledAgents        []string           `mapstructure:"enabled_agents"`
-	AgentWeights         map[string]float64 `mapstructure:"agent_weights"`
+	Provider             string               `mapstructure:"provider"`
+	Model                string               `mapstructure:"model"`
+	BaseURL              string               `mapstructure:"base_url"`
+	LLM                  map[string]LLMConfig `mapstructure:"llm"`             // Per-agent overrides, keyed by agent name
+	AutonomousMode       string               `mapstructure:"autonomous_mode"` // FULL_AUTO, SEMI_AUTO, NOTIFY_ONLY, MANUAL
+	AutoExecuteThreshold float64              `mapstructure:"auto_execute_threshold"`
+	MaxDailyTrades       int                  `mapstructure:"max_daily_trades"`
+	MaxDailyLoss         float64              `mapstructure:"max_daily_loss"`
+	MaxPositionSize      float64              `mapstructure:"max_position_size"`
+	CooldownMinutes      int                  `mapstructure:"cooldown_minutes"`
+	ConsecutiveLossLimit int                  `mapstructure:"consecutive_loss_limit"`
+	DayBoundary          string               `mapstructure:"day_boundary"` // IST "HH:MM" at which daily limits reset
+	EnabledAgents        []string             `mapstructure:"enabled_agents"`
+	AgentWeights         map[string]float64   `mapstructure:"agent_weights"`
+	LLMCache             bool                 `mapstructure:"llm_cache"`         // Reuse responses for unchanged prompts and candles
+	LLMDailyBudget       float64              `mapstructure:"llm_daily_budget"`  // USD per day across agents; 0 = unlimited
+	LLMAgentBudgets      map[string]float64   `mapstructure:"llm_agent_budgets"` // USD per day, keyed by agent name
+	LLMPricing           map[string]LLMPrice  `mapstructure:"llm_pricing"`       // Overrides built-in prices, keyed by model
+}
+
+// LLMPrice is a model's price in USD per million tokens.
+type LLMPrice struct {
+	Input  float64 `mapstructure:"input"`
+	Output float64 `mapstructure:"output"`
 }
 
 // DefaultConfigDir returns the default configuration directory.
@@ -212,7 +275,7 @@ func loadAgentConfig(configDir string, agents *AgentConfig) error {
 	v.AddConfigPath(configDir)
 
 	// Set defaults
-	v.SetDefault("model", "gpt-5.2")
+	v.SetDefault("provider", ProviderOpenAI)
 	v.SetDefault("autonomous_mode", "MANUAL")
 	v.SetDefault("auto_execute_threshold", 80.0)
 	v.SetDefault("max_daily_trades", 10)
@@ -220,7 +283,9 @@ func loadAgentConfig(configDir string, agents *AgentConfig) error {
 	v.SetDefault("max_position_size", 100000.0)
 	v.SetDefault("cooldown_minutes", 5)
 	v.SetDefault("consecutive_loss_limit", 3)
+	v.SetDefault("day_boundary", "09:00")
 	v.SetDefault("enabled_agents", []string{"technical", "research", "news", "risk", "trader"})
+	v.SetDefault("llm_cache", true)
 
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -229,7 +294,14 @@ func loadAgentConfig(configDir string, agents *AgentConfig) error {
 		return err
 	}
 
-	return v.Unmarshal(agents)
+	if err := v.Unmarshal(agents); err != nil {
+		return err
+	}
+	// Only OpenAI has a default model; other providers must name theirs
+	if agents.Model == "" && (agents.Provider == "" || agents.Provider == ProviderOpenAI) {
+		agents.Model = DefaultOpenAIModel
+	}
+	return nil
 }
 
 func applyEnvOverrides(cfg *Config) {
@@ -243,12 +315,31 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("ZERODHA_USER_ID"); v != "" {
 		cfg.Credentials.Zerodha.UserID = v
 	}
+	if v := os.Getenv("ZERODHA_BASE_URL"); v != "" {
+		cfg.Credentials.Zerodha.BaseURL = v
+	}
+	if v := os.Getenv("ZERODHA_TICKER_URL"); v != "" {
+		cfg.Credentials.Zerodha.TickerURL = v
+	}
 
 	// OpenAI credentials
 	if v := os.Getenv("OPENAI_API_KEY"); v != "" {
 		cfg.Credentials.OpenAI.APIKey = v
 	}
 
+	// Anthropic credentials
+	if v := os.Getenv("ANTHROPIC_API_KEY"); v != "" {
+		cfg.Credentials.Anthropic.APIKey = v
+	}
+
+	// Azure OpenAI credentials
+	if v := os.Getenv("AZURE_OPENAI_API_KEY"); v != "" {
+		cfg.Credentials.Azure.APIKey = v
+	}
+	if v := os.Getenv("AZURE_OPENAI_ENDPOINT"); v != "" {
+		cfg.Credentials.Azure.Endpoint = v
+	}
+
 	// Tavily credentials
 	if v := os.Getenv("TAVILY_API_KEY"); v != "" {
 		cfg.Credentials.Tavily.APIKey = v
@@ -266,6 +357,9 @@ func (c *Config) Validate() error {
 	if c.Trading.Mode != "" && c.Trading.Mode != "live" && c.Trading.Mode != "paper" {
 		return fmt.Errorf("invalid trading mode: %s (must be 'live' or 'paper')", c.Trading.Mode)
 	}
+	if c.Trading.PaperBalance < 0 {
+		return fmt.Errorf("paper_balance must be non-negative")
+	}
 
 	// Validate risk parameters
 	if c.Risk.MaxPositionPercent < 0 || c.Risk.MaxPositionPercent > 100 {
@@ -277,15 +371,117 @@ func (c *Config) Validate() error {
 	if c.Risk.MinRiskReward < 0 {
 		return fmt.Errorf("min_risk_reward must be non-negative")
 	}
+	if c.Risk.MaxOrderValue < 0 || c.Risk.MaxQuantityPerSymbol < 0 || c.Risk.PriceBandPercent < 0 {
+		return fmt.Errorf("max_order_value, max_quantity_per_symbol and price_band_percent must be non-negative")
+	}
 
 	// Validate agent config
 	if c.Agents.AutoExecuteThreshold < 0 || c.Agents.AutoExecuteThreshold > 100 {
 		return fmt.Errorf("auto_execute_threshold must be between 0 and 100")
 	}
+	if c.Agents.DayBoundary != "" {
+		if _, err := time.Parse("15:04", c.Agents.DayBoundary); err != nil {
+			return fmt.Errorf("day_boundary must be HH:MM, got %q", c.Agents.DayBoundary)
+		}
+	}
+	if c.Agents.LLMDailyBudget < 0 {
+		return fmt.Errorf("llm_daily_budget must be non-negative")
+	}
+	if err := validateProvider(c.Agents.Provider); err != nil {
+		return err
+	}
+	if err := validateModel(c.defaultProvider(), c.Agents.Model); err != nil {
+		return err
+	}
+	for agent, llm := range c.Agents.LLM {
+		if err := validateProvider(llm.Provider); err != nil {
+			return fmt.Errorf("llm.%s: %w", agent, err)
+		}
+		switchesProvider := llm.Provider != "" && llm.Provider != c.defaultProvider()
+		if switchesProvider && llm.Model == "" && llm.Provider != ProviderFake {
+			return fmt.Errorf("llm.%s: model is required when provider differs from the default (%s)", agent, c.defaultProvider())
+		}
+		resolved := c.LLMFor(agent)
+		if err := validateModel(resolved.Provider, resolved.Model); err != nil {
+			return fmt.Errorf("llm.%s: %w", agent, err)
+		}
+	}
+
+	return nil
+}
 
+func validateProvider(provider string) error {
+	switch provider {
+	case "", ProviderOpenAI, ProviderAzure, ProviderAnthropic, ProviderLocal, ProviderFake:
+		return nil
+	}
+	return fmt.Errorf("invalid LLM provider: %s (must be openai, azure, anthropic, local or fake)", provider)
+}
+
+// validateModel checks that a provider other than OpenAI has a model of its
+// own rather than none or the OpenAI default, which it would only reject at
+// the first API call.
+func validateModel(provider, model string) error {
+	switch provider {
+	case ProviderAzure, ProviderAnthropic, ProviderLocal:
+		if model == "" {
+			return fmt.Errorf("model is required for the %s provider", provider)
+		}
+		if model == DefaultOpenAIModel {
+			return fmt.Errorf("model %q is the OpenAI default; set a %s model", model, provider)
+		}
+	}
 	return nil
 }
 
+// defaultProvider returns the provider agents use without an override.
+func (c *Config) defaultProvider() string {
+	if c.Agents.Provider == "" {
+		return ProviderOpenAI
+	}
+	return c.Agents.Provider
+}
+
+// LLMFor returns the LLM settings for the named agent: the agent's [llm.<name>]
+// table layered over the top-level provider and model, with the API key taken
+// from credentials.toml when the table does not set one.
+func (c *Config) LLMFor(agent string) LLMConfig {
+	llm := LLMConfig{
+		Provider: c.defaultProvider(),
+		Model:    c.Agents.Model,
+		BaseURL:  c.Agents.BaseURL,
+	}
+	if o, ok := c.Agents.LLM[agent]; ok {
+		if o.Provider != "" && o.Provider != llm.Provider {
+			// A different provider does not inherit the default's model or endpoint
+			llm.Provider, llm.Model, llm.BaseURL = o.Provider, "", ""
+		}
+		if o.Model != "" {
+			llm.Model = o.Model
+		}
+		if o.BaseURL != "" {
+			llm.BaseURL = o.BaseURL
+		}
+		llm.APIKey = o.APIKey
+		llm.APIVersion = o.APIVersion
+		llm.MaxTokens = o.MaxTokens
+	}
+	if llm.APIKey == "" {
+		switch llm.Provider {
+		case ProviderOpenAI:
+			llm.APIKey = c.Credentials.OpenAI.APIKey
+		case ProviderAnthropic:
+			llm.APIKey = c.Credentials.Anthropic.APIKey
+		case ProviderAzure:
+			llm.APIKey = c.Credentials.Azure.APIKey
+		}
+	}
+	if llm.Provider == ProviderAzure && llm.BaseURL == "" {
+		llm.BaseURL = c.Credentials.Azure.Endpoint
+	}
+	return llm
+}
+
 // IsPaperMode returns true if paper trading mode is enabled.
 func (c *Config) IsPaperMode() bool {
 	return c.Trading.Mode == "paper"
@@ -15,6 +15,14 @@ mode = "paper"
 default_product = "MIS"
 # Default exchange: NSE, BSE
 default_exchange = "NSE"
+# Paper mode simulates every order on a persistent paper account while
+# quotes and history come from Zerodha. Created with paper_balance (INR).
+paper_account = "default"
+paper_balance = 1000000.0
+# Record every streamed tick for later replay with --replay YYYY-MM-DD.
+# Ticks are saved under ticks_dir (default: ~/.config/zerodha-trader/ticks).
+record_ticks = false
+# ticks_dir = "/path/to/ticks"
 
 [risk]
 # Maximum position size as percentage of portfolio
@@ -31,6 +39,13 @@ trailing_stop_percent = 1.0
 daily_loss_limit = 5000.0
 # Maximum slippage alert threshold
 max_slippage = 0.5
+# Hard limits checked before every order reaches the broker (0 disables)
+# Maximum value of a single order in INR
+max_order_value = 500000.0
+# Maximum quantity held or ordered in one symbol
+max_quantity_per_symbol = 0
+# Reject limit and trigger prices further than this percentage from LTP
+price_band_percent = 5.0
 
 [security]
 # Enable read-only mode (blocks all trading operations)
@@ -84,18 +99,34 @@ const credentialsTemplate = `# Zerodha Go Trader Credentials
 api_key = ""
 api_secret = ""
 user_id = ""
+# Point the broker at another Kite Connect endpoint, e.g. a local test server
+# base_url = "http://127.0.0.1:8080"
+# ticker_url = "ws://127.0.0.1:8080/ws"
 
 [openai]
 api_key = ""
 
+[anthropic]
+api_key = ""
+
+[azure]
+api_key = ""
+endpoint = ""
+
 [tavily]
 api_key = ""
 `
 
 const agentsTemplate = `# Zerodha Go Trader Agent Configuration
 
-# LLM model to use (e.g., gpt-5.2, gpt-4o)
+# LLM provider: openai, azure, anthropic, local (any OpenAI-compatible
+# server such as Ollama or llama.cpp) or fake (canned responses)
+provider = "openai"
+# LLM model to use (e.g., gpt-5.2, gpt-4o); for azure, the deployment name.
+# Defaults to gpt-5.2 for openai; required for every other provider
 model = "gpt-5.2"
+# Endpoint for local servers (default http://localhost:11434/v1)
+# base_url = ""
 
 # Operating mode:
 #   FULL_AUTO   - AI executes trades automatically when confidence >= threshold
@@ -116,6 +147,15 @@ max_position_size = 100000.0
 cooldown_minutes = 5
 # Stop trading after this many consecutive losses
 consecutive_loss_limit = 3
+# Time of day (IST, HH:MM) at which the daily trade, loss and streak
+# counters reset. They are restored from the database on restart.
+day_boundary = "09:00"
+
+# Reuse LLM responses when an agent sees the same prompt on the same candle
+llm_cache = true
+# Estimated LLM spend allowed per day in USD (0 = unlimited). Agents fall
+# back to rule-based analysis once it is used up.
+llm_daily_budget = 0.0
 
 # Enabled agents
 enabled_agents = ["technical", "research", "news", "risk", "trader"]
@@ -127,6 +167,26 @@ research = 0.2
 news = 0.15
 risk = 0.15
 trader = 0.2
+
+# Per-agent daily LLM budgets in USD
+# [llm_agent_budgets]
+# research = 0.50
+
+# Model prices in USD per million tokens, overriding the built-in estimates
+# [llm_pricing."gpt-5.2"]
+# input = 1.25
+# output = 10.0
+
+# Per-agent LLM overrides. Unset fields fall back to the settings above.
+# [llm.news]
+# provider = "local"
+# model = "llama3.1"
+# base_url = "http://localhost:11434/v1"
+#
+# [llm.trader]
+# provider = "anthropic"
+# model = "claude-sonnet-4-5"
+# max_tokens = 4096
 `
 
 func createTemplateConfig(configDir, name string) error {
@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"zerodha-trader/internal/agents"
+)
+
+// The control protocol is one newline-terminated JSON Request per connection,
+// answered by one JSON Response.
+
+// Control commands.
+const (
+	CommandStatus = "status"
+	CommandPause  = "pause"
+	CommandResume = "resume"
+	CommandStop   = "stop"
+)
+
+// ErrNotRunning is returned by Send when no daemon is listening.
+var ErrNotRunning = errors.New("daemon not running")
+
+// Controller is the running daemon's orchestrator.
+type Controller interface {
+	Stop() error
+	Pause() error
+	Resume() error
+	GetStatus() *agents.OrchestratorStatus
+}
+
+// Request is a control command.
+type Request struct {
+	Command string `json:"command"`
+}
+
+// Response answers a control command.
+type Response struct {
+	OK     bool    `json:"ok"`
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Status describes the running daemon.
+type Status struct {
+	PID          int                        `json:"pid"`
+	StartedAt    time.Time                  `json:"started_at"`
+	Orchestrator *agents.OrchestratorStatus `json:"orchestrator"`
+}
+
+// Server serves control commands on a Unix domain socket.
+type Server struct {
+	ctrl      Controller
+	listener  net.Listener
+	path      string
+	startedAt time.Time
+
+	closeOnce sync.Once
+}
+
+// Listen opens the control socket at path. The caller must hold the daemon
+// lock, so any existing socket file is left over from a dead daemon.
+func Listen(path string, ctrl Controller) (*Server, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("removing stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("securing socket: %w", err)
+	}
+
+	return &Server{
+		ctrl:      ctrl,
+		listener:  listener,
+		path:      path,
+		startedAt: time.Now(),
+	}, nil
+}
+
+// Serve accepts control connections until Close is called.
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close stops serving and removes the socket file.
+func (s *Server) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.listener.Close()
+		os.Remove(s.path)
+	})
+	return err
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var req Request
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &req)
+	}
+
+	resp := Response{}
+	if err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		resp = s.dispatch(req.Command)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+func (s *Server) dispatch(command string) Response {
+	var err error
+	switch command {
+	case CommandStatus:
+	case CommandPause:
+		err = s.ctrl.Pause()
+	case CommandResume:
+		err = s.ctrl.Resume()
+	case CommandStop:
+		err = s.ctrl.Stop()
+	default:
+		return Response{Error: fmt.Sprintf("unknown command %q", command)}
+	}
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+
+	return Response{
+		OK: true,
+		Status: &Status{
+			PID:          os.Getpid(),
+			StartedAt:    s.startedAt,
+			Orchestrator: s.ctrl.GetStatus(),
+		},
+	}
+}
+
+// Send sends a control command to the daemon listening on path. It returns
+// ErrNotRunning if nothing is listening, and the daemon's error if the
+// command failed.
+func Send(ctx context.Context, path, command string) (*Response, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := json.NewEncoder(conn).Encode(Request{Command: command}); err != nil {
+		return nil, fmt.Errorf("sending %s: %w", command, err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("reading %s response: %w", command, err)
+	}
+	if !resp.OK {
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,183 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/agents"
+)
+
+type fakeController struct {
+	mu      sync.Mutex
+	running bool
+	paused  bool
+}
+
+func (f *fakeController) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = false
+	return nil
+}
+
+func (f *fakeController) Pause() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.running {
+		return errors.New("orchestrator not running")
+	}
+	f.paused = true
+	return nil
+}
+
+func (f *fakeController) Resume() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = false
+	return nil
+}
+
+func (f *fakeController) GetStatus() *agents.OrchestratorStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &agents.OrchestratorStatus{Running: f.running, Paused: f.paused}
+}
+
+func TestAcquire_RefusesSecondInstance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trader.pid")
+
+	lock, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = Acquire(path)
+	var running *RunningError
+	if !errors.As(err, &running) || running.PID != os.Getpid() {
+		t.Fatalf("expected RunningError for PID %d, got %v", os.Getpid(), err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := Running(path); ok {
+		t.Error("expected no running daemon after release")
+	}
+}
+
+func TestAcquire_ReplacesStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trader.pid")
+	// PIDs are capped well below this on Linux and macOS
+	if err := os.WriteFile(path, []byte("999999999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("expected stale lock to be replaced, got %v", err)
+	}
+	defer lock.Release()
+
+	if pid, ok := Running(path); !ok || pid != os.Getpid() {
+		t.Errorf("expected lock held by this process, got %d (%v)", pid, ok)
+	}
+}
+
+func TestAcquire_ConcurrentCallersGetOneLock(t *testing.T) {
+	for _, stale := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "trader.pid")
+		if stale {
+			// Every caller sees the same dead PID
+			if err := os.WriteFile(path, []byte("999999999\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var locks []*Lock
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lock, err := Acquire(path)
+				var running *RunningError
+				if err != nil && !errors.As(err, &running) {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				if lock != nil {
+					mu.Lock()
+					locks = append(locks, lock)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(locks) != 1 {
+			t.Fatalf("stale=%v: expected exactly one lock holder, got %d", stale, len(locks))
+		}
+		locks[0].Release()
+	}
+}
+
+func TestAcquire_AfterRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trader.pid")
+
+	// Each holder's release lets exactly the next one in
+	for i := 0; i < 3; i++ {
+		lock, err := Acquire(path)
+		if err != nil {
+			t.Fatalf("acquire %d: %v", i, err)
+		}
+		if _, err := Acquire(path); err == nil {
+			t.Fatalf("acquire %d: second caller got the lock", i)
+		}
+		if err := lock.Release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestControlSocket(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	socket := filepath.Join(t.TempDir(), "trader.sock")
+	if _, err := Send(ctx, socket, CommandStatus); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("expected ErrNotRunning without a daemon, got %v", err)
+	}
+
+	ctrl := &fakeController{running: true}
+	srv, err := Listen(socket, ctrl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer srv.Close()
+	go srv.Serve()
+
+	resp, err := Send(ctx, socket, CommandPause)
+	if err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	if !resp.Status.Orchestrator.Paused || resp.Status.PID != os.Getpid() {
+		t.Errorf("expected paused status from this process, got %+v", resp.Status)
+	}
+
+	if _, err := Send(ctx, socket, "restart"); err == nil {
+		t.Error("expected unknown command to fail")
+	}
+
+	if _, err := Send(ctx, socket, CommandStop); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+	if _, err := Send(ctx, socket, CommandPause); err == nil || err.Error() != "orchestrator not running" {
+		t.Errorf("expected controller error to be returned, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package daemon provides single-instance locking and a local control socket
+// for the autonomous trading daemon.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Paths locates the daemon's PID file and control socket.
+type Paths struct {
+	PIDFile string
+	Socket  string
+}
+
+// DefaultPaths returns the PID file and socket paths inside dir.
+func DefaultPaths(dir string) Paths {
+	return Paths{
+		PIDFile: filepath.Join(dir, "trader.pid"),
+		Socket:  filepath.Join(dir, "trader.sock"),
+	}
+}
+
+// RunningError is returned when another daemon already holds the lock.
+type RunningError struct {
+	PID int
+}
+
+func (e *RunningError) Error() string {
+	return fmt.Sprintf("daemon already running with PID %d", e.PID)
+}
+
+// Lock is a held PID file.
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// Acquire takes an exclusive flock on the PID file at path and writes this
+// process's PID to it. It fails with *RunningError if another process holds
+// the lock. The lock is held until Release or until the process exits, so a
+// daemon that dies leaves nothing to clean up: a PID file nobody has locked
+// is simply reused.
+func Acquire(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating lock directory: %w", err)
+	}
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening PID file: %w", err)
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, &RunningError{PID: holderPID(path)}
+			}
+			return nil, fmt.Errorf("locking PID file: %w", err)
+		}
+
+		// A releasing daemon removes the file after we opened it; lock the
+		// file now at path instead
+		if !samePath(f, path) {
+			f.Close()
+			continue
+		}
+
+		if err := writePID(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("writing PID file: %w", err)
+		}
+		return &Lock{path: path, file: f}, nil
+	}
+}
+
+// Release removes the PID file and drops the lock.
+func (l *Lock) Release() error {
+	err := os.Remove(l.path)
+	l.file.Close()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing PID file: %w", err)
+	}
+	return nil
+}
+
+// Running reports the PID recorded in path and whether a daemon holds the
+// lock on it.
+func Running(path string) (int, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	pid := readPID(f)
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err == nil {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		return pid, false
+	}
+	return pid, true
+}
+
+// holderPID reads the PID of the process holding the lock on path. The
+// holder writes it just after locking, so an empty file is read again
+// briefly.
+func holderPID(path string) int {
+	for attempt := 0; attempt < 20; attempt++ {
+		if f, err := os.Open(path); err == nil {
+			pid := readPID(f)
+			f.Close()
+			if pid > 0 {
+				return pid
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return 0
+}
+
+func readPID(f *os.File) int {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
+
+func writePID(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return err
+}
+
+// samePath reports whether f is still the file at path.
+func samePath(f *os.File, path string) bool {
+	held, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(held, current)
+}
@@ -4,6 +4,7 @@ package integration
 
 import (
 	"context"
+	"math"
 	"sync"
 	"testing"
 	"time"
@@ -263,10 +264,17 @@ func TestPaperTradingSimulation(t *testing.T) {
 		}
 	}
 
-	// Test 7: Verify balance reflects profit
+	// Test 7: Verify balance reflects profit net of charges
 	finalBalance, _ := paperBroker.GetBalance(ctx)
-	expectedBalance := initialBalance - (3500.0 * 10) + (3600.0 * 10)
-	if finalBalance.AvailableCash != expectedBalance {
+	pnl := paperBroker.GetPnLSummary()
+	if pnl.Charges.Total <= 0 {
+		t.Error("Expected charges to be applied to paper fills")
+	}
+	if pnl.GrossRealized != 1000.0 {
+		t.Errorf("Expected gross realised P&L 1000.00, got %.2f", pnl.GrossRealized)
+	}
+	expectedBalance := initialBalance - (3500.0 * 10) + (3600.0 * 10) - pnl.Charges.Total
+	if math.Abs(finalBalance.AvailableCash-expectedBalance) > 0.001 {
 		t.Errorf("Expected final balance %.2f, got %.2f", expectedBalance, finalBalance.AvailableCash)
 	}
 
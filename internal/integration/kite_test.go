@@ -0,0 +1,298 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/broker/kitetest"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+)
+
+// newKiteBroker logs a ZerodhaBroker in to a fake Kite server.
+func newKiteBroker(t *testing.T, srv *kitetest.Server) *broker.ZerodhaBroker {
+	t.Helper()
+	zb := broker.NewZerodhaBroker(broker.ZerodhaConfig{
+		APIKey:    kitetest.APIKey,
+		APISecret: kitetest.APISecret,
+		UserID:    kitetest.UserID,
+		TokenPath: filepath.Join(t.TempDir(), "session.json"),
+		BaseURL:   srv.URL,
+		TickerURL: srv.TickerURL,
+	})
+	if err := zb.CompleteLogin(context.Background(), kitetest.RequestToken); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return zb
+}
+
+// findOrder returns the order with id from the broker's order book.
+func findOrder(t *testing.T, b broker.Broker, id string) models.Order {
+	t.Helper()
+	orders, err := b.GetOrders(context.Background())
+	if err != nil {
+		t.Fatalf("get orders: %v", err)
+	}
+	for _, o := range orders {
+		if o.ID == id {
+			return o
+		}
+	}
+	t.Fatalf("order %s not in order book", id)
+	return models.Order{}
+}
+
+// TestZerodhaBrokerOrderLifecycle drives ZerodhaBroker against a fake Kite
+// server: login, market data, order placement, modification, fills on
+// price moves, cancellation, rejection and GTT triggers.
+func TestZerodhaBrokerOrderLifecycle(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	srv := kitetest.NewServer()
+	defer srv.Close()
+	srv.AddInstrument(kitetest.Instrument{Symbol: "INFY", Name: "INFOSYS", Price: 1500})
+	srv.AddInstrument(kitetest.Instrument{Symbol: "RELIANCE", Name: "RELIANCE INDUSTRIES", Price: 2500})
+
+	unauthenticated := broker.NewZerodhaBroker(broker.ZerodhaConfig{
+		APIKey:    kitetest.APIKey,
+		TokenPath: filepath.Join(t.TempDir(), "session.json"),
+		BaseURL:   srv.URL,
+	})
+	if err := unauthenticated.CompleteLogin(ctx, "stale-token"); err == nil {
+		t.Fatal("login with a bad request token succeeded")
+	}
+
+	zb := newKiteBroker(t, srv)
+
+	quote, err := zb.GetQuote(ctx, "NSE:INFY")
+	if err != nil {
+		t.Fatalf("quote: %v", err)
+	}
+	if quote.LTP != 1500 || quote.Close != 1500 {
+		t.Errorf("quote LTP %.2f close %.2f, want 1500", quote.LTP, quote.Close)
+	}
+
+	// A scripted outage fails one request only
+	srv.Fail("/quote", http.StatusBadGateway, "NetworkException", "Gateway timed out")
+	if _, err := zb.GetQuote(ctx, "NSE:INFY"); err == nil {
+		t.Error("quote succeeded during a scripted outage")
+	}
+	if _, err := zb.GetQuote(ctx, "NSE:INFY"); err != nil {
+		t.Errorf("quote after outage: %v", err)
+	}
+
+	// 150 days of 5 minute candles exceed Kite's 100 day limit per request
+	start := time.Date(2024, 1, 1, 9, 15, 0, 0, resilience.IndiaLocation)
+	var candles []models.Candle
+	for day := 0; day < 150; day++ {
+		candles = append(candles, models.Candle{Timestamp: start.AddDate(0, 0, day), Open: 1500, High: 1510, Low: 1490, Close: 1505, Volume: 1000})
+	}
+	if err := srv.SetCandles("INFY", "5minute", candles); err != nil {
+		t.Fatal(err)
+	}
+	history, err := zb.GetHistorical(ctx, broker.HistoricalRequest{
+		Symbol: "INFY", Exchange: models.NSE, Timeframe: "5min",
+		From: start.Add(-time.Hour), To: start.AddDate(0, 0, 150),
+	})
+	if err != nil {
+		t.Fatalf("historical: %v", err)
+	}
+	if len(history) != 150 || !history[0].Timestamp.Equal(start) {
+		t.Errorf("got %d candles, want 150 from %v", len(history), start)
+	}
+	if hits := srv.Hits("/instruments/historical/"); hits != 2 {
+		t.Errorf("historical requests = %d, want 2 chunks", hits)
+	}
+
+	// Market buy fills at the last price
+	buy, err := zb.PlaceOrder(ctx, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy,
+		Type: models.OrderTypeMarket, Product: models.ProductMIS, Quantity: 10,
+	})
+	if err != nil {
+		t.Fatalf("place market order: %v", err)
+	}
+	if o := findOrder(t, zb, buy.OrderID); o.Status != kitetest.StatusComplete || o.AveragePrice != 1500 || o.FilledQty != 10 {
+		t.Errorf("market order %s filled %d at %.2f", o.Status, o.FilledQty, o.AveragePrice)
+	}
+	positions, err := zb.GetPositions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 1 || positions[0].Quantity != 10 || positions[0].AveragePrice != 1500 {
+		t.Fatalf("positions after buy: %+v", positions)
+	}
+
+	// A limit exit rests until the market reaches its modified price
+	exit, err := zb.PlaceOrder(ctx, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideSell,
+		Type: models.OrderTypeLimit, Product: models.ProductMIS, Quantity: 10, Price: 1520,
+	})
+	if err != nil {
+		t.Fatalf("place limit order: %v", err)
+	}
+	if o := findOrder(t, zb, exit.OrderID); o.Status != kitetest.StatusOpen {
+		t.Fatalf("limit order above the market is %s", o.Status)
+	}
+	if err := zb.ModifyOrder(ctx, exit.OrderID, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideSell,
+		Type: models.OrderTypeLimit, Product: models.ProductMIS, Quantity: 10, Price: 1510,
+	}); err != nil {
+		t.Fatalf("modify order: %v", err)
+	}
+	if err := srv.Play(ctx, kitetest.Ramp("INFY", 1500, 1508, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if o := findOrder(t, zb, exit.OrderID); o.Status != kitetest.StatusOpen {
+		t.Fatalf("limit sell at 1510 is %s with the market at 1508", o.Status)
+	}
+	if err := srv.Play(ctx, kitetest.Path("INFY", 1511)); err != nil {
+		t.Fatal(err)
+	}
+	if o := findOrder(t, zb, exit.OrderID); o.Status != kitetest.StatusComplete || o.AveragePrice != 1511 {
+		t.Errorf("limit sell %s at %.2f, want COMPLETE at 1511", o.Status, o.AveragePrice)
+	}
+	if positions, _ := zb.GetPositions(ctx); len(positions) != 0 {
+		t.Errorf("positions after exit: %+v", positions)
+	}
+	if err := zb.ModifyOrder(ctx, exit.OrderID, &models.Order{Price: 1530}); err == nil {
+		t.Error("modified a completed order")
+	}
+
+	// Resting orders can be cancelled
+	bid, err := zb.PlaceOrder(ctx, &models.Order{
+		Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy,
+		Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 5, Price: 1400,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zb.CancelOrder(ctx, bid.OrderID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if o := findOrder(t, zb, bid.OrderID); o.Status != kitetest.StatusCancelled {
+		t.Errorf("cancelled order is %s", o.Status)
+	}
+
+	// Orders beyond the available margin are accepted and then rejected
+	big, err := zb.PlaceOrder(ctx, &models.Order{
+		Symbol: "RELIANCE", Exchange: models.NSE, Side: models.OrderSideBuy,
+		Type: models.OrderTypeMarket, Product: models.ProductCNC, Quantity: 1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := findOrder(t, zb, big.OrderID); o.Status != kitetest.StatusRejected || o.StatusMessage == "" {
+		t.Errorf("oversized order %s: %q", o.Status, o.StatusMessage)
+	}
+
+	// A GTT buy below the market fires on the way down and fills
+	gtt, err := zb.PlaceGTT(ctx, &models.GTTOrder{
+		Symbol: "RELIANCE", Exchange: models.NSE, TriggerType: "single",
+		TriggerPrice: 2450, LastPrice: 2500,
+		Orders: []models.GTTOrderLeg{{Side: models.OrderSideBuy, Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 2, Price: 2455}},
+	})
+	if err != nil {
+		t.Fatalf("place GTT: %v", err)
+	}
+	if err := srv.Play(ctx, kitetest.Path("RELIANCE", 2480, 2460, 2449)); err != nil {
+		t.Fatal(err)
+	}
+	gtts, err := zb.GetGTTs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gtts) != 1 || gtts[0].ID != gtt.TriggerID || gtts[0].Status != kitetest.GTTTriggered {
+		t.Fatalf("GTTs after trigger: %+v", gtts)
+	}
+	positions, _ = zb.GetPositions(ctx)
+	if len(positions) != 1 || positions[0].Symbol != "RELIANCE" || positions[0].Quantity != 2 || positions[0].AveragePrice != 2449 {
+		t.Errorf("positions after GTT: %+v", positions)
+	}
+
+	balance, err := zb.GetBalance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Bought INFY at 1500 and sold at 1511, then bought RELIANCE at 2449
+	if want := kitetest.DefaultCash + 110 - 2*2449; balance.TotalEquity != want {
+		t.Errorf("equity %.2f, want %.2f", balance.TotalEquity, want)
+	}
+
+	accessToken := zb.GetAccessToken()
+	if err := zb.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/user/profile", nil)
+	req.Header.Set("Authorization", "token "+kitetest.APIKey+":"+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("profile after logout returned %d, want 403", resp.StatusCode)
+	}
+}
+
+// TestZerodhaTickerStream streams binary ticks from a fake Kite server
+// through ZerodhaTicker.
+func TestZerodhaTickerStream(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	srv := kitetest.NewServer()
+	defer srv.Close()
+	srv.AddInstrument(kitetest.Instrument{Symbol: "INFY", Price: 1500})
+	zb := newKiteBroker(t, srv)
+
+	token, err := zb.GetInstrumentToken(ctx, "INFY", models.NSE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticker, err := zb.CreateTicker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticks := make(chan models.Tick, 64)
+	ticker.OnTick(func(tick models.Tick) { ticks <- tick })
+	ticker.RegisterSymbol("INFY", token)
+	if err := ticker.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer ticker.Disconnect()
+	if err := ticker.Subscribe([]string{"INFY"}, broker.TickModeFull); err != nil {
+		t.Fatal(err)
+	}
+	for srv.Subscribers("INFY") == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("ticker never subscribed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	go srv.Play(ctx, kitetest.Ramp("INFY", 1500, 1525, 5).Every(20*time.Millisecond))
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatal("no tick at 1525")
+		case tick := <-ticks:
+			if tick.Symbol != "INFY" {
+				t.Fatalf("tick for %q", tick.Symbol)
+			}
+			if tick.LTP != 1525 {
+				continue
+			}
+			if tick.High != 1525 || tick.Close != 1500 || tick.BidPrice != 1524.95 || tick.AskPrice != 1525.05 {
+				t.Errorf("tick %+v", tick)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// LLMUsage records one LLM completion made on behalf of an agent.
+type LLMUsage struct {
+	ID               int64
+	Timestamp        time.Time
+	Agent            string
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Latency          time.Duration
+	Cost             float64 // Estimated, in USD
+	Cached           bool    // Served from the response cache; no tokens spent
+	Error            string
+}
+
+// LLMUsageSummary aggregates LLM usage for one agent.
+type LLMUsageSummary struct {
+	Agent            string
+	Calls            int
+	CachedCalls      int
+	Errors           int
+	PromptTokens     int
+	CompletionTokens int
+	AvgLatency       time.Duration
+	Cost             float64
+}
@@ -34,6 +34,21 @@ const (
 	OrderTypeStopLossM OrderType = "SL-M"
 )
 
+// Order statuses as reported by Kite.
+const (
+	OrderStatusOpen           = "OPEN"
+	OrderStatusTriggerPending = "TRIGGER PENDING"
+	OrderStatusComplete       = "COMPLETE"
+	OrderStatusCancelled      = "CANCELLED"
+	OrderStatusRejected       = "REJECTED"
+)
+
+// Order validities.
+const (
+	ValidityDay = "DAY"
+	ValidityIOC = "IOC"
+)
+
 // ProductType represents the product type of an order.
 type ProductType string
 
@@ -77,6 +92,21 @@ type Tick struct {
 	BidPrice     float64
 	AskPrice     float64
 	Timestamp    time.Time
+	// Depth is the five-level order book, sent only in full mode.
+	Depth *MarketDepth
+}
+
+// MarketDepth holds the best five bid and ask levels of an order book.
+type MarketDepth struct {
+	Bids [5]DepthLevel
+	Asks [5]DepthLevel
+}
+
+// DepthLevel is one price level of market depth.
+type DepthLevel struct {
+	Price    float64
+	Quantity int64
+	Orders   int
 }
 
 // Quote represents a market quote.
@@ -19,10 +19,11 @@ type OptionStrike struct {
 
 // OptionData represents option data for a single contract.
 type OptionData struct {
+	Symbol string // NFO trading symbol
 	LTP    float64
 	OI     int64
 	Volume int64
-	IV     float64
+	IV     float64 // implied volatility, percent
 	Greeks OptionGreeks
 }
 
@@ -55,18 +56,29 @@ type FuturesExpiry struct {
 // OptionStrategy represents an option strategy.
 type OptionStrategy struct {
 	Name       string
+	Symbol     string // underlying
+	Spot       float64
 	Legs       []OptionLeg
 	MaxProfit  float64
-	MaxLoss    float64
+	MaxLoss    float64 // positive amount
 	Breakevens []float64
-	NetPremium float64
+	NetPremium float64 // credit received is positive, debit paid negative
+	// UnlimitedProfit and UnlimitedLoss mark payoffs that keep growing as
+	// the underlying rises; MaxProfit/MaxLoss are then meaningless.
+	UnlimitedProfit     bool
+	UnlimitedLoss       bool
+	ProbabilityOfProfit float64 // percent
+	LotSize             int
 }
 
 // OptionLeg represents a leg of an option strategy.
 type OptionLeg struct {
+	Symbol   string // NFO trading symbol
 	Strike   float64
-	Type     string // CALL, PUT
+	Type     string // CALL, PUT, FUT
+	Expiry   time.Time
 	Side     OrderSide
 	Quantity int
 	Premium  float64
+	IV       float64 // implied volatility, percent
 }
@@ -16,9 +16,11 @@ type Order struct {
 	Validity     string // DAY, IOC
 	Tag          string
 	Status       string
-	FilledQty    int
-	AveragePrice float64
-	PlacedAt     time.Time
+	// StatusMessage explains a rejection or cancellation.
+	StatusMessage string
+	FilledQty     int
+	AveragePrice  float64
+	PlacedAt      time.Time
 }
 
 // GTTOrder represents a Good Till Triggered order.
@@ -31,8 +33,10 @@ type GTTOrder struct {
 	LastPrice    float64
 	Orders       []GTTOrderLeg
 	Status       string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// OrderID is the order placed when the GTT triggered.
+	OrderID   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // GTTOrderLeg represents a leg of a GTT order.
@@ -42,6 +46,8 @@ type GTTOrderLeg struct {
 	Product  ProductType
 	Quantity int
 	Price    float64
+	// TriggerPrice is the leg's trigger value for two-leg (OCO) GTTs.
+	TriggerPrice float64
 }
 
 // Position represents an open trading position.
@@ -68,6 +74,9 @@ type Holding struct {
 	PnLPercent    float64
 	InvestedValue float64
 	CurrentValue  float64
+	// T1Quantity is the part of Quantity bought in the last session and
+	// still awaiting T+1 settlement.
+	T1Quantity int
 }
 
 // Balance represents account balance.
@@ -90,3 +99,11 @@ type SegmentMargin struct {
 	Used      float64
 	Total     float64
 }
+
+// KillSwitch is the persisted state of the trading kill switch. While it is
+// engaged no new orders reach the broker.
+type KillSwitch struct {
+	Engaged   bool
+	Reason    string
+	UpdatedAt time.Time
+}
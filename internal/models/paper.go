@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Paper ledger entry types.
+const (
+	PaperLedgerDeposit    = "DEPOSIT"
+	PaperLedgerReset      = "RESET"
+	PaperLedgerBuy        = "BUY"
+	PaperLedgerSell       = "SELL"
+	PaperLedgerCharges    = "CHARGES"
+	PaperLedgerSettlement = "SETTLEMENT"
+)
+
+// PaperAccount is the persisted cash state of a named paper trading account.
+type PaperAccount struct {
+	Name           string
+	InitialBalance float64
+	Cash           float64
+	RealizedPnL    float64 // before charges
+	Charges        float64
+	OrderCounter   int
+	GTTCounter     int
+	// SessionClose is the close of the session the account is trading in.
+	// End-of-day processing runs once the clock passes it.
+	SessionClose time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	// Version counts saves, so a process can tell when another changed the
+	// account since it was loaded.
+	Version int64
+}
+
+// PaperAccountState is everything needed to restore a paper account.
+type PaperAccountState struct {
+	Account   PaperAccount
+	Orders    []Order
+	Positions []Position
+	Holdings  []Holding
+}
+
+// PaperLedgerEntry records a cash movement or settlement in a paper account.
+type PaperLedgerEntry struct {
+	ID      int64
+	Account string
+	Time    time.Time
+	Type    string
+	Symbol  string
+	OrderID string
+	Amount  float64 // signed change in cash
+	Balance float64 // cash after the entry
+	Note    string
+}
+
+// PaperSnapshot is a point-in-time summary of a paper account, used to track
+// and compare accounts over time.
+type PaperSnapshot struct {
+	ID            int64
+	Account       string
+	Label         string
+	TakenAt       time.Time
+	Cash          float64
+	Equity        float64
+	RealizedPnL   float64 // before charges
+	UnrealizedPnL float64
+	Charges       float64
+	ReturnPercent float64
+	OpenPositions int
+	Holdings      int
+	Trades        int
+}
@@ -0,0 +1,65 @@
+package options
+
+import (
+	"strings"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+// indexQuoteSymbols maps F&O index names to their NSE index quote symbols.
+var indexQuoteSymbols = map[string]string{
+	"NIFTY":      "NSE:NIFTY 50",
+	"BANKNIFTY":  "NSE:NIFTY BANK",
+	"FINNIFTY":   "NSE:NIFTY FIN SERVICE",
+	"MIDCPNIFTY": "NSE:NIFTY MID SELECT",
+	"NIFTYNXT50": "NSE:NIFTY NEXT 50",
+}
+
+// UnderlyingQuoteSymbol returns the quote symbol for an F&O underlying name,
+// e.g. "NSE:NIFTY 50" for NIFTY and "NSE:RELIANCE" for RELIANCE.
+func UnderlyingQuoteSymbol(name string) string {
+	name = strings.ToUpper(name)
+	if symbol, ok := indexQuoteSymbols[name]; ok {
+		return symbol
+	}
+	return "NSE:" + name
+}
+
+// Evaluate solves implied volatility from ltp and fills data's IV (in
+// percent) and Greeks. It reports false, leaving data untouched, when the
+// LTP admits no volatility, e.g. a stale quote below intrinsic value.
+func Evaluate(data *models.OptionData, in Inputs, ltp float64) bool {
+	vol, err := ImpliedVol(in, ltp)
+	if err != nil {
+		return false
+	}
+	in.Vol = vol
+	data.IV = vol * 100
+	data.Greeks = Greeks(in)
+	return true
+}
+
+// ApplyChain fills IV and Greeks for every contract in chain, pricing off the
+// spot with Black-Scholes at the given rate.
+func ApplyChain(chain *models.OptionChain, now time.Time, rate float64) {
+	t := YearsToExpiry(now, chain.Expiry)
+	for i := range chain.Strikes {
+		strike := &chain.Strikes[i]
+		in := Inputs{
+			Model:      BlackScholes,
+			Underlying: chain.SpotPrice,
+			Strike:     strike.Strike,
+			T:          t,
+			Rate:       rate,
+		}
+		if strike.Call != nil {
+			in.Kind = Call
+			Evaluate(strike.Call, in, strike.Call.LTP)
+		}
+		if strike.Put != nil {
+			in.Kind = Put
+			Evaluate(strike.Put, in, strike.Put.LTP)
+		}
+	}
+}
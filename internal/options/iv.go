@@ -0,0 +1,74 @@
+package options
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNoImpliedVol is returned when a price lies outside the no-arbitrage
+// bounds, so no volatility reproduces it.
+var ErrNoImpliedVol = errors.New("price outside no-arbitrage bounds")
+
+const (
+	minVol        = 1e-4
+	maxVol        = 5.0
+	ivTolerance   = 1e-6
+	maxIterations = 100
+)
+
+// ImpliedVol solves for the volatility at which the option prices at price.
+// in.Vol is ignored. Newton's method is tried first, falling back to
+// bisection when vega is too small for it to converge.
+func ImpliedVol(in Inputs, price float64) (float64, error) {
+	if in.T <= 0 || in.Underlying <= 0 || in.Strike <= 0 || price <= 0 {
+		return 0, ErrNoImpliedVol
+	}
+
+	s, k := in.forward()
+	lower, upper := math.Max(s-k, 0), s
+	if in.Kind == Put {
+		lower, upper = math.Max(k-s, 0), k
+	}
+	if price < lower-ivTolerance || price >= upper {
+		return 0, ErrNoImpliedVol
+	}
+
+	// Brenner-Subrahmanyam approximation as the starting point
+	in.Vol = math.Sqrt(2*math.Pi/in.T) * price / s
+	in.Vol = math.Min(math.Max(in.Vol, 0.05), 2)
+
+	for i := 0; i < maxIterations; i++ {
+		diff := Price(in) - price
+		if math.Abs(diff) < ivTolerance {
+			return in.Vol, nil
+		}
+		vega := Greeks(in).Vega * 100
+		if vega < 1e-8 {
+			break
+		}
+		next := in.Vol - diff/vega
+		if next <= minVol || next >= maxVol {
+			break
+		}
+		in.Vol = next
+	}
+
+	return bisect(in, price)
+}
+
+func bisect(in Inputs, price float64) (float64, error) {
+	lo, hi := minVol, maxVol
+	for i := 0; i < 200; i++ {
+		in.Vol = (lo + hi) / 2
+		diff := Price(in) - price
+		if math.Abs(diff) < ivTolerance || hi-lo < 1e-10 {
+			return in.Vol, nil
+		}
+		if diff > 0 {
+			hi = in.Vol
+		} else {
+			lo = in.Vol
+		}
+	}
+	return 0, ErrNoImpliedVol
+}
@@ -0,0 +1,97 @@
+package options
+
+import (
+	"math"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+// FutureLeg is the models.OptionLeg type of a futures position held
+// alongside options; its value is the underlying price.
+const FutureLeg = "FUT"
+
+// Scenario is a hypothetical market: the underlying at Price at time At,
+// with every leg's IV shifted by IVShift volatility points.
+type Scenario struct {
+	Price   float64
+	At      time.Time
+	IVShift float64
+}
+
+// PayoffPoint is one point of a payoff curve.
+type PayoffPoint struct {
+	Price float64 `json:"price"`
+	PnL   float64 `json:"pnl"`
+}
+
+// ScenarioPnL returns the strategy's profit or loss in a scenario. Expired
+// legs are worth their intrinsic value; the rest are priced with
+// Black-Scholes.
+func ScenarioPnL(s *models.OptionStrategy, sc Scenario, rate float64) float64 {
+	var pnl float64
+	for _, leg := range s.Legs {
+		value := sc.Price
+		if leg.Type != FutureLeg {
+			kind, _ := ParseKind(leg.Type)
+			value = Price(Inputs{
+				Kind:       kind,
+				Underlying: sc.Price,
+				Strike:     leg.Strike,
+				T:          YearsToExpiry(sc.At, leg.Expiry),
+				Rate:       rate,
+				Vol:        math.Max(leg.IV+sc.IVShift, 0) / 100,
+			})
+		}
+
+		change := value - leg.Premium
+		if leg.Side == models.OrderSideSell {
+			change = -change
+		}
+		pnl += change * float64(leg.Quantity)
+	}
+	return pnl
+}
+
+// PayoffCurve samples the strategy's profit or loss at points prices evenly
+// spaced from lo to hi, at time at and with IVs shifted by ivShift.
+func PayoffCurve(s *models.OptionStrategy, lo, hi float64, points int, at time.Time, ivShift, rate float64) []PayoffPoint {
+	if points < 2 {
+		points = 2
+	}
+	curve := make([]PayoffPoint, points)
+	for i := range curve {
+		price := lo + (hi-lo)*float64(i)/float64(points-1)
+		curve[i] = PayoffPoint{
+			Price: price,
+			PnL:   ScenarioPnL(s, Scenario{Price: price, At: at, IVShift: ivShift}, rate),
+		}
+	}
+	return curve
+}
+
+// FillLegIV solves the IV of option legs that have none from their premium,
+// with the underlying at s.Spot. Legs whose premium admits no volatility
+// keep a zero IV and are valued at intrinsic.
+func FillLegIV(s *models.OptionStrategy, now time.Time, rate float64) {
+	for i := range s.Legs {
+		leg := &s.Legs[i]
+		if leg.IV > 0 || leg.Type == FutureLeg {
+			continue
+		}
+		kind, err := ParseKind(leg.Type)
+		if err != nil {
+			continue
+		}
+		vol, err := ImpliedVol(Inputs{
+			Kind:       kind,
+			Underlying: s.Spot,
+			Strike:     leg.Strike,
+			T:          YearsToExpiry(now, leg.Expiry),
+			Rate:       rate,
+		}, leg.Premium)
+		if err == nil {
+			leg.IV = vol * 100
+		}
+	}
+}
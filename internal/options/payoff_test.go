@@ -0,0 +1,144 @@
+package options
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+var payoffExpiry = time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC)
+
+// payoffBullCallSpread buys the 22000 call at 100 and sells the 22100 call
+// at 60, one lot of 50: a 40 debit, so 2000 max loss, 3000 max profit and a
+// breakeven at 22040.
+func payoffBullCallSpread() *models.OptionStrategy {
+	return &models.OptionStrategy{
+		Symbol: "NIFTY",
+		Spot:   22050,
+		Legs: []models.OptionLeg{
+			{Strike: 22000, Type: "CALL", Expiry: payoffExpiry, Side: models.OrderSideBuy, Quantity: 50, Premium: 100, IV: 14},
+			{Strike: 22100, Type: "CALL", Expiry: payoffExpiry, Side: models.OrderSideSell, Quantity: 50, Premium: 60, IV: 13},
+		},
+	}
+}
+
+func TestScenarioPnL_AtExpiry(t *testing.T) {
+	afterExpiry := payoffExpiry.Add(24 * time.Hour)
+	spread := payoffBullCallSpread()
+	straddle := &models.OptionStrategy{Legs: []models.OptionLeg{
+		{Strike: 22000, Type: "CALL", Expiry: payoffExpiry, Side: models.OrderSideSell, Quantity: 50, Premium: 150},
+		{Strike: 22000, Type: "PUT", Expiry: payoffExpiry, Side: models.OrderSideSell, Quantity: 50, Premium: 130},
+	}}
+	covered := &models.OptionStrategy{Legs: []models.OptionLeg{
+		{Type: FutureLeg, Side: models.OrderSideBuy, Quantity: 50, Premium: 22000},
+		{Strike: 22200, Type: "CALL", Expiry: payoffExpiry, Side: models.OrderSideSell, Quantity: 50, Premium: 80},
+	}}
+
+	tests := []struct {
+		name     string
+		strategy *models.OptionStrategy
+		price    float64
+		want     float64
+	}{
+		{"spread below both strikes", spread, 21900, -2000},
+		{"spread at the long strike", spread, 22000, -2000},
+		{"spread at breakeven", spread, 22040, 0},
+		{"spread between strikes", spread, 22070, 1500},
+		{"spread above both strikes", spread, 22300, 3000},
+		{"straddle at the strike", straddle, 22000, 14000},
+		{"straddle upper breakeven", straddle, 22280, 0},
+		{"straddle lower breakeven", straddle, 21720, 0},
+		{"straddle far below", straddle, 21500, -11000},
+		{"covered future below", covered, 21900, -1000},
+		{"covered future capped", covered, 22500, 14000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScenarioPnL(tt.strategy, Scenario{Price: tt.price, At: afterExpiry}, DefaultRate)
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("ScenarioPnL(%.0f) = %.2f, want %.2f", tt.price, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScenarioPnL_BeforeExpiry(t *testing.T) {
+	now := payoffExpiry.AddDate(0, 0, -10)
+	long := &models.OptionStrategy{Legs: []models.OptionLeg{
+		{Strike: 22000, Type: "CALL", Expiry: payoffExpiry, Side: models.OrderSideBuy, Quantity: 50, Premium: 100, IV: 14},
+	}}
+
+	base := ScenarioPnL(long, Scenario{Price: 22000, At: now}, DefaultRate)
+	atExpiry := ScenarioPnL(long, Scenario{Price: 22000, At: payoffExpiry.Add(24 * time.Hour)}, DefaultRate)
+	if base <= atExpiry {
+		t.Errorf("time value missing: %.2f before expiry, %.2f at expiry", base, atExpiry)
+	}
+
+	if up := ScenarioPnL(long, Scenario{Price: 22000, At: now, IVShift: 5}, DefaultRate); up <= base {
+		t.Errorf("IV +5 gave %.2f, want more than %.2f", up, base)
+	}
+
+	// IV cannot be shifted below zero: the leg is valued at zero volatility
+	crushed := ScenarioPnL(long, Scenario{Price: 22100, At: now, IVShift: -50}, DefaultRate)
+	zeroVol := Price(Inputs{Kind: Call, Underlying: 22100, Strike: 22000, T: YearsToExpiry(now, payoffExpiry), Rate: DefaultRate})
+	if want := (zeroVol - 100) * 50; math.Abs(crushed-want) > 1e-6 {
+		t.Errorf("IV crushed below zero gave %.2f, want %.2f", crushed, want)
+	}
+}
+
+func TestPayoffCurve(t *testing.T) {
+	spread := payoffBullCallSpread()
+	at := payoffExpiry.Add(24 * time.Hour)
+
+	curve := PayoffCurve(spread, 21800, 22300, 6, at, 0, DefaultRate)
+	want := []PayoffPoint{
+		{21800, -2000}, {21900, -2000}, {22000, -2000}, {22100, 3000}, {22200, 3000}, {22300, 3000},
+	}
+	if len(curve) != len(want) {
+		t.Fatalf("got %d points, want %d", len(curve), len(want))
+	}
+	for i, p := range curve {
+		if math.Abs(p.Price-want[i].Price) > 1e-9 || math.Abs(p.PnL-want[i].PnL) > 1e-6 {
+			t.Errorf("point %d = %+v, want %+v", i, p, want[i])
+		}
+	}
+
+	if curve := PayoffCurve(spread, 21800, 22300, 1, at, 0, DefaultRate); len(curve) != 2 ||
+		curve[0].Price != 21800 || curve[1].Price != 22300 {
+		t.Errorf("expected fewer than two points to give the endpoints, got %+v", curve)
+	}
+}
+
+func TestFillLegIV(t *testing.T) {
+	now := payoffExpiry.AddDate(0, 0, -14)
+	in := Inputs{Kind: Call, Underlying: 22000, Strike: 22100, T: YearsToExpiry(now, payoffExpiry), Rate: DefaultRate, Vol: 0.15}
+	premium := Price(in)
+
+	s := &models.OptionStrategy{
+		Spot: 22000,
+		Legs: []models.OptionLeg{
+			{Strike: 22100, Type: "CALL", Expiry: payoffExpiry, Side: models.OrderSideBuy, Quantity: 50, Premium: premium},
+			{Strike: 22100, Type: "CALL", Expiry: payoffExpiry, Side: models.OrderSideBuy, Quantity: 50, Premium: premium, IV: 30},
+			{Type: FutureLeg, Side: models.OrderSideBuy, Quantity: 50, Premium: 22050},
+			// Below intrinsic: no volatility reproduces it
+			{Strike: 21500, Type: "CALL", Expiry: payoffExpiry, Side: models.OrderSideBuy, Quantity: 50, Premium: 100},
+		},
+	}
+	FillLegIV(s, now, DefaultRate)
+
+	if math.Abs(s.Legs[0].IV-15) > 0.01 {
+		t.Errorf("solved IV = %.4f, want 15", s.Legs[0].IV)
+	}
+	if s.Legs[1].IV != 30 {
+		t.Errorf("existing IV overwritten with %.4f", s.Legs[1].IV)
+	}
+	if s.Legs[2].IV != 0 {
+		t.Errorf("futures leg given IV %.4f", s.Legs[2].IV)
+	}
+	if s.Legs[3].IV != 0 {
+		t.Errorf("premium below intrinsic given IV %.4f", s.Legs[3].IV)
+	}
+}
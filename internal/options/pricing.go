@@ -0,0 +1,185 @@
+// Package options prices European options and computes implied volatility
+// and Greeks for NSE index and stock options.
+package options
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+)
+
+// DefaultRate is the annualised risk-free rate used when none is given,
+// roughly the 91-day T-bill yield.
+const DefaultRate = 0.065
+
+// Kind is the option type.
+type Kind string
+
+const (
+	Call Kind = "CE"
+	Put  Kind = "PE"
+)
+
+// ParseKind accepts CE/PE as well as CALL/PUT and C/P, case-insensitively.
+func ParseKind(s string) (Kind, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "CE", "CALL", "C":
+		return Call, nil
+	case "PE", "PUT", "P":
+		return Put, nil
+	}
+	return "", fmt.Errorf("invalid option type %q: use CE or PE", s)
+}
+
+// Model selects what the underlying price refers to.
+type Model int
+
+const (
+	// BlackScholes prices off the spot with a continuous dividend yield.
+	BlackScholes Model = iota
+	// Black76 prices off the futures price for the same expiry.
+	Black76
+)
+
+// Inputs describes a European option to price.
+type Inputs struct {
+	Kind       Kind
+	Model      Model
+	Underlying float64 // spot for BlackScholes, futures price for Black76
+	Strike     float64
+	T          float64 // years to expiry
+	Rate       float64 // annualised, continuously compounded
+	Dividend   float64 // continuous dividend yield, BlackScholes only
+	Vol        float64 // annualised volatility as a fraction
+}
+
+// carry returns the discount factor applied to the underlying, e^{-qT} for
+// Black-Scholes and e^{-rT} for Black-76.
+func (in Inputs) carry() float64 {
+	if in.Model == Black76 {
+		return math.Exp(-in.Rate * in.T)
+	}
+	return math.Exp(-in.Dividend * in.T)
+}
+
+// forward returns the discounted underlying and discounted strike.
+func (in Inputs) forward() (float64, float64) {
+	return in.Underlying * in.carry(), in.Strike * math.Exp(-in.Rate*in.T)
+}
+
+func (in Inputs) d1d2() (float64, float64) {
+	s, k := in.forward()
+	sd := in.Vol * math.Sqrt(in.T)
+	d1 := (math.Log(s/k) + 0.5*sd*sd) / sd
+	return d1, d1 - sd
+}
+
+// degenerate reports whether the option has no time value left to model.
+func (in Inputs) degenerate() bool {
+	return in.T <= 0 || in.Vol <= 0 || in.Underlying <= 0 || in.Strike <= 0
+}
+
+// Price returns the option's fair value.
+func Price(in Inputs) float64 {
+	s, k := in.forward()
+	if in.degenerate() {
+		if in.T <= 0 {
+			s, k = in.Underlying, in.Strike
+		}
+		if in.Kind == Put {
+			return math.Max(k-s, 0)
+		}
+		return math.Max(s-k, 0)
+	}
+
+	d1, d2 := in.d1d2()
+	if in.Kind == Put {
+		return k*normCDF(-d2) - s*normCDF(-d1)
+	}
+	return s*normCDF(d1) - k*normCDF(d2)
+}
+
+// Greeks returns the option's sensitivities per unit of underlying. Theta is
+// per calendar day, Vega per one volatility point and Rho per one percentage
+// point of rate.
+func Greeks(in Inputs) models.OptionGreeks {
+	if in.degenerate() {
+		// Only delta survives: the option is worth its (discounted) intrinsic value
+		s, k, carry := in.Underlying, in.Strike, 1.0
+		if in.T > 0 {
+			s, k = in.forward()
+			carry = in.carry()
+		}
+		var delta float64
+		switch {
+		case in.Kind == Call && s > k:
+			delta = carry
+		case in.Kind == Put && s < k:
+			delta = -carry
+		}
+		return models.OptionGreeks{Delta: delta}
+	}
+
+	s, k := in.forward()
+	carry := in.carry()
+	sqrtT := math.Sqrt(in.T)
+	d1, d2 := in.d1d2()
+	pdf := normPDF(d1)
+
+	var g models.OptionGreeks
+	g.Gamma = carry * pdf / (in.Underlying * in.Vol * sqrtT)
+	g.Vega = s * pdf * sqrtT / 100
+
+	// Yield on the underlying: q for Black-Scholes, r for Black-76
+	yield := in.Dividend
+	if in.Model == Black76 {
+		yield = in.Rate
+	}
+
+	decay := -s * pdf * in.Vol / (2 * sqrtT)
+	if in.Kind == Put {
+		g.Delta = -carry * normCDF(-d1)
+		g.Theta = decay + in.Rate*k*normCDF(-d2) - yield*s*normCDF(-d1)
+		g.Rho = -k * in.T * normCDF(-d2)
+	} else {
+		g.Delta = carry * normCDF(d1)
+		g.Theta = decay - in.Rate*k*normCDF(d2) + yield*s*normCDF(d1)
+		g.Rho = k * in.T * normCDF(d2)
+	}
+	if in.Model == Black76 {
+		// Holding the futures price fixed, the rate only enters through discounting
+		g.Rho = -in.T * Price(in)
+	}
+	g.Theta /= 365
+	g.Rho /= 100
+	return g
+}
+
+// ExpiryClose returns the moment an option expiring on the given date stops
+// trading: 15:30 IST.
+func ExpiryClose(expiry time.Time) time.Time {
+	d := expiry.In(resilience.IndiaLocation)
+	return time.Date(d.Year(), d.Month(), d.Day(), 15, 30, 0, 0, resilience.IndiaLocation)
+}
+
+// YearsToExpiry returns the time from now to the expiry close in years,
+// floored at zero.
+func YearsToExpiry(now, expiry time.Time) float64 {
+	remaining := ExpiryClose(expiry).Sub(now)
+	if remaining <= 0 {
+		return 0
+	}
+	return remaining.Hours() / (365 * 24)
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-0.5*x*x) / math.Sqrt(2*math.Pi)
+}
@@ -0,0 +1,125 @@
+package options
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/resilience"
+)
+
+func TestPrice_HullExample(t *testing.T) {
+	// Hull, Options Futures and Other Derivatives, example 15.6
+	in := Inputs{Kind: Call, Underlying: 42, Strike: 40, T: 0.5, Rate: 0.1, Vol: 0.2}
+	if got := Price(in); math.Abs(got-4.76) > 0.005 {
+		t.Errorf("call: expected 4.76, got %.4f", got)
+	}
+	in.Kind = Put
+	if got := Price(in); math.Abs(got-0.81) > 0.005 {
+		t.Errorf("put: expected 0.81, got %.4f", got)
+	}
+}
+
+func TestPrice_PutCallParity(t *testing.T) {
+	for _, model := range []Model{BlackScholes, Black76} {
+		in := Inputs{Model: model, Underlying: 19500, Strike: 19700, T: 20.0 / 365, Rate: 0.065, Dividend: 0.012, Vol: 0.14}
+		in.Kind = Call
+		call := Price(in)
+		in.Kind = Put
+		put := Price(in)
+
+		s, k := in.forward()
+		if diff := call - put - (s - k); math.Abs(diff) > 1e-8 {
+			t.Errorf("model %d: parity violated by %.10f", model, diff)
+		}
+	}
+}
+
+func TestGreeks_MatchFiniteDifferences(t *testing.T) {
+	const h = 1e-4
+	for _, kind := range []Kind{Call, Put} {
+		in := Inputs{Kind: kind, Underlying: 2450, Strike: 2500, T: 30.0 / 365, Rate: 0.065, Dividend: 0.01, Vol: 0.22}
+		g := Greeks(in)
+
+		up, down := in, in
+		up.Underlying += h
+		down.Underlying -= h
+		if fd := (Price(up) - Price(down)) / (2 * h); math.Abs(fd-g.Delta) > 1e-5 {
+			t.Errorf("%s delta: expected %.6f, got %.6f", kind, fd, g.Delta)
+		}
+		if fd := (Greeks(up).Delta - Greeks(down).Delta) / (2 * h); math.Abs(fd-g.Gamma) > 1e-6 {
+			t.Errorf("%s gamma: expected %.8f, got %.8f", kind, fd, g.Gamma)
+		}
+
+		up, down = in, in
+		up.Vol += h
+		down.Vol -= h
+		if fd := (Price(up) - Price(down)) / (2 * h) / 100; math.Abs(fd-g.Vega) > 1e-5 {
+			t.Errorf("%s vega: expected %.6f, got %.6f", kind, fd, g.Vega)
+		}
+
+		up, down = in, in
+		up.Rate += h
+		down.Rate -= h
+		if fd := (Price(up) - Price(down)) / (2 * h) / 100; math.Abs(fd-g.Rho) > 1e-5 {
+			t.Errorf("%s rho: expected %.6f, got %.6f", kind, fd, g.Rho)
+		}
+
+		up, down = in, in
+		up.T -= h
+		down.T += h
+		if fd := (Price(up) - Price(down)) / (2 * h) / 365; math.Abs(fd-g.Theta) > 1e-5 {
+			t.Errorf("%s theta: expected %.6f, got %.6f", kind, fd, g.Theta)
+		}
+	}
+}
+
+func TestImpliedVol_RoundTrip(t *testing.T) {
+	cases := []Inputs{
+		{Kind: Call, Underlying: 19500, Strike: 19500, T: 7.0 / 365, Rate: 0.065, Vol: 0.12},
+		{Kind: Put, Underlying: 19500, Strike: 18800, T: 7.0 / 365, Rate: 0.065, Vol: 0.18},
+		{Kind: Call, Underlying: 2450, Strike: 2000, T: 60.0 / 365, Rate: 0.065, Vol: 0.35},
+		{Kind: Put, Model: Black76, Underlying: 44200, Strike: 45000, T: 25.0 / 365, Rate: 0.065, Vol: 0.16},
+		{Kind: Call, Underlying: 100, Strike: 100, T: 1, Rate: 0.05, Vol: 1.5},
+	}
+	for _, in := range cases {
+		price := Price(in)
+		vol, err := ImpliedVol(in, price)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %v", in, err)
+		}
+		if math.Abs(vol-in.Vol) > 1e-4 {
+			t.Errorf("%+v: expected vol %.4f, got %.6f", in, in.Vol, vol)
+		}
+	}
+}
+
+func TestImpliedVol_OutsideBounds(t *testing.T) {
+	in := Inputs{Kind: Call, Underlying: 19500, Strike: 19000, T: 7.0 / 365, Rate: 0.065}
+
+	// Below discounted intrinsic value
+	if _, err := ImpliedVol(in, 400); err != ErrNoImpliedVol {
+		t.Errorf("expected ErrNoImpliedVol below intrinsic, got %v", err)
+	}
+	// Above the underlying
+	if _, err := ImpliedVol(in, 20000); err != ErrNoImpliedVol {
+		t.Errorf("expected ErrNoImpliedVol above spot, got %v", err)
+	}
+	// Expired
+	in.T = 0
+	if _, err := ImpliedVol(in, 500); err != ErrNoImpliedVol {
+		t.Errorf("expected ErrNoImpliedVol at expiry, got %v", err)
+	}
+}
+
+func TestYearsToExpiry(t *testing.T) {
+	expiry := time.Date(2024, 1, 25, 0, 0, 0, 0, resilience.IndiaLocation)
+	now := time.Date(2024, 1, 24, 15, 30, 0, 0, resilience.IndiaLocation)
+
+	if got := YearsToExpiry(now, expiry); math.Abs(got-1.0/365) > 1e-12 {
+		t.Errorf("expected one day, got %.8f years", got)
+	}
+	if got := YearsToExpiry(now.Add(48*time.Hour), expiry); got != 0 {
+		t.Errorf("expected zero after expiry, got %v", got)
+	}
+}
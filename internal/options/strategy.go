@@ -0,0 +1,352 @@
+package options
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+// StrategyKind names a multi-leg option strategy.
+type StrategyKind string
+
+const (
+	Straddle       StrategyKind = "straddle"
+	Strangle       StrategyKind = "strangle"
+	BullCallSpread StrategyKind = "bull-call-spread"
+	BearCallSpread StrategyKind = "bear-call-spread"
+	BullPutSpread  StrategyKind = "bull-put-spread"
+	BearPutSpread  StrategyKind = "bear-put-spread"
+	IronCondor     StrategyKind = "iron-condor"
+	Butterfly      StrategyKind = "butterfly"
+	CalendarSpread StrategyKind = "calendar-spread"
+	RatioSpread    StrategyKind = "ratio-spread"
+)
+
+// StrategyParams selects the legs of a strategy. Distances are counted in
+// strikes listed on the chain, not in points.
+type StrategyParams struct {
+	Kind StrategyKind
+	// Strike is the centre strike; zero picks the strike nearest the spot.
+	Strike float64
+	// Width is the distance from the centre to the inner legs (default 1).
+	Width int
+	// Wing is the distance from the short to the long legs of an iron
+	// condor (default 1).
+	Wing int
+	// Ratio is the number of short legs per long leg of a ratio spread
+	// (default 2).
+	Ratio int
+	// Type is the option type for butterflies, calendars and ratio
+	// spreads (default Call).
+	Type Kind
+	// Short sells the structure instead of buying it.
+	Short   bool
+	Lots    int
+	LotSize int
+}
+
+// LegType returns the models.OptionLeg type for k: CALL or PUT.
+func (k Kind) LegType() string {
+	if k == Put {
+		return "PUT"
+	}
+	return "CALL"
+}
+
+// BuildStrategy constructs a strategy's legs from live option chains,
+// pricing each leg at its LTP. far is only used by calendar spreads, whose
+// long leg is bought in the later expiry.
+func BuildStrategy(p StrategyParams, near, far *models.OptionChain) (*models.OptionStrategy, error) {
+	if near == nil || len(near.Strikes) == 0 {
+		return nil, fmt.Errorf("option chain is empty")
+	}
+	if p.Width <= 0 {
+		p.Width = 1
+	}
+	if p.Wing <= 0 {
+		p.Wing = 1
+	}
+	if p.Ratio <= 0 {
+		p.Ratio = 2
+	}
+	if p.Lots <= 0 {
+		p.Lots = 1
+	}
+	if p.LotSize <= 0 {
+		p.LotSize = 1
+	}
+	if p.Type == "" {
+		p.Type = Call
+	}
+
+	b := &legBuilder{chain: near, strikes: sortedStrikes(near), units: p.Lots * p.LotSize}
+	c, err := b.centre(p.Strike)
+	if err != nil {
+		return nil, err
+	}
+	w, wing := p.Width, p.Wing
+
+	switch p.Kind {
+	case Straddle:
+		b.add(c, Call, models.OrderSideBuy, 1)
+		b.add(c, Put, models.OrderSideBuy, 1)
+	case Strangle:
+		b.add(c+w, Call, models.OrderSideBuy, 1)
+		b.add(c-w, Put, models.OrderSideBuy, 1)
+	case BullCallSpread:
+		b.add(c, Call, models.OrderSideBuy, 1)
+		b.add(c+w, Call, models.OrderSideSell, 1)
+	case BearCallSpread:
+		b.add(c, Call, models.OrderSideSell, 1)
+		b.add(c+w, Call, models.OrderSideBuy, 1)
+	case BullPutSpread:
+		b.add(c, Put, models.OrderSideSell, 1)
+		b.add(c-w, Put, models.OrderSideBuy, 1)
+	case BearPutSpread:
+		b.add(c, Put, models.OrderSideBuy, 1)
+		b.add(c-w, Put, models.OrderSideSell, 1)
+	case IronCondor:
+		b.add(c-w-wing, Put, models.OrderSideBuy, 1)
+		b.add(c-w, Put, models.OrderSideSell, 1)
+		b.add(c+w, Call, models.OrderSideSell, 1)
+		b.add(c+w+wing, Call, models.OrderSideBuy, 1)
+	case Butterfly:
+		b.add(c-w, p.Type, models.OrderSideBuy, 1)
+		b.add(c, p.Type, models.OrderSideSell, 2)
+		b.add(c+w, p.Type, models.OrderSideBuy, 1)
+	case CalendarSpread:
+		if far == nil || len(far.Strikes) == 0 {
+			return nil, fmt.Errorf("calendar spread needs a far expiry chain")
+		}
+		if !far.Expiry.After(near.Expiry) {
+			return nil, fmt.Errorf("far expiry %s is not after near expiry %s",
+				far.Expiry.Format("2006-01-02"), near.Expiry.Format("2006-01-02"))
+		}
+		strike := b.strikes[c].Strike
+		b.add(c, p.Type, models.OrderSideSell, 1)
+		b.chain, b.strikes = far, sortedStrikes(far)
+		fc, err := b.centre(strike)
+		if err != nil {
+			return nil, fmt.Errorf("far expiry: %w", err)
+		}
+		b.add(fc, p.Type, models.OrderSideBuy, 1)
+	case RatioSpread:
+		step := w
+		if p.Type == Put {
+			step = -w
+		}
+		b.add(c, p.Type, models.OrderSideBuy, 1)
+		b.add(c+step, p.Type, models.OrderSideSell, p.Ratio)
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", p.Kind)
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if p.Short {
+		for i := range b.legs {
+			if b.legs[i].Side == models.OrderSideBuy {
+				b.legs[i].Side = models.OrderSideSell
+			} else {
+				b.legs[i].Side = models.OrderSideBuy
+			}
+		}
+	}
+
+	return &models.OptionStrategy{
+		Name:    string(p.Kind),
+		Symbol:  near.Symbol,
+		Spot:    near.SpotPrice,
+		Legs:    b.legs,
+		LotSize: p.LotSize,
+	}, nil
+}
+
+// legBuilder accumulates legs, keeping the first error.
+type legBuilder struct {
+	chain   *models.OptionChain
+	strikes []models.OptionStrike
+	units   int
+	legs    []models.OptionLeg
+	err     error
+}
+
+func sortedStrikes(chain *models.OptionChain) []models.OptionStrike {
+	strikes := append([]models.OptionStrike(nil), chain.Strikes...)
+	sort.Slice(strikes, func(i, j int) bool { return strikes[i].Strike < strikes[j].Strike })
+	return strikes
+}
+
+// centre returns the index of strike, or of the strike nearest the spot when
+// strike is zero.
+func (b *legBuilder) centre(strike float64) (int, error) {
+	if strike > 0 {
+		for i, s := range b.strikes {
+			if s.Strike == strike {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("strike %.2f not listed for %s", strike, b.chain.Symbol)
+	}
+
+	best := 0
+	for i, s := range b.strikes {
+		if math.Abs(s.Strike-b.chain.SpotPrice) < math.Abs(b.strikes[best].Strike-b.chain.SpotPrice) {
+			best = i
+		}
+	}
+	return best, nil
+}
+
+func (b *legBuilder) add(i int, kind Kind, side models.OrderSide, ratio int) {
+	if b.err != nil {
+		return
+	}
+	if i < 0 || i >= len(b.strikes) {
+		b.err = fmt.Errorf("strike is outside the listed %s chain", b.chain.Symbol)
+		return
+	}
+
+	strike := b.strikes[i]
+	data := strike.Call
+	if kind == Put {
+		data = strike.Put
+	}
+	if data == nil || data.LTP <= 0 {
+		b.err = fmt.Errorf("no quote for %s %.2f %s", b.chain.Symbol, strike.Strike, kind)
+		return
+	}
+
+	b.legs = append(b.legs, models.OptionLeg{
+		Symbol:   data.Symbol,
+		Strike:   strike.Strike,
+		Type:     kind.LegType(),
+		Expiry:   b.chain.Expiry,
+		Side:     side,
+		Quantity: ratio * b.units,
+		Premium:  data.LTP,
+		IV:       data.IV,
+	})
+}
+
+// StrategyExpiry returns when the strategy's first leg expires; payoffs are
+// evaluated at this moment.
+func StrategyExpiry(s *models.OptionStrategy) time.Time {
+	var first time.Time
+	for _, leg := range s.Legs {
+		if first.IsZero() || leg.Expiry.Before(first) {
+			first = leg.Expiry
+		}
+	}
+	return ExpiryClose(first)
+}
+
+// StrategyPnL returns the strategy's profit or loss if the underlying is at
+// price at time at, with every leg at its entry IV.
+func StrategyPnL(s *models.OptionStrategy, price float64, at time.Time, rate float64) float64 {
+	return ScenarioPnL(s, Scenario{Price: price, At: at}, rate)
+}
+
+// AnalyzeStrategy fills the strategy's net premium, maximum profit and
+// loss, breakevens and probability of profit at the first expiry. The
+// probability assumes a lognormal underlying at the legs' average IV.
+func AnalyzeStrategy(s *models.OptionStrategy, rate float64, now time.Time) {
+	s.NetPremium = 0
+	maxStrike := s.Spot
+	var ivSum float64
+	var ivCount int
+	for _, leg := range s.Legs {
+		if leg.Type == FutureLeg {
+			continue
+		}
+		amount := leg.Premium * float64(leg.Quantity)
+		if leg.Side == models.OrderSideSell {
+			s.NetPremium += amount
+		} else {
+			s.NetPremium -= amount
+		}
+		maxStrike = math.Max(maxStrike, leg.Strike)
+		if leg.IV > 0 {
+			ivSum += leg.IV
+			ivCount++
+		}
+	}
+
+	at := StrategyExpiry(s)
+	pnl := func(price float64) float64 { return StrategyPnL(s, price, at, rate) }
+
+	// Expiry payoffs are piecewise linear with kinks at the strikes, so the
+	// strikes plus a grid find the extremes exactly; the grid covers
+	// calendars whose far legs still carry time value.
+	hi := 2 * maxStrike
+	prices := []float64{0, hi, 2 * hi, 4 * hi}
+	for _, leg := range s.Legs {
+		prices = append(prices, leg.Strike)
+	}
+	const steps = 400
+	for i := 1; i < steps; i++ {
+		prices = append(prices, hi*float64(i)/steps)
+	}
+	sort.Float64s(prices)
+
+	values := make([]float64, len(prices))
+	s.MaxProfit, s.MaxLoss = math.Inf(-1), 0
+	for i, price := range prices {
+		values[i] = pnl(price)
+		s.MaxProfit = math.Max(s.MaxProfit, values[i])
+		s.MaxLoss = math.Max(s.MaxLoss, -values[i])
+	}
+
+	last := len(prices) - 1
+	slope := values[last] - values[last-1]
+	s.UnlimitedProfit = slope > 0.01
+	s.UnlimitedLoss = slope < -0.01
+
+	s.Breakevens = nil
+	for i := 0; i < last; i++ {
+		v1, v2 := values[i], values[i+1]
+		if (v1 < 0) == (v2 < 0) || v1 == v2 {
+			continue
+		}
+		x := prices[i] + (prices[i+1]-prices[i])*(-v1)/(v2-v1)
+		s.Breakevens = append(s.Breakevens, math.Round(x*100)/100)
+	}
+
+	s.ProbabilityOfProfit = 0
+	t := YearsToExpiry(now, at)
+	if ivCount == 0 || t <= 0 || s.Spot <= 0 {
+		return
+	}
+	vol := ivSum / float64(ivCount) / 100
+	cdf := func(x float64) float64 {
+		if x <= 0 {
+			return 0
+		}
+		return normCDF((math.Log(x/s.Spot) - (rate-vol*vol/2)*t) / (vol * math.Sqrt(t)))
+	}
+
+	// Sum the probability of each region between breakevens that profits
+	bounds := append([]float64{0}, s.Breakevens...)
+	for i, lo := range bounds {
+		var mid, upper float64
+		if i+1 < len(bounds) {
+			upper = bounds[i+1]
+			mid = (lo + upper) / 2
+		} else {
+			upper = math.Inf(1)
+			mid = math.Max(lo, s.Spot)*1.5 + 1
+		}
+		if pnl(mid) <= 0 {
+			continue
+		}
+		p := 1.0
+		if !math.IsInf(upper, 1) {
+			p = cdf(upper)
+		}
+		s.ProbabilityOfProfit += (p - cdf(lo)) * 100
+	}
+}
@@ -0,0 +1,93 @@
+package options
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+func testChain(now time.Time) *models.OptionChain {
+	chain := &models.OptionChain{Symbol: "NIFTY", SpotPrice: 19510, Expiry: now.AddDate(0, 0, 7)}
+	for strike := 19300.0; strike <= 19700; strike += 100 {
+		in := Inputs{Underlying: chain.SpotPrice, Strike: strike, T: YearsToExpiry(now, chain.Expiry), Rate: DefaultRate, Vol: 0.13}
+		in.Kind = Call
+		call := &models.OptionData{Symbol: "C", LTP: math.Round(Price(in)*20) / 20}
+		in.Kind = Put
+		put := &models.OptionData{Symbol: "P", LTP: math.Round(Price(in)*20) / 20}
+		chain.Strikes = append(chain.Strikes, models.OptionStrike{Strike: strike, Call: call, Put: put})
+	}
+	ApplyChain(chain, now, DefaultRate)
+	return chain
+}
+
+func TestBuildStrategy_BullCallSpread(t *testing.T) {
+	now := time.Now()
+	chain := testChain(now)
+
+	s, err := BuildStrategy(StrategyParams{Kind: BullCallSpread, Width: 2, LotSize: 50}, chain, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	AnalyzeStrategy(s, DefaultRate, now)
+
+	if len(s.Legs) != 2 || s.Legs[0].Strike != 19500 || s.Legs[1].Strike != 19700 {
+		t.Fatalf("unexpected legs: %+v", s.Legs)
+	}
+	debit := (s.Legs[0].Premium - s.Legs[1].Premium) * 50
+	if math.Abs(s.NetPremium+debit) > 1e-6 {
+		t.Errorf("net premium: expected %.2f, got %.2f", -debit, s.NetPremium)
+	}
+	if math.Abs(s.MaxLoss-debit) > 1e-6 {
+		t.Errorf("max loss: expected %.2f, got %.2f", debit, s.MaxLoss)
+	}
+	if math.Abs(s.MaxProfit-(200*50-debit)) > 1e-6 {
+		t.Errorf("max profit: expected %.2f, got %.2f", 200*50-debit, s.MaxProfit)
+	}
+	if s.UnlimitedProfit || s.UnlimitedLoss {
+		t.Error("a vertical spread is bounded")
+	}
+	if len(s.Breakevens) != 1 || math.Abs(s.Breakevens[0]-(19500+debit/50)) > 0.01 {
+		t.Errorf("breakevens: expected [%.2f], got %v", 19500+debit/50, s.Breakevens)
+	}
+	if s.ProbabilityOfProfit <= 0 || s.ProbabilityOfProfit >= 100 {
+		t.Errorf("probability of profit out of range: %.2f", s.ProbabilityOfProfit)
+	}
+}
+
+func TestBuildStrategy_ShortStraddle(t *testing.T) {
+	now := time.Now()
+	chain := testChain(now)
+
+	s, err := BuildStrategy(StrategyParams{Kind: Straddle, Short: true, Lots: 2, LotSize: 50}, chain, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	AnalyzeStrategy(s, DefaultRate, now)
+
+	for _, leg := range s.Legs {
+		if leg.Side != models.OrderSideSell || leg.Strike != 19500 || leg.Quantity != 100 {
+			t.Errorf("unexpected leg: %+v", leg)
+		}
+	}
+	if s.NetPremium <= 0 {
+		t.Errorf("short straddle should collect a credit, got %.2f", s.NetPremium)
+	}
+	if !s.UnlimitedLoss || s.UnlimitedProfit {
+		t.Error("short straddle has unlimited loss on the upside")
+	}
+	if math.Abs(s.MaxProfit-s.NetPremium) > 1e-6 {
+		t.Errorf("max profit: expected the credit %.2f, got %.2f", s.NetPremium, s.MaxProfit)
+	}
+	if len(s.Breakevens) != 2 {
+		t.Errorf("expected two breakevens, got %v", s.Breakevens)
+	}
+}
+
+func TestBuildStrategy_StrikeOutsideChain(t *testing.T) {
+	chain := testChain(time.Now())
+	if _, err := BuildStrategy(StrategyParams{Kind: IronCondor, Width: 2, Wing: 2}, chain, nil); err == nil {
+		t.Error("expected an error for wings beyond the listed strikes")
+	}
+}
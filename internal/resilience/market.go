@@ -55,6 +55,15 @@ func (m *MarketHoursManager) IsHoliday(date time.Time) bool {
 	return m.holidays[key]
 }
 
+// IsTradingDay reports whether the exchange holds a session on date.
+func (m *MarketHoursManager) IsTradingDay(date time.Time) bool {
+	date = date.In(IndiaLocation)
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	return !m.IsHoliday(date)
+}
+
 // GetMarketStatus returns the current detailed market status.
 func (m *MarketHoursManager) GetMarketStatus() models.MarketStatus {
 	return m.GetMarketStatusAt(time.Now())
@@ -4,7 +4,6 @@ package security
 import (
 	"context"
 	"crypto/rand"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -30,6 +29,9 @@ const (
 	AuditOrderCancelled AuditEventType = "ORDER_CANCELLED"
 	AuditOrderExecuted  AuditEventType = "ORDER_EXECUTED"
 	AuditOrderRejected  AuditEventType = "ORDER_REJECTED"
+	AuditGTTPlaced      AuditEventType = "GTT_PLACED"
+	AuditGTTModified    AuditEventType = "GTT_MODIFIED"
+	AuditGTTCancelled   AuditEventType = "GTT_CANCELLED"
 
 	// Position events
 	AuditPositionOpened AuditEventType = "POSITION_OPENED"
@@ -65,14 +67,30 @@ type AuditEvent struct {
 	IPAddress   string                 `json:"ip_address,omitempty"`
 	SessionID   string                 `json:"session_id,omitempty"`
 	RequestID   string                 `json:"request_id,omitempty"`
+	// Seq and PrevHash chain each entry to the one before it; Hash seals
+	// the entry and is always written last.
+	Seq      uint64 `json:"seq,omitempty"`
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
 }
 
-// AuditLogger handles audit logging for trading actions.
+// AuditLogger handles audit logging for trading actions. Entries are
+// hash-chained so that editing, inserting or deleting one is detected by
+// VerifyAuditLog.
+//
+// Several processes may log to the same directory: each append holds an
+// exclusive lock on the directory's lock file and first re-reads the chain
+// tail if another writer has appended or rotated since.
 type AuditLogger struct {
 	writer    *lumberjack.Logger
+	dir       string
 	mu        sync.Mutex
 	sessionID string
 	userID    string
+	seq       uint64
+	lastHash  string
+	// tail is the live file as this logger left it after its last append.
+	tail os.FileInfo
 }
 
 // AuditConfig holds audit logger configuration.
@@ -103,7 +121,7 @@ func NewAuditLogger(cfg AuditConfig) (*AuditLogger, error) {
 		return nil, fmt.Errorf("creating audit directory: %w", err)
 	}
 
-	logPath := filepath.Join(cfg.LogDir, "audit.log")
+	logPath := filepath.Join(cfg.LogDir, auditLogName)
 
 	writer := &lumberjack.Logger{
 		Filename:   logPath,
@@ -113,10 +131,22 @@ func NewAuditLogger(cfg AuditConfig) (*AuditLogger, error) {
 		Compress:   cfg.Compress,
 	}
 
-	return &AuditLogger{
+	al := &AuditLogger{
 		writer:    writer,
+		dir:       cfg.LogDir,
 		sessionID: generateSessionID(),
-	}, nil
+	}
+
+	// Continue the chain from the last sealed entry
+	unlock, err := lockAuditDir(cfg.LogDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := al.syncChain(); err != nil {
+		return nil, err
+	}
+	return al, nil
 }
 
 // SetUserID sets the user ID for audit events.
@@ -138,22 +168,67 @@ func (al *AuditLogger) Log(ctx context.Context, event AuditEvent) error {
 		event.UserID = al.userID
 	}
 
-	// Get request ID from context if available
+	// Get request ID and client address from context if available
 	if reqID, ok := ctx.Value("request_id").(string); ok {
 		event.RequestID = reqID
 	}
+	if ip, ok := ctx.Value("ip_address").(string); ok && event.IPAddress == "" {
+		event.IPAddress = ip
+	}
+
+	unlock, err := lockAuditDir(al.dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := al.syncChain(); err != nil {
+		return err
+	}
+
+	event.Seq = al.seq + 1
+	event.PrevHash = al.lastHash
 
-	// Serialize to JSON
-	data, err := json.Marshal(event)
+	// Serialize and seal
+	data, hash, err := sealAuditEvent(event)
 	if err != nil {
 		return fmt.Errorf("serializing audit event: %w", err)
 	}
 
-	// Write with newline
-	if _, err := al.writer.Write(append(data, '\n')); err != nil {
+	// Write with newline, then release the file so a rotation by another
+	// writer is followed on the next append
+	_, err = al.writer.Write(append(data, '\n'))
+	if cerr := al.writer.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		al.tail = nil
 		return fmt.Errorf("writing audit event: %w", err)
 	}
 
+	al.seq = event.Seq
+	al.lastHash = hash
+	al.tail, _ = os.Stat(al.writer.Filename)
+	return nil
+}
+
+// syncChain reloads the chain tail unless the live file is exactly as this
+// logger left it. Callers hold the directory lock.
+func (al *AuditLogger) syncChain() error {
+	if al.tail != nil {
+		if fi, err := os.Stat(al.writer.Filename); err == nil && os.SameFile(fi, al.tail) && fi.Size() == al.tail.Size() {
+			return nil
+		}
+	}
+
+	last, err := lastAuditEntry(al.dir)
+	if err != nil {
+		return fmt.Errorf("reading audit chain: %w", err)
+	}
+	al.seq, al.lastHash = 0, ""
+	if last != nil {
+		al.seq, al.lastHash = last.Seq, last.Hash
+	}
+	al.tail, _ = os.Stat(al.writer.Filename)
 	return nil
 }
 
@@ -0,0 +1,282 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+)
+
+const (
+	auditLogName  = "audit.log"
+	auditLockName = "audit.lock"
+)
+
+// lockAuditDir takes an exclusive lock shared by every process logging to
+// dir and returns the function that releases it.
+func lockAuditDir(dir string) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(dir, auditLockName), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking audit log: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// A sealed line ends with ,"hash":"<hex SHA-256 of the line before it>"}.
+const (
+	hashFieldPrefix = `,"hash":"`
+	hashFieldSuffix = `"}`
+	sealLen         = len(hashFieldPrefix) + sha256.Size*2 + len(hashFieldSuffix)
+)
+
+// sealAuditEvent serializes event and appends the hash of those exact
+// bytes, so verification never depends on re-encoding the JSON.
+func sealAuditEvent(event AuditEvent) ([]byte, string, error) {
+	event.Hash = ""
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	line := make([]byte, 0, len(body)-1+sealLen)
+	line = append(line, body[:len(body)-1]...)
+	line = append(line, hashFieldPrefix...)
+	line = append(line, hash...)
+	line = append(line, hashFieldSuffix...)
+	return line, hash, nil
+}
+
+// unsealAuditLine splits a sealed line into the bytes that were hashed and
+// the hash recorded for them. ok is false for unsealed lines.
+func unsealAuditLine(line []byte) (body []byte, hash string, ok bool) {
+	if len(line) < sealLen+2 {
+		return nil, "", false
+	}
+	seal := line[len(line)-sealLen:]
+	if !bytes.HasPrefix(seal, []byte(hashFieldPrefix)) || !bytes.HasSuffix(seal, []byte(hashFieldSuffix)) {
+		return nil, "", false
+	}
+
+	hash = string(seal[len(hashFieldPrefix) : len(hashFieldPrefix)+sha256.Size*2])
+	body = make([]byte, 0, len(line)-sealLen+1)
+	body = append(body, line[:len(line)-sealLen]...)
+	body = append(body, '}')
+	return body, hash, true
+}
+
+// AuditProblem is an entry that failed verification.
+type AuditProblem struct {
+	File   string
+	Line   int
+	Seq    uint64
+	Reason string
+}
+
+// AuditVerifyResult summarizes a walk of the audit chain.
+type AuditVerifyResult struct {
+	Files   []string
+	Entries int
+	// Legacy counts unsealed entries written before chaining began.
+	Legacy   int
+	FirstSeq uint64
+	LastSeq  uint64
+	LastHash string
+	// Anchored is false when the oldest entry links to one that rotation
+	// has since deleted; the chain is only verified from there on.
+	Anchored bool
+	Problems []AuditProblem
+}
+
+// Valid reports whether the chain verified without problems.
+func (r *AuditVerifyResult) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+// VerifyAuditLog checks every entry in the audit directory, oldest rotated
+// file first: each must hash to its recorded hash and link to the entry
+// before it. Lines removed from the end of the newest file cannot be
+// detected this way; compare LastSeq with an externally noted value.
+func VerifyAuditLog(dir string) (*AuditVerifyResult, error) {
+	files, err := auditFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AuditVerifyResult{Files: files, Anchored: true}
+	started := false
+	var prevHash string
+	var prevSeq uint64
+
+	for _, file := range files {
+		err := scanAuditFile(file, func(n int, line []byte) {
+			body, hash, ok := unsealAuditLine(line)
+			if !ok {
+				if started {
+					result.Problems = append(result.Problems, AuditProblem{File: file, Line: n, Reason: "entry is not sealed"})
+				} else {
+					result.Legacy++
+				}
+				return
+			}
+
+			var event AuditEvent
+			if err := json.Unmarshal(body, &event); err != nil {
+				result.Problems = append(result.Problems, AuditProblem{File: file, Line: n, Reason: "malformed entry: " + err.Error()})
+				return
+			}
+			result.Entries++
+
+			sum := sha256.Sum256(body)
+			switch {
+			case hex.EncodeToString(sum[:]) != hash:
+				result.Problems = append(result.Problems, AuditProblem{File: file, Line: n, Seq: event.Seq, Reason: "hash mismatch: entry was modified"})
+			case !started:
+				result.FirstSeq = event.Seq
+				result.Anchored = event.PrevHash == "" && event.Seq == 1
+			case event.PrevHash != prevHash:
+				result.Problems = append(result.Problems, AuditProblem{File: file, Line: n, Seq: event.Seq,
+					Reason: fmt.Sprintf("chain broken after seq %d: entries inserted or removed", prevSeq)})
+			case event.Seq != prevSeq+1:
+				result.Problems = append(result.Problems, AuditProblem{File: file, Line: n, Seq: event.Seq,
+					Reason: fmt.Sprintf("sequence jumps from %d", prevSeq)})
+			}
+
+			// Carry on from the recorded hash so one bad entry is reported once
+			started = true
+			prevHash, prevSeq = hash, event.Seq
+			result.LastSeq, result.LastHash = event.Seq, hash
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// ReadAuditEvents returns the events in the audit directory that match,
+// oldest first. Unsealed and malformed lines are skipped.
+func ReadAuditEvents(dir string, match func(AuditEvent) bool) ([]AuditEvent, error) {
+	files, err := auditFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []AuditEvent
+	for _, file := range files {
+		err := scanAuditFile(file, func(_ int, line []byte) {
+			var event AuditEvent
+			if json.Unmarshal(line, &event) == nil && match(event) {
+				events = append(events, event)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// lastAuditEntry returns the newest sealed entry in dir, or nil if the
+// chain has not started.
+func lastAuditEntry(dir string) (*AuditEvent, error) {
+	files, err := auditFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		var last []byte
+		err := scanAuditFile(files[i], func(_ int, line []byte) {
+			if _, _, ok := unsealAuditLine(line); ok {
+				last = append(last[:0], line...)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if last == nil {
+			continue
+		}
+
+		var event AuditEvent
+		if err := json.Unmarshal(last, &event); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", files[i], err)
+		}
+		return &event, nil
+	}
+	return nil, nil
+}
+
+// auditFiles lists the audit log's rotated backups oldest first, followed
+// by the live file. Backups are named audit-<timestamp>.log[.gz], so name
+// order is time order.
+func auditFiles(dir string) ([]string, error) {
+	var backups []string
+	for _, pattern := range []string{"audit-*.log", "audit-*.log.gz"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, matches...)
+	}
+	sort.Strings(backups)
+
+	live := filepath.Join(dir, auditLogName)
+	if _, err := os.Stat(live); err == nil {
+		backups = append(backups, live)
+	}
+	return backups, nil
+}
+
+// scanAuditFile calls fn with each non-empty line of file and its 1-based
+// line number, decompressing gzipped backups.
+func scanAuditFile(file string, fn func(n int, line []byte)) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if filepath.Ext(file) == ".gz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", file, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			fn(n, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package security
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeAuditChain logs n order events to a fresh audit directory and
+// returns the directory with the lines written.
+func writeAuditChain(t *testing.T, n int) (string, [][]byte) {
+	t.Helper()
+	dir := t.TempDir()
+	al := newTestAuditLogger(t, dir)
+	for i := 0; i < n; i++ {
+		if err := al.LogOrderPlaced(context.Background(), "ord", "INFY", "BUY", i+1, 1500, "LIMIT", "CNC", true, ""); err != nil {
+			t.Fatalf("Log() error = %v", err)
+		}
+	}
+	return dir, readAuditLines(t, filepath.Join(dir, auditLogName))
+}
+
+func newTestAuditLogger(t *testing.T, dir string) *AuditLogger {
+	t.Helper()
+	al, err := NewAuditLogger(AuditConfig{LogDir: dir, MaxSize: 10})
+	if err != nil {
+		t.Fatalf("NewAuditLogger() error = %v", err)
+	}
+	t.Cleanup(func() { al.Close() })
+	return al
+}
+
+func readAuditLines(t *testing.T, file string) [][]byte {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+}
+
+func writeAuditLines(t *testing.T, file string, lines [][]byte) {
+	t.Helper()
+	data := append(bytes.Join(lines, []byte("\n")), '\n')
+	if strings.HasSuffix(file, ".gz") {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(data)
+		gz.Close()
+		data = buf.Bytes()
+	}
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSealAuditEvent(t *testing.T) {
+	event := AuditEvent{EventType: AuditOrderPlaced, Symbol: "INFY", OrderID: "1", Seq: 7, PrevHash: "abc", Hash: "stale"}
+	line, hash, err := sealAuditEvent(event)
+	if err != nil {
+		t.Fatalf("sealAuditEvent() error = %v", err)
+	}
+
+	body, gotHash, ok := unsealAuditLine(line)
+	if !ok || gotHash != hash {
+		t.Fatalf("unsealAuditLine() = %q, %v; want %q, true", gotHash, ok, hash)
+	}
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != hash {
+		t.Errorf("recorded hash does not match the sealed body")
+	}
+
+	var decoded AuditEvent
+	if err := json.Unmarshal(line, &decoded); err != nil {
+		t.Fatalf("sealed line is not JSON: %v", err)
+	}
+	if decoded.Hash != hash || decoded.Seq != 7 || decoded.PrevHash != "abc" || decoded.Symbol != "INFY" {
+		t.Errorf("decoded %+v", decoded)
+	}
+}
+
+func TestUnsealAuditLine(t *testing.T) {
+	sealed, _, err := sealAuditEvent(AuditEvent{EventType: AuditLogin, Seq: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		line string
+		ok   bool
+	}{
+		{"sealed", string(sealed), true},
+		{"legacy entry", `{"timestamp":"2024-03-04T10:00:00Z","event_type":"LOGIN","success":true}`, false},
+		{"truncated seal", string(sealed[:len(sealed)-3]), false},
+		{"hash too short", `{"event_type":"LOGIN","hash":"abc"}`, false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, ok := unsealAuditLine([]byte(tt.line)); ok != tt.ok {
+				t.Errorf("unsealAuditLine() ok = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	legacy := []byte(`{"timestamp":"2024-03-04T10:00:00Z","event_type":"LOGIN","success":true}`)
+	forged, _, err := sealAuditEvent(AuditEvent{EventType: AuditOrderPlaced, Symbol: "TCS", Seq: 3, PrevHash: "forged"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// tamper rewrites the five sealed lines into the files to verify,
+		// keyed by name within the audit directory.
+		tamper   func(lines [][]byte) map[string][][]byte
+		problems int
+		reason   string
+		legacy   int
+		firstSeq uint64
+		anchored bool
+	}{
+		{
+			name:     "intact",
+			tamper:   func(l [][]byte) map[string][][]byte { return map[string][][]byte{auditLogName: l} },
+			firstSeq: 1,
+			anchored: true,
+		},
+		{
+			name: "edited entry",
+			tamper: func(l [][]byte) map[string][][]byte {
+				l[2] = bytes.Replace(l[2], []byte(`"INFY"`), []byte(`"SBIN"`), 1)
+				return map[string][][]byte{auditLogName: l}
+			},
+			problems: 1,
+			reason:   "hash mismatch",
+			firstSeq: 1,
+			anchored: true,
+		},
+		{
+			name: "inserted entry",
+			tamper: func(l [][]byte) map[string][][]byte {
+				l = append(l[:3], append([][]byte{forged}, l[3:]...)...)
+				return map[string][][]byte{auditLogName: l}
+			},
+			problems: 2,
+			reason:   "chain broken",
+			firstSeq: 1,
+			anchored: true,
+		},
+		{
+			name: "deleted entry",
+			tamper: func(l [][]byte) map[string][][]byte {
+				return map[string][][]byte{auditLogName: append(l[:2], l[3:]...)}
+			},
+			problems: 1,
+			reason:   "chain broken",
+			firstSeq: 1,
+			anchored: true,
+		},
+		{
+			name: "rotated",
+			tamper: func(l [][]byte) map[string][][]byte {
+				return map[string][][]byte{
+					"audit-2024-03-04T10-00-00.000.log":    l[:1],
+					"audit-2024-03-05T10-00-00.000.log.gz": l[1:3],
+					auditLogName:                           l[3:],
+				}
+			},
+			firstSeq: 1,
+			anchored: true,
+		},
+		{
+			name: "oldest backup deleted by rotation",
+			tamper: func(l [][]byte) map[string][][]byte {
+				return map[string][][]byte{
+					"audit-2024-03-05T10-00-00.000.log.gz": l[2:3],
+					auditLogName:                           l[3:],
+				}
+			},
+			firstSeq: 3,
+			anchored: false,
+		},
+		{
+			name: "legacy lines before the chain",
+			tamper: func(l [][]byte) map[string][][]byte {
+				return map[string][][]byte{auditLogName: append([][]byte{legacy, legacy}, l...)}
+			},
+			legacy:   2,
+			firstSeq: 1,
+			anchored: true,
+		},
+		{
+			name: "unsealed line inside the chain",
+			tamper: func(l [][]byte) map[string][][]byte {
+				l = append(l[:2], append([][]byte{legacy}, l[2:]...)...)
+				return map[string][][]byte{auditLogName: l}
+			},
+			problems: 1,
+			reason:   "not sealed",
+			firstSeq: 1,
+			anchored: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, lines := writeAuditChain(t, 5)
+			dir := t.TempDir()
+			for name, l := range tt.tamper(lines) {
+				writeAuditLines(t, filepath.Join(dir, name), l)
+			}
+
+			result, err := VerifyAuditLog(dir)
+			if err != nil {
+				t.Fatalf("VerifyAuditLog() error = %v", err)
+			}
+			if len(result.Problems) != tt.problems {
+				t.Fatalf("got %d problems, want %d: %+v", len(result.Problems), tt.problems, result.Problems)
+			}
+			if tt.problems > 0 && !strings.Contains(result.Problems[0].Reason, tt.reason) {
+				t.Errorf("problem %q, want %q", result.Problems[0].Reason, tt.reason)
+			}
+			if result.Legacy != tt.legacy {
+				t.Errorf("Legacy = %d, want %d", result.Legacy, tt.legacy)
+			}
+			if result.FirstSeq != tt.firstSeq || result.Anchored != tt.anchored {
+				t.Errorf("FirstSeq = %d, Anchored = %v; want %d, %v", result.FirstSeq, result.Anchored, tt.firstSeq, tt.anchored)
+			}
+		})
+	}
+}
+
+func TestAuditLoggerResumesChain(t *testing.T) {
+	dir, _ := writeAuditChain(t, 3)
+
+	last, err := lastAuditEntry(dir)
+	if err != nil || last == nil || last.Seq != 3 {
+		t.Fatalf("lastAuditEntry() = %+v, %v; want seq 3", last, err)
+	}
+
+	// Unsealed lines after the last entry are skipped
+	f, err := os.OpenFile(filepath.Join(dir, auditLogName), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"event_type":"LOGIN"}` + "\n")
+	f.Close()
+
+	al := newTestAuditLogger(t, dir)
+	if err := al.LogLogin(context.Background(), "AB1234", true, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := VerifyAuditLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The stray line is reported, but the new entry links to seq 3
+	if result.LastSeq != 4 || len(result.Problems) != 1 || !strings.Contains(result.Problems[0].Reason, "not sealed") {
+		t.Errorf("LastSeq = %d, problems = %+v; want seq 4 and the stray line only", result.LastSeq, result.Problems)
+	}
+}
+
+func TestAuditLoggerSharedDirectory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	a := newTestAuditLogger(t, dir)
+	b := newTestAuditLogger(t, dir)
+
+	var wg sync.WaitGroup
+	for _, al := range []*AuditLogger{a, b} {
+		wg.Add(1)
+		go func(al *AuditLogger) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if err := al.LogLogin(ctx, "AB1234", true, ""); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(al)
+	}
+	wg.Wait()
+
+	// Another writer rotates the live file away
+	if err := os.Rename(filepath.Join(dir, auditLogName), filepath.Join(dir, "audit-2024-03-04T10-00-00.000.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.LogLogout(ctx, "AB1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.LogLogout(ctx, "AB1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := VerifyAuditLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid() || result.Entries != 42 || result.LastSeq != 42 {
+		t.Errorf("Entries = %d, LastSeq = %d, problems = %+v; want 42 chained entries", result.Entries, result.LastSeq, result.Problems)
+	}
+}
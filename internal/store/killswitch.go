@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"zerodha-trader/internal/models"
+)
+
+// initKillSwitchSchema creates the single-row table holding the kill switch.
+func (s *SQLiteStore) initKillSwitchSchema() error {
+	schema := `
+	-- Trading kill switch (at most one row)
+	CREATE TABLE IF NOT EXISTS kill_switch (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		engaged INTEGER NOT NULL DEFAULT 0,
+		reason TEXT,
+		updated_at DATETIME NOT NULL
+	);
+	`
+
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// ============================================================================
+// Kill Switch Methods
+// ============================================================================
+
+// GetKillSwitch returns the kill switch state. A switch that has never been
+// set is disengaged.
+func (s *SQLiteStore) GetKillSwitch(ctx context.Context) (*models.KillSwitch, error) {
+	var ks models.KillSwitch
+	var reason sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT engaged, reason, updated_at FROM kill_switch WHERE id = 1
+	`).Scan(&ks.Engaged, &reason, &ks.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &models.KillSwitch{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kill switch: %w", err)
+	}
+	ks.Reason = reason.String
+	return &ks, nil
+}
+
+// SetKillSwitch persists the kill switch state.
+func (s *SQLiteStore) SetKillSwitch(ctx context.Context, ks *models.KillSwitch) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO kill_switch (id, engaged, reason, updated_at)
+		VALUES (1, ?, ?, ?)
+	`, ks.Engaged, ks.Reason, ks.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set kill switch: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+// initLLMSchema creates the tables backing the agents' LLM response cache
+// and usage accounting.
+func (s *SQLiteStore) initLLMSchema() error {
+	schema := `
+	-- Cached LLM responses
+	CREATE TABLE IF NOT EXISTS llm_cache (
+		cache_key TEXT PRIMARY KEY,
+		agent TEXT NOT NULL,
+		response TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	-- LLM token usage, latency and cost
+	CREATE TABLE IF NOT EXISTS llm_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL,
+		agent TEXT NOT NULL,
+		provider TEXT,
+		model TEXT,
+		prompt_tokens INTEGER NOT NULL DEFAULT 0,
+		completion_tokens INTEGER NOT NULL DEFAULT 0,
+		latency_ms INTEGER NOT NULL DEFAULT 0,
+		cost REAL NOT NULL DEFAULT 0,
+		cached INTEGER NOT NULL DEFAULT 0,
+		error TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_llm_cache_created ON llm_cache(created_at);
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_timestamp ON llm_usage(timestamp);
+	`
+
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// ============================================================================
+// LLM Cache & Usage Methods
+// ============================================================================
+
+// GetLLMResponse returns the cached response for key, if any.
+func (s *SQLiteStore) GetLLMResponse(ctx context.Context, key string) (string, bool, error) {
+	var response string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT response FROM llm_cache WHERE cache_key = ?
+	`, key).Scan(&response)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get cached LLM response: %w", err)
+	}
+	return response, true, nil
+}
+
+// SaveLLMResponse caches an agent's LLM response under key.
+func (s *SQLiteStore) SaveLLMResponse(ctx context.Context, key, agent, response string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO llm_cache (cache_key, agent, response, created_at)
+		VALUES (?, ?, ?, ?)
+	`, key, agent, response, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to cache LLM response: %w", err)
+	}
+	return nil
+}
+
+// PruneLLMCache deletes cached responses created before cutoff.
+func (s *SQLiteStore) PruneLLMCache(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM llm_cache WHERE created_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune LLM cache: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// LogLLMUsage records one LLM completion.
+func (s *SQLiteStore) LogLLMUsage(ctx context.Context, usage *models.LLMUsage) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO llm_usage (timestamp, agent, provider, model, prompt_tokens, completion_tokens, latency_ms, cost, cached, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, usage.Timestamp, usage.Agent, usage.Provider, usage.Model, usage.PromptTokens, usage.CompletionTokens,
+		usage.Latency.Milliseconds(), usage.Cost, usage.Cached, usage.Error)
+	if err != nil {
+		return fmt.Errorf("failed to log LLM usage: %w", err)
+	}
+	usage.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// GetLLMUsageSummary aggregates LLM usage per agent over dateRange.
+func (s *SQLiteStore) GetLLMUsageSummary(ctx context.Context, dateRange DateRange) ([]models.LLMUsageSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT agent,
+			COUNT(*),
+			COALESCE(SUM(cached), 0),
+			COALESCE(SUM(CASE WHEN error IS NOT NULL AND error != '' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(prompt_tokens), 0),
+			COALESCE(SUM(completion_tokens), 0),
+			COALESCE(AVG(CASE WHEN cached = 0 THEN latency_ms END), 0),
+			COALESCE(SUM(cost), 0)
+		FROM llm_usage
+		WHERE timestamp >= ? AND timestamp <= ?
+		GROUP BY agent
+		ORDER BY agent ASC
+	`, dateRange.Start, dateRange.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query LLM usage: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []models.LLMUsageSummary
+	for rows.Next() {
+		var sum models.LLMUsageSummary
+		var latencyMs float64
+		if err := rows.Scan(&sum.Agent, &sum.Calls, &sum.CachedCalls, &sum.Errors,
+			&sum.PromptTokens, &sum.CompletionTokens, &latencyMs, &sum.Cost); err != nil {
+			return nil, fmt.Errorf("failed to scan LLM usage: %w", err)
+		}
+		sum.AvgLatency = time.Duration(latencyMs * float64(time.Millisecond))
+		summaries = append(summaries, sum)
+	}
+
+	return summaries, rows.Err()
+}
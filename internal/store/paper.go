@@ -0,0 +1,714 @@
+// Package store provides data persistence implementations.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"syscall"
+
+	"zerodha-trader/internal/models"
+)
+
+// initPaperSchema creates the tables backing named paper trading accounts.
+func (s *SQLiteStore) initPaperSchema() error {
+	schema := `
+	-- Paper trading accounts
+	CREATE TABLE IF NOT EXISTS paper_accounts (
+		name TEXT PRIMARY KEY,
+		initial_balance REAL NOT NULL,
+		cash REAL NOT NULL,
+		realized_pnl REAL NOT NULL DEFAULT 0,
+		charges REAL NOT NULL DEFAULT 0,
+		order_counter INTEGER NOT NULL DEFAULT 0,
+		gtt_counter INTEGER NOT NULL DEFAULT 0,
+		session_close DATETIME,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		version INTEGER NOT NULL DEFAULT 0
+	);
+
+	-- Paper trading orders
+	CREATE TABLE IF NOT EXISTS paper_orders (
+		account TEXT NOT NULL,
+		id TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		exchange TEXT NOT NULL,
+		side TEXT NOT NULL,
+		order_type TEXT NOT NULL,
+		product TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		price REAL,
+		trigger_price REAL,
+		validity TEXT,
+		tag TEXT,
+		status TEXT NOT NULL,
+		status_message TEXT,
+		filled_qty INTEGER NOT NULL DEFAULT 0,
+		average_price REAL,
+		placed_at DATETIME NOT NULL,
+		PRIMARY KEY (account, id)
+	);
+
+	-- Paper trading positions
+	CREATE TABLE IF NOT EXISTS paper_positions (
+		account TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		exchange TEXT NOT NULL,
+		product TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		average_price REAL NOT NULL,
+		ltp REAL,
+		PRIMARY KEY (account, exchange, symbol, product)
+	);
+
+	-- Paper trading holdings
+	CREATE TABLE IF NOT EXISTS paper_holdings (
+		account TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		t1_quantity INTEGER NOT NULL DEFAULT 0,
+		average_price REAL NOT NULL,
+		ltp REAL,
+		PRIMARY KEY (account, symbol)
+	);
+
+	-- Paper trading cash ledger
+	CREATE TABLE IF NOT EXISTS paper_ledger (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		entry_type TEXT NOT NULL,
+		symbol TEXT,
+		order_id TEXT,
+		amount REAL NOT NULL,
+		balance REAL NOT NULL,
+		note TEXT
+	);
+
+	-- Paper trading account snapshots
+	CREATE TABLE IF NOT EXISTS paper_snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account TEXT NOT NULL,
+		label TEXT,
+		taken_at DATETIME NOT NULL,
+		cash REAL NOT NULL,
+		equity REAL NOT NULL,
+		realized_pnl REAL NOT NULL,
+		unrealized_pnl REAL NOT NULL,
+		charges REAL NOT NULL,
+		return_percent REAL NOT NULL,
+		open_positions INTEGER NOT NULL,
+		holdings INTEGER NOT NULL,
+		trades INTEGER NOT NULL
+	);
+
+	-- Paper trading GTTs
+	CREATE TABLE IF NOT EXISTS paper_gtts (
+		account TEXT NOT NULL,
+		id TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		exchange TEXT NOT NULL,
+		trigger_type TEXT NOT NULL,
+		trigger_price REAL,
+		last_price REAL,
+		legs TEXT NOT NULL,
+		status TEXT NOT NULL,
+		order_id TEXT,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (account, id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_paper_ledger_account ON paper_ledger(account, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_paper_snapshots_account ON paper_snapshots(account, taken_at);
+	`
+
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// GTTs were stored before accounts were named; they belong to the default account
+	if err := s.addColumnIfMissing("paper_gtts", "account", "TEXT NOT NULL DEFAULT '"+DefaultPaperAccount+"'"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfMissing("paper_accounts", "version", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return s.migratePaperGTTKey()
+}
+
+// tableColumns returns the columns of a table mapped to their position in the
+// primary key, 0 for columns outside it.
+func (s *SQLiteStore) tableColumns(table string) (map[string]int, error) {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]int)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", table, err)
+		}
+		columns[name] = pk
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", table, err)
+	}
+	return columns, nil
+}
+
+// addColumnIfMissing adds a column to a table created by an older schema.
+func (s *SQLiteStore) addColumnIfMissing(table, column, definition string) error {
+	columns, err := s.tableColumns(table)
+	if err != nil {
+		return err
+	}
+	if _, ok := columns[column]; ok {
+		return nil
+	}
+
+	if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// migratePaperGTTKey rebuilds paper_gtts tables keyed on id alone, where GTT
+// IDs of different accounts overwrite each other, with the (account, id) key.
+func (s *SQLiteStore) migratePaperGTTKey() error {
+	columns, err := s.tableColumns("paper_gtts")
+	if err != nil {
+		return err
+	}
+	if columns["account"] > 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const cols = "account, id, symbol, exchange, trigger_type, trigger_price, last_price, legs, status, order_id, created_at, updated_at"
+	stmts := []string{
+		`CREATE TABLE paper_gtts_new (
+			account TEXT NOT NULL,
+			id TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			exchange TEXT NOT NULL,
+			trigger_type TEXT NOT NULL,
+			trigger_price REAL,
+			last_price REAL,
+			legs TEXT NOT NULL,
+			status TEXT NOT NULL,
+			order_id TEXT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			PRIMARY KEY (account, id)
+		)`,
+		"INSERT INTO paper_gtts_new (" + cols + ") SELECT " + cols + " FROM paper_gtts",
+		"DROP TABLE paper_gtts",
+		"ALTER TABLE paper_gtts_new RENAME TO paper_gtts",
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to migrate paper_gtts: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ============================================================================
+// Paper Account Methods
+// ============================================================================
+
+// DefaultPaperAccount is the account used when none is named.
+const DefaultPaperAccount = "default"
+
+// ErrPaperAccountChanged is returned by SavePaperAccount when another
+// process saved the account since it was loaded.
+var ErrPaperAccountChanged = errors.New("paper account changed by another process")
+
+// PaperAccountStore persists the state of one named paper trading account.
+// It satisfies broker.PaperStore.
+type PaperAccountStore struct {
+	s    *SQLiteStore
+	name string
+}
+
+// PaperAccount returns the store for the named paper account. The account
+// itself is created on the first save.
+func (s *SQLiteStore) PaperAccount(name string) *PaperAccountStore {
+	if name == "" {
+		name = DefaultPaperAccount
+	}
+	return &PaperAccountStore{s: s, name: name}
+}
+
+// GetPaperAccounts returns all paper accounts ordered by name.
+func (s *SQLiteStore) GetPaperAccounts(ctx context.Context) ([]models.PaperAccount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, initial_balance, cash, realized_pnl, charges, order_counter, gtt_counter, session_close, created_at, updated_at, version
+		FROM paper_accounts ORDER BY name ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paper accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []models.PaperAccount
+	for rows.Next() {
+		a, err := scanPaperAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan paper account: %w", err)
+		}
+		accounts = append(accounts, *a)
+	}
+
+	return accounts, rows.Err()
+}
+
+// scanPaperAccount scans a paper_accounts row.
+func scanPaperAccount(row interface{ Scan(...any) error }) (*models.PaperAccount, error) {
+	var a models.PaperAccount
+	var sessionClose sql.NullTime
+	if err := row.Scan(&a.Name, &a.InitialBalance, &a.Cash, &a.RealizedPnL, &a.Charges,
+		&a.OrderCounter, &a.GTTCounter, &sessionClose, &a.CreatedAt, &a.UpdatedAt, &a.Version); err != nil {
+		return nil, err
+	}
+	a.SessionClose = sessionClose.Time
+	return &a, nil
+}
+
+// Name returns the account name.
+func (a *PaperAccountStore) Name() string {
+	return a.name
+}
+
+// LockPaperAccount takes the lock that serializes changes to the account
+// across processes, such as the daemon and a manual order, and returns the
+// function that releases it. It blocks until the lock is free. Each account
+// has its own lock, so independent accounts never wait on each other.
+func (a *PaperAccountStore) LockPaperAccount(ctx context.Context) (func(), error) {
+	if a.s.path == "" {
+		return func() {}, nil
+	}
+
+	// Escaped so that any account name is a single file name
+	lockPath := a.s.path + ".paper-" + url.PathEscape(a.name) + ".lock"
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open paper account lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock paper account: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// PaperAccountVersion returns the version of the last save, 0 if the
+// account does not exist yet.
+func (a *PaperAccountStore) PaperAccountVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := a.s.db.QueryRowContext(ctx, `SELECT version FROM paper_accounts WHERE name = ?`, a.name).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read paper account version: %w", err)
+	}
+	return version, nil
+}
+
+// LoadPaperAccount loads the account's state. It returns nil if the account
+// does not exist yet.
+func (a *PaperAccountStore) LoadPaperAccount(ctx context.Context) (*models.PaperAccountState, error) {
+	account, err := scanPaperAccount(a.s.db.QueryRowContext(ctx, `
+		SELECT name, initial_balance, cash, realized_pnl, charges, order_counter, gtt_counter, session_close, created_at, updated_at, version
+		FROM paper_accounts WHERE name = ?
+	`, a.name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load paper account: %w", err)
+	}
+
+	state := &models.PaperAccountState{Account: *account}
+	if state.Orders, err = a.loadOrders(ctx); err != nil {
+		return nil, err
+	}
+	if state.Positions, err = a.loadPositions(ctx); err != nil {
+		return nil, err
+	}
+	if state.Holdings, err = a.loadHoldings(ctx); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (a *PaperAccountStore) loadOrders(ctx context.Context) ([]models.Order, error) {
+	rows, err := a.s.db.QueryContext(ctx, `
+		SELECT id, symbol, exchange, side, order_type, product, quantity, price, trigger_price, validity, tag,
+			status, status_message, filled_qty, average_price, placed_at
+		FROM paper_orders WHERE account = ? ORDER BY placed_at ASC
+	`, a.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paper orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var o models.Order
+		var exchange, side, orderType, product string
+		var validity, tag, statusMessage sql.NullString
+		if err := rows.Scan(&o.ID, &o.Symbol, &exchange, &side, &orderType, &product, &o.Quantity, &o.Price,
+			&o.TriggerPrice, &validity, &tag, &o.Status, &statusMessage, &o.FilledQty, &o.AveragePrice, &o.PlacedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan paper order: %w", err)
+		}
+		o.Exchange = models.Exchange(exchange)
+		o.Side = models.OrderSide(side)
+		o.Type = models.OrderType(orderType)
+		o.Product = models.ProductType(product)
+		o.Validity = validity.String
+		o.Tag = tag.String
+		o.StatusMessage = statusMessage.String
+		orders = append(orders, o)
+	}
+
+	return orders, rows.Err()
+}
+
+func (a *PaperAccountStore) loadPositions(ctx context.Context) ([]models.Position, error) {
+	rows, err := a.s.db.QueryContext(ctx, `
+		SELECT symbol, exchange, product, quantity, average_price, ltp
+		FROM paper_positions WHERE account = ?
+	`, a.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paper positions: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []models.Position
+	for rows.Next() {
+		var p models.Position
+		var exchange, product string
+		var ltp sql.NullFloat64
+		if err := rows.Scan(&p.Symbol, &exchange, &product, &p.Quantity, &p.AveragePrice, &ltp); err != nil {
+			return nil, fmt.Errorf("failed to scan paper position: %w", err)
+		}
+		p.Exchange = models.Exchange(exchange)
+		p.Product = models.ProductType(product)
+		p.LTP = ltp.Float64
+		positions = append(positions, p)
+	}
+
+	return positions, rows.Err()
+}
+
+func (a *PaperAccountStore) loadHoldings(ctx context.Context) ([]models.Holding, error) {
+	rows, err := a.s.db.QueryContext(ctx, `
+		SELECT symbol, quantity, t1_quantity, average_price, ltp
+		FROM paper_holdings WHERE account = ?
+	`, a.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paper holdings: %w", err)
+	}
+	defer rows.Close()
+
+	var holdings []models.Holding
+	for rows.Next() {
+		var h models.Holding
+		var ltp sql.NullFloat64
+		if err := rows.Scan(&h.Symbol, &h.Quantity, &h.T1Quantity, &h.AveragePrice, &ltp); err != nil {
+			return nil, fmt.Errorf("failed to scan paper holding: %w", err)
+		}
+		h.LTP = ltp.Float64
+		h.InvestedValue = h.AveragePrice * float64(h.Quantity)
+		holdings = append(holdings, h)
+	}
+
+	return holdings, rows.Err()
+}
+
+// SavePaperAccount writes the account row, replaces its positions and
+// holdings, and upserts the given orders. Orders not in state are left as
+// they are, so callers only need to pass orders that changed.
+//
+// The save only applies if the stored version still equals
+// state.Account.Version, and bumps it by one; otherwise it returns
+// ErrPaperAccountChanged and writes nothing.
+func (a *PaperAccountStore) SavePaperAccount(ctx context.Context, state *models.PaperAccountState) error {
+	tx, err := a.s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	acct := state.Account
+	var sessionClose any
+	if !acct.SessionClose.IsZero() {
+		sessionClose = acct.SessionClose
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO paper_accounts (name, initial_balance, cash, realized_pnl, charges, order_counter, gtt_counter, session_close, created_at, updated_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT(name) DO UPDATE SET
+			initial_balance = excluded.initial_balance,
+			cash = excluded.cash,
+			realized_pnl = excluded.realized_pnl,
+			charges = excluded.charges,
+			order_counter = excluded.order_counter,
+			gtt_counter = excluded.gtt_counter,
+			session_close = excluded.session_close,
+			updated_at = excluded.updated_at,
+			version = paper_accounts.version + 1
+		WHERE paper_accounts.version = ?
+	`, a.name, acct.InitialBalance, acct.Cash, acct.RealizedPnL, acct.Charges, acct.OrderCounter, acct.GTTCounter,
+		sessionClose, acct.CreatedAt, acct.UpdatedAt, acct.Version)
+	if err != nil {
+		return fmt.Errorf("failed to save paper account: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to save paper account: %w", err)
+	} else if n == 0 {
+		return ErrPaperAccountChanged
+	}
+
+	for _, o := range state.Orders {
+		_, err := tx.ExecContext(ctx, `
+			INSERT OR REPLACE INTO paper_orders (account, id, symbol, exchange, side, order_type, product, quantity, price,
+				trigger_price, validity, tag, status, status_message, filled_qty, average_price, placed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, a.name, o.ID, o.Symbol, string(o.Exchange), string(o.Side), string(o.Type), string(o.Product), o.Quantity, o.Price,
+			o.TriggerPrice, o.Validity, o.Tag, o.Status, o.StatusMessage, o.FilledQty, o.AveragePrice, o.PlacedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save paper order: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM paper_positions WHERE account = ?`, a.name); err != nil {
+		return fmt.Errorf("failed to clear paper positions: %w", err)
+	}
+	for _, p := range state.Positions {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO paper_positions (account, symbol, exchange, product, quantity, average_price, ltp)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, a.name, p.Symbol, string(p.Exchange), string(p.Product), p.Quantity, p.AveragePrice, p.LTP)
+		if err != nil {
+			return fmt.Errorf("failed to save paper position: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM paper_holdings WHERE account = ?`, a.name); err != nil {
+		return fmt.Errorf("failed to clear paper holdings: %w", err)
+	}
+	for _, h := range state.Holdings {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO paper_holdings (account, symbol, quantity, t1_quantity, average_price, ltp)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, a.name, h.Symbol, h.Quantity, h.T1Quantity, h.AveragePrice, h.LTP)
+		if err != nil {
+			return fmt.Errorf("failed to save paper holding: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ClearPaperAccount deletes the account's orders, positions, holdings and
+// GTTs. The ledger and snapshots are kept as history.
+func (a *PaperAccountStore) ClearPaperAccount(ctx context.Context) error {
+	tx, err := a.s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"paper_orders", "paper_positions", "paper_holdings", "paper_gtts"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE account = ?", a.name); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AppendPaperLedger records ledger entries for the account.
+func (a *PaperAccountStore) AppendPaperLedger(ctx context.Context, entries []models.PaperLedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := a.s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, e := range entries {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO paper_ledger (account, timestamp, entry_type, symbol, order_id, amount, balance, note)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, a.name, e.Time, e.Type, e.Symbol, e.OrderID, e.Amount, e.Balance, e.Note)
+		if err != nil {
+			return fmt.Errorf("failed to save paper ledger entry: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetPaperLedger returns the most recent ledger entries, oldest first.
+func (a *PaperAccountStore) GetPaperLedger(ctx context.Context, limit int) ([]models.PaperLedgerEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := a.s.db.QueryContext(ctx, `
+		SELECT id, timestamp, entry_type, symbol, order_id, amount, balance, note FROM (
+			SELECT * FROM paper_ledger WHERE account = ? ORDER BY id DESC LIMIT ?
+		) ORDER BY id ASC
+	`, a.name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paper ledger: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.PaperLedgerEntry
+	for rows.Next() {
+		e := models.PaperLedgerEntry{Account: a.name}
+		var symbol, orderID, note sql.NullString
+		if err := rows.Scan(&e.ID, &e.Time, &e.Type, &symbol, &orderID, &e.Amount, &e.Balance, &note); err != nil {
+			return nil, fmt.Errorf("failed to scan paper ledger entry: %w", err)
+		}
+		e.Symbol = symbol.String
+		e.OrderID = orderID.String
+		e.Note = note.String
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// SavePaperSnapshot records a snapshot of the account.
+func (a *PaperAccountStore) SavePaperSnapshot(ctx context.Context, snap *models.PaperSnapshot) error {
+	res, err := a.s.db.ExecContext(ctx, `
+		INSERT INTO paper_snapshots (account, label, taken_at, cash, equity, realized_pnl, unrealized_pnl, charges,
+			return_percent, open_positions, holdings, trades)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.name, snap.Label, snap.TakenAt, snap.Cash, snap.Equity, snap.RealizedPnL, snap.UnrealizedPnL, snap.Charges,
+		snap.ReturnPercent, snap.OpenPositions, snap.Holdings, snap.Trades)
+	if err != nil {
+		return fmt.Errorf("failed to save paper snapshot: %w", err)
+	}
+
+	snap.Account = a.name
+	snap.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// GetPaperSnapshots returns the account's snapshots, oldest first.
+func (a *PaperAccountStore) GetPaperSnapshots(ctx context.Context) ([]models.PaperSnapshot, error) {
+	rows, err := a.s.db.QueryContext(ctx, `
+		SELECT id, label, taken_at, cash, equity, realized_pnl, unrealized_pnl, charges, return_percent,
+			open_positions, holdings, trades
+		FROM paper_snapshots WHERE account = ? ORDER BY taken_at ASC, id ASC
+	`, a.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paper snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snaps []models.PaperSnapshot
+	for rows.Next() {
+		s := models.PaperSnapshot{Account: a.name}
+		var label sql.NullString
+		if err := rows.Scan(&s.ID, &label, &s.TakenAt, &s.Cash, &s.Equity, &s.RealizedPnL, &s.UnrealizedPnL,
+			&s.Charges, &s.ReturnPercent, &s.OpenPositions, &s.Holdings, &s.Trades); err != nil {
+			return nil, fmt.Errorf("failed to scan paper snapshot: %w", err)
+		}
+		s.Label = label.String
+		snaps = append(snaps, s)
+	}
+
+	return snaps, rows.Err()
+}
+
+// SavePaperGTT inserts or updates a GTT of the account.
+func (a *PaperAccountStore) SavePaperGTT(ctx context.Context, gtt *models.GTTOrder) error {
+	legs, err := json.Marshal(gtt.Orders)
+	if err != nil {
+		return fmt.Errorf("failed to encode GTT legs: %w", err)
+	}
+
+	_, err = a.s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO paper_gtts (id, account, symbol, exchange, trigger_type, trigger_price, last_price, legs, status, order_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, gtt.ID, a.name, gtt.Symbol, string(gtt.Exchange), gtt.TriggerType, gtt.TriggerPrice, gtt.LastPrice,
+		string(legs), gtt.Status, gtt.OrderID, gtt.CreatedAt, gtt.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save paper GTT: %w", err)
+	}
+	return nil
+}
+
+// GetPaperGTTs retrieves all GTTs of the account.
+func (a *PaperAccountStore) GetPaperGTTs(ctx context.Context) ([]models.GTTOrder, error) {
+	rows, err := a.s.db.QueryContext(ctx, `
+		SELECT id, symbol, exchange, trigger_type, trigger_price, last_price, legs, status, order_id, created_at, updated_at
+		FROM paper_gtts WHERE account = ? ORDER BY created_at ASC
+	`, a.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paper GTTs: %w", err)
+	}
+	defer rows.Close()
+
+	var gtts []models.GTTOrder
+	for rows.Next() {
+		var g models.GTTOrder
+		var exchange, legs string
+		var orderID sql.NullString
+		if err := rows.Scan(&g.ID, &g.Symbol, &exchange, &g.TriggerType, &g.TriggerPrice, &g.LastPrice,
+			&legs, &g.Status, &orderID, &g.CreatedAt, &g.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan paper GTT: %w", err)
+		}
+		g.Exchange = models.Exchange(exchange)
+		g.OrderID = orderID.String
+		if err := json.Unmarshal([]byte(legs), &g.Orders); err != nil {
+			return nil, fmt.Errorf("failed to decode GTT legs: %w", err)
+		}
+		gtts = append(gtts, g)
+	}
+
+	return gtts, rows.Err()
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+func TestPaperGTTs_KeyedPerAccount(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "paper.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer s.Close()
+
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	for _, account := range []string{"alpha", "beta"} {
+		gtt := &models.GTTOrder{ID: "1", Symbol: account, Exchange: models.NSE, TriggerType: "single",
+			TriggerPrice: 100, Status: "active", CreatedAt: now, UpdatedAt: now}
+		if err := s.PaperAccount(account).SavePaperGTT(ctx, gtt); err != nil {
+			t.Fatalf("failed to save GTT for %s: %v", account, err)
+		}
+	}
+
+	for _, account := range []string{"alpha", "beta"} {
+		gtts, err := s.PaperAccount(account).GetPaperGTTs(ctx)
+		if err != nil {
+			t.Fatalf("failed to load GTTs for %s: %v", account, err)
+		}
+		if len(gtts) != 1 || gtts[0].Symbol != account {
+			t.Errorf("%s: expected its own GTT 1, got %+v", account, gtts)
+		}
+	}
+}
+
+func TestPaperGTTs_MigratesIDOnlyKey(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "paper.db")
+
+	// Schema written before GTTs were keyed per account
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE paper_gtts (
+			id TEXT PRIMARY KEY,
+			symbol TEXT NOT NULL,
+			exchange TEXT NOT NULL,
+			trigger_type TEXT NOT NULL,
+			trigger_price REAL,
+			last_price REAL,
+			legs TEXT NOT NULL,
+			status TEXT NOT NULL,
+			order_id TEXT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			account TEXT NOT NULL DEFAULT 'default'
+		);
+		INSERT INTO paper_gtts (id, symbol, exchange, trigger_type, trigger_price, last_price, legs, status, created_at, updated_at)
+		VALUES ('1', 'INFY', 'NSE', 'single', 1500, 1450, '[]', 'active', '2024-03-04 10:00:00', '2024-03-04 10:00:00');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer s.Close()
+
+	now := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	other := &models.GTTOrder{ID: "1", Symbol: "TCS", Exchange: models.NSE, TriggerType: "single",
+		TriggerPrice: 3500, Status: "active", CreatedAt: now, UpdatedAt: now}
+	if err := s.PaperAccount("other").SavePaperGTT(ctx, other); err != nil {
+		t.Fatalf("failed to save GTT: %v", err)
+	}
+
+	gtts, err := s.PaperAccount(DefaultPaperAccount).GetPaperGTTs(ctx)
+	if err != nil {
+		t.Fatalf("failed to load GTTs: %v", err)
+	}
+	if len(gtts) != 1 || gtts[0].Symbol != "INFY" {
+		t.Errorf("expected migrated INFY GTT to survive, got %+v", gtts)
+	}
+}
+
+func TestSavePaperAccount_RejectsStaleVersion(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "paper.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer s.Close()
+
+	account := s.PaperAccount("swing")
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	state := &models.PaperAccountState{Account: models.PaperAccount{InitialBalance: 100000, Cash: 100000, CreatedAt: now, UpdatedAt: now}}
+	if err := account.SavePaperAccount(ctx, state); err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	// Two writers loaded version 1; the second save must not overwrite the first
+	state.Account.Version = 1
+	state.Account.Cash = 90000
+	if err := account.SavePaperAccount(ctx, state); err != nil {
+		t.Fatalf("failed to save at the current version: %v", err)
+	}
+	state.Account.Cash = 80000
+	if err := account.SavePaperAccount(ctx, state); !errors.Is(err, ErrPaperAccountChanged) {
+		t.Fatalf("expected ErrPaperAccountChanged, got %v", err)
+	}
+
+	loaded, err := account.LoadPaperAccount(ctx)
+	if err != nil {
+		t.Fatalf("failed to load account: %v", err)
+	}
+	if loaded.Account.Cash != 90000 || loaded.Account.Version != 2 {
+		t.Errorf("expected cash 90000 at version 2, got %.2f at %d", loaded.Account.Cash, loaded.Account.Version)
+	}
+	if version, err := account.PaperAccountVersion(ctx); err != nil || version != 2 {
+		t.Errorf("PaperAccountVersion() = %d, %v; want 2", version, err)
+	}
+}
+
+func TestLockPaperAccount_PerAccount(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "paper.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer s.Close()
+
+	unlock, err := s.PaperAccount("swing").LockPaperAccount(ctx)
+	if err != nil {
+		t.Fatalf("failed to lock swing: %v", err)
+	}
+
+	// Another account, even one with a path-like name, is not blocked
+	for _, name := range []string{"intraday", "../swing"} {
+		other, err := s.PaperAccount(name).LockPaperAccount(ctx)
+		if err != nil {
+			t.Fatalf("failed to lock %s: %v", name, err)
+		}
+		other()
+	}
+
+	// The same account waits for the holder
+	locked := make(chan struct{})
+	go func() {
+		again, err := s.PaperAccount("swing").LockPaperAccount(ctx)
+		if err == nil {
+			again()
+		}
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("second lock on the same account did not wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+}
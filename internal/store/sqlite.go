@@ -16,7 +16,9 @@ import (
 
 // SQLiteStore implements DataStore using SQLite.
 type SQLiteStore struct {
-	db        *sql.DB
+	db *sql.DB
+	// path is the database file, used for lock files beside it
+	path      string
 	mu        sync.RWMutex
 	syncTimes map[string]time.Time
 }
@@ -35,6 +37,7 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 
 	store := &SQLiteStore{
 		db:        db,
+		path:      dbPath,
 		syncTimes: make(map[string]time.Time),
 	}
 
@@ -43,6 +46,21 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	if err := store.initPaperSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize paper trading schema: %w", err)
+	}
+
+	if err := store.initLLMSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize LLM schema: %w", err)
+	}
+
+	if err := store.initKillSwitchSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize kill switch schema: %w", err)
+	}
+
 	return store, nil
 }
 
@@ -263,6 +281,16 @@ func (s *SQLiteStore) Close() error {
 	return s.db.Close()
 }
 
+// Snapshot writes a consistent copy of the database to path, which must not
+// exist. Opening the copy gives a scratch store that starts with the same
+// watchlists, plans and history but leaves this one untouched.
+func (s *SQLiteStore) Snapshot(ctx context.Context, path string) error {
+	if _, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return nil
+}
+
 // ============================================================================
 // Candles Methods
 // ============================================================================
@@ -333,9 +361,11 @@ func (s *SQLiteStore) GetCandles(ctx context.Context, symbol, timeframe string,
 
 // GetCandlesFreshness returns the timestamp of the most recent candle.
 func (s *SQLiteStore) GetCandlesFreshness(ctx context.Context, symbol, timeframe string) (time.Time, error) {
+	// MAX() loses the column's DATETIME type, so select the row instead
 	var timestamp sql.NullTime
 	err := s.db.QueryRowContext(ctx, `
-		SELECT MAX(timestamp) FROM candles WHERE symbol = ? AND timeframe = ?
+		SELECT timestamp FROM candles WHERE symbol = ? AND timeframe = ?
+		ORDER BY timestamp DESC LIMIT 1
 	`, symbol, timeframe).Scan(&timestamp)
 	if err != nil && err != sql.ErrNoRows {
 		return time.Time{}, fmt.Errorf("failed to get candles freshness: %w", err)
@@ -397,6 +427,11 @@ func (s *SQLiteStore) GetTrades(ctx context.Context, filter TradeFilter) ([]mode
 		query += " AND is_paper = ?"
 		args = append(args, isPaper)
 	}
+	if filter.OrderID != "" {
+		orderID, _ := json.Marshal(filter.OrderID)
+		query += " AND order_ids LIKE ?"
+		args = append(args, "%"+string(orderID)+"%")
+	}
 
 	query += " ORDER BY timestamp DESC"
 	if filter.Limit > 0 {
@@ -454,6 +489,26 @@ func (s *SQLiteStore) SaveTradeAnalysis(ctx context.Context, analysis *models.Tr
 	return nil
 }
 
+// GetTradeContext returns the market context saved with a trade's analysis,
+// or nil if none was saved.
+func (s *SQLiteStore) GetTradeContext(ctx context.Context, tradeID string) (*models.TradeContext, error) {
+	var tc models.TradeContext
+	var trend, news sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(nifty_level, 0), COALESCE(sector_index, 0), COALESCE(vix_level, 0), market_trend, news_events
+		FROM trade_context WHERE trade_id = ?
+	`, tradeID).Scan(&tc.NiftyLevel, &tc.SectorIndex, &tc.VIXLevel, &trend, &news)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trade context: %w", err)
+	}
+	tc.MarketTrend = trend.String
+	tc.NewsEvents = news.String
+	return &tc, nil
+}
+
 // SaveJournalEntry saves a journal entry to the database.
 func (s *SQLiteStore) SaveJournalEntry(ctx context.Context, entry *models.JournalEntry) error {
 	tags, _ := json.Marshal(entry.Tags)
@@ -646,6 +701,10 @@ func (s *SQLiteStore) GetDecisions(ctx context.Context, filter DecisionFilter) (
 		query += " AND outcome = ?"
 		args = append(args, filter.Outcome)
 	}
+	if filter.OrderID != "" {
+		query += " AND order_id = ?"
+		args = append(args, filter.OrderID)
+	}
 
 	query += " ORDER BY timestamp DESC"
 	if filter.Limit > 0 {
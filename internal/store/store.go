@@ -19,6 +19,7 @@ type DataStore interface {
 	LogTrade(ctx context.Context, trade *models.Trade) error
 	GetTrades(ctx context.Context, filter TradeFilter) ([]models.Trade, error)
 	SaveTradeAnalysis(ctx context.Context, analysis *models.TradeAnalysis) error
+	GetTradeContext(ctx context.Context, tradeID string) (*models.TradeContext, error)
 	SaveJournalEntry(ctx context.Context, entry *models.JournalEntry) error
 	GetJournal(ctx context.Context, filter JournalFilter) ([]models.JournalEntry, error)
 
@@ -54,6 +55,21 @@ type DataStore interface {
 	GetScreenerQuery(ctx context.Context, name string) (*ScreenerQuery, error)
 	ListScreenerQueries(ctx context.Context) ([]string, error)
 
+	// LLM Cache & Usage
+	GetLLMResponse(ctx context.Context, key string) (string, bool, error)
+	SaveLLMResponse(ctx context.Context, key, agent, response string) error
+	PruneLLMCache(ctx context.Context, cutoff time.Time) (int64, error)
+	LogLLMUsage(ctx context.Context, usage *models.LLMUsage) error
+	GetLLMUsageSummary(ctx context.Context, dateRange DateRange) ([]models.LLMUsageSummary, error)
+
+	// Kill Switch
+	GetKillSwitch(ctx context.Context) (*models.KillSwitch, error)
+	SetKillSwitch(ctx context.Context, ks *models.KillSwitch) error
+
+	// Paper Accounts
+	PaperAccount(name string) *PaperAccountStore
+	GetPaperAccounts(ctx context.Context) ([]models.PaperAccount, error)
+
 	// Sync
 	GetLastSync(dataType string) time.Time
 	SetLastSync(dataType string, t time.Time) error
@@ -69,7 +85,9 @@ type TradeFilter struct {
 	EndDate   time.Time
 	Side      string
 	IsPaper   *bool
-	Limit     int
+	// OrderID matches trades that include the order.
+	OrderID string
+	Limit   int
 }
 
 // JournalFilter represents filters for querying journal entries.
@@ -96,6 +114,7 @@ type DecisionFilter struct {
 	EndDate   time.Time
 	Executed  *bool
 	Outcome   string
+	OrderID   string
 	Limit     int
 }
 
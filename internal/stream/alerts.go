@@ -194,7 +194,7 @@ func (m *AlertMonitor) Check(tick models.Tick) {
 			continue
 		}
 
-		state.LastChecked = time.Now()
+		state.LastChecked = tickTime(tick)
 		state.CheckCount++
 
 		if m.isTriggered(state.Alert, tick, prevPrice) {
@@ -208,6 +208,15 @@ func (m *AlertMonitor) Check(tick models.Tick) {
 	m.prevMu.Unlock()
 }
 
+// tickTime returns when a tick happened: its exchange timestamp, which a
+// replayed tick always carries, or the current time.
+func tickTime(tick models.Tick) time.Time {
+	if tick.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return tick.Timestamp
+}
+
 // isTriggered checks if an alert condition is met.
 func (m *AlertMonitor) isTriggered(alert *models.Alert, tick models.Tick, prevPrice float64) bool {
 	condition := AlertCondition(alert.Condition)
@@ -254,7 +263,7 @@ func (m *AlertMonitor) isTriggered(alert *models.Alert, tick models.Tick, prevPr
 func (m *AlertMonitor) trigger(state *AlertState, tick models.Tick) {
 	alert := state.Alert
 	alert.Triggered = true
-	now := time.Now()
+	now := tickTime(tick)
 	alert.TriggeredAt = &now
 
 	// Update in data store
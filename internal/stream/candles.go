@@ -0,0 +1,407 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"zerodha-trader/internal/analysis/indicators"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/store"
+)
+
+// Live bars cover the normal market session, 09:15 to 15:30 IST, and are
+// aligned to its open as Kite's historical candles are.
+const (
+	liveSessionOpen  = 9*time.Hour + 15*time.Minute
+	liveSessionClose = 15*time.Hour + 30*time.Minute
+)
+
+// DefaultLiveTimeframes are the bars a CandleAggregator builds by default.
+var DefaultLiveTimeframes = []string{"1min", "5min", "15min"}
+
+// liveTimeframes are the intraday bar sizes a CandleAggregator can build,
+// named as in Kite's historical API.
+var liveTimeframes = map[string]time.Duration{
+	"1min":  time.Minute,
+	"5min":  5 * time.Minute,
+	"15min": 15 * time.Minute,
+	"30min": 30 * time.Minute,
+	"1hour": time.Hour,
+}
+
+// candleSaveBuffer is how many closed bars may wait to be persisted.
+const candleSaveBuffer = 1024
+
+// CandleAggregatorConfig holds configuration for a CandleAggregator.
+type CandleAggregatorConfig struct {
+	// Timeframes to build. Defaults to DefaultLiveTimeframes.
+	Timeframes []string
+	// Symbols limits aggregation to these symbols. Empty means all.
+	Symbols []string
+	// Store persists closed bars with SaveCandles. Optional.
+	Store store.DataStore
+	// Indicators and MultiIndicators are updated from each closed bar.
+	Indicators      []indicators.Incremental
+	MultiIndicators []indicators.MultiIncremental
+	// OnBar is called with each closed bar. Optional.
+	OnBar func(LiveBar)
+	// OnError is called when a closed bar cannot be persisted. Optional.
+	OnError func(error)
+}
+
+// LiveBar is a closed bar and the indicator values updated from it.
+type LiveBar struct {
+	Symbol    string
+	Timeframe string
+	Candle    models.Candle
+	// Values holds ready single-value indicators by name; Multi holds ready
+	// multi-value indicators by name, then by key.
+	Values map[string]float64
+	Multi  map[string]map[string]float64
+}
+
+// CandleAggregator builds OHLCV bars from ticks as they arrive. Bar volume
+// is the change in the tick's cumulative day volume, so the first tick of a
+// symbol only sets the baseline. A bar closes when a tick for any symbol
+// reaches its end, so a quiet symbol's bar still closes on time, or when
+// Flush is called. Bars are timed by tick timestamps, which keeps a replayed
+// session deterministic.
+//
+// Register it with Hub.RegisterOrderedConsumer so it sees ticks in order.
+type CandleAggregator struct {
+	cfg    CandleAggregatorConfig
+	frames []liveFrame
+
+	mu        sync.Mutex
+	series    map[seriesKey]*liveSeries
+	volumes   map[string]*dayVolume
+	nextClose time.Time // earliest end of an open bar
+
+	saves     chan LiveBar
+	saverDone chan struct{}
+	closeOnce sync.Once
+}
+
+type liveFrame struct {
+	name     string
+	duration time.Duration
+}
+
+type seriesKey struct {
+	symbol    string
+	timeframe string
+}
+
+// liveSeries is the forming bar and indicator state of one symbol and
+// timeframe.
+type liveSeries struct {
+	bar     models.Candle
+	end     time.Time
+	forming bool
+	day     time.Time // session of the last closed bar
+	last    LiveBar
+	closed  bool
+
+	updaters []indicators.Updater
+	multi    []indicators.MultiUpdater
+}
+
+// dayVolume tracks a symbol's cumulative day volume.
+type dayVolume struct {
+	day    time.Time
+	volume int64
+}
+
+// NewCandleAggregator creates an aggregator. Close it to finish persisting.
+func NewCandleAggregator(cfg CandleAggregatorConfig) (*CandleAggregator, error) {
+	if len(cfg.Timeframes) == 0 {
+		cfg.Timeframes = DefaultLiveTimeframes
+	}
+	a := &CandleAggregator{
+		cfg:     cfg,
+		series:  make(map[seriesKey]*liveSeries),
+		volumes: make(map[string]*dayVolume),
+	}
+	for _, tf := range cfg.Timeframes {
+		d, ok := liveTimeframes[tf]
+		if !ok {
+			return nil, fmt.Errorf("unsupported live timeframe %q", tf)
+		}
+		a.frames = append(a.frames, liveFrame{name: tf, duration: d})
+	}
+
+	if cfg.Store != nil {
+		a.saves = make(chan LiveBar, candleSaveBuffer)
+		a.saverDone = make(chan struct{})
+		go a.saveLoop()
+	}
+	return a, nil
+}
+
+// Symbols implements Consumer.
+func (a *CandleAggregator) Symbols() []string {
+	return a.cfg.Symbols
+}
+
+// OnTick implements Consumer.
+func (a *CandleAggregator) OnTick(tick models.Tick) {
+	at := tickTime(tick).In(resilience.IndiaLocation)
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, resilience.IndiaLocation)
+	open, end := day.Add(liveSessionOpen), day.Add(liveSessionClose)
+
+	a.mu.Lock()
+	closed := a.closeDue(at)
+	delta := a.volumeDelta(tick, day)
+	if !at.Before(open) && at.Before(end) {
+		for _, f := range a.frames {
+			a.addTick(tick, f, at, open, end, delta)
+		}
+	}
+	a.mu.Unlock()
+
+	a.emit(closed)
+}
+
+// Flush closes every bar that ends at or before now, for when ticks stop,
+// as they do at the close.
+func (a *CandleAggregator) Flush(now time.Time) {
+	a.mu.Lock()
+	closed := a.closeDue(now)
+	a.mu.Unlock()
+
+	a.emit(closed)
+}
+
+// Seed feeds closed history bars, oldest first, to a series' indicators so
+// they are ready before the first live bar closes.
+func (a *CandleAggregator) Seed(symbol, timeframe string, history []models.Candle) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s := a.seriesFor(symbol, timeframe)
+	for _, c := range history {
+		s.last = a.update(s, symbol, timeframe, c)
+		s.closed = true
+	}
+}
+
+// Latest returns the last closed bar of a symbol and timeframe.
+func (a *CandleAggregator) Latest(symbol, timeframe string) (LiveBar, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.series[seriesKey{symbol, timeframe}]
+	if !ok || !s.closed {
+		return LiveBar{}, false
+	}
+	return s.last, true
+}
+
+// Forming returns the bar still being built for a symbol and timeframe.
+func (a *CandleAggregator) Forming(symbol, timeframe string) (models.Candle, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.series[seriesKey{symbol, timeframe}]
+	if !ok || !s.forming {
+		return models.Candle{}, false
+	}
+	return s.bar, true
+}
+
+// Close persists the bars already closed and stops the writer. Forming bars
+// are dropped.
+func (a *CandleAggregator) Close() {
+	a.closeOnce.Do(func() {
+		if a.saves != nil {
+			a.mu.Lock()
+			close(a.saves)
+			a.saves = nil
+			a.mu.Unlock()
+			<-a.saverDone
+		}
+	})
+}
+
+// volumeDelta returns the volume traded since the symbol's previous tick.
+// Callers hold a.mu.
+func (a *CandleAggregator) volumeDelta(tick models.Tick, day time.Time) int64 {
+	v, ok := a.volumes[tick.Symbol]
+	if !ok {
+		a.volumes[tick.Symbol] = &dayVolume{day: day, volume: tick.Volume}
+		return 0
+	}
+	if !v.day.Equal(day) {
+		// A new session's volume starts from zero
+		v.day, v.volume = day, tick.Volume
+		return tick.Volume
+	}
+	if tick.Volume <= v.volume {
+		return 0
+	}
+	delta := tick.Volume - v.volume
+	v.volume = tick.Volume
+	return delta
+}
+
+// addTick folds a tick into the forming bar of one timeframe. Callers hold
+// a.mu and have closed bars that ended before the tick.
+func (a *CandleAggregator) addTick(tick models.Tick, f liveFrame, at, open, end time.Time, volume int64) {
+	s := a.seriesFor(tick.Symbol, f.name)
+	start := open.Add(at.Sub(open) / f.duration * f.duration)
+
+	if s.closed && !start.After(s.last.Candle.Timestamp) {
+		return // late tick for a closed bar
+	}
+	if s.forming {
+		if tick.LTP > s.bar.High {
+			s.bar.High = tick.LTP
+		}
+		if tick.LTP < s.bar.Low {
+			s.bar.Low = tick.LTP
+		}
+		s.bar.Close = tick.LTP
+		s.bar.Volume += volume
+		return
+	}
+
+	s.bar = models.Candle{Timestamp: start, Open: tick.LTP, High: tick.LTP, Low: tick.LTP, Close: tick.LTP, Volume: volume}
+	s.end = start.Add(f.duration)
+	if s.end.After(end) {
+		s.end = end
+	}
+	s.forming = true
+	if a.nextClose.IsZero() || s.end.Before(a.nextClose) {
+		a.nextClose = s.end
+	}
+}
+
+// closeDue closes forming bars that end at or before now and returns them.
+// Callers hold a.mu.
+func (a *CandleAggregator) closeDue(now time.Time) []LiveBar {
+	if a.nextClose.IsZero() || now.Before(a.nextClose) {
+		return nil
+	}
+
+	var closed []LiveBar
+	a.nextClose = time.Time{}
+	for key, s := range a.series {
+		if !s.forming {
+			continue
+		}
+		if now.Before(s.end) {
+			if a.nextClose.IsZero() || s.end.Before(a.nextClose) {
+				a.nextClose = s.end
+			}
+			continue
+		}
+		s.forming = false
+		s.last = a.update(s, key.symbol, key.timeframe, s.bar)
+		s.closed = true
+		closed = append(closed, s.last)
+	}
+	sort.Slice(closed, func(i, j int) bool {
+		if closed[i].Symbol != closed[j].Symbol {
+			return closed[i].Symbol < closed[j].Symbol
+		}
+		if !closed[i].Candle.Timestamp.Equal(closed[j].Candle.Timestamp) {
+			return closed[i].Candle.Timestamp.Before(closed[j].Candle.Timestamp)
+		}
+		return closed[i].Timeframe < closed[j].Timeframe
+	})
+	return closed
+}
+
+// update advances a series' indicators by one closed bar. VWAP restarts
+// each session. Callers hold a.mu.
+func (a *CandleAggregator) update(s *liveSeries, symbol, timeframe string, c models.Candle) LiveBar {
+	t := c.Timestamp.In(resilience.IndiaLocation)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, resilience.IndiaLocation)
+	if !s.day.IsZero() && !s.day.Equal(day) {
+		for i, ind := range a.cfg.Indicators {
+			if _, ok := ind.(*indicators.VWAP); ok {
+				s.updaters[i] = ind.NewUpdater()
+			}
+		}
+	}
+	s.day = day
+
+	bar := LiveBar{Symbol: symbol, Timeframe: timeframe, Candle: c, Values: make(map[string]float64), Multi: make(map[string]map[string]float64)}
+	for i, u := range s.updaters {
+		if v, ok := u.Update(c); ok {
+			bar.Values[a.cfg.Indicators[i].Name()] = v
+		}
+	}
+	for i, u := range s.multi {
+		if values, ok := u.Update(c); ok {
+			bar.Multi[a.cfg.MultiIndicators[i].Name()] = values
+		}
+	}
+	return bar
+}
+
+// seriesFor returns the series of a symbol and timeframe, creating it with
+// fresh indicator state. Callers hold a.mu.
+func (a *CandleAggregator) seriesFor(symbol, timeframe string) *liveSeries {
+	key := seriesKey{symbol, timeframe}
+	if s, ok := a.series[key]; ok {
+		return s
+	}
+	s := &liveSeries{}
+	for _, ind := range a.cfg.Indicators {
+		s.updaters = append(s.updaters, ind.NewUpdater())
+	}
+	for _, ind := range a.cfg.MultiIndicators {
+		s.multi = append(s.multi, ind.NewUpdater())
+	}
+	a.series[key] = s
+	return s
+}
+
+// emit queues closed bars for persistence and calls OnBar.
+func (a *CandleAggregator) emit(closed []LiveBar) {
+	for _, bar := range closed {
+		a.save(bar)
+		if a.cfg.OnBar != nil {
+			a.cfg.OnBar(bar)
+		}
+	}
+}
+
+// save queues a bar for the writer without blocking the hub.
+func (a *CandleAggregator) save(bar LiveBar) {
+	a.mu.Lock()
+	queued := true
+	if a.saves != nil {
+		select {
+		case a.saves <- bar:
+		default:
+			queued = false
+		}
+	}
+	a.mu.Unlock()
+
+	if !queued {
+		a.report(fmt.Errorf("candle writer behind, dropped %s %s bar at %s", bar.Symbol, bar.Timeframe, bar.Candle.Timestamp.Format("15:04")))
+	}
+}
+
+// saveLoop persists closed bars until Close.
+func (a *CandleAggregator) saveLoop() {
+	defer close(a.saverDone)
+	for bar := range a.saves {
+		if err := a.cfg.Store.SaveCandles(context.Background(), bar.Symbol, bar.Timeframe, []models.Candle{bar.Candle}); err != nil {
+			a.report(fmt.Errorf("saving %s %s bar: %w", bar.Symbol, bar.Timeframe, err))
+		}
+	}
+}
+
+func (a *CandleAggregator) report(err error) {
+	if a.cfg.OnError != nil {
+		a.cfg.OnError(err)
+	}
+}
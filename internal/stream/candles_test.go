@@ -0,0 +1,260 @@
+package stream
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/analysis/indicators"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/store"
+)
+
+// istTime returns a time on March 2024 day d in IST.
+func istTime(d, hour, min, sec int) time.Time {
+	return time.Date(2024, time.March, d, hour, min, sec, 0, resilience.IndiaLocation)
+}
+
+func liveTick(symbol string, at time.Time, ltp float64, volume int64) models.Tick {
+	return models.Tick{Symbol: symbol, LTP: ltp, Volume: volume, Timestamp: at}
+}
+
+// newTestAggregator returns an aggregator that collects closed bars.
+func newTestAggregator(t *testing.T, cfg CandleAggregatorConfig) (*CandleAggregator, *[]LiveBar) {
+	t.Helper()
+	var bars []LiveBar
+	cfg.OnBar = func(bar LiveBar) { bars = append(bars, bar) }
+	a, err := NewCandleAggregator(cfg)
+	if err != nil {
+		t.Fatalf("NewCandleAggregator() error = %v", err)
+	}
+	t.Cleanup(a.Close)
+	return a, &bars
+}
+
+// candleStore records the bars saved to it. When release is set, the first
+// save signals started and waits for release.
+type candleStore struct {
+	store.DataStore
+
+	mu      sync.Mutex
+	saved   []LiveBar
+	stall   sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *candleStore) SaveCandles(ctx context.Context, symbol, timeframe string, candles []models.Candle) error {
+	if s.release != nil {
+		s.stall.Do(func() {
+			s.started <- struct{}{}
+			<-s.release
+		})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range candles {
+		s.saved = append(s.saved, LiveBar{Symbol: symbol, Timeframe: timeframe, Candle: c})
+	}
+	return nil
+}
+
+func TestNewCandleAggregator_RejectsUnknownTimeframe(t *testing.T) {
+	if _, err := NewCandleAggregator(CandleAggregatorConfig{Timeframes: []string{"3min"}}); err == nil {
+		t.Error("expected an error for an unsupported timeframe")
+	}
+}
+
+func TestCandleAggregator_VolumeDeltas(t *testing.T) {
+	a, bars := newTestAggregator(t, CandleAggregatorConfig{Timeframes: []string{"1min"}})
+
+	a.OnTick(liveTick("INFY", istTime(4, 9, 15, 5), 1500, 1000))  // baseline only
+	a.OnTick(liveTick("INFY", istTime(4, 9, 15, 30), 1510, 1200)) // +200
+	a.OnTick(liveTick("INFY", istTime(4, 9, 15, 50), 1495, 1150)) // stale volume, +0
+	a.OnTick(liveTick("INFY", istTime(4, 9, 16, 10), 1505, 1500)) // closes 9:15, +300
+
+	if len(*bars) != 1 {
+		t.Fatalf("got %d closed bars, want 1", len(*bars))
+	}
+	got := (*bars)[0].Candle
+	want := models.Candle{Timestamp: istTime(4, 9, 15, 0), Open: 1500, High: 1510, Low: 1495, Close: 1495, Volume: 200}
+	if !got.Timestamp.Equal(want.Timestamp) || got.Open != want.Open || got.High != want.High ||
+		got.Low != want.Low || got.Close != want.Close || got.Volume != want.Volume {
+		t.Errorf("closed bar = %+v, want %+v", got, want)
+	}
+	if forming, ok := a.Forming("INFY", "1min"); !ok || forming.Volume != 300 {
+		t.Errorf("forming bar = %+v, %v; want volume 300", forming, ok)
+	}
+
+	// The next session's volume counts from zero
+	a.OnTick(liveTick("INFY", istTime(5, 9, 15, 1), 1520, 250))
+	if forming, ok := a.Forming("INFY", "1min"); !ok || forming.Volume != 250 {
+		t.Errorf("first bar of the next day = %+v, %v; want volume 250", forming, ok)
+	}
+}
+
+func TestCandleAggregator_SessionAlignment(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeframe string
+		at        time.Time
+		start     time.Time
+		forming   bool
+	}{
+		{"5min mid bar", "5min", istTime(4, 9, 22, 30), istTime(4, 9, 20, 0), true},
+		{"15min from the open", "15min", istTime(4, 9, 44, 59), istTime(4, 9, 30, 0), true},
+		{"1hour aligned to 9:15", "1hour", istTime(4, 10, 20, 0), istTime(4, 10, 15, 0), true},
+		{"last hour", "1hour", istTime(4, 15, 20, 0), istTime(4, 15, 15, 0), true},
+		{"pre-open", "5min", istTime(4, 9, 10, 0), time.Time{}, false},
+		{"after the close", "5min", istTime(4, 15, 30, 0), time.Time{}, false},
+		{"UTC timestamp", "5min", time.Date(2024, time.March, 4, 4, 0, 0, 0, time.UTC), istTime(4, 9, 30, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestAggregator(t, CandleAggregatorConfig{Timeframes: []string{tt.timeframe}})
+			a.OnTick(liveTick("INFY", tt.at, 1500, 100))
+
+			bar, ok := a.Forming("INFY", tt.timeframe)
+			if ok != tt.forming {
+				t.Fatalf("Forming() ok = %v, want %v", ok, tt.forming)
+			}
+			if ok && !bar.Timestamp.Equal(tt.start) {
+				t.Errorf("bar starts %v, want %v", bar.Timestamp, tt.start)
+			}
+		})
+	}
+
+	// The last hourly bar is cut short at the close
+	a, bars := newTestAggregator(t, CandleAggregatorConfig{Timeframes: []string{"1hour"}})
+	a.OnTick(liveTick("INFY", istTime(4, 15, 20, 0), 1500, 100))
+	a.Flush(istTime(4, 15, 30, 0))
+	if len(*bars) != 1 {
+		t.Errorf("got %d bars at the close, want the 15:15 bar", len(*bars))
+	}
+}
+
+func TestCandleAggregator_LateTicksIgnored(t *testing.T) {
+	a, bars := newTestAggregator(t, CandleAggregatorConfig{Timeframes: []string{"1min"}})
+
+	a.OnTick(liveTick("INFY", istTime(4, 9, 15, 10), 1500, 1000))
+	a.OnTick(liveTick("INFY", istTime(4, 9, 16, 5), 1502, 1100))
+	a.OnTick(liveTick("INFY", istTime(4, 9, 15, 40), 1600, 1200)) // belongs to the closed 9:15 bar
+
+	latest, ok := a.Latest("INFY", "1min")
+	if !ok || latest.Candle.High != 1500 {
+		t.Errorf("closed bar = %+v, %v; late tick changed it", latest.Candle, ok)
+	}
+	forming, _ := a.Forming("INFY", "1min")
+	if forming.High != 1502 || forming.Close != 1502 {
+		t.Errorf("forming bar = %+v; late tick leaked into it", forming)
+	}
+	if len(*bars) != 1 {
+		t.Errorf("got %d closed bars, want 1", len(*bars))
+	}
+}
+
+func TestCandleAggregator_Flush(t *testing.T) {
+	a, bars := newTestAggregator(t, CandleAggregatorConfig{Timeframes: []string{"1min", "5min"}})
+
+	a.OnTick(liveTick("INFY", istTime(4, 9, 15, 10), 1500, 1000))
+	a.OnTick(liveTick("TCS", istTime(4, 9, 15, 20), 3800, 500))
+
+	a.Flush(istTime(4, 9, 15, 59))
+	if len(*bars) != 0 {
+		t.Fatalf("Flush() before the bar ends closed %d bars", len(*bars))
+	}
+
+	a.Flush(istTime(4, 9, 16, 0))
+	if len(*bars) != 2 || (*bars)[0].Symbol != "INFY" || (*bars)[1].Symbol != "TCS" {
+		t.Fatalf("Flush() closed %+v, want the INFY and TCS 1min bars", *bars)
+	}
+	if _, ok := a.Forming("INFY", "5min"); !ok {
+		t.Error("Flush() closed the 5min bar early")
+	}
+
+	// A tick for one symbol closes a quiet symbol's bar too
+	a.OnTick(liveTick("INFY", istTime(4, 9, 20, 1), 1501, 1100))
+	if _, ok := a.Latest("TCS", "5min"); !ok {
+		t.Error("quiet symbol's 5min bar did not close")
+	}
+}
+
+func TestCandleAggregator_VWAPResetsEachSession(t *testing.T) {
+	a, bars := newTestAggregator(t, CandleAggregatorConfig{
+		Timeframes: []string{"1min"},
+		Indicators: []indicators.Incremental{indicators.NewVWAP()},
+	})
+
+	// Yesterday's bar warms the VWAP up
+	a.Seed("INFY", "1min", []models.Candle{
+		{Timestamp: istTime(4, 15, 29, 0), Open: 500, High: 500, Low: 500, Close: 500, Volume: 1000},
+	})
+	if seeded, ok := a.Latest("INFY", "1min"); !ok || seeded.Values["VWAP"] != 500 {
+		t.Fatalf("seeded VWAP = %v, %v; want 500", seeded.Values["VWAP"], ok)
+	}
+
+	a.OnTick(liveTick("INFY", istTime(5, 9, 15, 5), 100, 100))
+	a.OnTick(liveTick("INFY", istTime(5, 9, 15, 30), 102, 300))
+	a.Flush(istTime(5, 9, 16, 0))
+
+	if len(*bars) != 1 {
+		t.Fatalf("got %d closed bars, want 1", len(*bars))
+	}
+	// Only today's bar counts: its typical price
+	want := (102.0 + 100 + 102) / 3
+	if got := (*bars)[0].Values["VWAP"]; math.Abs(got-want) > 1e-9 {
+		t.Errorf("VWAP = %v, want %v", got, want)
+	}
+}
+
+func TestCandleAggregator_SaveQueue(t *testing.T) {
+	s := &candleStore{started: make(chan struct{}), release: make(chan struct{})}
+	var errs []error
+	var errMu sync.Mutex
+	a, err := NewCandleAggregator(CandleAggregatorConfig{
+		Timeframes: []string{"1min"},
+		Store:      s,
+		OnError: func(err error) {
+			errMu.Lock()
+			errs = append(errs, err)
+			errMu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A closed bar reaches the store
+	a.OnTick(liveTick("INFY", istTime(4, 9, 15, 10), 1500, 1000))
+	a.Flush(istTime(4, 9, 16, 0))
+	<-s.started
+
+	// With the writer stuck, the queue fills and further bars are dropped
+	// rather than blocking the hub
+	bar := LiveBar{Symbol: "INFY", Timeframe: "1min", Candle: models.Candle{Timestamp: istTime(4, 9, 16, 0)}}
+	for i := 0; i < candleSaveBuffer+1; i++ {
+		a.save(bar)
+	}
+	errMu.Lock()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "dropped INFY 1min bar at 09:16") {
+		t.Errorf("errors = %v, want one dropped bar", errs)
+	}
+	errMu.Unlock()
+
+	// Close persists everything queued
+	close(s.release)
+	a.Close()
+
+	if len(s.saved) != candleSaveBuffer+1 {
+		t.Fatalf("saved %d bars, want %d", len(s.saved), candleSaveBuffer+1)
+	}
+	first := s.saved[0]
+	if first.Symbol != "INFY" || first.Timeframe != "1min" || !first.Candle.Timestamp.Equal(istTime(4, 9, 15, 0)) || first.Candle.Open != 1500 {
+		t.Errorf("first saved bar = %+v", first)
+	}
+}
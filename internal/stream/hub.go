@@ -44,6 +44,7 @@ type Hub struct {
 	done        chan struct{}
 	started     bool
 	consumers   []Consumer
+	ordered     []Consumer
 	consumersMu sync.RWMutex
 
 	// Metrics
@@ -136,6 +137,7 @@ func (h *Hub) broadcastLoop(ctx context.Context) {
 			h.metricsMu.Unlock()
 
 			h.broadcast(tick)
+			h.notifyOrdered(tick)
 			h.notifyConsumers(tick)
 		}
 	}
@@ -390,6 +392,15 @@ func (h *Hub) RegisterConsumer(consumer Consumer) {
 	h.consumersMu.Unlock()
 }
 
+// RegisterOrderedConsumer adds a consumer that receives ticks in arrival
+// order on the hub's distribution goroutine, for consumers such as candle
+// aggregation that depend on tick order. It must return quickly.
+func (h *Hub) RegisterOrderedConsumer(consumer Consumer) {
+	h.consumersMu.Lock()
+	h.ordered = append(h.ordered, consumer)
+	h.consumersMu.Unlock()
+}
+
 // UnregisterConsumer removes a consumer.
 func (h *Hub) UnregisterConsumer(consumer Consumer) {
 	h.consumersMu.Lock()
@@ -401,6 +412,27 @@ func (h *Hub) UnregisterConsumer(consumer Consumer) {
 			break
 		}
 	}
+	for i, c := range h.ordered {
+		if c == consumer {
+			h.ordered = append(h.ordered[:i], h.ordered[i+1:]...)
+			break
+		}
+	}
+}
+
+// notifyOrdered passes a tick to each ordered consumer in turn.
+func (h *Hub) notifyOrdered(tick models.Tick) {
+	h.consumersMu.RLock()
+	ordered := make([]Consumer, len(h.ordered))
+	copy(ordered, h.ordered)
+	h.consumersMu.RUnlock()
+
+	for _, consumer := range ordered {
+		symbols := consumer.Symbols()
+		if len(symbols) == 0 || containsSymbol(symbols, tick.Symbol) {
+			consumer.OnTick(tick)
+		}
+	}
 }
 
 // notifyConsumers sends a tick to all registered consumers.
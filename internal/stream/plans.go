@@ -228,7 +228,7 @@ func (m *PlanMonitor) Check(tick models.Tick) {
 
 	for _, state := range states {
 		m.checkPlanLevels(state, tick)
-		state.LastChecked = time.Now()
+		state.LastChecked = tickTime(tick)
 		state.LastPrice = tick.LTP
 	}
 }
@@ -306,7 +306,7 @@ func (m *PlanMonitor) notifyApproaching(state *PlanState, levelType PlanLevelTyp
 		CurrentPrice: tick.LTP,
 		Distance:     distance,
 		Approaching:  true,
-		Timestamp:    time.Now(),
+		Timestamp:    tickTime(tick),
 	}
 
 	// Mark as notified
@@ -332,7 +332,7 @@ func (m *PlanMonitor) notifyCrossed(state *PlanState, levelType PlanLevelType, l
 		CurrentPrice: tick.LTP,
 		Distance:     distance,
 		Approaching:  false,
-		Timestamp:    time.Now(),
+		Timestamp:    tickTime(tick),
 	}
 
 	// Mark as notified
@@ -0,0 +1,176 @@
+package trading
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/security"
+)
+
+// AuditedBroker is a broker.Broker decorator that records every order and
+// GTT placement, modification and cancellation in the audit log, whichever
+// command, plan or agent sent it. A failed audit write is logged but never
+// blocks the order.
+type AuditedBroker struct {
+	broker.Broker
+
+	audit  *security.AuditLogger
+	logger zerolog.Logger
+}
+
+// NewAuditedBroker wraps b so that its orders are written to audit.
+func NewAuditedBroker(b broker.Broker, audit *security.AuditLogger, logger zerolog.Logger) *AuditedBroker {
+	return &AuditedBroker{Broker: b, audit: audit, logger: logger}
+}
+
+// Unwrap returns the broker behind the audit log.
+func (a *AuditedBroker) Unwrap() broker.Broker {
+	return a.Broker
+}
+
+// PlaceOrder places the order and records the outcome. Orders the broker
+// refuses, or accepts with a REJECTED status, are logged as rejections.
+func (a *AuditedBroker) PlaceOrder(ctx context.Context, order *models.Order) (*broker.OrderResult, error) {
+	result, err := a.Broker.PlaceOrder(ctx, order)
+
+	event := orderAuditEvent(security.AuditOrderPlaced, order, err)
+	if result != nil {
+		event.OrderID = result.OrderID
+		if result.Status == models.OrderStatusRejected {
+			event.EventType = security.AuditOrderRejected
+			event.Success = false
+			event.ErrorMsg = result.Message
+		}
+	}
+	if err != nil {
+		event.EventType = security.AuditOrderRejected
+	}
+	a.log(ctx, event)
+	return result, err
+}
+
+// ModifyOrder modifies the order and records the outcome.
+func (a *AuditedBroker) ModifyOrder(ctx context.Context, orderID string, order *models.Order) error {
+	err := a.Broker.ModifyOrder(ctx, orderID, order)
+
+	event := orderAuditEvent(security.AuditOrderModified, order, err)
+	event.OrderID = orderID
+	a.log(ctx, event)
+	return err
+}
+
+// CancelOrder cancels the order and records the outcome.
+func (a *AuditedBroker) CancelOrder(ctx context.Context, orderID string) error {
+	err := a.Broker.CancelOrder(ctx, orderID)
+
+	event := security.AuditEvent{
+		EventType: security.AuditOrderCancelled,
+		OrderID:   orderID,
+		Success:   err == nil,
+	}
+	if err != nil {
+		event.ErrorMsg = err.Error()
+	}
+	a.log(ctx, event)
+	return err
+}
+
+// PlaceGTT places the GTT and records the outcome.
+func (a *AuditedBroker) PlaceGTT(ctx context.Context, gtt *models.GTTOrder) (*broker.GTTResult, error) {
+	result, err := a.Broker.PlaceGTT(ctx, gtt)
+
+	event := gttAuditEvent(security.AuditGTTPlaced, gtt, err)
+	if result != nil {
+		event.OrderID = result.TriggerID
+	}
+	a.log(ctx, event)
+	return result, err
+}
+
+// ModifyGTT modifies the GTT and records the outcome.
+func (a *AuditedBroker) ModifyGTT(ctx context.Context, gttID string, gtt *models.GTTOrder) error {
+	err := a.Broker.ModifyGTT(ctx, gttID, gtt)
+
+	event := gttAuditEvent(security.AuditGTTModified, gtt, err)
+	event.OrderID = gttID
+	a.log(ctx, event)
+	return err
+}
+
+// CancelGTT cancels the GTT and records the outcome.
+func (a *AuditedBroker) CancelGTT(ctx context.Context, gttID string) error {
+	err := a.Broker.CancelGTT(ctx, gttID)
+
+	event := security.AuditEvent{
+		EventType: security.AuditGTTCancelled,
+		OrderID:   gttID,
+		Success:   err == nil,
+	}
+	if err != nil {
+		event.ErrorMsg = err.Error()
+	}
+	a.log(ctx, event)
+	return err
+}
+
+func (a *AuditedBroker) log(ctx context.Context, event security.AuditEvent) {
+	if err := a.audit.Log(ctx, event); err != nil {
+		a.logger.Error().Err(err).Str("event", string(event.EventType)).Str("order_id", event.OrderID).Msg("Failed to write audit event")
+	}
+}
+
+func orderAuditEvent(eventType security.AuditEventType, order *models.Order, err error) security.AuditEvent {
+	event := security.AuditEvent{
+		EventType: eventType,
+		Symbol:    order.Symbol,
+		Action:    string(order.Side),
+		Success:   err == nil,
+		Details: map[string]interface{}{
+			"exchange":      order.Exchange,
+			"quantity":      order.Quantity,
+			"price":         order.Price,
+			"trigger_price": order.TriggerPrice,
+			"order_type":    order.Type,
+			"product":       order.Product,
+		},
+	}
+	if order.Tag != "" {
+		event.Details["tag"] = order.Tag
+	}
+	if err != nil {
+		event.ErrorMsg = err.Error()
+	}
+	return event
+}
+
+func gttAuditEvent(eventType security.AuditEventType, gtt *models.GTTOrder, err error) security.AuditEvent {
+	legs := make([]map[string]interface{}, len(gtt.Orders))
+	for i, leg := range gtt.Orders {
+		legs[i] = map[string]interface{}{
+			"side":          leg.Side,
+			"quantity":      leg.Quantity,
+			"price":         leg.Price,
+			"trigger_price": leg.TriggerPrice,
+			"order_type":    leg.Type,
+			"product":       leg.Product,
+		}
+	}
+	event := security.AuditEvent{
+		EventType: eventType,
+		Symbol:    gtt.Symbol,
+		Action:    gtt.TriggerType,
+		Success:   err == nil,
+		Details: map[string]interface{}{
+			"exchange":      gtt.Exchange,
+			"trigger_price": gtt.TriggerPrice,
+			"legs":          legs,
+		},
+	}
+	if err != nil {
+		event.ErrorMsg = err.Error()
+	}
+	return event
+}
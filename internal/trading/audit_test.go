@@ -0,0 +1,170 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/config"
+	apperrors "zerodha-trader/internal/errors"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/security"
+)
+
+// auditFakeBroker returns canned results for order calls.
+type auditFakeBroker struct {
+	broker.Broker
+	result    *broker.OrderResult
+	cancelErr error
+}
+
+func (b *auditFakeBroker) PlaceOrder(ctx context.Context, order *models.Order) (*broker.OrderResult, error) {
+	return b.result, nil
+}
+
+func (b *auditFakeBroker) CancelOrder(ctx context.Context, orderID string) error {
+	return b.cancelErr
+}
+
+func (b *auditFakeBroker) GetPositions(ctx context.Context) ([]models.Position, error) {
+	return nil, nil
+}
+
+func (b *auditFakeBroker) PlaceGTT(ctx context.Context, gtt *models.GTTOrder) (*broker.GTTResult, error) {
+	return &broker.GTTResult{TriggerID: "G1", Status: "active"}, nil
+}
+
+func (b *auditFakeBroker) ModifyGTT(ctx context.Context, gttID string, gtt *models.GTTOrder) error {
+	return nil
+}
+
+func (b *auditFakeBroker) CancelGTT(ctx context.Context, gttID string) error {
+	return b.cancelErr
+}
+
+func TestAuditedBrokerLogsOrders(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	audit, err := security.NewAuditLogger(security.AuditConfig{LogDir: dir, MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	inner := &auditFakeBroker{cancelErr: errors.New("order already complete")}
+	b := NewAuditedBroker(inner, audit, zerolog.Nop())
+	order := &models.Order{Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy, Type: models.OrderTypeMarket, Quantity: 10}
+
+	inner.result = &broker.OrderResult{OrderID: "1", Status: models.OrderStatusOpen}
+	if _, err := b.PlaceOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	inner.result = &broker.OrderResult{OrderID: "2", Status: models.OrderStatusRejected, Message: "insufficient funds"}
+	if _, err := b.PlaceOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CancelOrder(ctx, "1"); err == nil {
+		t.Fatal("expected the cancel error to pass through")
+	}
+
+	events, err := security.ReadAuditEvents(dir, func(security.AuditEvent) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		eventType security.AuditEventType
+		orderID   string
+		success   bool
+	}{
+		{security.AuditOrderPlaced, "1", true},
+		{security.AuditOrderRejected, "2", false},
+		{security.AuditOrderCancelled, "1", false},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d audit events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.EventType != w.eventType || e.OrderID != w.orderID || e.Success != w.success {
+			t.Errorf("event %d = %s %s success=%v, want %s %s success=%v", i, e.EventType, e.OrderID, e.Success, w.eventType, w.orderID, w.success)
+		}
+	}
+
+	// The risk gate sits in front and still sees the inner broker
+	if UnwrapBroker(NewRiskGate(b, config.RiskConfig{}, nil)) != inner {
+		t.Errorf("UnwrapBroker() did not see through the audit layer")
+	}
+}
+
+func TestGuardedBrokerAuditsGTTsAndRejections(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	audit, err := security.NewAuditLogger(security.AuditConfig{LogDir: dir, MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	inner := &auditFakeBroker{cancelErr: errors.New("gtt not found")}
+	gate := NewGuardedBroker(inner, audit, zerolog.Nop(), config.RiskConfig{}, nil)
+	gtt := &models.GTTOrder{
+		Symbol: "INFY", Exchange: models.NSE, TriggerType: "single", TriggerPrice: 1400,
+		Orders: []models.GTTOrderLeg{{Side: models.OrderSideSell, Type: models.OrderTypeLimit, Product: models.ProductCNC, Quantity: 5, Price: 1395}},
+	}
+
+	if _, err := gate.PlaceGTT(ctx, gtt); err != nil {
+		t.Fatal(err)
+	}
+	if err := gate.ModifyGTT(ctx, "G1", gtt); err != nil {
+		t.Fatal(err)
+	}
+	if err := gate.CancelGTT(ctx, "G1"); err == nil {
+		t.Fatal("expected the cancel error to pass through")
+	}
+
+	// Refused by the gate, so the broker and its audit layer never see them
+	if err := gate.SetKillSwitch(ctx, true, "test"); err != nil {
+		t.Fatal(err)
+	}
+	order := &models.Order{Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy, Type: models.OrderTypeLimit, Product: models.ProductMIS, Quantity: 10, Price: 1500}
+	if _, err := gate.PlaceOrder(ctx, order); !errors.Is(err, apperrors.ErrOrderRejected) {
+		t.Fatalf("PlaceOrder() error = %v, want a kill switch rejection", err)
+	}
+	if _, err := gate.PlaceGTT(ctx, gtt); !errors.Is(err, apperrors.ErrOrderRejected) {
+		t.Fatalf("PlaceGTT() error = %v, want a kill switch rejection", err)
+	}
+
+	events, err := security.ReadAuditEvents(dir, func(security.AuditEvent) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		eventType security.AuditEventType
+		orderID   string
+		success   bool
+	}{
+		{security.AuditGTTPlaced, "G1", true},
+		{security.AuditGTTModified, "G1", true},
+		{security.AuditGTTCancelled, "G1", false},
+		{security.AuditOrderRejected, "", false},
+		{security.AuditGTTPlaced, "", false},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d audit events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.EventType != w.eventType || e.OrderID != w.orderID || e.Success != w.success {
+			t.Errorf("event %d = %s %s success=%v, want %s %s success=%v", i, e.EventType, e.OrderID, e.Success, w.eventType, w.orderID, w.success)
+		}
+	}
+	for _, e := range events[3:] {
+		if !strings.Contains(e.ErrorMsg, "kill switch engaged: test") {
+			t.Errorf("rejection recorded as %q, want the kill switch reason", e.ErrorMsg)
+		}
+	}
+}
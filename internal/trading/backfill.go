@@ -0,0 +1,253 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/performance"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/store"
+)
+
+// KiteHistoricalRateLimit is the number of historical data requests Kite
+// allows per second.
+const KiteHistoricalRateLimit = 3
+
+// BackfillRequest describes candles to load into the store.
+type BackfillRequest struct {
+	Symbols   []string
+	Exchange  models.Exchange
+	Timeframe string
+	From      time.Time
+	To        time.Time
+	// Full refetches the whole range. By default a symbol resumes after its
+	// newest stored candle and only sessions missing before it are fetched.
+	Full bool
+	// Progress, if set, is called after every request.
+	Progress func(BackfillProgress)
+}
+
+// BackfillProgress reports one historical data request.
+type BackfillProgress struct {
+	Symbol  string
+	Chunk   int // 1-based
+	Chunks  int
+	From    time.Time
+	To      time.Time
+	Candles int
+	Err     error
+}
+
+// BackfillResult summarizes the backfill of one symbol.
+type BackfillResult struct {
+	Symbol string
+	// Resumed is the newest stored candle the fill resumed from, zero when
+	// the whole range was fetched.
+	Resumed time.Time
+	// Gaps counts the sessions before Resumed that had no candles.
+	Gaps     int
+	Requests int
+	Candles  int
+	// Missing lists the sessions in range still without candles, such as
+	// days before listing or when the symbol did not trade.
+	Missing []time.Time
+	// Err is the first failed request; later chunks are still attempted.
+	Err error
+}
+
+// BackfillManager loads historical candles into the store in chunks Kite
+// accepts, at the rate it allows, and fills sessions missing from the
+// candles table.
+type BackfillManager struct {
+	broker   broker.Broker
+	store    store.DataStore
+	limiter  *performance.RateLimiter
+	calendar *resilience.MarketHoursManager
+}
+
+// NewBackfillManager creates a backfill manager using the NSE holiday
+// calendar.
+func NewBackfillManager(b broker.Broker, dataStore store.DataStore) *BackfillManager {
+	return &BackfillManager{
+		broker:   b,
+		store:    dataStore,
+		limiter:  performance.NewRateLimiter(KiteHistoricalRateLimit, 1),
+		calendar: resilience.NewMarketHoursManager(),
+	}
+}
+
+// SetRateLimit sets the historical requests allowed per second.
+func (m *BackfillManager) SetRateLimit(perSecond float64) {
+	m.limiter = performance.NewRateLimiter(perSecond, 1)
+}
+
+// Backfill fetches and stores candles for each symbol in turn. A symbol's
+// failed requests are reported in its result; an error is returned only
+// when the store fails or ctx is done, with the results so far.
+func (m *BackfillManager) Backfill(ctx context.Context, req BackfillRequest) ([]BackfillResult, error) {
+	if _, err := TimeframeDuration(req.Timeframe); err != nil {
+		return nil, err
+	}
+	if !req.From.Before(req.To) {
+		return nil, fmt.Errorf("backfill range is empty: %s to %s",
+			req.From.Format("2006-01-02"), req.To.Format("2006-01-02"))
+	}
+
+	// Candle timestamps are stored in IST and compared as text
+	req.From = req.From.In(resilience.IndiaLocation)
+	req.To = req.To.In(resilience.IndiaLocation)
+	for year := req.From.Year(); year <= req.To.Year(); year++ {
+		resilience.InitializeHolidays(m.calendar, year)
+	}
+
+	results := make([]BackfillResult, 0, len(req.Symbols))
+	for _, symbol := range req.Symbols {
+		result, err := m.backfillSymbol(ctx, req, symbol)
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func (m *BackfillManager) backfillSymbol(ctx context.Context, req BackfillRequest, symbol string) (BackfillResult, error) {
+	result := BackfillResult{Symbol: symbol}
+	base := broker.HistoricalRequest{Symbol: symbol, Exchange: req.Exchange, Timeframe: req.Timeframe}
+
+	var ranges []broker.HistoricalRequest
+	tail := req.From
+	if !req.Full {
+		newest, err := m.store.GetCandlesFreshness(ctx, symbol, req.Timeframe)
+		if err != nil {
+			return result, err
+		}
+		if newest.After(req.From) {
+			newest = newest.In(resilience.IndiaLocation)
+			missing, err := m.MissingSessions(ctx, symbol, req.Timeframe, req.From, newest)
+			if err != nil {
+				return result, err
+			}
+			result.Resumed, result.Gaps = newest, len(missing)
+			ranges = append(ranges, m.gapRanges(base, missing)...)
+			// Refetch the newest bar too, which may have been stored unfinished
+			tail = newest
+		}
+	}
+	if tail.Before(req.To) {
+		r := base
+		r.From, r.To = tail, req.To
+		ranges = append(ranges, r)
+	}
+
+	var chunks []broker.HistoricalRequest
+	for _, r := range ranges {
+		chunks = append(chunks, r.Chunks()...)
+	}
+
+	for i, chunk := range chunks {
+		if err := m.limiter.Wait(ctx); err != nil {
+			return result, err
+		}
+		candles, err := m.broker.GetHistorical(ctx, chunk)
+		result.Requests++
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			if result.Err == nil {
+				result.Err = err
+			}
+		} else if err := m.store.SaveCandles(ctx, symbol, req.Timeframe, candles); err != nil {
+			return result, fmt.Errorf("saving %s candles: %w", symbol, err)
+		}
+		result.Candles += len(candles)
+
+		if req.Progress != nil {
+			req.Progress(BackfillProgress{
+				Symbol:  symbol,
+				Chunk:   i + 1,
+				Chunks:  len(chunks),
+				From:    chunk.From,
+				To:      chunk.To,
+				Candles: len(candles),
+				Err:     err,
+			})
+		}
+	}
+
+	missing, err := m.MissingSessions(ctx, symbol, req.Timeframe, req.From, req.To)
+	if err != nil {
+		return result, err
+	}
+	result.Missing = missing
+	return result, nil
+}
+
+// MissingSessions returns the trading days between from and to, as IST
+// midnight, on which the store has no candles for symbol.
+func (m *BackfillManager) MissingSessions(ctx context.Context, symbol, timeframe string, from, to time.Time) ([]time.Time, error) {
+	from, to = from.In(resilience.IndiaLocation), to.In(resilience.IndiaLocation)
+	candles, err := m.store.GetCandles(ctx, symbol, timeframe, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]bool)
+	for _, c := range candles {
+		stored[c.Timestamp.In(resilience.IndiaLocation).Format("2006-01-02")] = true
+	}
+
+	var missing []time.Time
+	for _, day := range m.sessions(from, to) {
+		if !stored[day.Format("2006-01-02")] {
+			missing = append(missing, day)
+		}
+	}
+	return missing, nil
+}
+
+// sessions returns IST midnight of every trading day whose 09:15–15:30
+// session overlaps from–to.
+func (m *BackfillManager) sessions(from, to time.Time) []time.Time {
+	var days []time.Time
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, resilience.IndiaLocation)
+	for ; !day.After(to); day = day.AddDate(0, 0, 1) {
+		open := day.Add(9*time.Hour + 15*time.Minute)
+		close := day.Add(15*time.Hour + 30*time.Minute)
+		if m.calendar.IsTradingDay(day) && !open.After(to) && close.After(from) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// gapRanges merges runs of consecutive missing sessions into requests
+// covering those whole days.
+func (m *BackfillManager) gapRanges(base broker.HistoricalRequest, missing []time.Time) []broker.HistoricalRequest {
+	var ranges []broker.HistoricalRequest
+	for i := 0; i < len(missing); {
+		j := i
+		for j+1 < len(missing) && m.nextSession(missing[j]).Equal(missing[j+1]) {
+			j++
+		}
+		r := base
+		r.From = missing[i]
+		r.To = missing[j].AddDate(0, 0, 1).Add(-time.Second)
+		ranges = append(ranges, r)
+		i = j + 1
+	}
+	return ranges
+}
+
+// nextSession returns the trading day after day.
+func (m *BackfillManager) nextSession(day time.Time) time.Time {
+	day = day.AddDate(0, 0, 1)
+	for !m.calendar.IsTradingDay(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
@@ -0,0 +1,99 @@
+package trading
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+	"zerodha-trader/internal/store"
+)
+
+// historyBroker serves one 09:15 candle per weekday, except days in skip,
+// and records each request.
+type historyBroker struct {
+	broker.Broker
+	skip     map[string]bool
+	requests []broker.HistoricalRequest
+}
+
+func (b *historyBroker) GetHistorical(ctx context.Context, req broker.HistoricalRequest) ([]models.Candle, error) {
+	b.requests = append(b.requests, req)
+	var candles []models.Candle
+	from := req.From.In(resilience.IndiaLocation)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, resilience.IndiaLocation)
+	for ; !day.After(req.To); day = day.AddDate(0, 0, 1) {
+		bar := day.Add(9*time.Hour + 15*time.Minute)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday || b.skip[day.Format("2006-01-02")] ||
+			bar.Before(req.From) || bar.After(req.To) {
+			continue
+		}
+		candles = append(candles, models.Candle{Timestamp: bar, Open: 100, High: 101, Low: 99, Close: 100, Volume: 1000})
+	}
+	return candles, nil
+}
+
+func TestBackfillChunksResumesAndFillsGaps(t *testing.T) {
+	ctx := context.Background()
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "trader.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	day := func(s string) time.Time {
+		d, _ := time.ParseInLocation("2006-01-02", s, resilience.IndiaLocation)
+		return d
+	}
+	b := &historyBroker{skip: map[string]bool{"2024-01-03": true}}
+	m := NewBackfillManager(b, db)
+	m.SetRateLimit(1000)
+
+	// 225 days of 5min candles takes three requests at Kite's 100-day limit
+	req := BackfillRequest{
+		Symbols: []string{"INFY"}, Exchange: models.NSE, Timeframe: "5min",
+		From: day("2023-06-01"), To: day("2024-01-12").Add(16 * time.Hour),
+	}
+	results, err := m.Backfill(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.requests) != 3 {
+		t.Fatalf("made %d requests, want 3", len(b.requests))
+	}
+	for i, r := range b.requests {
+		if r.To.Sub(r.From) > 100*24*time.Hour {
+			t.Errorf("request %d spans %v", i, r.To.Sub(r.From))
+		}
+		if i > 0 && r.From.Sub(b.requests[i-1].To) != time.Second {
+			t.Errorf("request %d does not continue request %d", i, i-1)
+		}
+	}
+	if missing := results[0].Missing; len(missing) != 1 || !missing[0].Equal(day("2024-01-03")) {
+		t.Fatalf("missing sessions %v, want 2024-01-03", missing)
+	}
+
+	// The next run resumes after the newest candle and refetches the gap
+	b.skip, b.requests = nil, nil
+	req.From, req.To = day("2024-01-01"), day("2024-01-19").Add(16*time.Hour)
+	results, err = m.Backfill(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results[0]
+	if !r.Resumed.Equal(day("2024-01-12").Add(9*time.Hour + 15*time.Minute)) {
+		t.Errorf("resumed from %v", r.Resumed)
+	}
+	if r.Gaps != 1 || len(b.requests) != 2 {
+		t.Errorf("gaps = %d, requests = %d; want 1 gap fetched before the tail", r.Gaps, len(b.requests))
+	}
+	if !b.requests[0].From.Equal(day("2024-01-03")) || !b.requests[0].To.Before(day("2024-01-04")) {
+		t.Errorf("gap request %v to %v, want 2024-01-03", b.requests[0].From, b.requests[0].To)
+	}
+	if len(r.Missing) != 0 {
+		t.Errorf("still missing %v", r.Missing)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"zerodha-trader/internal/analysis/indicators"
+	"zerodha-trader/internal/charges"
 	"zerodha-trader/internal/models"
 	"zerodha-trader/internal/store"
 )
@@ -16,16 +18,43 @@ import (
 // DefaultBacktestEngine implements the BacktestEngine interface.
 // Requirements: 37.1-37.8
 type DefaultBacktestEngine struct {
-	store store.DataStore
+	store      store.DataStore
+	strategies *StrategyRegistry
+	sessions   *SessionManager
+	charges    *charges.Calculator
 }
 
-// NewBacktestEngine creates a new backtest engine.
+// NewBacktestEngine creates a new backtest engine using the default strategy registry.
 func NewBacktestEngine(dataStore store.DataStore) *DefaultBacktestEngine {
 	return &DefaultBacktestEngine{
-		store: dataStore,
+		store:      dataStore,
+		strategies: DefaultStrategyRegistry(),
+		sessions:   NewSessionManager(),
+		charges:    charges.NewCalculator(),
 	}
 }
 
+// SetChargesCalculator replaces the calculator used for brokerage and
+// statutory charges. A nil calculator disables charges.
+func (be *DefaultBacktestEngine) SetChargesCalculator(calc *charges.Calculator) {
+	be.charges = calc
+}
+
+// SetSessionManager replaces the session manager used for intraday session rules.
+func (be *DefaultBacktestEngine) SetSessionManager(sessions *SessionManager) {
+	be.sessions = sessions
+}
+
+// SetStrategyRegistry replaces the registry used to resolve strategies.
+func (be *DefaultBacktestEngine) SetStrategyRegistry(registry *StrategyRegistry) {
+	be.strategies = registry
+}
+
+// Strategies returns the registry used to resolve strategies.
+func (be *DefaultBacktestEngine) Strategies() *StrategyRegistry {
+	return be.strategies
+}
+
 // SignalGenerator is a function that generates trading signals from candles.
 type SignalGenerator func(candles []models.Candle, index int) (signal string, confidence float64)
 
@@ -38,15 +67,83 @@ func (be *DefaultBacktestEngine) Run(ctx context.Context, config BacktestConfig)
 	}
 
 	// Fetch historical data
-	candles, err := be.store.GetCandles(ctx, config.Symbol, "1day", config.StartDate, config.EndDate)
+	candles, err := be.store.GetCandles(ctx, config.Symbol, backtestTimeframe(config), config.StartDate, config.EndDate)
 	if err != nil {
 		return nil, fmt.Errorf("fetching candles: %w", err)
 	}
 
+	var higher []models.Candle
+	if config.HigherTimeframe != "" {
+		higher, err = be.store.GetCandles(ctx, config.Symbol, config.HigherTimeframe, config.StartDate, config.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s candles: %w", config.HigherTimeframe, err)
+		}
+	}
+
+	return be.RunMultiTimeframe(ctx, config, candles, higher)
+}
+
+// RunCandles executes a backtest over the supplied candles instead of loading
+// them from the data store.
+func (be *DefaultBacktestEngine) RunCandles(ctx context.Context, config BacktestConfig, candles []models.Candle) (*BacktestResult, error) {
+	return be.RunMultiTimeframe(ctx, config, candles, nil)
+}
+
+// RunMultiTimeframe executes a backtest stepping over candles while exposing the
+// higher timeframe candles to the strategy as a filter.
+// Requires config.HigherTimeframe when higher is non-empty.
+func (be *DefaultBacktestEngine) RunMultiTimeframe(ctx context.Context, config BacktestConfig, candles, higher []models.Candle) (*BacktestResult, error) {
+	if err := be.validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	if len(candles) < 20 {
 		return nil, fmt.Errorf("insufficient data: need at least 20 candles, got %d", len(candles))
 	}
 
+	timeframe := backtestTimeframe(config)
+	barDuration, err := TimeframeDuration(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	intraday := IsIntradayTimeframe(timeframe)
+	product := backtestProduct(config)
+
+	// Resolve the strategy and build its signal generator
+	name := config.Strategy
+	if name == "" {
+		name = DefaultStrategy
+	}
+	strategy, err := be.strategies.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	params, err := ResolveParams(strategy, config.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	var signalGen SignalGenerator
+	if config.HigherTimeframe != "" {
+		mtfStrategy, ok := strategy.(MultiTimeframeStrategy)
+		if !ok {
+			return nil, fmt.Errorf("strategy %s does not support a higher timeframe", strategy.Name())
+		}
+		htf, err := NewHigherTimeframe(timeframe, candles, config.HigherTimeframe, higher)
+		if err != nil {
+			return nil, err
+		}
+		signalGen, err = mtfStrategy.PrepareMultiTimeframe(ctx, indicators.NewEngine(1), candles, htf, params)
+		if err != nil {
+			return nil, fmt.Errorf("preparing strategy %s: %w", strategy.Name(), err)
+		}
+	} else {
+		signalGen, err = strategy.Prepare(ctx, indicators.NewEngine(1), candles, params)
+		if err != nil {
+			return nil, fmt.Errorf("preparing strategy %s: %w", strategy.Name(), err)
+		}
+	}
+
 	// Initialize result
 	result := &BacktestResult{
 		EquityCurve: make([]EquityPoint, 0),
@@ -64,20 +161,47 @@ func (be *DefaultBacktestEngine) Run(ctx context.Context, config BacktestConfig)
 		maxDrawdown: 0,
 	}
 
-	// Get signal generator for strategy
-	signalGen := be.getSignalGenerator(config.Strategy, config.Parameters)
+	sessions := be.sessions
+	if sessions == nil {
+		sessions = NewSessionManager()
+	}
 
 	// Run simulation
 	// Requirement 37.2: THE backtest mode SHALL simulate trades based on agent decisions using historical prices
 	for i := 20; i < len(candles); i++ { // Start after warmup period
 		candle := candles[i]
+		canEnter := true
+
+		if candle.Timestamp.Before(config.TradeFrom) {
+			continue
+		}
+
+		if intraday {
+			// MIS positions never carry across days; square off at the previous
+			// day's last bar if the cutoff bar was missing from the data.
+			if product == models.ProductMIS && state.position != 0 && !sessions.SameTradingDay(state.entryTime, candle.Timestamp) {
+				if trade := be.closePosition(state, candles[i-1], config, string(ExitReasonMISSquareOff)); trade != nil {
+					result.Trades = append(result.Trades, *trade)
+				}
+			}
+
+			// No entries outside the normal session (pre-open, closing, post-close)
+			barClose := candle.Timestamp.Add(barDuration)
+			canEnter = sessions.GetSessionAt(candle.Timestamp).Session == SessionNormal
+
+			if product == models.ProductMIS && !barClose.Before(sessions.MISSquareOffAt(candle.Timestamp)) {
+				canEnter = false
+				if trade := be.closePosition(state, candle, config, string(ExitReasonMISSquareOff)); trade != nil {
+					result.Trades = append(result.Trades, *trade)
+				}
+			}
+		}
 
 		// Generate signal
 		signal, confidence := signalGen(candles[:i+1], i)
 
 		// Process signal
-		trade := be.processSignal(state, signal, confidence, candle, config)
-		if trade != nil {
+		for _, trade := range be.processSignal(state, signal, confidence, candle, config, canEnter) {
 			result.Trades = append(result.Trades, *trade)
 		}
 
@@ -111,6 +235,7 @@ func (be *DefaultBacktestEngine) Run(ctx context.Context, config BacktestConfig)
 		if trade != nil {
 			result.Trades = append(result.Trades, *trade)
 		}
+		state.equity = state.capital
 	}
 
 	// Calculate metrics
@@ -120,6 +245,26 @@ func (be *DefaultBacktestEngine) Run(ctx context.Context, config BacktestConfig)
 	return result, nil
 }
 
+// backtestTimeframe returns the candle timeframe for a config, defaulting to daily.
+func backtestTimeframe(config BacktestConfig) string {
+	if config.Timeframe == "" {
+		return "1day"
+	}
+	return config.Timeframe
+}
+
+// backtestProduct returns the product for a config: MIS for intraday
+// timeframes and CNC otherwise unless set explicitly.
+func backtestProduct(config BacktestConfig) models.ProductType {
+	if config.Product != "" {
+		return config.Product
+	}
+	if IsIntradayTimeframe(backtestTimeframe(config)) {
+		return models.ProductMIS
+	}
+	return models.ProductCNC
+}
+
 // backtestState holds the state during backtesting.
 type backtestState struct {
 	capital     float64
@@ -127,6 +272,7 @@ type backtestState struct {
 	position    int
 	entryPrice  float64
 	entryTime   time.Time
+	entryCosts  float64
 	peakEquity  float64
 	maxDrawdown float64
 }
@@ -148,133 +294,23 @@ func (be *DefaultBacktestEngine) validateConfig(config BacktestConfig) error {
 	if config.InitialCapital <= 0 {
 		return fmt.Errorf("initial capital must be positive")
 	}
-	return nil
-}
-
-// getSignalGenerator returns a signal generator for the given strategy.
-func (be *DefaultBacktestEngine) getSignalGenerator(strategy string, params map[string]interface{}) SignalGenerator {
-	switch strings.ToLower(strategy) {
-	case "sma_crossover":
-		return be.smaCrossoverStrategy(params)
-	case "rsi_oversold":
-		return be.rsiOversoldStrategy(params)
-	case "macd":
-		return be.macdStrategy(params)
-	default:
-		// Default to SMA crossover
-		return be.smaCrossoverStrategy(params)
+	if _, err := TimeframeDuration(backtestTimeframe(config)); err != nil {
+		return err
 	}
-}
-
-// smaCrossoverStrategy implements a simple SMA crossover strategy.
-func (be *DefaultBacktestEngine) smaCrossoverStrategy(params map[string]interface{}) SignalGenerator {
-	shortPeriod := 10
-	longPeriod := 20
-
-	if p, ok := params["short_period"].(int); ok {
-		shortPeriod = p
-	}
-	if p, ok := params["long_period"].(int); ok {
-		longPeriod = p
-	}
-
-	return func(candles []models.Candle, index int) (string, float64) {
-		if index < longPeriod {
-			return "HOLD", 0
-		}
-
-		shortSMA := be.calculateSMA(candles, index, shortPeriod)
-		longSMA := be.calculateSMA(candles, index, longPeriod)
-		prevShortSMA := be.calculateSMA(candles, index-1, shortPeriod)
-		prevLongSMA := be.calculateSMA(candles, index-1, longPeriod)
-
-		// Crossover detection
-		if prevShortSMA <= prevLongSMA && shortSMA > longSMA {
-			return "BUY", 70
-		}
-		if prevShortSMA >= prevLongSMA && shortSMA < longSMA {
-			return "SELL", 70
+	if config.HigherTimeframe != "" {
+		if _, err := TimeframeDuration(config.HigherTimeframe); err != nil {
+			return err
 		}
-
-		return "HOLD", 0
-	}
-}
-
-// rsiOversoldStrategy implements an RSI oversold/overbought strategy.
-func (be *DefaultBacktestEngine) rsiOversoldStrategy(params map[string]interface{}) SignalGenerator {
-	period := 14
-	oversold := 30.0
-	overbought := 70.0
-
-	if p, ok := params["period"].(int); ok {
-		period = p
 	}
-	if p, ok := params["oversold"].(float64); ok {
-		oversold = p
-	}
-	if p, ok := params["overbought"].(float64); ok {
-		overbought = p
-	}
-
-	return func(candles []models.Candle, index int) (string, float64) {
-		if index < period+1 {
-			return "HOLD", 0
-		}
-
-		rsi := be.calculateRSI(candles, index, period)
-		prevRSI := be.calculateRSI(candles, index-1, period)
-
-		// Buy when RSI crosses above oversold
-		if prevRSI <= oversold && rsi > oversold {
-			return "BUY", 65
-		}
-		// Sell when RSI crosses below overbought
-		if prevRSI >= overbought && rsi < overbought {
-			return "SELL", 65
-		}
-
-		return "HOLD", 0
-	}
-}
-
-// macdStrategy implements a MACD crossover strategy.
-func (be *DefaultBacktestEngine) macdStrategy(params map[string]interface{}) SignalGenerator {
-	fastPeriod := 12
-	slowPeriod := 26
-	signalPeriod := 9
-
-	if p, ok := params["fast_period"].(int); ok {
-		fastPeriod = p
-	}
-	if p, ok := params["slow_period"].(int); ok {
-		slowPeriod = p
-	}
-	if p, ok := params["signal_period"].(int); ok {
-		signalPeriod = p
-	}
-
-	return func(candles []models.Candle, index int) (string, float64) {
-		if index < slowPeriod+signalPeriod {
-			return "HOLD", 0
-		}
-
-		macd, signal := be.calculateMACD(candles, index, fastPeriod, slowPeriod, signalPeriod)
-		prevMACD, prevSignal := be.calculateMACD(candles, index-1, fastPeriod, slowPeriod, signalPeriod)
-
-		// MACD crossover
-		if prevMACD <= prevSignal && macd > signal {
-			return "BUY", 75
-		}
-		if prevMACD >= prevSignal && macd < signal {
-			return "SELL", 75
-		}
-
-		return "HOLD", 0
+	if config.AllowShort && backtestProduct(config) != models.ProductMIS {
+		return fmt.Errorf("short selling requires MIS product")
 	}
+	return nil
 }
 
-// processSignal processes a trading signal and returns a trade if executed.
-func (be *DefaultBacktestEngine) processSignal(state *backtestState, signal string, confidence float64, candle models.Candle, config BacktestConfig) *BacktestTrade {
+// processSignal processes a trading signal and returns any trades closed by it.
+// New positions are only opened when canEnter is true.
+func (be *DefaultBacktestEngine) processSignal(state *backtestState, signal string, confidence float64, candle models.Candle, config BacktestConfig, canEnter bool) []*BacktestTrade {
 	// Apply slippage to execution price
 	// Requirement 37.6: THE backtest mode SHALL account for slippage and transaction costs
 	slippage := config.Slippage
@@ -282,45 +318,60 @@ func (be *DefaultBacktestEngine) processSignal(state *backtestState, signal stri
 		slippage = 0.001 // Default 0.1% slippage
 	}
 
+	var trades []*BacktestTrade
+
 	switch signal {
-	case "BUY":
-		if state.position <= 0 {
-			// Close short position if any
-			var closeTrade *BacktestTrade
-			if state.position < 0 {
-				closeTrade = be.closePosition(state, candle, config, "signal_reversal")
+	case SignalBuy:
+		if state.position > 0 {
+			return nil
+		}
+		// Close short position if any
+		if state.position < 0 {
+			if trade := be.closePosition(state, candle, config, "signal_reversal"); trade != nil {
+				trades = append(trades, trade)
 			}
+		}
+		if canEnter {
+			be.openPosition(state, candle.Close*(1+slippage), 1, candle, config)
+		}
 
-			// Open long position
-			executionPrice := candle.Close * (1 + slippage)
-			positionSize := be.calculatePositionSize(state.capital, executionPrice, config)
-
-			if positionSize > 0 {
-				commission := executionPrice * float64(positionSize) * config.Commission
-				state.capital -= commission
-				state.position = positionSize
-				state.entryPrice = executionPrice
-				state.entryTime = candle.Timestamp
+	case SignalSell:
+		if state.position < 0 {
+			return nil
+		}
+		// Close long position if any
+		if state.position > 0 {
+			if trade := be.closePosition(state, candle, config, "signal_reversal"); trade != nil {
+				trades = append(trades, trade)
 			}
-
-			return closeTrade
 		}
+		// Shorts are intraday only
+		if canEnter && config.AllowShort {
+			be.openPosition(state, candle.Close*(1-slippage), -1, candle, config)
+		}
+	}
 
-	case "SELL":
-		if state.position >= 0 {
-			// Close long position if any
-			var closeTrade *BacktestTrade
-			if state.position > 0 {
-				closeTrade = be.closePosition(state, candle, config, "signal_reversal")
-			}
+	return trades
+}
 
-			// Open short position (if allowed)
-			// For simplicity, we only go long in this implementation
-			return closeTrade
-		}
+// openPosition opens a long (direction 1) or short (direction -1) position at price.
+func (be *DefaultBacktestEngine) openPosition(state *backtestState, price float64, direction int, candle models.Candle, config BacktestConfig) {
+	positionSize := be.calculatePositionSize(state.capital, price, config)
+	if positionSize <= 0 {
+		return
 	}
 
-	return nil
+	side := models.OrderSideBuy
+	if direction < 0 {
+		side = models.OrderSideSell
+	}
+	costs := price*float64(positionSize)*config.Commission + be.orderCharges(config, side, positionSize, price)
+
+	state.capital -= costs
+	state.entryCosts = costs
+	state.position = direction * positionSize
+	state.entryPrice = price
+	state.entryTime = candle.Timestamp
 }
 
 // closePosition closes the current position and returns the trade.
@@ -336,12 +387,14 @@ func (be *DefaultBacktestEngine) closePosition(state *backtestState, candle mode
 
 	var exitPrice float64
 	var side string
+	exitSide := models.OrderSideSell
 	if state.position > 0 {
 		exitPrice = candle.Close * (1 - slippage) // Sell at lower price
 		side = "LONG"
 	} else {
 		exitPrice = candle.Close * (1 + slippage) // Buy to cover at higher price
 		side = "SHORT"
+		exitSide = models.OrderSideBuy
 	}
 
 	qty := state.position
@@ -349,9 +402,11 @@ func (be *DefaultBacktestEngine) closePosition(state *backtestState, candle mode
 		qty = -qty
 	}
 
-	pnl := float64(state.position) * (exitPrice - state.entryPrice)
-	commission := exitPrice * float64(qty) * config.Commission
-	pnl -= commission
+	// Requirement 37.6: net P&L includes commission and statutory charges on both legs
+	grossPnL := float64(state.position) * (exitPrice - state.entryPrice)
+	exitCosts := exitPrice*float64(qty)*config.Commission + be.orderCharges(config, exitSide, qty, exitPrice)
+	totalCosts := state.entryCosts + exitCosts
+	pnl := grossPnL - totalCosts
 
 	pnlPercent := (exitPrice - state.entryPrice) / state.entryPrice * 100
 	if state.position < 0 {
@@ -366,12 +421,17 @@ func (be *DefaultBacktestEngine) closePosition(state *backtestState, candle mode
 		EntryPrice: state.entryPrice,
 		ExitPrice:  exitPrice,
 		Quantity:   qty,
+		GrossPnL:   grossPnL,
+		Charges:    totalCosts,
 		PnL:        pnl,
 		PnLPercent: pnlPercent,
+		ExitReason: reason,
 	}
 
-	// Update state
-	state.capital += pnl + (state.entryPrice * float64(qty)) // Return capital + P&L
+	// Update state; entry costs were deducted at entry and the position's
+	// notional never left capital, so only the remaining P&L is booked
+	state.capital += grossPnL - exitCosts
+	state.entryCosts = 0
 	state.position = 0
 	state.entryPrice = 0
 	state.entryTime = time.Time{}
@@ -379,6 +439,31 @@ func (be *DefaultBacktestEngine) closePosition(state *backtestState, candle mode
 	return trade
 }
 
+// orderCharges returns brokerage and statutory charges for one order leg.
+func (be *DefaultBacktestEngine) orderCharges(config BacktestConfig, side models.OrderSide, qty int, price float64) float64 {
+	if be.charges == nil || qty <= 0 {
+		return 0
+	}
+
+	exchange := config.Exchange
+	if exchange == "" {
+		exchange = models.NSE
+	}
+
+	b, err := be.charges.Calculate(charges.Trade{
+		Symbol:   config.Symbol,
+		Exchange: exchange,
+		Product:  backtestProduct(config),
+		Side:     side,
+		Quantity: qty,
+		Price:    price,
+	})
+	if err != nil {
+		return 0
+	}
+	return b.Total
+}
+
 // calculatePositionSize calculates position size based on capital.
 func (be *DefaultBacktestEngine) calculatePositionSize(capital, price float64, config BacktestConfig) int {
 	// Use 95% of capital for position
@@ -401,6 +486,8 @@ func (be *DefaultBacktestEngine) calculateMetrics(result *BacktestResult, initia
 
 	for _, trade := range result.Trades {
 		totalPnL += trade.PnL
+		result.GrossPnL += trade.GrossPnL
+		result.TotalCharges += trade.Charges
 		if trade.PnL > 0 {
 			result.WinningTrades++
 			wins = append(wins, trade.PnL)
@@ -410,6 +497,8 @@ func (be *DefaultBacktestEngine) calculateMetrics(result *BacktestResult, initia
 		}
 	}
 
+	result.NetPnL = totalPnL
+
 	// Total return
 	result.TotalReturn = (state.equity - initialCapital) / initialCapital * 100
 
@@ -460,6 +549,8 @@ func (be *DefaultBacktestEngine) calculateMetrics(result *BacktestResult, initia
 
 // calculateSharpeRatio calculates the Sharpe ratio from equity curve.
 func (be *DefaultBacktestEngine) calculateSharpeRatio(equityCurve []EquityPoint, initialCapital float64) float64 {
+	// Intraday curves are reduced to end-of-day equity before annualizing
+	equityCurve = dailyEquity(equityCurve)
 	if len(equityCurve) < 2 {
 		return 0
 	}
@@ -496,71 +587,18 @@ func (be *DefaultBacktestEngine) calculateSharpeRatio(equityCurve []EquityPoint,
 	return sharpe
 }
 
-// Helper functions for indicator calculations
-
-func (be *DefaultBacktestEngine) calculateSMA(candles []models.Candle, index, period int) float64 {
-	if index < period-1 {
-		return 0
-	}
-
-	var sum float64
-	for i := index - period + 1; i <= index; i++ {
-		sum += candles[i].Close
-	}
-	return sum / float64(period)
-}
-
-func (be *DefaultBacktestEngine) calculateEMA(candles []models.Candle, index, period int) float64 {
-	if index < period-1 {
-		return be.calculateSMA(candles, index, period)
-	}
-
-	multiplier := 2.0 / float64(period+1)
-	ema := be.calculateSMA(candles, period-1, period)
-
-	for i := period; i <= index; i++ {
-		ema = (candles[i].Close-ema)*multiplier + ema
-	}
-
-	return ema
-}
-
-func (be *DefaultBacktestEngine) calculateRSI(candles []models.Candle, index, period int) float64 {
-	if index < period {
-		return 50
-	}
-
-	var gains, losses float64
-	for i := index - period + 1; i <= index; i++ {
-		change := candles[i].Close - candles[i-1].Close
-		if change > 0 {
-			gains += change
-		} else {
-			losses -= change
+// dailyEquity keeps the last equity point of each calendar day.
+func dailyEquity(equityCurve []EquityPoint) []EquityPoint {
+	daily := make([]EquityPoint, 0, len(equityCurve))
+	for _, p := range equityCurve {
+		n := len(daily)
+		if n > 0 && sameDay(daily[n-1].Timestamp, p.Timestamp) {
+			daily[n-1] = p
+			continue
 		}
+		daily = append(daily, p)
 	}
-
-	avgGain := gains / float64(period)
-	avgLoss := losses / float64(period)
-
-	if avgLoss == 0 {
-		return 100
-	}
-
-	rs := avgGain / avgLoss
-	return 100 - (100 / (1 + rs))
-}
-
-func (be *DefaultBacktestEngine) calculateMACD(candles []models.Candle, index, fastPeriod, slowPeriod, signalPeriod int) (float64, float64) {
-	fastEMA := be.calculateEMA(candles, index, fastPeriod)
-	slowEMA := be.calculateEMA(candles, index, slowPeriod)
-	macd := fastEMA - slowEMA
-
-	// Calculate signal line (EMA of MACD)
-	// Simplified: use current MACD as signal approximation
-	signal := macd * 0.9 // Approximation
-
-	return macd, signal
+	return daily
 }
 
 // GenerateEquityCurveASCII generates an ASCII chart of the equity curve.
@@ -0,0 +1,564 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"zerodha-trader/internal/agents"
+	"zerodha-trader/internal/analysis/indicators"
+	"zerodha-trader/internal/config"
+	"zerodha-trader/internal/models"
+)
+
+// PortfolioBacktestConfig configures a backtest across several symbols that
+// share one capital pool.
+type PortfolioBacktestConfig struct {
+	Symbols        []string
+	StartDate      time.Time
+	EndDate        time.Time
+	InitialCapital float64
+	Strategy       string
+	Parameters     map[string]interface{}
+	Slippage       float64
+	Commission     float64
+	// Timeframe is the candle timeframe to step on; defaults to "1day".
+	Timeframe string
+	// Product defaults to MIS for intraday timeframes and CNC otherwise.
+	Product models.ProductType
+	// Exchange selects the exchange charge schedule; defaults to NSE.
+	Exchange models.Exchange
+	// Risk supplies max_concurrent_positions and the position sizing limits
+	// used by the risk agent. Defaults to the risk agent's defaults when nil.
+	Risk *config.RiskConfig
+}
+
+// PortfolioBacktestResult holds the results of a portfolio backtest.
+type PortfolioBacktestResult struct {
+	// Combined holds metrics, trades and the equity curve of the whole portfolio.
+	Combined *BacktestResult
+	// Symbols holds per-symbol results keyed by symbol.
+	Symbols map[string]*SymbolBacktestResult
+	// Exposure tracks gross exposure and open positions over time.
+	Exposure []ExposurePoint
+	// Correlation holds pairwise correlations of daily returns between symbols.
+	Correlation map[string]map[string]float64
+	// AvgCorrelation is the mean pairwise correlation across symbols.
+	AvgCorrelation float64
+	// UndiversifiedDrawdown is the drawdown (percent of initial capital) the
+	// portfolio would have seen had every symbol's worst drawdown coincided.
+	UndiversifiedDrawdown float64
+	// DiversificationBenefit is UndiversifiedDrawdown minus the realised max drawdown.
+	DiversificationBenefit float64
+	// MaxExposure is the peak gross exposure as a percent of equity.
+	MaxExposure float64
+	// SkippedSignals counts entry signals rejected for capital or position limits.
+	SkippedSignals int
+}
+
+// SymbolBacktestResult holds the contribution of one symbol to a portfolio backtest.
+type SymbolBacktestResult struct {
+	Symbol string
+	// EquityCurve is the cumulative realised plus unrealised P&L of the symbol.
+	EquityCurve []EquityPoint
+	Trades      []BacktestTrade
+	TotalPnL    float64
+	WinRate     float64
+	// MaxDrawdown is the largest peak-to-trough fall of the symbol's P&L, in rupees.
+	MaxDrawdown float64
+}
+
+// ExposurePoint represents portfolio exposure at a point in time.
+type ExposurePoint struct {
+	Timestamp       time.Time
+	GrossExposure   float64
+	ExposurePercent float64
+	OpenPositions   int
+}
+
+// portfolioPosition is an open position during a portfolio backtest.
+type portfolioPosition struct {
+	quantity   int
+	entryPrice float64
+	entryTime  time.Time
+	entryCost  float64
+}
+
+// portfolioSymbol holds per-symbol simulation state.
+type portfolioSymbol struct {
+	candles   []models.Candle
+	index     map[int64]int
+	signalGen SignalGenerator
+	lastPrice float64
+	position  *portfolioPosition
+	realized  float64
+	peakPnL   float64
+	result    *SymbolBacktestResult
+}
+
+// portfolioEntry is an entry signal waiting for capital allocation.
+type portfolioEntry struct {
+	symbol     string
+	confidence float64
+	candle     models.Candle
+}
+
+// RunPortfolio executes a portfolio backtest using candles from the data store.
+func (be *DefaultBacktestEngine) RunPortfolio(ctx context.Context, cfg PortfolioBacktestConfig) (*PortfolioBacktestResult, error) {
+	if err := validatePortfolioConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	timeframe := cfg.Timeframe
+	if timeframe == "" {
+		timeframe = "1day"
+	}
+
+	candles := make(map[string][]models.Candle, len(cfg.Symbols))
+	for _, symbol := range cfg.Symbols {
+		c, err := be.store.GetCandles(ctx, symbol, timeframe, cfg.StartDate, cfg.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("fetching candles for %s: %w", symbol, err)
+		}
+		candles[symbol] = c
+	}
+
+	return be.RunPortfolioCandles(ctx, cfg, candles)
+}
+
+// RunPortfolioCandles executes a portfolio backtest over the supplied candles.
+// All symbols are stepped on a common clock; on each bar exits are processed
+// first, then entry signals are ranked by confidence and allocated capital
+// until the concurrent position limit or available cash is exhausted.
+func (be *DefaultBacktestEngine) RunPortfolioCandles(ctx context.Context, cfg PortfolioBacktestConfig, candles map[string][]models.Candle) (*PortfolioBacktestResult, error) {
+	if err := validatePortfolioConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	single := BacktestConfig{
+		Symbol:         strings.Join(cfg.Symbols, ","),
+		StartDate:      cfg.StartDate,
+		EndDate:        cfg.EndDate,
+		InitialCapital: cfg.InitialCapital,
+		Strategy:       cfg.Strategy,
+		Parameters:     cfg.Parameters,
+		Slippage:       cfg.Slippage,
+		Commission:     cfg.Commission,
+		Timeframe:      cfg.Timeframe,
+		Product:        cfg.Product,
+		Exchange:       cfg.Exchange,
+	}
+	if err := be.validateConfig(single); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	timeframe := backtestTimeframe(single)
+	barDuration, _ := TimeframeDuration(timeframe)
+	intraday := IsIntradayTimeframe(timeframe)
+	product := backtestProduct(single)
+
+	slippage := cfg.Slippage
+	if slippage == 0 {
+		slippage = 0.001 // Default 0.1% slippage
+	}
+
+	riskAgent := agents.NewRiskAgent(cfg.Risk, 1.0)
+	maxPositions := len(cfg.Symbols)
+	if cfg.Risk != nil && cfg.Risk.MaxConcurrentPositions > 0 {
+		maxPositions = cfg.Risk.MaxConcurrentPositions
+	}
+
+	name := cfg.Strategy
+	if name == "" {
+		name = DefaultStrategy
+	}
+	strategy, err := be.strategies.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	params, err := ResolveParams(strategy, cfg.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare per-symbol state and the common clock
+	symbols := make(map[string]*portfolioSymbol, len(cfg.Symbols))
+	clock := make(map[int64]time.Time)
+	for _, symbol := range cfg.Symbols {
+		c := candles[symbol]
+		if len(c) < 20 {
+			return nil, fmt.Errorf("insufficient data for %s: need at least 20 candles, got %d", symbol, len(c))
+		}
+
+		signalGen, err := strategy.Prepare(ctx, indicators.NewEngine(1), c, params)
+		if err != nil {
+			return nil, fmt.Errorf("preparing strategy %s for %s: %w", strategy.Name(), symbol, err)
+		}
+
+		ps := &portfolioSymbol{
+			candles:   c,
+			index:     make(map[int64]int, len(c)),
+			signalGen: signalGen,
+			result: &SymbolBacktestResult{
+				Symbol:      symbol,
+				EquityCurve: make([]EquityPoint, 0, len(c)),
+				Trades:      make([]BacktestTrade, 0),
+			},
+		}
+		for i, candle := range c {
+			key := candle.Timestamp.UnixNano()
+			ps.index[key] = i
+			clock[key] = candle.Timestamp
+		}
+		symbols[symbol] = ps
+	}
+
+	timestamps := make([]time.Time, 0, len(clock))
+	for _, ts := range clock {
+		timestamps = append(timestamps, ts)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+
+	sessions := be.sessions
+	if sessions == nil {
+		sessions = NewSessionManager()
+	}
+
+	result := &PortfolioBacktestResult{
+		Combined: &BacktestResult{
+			EquityCurve: make([]EquityPoint, 0, len(timestamps)),
+			Trades:      make([]BacktestTrade, 0),
+		},
+		Symbols:  make(map[string]*SymbolBacktestResult, len(symbols)),
+		Exposure: make([]ExposurePoint, 0, len(timestamps)),
+	}
+
+	state := &backtestState{
+		capital:    cfg.InitialCapital,
+		equity:     cfg.InitialCapital,
+		peakEquity: cfg.InitialCapital,
+	}
+	cash := cfg.InitialCapital
+	dayStartEquity := cfg.InitialCapital
+	var lastDay time.Time
+
+	symbolConfig := func(symbol string) BacktestConfig {
+		c := single
+		c.Symbol = symbol
+		return c
+	}
+
+	closeSymbol := func(symbol string, candle models.Candle, reason string) {
+		ps := symbols[symbol]
+		pos := ps.position
+		if pos == nil {
+			return
+		}
+
+		exitPrice := candle.Close * (1 - slippage)
+		proceeds := exitPrice * float64(pos.quantity)
+		exitCosts := proceeds*cfg.Commission + be.orderCharges(symbolConfig(symbol), models.OrderSideSell, pos.quantity, exitPrice)
+		cash += proceeds - exitCosts
+
+		grossPnL := (exitPrice - pos.entryPrice) * float64(pos.quantity)
+		pnl := proceeds - exitCosts - pos.entryCost
+		trade := BacktestTrade{
+			EntryTime:  pos.entryTime,
+			ExitTime:   candle.Timestamp,
+			Symbol:     symbol,
+			Side:       "LONG",
+			EntryPrice: pos.entryPrice,
+			ExitPrice:  exitPrice,
+			Quantity:   pos.quantity,
+			GrossPnL:   grossPnL,
+			Charges:    grossPnL - pnl,
+			PnL:        pnl,
+			PnLPercent: (exitPrice - pos.entryPrice) / pos.entryPrice * 100,
+			ExitReason: reason,
+		}
+
+		ps.realized += pnl
+		ps.position = nil
+		ps.result.Trades = append(ps.result.Trades, trade)
+		result.Combined.Trades = append(result.Combined.Trades, trade)
+	}
+
+	openPositions := func() int {
+		n := 0
+		for _, ps := range symbols {
+			if ps.position != nil {
+				n++
+			}
+		}
+		return n
+	}
+
+	for _, ts := range timestamps {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if !sessions.SameTradingDay(lastDay, ts) {
+			dayStartEquity = state.equity
+			lastDay = ts
+		}
+
+		var entries []portfolioEntry
+
+		for _, symbol := range cfg.Symbols {
+			ps := symbols[symbol]
+			i, ok := ps.index[ts.UnixNano()]
+			if !ok {
+				continue
+			}
+			candle := ps.candles[i]
+			ps.lastPrice = candle.Close
+
+			canEnter := true
+			if intraday {
+				if product == models.ProductMIS && ps.position != nil && !sessions.SameTradingDay(ps.position.entryTime, ts) {
+					closeSymbol(symbol, ps.candles[i-1], string(ExitReasonMISSquareOff))
+				}
+
+				canEnter = sessions.GetSessionAt(ts).Session == SessionNormal
+				if product == models.ProductMIS && !ts.Add(barDuration).Before(sessions.MISSquareOffAt(ts)) {
+					canEnter = false
+					closeSymbol(symbol, candle, string(ExitReasonMISSquareOff))
+				}
+			}
+
+			if i < 20 { // Warmup period
+				continue
+			}
+
+			signal, confidence := ps.signalGen(ps.candles[:i+1], i)
+			switch signal {
+			case SignalSell:
+				closeSymbol(symbol, candle, "signal_reversal")
+			case SignalBuy:
+				if ps.position == nil && canEnter {
+					entries = append(entries, portfolioEntry{symbol: symbol, confidence: confidence, candle: candle})
+				}
+			}
+		}
+
+		// Allocate capital to the strongest signals first
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].confidence > entries[j].confidence
+		})
+
+		for _, entry := range entries {
+			open := openPositions()
+			if open >= maxPositions {
+				result.SkippedSignals++
+				continue
+			}
+
+			price := entry.candle.Close * (1 + slippage)
+			qty := int(riskAgent.CalculatePositionSize(agents.AnalysisRequest{
+				Symbol:       entry.symbol,
+				CurrentPrice: price,
+				Portfolio: &agents.PortfolioState{
+					TotalValue:        state.equity,
+					AvailableCash:     cash,
+					DailyPnL:          state.equity - dayStartEquity,
+					OpenPositionCount: open,
+				},
+			}))
+
+			cost := price * float64(qty)
+			entryCosts := cost*cfg.Commission + be.orderCharges(symbolConfig(entry.symbol), models.OrderSideBuy, qty, price)
+			if qty <= 0 || cost+entryCosts > cash {
+				result.SkippedSignals++
+				continue
+			}
+
+			cash -= cost + entryCosts
+			symbols[entry.symbol].position = &portfolioPosition{
+				quantity:   qty,
+				entryPrice: price,
+				entryTime:  entry.candle.Timestamp,
+				entryCost:  cost + entryCosts,
+			}
+		}
+
+		// Mark to market
+		var gross float64
+		for _, symbol := range cfg.Symbols {
+			ps := symbols[symbol]
+			pnl := ps.realized
+			if ps.position != nil {
+				value := float64(ps.position.quantity) * ps.lastPrice
+				gross += value
+				pnl += value - ps.position.entryCost
+			}
+
+			if _, ok := ps.index[ts.UnixNano()]; ok {
+				ps.result.EquityCurve = append(ps.result.EquityCurve, EquityPoint{Timestamp: ts, Equity: pnl})
+				if pnl > ps.peakPnL {
+					ps.peakPnL = pnl
+				}
+				if dd := ps.peakPnL - pnl; dd > ps.result.MaxDrawdown {
+					ps.result.MaxDrawdown = dd
+				}
+			}
+		}
+
+		equity := cash + gross
+		state.equity = equity
+		if equity > state.peakEquity {
+			state.peakEquity = equity
+		}
+		if dd := (state.peakEquity - equity) / state.peakEquity; dd > state.maxDrawdown {
+			state.maxDrawdown = dd
+		}
+
+		exposure := ExposurePoint{
+			Timestamp:     ts,
+			GrossExposure: gross,
+			OpenPositions: openPositions(),
+		}
+		if equity > 0 {
+			exposure.ExposurePercent = gross / equity * 100
+		}
+		if exposure.ExposurePercent > result.MaxExposure {
+			result.MaxExposure = exposure.ExposurePercent
+		}
+
+		result.Exposure = append(result.Exposure, exposure)
+		result.Combined.EquityCurve = append(result.Combined.EquityCurve, EquityPoint{Timestamp: ts, Equity: equity})
+	}
+
+	// Close any open positions at the end
+	for _, symbol := range cfg.Symbols {
+		ps := symbols[symbol]
+		if ps.position != nil {
+			closeSymbol(symbol, ps.candles[len(ps.candles)-1], "end_of_backtest")
+		}
+	}
+	state.capital = cash
+	state.equity = cash
+
+	sort.SliceStable(result.Combined.Trades, func(i, j int) bool {
+		return result.Combined.Trades[i].ExitTime.Before(result.Combined.Trades[j].ExitTime)
+	})
+	be.calculateMetrics(result.Combined, cfg.InitialCapital, state)
+
+	// Per-symbol summaries
+	var drawdownSum float64
+	for symbol, ps := range symbols {
+		var wins int
+		for _, t := range ps.result.Trades {
+			ps.result.TotalPnL += t.PnL
+			if t.PnL > 0 {
+				wins++
+			}
+		}
+		if len(ps.result.Trades) > 0 {
+			ps.result.WinRate = float64(wins) / float64(len(ps.result.Trades)) * 100
+		}
+		drawdownSum += ps.result.MaxDrawdown
+		result.Symbols[symbol] = ps.result
+	}
+
+	// Correlation-aware drawdown
+	result.Correlation, result.AvgCorrelation = returnCorrelations(cfg.Symbols, candles)
+	result.UndiversifiedDrawdown = drawdownSum / cfg.InitialCapital * 100
+	result.DiversificationBenefit = result.UndiversifiedDrawdown - result.Combined.MaxDrawdown
+
+	return result, nil
+}
+
+// validatePortfolioConfig validates the portfolio backtest configuration.
+func validatePortfolioConfig(cfg PortfolioBacktestConfig) error {
+	if len(cfg.Symbols) == 0 {
+		return fmt.Errorf("at least one symbol is required")
+	}
+	seen := make(map[string]bool, len(cfg.Symbols))
+	for _, symbol := range cfg.Symbols {
+		if seen[symbol] {
+			return fmt.Errorf("duplicate symbol %s", symbol)
+		}
+		seen[symbol] = true
+	}
+	return nil
+}
+
+// returnCorrelations computes pairwise correlations of per-bar close returns
+// on timestamps shared by both symbols, and the mean pairwise correlation.
+func returnCorrelations(symbols []string, candles map[string][]models.Candle) (map[string]map[string]float64, float64) {
+	returns := make(map[string]map[int64]float64, len(symbols))
+	for _, symbol := range symbols {
+		c := candles[symbol]
+		r := make(map[int64]float64, len(c))
+		for i := 1; i < len(c); i++ {
+			if c[i-1].Close > 0 {
+				r[c[i].Timestamp.UnixNano()] = c[i].Close/c[i-1].Close - 1
+			}
+		}
+		returns[symbol] = r
+	}
+
+	matrix := make(map[string]map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		matrix[symbol] = map[string]float64{symbol: 1}
+	}
+
+	var sum float64
+	var pairs int
+	for i := 0; i < len(symbols); i++ {
+		for j := i + 1; j < len(symbols); j++ {
+			a, b := returns[symbols[i]], returns[symbols[j]]
+			var xs, ys []float64
+			for ts, ra := range a {
+				if rb, ok := b[ts]; ok {
+					xs = append(xs, ra)
+					ys = append(ys, rb)
+				}
+			}
+
+			corr := correlation(xs, ys)
+			matrix[symbols[i]][symbols[j]] = corr
+			matrix[symbols[j]][symbols[i]] = corr
+			sum += corr
+			pairs++
+		}
+	}
+
+	if pairs == 0 {
+		return matrix, 0
+	}
+	return matrix, sum / float64(pairs)
+}
+
+// correlation returns the Pearson correlation of xs and ys, or 0 when undefined.
+func correlation(xs, ys []float64) float64 {
+	n := len(xs)
+	if n < 2 || n != len(ys) {
+		return 0
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
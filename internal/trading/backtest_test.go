@@ -0,0 +1,269 @@
+package trading
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/analysis/indicators"
+	"zerodha-trader/internal/config"
+	"zerodha-trader/internal/models"
+)
+
+// alwaysBuyStrategy emits BUY on every bar so session rules decide when trades happen.
+type alwaysBuyStrategy struct{}
+
+func (s *alwaysBuyStrategy) Name() string        { return "always_buy" }
+func (s *alwaysBuyStrategy) Description() string { return "Buys on every bar" }
+func (s *alwaysBuyStrategy) Params() []ParamSpec { return nil }
+
+func (s *alwaysBuyStrategy) Prepare(_ context.Context, _ *indicators.Engine, _ []models.Candle, _ Params) (SignalGenerator, error) {
+	return func(_ []models.Candle, _ int) (string, float64) {
+		return SignalBuy, 80
+	}, nil
+}
+
+// intradayCandles returns 5min candles from 09:00 to 15:25 IST for each day,
+// including pre-open bars.
+func intradayCandles(days ...time.Time) []models.Candle {
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	var candles []models.Candle
+	for _, day := range days {
+		start := time.Date(day.Year(), day.Month(), day.Day(), 9, 0, 0, 0, ist)
+		end := time.Date(day.Year(), day.Month(), day.Day(), 15, 30, 0, 0, ist)
+		for ts := start; ts.Before(end); ts = ts.Add(5 * time.Minute) {
+			price := 100 + float64(len(candles)%7)
+			candles = append(candles, models.Candle{
+				Timestamp: ts,
+				Open:      price,
+				High:      price + 0.5,
+				Low:       price - 0.5,
+				Close:     price,
+				Volume:    1000,
+			})
+		}
+	}
+	return candles
+}
+
+func TestBacktestEngine_IntradayMISSquareOff(t *testing.T) {
+	registry := NewStrategyRegistry()
+	registry.Register(&alwaysBuyStrategy{})
+
+	engine := NewBacktestEngine(nil)
+	engine.SetStrategyRegistry(registry)
+	sessions := NewSessionManager()
+
+	// Monday to Wednesday
+	candles := intradayCandles(
+		time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+	)
+
+	result, err := engine.RunCandles(context.Background(), BacktestConfig{
+		Symbol:         "TEST",
+		StartDate:      candles[0].Timestamp,
+		EndDate:        candles[len(candles)-1].Timestamp,
+		InitialCapital: 100000,
+		Strategy:       "always_buy",
+		Timeframe:      "5min",
+	}, candles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Trades) != 3 {
+		t.Fatalf("expected one squared-off trade per day, got %d", len(result.Trades))
+	}
+
+	for _, trade := range result.Trades {
+		if trade.ExitReason != string(ExitReasonMISSquareOff) {
+			t.Errorf("expected MIS square-off exit, got %s", trade.ExitReason)
+		}
+		if !sessions.SameTradingDay(trade.EntryTime, trade.ExitTime) {
+			t.Errorf("MIS trade carried across days: %v -> %v", trade.EntryTime, trade.ExitTime)
+		}
+		if session := sessions.GetSessionAt(trade.EntryTime).Session; session != SessionNormal {
+			t.Errorf("entry at %v during %s session", trade.EntryTime, session)
+		}
+		if trade.ExitTime.Add(5 * time.Minute).Before(sessions.MISSquareOffAt(trade.ExitTime)) {
+			t.Errorf("square-off at %v is before the MIS cutoff", trade.ExitTime)
+		}
+	}
+}
+
+func TestBacktestEngine_RejectsShortForDelivery(t *testing.T) {
+	engine := NewBacktestEngine(nil)
+	candles := testCandles(50)
+
+	_, err := engine.RunCandles(context.Background(), BacktestConfig{
+		Symbol:         "TEST",
+		StartDate:      candles[0].Timestamp,
+		EndDate:        candles[len(candles)-1].Timestamp,
+		InitialCapital: 100000,
+		AllowShort:     true,
+	}, candles)
+	if err == nil {
+		t.Error("expected error when shorting with CNC product")
+	}
+}
+
+func TestHigherTimeframe_NoLookahead(t *testing.T) {
+	lower := intradayCandles(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC))
+
+	var higher []models.Candle
+	for i := 0; i < len(lower); i += 3 {
+		higher = append(higher, models.Candle{Timestamp: lower[i].Timestamp, Close: float64(i)})
+	}
+
+	htf, err := NewHigherTimeframe("5min", lower, "15min", higher)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, c := range lower {
+		h := htf.Index(i)
+		if h < 0 {
+			continue
+		}
+		if htf.Candles[h].Timestamp.Add(15 * time.Minute).After(c.Timestamp.Add(5 * time.Minute)) {
+			t.Fatalf("bar %d sees higher bar %d before it closed", i, h)
+		}
+	}
+
+	if htf.Index(0) != -1 || htf.Index(1) != -1 || htf.Index(2) != 0 {
+		t.Errorf("unexpected alignment: %d %d %d", htf.Index(0), htf.Index(1), htf.Index(2))
+	}
+
+	if _, err := NewHigherTimeframe("15min", lower, "5min", higher); err == nil {
+		t.Error("expected error when higher timeframe is shorter")
+	}
+}
+
+func TestBacktestEngine_PortfolioRespectsPositionLimit(t *testing.T) {
+	engine := NewBacktestEngine(nil)
+
+	symbols := []string{"AAA", "BBB", "CCC", "DDD"}
+	candles := make(map[string][]models.Candle, len(symbols))
+	for i, symbol := range symbols {
+		c := testCandles(120)
+		for j := range c {
+			// Offset prices so symbols are not identical
+			c[j].Close += float64(i * 10)
+		}
+		candles[symbol] = c
+	}
+
+	result, err := engine.RunPortfolioCandles(context.Background(), PortfolioBacktestConfig{
+		Symbols:        symbols,
+		StartDate:      candles["AAA"][0].Timestamp,
+		EndDate:        candles["AAA"][119].Timestamp,
+		InitialCapital: 1000000,
+		Strategy:       "momentum",
+		Parameters:     map[string]interface{}{"fast_period": 3, "slow_period": 6},
+		Risk: &config.RiskConfig{
+			MaxPositionPercent:     20,
+			MaxConcurrentPositions: 2,
+			DailyLossLimit:         100000,
+		},
+	}, candles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Combined.Trades) == 0 {
+		t.Fatal("expected trades")
+	}
+	if result.SkippedSignals == 0 {
+		t.Error("expected signals to be skipped by the position limit")
+	}
+
+	for _, e := range result.Exposure {
+		if e.OpenPositions > 2 {
+			t.Fatalf("exceeded max concurrent positions: %d at %v", e.OpenPositions, e.Timestamp)
+		}
+		if e.ExposurePercent > 100 {
+			t.Fatalf("exposure above equity without leverage: %.1f%%", e.ExposurePercent)
+		}
+	}
+
+	if len(result.Symbols) != len(symbols) {
+		t.Errorf("expected per-symbol results for %d symbols, got %d", len(symbols), len(result.Symbols))
+	}
+	if result.Correlation["AAA"]["BBB"] < 0.9 {
+		t.Errorf("expected high correlation for co-moving prices, got %.2f", result.Correlation["AAA"]["BBB"])
+	}
+}
+
+func TestBacktestEngine_OptimizeWalkForward(t *testing.T) {
+	registry := NewStrategyRegistry()
+	registry.Register(&alternatingStrategy{})
+
+	engine := NewBacktestEngine(nil)
+	engine.SetStrategyRegistry(registry)
+	candles := testCandles(300)
+
+	result, err := engine.OptimizeCandles(context.Background(), OptimizeConfig{
+		Base: BacktestConfig{
+			Symbol:         "TEST",
+			InitialCapital: 100000,
+			Strategy:       "alternating",
+		},
+		Ranges:      []ParamRange{{Name: "every", Min: 2, Max: 10, Step: 2}},
+		WalkForward: &WalkForwardConfig{InSampleBars: 100, OutOfSampleBars: 50},
+		Workers:     2,
+	}, candles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Trials) != 5 {
+		t.Fatalf("expected 5 grid trials, got %d", len(result.Trials))
+	}
+	for i := 1; i < len(result.Trials); i++ {
+		if result.Trials[i].Score > result.Trials[i-1].Score {
+			t.Fatalf("trials not sorted by score")
+		}
+	}
+
+	wf := result.WalkForward
+	if wf == nil || len(wf.Windows) != 4 {
+		t.Fatalf("expected 4 walk-forward windows, got %+v", wf)
+	}
+	for _, w := range wf.Windows {
+		if !w.OutOfSampleStart.After(w.InSampleEnd) {
+			t.Errorf("out-of-sample window %v overlaps in-sample end %v", w.OutOfSampleStart, w.InSampleEnd)
+		}
+	}
+	if first := wf.EquityCurve[0].Timestamp; first.Before(wf.Windows[0].OutOfSampleStart) {
+		t.Errorf("out-of-sample equity starts in-sample at %v", first)
+	}
+	if len(wf.Stability) != 1 || wf.Stability[0].Name != "every" {
+		t.Errorf("expected stability for every, got %+v", wf.Stability)
+	}
+}
+
+func TestBacktestEngine_OptimizeLeavesRangesUntouched(t *testing.T) {
+	registry := NewStrategyRegistry()
+	registry.Register(&alternatingStrategy{})
+
+	engine := NewBacktestEngine(nil)
+	engine.SetStrategyRegistry(registry)
+
+	ranges := []ParamRange{{Name: "every", Min: 2, Max: 6}}
+	_, err := engine.OptimizeCandles(context.Background(), OptimizeConfig{
+		Base: BacktestConfig{
+			Symbol:         "TEST",
+			InitialCapital: 100000,
+			Strategy:       "alternating",
+		},
+		Ranges: ranges,
+	}, testCandles(120))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ranges[0].Step != 0 {
+		t.Errorf("caller's range step was overwritten with %v", ranges[0].Step)
+	}
+}
@@ -0,0 +1,584 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/performance"
+)
+
+// OptimizeMethod selects how the parameter space is searched.
+type OptimizeMethod string
+
+const (
+	OptimizeGrid   OptimizeMethod = "grid"
+	OptimizeRandom OptimizeMethod = "random"
+)
+
+// Optimisation objectives used to rank trials.
+const (
+	ObjectiveSharpe       = "sharpe"
+	ObjectiveReturn       = "return"
+	ObjectiveProfitFactor = "profit_factor"
+)
+
+// DefaultMaxTrials caps the number of grid combinations evaluated.
+const DefaultMaxTrials = 5000
+
+// ParamRange is a search range for one strategy parameter.
+type ParamRange struct {
+	Name string  `json:"name"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Step float64 `json:"step"`
+}
+
+// Values returns the values of the range from Min to Max in Step increments.
+func (r ParamRange) Values() []float64 {
+	if r.Step <= 0 || r.Max <= r.Min {
+		return []float64{r.Min}
+	}
+	var values []float64
+	for i := 0; ; i++ {
+		v := r.Min + float64(i)*r.Step
+		if v > r.Max+r.Step*1e-9 {
+			break
+		}
+		values = append(values, math.Round(v*1e6)/1e6)
+	}
+	return values
+}
+
+// ParseParamRange parses a "min:max:step" or "min:max" range for name.
+func ParseParamRange(name, spec string) (ParamRange, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return ParamRange{}, fmt.Errorf("range for %s must be min:max[:step], got %q", name, spec)
+	}
+
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		v, err := paramFloat(part)
+		if err != nil {
+			return ParamRange{}, fmt.Errorf("range for %s: %w", name, err)
+		}
+		values[i] = v
+	}
+
+	r := ParamRange{Name: name, Min: values[0], Max: values[1]}
+	if len(values) == 3 {
+		r.Step = values[2]
+	}
+	if r.Max < r.Min {
+		return ParamRange{}, fmt.Errorf("range for %s: max %v is below min %v", name, r.Max, r.Min)
+	}
+	return r, nil
+}
+
+// WalkForwardConfig splits the data into rolling in-sample/out-of-sample windows.
+// Each window is optimised on InSampleBars and the best parameters are then
+// traded on the following OutOfSampleBars. Windows roll forward by OutOfSampleBars.
+type WalkForwardConfig struct {
+	InSampleBars    int
+	OutOfSampleBars int
+}
+
+// OptimizeConfig configures a parameter search.
+type OptimizeConfig struct {
+	// Base is the backtest configuration shared by every trial; its Parameters
+	// are fixed values for parameters that are not searched.
+	Base BacktestConfig
+	// Ranges to search. Parameters of the strategy without a range keep their
+	// base or default value. When empty, every strategy parameter is searched
+	// over its declared Min/Max/Step.
+	Ranges    []ParamRange
+	Method    OptimizeMethod
+	Objective string
+	// Samples is the number of random draws for random search.
+	Samples int
+	// Seed makes random search reproducible; zero uses the current time.
+	Seed int64
+	// MaxTrials caps grid combinations; defaults to DefaultMaxTrials.
+	MaxTrials int
+	// Workers is the number of parallel backtests; zero uses all CPUs.
+	Workers int
+	// WalkForward enables rolling walk-forward analysis when set.
+	WalkForward *WalkForwardConfig
+}
+
+// OptimizeTrial is a single parameter set and its backtest result.
+type OptimizeTrial struct {
+	Params map[string]float64 `json:"params"`
+	Score  float64            `json:"score"`
+	// Summary metrics of Result for reporting
+	TotalReturn float64         `json:"total_return"`
+	MaxDrawdown float64         `json:"max_drawdown"`
+	TotalTrades int             `json:"total_trades"`
+	Result      *BacktestResult `json:"-"`
+	Err         string          `json:"error,omitempty"`
+}
+
+// WalkForwardWindow is the outcome of one walk-forward step.
+type WalkForwardWindow struct {
+	InSampleStart     time.Time          `json:"in_sample_start"`
+	InSampleEnd       time.Time          `json:"in_sample_end"`
+	OutOfSampleStart  time.Time          `json:"out_of_sample_start"`
+	OutOfSampleEnd    time.Time          `json:"out_of_sample_end"`
+	Params            map[string]float64 `json:"params"`
+	InSampleScore     float64            `json:"in_sample_score"`
+	InSampleReturn    float64            `json:"in_sample_return"`
+	OutOfSampleScore  float64            `json:"out_of_sample_score"`
+	OutOfSampleReturn float64            `json:"out_of_sample_return"`
+	Trades            int                `json:"trades"`
+}
+
+// ParamStability summarises how a parameter's chosen value moved across windows.
+// A high coefficient of variation means the optimum is unstable and likely overfit.
+type ParamStability struct {
+	Name   string  `json:"name"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+	CV     float64 `json:"cv"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// WalkForwardResult aggregates the out-of-sample performance of all windows.
+type WalkForwardResult struct {
+	Windows     []WalkForwardWindow `json:"windows"`
+	Stability   []ParamStability    `json:"stability"`
+	EquityCurve []EquityPoint       `json:"equity_curve"`
+	TotalReturn float64             `json:"total_return"`
+	MaxDrawdown float64             `json:"max_drawdown"`
+	TotalTrades int                 `json:"total_trades"`
+	// Efficiency is the mean out-of-sample return divided by the mean
+	// in-sample return; values well below 1 indicate overfitting.
+	Efficiency float64 `json:"efficiency"`
+}
+
+// OptimizeResult is the outcome of a parameter search.
+type OptimizeResult struct {
+	Strategy    string             `json:"strategy"`
+	Method      OptimizeMethod     `json:"method"`
+	Objective   string             `json:"objective"`
+	Trials      []OptimizeTrial    `json:"trials"`
+	Best        *OptimizeTrial     `json:"best,omitempty"`
+	WalkForward *WalkForwardResult `json:"walk_forward,omitempty"`
+}
+
+// Optimize searches strategy parameters over candles loaded from the data store.
+func (be *DefaultBacktestEngine) Optimize(ctx context.Context, config OptimizeConfig) (*OptimizeResult, error) {
+	candles, err := be.store.GetCandles(ctx, config.Base.Symbol, backtestTimeframe(config.Base), config.Base.StartDate, config.Base.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("fetching candles: %w", err)
+	}
+	return be.OptimizeCandles(ctx, config, candles)
+}
+
+// OptimizeCandles searches strategy parameters over the supplied candles. The
+// full-period search ranks every trial; with WalkForward set it also runs
+// rolling in-sample optimisation and out-of-sample evaluation.
+func (be *DefaultBacktestEngine) OptimizeCandles(ctx context.Context, config OptimizeConfig, candles []models.Candle) (*OptimizeResult, error) {
+	if config.Base.HigherTimeframe != "" {
+		return nil, fmt.Errorf("optimisation does not support a higher timeframe")
+	}
+	if len(candles) < 20 {
+		return nil, fmt.Errorf("insufficient data: need at least 20 candles, got %d", len(candles))
+	}
+
+	name := config.Base.Strategy
+	if name == "" {
+		name = DefaultStrategy
+	}
+	strategy, err := be.strategies.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	config.Base.Strategy = name
+
+	objective := config.Objective
+	if objective == "" {
+		objective = ObjectiveSharpe
+	}
+	if _, err := objectiveScore(objective, &BacktestResult{}); err != nil {
+		return nil, err
+	}
+	config.Objective = objective
+
+	method := config.Method
+	if method == "" {
+		method = OptimizeGrid
+	}
+
+	paramSets, err := be.parameterSets(strategy, config, method)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := performance.NewWorkerPool(config.Workers)
+	pool.Start()
+	defer pool.Stop()
+
+	result := &OptimizeResult{
+		Strategy:  name,
+		Method:    method,
+		Objective: objective,
+	}
+
+	result.Trials = be.runTrials(ctx, pool, config, paramSets, candles, time.Time{})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result.Best = bestTrial(result.Trials)
+	if result.Best == nil {
+		return nil, fmt.Errorf("no parameter set produced a valid backtest")
+	}
+
+	if config.WalkForward != nil {
+		wf, err := be.walkForward(ctx, pool, config, paramSets, candles)
+		if err != nil {
+			return nil, err
+		}
+		wf.Stability = paramStability(wf.Windows, config.Ranges, strategy)
+		result.WalkForward = wf
+	}
+
+	return result, nil
+}
+
+// parameterSets expands the configured ranges into concrete parameter sets.
+func (be *DefaultBacktestEngine) parameterSets(strategy Strategy, config OptimizeConfig, method OptimizeMethod) ([]map[string]interface{}, error) {
+	base, err := ResolveParams(strategy, config.Base.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy so defaulting steps below does not write into the caller's config.
+	ranges := append([]ParamRange(nil), config.Ranges...)
+	if len(ranges) == 0 {
+		for _, spec := range strategy.Params() {
+			ranges = append(ranges, ParamRange{Name: spec.Name, Min: spec.Min, Max: spec.Max, Step: spec.Step})
+		}
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("strategy %s has no parameters to optimise", strategy.Name())
+	}
+
+	specs := make(map[string]ParamSpec)
+	for _, spec := range strategy.Params() {
+		specs[spec.Name] = spec
+	}
+	for i, r := range ranges {
+		spec, ok := specs[r.Name]
+		if !ok {
+			return nil, fmt.Errorf("strategy %s has no parameter %q", strategy.Name(), r.Name)
+		}
+		if r.Min < spec.Min || r.Max > spec.Max {
+			return nil, fmt.Errorf("range for %s must be within %v and %v", r.Name, spec.Min, spec.Max)
+		}
+		if r.Step <= 0 {
+			ranges[i].Step = spec.Step
+		}
+	}
+
+	newSet := func() map[string]interface{} {
+		set := make(map[string]interface{}, len(base))
+		for k, v := range base {
+			set[k] = v
+		}
+		return set
+	}
+
+	var sets []map[string]interface{}
+	switch method {
+	case OptimizeGrid:
+		maxTrials := config.MaxTrials
+		if maxTrials <= 0 {
+			maxTrials = DefaultMaxTrials
+		}
+		total := 1
+		for _, r := range ranges {
+			total *= len(r.Values())
+			if total > maxTrials {
+				return nil, fmt.Errorf("grid has more than %d combinations; narrow the ranges or use random search", maxTrials)
+			}
+		}
+
+		sets = []map[string]interface{}{newSet()}
+		for _, r := range ranges {
+			var next []map[string]interface{}
+			for _, set := range sets {
+				for _, v := range r.Values() {
+					s := newSet()
+					for k, val := range set {
+						s[k] = val
+					}
+					s[r.Name] = v
+					next = append(next, s)
+				}
+			}
+			sets = next
+		}
+
+	case OptimizeRandom:
+		samples := config.Samples
+		if samples <= 0 {
+			samples = 100
+		}
+		seed := config.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		rng := rand.New(rand.NewSource(seed))
+
+		seen := make(map[string]bool)
+		for attempts := 0; len(sets) < samples && attempts < samples*10; attempts++ {
+			s := newSet()
+			for _, r := range ranges {
+				values := r.Values()
+				s[r.Name] = values[rng.Intn(len(values))]
+			}
+			key := fmt.Sprint(s)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			sets = append(sets, s)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown optimisation method %q (use grid or random)", method)
+	}
+
+	return sets, nil
+}
+
+// runTrials backtests every parameter set in parallel and returns the trials
+// sorted by score, best first. Invalid combinations are kept with their error.
+func (be *DefaultBacktestEngine) runTrials(ctx context.Context, pool *performance.WorkerPool, config OptimizeConfig, paramSets []map[string]interface{}, candles []models.Candle, tradeFrom time.Time) []OptimizeTrial {
+	trials := make([]OptimizeTrial, len(paramSets))
+
+	var wg sync.WaitGroup
+	for i, set := range paramSets {
+		i, set := i, set
+		task := func() {
+			defer wg.Done()
+			trials[i] = be.runTrial(ctx, config, set, candles, tradeFrom)
+		}
+
+		wg.Add(1)
+		if !pool.Submit(task) {
+			// Queue full: run on the caller so the search never drops a trial
+			task()
+		}
+	}
+	wg.Wait()
+
+	sortTrials(trials)
+	return trials
+}
+
+// runTrial runs a single backtest for a parameter set.
+func (be *DefaultBacktestEngine) runTrial(ctx context.Context, config OptimizeConfig, set map[string]interface{}, candles []models.Candle, tradeFrom time.Time) OptimizeTrial {
+	params := make(map[string]float64, len(set))
+	for k, v := range set {
+		params[k] = v.(float64)
+	}
+	trial := OptimizeTrial{Params: params}
+
+	if err := ctx.Err(); err != nil {
+		trial.Err = err.Error()
+		return trial
+	}
+
+	cfg := config.Base
+	cfg.Parameters = set
+	cfg.TradeFrom = tradeFrom
+	cfg.StartDate = candles[0].Timestamp
+	cfg.EndDate = candles[len(candles)-1].Timestamp
+
+	result, err := be.RunCandles(ctx, cfg, candles)
+	if err != nil {
+		trial.Err = err.Error()
+		return trial
+	}
+
+	trial.Result = result
+	trial.TotalReturn = result.TotalReturn
+	trial.MaxDrawdown = result.MaxDrawdown
+	trial.TotalTrades = result.TotalTrades
+	trial.Score, _ = objectiveScore(config.Objective, result)
+	return trial
+}
+
+// walkForward runs rolling in-sample optimisation and out-of-sample evaluation,
+// chaining the out-of-sample equity curves.
+func (be *DefaultBacktestEngine) walkForward(ctx context.Context, pool *performance.WorkerPool, config OptimizeConfig, paramSets []map[string]interface{}, candles []models.Candle) (*WalkForwardResult, error) {
+	wf := config.WalkForward
+	if wf.InSampleBars < 20 || wf.OutOfSampleBars < 1 {
+		return nil, fmt.Errorf("walk-forward needs at least 20 in-sample bars and 1 out-of-sample bar")
+	}
+	if len(candles) < wf.InSampleBars+wf.OutOfSampleBars {
+		return nil, fmt.Errorf("insufficient data for walk-forward: need %d candles, got %d", wf.InSampleBars+wf.OutOfSampleBars, len(candles))
+	}
+
+	result := &WalkForwardResult{}
+	capital := config.Base.InitialCapital
+	peak := capital
+	var sumIS, sumOOS float64
+
+	for start := 0; start+wf.InSampleBars < len(candles); start += wf.OutOfSampleBars {
+		isEnd := start + wf.InSampleBars
+		oosEnd := isEnd + wf.OutOfSampleBars
+		if oosEnd > len(candles) {
+			oosEnd = len(candles)
+		}
+
+		inSample := candles[start:isEnd]
+		trials := be.runTrials(ctx, pool, config, paramSets, inSample, time.Time{})
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		best := bestTrial(trials)
+		if best == nil {
+			continue
+		}
+
+		// Trade out-of-sample with the in-sample bars as indicator warmup
+		oosConfig := config
+		oosConfig.Base.InitialCapital = capital
+		set := make(map[string]interface{}, len(best.Params))
+		for k, v := range best.Params {
+			set[k] = v
+		}
+		oos := be.runTrial(ctx, oosConfig, set, candles[start:oosEnd], candles[isEnd].Timestamp)
+		if oos.Result == nil {
+			return nil, fmt.Errorf("out-of-sample window at %s: %s", candles[isEnd].Timestamp.Format("2006-01-02"), oos.Err)
+		}
+
+		for _, p := range oos.Result.EquityCurve {
+			result.EquityCurve = append(result.EquityCurve, p)
+			if p.Equity > peak {
+				peak = p.Equity
+			}
+			if dd := (peak - p.Equity) / peak * 100; dd > result.MaxDrawdown {
+				result.MaxDrawdown = dd
+			}
+		}
+		if n := len(oos.Result.EquityCurve); n > 0 {
+			capital = oos.Result.EquityCurve[n-1].Equity
+		}
+
+		result.Windows = append(result.Windows, WalkForwardWindow{
+			InSampleStart:     inSample[0].Timestamp,
+			InSampleEnd:       inSample[len(inSample)-1].Timestamp,
+			OutOfSampleStart:  candles[isEnd].Timestamp,
+			OutOfSampleEnd:    candles[oosEnd-1].Timestamp,
+			Params:            best.Params,
+			InSampleScore:     best.Score,
+			InSampleReturn:    best.Result.TotalReturn,
+			OutOfSampleScore:  oos.Score,
+			OutOfSampleReturn: oos.Result.TotalReturn,
+			Trades:            oos.Result.TotalTrades,
+		})
+		result.TotalTrades += oos.Result.TotalTrades
+		sumIS += best.Result.TotalReturn
+		sumOOS += oos.Result.TotalReturn
+	}
+
+	if len(result.Windows) == 0 {
+		return nil, fmt.Errorf("no walk-forward window produced a valid backtest")
+	}
+
+	result.TotalReturn = (capital - config.Base.InitialCapital) / config.Base.InitialCapital * 100
+	if sumIS != 0 {
+		result.Efficiency = sumOOS / sumIS
+	}
+
+	return result, nil
+}
+
+// objectiveScore returns the value of an optimisation objective; higher is better.
+func objectiveScore(objective string, result *BacktestResult) (float64, error) {
+	switch objective {
+	case ObjectiveSharpe:
+		return result.SharpeRatio, nil
+	case ObjectiveReturn:
+		return result.TotalReturn, nil
+	case ObjectiveProfitFactor:
+		return result.ProfitFactor, nil
+	default:
+		return 0, fmt.Errorf("unknown objective %q (use %s, %s or %s)", objective, ObjectiveSharpe, ObjectiveReturn, ObjectiveProfitFactor)
+	}
+}
+
+// sortTrials orders valid trials by score descending, followed by failed trials.
+func sortTrials(trials []OptimizeTrial) {
+	sort.SliceStable(trials, func(i, j int) bool {
+		if (trials[i].Result == nil) != (trials[j].Result == nil) {
+			return trials[i].Result != nil
+		}
+		return trials[i].Score > trials[j].Score
+	})
+}
+
+// bestTrial returns the highest scoring trial that traded at least once,
+// falling back to the highest scoring valid trial.
+func bestTrial(trials []OptimizeTrial) *OptimizeTrial {
+	var fallback *OptimizeTrial
+	for i := range trials {
+		if trials[i].Result == nil {
+			continue
+		}
+		if trials[i].Result.TotalTrades > 0 {
+			return &trials[i]
+		}
+		if fallback == nil {
+			fallback = &trials[i]
+		}
+	}
+	return fallback
+}
+
+// paramStability summarises the chosen value of each searched parameter across windows.
+func paramStability(windows []WalkForwardWindow, ranges []ParamRange, strategy Strategy) []ParamStability {
+	var names []string
+	for _, r := range ranges {
+		names = append(names, r.Name)
+	}
+	if len(names) == 0 {
+		for _, spec := range strategy.Params() {
+			names = append(names, spec.Name)
+		}
+	}
+
+	stability := make([]ParamStability, 0, len(names))
+	for _, name := range names {
+		s := ParamStability{Name: name, Min: math.Inf(1), Max: math.Inf(-1)}
+		for _, w := range windows {
+			v := w.Params[name]
+			s.Mean += v
+			s.Min = math.Min(s.Min, v)
+			s.Max = math.Max(s.Max, v)
+		}
+		s.Mean /= float64(len(windows))
+
+		for _, w := range windows {
+			d := w.Params[name] - s.Mean
+			s.StdDev += d * d
+		}
+		s.StdDev = math.Sqrt(s.StdDev / float64(len(windows)))
+		if s.Mean != 0 {
+			s.CV = s.StdDev / math.Abs(s.Mean)
+		}
+		stability = append(stability, s)
+	}
+	return stability
+}
@@ -0,0 +1,370 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/options"
+)
+
+// OptionStrategyBuilder builds option strategies from live option chains
+// and places them as baskets.
+type OptionStrategyBuilder struct {
+	broker   broker.Broker
+	segments *broker.SegmentManager
+	rate     float64
+}
+
+// NewOptionStrategyBuilder creates a strategy builder. Lot sizes come from
+// the segment manager, which loads NFO instruments on first use.
+func NewOptionStrategyBuilder(b broker.Broker, sm *broker.SegmentManager) *OptionStrategyBuilder {
+	return &OptionStrategyBuilder{
+		broker:   b,
+		segments: sm,
+		rate:     options.DefaultRate,
+	}
+}
+
+// Build constructs and analyzes a strategy on symbol. A zero expiry picks the
+// nearest listed expiry; calendar spreads buy their long leg in the expiry
+// after it.
+func (sb *OptionStrategyBuilder) Build(ctx context.Context, symbol string, expiry time.Time, p options.StrategyParams) (*models.OptionStrategy, error) {
+	expiries, err := sb.expiries(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	nearIdx := 0
+	if !expiry.IsZero() {
+		nearIdx = -1
+		for i, e := range expiries {
+			if sameExpiry(e, expiry) {
+				nearIdx = i
+				break
+			}
+		}
+		if nearIdx < 0 {
+			return nil, fmt.Errorf("no %s options expiring %s", symbol, expiry.Format("2006-01-02"))
+		}
+	}
+
+	near, err := sb.broker.GetOptionChain(ctx, symbol, expiries[nearIdx])
+	if err != nil {
+		return nil, fmt.Errorf("fetching option chain: %w", err)
+	}
+
+	var far *models.OptionChain
+	if p.Kind == options.CalendarSpread {
+		if nearIdx+1 >= len(expiries) {
+			return nil, fmt.Errorf("no %s expiry after %s for the calendar's long leg", symbol, expiries[nearIdx].Format("2006-01-02"))
+		}
+		far, err = sb.broker.GetOptionChain(ctx, symbol, expiries[nearIdx+1])
+		if err != nil {
+			return nil, fmt.Errorf("fetching far option chain: %w", err)
+		}
+	}
+
+	p.LotSize, err = sb.lotSize(ctx, near)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, err := options.BuildStrategy(p, near, far)
+	if err != nil {
+		return nil, err
+	}
+	options.AnalyzeStrategy(strategy, sb.rate, time.Now())
+	return strategy, nil
+}
+
+// expiries returns the upcoming option expiries for symbol, nearest first.
+func (sb *OptionStrategyBuilder) expiries(ctx context.Context, symbol string) ([]time.Time, error) {
+	instruments, err := sb.broker.GetInstruments(ctx, models.NFO)
+	if err != nil {
+		return nil, fmt.Errorf("fetching NFO instruments: %w", err)
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var expiries []time.Time
+	for _, inst := range instruments {
+		if inst.Name != symbol || (inst.InstrType != "CE" && inst.InstrType != "PE") {
+			continue
+		}
+		if options.ExpiryClose(inst.Expiry).Before(now) {
+			continue
+		}
+		key := inst.Expiry.Format("2006-01-02")
+		if !seen[key] {
+			seen[key] = true
+			expiries = append(expiries, inst.Expiry)
+		}
+	}
+	if len(expiries) == 0 {
+		return nil, fmt.Errorf("no options listed for %s", symbol)
+	}
+
+	sort.Slice(expiries, func(i, j int) bool { return expiries[i].Before(expiries[j]) })
+	return expiries, nil
+}
+
+// lotSize looks up the contract lot size of any option on the chain; all
+// strikes and expiries of an underlying share it.
+func (sb *OptionStrategyBuilder) lotSize(ctx context.Context, chain *models.OptionChain) (int, error) {
+	var symbol string
+	for _, s := range chain.Strikes {
+		if s.Call != nil && s.Call.Symbol != "" {
+			symbol = s.Call.Symbol
+			break
+		}
+		if s.Put != nil && s.Put.Symbol != "" {
+			symbol = s.Put.Symbol
+			break
+		}
+	}
+	if symbol == "" {
+		return 0, fmt.Errorf("option chain for %s has no contracts", chain.Symbol)
+	}
+
+	if _, err := sb.segments.GetInstrument(models.NFO, symbol); err != nil {
+		if err := sb.segments.LoadInstruments(ctx, broker.SegmentNSEFO); err != nil {
+			return 0, err
+		}
+	}
+	return sb.segments.GetLotSize(models.NFO, symbol), nil
+}
+
+// FromPositions collects the open NFO positions on symbol into a strategy,
+// so payoffs can be drawn for what is actually held. Legs are priced at the
+// position's average price and carry the IV implied by its LTP. symbol may
+// be empty when every open position shares one underlying.
+func (sb *OptionStrategyBuilder) FromPositions(ctx context.Context, symbol string) (*models.OptionStrategy, error) {
+	positions, err := sb.broker.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching positions: %w", err)
+	}
+	contracts, err := nfoContracts(ctx, sb.broker)
+	if err != nil {
+		return nil, err
+	}
+
+	type held struct {
+		pos  models.Position
+		inst models.Instrument
+	}
+	var open []held
+	underlyings := make(map[string]bool)
+	for _, pos := range positions {
+		if pos.Exchange != models.NFO || pos.Quantity == 0 {
+			continue
+		}
+		inst, ok := contracts[pos.Symbol]
+		if !ok || (symbol != "" && inst.Name != symbol) {
+			continue
+		}
+		open = append(open, held{pos, inst})
+		underlyings[inst.Name] = true
+	}
+	if len(open) == 0 {
+		return nil, fmt.Errorf("no open NFO positions")
+	}
+	if len(underlyings) > 1 {
+		return nil, fmt.Errorf("positions span %d underlyings; choose one with a symbol", len(underlyings))
+	}
+	symbol = open[0].inst.Name
+
+	spot, err := sb.broker.GetQuote(ctx, options.UnderlyingQuoteSymbol(symbol))
+	if err != nil {
+		return nil, fmt.Errorf("fetching underlying quote: %w", err)
+	}
+
+	now := time.Now()
+	strategy := &models.OptionStrategy{
+		Name:    "positions",
+		Symbol:  symbol,
+		Spot:    spot.LTP,
+		LotSize: open[0].inst.LotSize,
+	}
+	for _, h := range open {
+		leg := models.OptionLeg{
+			Symbol:   h.pos.Symbol,
+			Strike:   h.inst.Strike,
+			Type:     options.FutureLeg,
+			Expiry:   h.inst.Expiry,
+			Side:     models.OrderSideBuy,
+			Quantity: h.pos.Quantity,
+			Premium:  h.pos.AveragePrice,
+		}
+		if leg.Quantity < 0 {
+			leg.Side = models.OrderSideSell
+			leg.Quantity = -leg.Quantity
+		}
+
+		if kind, err := options.ParseKind(h.inst.InstrType); err == nil {
+			leg.Type = kind.LegType()
+			vol, err := options.ImpliedVol(options.Inputs{
+				Kind:       kind,
+				Underlying: spot.LTP,
+				Strike:     h.inst.Strike,
+				T:          options.YearsToExpiry(now, h.inst.Expiry),
+				Rate:       sb.rate,
+			}, h.pos.LTP)
+			if err == nil {
+				leg.IV = vol * 100
+			}
+		}
+		strategy.Legs = append(strategy.Legs, leg)
+	}
+
+	options.AnalyzeStrategy(strategy, sb.rate, now)
+	return strategy, nil
+}
+
+// StrategyBasket turns a strategy into a basket of NFO orders. Buy legs come
+// first so the hedges are in place before the short legs add margin.
+// Limit orders are placed at each leg's premium; market orders otherwise.
+func StrategyBasket(s *models.OptionStrategy, product models.ProductType, limit bool) *BasketOrder {
+	orders := make([]models.Order, 0, len(s.Legs))
+	var total float64
+	for _, leg := range s.Legs {
+		order := models.Order{
+			Symbol:   leg.Symbol,
+			Exchange: models.NFO,
+			Side:     leg.Side,
+			Type:     models.OrderTypeMarket,
+			Product:  product,
+			Quantity: leg.Quantity,
+			Validity: models.ValidityDay,
+			Tag:      "strategy",
+		}
+		if limit {
+			order.Type = models.OrderTypeLimit
+			order.Price = leg.Premium
+		}
+		orders = append(orders, order)
+		total += leg.Premium * float64(leg.Quantity)
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].Side == models.OrderSideBuy && orders[j].Side != models.OrderSideBuy
+	})
+
+	return &BasketOrder{
+		BasketID:   fmt.Sprintf("%s-%s", s.Symbol, s.Name),
+		Orders:     orders,
+		TotalValue: total,
+		Status:     "PENDING",
+	}
+}
+
+// PlaceStrategy places the legs of a strategy one at a time, buy legs
+// first, recording each leg's order ID. If a leg errors or is rejected, no
+// further legs are placed and the legs already placed are cancelled or, once
+// filled, exited at market, so a short leg is never left without its hedge.
+// The returned basket reflects what was placed either way.
+func (sb *OptionStrategyBuilder) PlaceStrategy(ctx context.Context, s *models.OptionStrategy, product models.ProductType, limit bool) (*BasketOrder, error) {
+	basket := StrategyBasket(s, product, limit)
+
+	for i := range basket.Orders {
+		order := &basket.Orders[i]
+		result, err := sb.broker.PlaceOrder(ctx, order)
+		if result != nil {
+			order.ID = result.OrderID
+			order.Status = result.Status
+			order.StatusMessage = result.Message
+		}
+		if err == nil && order.Status == models.OrderStatusRejected {
+			err = fmt.Errorf("rejected: %s", order.StatusMessage)
+		}
+		if err == nil {
+			continue
+		}
+
+		basket.Status = "FAILED"
+		err = fmt.Errorf("placing %s %s: %w", order.Side, order.Symbol, err)
+		if uerr := sb.unwindLegs(ctx, basket.Orders[:i]); uerr != nil {
+			return basket, fmt.Errorf("%w; unwinding placed legs: %v", err, uerr)
+		}
+		return basket, err
+	}
+
+	now := time.Now()
+	basket.ExecutedAt = &now
+	basket.Status = "EXECUTED"
+	return basket, nil
+}
+
+// unwindLegs cancels the legs of a partly placed strategy that are still
+// working and exits whatever of them has filled, shorts before hedges.
+func (sb *OptionStrategyBuilder) unwindLegs(ctx context.Context, legs []models.Order) error {
+	if len(legs) == 0 {
+		return nil
+	}
+
+	orders, err := sb.broker.GetOrders(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching orders: %w", err)
+	}
+	current := make(map[string]models.Order, len(orders))
+	for _, o := range orders {
+		current[o.ID] = o
+	}
+
+	var errs []error
+	for i := len(legs) - 1; i >= 0; i-- {
+		leg := legs[i]
+		o, ok := current[leg.ID]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s %s: order %s not found", leg.Side, leg.Symbol, leg.ID))
+			continue
+		}
+
+		if o.Status == models.OrderStatusOpen || o.Status == models.OrderStatusTriggerPending {
+			if err := sb.broker.CancelOrder(ctx, o.ID); err != nil {
+				errs = append(errs, fmt.Errorf("cancelling %s %s: %w", leg.Side, leg.Symbol, err))
+			}
+		}
+
+		filled := o.FilledQty
+		if filled == 0 && o.Status == models.OrderStatusComplete {
+			filled = o.Quantity
+		}
+		if filled == 0 {
+			continue
+		}
+
+		side := models.OrderSideSell
+		if leg.Side == models.OrderSideSell {
+			side = models.OrderSideBuy
+		}
+		result, err := sb.broker.PlaceOrder(ctx, &models.Order{
+			Symbol:   leg.Symbol,
+			Exchange: leg.Exchange,
+			Side:     side,
+			Type:     models.OrderTypeMarket,
+			Product:  leg.Product,
+			Quantity: filled,
+			Validity: models.ValidityDay,
+			Tag:      "strategy_unwind",
+		})
+		if err == nil && result.Status == models.OrderStatusRejected {
+			err = fmt.Errorf("rejected: %s", result.Message)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("exiting %s %s: %w", leg.Side, leg.Symbol, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+func sameExpiry(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
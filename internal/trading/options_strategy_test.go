@@ -0,0 +1,106 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/models"
+)
+
+// legBroker fills every order at once, except orders for symbols in reject,
+// which come back REJECTED. Methods the builder does not use panic through
+// the nil embedded interface.
+type legBroker struct {
+	broker.Broker
+	reject    map[string]bool
+	orders    []models.Order
+	cancelled []string
+}
+
+func (b *legBroker) PlaceOrder(ctx context.Context, order *models.Order) (*broker.OrderResult, error) {
+	o := *order
+	o.ID = fmt.Sprintf("%d", len(b.orders)+1)
+	o.Status, o.FilledQty = models.OrderStatusComplete, o.Quantity
+	if b.reject[o.Symbol] {
+		o.Status, o.StatusMessage, o.FilledQty = models.OrderStatusRejected, "insufficient margin", 0
+	}
+	b.orders = append(b.orders, o)
+	return &broker.OrderResult{OrderID: o.ID, Status: o.Status, Message: o.StatusMessage}, nil
+}
+
+func (b *legBroker) GetOrders(ctx context.Context) ([]models.Order, error) {
+	return b.orders, nil
+}
+
+func (b *legBroker) CancelOrder(ctx context.Context, orderID string) error {
+	b.cancelled = append(b.cancelled, orderID)
+	return nil
+}
+
+func bullCallSpread() *models.OptionStrategy {
+	return &models.OptionStrategy{
+		Name:   "bull_call_spread",
+		Symbol: "NIFTY",
+		Legs: []models.OptionLeg{
+			{Symbol: "NIFTY24MAR22100CE", Strike: 22100, Type: "CALL", Side: models.OrderSideSell, Quantity: 50, Premium: 60},
+			{Symbol: "NIFTY24MAR22000CE", Strike: 22000, Type: "CALL", Side: models.OrderSideBuy, Quantity: 50, Premium: 100},
+		},
+	}
+}
+
+func TestPlaceStrategy_RecordsOrderIDsHedgeFirst(t *testing.T) {
+	b := &legBroker{}
+	basket, err := NewOptionStrategyBuilder(b, nil).PlaceStrategy(context.Background(), bullCallSpread(), models.ProductNRML, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if basket.Status != "EXECUTED" || len(basket.Orders) != 2 {
+		t.Fatalf("basket %s with %d orders, want EXECUTED with 2", basket.Status, len(basket.Orders))
+	}
+	if basket.Orders[0].Side != models.OrderSideBuy {
+		t.Errorf("first leg placed is %s, want the BUY hedge", basket.Orders[0].Side)
+	}
+	for i, o := range basket.Orders {
+		if o.ID != b.orders[i].ID || o.Status != models.OrderStatusComplete {
+			t.Errorf("leg %d recorded ID %q status %q, want %q COMPLETE", i, o.ID, o.Status, b.orders[i].ID)
+		}
+		if o.Validity != models.ValidityDay {
+			t.Errorf("leg %d validity %q", i, o.Validity)
+		}
+	}
+}
+
+func TestPlaceStrategy_RejectedLegUnwindsPlacedLegs(t *testing.T) {
+	b := &legBroker{reject: map[string]bool{"NIFTY24MAR22100CE": true}}
+	basket, err := NewOptionStrategyBuilder(b, nil).PlaceStrategy(context.Background(), bullCallSpread(), models.ProductNRML, false)
+	if err == nil {
+		t.Fatal("expected the rejected short leg to fail the strategy")
+	}
+	if basket.Status != "FAILED" {
+		t.Errorf("basket status %s, want FAILED", basket.Status)
+	}
+
+	// hedge buy, rejected short, then the hedge sold back at market
+	if len(b.orders) != 3 {
+		t.Fatalf("placed %d orders, want 3", len(b.orders))
+	}
+	exit := b.orders[2]
+	if exit.Symbol != "NIFTY24MAR22000CE" || exit.Side != models.OrderSideSell || exit.Quantity != 50 ||
+		exit.Type != models.OrderTypeMarket || exit.Product != models.ProductNRML {
+		t.Errorf("unwind order %+v, want market SELL 50 NIFTY24MAR22000CE NRML", exit)
+	}
+}
+
+func TestPlaceStrategy_FirstLegRejectedPlacesNothingElse(t *testing.T) {
+	b := &legBroker{reject: map[string]bool{"NIFTY24MAR22000CE": true}}
+	_, err := NewOptionStrategyBuilder(b, nil).PlaceStrategy(context.Background(), bullCallSpread(), models.ProductNRML, false)
+	if err == nil {
+		t.Fatal("expected the rejected hedge to fail the strategy")
+	}
+	if len(b.orders) != 1 {
+		t.Errorf("placed %d orders after the hedge was rejected, want 1", len(b.orders))
+	}
+}
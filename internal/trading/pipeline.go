@@ -24,14 +24,17 @@ type DecisionPipeline struct {
 	riskConfig   *config.RiskConfig
 	store        store.DataStore
 	notifier     Notifier
+	risk         *agents.RiskState
+
+	// riskLoaded is set once risk holds today's counters: at construction
+	// when shared with the orchestrator, which loads them on Start, and
+	// otherwise on first use.
+	riskMu     sync.Mutex
+	riskLoaded bool
 
 	// State tracking
-	mu                sync.RWMutex
-	dailyTrades       int
-	dailyLoss         float64
-	lastTradeAt       time.Time
-	consecutiveLosses int
-	decisionHistory   []DecisionRecord
+	mu              sync.RWMutex
+	decisionHistory []DecisionRecord
 }
 
 // Notifier defines the interface for sending notifications.
@@ -53,7 +56,9 @@ type DecisionRecord struct {
 	Timestamp  time.Time
 }
 
-// NewDecisionPipeline creates a new decision pipeline.
+// NewDecisionPipeline creates a new decision pipeline. It shares the
+// orchestrator's daily risk counters, so limits hit through either apply to
+// both.
 func NewDecisionPipeline(
 	orchestrator *agents.Orchestrator,
 	riskAgent *agents.RiskAgent,
@@ -62,8 +67,14 @@ func NewDecisionPipeline(
 	dataStore store.DataStore,
 	notifier Notifier,
 ) *DecisionPipeline {
+	risk := agents.NewRiskState(dataStore, nil)
+	if orchestrator != nil {
+		risk = orchestrator.RiskState()
+	}
 	return &DecisionPipeline{
 		orchestrator:    orchestrator,
+		risk:            risk,
+		riskLoaded:      orchestrator != nil,
 		riskAgent:       riskAgent,
 		config:          agentConfig,
 		riskConfig:      riskConfig,
@@ -146,7 +157,7 @@ func (p *DecisionPipeline) Process(ctx context.Context, input PipelineInput) (*P
 	}
 
 	// Step 4: Determine if we should execute
-	shouldExecute, blockReason := p.shouldExecute(decision)
+	shouldExecute, blockReason := p.shouldExecute(ctx, decision)
 	output.ShouldExecute = shouldExecute
 	output.ExecutionBlock = blockReason
 	decision.Executed = shouldExecute
@@ -244,7 +255,7 @@ func (p *DecisionPipeline) validateAgainstRisk(ctx context.Context, decision *mo
 // shouldExecute determines if a decision should be auto-executed.
 // Requirements: 26.1-26.12, 62.1-62.6
 // Property 8: Auto-execute only when confidence >= threshold and risk approved
-func (p *DecisionPipeline) shouldExecute(decision *models.Decision) (bool, string) {
+func (p *DecisionPipeline) shouldExecute(ctx context.Context, decision *models.Decision) (bool, string) {
 	if p.config == nil {
 		return false, "agent config not configured"
 	}
@@ -280,12 +291,14 @@ func (p *DecisionPipeline) shouldExecute(decision *models.Decision) (bool, strin
 	}
 
 	// Check 4: Daily trade limit
-	p.mu.RLock()
-	dailyTrades := p.dailyTrades
-	dailyLoss := p.dailyLoss
-	lastTradeAt := p.lastTradeAt
-	consecutiveLosses := p.consecutiveLosses
-	p.mu.RUnlock()
+	if err := p.loadRisk(ctx); err != nil {
+		return false, fmt.Sprintf("restoring daily risk counters: %v", err)
+	}
+	counters := p.risk.Counters()
+	dailyTrades := counters.Trades
+	dailyLoss := counters.Loss
+	lastTradeAt := counters.LastTradeAt
+	consecutiveLosses := counters.ConsecutiveLosses
 
 	if dailyTrades >= p.config.MaxDailyTrades {
 		return false, fmt.Sprintf("daily trade limit reached: %d trades", dailyTrades)
@@ -387,16 +400,9 @@ func (p *DecisionPipeline) RecordTradeOutcome(decisionID string, outcome models.
 		}
 	}
 
-	// Update daily counters
-	if pnl < 0 {
-		p.dailyLoss += -pnl
-		p.consecutiveLosses++
-	} else {
-		p.consecutiveLosses = 0
-	}
-
-	p.dailyTrades++
-	p.lastTradeAt = time.Now()
+	// Update daily counters; a failed restore is retried on the next check
+	_ = p.loadRisk(context.Background())
+	p.risk.RecordTrade(pnl)
 }
 
 // GetAccuracyStats returns AI decision accuracy statistics.
@@ -492,26 +498,46 @@ func getConfidenceRange(confidence float64) string {
 	}
 }
 
+// loadRisk restores today's counters from the store the first time they are
+// needed, so a pipeline running without the orchestrator keeps its daily
+// limits across restarts.
+func (p *DecisionPipeline) loadRisk(ctx context.Context) error {
+	p.riskMu.Lock()
+	defer p.riskMu.Unlock()
+	if p.riskLoaded {
+		return nil
+	}
+	if err := p.risk.Load(ctx); err != nil {
+		return err
+	}
+	p.riskLoaded = true
+	return nil
+}
+
+// markRiskLoaded stops a later loadRisk from overwriting counters set
+// explicitly.
+func (p *DecisionPipeline) markRiskLoaded() {
+	p.riskMu.Lock()
+	p.riskLoaded = true
+	p.riskMu.Unlock()
+}
+
 // ResetDailyCounters resets daily tracking counters.
 func (p *DecisionPipeline) ResetDailyCounters() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	p.dailyTrades = 0
-	p.dailyLoss = 0
-	p.consecutiveLosses = 0
+	p.markRiskLoaded()
+	p.risk.Reset()
 }
 
 // GetDailyStats returns current daily statistics.
 func (p *DecisionPipeline) GetDailyStats() DailyStats {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	_ = p.loadRisk(context.Background())
+	counters := p.risk.Counters()
 
 	return DailyStats{
-		Trades:            p.dailyTrades,
-		Loss:              p.dailyLoss,
-		ConsecutiveLosses: p.consecutiveLosses,
-		LastTradeAt:       p.lastTradeAt,
+		Trades:            counters.Trades,
+		Loss:              counters.Loss,
+		ConsecutiveLosses: counters.ConsecutiveLosses,
+		LastTradeAt:       counters.LastTradeAt,
 	}
 }
 
@@ -525,13 +551,8 @@ type DailyStats struct {
 
 // SetDailyStats sets daily statistics (for testing or state recovery).
 func (p *DecisionPipeline) SetDailyStats(trades int, loss float64, consecutiveLosses int, lastTradeAt time.Time) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	p.dailyTrades = trades
-	p.dailyLoss = loss
-	p.consecutiveLosses = consecutiveLosses
-	p.lastTradeAt = lastTradeAt
+	p.markRiskLoaded()
+	p.risk.Set(trades, loss, consecutiveLosses, lastTradeAt)
 }
 
 // CanTrade checks if trading is currently allowed.
@@ -540,19 +561,21 @@ func (p *DecisionPipeline) CanTrade() (bool, string) {
 		return false, "agent config not configured"
 	}
 
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	if err := p.loadRisk(context.Background()); err != nil {
+		return false, fmt.Sprintf("restoring daily risk counters: %v", err)
+	}
+	counters := p.risk.Counters()
 
-	if p.dailyTrades >= p.config.MaxDailyTrades {
-		return false, fmt.Sprintf("daily trade limit reached: %d", p.dailyTrades)
+	if counters.Trades >= p.config.MaxDailyTrades {
+		return false, fmt.Sprintf("daily trade limit reached: %d", counters.Trades)
 	}
 
-	if p.dailyLoss >= p.config.MaxDailyLoss {
-		return false, fmt.Sprintf("daily loss limit reached: ₹%.2f", p.dailyLoss)
+	if counters.Loss >= p.config.MaxDailyLoss {
+		return false, fmt.Sprintf("daily loss limit reached: ₹%.2f", counters.Loss)
 	}
 
-	if p.config.ConsecutiveLossLimit > 0 && p.consecutiveLosses >= p.config.ConsecutiveLossLimit {
-		return false, fmt.Sprintf("consecutive loss limit reached: %d", p.consecutiveLosses)
+	if p.config.ConsecutiveLossLimit > 0 && counters.ConsecutiveLosses >= p.config.ConsecutiveLossLimit {
+		return false, fmt.Sprintf("consecutive loss limit reached: %d", counters.ConsecutiveLosses)
 	}
 
 	return true, ""
@@ -0,0 +1,36 @@
+package trading
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/config"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/store"
+)
+
+func TestDecisionPipeline_RestoresDailyCountersWithoutOrchestrator(t *testing.T) {
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "trader.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Trades logged before the restart
+	at := time.Now().Add(-time.Second)
+	db.LogTrade(context.Background(), &models.Trade{ID: "t1", Timestamp: at, Symbol: "INFY", PnL: -600})
+	db.LogTrade(context.Background(), &models.Trade{ID: "t2", Timestamp: at, Symbol: "TCS", PnL: -500})
+
+	pipeline := NewDecisionPipeline(nil, nil, &config.AgentConfig{MaxDailyTrades: 10, MaxDailyLoss: 1000}, nil, db, nil)
+
+	if stats := pipeline.GetDailyStats(); stats.Trades != 2 || stats.Loss != 1100 {
+		t.Errorf("restored %+v, want 2 trades and 1100 loss", stats)
+	}
+	if ok, reason := pipeline.CanTrade(); ok {
+		t.Error("expected the restored loss to block trading")
+	} else if reason == "" {
+		t.Error("expected a block reason")
+	}
+}
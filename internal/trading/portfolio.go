@@ -7,9 +7,12 @@ import (
 	"math"
 	"sort"
 	"sync"
+	"time"
 
 	"zerodha-trader/internal/broker"
 	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/options"
+	"zerodha-trader/internal/store"
 )
 
 // DefaultPortfolioAnalyzer implements the PortfolioAnalyzer interface.
@@ -22,17 +25,22 @@ type DefaultPortfolioAnalyzer struct {
 	// Sector mappings (symbol -> sector)
 	sectorMap map[string]string
 
-	// Index data for beta calculation
-	indexReturns map[string][]float64
+	// Daily candles for beta, covariance and volatility
+	store     store.DataStore
+	benchmark string
+	lookback  int
 }
 
-// NewPortfolioAnalyzer creates a new portfolio analyzer.
-func NewPortfolioAnalyzer(b broker.Broker, pm *DefaultPositionManager) *DefaultPortfolioAnalyzer {
+// NewPortfolioAnalyzer creates a new portfolio analyzer. Historical risk
+// (beta, volatility, VaR) is computed from daily candles in s.
+func NewPortfolioAnalyzer(b broker.Broker, pm *DefaultPositionManager, s store.DataStore) *DefaultPortfolioAnalyzer {
 	return &DefaultPortfolioAnalyzer{
 		broker:          b,
 		positionManager: pm,
 		sectorMap:       make(map[string]string),
-		indexReturns:    make(map[string][]float64),
+		store:           s,
+		benchmark:       DefaultBenchmark,
+		lookback:        DefaultRiskLookback,
 	}
 }
 
@@ -159,140 +167,118 @@ func (pa *DefaultPortfolioAnalyzer) GetPortfolioGreeks(ctx context.Context) (*Po
 	}
 
 	greeks := &PortfolioGreeks{}
+	now := time.Now()
 
+	var contracts map[string]models.Instrument
 	for _, pos := range positions {
 		// Only F&O positions have Greeks
-		if pos.Exchange != models.NFO {
+		if pos.Exchange != models.NFO || pos.Quantity == 0 {
 			continue
 		}
 
-		// Get option chain to fetch Greeks
-		// Note: In a real implementation, we'd fetch Greeks from the broker
-		// For now, we'll use placeholder logic
-		optionGreeks := pa.estimateGreeks(pos)
+		if contracts == nil {
+			contracts, err = nfoContracts(ctx, pa.broker)
+			if err != nil {
+				return nil, err
+			}
+		}
+		inst, ok := contracts[pos.Symbol]
+		if !ok {
+			return nil, fmt.Errorf("unknown NFO contract %s", pos.Symbol)
+		}
+
+		optionGreeks, err := pa.contractGreeks(ctx, pos, inst, now)
+		if err != nil {
+			return nil, fmt.Errorf("greeks for %s: %w", pos.Symbol, err)
+		}
 
 		qty := float64(pos.Quantity)
 		greeks.Delta += optionGreeks.Delta * qty
 		greeks.Gamma += optionGreeks.Gamma * qty
 		greeks.Theta += optionGreeks.Theta * qty
 		greeks.Vega += optionGreeks.Vega * qty
+		greeks.Rho += optionGreeks.Rho * qty
 	}
 
 	return greeks, nil
 }
 
-// estimateGreeks estimates Greeks for a position (placeholder implementation).
-func (pa *DefaultPortfolioAnalyzer) estimateGreeks(pos models.Position) models.OptionGreeks {
-	// In a real implementation, this would fetch actual Greeks from the broker
-	// or calculate them using Black-Scholes
-	return models.OptionGreeks{
-		Delta: 0.5,  // Placeholder
-		Gamma: 0.05, // Placeholder
-		Theta: -0.1, // Placeholder
-		Vega:  0.2,  // Placeholder
+// nfoContracts returns the NFO instrument list keyed by trading symbol.
+func nfoContracts(ctx context.Context, b broker.Broker) (map[string]models.Instrument, error) {
+	instruments, err := b.GetInstruments(ctx, models.NFO)
+	if err != nil {
+		return nil, fmt.Errorf("fetching NFO instruments: %w", err)
+	}
+
+	contracts := make(map[string]models.Instrument, len(instruments))
+	for _, inst := range instruments {
+		contracts[inst.Symbol] = inst
 	}
+	return contracts, nil
 }
 
-// GetPortfolioBeta calculates portfolio beta relative to an index.
-// Requirement 51.3: THE CLI SHALL calculate portfolio beta and correlation with indices
-func (pa *DefaultPortfolioAnalyzer) GetPortfolioBeta(ctx context.Context) (float64, error) {
-	positions, err := pa.positionManager.GetPositions(ctx)
+// contractGreeks returns per-unit Greeks for an F&O position. Futures carry a
+// delta of one; options are priced off the underlying spot with the
+// volatility implied by the position's LTP.
+func (pa *DefaultPortfolioAnalyzer) contractGreeks(ctx context.Context, pos models.Position, inst models.Instrument, now time.Time) (models.OptionGreeks, error) {
+	kind, err := options.ParseKind(inst.InstrType)
 	if err != nil {
-		return 0, fmt.Errorf("fetching positions: %w", err)
+		return models.OptionGreeks{Delta: 1}, nil
 	}
 
-	holdings, err := pa.broker.GetHoldings(ctx)
+	spot, err := pa.broker.GetQuote(ctx, options.UnderlyingQuoteSymbol(inst.Name))
 	if err != nil {
-		return 0, fmt.Errorf("fetching holdings: %w", err)
+		return models.OptionGreeks{}, fmt.Errorf("fetching underlying quote: %w", err)
 	}
 
-	// Calculate weighted average beta
-	var totalValue float64
-	var weightedBeta float64
-
-	// Process positions
-	for _, pos := range positions {
-		if pos.Quantity == 0 {
-			continue
+	ltp := pos.LTP
+	if ltp <= 0 {
+		quote, err := pa.broker.GetQuote(ctx, "NFO:"+pos.Symbol)
+		if err != nil {
+			return models.OptionGreeks{}, fmt.Errorf("fetching option quote: %w", err)
 		}
-		value := pos.Value
-		if value < 0 {
-			value = -value
-		}
-		beta := pa.getStockBeta(pos.Symbol)
-		weightedBeta += beta * value
-		totalValue += value
+		ltp = quote.LTP
 	}
 
-	// Process holdings
-	for _, hold := range holdings {
-		if hold.Quantity == 0 {
-			continue
-		}
-		beta := pa.getStockBeta(hold.Symbol)
-		weightedBeta += beta * hold.CurrentValue
-		totalValue += hold.CurrentValue
+	in := options.Inputs{
+		Kind:       kind,
+		Model:      options.BlackScholes,
+		Underlying: spot.LTP,
+		Strike:     inst.Strike,
+		T:          options.YearsToExpiry(now, inst.Expiry),
+		Rate:       options.DefaultRate,
 	}
-
-	if totalValue == 0 {
-		return 1.0, nil // Default beta
+	// An LTP outside the no-arbitrage bounds (stale or at intrinsic) leaves
+	// Vol at zero, which reduces the option to its intrinsic delta.
+	if vol, err := options.ImpliedVol(in, ltp); err == nil {
+		in.Vol = vol
 	}
-
-	return weightedBeta / totalValue, nil
+	return options.Greeks(in), nil
 }
 
-// getStockBeta returns the beta for a stock (placeholder implementation).
-func (pa *DefaultPortfolioAnalyzer) getStockBeta(symbol string) float64 {
-	// In a real implementation, this would fetch historical beta
-	// or calculate it from price data
-	// For now, return a default beta of 1.0
-	return 1.0
+// GetPortfolioBeta calculates portfolio beta relative to an index.
+// Requirement 51.3: THE CLI SHALL calculate portfolio beta and correlation with indices
+func (pa *DefaultPortfolioAnalyzer) GetPortfolioBeta(ctx context.Context) (float64, error) {
+	model, err := pa.GetRiskModel(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("building risk model: %w", err)
+	}
+	return model.Beta, nil
 }
 
 // GetVaR calculates Value at Risk for the portfolio.
 // Requirement 51.5: THE CLI SHALL calculate portfolio VaR (Value at Risk)
 func (pa *DefaultPortfolioAnalyzer) GetVaR(ctx context.Context, confidence float64) (float64, error) {
-	if confidence <= 0 || confidence >= 1 {
-		return 0, fmt.Errorf("confidence must be between 0 and 1")
-	}
-
-	summary, err := pa.GetPortfolioSummary(ctx)
+	// 1-day delta-normal VaR; ComputeVaR offers the other methods
+	result, err := pa.ComputeVaR(ctx, VaRRequest{
+		Method:      VaRParametric,
+		Confidence:  confidence,
+		HorizonDays: 1,
+	})
 	if err != nil {
-		return 0, fmt.Errorf("getting portfolio summary: %w", err)
-	}
-
-	// Get portfolio volatility (simplified calculation)
-	volatility := pa.estimatePortfolioVolatility(ctx)
-
-	// Calculate VaR using parametric method
-	// VaR = Portfolio Value * Z-score * Volatility * sqrt(time horizon)
-	// Assuming 1-day VaR
-	zScore := pa.getZScore(confidence)
-	var1Day := summary.TotalValue * zScore * volatility
-
-	return var1Day, nil
-}
-
-// estimatePortfolioVolatility estimates portfolio volatility.
-func (pa *DefaultPortfolioAnalyzer) estimatePortfolioVolatility(ctx context.Context) float64 {
-	// Simplified: assume 2% daily volatility
-	// In a real implementation, this would calculate from historical returns
-	return 0.02
-}
-
-// getZScore returns the Z-score for a given confidence level.
-func (pa *DefaultPortfolioAnalyzer) getZScore(confidence float64) float64 {
-	// Common Z-scores
-	switch {
-	case confidence >= 0.99:
-		return 2.326
-	case confidence >= 0.95:
-		return 1.645
-	case confidence >= 0.90:
-		return 1.282
-	default:
-		return 1.0
+		return 0, err
 	}
+	return result.VaR, nil
 }
 
 // SuggestHedges suggests hedging opportunities for the portfolio.
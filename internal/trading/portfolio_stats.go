@@ -0,0 +1,403 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+const (
+	// DefaultBenchmark is the index betas are measured against.
+	DefaultBenchmark = "NIFTY 50"
+	// DefaultRiskLookback is the number of trading days of history used.
+	DefaultRiskLookback = 252
+
+	riskTimeframe = "1day"
+	// minRiskObservations is the fewest overlapping daily returns a symbol
+	// needs before its own history is trusted over the benchmark proxy.
+	minRiskObservations = 30
+	// riskStaleAfter allows for a weekend plus a holiday.
+	riskStaleAfter = 4 * 24 * time.Hour
+)
+
+// RiskDataWarning flags a symbol whose candle history was missing, short or
+// stale. Symbols without usable history are modelled as the benchmark.
+type RiskDataWarning struct {
+	Symbol     string
+	Message    string
+	LastCandle time.Time
+}
+
+// PortfolioRiskModel is the historical risk model of the current portfolio,
+// built from stored daily candles. Returns are simple daily returns.
+type PortfolioRiskModel struct {
+	Benchmark string
+	From      time.Time
+	To        time.Time
+
+	Symbols     []string
+	Exposures   []float64 // rupee exposure, negative for short positions
+	Betas       []float64
+	Volatility  []float64 // daily return standard deviation
+	Covariance  [][]float64
+	Correlation [][]float64
+
+	GrossExposure float64
+	// Beta is the exposure-weighted beta of the portfolio.
+	Beta float64
+	// DailyVolatility is the portfolio's daily return standard deviation as
+	// a fraction of gross exposure.
+	DailyVolatility float64
+
+	Warnings []RiskDataWarning
+}
+
+// BetaPoint is one observation of a rolling beta.
+type BetaPoint struct {
+	Date time.Time
+	Beta float64
+}
+
+// SetBenchmark sets the index betas are measured against and the number of
+// trading days of history used.
+func (pa *DefaultPortfolioAnalyzer) SetBenchmark(symbol string, lookback int) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	pa.benchmark = symbol
+	if lookback > 1 {
+		pa.lookback = lookback
+	}
+}
+
+// returnSeries holds daily returns keyed by date (YYYY-MM-DD).
+type returnSeries struct {
+	dates      []string
+	returns    map[string]float64
+	lastCandle time.Time
+}
+
+// loadReturns reads daily candles for symbol between from and to.
+func (pa *DefaultPortfolioAnalyzer) loadReturns(ctx context.Context, symbol string, from, to time.Time) (*returnSeries, error) {
+	candles, err := pa.store.GetCandles(ctx, symbol, riskTimeframe, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s candles: %w", symbol, err)
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Timestamp.Before(candles[j].Timestamp) })
+
+	series := &returnSeries{returns: make(map[string]float64)}
+	for i := 1; i < len(candles); i++ {
+		prev := candles[i-1].Close
+		if prev <= 0 {
+			continue
+		}
+		date := candles[i].Timestamp.Format("2006-01-02")
+		series.dates = append(series.dates, date)
+		series.returns[date] = candles[i].Close/prev - 1
+	}
+	if len(candles) > 0 {
+		series.lastCandle = candles[len(candles)-1].Timestamp
+	}
+	return series, nil
+}
+
+// align returns the returns of a and b on the dates both have, oldest first.
+func align(a, b *returnSeries) ([]float64, []float64, []string) {
+	var xs, ys []float64
+	var dates []string
+	for _, date := range a.dates {
+		if y, ok := b.returns[date]; ok {
+			xs = append(xs, a.returns[date])
+			ys = append(ys, y)
+			dates = append(dates, date)
+		}
+	}
+	return xs, ys, dates
+}
+
+func mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// covariance is the sample covariance of two equal-length series.
+func covariance(xs, ys []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	mx, my := mean(xs), mean(ys)
+	var sum float64
+	for i := range xs {
+		sum += (xs[i] - mx) * (ys[i] - my)
+	}
+	return sum / float64(len(xs)-1)
+}
+
+func betaOf(stock, bench []float64) float64 {
+	v := covariance(bench, bench)
+	if v == 0 {
+		return 1.0
+	}
+	return covariance(stock, bench) / v
+}
+
+// riskWindow returns the date range covering the configured lookback.
+func (pa *DefaultPortfolioAnalyzer) riskWindow(now time.Time) (from, to time.Time, benchmark string, lookback int) {
+	pa.mu.RLock()
+	defer pa.mu.RUnlock()
+	// Calendar days spanning the lookback in trading days, with slack for holidays
+	days := pa.lookback*7/5 + 10
+	return now.AddDate(0, 0, -days), now, pa.benchmark, pa.lookback
+}
+
+// factorSet holds daily returns for a set of symbols on the benchmark's
+// trading days within the lookback.
+type factorSet struct {
+	benchmark string
+	from, to  time.Time
+	dates     []string
+	bench     []float64
+	series    [][]float64 // per symbol, in the order requested
+	warnings  []RiskDataWarning
+}
+
+// loadFactors loads aligned daily returns for symbols. Symbols without
+// enough history stand in as the benchmark itself, with a warning.
+func (pa *DefaultPortfolioAnalyzer) loadFactors(ctx context.Context, symbols []string, now time.Time) (*factorSet, error) {
+	from, to, benchmark, lookback := pa.riskWindow(now)
+	fs := &factorSet{benchmark: benchmark, from: from, to: to}
+
+	bench, err := pa.loadReturns(ctx, benchmark, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(bench.dates) < minRiskObservations {
+		return nil, fmt.Errorf("insufficient %s history: %d daily returns, need %d", benchmark, len(bench.dates), minRiskObservations)
+	}
+	if now.Sub(bench.lastCandle) > riskStaleAfter {
+		fs.warnings = append(fs.warnings, RiskDataWarning{
+			Symbol:     benchmark,
+			Message:    "benchmark candles are stale",
+			LastCandle: bench.lastCandle,
+		})
+	}
+	// Keep to the lookback window of the benchmark's trading days
+	if len(bench.dates) > lookback {
+		bench.dates = bench.dates[len(bench.dates)-lookback:]
+	}
+	fs.dates = bench.dates
+	fs.bench = make([]float64, len(bench.dates))
+	for i, date := range bench.dates {
+		fs.bench[i] = bench.returns[date]
+	}
+
+	fs.series = make([][]float64, len(symbols))
+	for i, symbol := range symbols {
+		if symbol == benchmark {
+			fs.series[i] = fs.bench
+			continue
+		}
+		stock, err := pa.loadReturns(ctx, symbol, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		xs, _, _ := align(stock, bench)
+		switch {
+		case len(xs) < minRiskObservations:
+			fs.warnings = append(fs.warnings, RiskDataWarning{
+				Symbol:     symbol,
+				Message:    fmt.Sprintf("only %d daily returns, modelled as %s", len(xs), benchmark),
+				LastCandle: stock.lastCandle,
+			})
+			fs.series[i] = fs.bench
+			continue
+		case now.Sub(stock.lastCandle) > riskStaleAfter:
+			fs.warnings = append(fs.warnings, RiskDataWarning{
+				Symbol:     symbol,
+				Message:    "candles are stale",
+				LastCandle: stock.lastCandle,
+			})
+		}
+		fs.series[i] = filled(stock, bench)
+	}
+	return fs, nil
+}
+
+// GetRollingBeta returns symbol's beta against the benchmark over a rolling
+// window of trading days, one point per day.
+func (pa *DefaultPortfolioAnalyzer) GetRollingBeta(ctx context.Context, symbol string, window int) ([]BetaPoint, error) {
+	if pa.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if window < 2 {
+		return nil, fmt.Errorf("window must be at least 2 days")
+	}
+
+	from, to, benchmark, _ := pa.riskWindow(time.Now())
+	bench, err := pa.loadReturns(ctx, benchmark, from, to)
+	if err != nil {
+		return nil, err
+	}
+	stock, err := pa.loadReturns(ctx, symbol, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	xs, ys, dates := align(stock, bench)
+	if len(xs) < window {
+		return nil, fmt.Errorf("insufficient history for %s: %d overlapping days, need %d", symbol, len(xs), window)
+	}
+
+	points := make([]BetaPoint, 0, len(xs)-window+1)
+	for end := window; end <= len(xs); end++ {
+		date, _ := time.Parse("2006-01-02", dates[end-1])
+		points = append(points, BetaPoint{
+			Date: date,
+			Beta: betaOf(xs[end-window:end], ys[end-window:end]),
+		})
+	}
+	return points, nil
+}
+
+// portfolioExposures returns the rupee exposure per equity symbol across
+// positions and holdings, and separately the open derivative positions.
+func (pa *DefaultPortfolioAnalyzer) portfolioExposures(ctx context.Context) (map[string]float64, []models.Position, error) {
+	positions, err := pa.positionManager.GetPositions(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching positions: %w", err)
+	}
+	holdings, err := pa.broker.GetHoldings(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching holdings: %w", err)
+	}
+
+	exposures := make(map[string]float64)
+	var derivatives []models.Position
+	for _, pos := range positions {
+		if pos.Quantity == 0 {
+			continue
+		}
+		if pos.Exchange == models.NFO || pos.Exchange == models.MCX || pos.Exchange == models.CDS {
+			derivatives = append(derivatives, pos)
+			continue
+		}
+		exposures[pos.Symbol] += float64(pos.Quantity) * pos.LTP
+	}
+	for _, hold := range holdings {
+		if hold.Quantity == 0 {
+			continue
+		}
+		exposures[hold.Symbol] += hold.CurrentValue
+	}
+	return exposures, derivatives, nil
+}
+
+// GetRiskModel builds the historical risk model for the current positions
+// and holdings: per-symbol betas and volatilities, the covariance and
+// correlation matrices, and portfolio beta and volatility from exposure
+// weights.
+func (pa *DefaultPortfolioAnalyzer) GetRiskModel(ctx context.Context) (*PortfolioRiskModel, error) {
+	if pa.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+
+	exposures, derivatives, err := pa.portfolioExposures(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := exposureSymbols(exposures)
+	factors, err := pa.loadFactors(ctx, symbols, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	model := &PortfolioRiskModel{
+		Benchmark: factors.benchmark,
+		From:      factors.from,
+		To:        factors.to,
+		Warnings:  factors.warnings,
+	}
+	if len(derivatives) > 0 {
+		model.Warnings = append(model.Warnings, RiskDataWarning{
+			Message: "F&O and commodity positions are not in the covariance model; see portfolio Greeks",
+		})
+	}
+	series, benchReturns := factors.series, factors.bench
+
+	n := len(symbols)
+	model.Symbols = symbols
+	model.Exposures = make([]float64, n)
+	model.Betas = make([]float64, n)
+	model.Volatility = make([]float64, n)
+	model.Covariance = make([][]float64, n)
+	model.Correlation = make([][]float64, n)
+	for i, symbol := range symbols {
+		model.Exposures[i] = exposures[symbol]
+		model.GrossExposure += math.Abs(exposures[symbol])
+		model.Betas[i] = betaOf(series[i], benchReturns)
+		model.Covariance[i] = make([]float64, n)
+		model.Correlation[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			c := covariance(series[i], series[j])
+			model.Covariance[i][j], model.Covariance[j][i] = c, c
+		}
+		model.Volatility[i] = math.Sqrt(math.Max(model.Covariance[i][i], 0))
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if model.Volatility[i] > 0 && model.Volatility[j] > 0 {
+				model.Correlation[i][j] = model.Covariance[i][j] / (model.Volatility[i] * model.Volatility[j])
+			}
+		}
+	}
+
+	if model.GrossExposure == 0 {
+		model.Beta = 1.0
+		return model, nil
+	}
+
+	var variance float64
+	for i := 0; i < n; i++ {
+		model.Beta += model.Betas[i] * model.Exposures[i] / model.GrossExposure
+		for j := 0; j < n; j++ {
+			variance += model.Exposures[i] * model.Exposures[j] * model.Covariance[i][j]
+		}
+	}
+	model.DailyVolatility = math.Sqrt(math.Max(variance, 0)) / model.GrossExposure
+
+	return model, nil
+}
+
+// exposureSymbols returns the symbols with a non-zero exposure, sorted.
+func exposureSymbols(exposures map[string]float64) []string {
+	symbols := make([]string, 0, len(exposures))
+	for symbol, exposure := range exposures {
+		if exposure != 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// filled returns stock's returns on each of the benchmark's dates, with
+// zero for days the stock did not trade.
+func filled(stock, bench *returnSeries) []float64 {
+	out := make([]float64, len(bench.dates))
+	for i, date := range bench.dates {
+		out[i] = stock.returns[date]
+	}
+	return out
+}
@@ -0,0 +1,228 @@
+package trading
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/store"
+)
+
+func TestAlign(t *testing.T) {
+	a := &returnSeries{
+		dates:   []string{"2024-03-01", "2024-03-02", "2024-03-04", "2024-03-05"},
+		returns: map[string]float64{"2024-03-01": 0.01, "2024-03-02": 0.02, "2024-03-04": 0.03, "2024-03-05": 0.04},
+	}
+	b := &returnSeries{
+		dates:   []string{"2024-03-01", "2024-03-04", "2024-03-06"},
+		returns: map[string]float64{"2024-03-01": -0.01, "2024-03-04": -0.03, "2024-03-06": -0.06},
+	}
+
+	xs, ys, dates := align(a, b)
+	if len(dates) != 2 || dates[0] != "2024-03-01" || dates[1] != "2024-03-04" {
+		t.Fatalf("aligned dates = %v, want [2024-03-01 2024-03-04]", dates)
+	}
+	if xs[0] != 0.01 || xs[1] != 0.03 || ys[0] != -0.01 || ys[1] != -0.03 {
+		t.Errorf("aligned returns = %v / %v", xs, ys)
+	}
+
+	if xs, _, _ := align(a, &returnSeries{returns: map[string]float64{}}); len(xs) != 0 {
+		t.Errorf("expected nothing aligned against an empty series, got %v", xs)
+	}
+}
+
+func TestCovarianceAndBeta(t *testing.T) {
+	tests := []struct {
+		name       string
+		stock      []float64
+		bench      []float64
+		covariance float64
+		beta       float64
+	}{
+		{"scaled and shifted", []float64{0.021, 0.041, 0.061}, []float64{0.01, 0.02, 0.03}, 0.0002, 2},
+		{"inverse", []float64{0.01, -0.01, 0.02, -0.02}, []float64{-0.005, 0.005, -0.01, 0.01}, -0.000125 * 4 / 3, -2},
+		{"single observation", []float64{0.05}, []float64{0.01}, 0, 1},
+		{"flat benchmark", []float64{0.01, 0.02, 0.03}, []float64{0.01, 0.01, 0.01}, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := covariance(tt.stock, tt.bench); math.Abs(got-tt.covariance) > 1e-12 {
+				t.Errorf("covariance = %v, want %v", got, tt.covariance)
+			}
+			if got := betaOf(tt.stock, tt.bench); math.Abs(got-tt.beta) > 1e-9 {
+				t.Errorf("beta = %v, want %v", got, tt.beta)
+			}
+		})
+	}
+}
+
+// riskFixture stores daily candles ending today for the benchmark, BETA2
+// (exactly twice the benchmark's daily return, with extra Saturday sessions
+// the benchmark does not have), SHORT (too little history) and STALE (no
+// candles for the last fortnight).
+func riskFixture(t *testing.T) (*store.SQLiteStore, float64) {
+	t.Helper()
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "trader.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	var days []time.Time
+	for d := today.AddDate(0, 0, -120); !d.After(today); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+
+	rng := rand.New(rand.NewSource(7))
+	benchClose, stockClose := 20000.0, 500.0
+	var bench, stock, stale []models.Candle
+	var benchReturns []float64
+	for i, d := range days {
+		switch d.Weekday() {
+		case time.Sunday:
+			continue
+		case time.Saturday:
+			// Special session: flat, so Monday's return is unchanged
+			stock = append(stock, models.Candle{Timestamp: d, Open: stockClose, High: stockClose, Low: stockClose, Close: stockClose})
+			continue
+		}
+		if i > 0 && len(bench) > 0 {
+			r := rng.NormFloat64() * 0.01
+			benchReturns = append(benchReturns, r)
+			benchClose *= 1 + r
+			stockClose *= 1 + 2*r
+		}
+		bench = append(bench, models.Candle{Timestamp: d, Open: benchClose, High: benchClose, Low: benchClose, Close: benchClose})
+		stock = append(stock, models.Candle{Timestamp: d, Open: stockClose, High: stockClose, Low: stockClose, Close: stockClose})
+		if today.Sub(d) > 14*24*time.Hour {
+			stale = append(stale, models.Candle{Timestamp: d, Open: stockClose, High: stockClose, Low: stockClose, Close: stockClose})
+		}
+	}
+
+	ctx := context.Background()
+	for symbol, candles := range map[string][]models.Candle{
+		DefaultBenchmark: bench,
+		"BETA2":          stock,
+		"SHORT":          stock[len(stock)-10:],
+		"STALE":          stale,
+	} {
+		if err := db.SaveCandles(ctx, symbol, riskTimeframe, candles); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db, math.Sqrt(covariance(benchReturns, benchReturns))
+}
+
+func TestGetRollingBeta(t *testing.T) {
+	db, _ := riskFixture(t)
+	pa := NewPortfolioAnalyzer(nil, nil, db)
+
+	points, err := pa.GetRollingBeta(context.Background(), "BETA2", 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(points) < 30 {
+		t.Fatalf("got %d points, want one per day after the first window", len(points))
+	}
+	for _, p := range points {
+		if math.Abs(p.Beta-2) > 1e-6 {
+			t.Fatalf("beta on %s = %v, want 2", p.Date.Format("2006-01-02"), p.Beta)
+		}
+		if p.Date.Weekday() == time.Saturday {
+			t.Fatalf("point on %s, a session the benchmark did not trade", p.Date.Format("2006-01-02"))
+		}
+	}
+	for i := 1; i < len(points); i++ {
+		if !points[i].Date.After(points[i-1].Date) {
+			t.Fatalf("points not in date order at %d", i)
+		}
+	}
+
+	if _, err := pa.GetRollingBeta(context.Background(), "SHORT", 20); err == nil {
+		t.Error("expected too little history for a 20-day window to fail")
+	}
+	if _, err := pa.GetRollingBeta(context.Background(), "BETA2", 1); err == nil {
+		t.Error("expected a 1-day window to be refused")
+	}
+}
+
+func TestGetRiskModel(t *testing.T) {
+	db, benchVol := riskFixture(t)
+	b := &gateBroker{
+		positions: []models.Position{
+			{Symbol: "BETA2", Exchange: models.NSE, Quantity: 100, LTP: 100},
+			{Symbol: "NIFTY24MARFUT", Exchange: models.NFO, Quantity: 50, LTP: 22000},
+		},
+		holdings: []models.Holding{
+			{Symbol: "SHORT", Quantity: 10, CurrentValue: 10000},
+			{Symbol: "STALE", Quantity: 10, CurrentValue: 0},
+		},
+	}
+	pa := NewPortfolioAnalyzer(b, NewPositionManager(b), db)
+
+	model, err := pa.GetRiskModel(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(model.Symbols) != 2 || model.Symbols[0] != "BETA2" || model.Symbols[1] != "SHORT" {
+		t.Fatalf("symbols = %v, want [BETA2 SHORT]", model.Symbols)
+	}
+	if math.Abs(model.Betas[0]-2) > 1e-6 {
+		t.Errorf("BETA2 beta = %v, want 2", model.Betas[0])
+	}
+	if model.Betas[1] != 1 {
+		t.Errorf("SHORT beta = %v, want 1 as the benchmark proxy", model.Betas[1])
+	}
+	if math.Abs(model.Beta-1.5) > 1e-6 {
+		t.Errorf("portfolio beta = %v, want 1.5", model.Beta)
+	}
+	if math.Abs(model.Volatility[0]-2*benchVol) > 1e-9 || math.Abs(model.Volatility[1]-benchVol) > 1e-9 {
+		t.Errorf("volatilities = %v, want [%v %v]", model.Volatility, 2*benchVol, benchVol)
+	}
+	if math.Abs(model.Correlation[0][1]-1) > 1e-9 {
+		t.Errorf("correlation with the proxy = %v, want 1", model.Correlation[0][1])
+	}
+	// Equal exposures in returns of 2σ and σ, perfectly correlated: (2σ+σ)/2
+	if math.Abs(model.DailyVolatility-1.5*benchVol) > 1e-9 {
+		t.Errorf("portfolio volatility = %v, want %v", model.DailyVolatility, 1.5*benchVol)
+	}
+
+	var short, derivatives bool
+	for _, w := range model.Warnings {
+		if w.Symbol == "SHORT" && strings.Contains(w.Message, "modelled as") {
+			short = true
+		}
+		if strings.Contains(w.Message, "F&O") {
+			derivatives = true
+		}
+	}
+	if !short || !derivatives {
+		t.Errorf("expected short-history and derivatives warnings, got %+v", model.Warnings)
+	}
+}
+
+func TestLoadFactorsWarnsOnStaleHistory(t *testing.T) {
+	db, _ := riskFixture(t)
+	pa := NewPortfolioAnalyzer(nil, nil, db)
+
+	fs, err := pa.loadFactors(context.Background(), []string{"STALE"}, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fs.warnings) != 1 || fs.warnings[0].Symbol != "STALE" || fs.warnings[0].Message != "candles are stale" {
+		t.Errorf("warnings = %+v, want STALE flagged stale", fs.warnings)
+	}
+	// Days after the last candle count as flat
+	if last := fs.series[0][len(fs.series[0])-1]; last != 0 {
+		t.Errorf("return after the last candle = %v, want 0", last)
+	}
+}
@@ -0,0 +1,416 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/options"
+)
+
+// VaRMethod selects how portfolio loss scenarios are generated.
+type VaRMethod string
+
+const (
+	// VaRParametric is delta-normal: options enter at their delta.
+	VaRParametric VaRMethod = "parametric"
+	// VaRHistorical replays overlapping windows of past daily returns.
+	VaRHistorical VaRMethod = "historical"
+	// VaRMonteCarlo draws correlated lognormal returns from the covariance
+	// of the constituents.
+	VaRMonteCarlo VaRMethod = "montecarlo"
+)
+
+const defaultSimulations = 10000
+
+// VaRRequest configures a Value at Risk calculation.
+type VaRRequest struct {
+	Method      VaRMethod
+	Confidence  float64 // e.g. 0.99
+	HorizonDays int     // trading days, default 1
+	Simulations int     // Monte Carlo paths, default 10,000
+	Seed        int64   // Monte Carlo seed; zero seeds from the clock
+}
+
+// VaRResult is a portfolio Value at Risk and Expected Shortfall. Both are
+// positive rupee losses over the horizon.
+type VaRResult struct {
+	Method            VaRMethod
+	Confidence        float64
+	HorizonDays       int
+	VaR               float64
+	ExpectedShortfall float64 // average loss in the tail beyond VaR
+	Scenarios         int
+	GrossExposure     float64
+	Equities          int
+	Derivatives       int
+	Warnings          []RiskDataWarning
+}
+
+// derivativeExposure is an F&O position ready for revaluation.
+type derivativeExposure struct {
+	symbol   string
+	factor   int // index into the factor list
+	future   bool
+	expiry   time.Time
+	in       options.Inputs
+	quantity float64 // signed
+	price    float64 // current model value per unit
+}
+
+// value returns the contract's value per unit if the underlying moves by
+// ret and time moves to T.
+func (d *derivativeExposure) value(ret, t float64) float64 {
+	if d.future {
+		return d.price * (1 + ret)
+	}
+	in := d.in
+	in.Underlying *= 1 + ret
+	in.T = t
+	return options.Price(in)
+}
+
+// delta returns the rupee delta exposure of the position.
+func (d *derivativeExposure) delta() float64 {
+	if d.future {
+		return d.quantity * d.price
+	}
+	return d.quantity * options.Greeks(d.in).Delta * d.in.Underlying
+}
+
+// ComputeVaR calculates Value at Risk and Expected Shortfall for positions
+// and holdings. Equities are revalued linearly; options are fully repriced
+// with Black-Scholes at their current IV in every scenario.
+func (pa *DefaultPortfolioAnalyzer) ComputeVaR(ctx context.Context, req VaRRequest) (*VaRResult, error) {
+	if pa.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if req.Confidence <= 0 || req.Confidence >= 1 {
+		return nil, fmt.Errorf("confidence must be between 0 and 1")
+	}
+	if req.HorizonDays <= 0 {
+		req.HorizonDays = 1
+	}
+	if req.Method == "" {
+		req.Method = VaRHistorical
+	}
+
+	exposures, positions, err := pa.portfolioExposures(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	factors := exposureSymbols(exposures)
+	equities := len(factors)
+	result := &VaRResult{
+		Method:      req.Method,
+		Confidence:  req.Confidence,
+		HorizonDays: req.HorizonDays,
+		Equities:    equities,
+	}
+	for _, symbol := range factors {
+		result.GrossExposure += math.Abs(exposures[symbol])
+	}
+
+	derivatives, factors, warnings, err := pa.loadDerivatives(ctx, positions, factors, now)
+	if err != nil {
+		return nil, err
+	}
+	result.Derivatives = len(derivatives)
+	result.Warnings = warnings
+	for _, d := range derivatives {
+		result.GrossExposure += math.Abs(d.quantity * d.price)
+	}
+
+	fs, err := pa.loadFactors(ctx, factors, now)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(result.Warnings, fs.warnings...)
+
+	// Options are aged by the horizon in calendar days
+	horizon := now.AddDate(0, 0, req.HorizonDays)
+	remaining := make([]float64, len(derivatives))
+	for i, d := range derivatives {
+		remaining[i] = options.YearsToExpiry(horizon, d.expiry)
+	}
+
+	pnl := func(returns []float64) float64 {
+		var total float64
+		for i := 0; i < equities; i++ {
+			total += exposures[factors[i]] * returns[i]
+		}
+		for i, d := range derivatives {
+			total += d.quantity * (d.value(returns[d.factor], remaining[i]) - d.price)
+		}
+		return total
+	}
+
+	var scenarios []float64
+	switch req.Method {
+	case VaRParametric:
+		pa.parametricVaR(result, fs, exposures, factors[:equities], derivatives)
+		return result, nil
+	case VaRHistorical:
+		scenarios = historicalScenarios(fs.series, req.HorizonDays, pnl)
+	case VaRMonteCarlo:
+		if req.Simulations <= 0 {
+			req.Simulations = defaultSimulations
+		}
+		seed := req.Seed
+		if seed == 0 {
+			seed = now.UnixNano()
+		}
+		scenarios = monteCarloScenarios(fs.series, req.HorizonDays, req.Simulations, rand.New(rand.NewSource(seed)), pnl)
+	default:
+		return nil, fmt.Errorf("unknown VaR method %q", req.Method)
+	}
+
+	if len(scenarios) == 0 {
+		return nil, fmt.Errorf("not enough history for a %d-day horizon", req.HorizonDays)
+	}
+	result.Scenarios = len(scenarios)
+	result.VaR, result.ExpectedShortfall = tailLoss(scenarios, req.Confidence)
+	return result, nil
+}
+
+// loadDerivatives prices the NFO positions and appends their underlyings to
+// factors. Positions on other derivative exchanges have no candle history
+// and are reported as warnings.
+func (pa *DefaultPortfolioAnalyzer) loadDerivatives(ctx context.Context, positions []models.Position, factors []string, now time.Time) ([]*derivativeExposure, []string, []RiskDataWarning, error) {
+	var warnings []RiskDataWarning
+	var nfo []models.Position
+	for _, pos := range positions {
+		if pos.Exchange == models.NFO {
+			nfo = append(nfo, pos)
+			continue
+		}
+		warnings = append(warnings, RiskDataWarning{
+			Symbol:  pos.Symbol,
+			Message: fmt.Sprintf("%s positions are not modelled", pos.Exchange),
+		})
+	}
+	if len(nfo) == 0 {
+		return nil, factors, warnings, nil
+	}
+
+	contracts, err := nfoContracts(ctx, pa.broker)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	factorIndex := make(map[string]int, len(factors))
+	for i, f := range factors {
+		factorIndex[f] = i
+	}
+	spots := make(map[string]float64)
+
+	var derivatives []*derivativeExposure
+	for _, pos := range nfo {
+		inst, ok := contracts[pos.Symbol]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("unknown NFO contract %s", pos.Symbol)
+		}
+
+		quoteSymbol := options.UnderlyingQuoteSymbol(inst.Name)
+		spot, ok := spots[quoteSymbol]
+		if !ok {
+			quote, err := pa.broker.GetQuote(ctx, quoteSymbol)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("fetching %s quote: %w", inst.Name, err)
+			}
+			spot = quote.LTP
+			spots[quoteSymbol] = spot
+		}
+
+		// Underlying candles are stored under the cash market symbol
+		factor := strings.TrimPrefix(quoteSymbol, "NSE:")
+		idx, ok := factorIndex[factor]
+		if !ok {
+			idx = len(factors)
+			factors = append(factors, factor)
+			factorIndex[factor] = idx
+		}
+
+		d := &derivativeExposure{
+			symbol:   pos.Symbol,
+			factor:   idx,
+			expiry:   inst.Expiry,
+			quantity: float64(pos.Quantity),
+			price:    pos.LTP,
+		}
+		if kind, err := options.ParseKind(inst.InstrType); err == nil {
+			d.in = options.Inputs{
+				Kind:       kind,
+				Model:      options.BlackScholes,
+				Underlying: spot,
+				Strike:     inst.Strike,
+				T:          options.YearsToExpiry(now, inst.Expiry),
+				Rate:       options.DefaultRate,
+			}
+			if vol, err := options.ImpliedVol(d.in, pos.LTP); err == nil {
+				d.in.Vol = vol
+			} else {
+				warnings = append(warnings, RiskDataWarning{
+					Symbol:  pos.Symbol,
+					Message: "no implied volatility at LTP, valued at intrinsic",
+				})
+			}
+			d.price = options.Price(d.in)
+		} else {
+			d.future = true
+		}
+		derivatives = append(derivatives, d)
+	}
+	return derivatives, factors, warnings, nil
+}
+
+// parametricVaR fills a delta-normal VaR: options enter at their rupee
+// delta on the underlying.
+func (pa *DefaultPortfolioAnalyzer) parametricVaR(result *VaRResult, fs *factorSet, exposures map[string]float64, equities []string, derivatives []*derivativeExposure) {
+	e := make([]float64, len(fs.series))
+	for i, symbol := range equities {
+		e[i] = exposures[symbol]
+	}
+	for _, d := range derivatives {
+		e[d.factor] += d.delta()
+	}
+
+	var variance float64
+	for i := range e {
+		for j := range e {
+			variance += e[i] * e[j] * covariance(fs.series[i], fs.series[j])
+		}
+	}
+	sigma := math.Sqrt(math.Max(variance, 0) * float64(result.HorizonDays))
+
+	z := normInv(result.Confidence)
+	result.VaR = z * sigma
+	result.ExpectedShortfall = sigma * math.Exp(-z*z/2) / math.Sqrt(2*math.Pi) / (1 - result.Confidence)
+}
+
+// historicalScenarios compounds each overlapping window of horizon daily
+// returns into one scenario.
+func historicalScenarios(series [][]float64, horizon int, pnl func([]float64) float64) []float64 {
+	if len(series) == 0 {
+		return []float64{0}
+	}
+	days := len(series[0])
+	if days < horizon {
+		return nil
+	}
+
+	scenarios := make([]float64, 0, days-horizon+1)
+	returns := make([]float64, len(series))
+	for start := 0; start+horizon <= days; start++ {
+		for f, s := range series {
+			growth := 1.0
+			for d := start; d < start+horizon; d++ {
+				growth *= 1 + s[d]
+			}
+			returns[f] = growth - 1
+		}
+		scenarios = append(scenarios, pnl(returns))
+	}
+	return scenarios
+}
+
+// monteCarloScenarios draws correlated lognormal horizon returns with the
+// sample covariance of the daily returns.
+func monteCarloScenarios(series [][]float64, horizon, paths int, rng *rand.Rand, pnl func([]float64) float64) []float64 {
+	n := len(series)
+	if n == 0 {
+		return []float64{0}
+	}
+
+	cov := make([][]float64, n)
+	for i := range cov {
+		cov[i] = make([]float64, n)
+		for j := range cov[i] {
+			cov[i][j] = covariance(series[i], series[j]) * float64(horizon)
+		}
+	}
+	chol := cholesky(cov)
+
+	scenarios := make([]float64, paths)
+	z := make([]float64, n)
+	returns := make([]float64, n)
+	for p := range scenarios {
+		for i := range z {
+			z[i] = rng.NormFloat64()
+		}
+		for i := 0; i < n; i++ {
+			var x float64
+			for j := 0; j <= i; j++ {
+				x += chol[i][j] * z[j]
+			}
+			returns[i] = math.Exp(x-cov[i][i]/2) - 1
+		}
+		scenarios[p] = pnl(returns)
+	}
+	return scenarios
+}
+
+// cholesky returns the lower-triangular factor of a covariance matrix.
+// Directions with no remaining variance, as when one symbol stands in as
+// another, get a zero column instead of failing.
+func cholesky(a [][]float64) [][]float64 {
+	n := len(a)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+	}
+	for j := 0; j < n; j++ {
+		d := a[j][j]
+		for k := 0; k < j; k++ {
+			d -= l[j][k] * l[j][k]
+		}
+		if d <= 1e-18 {
+			continue
+		}
+		l[j][j] = math.Sqrt(d)
+		for i := j + 1; i < n; i++ {
+			s := a[i][j]
+			for k := 0; k < j; k++ {
+				s -= l[i][k] * l[j][k]
+			}
+			l[i][j] = s / l[j][j]
+		}
+	}
+	return l
+}
+
+// tailLoss returns the loss not exceeded with probability confidence and
+// the average loss at or beyond it.
+func tailLoss(pnls []float64, confidence float64) (float64, float64) {
+	losses := make([]float64, len(pnls))
+	for i, p := range pnls {
+		losses[i] = -p
+	}
+	sort.Float64s(losses)
+
+	idx := int(math.Ceil(confidence*float64(len(losses)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(losses) {
+		idx = len(losses) - 1
+	}
+
+	var tail float64
+	for _, l := range losses[idx:] {
+		tail += l
+	}
+	return losses[idx], tail / float64(len(losses)-idx)
+}
+
+// normInv is the standard normal quantile function.
+func normInv(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
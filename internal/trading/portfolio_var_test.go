@@ -0,0 +1,73 @@
+package trading
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestTailLoss(t *testing.T) {
+	pnls := make([]float64, 100)
+	for i := range pnls {
+		pnls[i] = float64(i - 50) // losses of 50 down to -49
+	}
+
+	v, es := tailLoss(pnls, 0.95)
+	if v != 45 {
+		t.Errorf("VaR = %v, want 45", v)
+	}
+	if want := (45.0 + 46 + 47 + 48 + 49 + 50) / 6; math.Abs(es-want) > 1e-9 {
+		t.Errorf("ES = %v, want %v", es, want)
+	}
+}
+
+func TestHistoricalScenariosCompoundWindows(t *testing.T) {
+	series := [][]float64{{0.1, -0.1, 0.2}}
+	got := historicalScenarios(series, 2, func(r []float64) float64 { return r[0] })
+
+	want := []float64{1.1*0.9 - 1, 0.9*1.2 - 1}
+	if len(got) != len(want) {
+		t.Fatalf("got %d scenarios, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Errorf("scenario %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCholeskySingular(t *testing.T) {
+	// The second factor is a proxy for the first: perfectly correlated
+	a := [][]float64{{4, 4}, {4, 4}}
+	l := cholesky(a)
+	for i := range a {
+		for j := range a {
+			var s float64
+			for k := range a {
+				s += l[i][k] * l[j][k]
+			}
+			if math.Abs(s-a[i][j]) > 1e-9 {
+				t.Errorf("LLᵀ[%d][%d] = %v, want %v", i, j, s, a[i][j])
+			}
+		}
+	}
+}
+
+func TestMonteCarloMatchesParametric(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	returns := make([]float64, 500)
+	for i := range returns {
+		returns[i] = rng.NormFloat64() * 0.01
+	}
+	sigma := math.Sqrt(covariance(returns, returns))
+
+	const exposure = 1e6
+	pnls := monteCarloScenarios([][]float64{returns}, 1, 20000, rand.New(rand.NewSource(2)),
+		func(r []float64) float64 { return exposure * r[0] })
+	v, _ := tailLoss(pnls, 0.99)
+
+	want := exposure * sigma * normInv(0.99)
+	if math.Abs(v-want)/want > 0.1 {
+		t.Errorf("Monte Carlo VaR = %.0f, parametric = %.0f", v, want)
+	}
+}
@@ -0,0 +1,467 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"zerodha-trader/internal/agents"
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/config"
+	apperrors "zerodha-trader/internal/errors"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/security"
+	"zerodha-trader/internal/store"
+)
+
+// Risk gate checks, reported in RiskGateError.Check.
+const (
+	CheckKillSwitch      = "kill_switch"
+	CheckOrderValue      = "max_order_value"
+	CheckQuantity        = "max_quantity_per_symbol"
+	CheckPositions       = "max_concurrent_positions"
+	CheckDailyLoss       = "daily_loss_limit"
+	CheckPriceBand       = "price_band"
+	CheckCircuit         = "circuit_limit"
+	CheckMarketData      = "market_data"
+	CheckKillSwitchState = "kill_switch_state"
+)
+
+// RiskGateError is returned for orders the risk gate refuses. It wraps
+// errors.ErrOrderRejected.
+type RiskGateError struct {
+	Check  string
+	Symbol string
+	Reason string
+}
+
+func (e *RiskGateError) Error() string {
+	if e.Symbol == "" {
+		return fmt.Sprintf("order blocked by risk gate [%s]: %s", e.Check, e.Reason)
+	}
+	return fmt.Sprintf("order blocked by risk gate [%s] %s: %s", e.Check, e.Symbol, e.Reason)
+}
+
+func (e *RiskGateError) Unwrap() error {
+	return apperrors.ErrOrderRejected
+}
+
+// RiskGate is a broker.Broker decorator that applies hard pre-trade limits
+// to every order, whoever places it: manual commands, plans, baskets, exits
+// and the agents all go through it. Orders that shrink an existing position
+// or sell delivery holdings skip the value, quantity, position-count and
+// daily-loss limits so exits are never trapped.
+//
+// The kill switch is read from the store on every order, so engaging it
+// from another process stops a running trader. It blocks new risk only:
+// exits and stop-loss modifications that shrink a position still pass, so
+// open positions are never left unprotected.
+type RiskGate struct {
+	broker.Broker
+
+	cfg      config.RiskConfig
+	store    store.DataStore
+	circuits *CircuitMonitor
+
+	mu         sync.Mutex
+	risk       *agents.RiskState
+	riskLoaded bool
+	// killSwitch is the in-memory switch used when there is no store.
+	killSwitch models.KillSwitch
+	// audit records the orders the gate refuses, which never reach the
+	// audit layer behind it.
+	audit *AuditedBroker
+}
+
+// NewRiskGate wraps b with the limits in cfg. dataStore holds the kill
+// switch and the day's realized losses; it may be nil.
+func NewRiskGate(b broker.Broker, cfg config.RiskConfig, dataStore store.DataStore) *RiskGate {
+	return &RiskGate{
+		Broker:   b,
+		cfg:      cfg,
+		store:    dataStore,
+		circuits: NewCircuitMonitor(b),
+		risk:     agents.NewRiskState(dataStore, nil),
+	}
+}
+
+// NewGuardedBroker builds the stack every order passes through: the risk
+// gate in front of the audit log in front of b. Orders the gate refuses are
+// audited as well. audit may be nil, leaving the gate alone.
+func NewGuardedBroker(b broker.Broker, audit *security.AuditLogger, logger zerolog.Logger, cfg config.RiskConfig, dataStore store.DataStore) *RiskGate {
+	if audit == nil {
+		return NewRiskGate(b, cfg, dataStore)
+	}
+	audited := NewAuditedBroker(b, audit, logger)
+	g := NewRiskGate(audited, cfg, dataStore)
+	g.audit = audited
+	return g
+}
+
+// Unwrap returns the broker behind the gate.
+func (g *RiskGate) Unwrap() broker.Broker {
+	return g.Broker
+}
+
+// UnwrapBroker returns the innermost broker behind any risk gates and audit
+// layers, for callers that need a concrete implementation.
+func UnwrapBroker(b broker.Broker) broker.Broker {
+	for {
+		w, ok := b.(interface{ Unwrap() broker.Broker })
+		if !ok {
+			return b
+		}
+		b = w.Unwrap()
+	}
+}
+
+// SetRiskState shares the daily risk counters of a running orchestrator,
+// which loads them itself.
+func (g *RiskGate) SetRiskState(rs *agents.RiskState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.risk = rs
+	g.riskLoaded = true
+}
+
+// PlaceOrder places order if it passes every check.
+func (g *RiskGate) PlaceOrder(ctx context.Context, order *models.Order) (*broker.OrderResult, error) {
+	if err := g.checkAll(ctx, order); err != nil {
+		g.rejected(ctx, orderAuditEvent(security.AuditOrderRejected, order, err))
+		return nil, err
+	}
+	return g.Broker.PlaceOrder(ctx, order)
+}
+
+// ModifyOrder checks the modified order as if it were new.
+func (g *RiskGate) ModifyOrder(ctx context.Context, orderID string, order *models.Order) error {
+	if err := g.checkAll(ctx, order); err != nil {
+		event := orderAuditEvent(security.AuditOrderModified, order, err)
+		event.OrderID = orderID
+		g.rejected(ctx, event)
+		return err
+	}
+	return g.Broker.ModifyOrder(ctx, orderID, order)
+}
+
+// PlaceGTT places a GTT unless the kill switch is engaged. A live GTT's
+// legs trigger at the exchange, outside the gate, so they are only blocked
+// wholesale; a paper broker routes its triggered legs back through
+// PlaceOrder.
+func (g *RiskGate) PlaceGTT(ctx context.Context, gtt *models.GTTOrder) (*broker.GTTResult, error) {
+	if err := g.checkKillSwitch(ctx, gtt.Symbol); err != nil {
+		g.rejected(ctx, gttAuditEvent(security.AuditGTTPlaced, gtt, err))
+		return nil, err
+	}
+	return g.Broker.PlaceGTT(ctx, gtt)
+}
+
+// ModifyGTT modifies a GTT unless the kill switch is engaged.
+func (g *RiskGate) ModifyGTT(ctx context.Context, gttID string, gtt *models.GTTOrder) error {
+	if err := g.checkKillSwitch(ctx, gtt.Symbol); err != nil {
+		event := gttAuditEvent(security.AuditGTTModified, gtt, err)
+		event.OrderID = gttID
+		g.rejected(ctx, event)
+		return err
+	}
+	return g.Broker.ModifyGTT(ctx, gttID, gtt)
+}
+
+// KillSwitch returns the kill switch state.
+func (g *RiskGate) KillSwitch(ctx context.Context) (*models.KillSwitch, error) {
+	if g.store == nil {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		ks := g.killSwitch
+		return &ks, nil
+	}
+	return g.store.GetKillSwitch(ctx)
+}
+
+// SetKillSwitch engages or releases the kill switch.
+func (g *RiskGate) SetKillSwitch(ctx context.Context, engaged bool, reason string) error {
+	ks := models.KillSwitch{Engaged: engaged, Reason: reason, UpdatedAt: time.Now()}
+	if g.store == nil {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.killSwitch = ks
+		return nil
+	}
+	return g.store.SetKillSwitch(ctx, &ks)
+}
+
+// Flatten cancels every open order and squares off every open position at
+// market, bypassing the gate. It carries on past failures and reports them
+// together.
+func (g *RiskGate) Flatten(ctx context.Context) error {
+	var errs []error
+
+	orders, err := g.Broker.GetOrders(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("fetching orders: %w", err))
+	}
+	for _, o := range orders {
+		if o.Status != models.OrderStatusOpen && o.Status != models.OrderStatusTriggerPending {
+			continue
+		}
+		if err := g.Broker.CancelOrder(ctx, o.ID); err != nil {
+			errs = append(errs, fmt.Errorf("cancelling %s (%s): %w", o.ID, o.Symbol, err))
+		}
+	}
+
+	if err := NewPositionManager(g.Broker).ExitAllPositions(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("flatten incomplete: %v", errs)
+	}
+	return nil
+}
+
+// checkAll applies the kill switch and every order limit.
+func (g *RiskGate) checkAll(ctx context.Context, order *models.Order) error {
+	if err := g.checkOrderKillSwitch(ctx, order); err != nil {
+		return err
+	}
+	return g.CheckOrder(ctx, order)
+}
+
+// rejected audits a request the gate refused.
+func (g *RiskGate) rejected(ctx context.Context, event security.AuditEvent) {
+	if g.audit != nil {
+		g.audit.log(ctx, event)
+	}
+}
+
+func (g *RiskGate) checkKillSwitch(ctx context.Context, symbol string) error {
+	ks, err := g.KillSwitch(ctx)
+	if err != nil {
+		// Fail closed: an unreadable switch may be engaged
+		return &RiskGateError{Check: CheckKillSwitchState, Symbol: symbol, Reason: err.Error()}
+	}
+	if ks.Engaged {
+		reason := "kill switch engaged"
+		if ks.Reason != "" {
+			reason += ": " + ks.Reason
+		}
+		return &RiskGateError{Check: CheckKillSwitch, Symbol: symbol, Reason: reason}
+	}
+	return nil
+}
+
+// checkOrderKillSwitch applies the kill switch to an order, letting orders
+// that only shrink a position through.
+func (g *RiskGate) checkOrderKillSwitch(ctx context.Context, order *models.Order) error {
+	err := g.checkKillSwitch(ctx, order.Symbol)
+	if err == nil {
+		return nil
+	}
+	if exp, xerr := g.exposure(ctx, order); xerr == nil && exp.reducing {
+		return nil
+	}
+	return err
+}
+
+// orderExposure is what an order does to the account's open exposure.
+type orderExposure struct {
+	// held is the signed quantity of the order's symbol before the order,
+	// including delivery holdings for CNC sells.
+	held int
+	// after is held once the order fills.
+	after int
+	// open counts open positions.
+	open int
+	// unrealized is the open positions' net unrealized P&L.
+	unrealized float64
+	// reducing is set when the order only shrinks held.
+	reducing bool
+}
+
+// exposure measures order against the open positions and, for CNC sells,
+// the delivery holdings it can be sold from.
+func (g *RiskGate) exposure(ctx context.Context, order *models.Order) (*orderExposure, error) {
+	positions, err := g.Broker.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching positions: %w", err)
+	}
+
+	exp := &orderExposure{}
+	for _, p := range positions {
+		if p.Quantity == 0 {
+			continue
+		}
+		exp.open++
+		exp.unrealized += p.PnL
+		if p.Symbol == order.Symbol && p.Exchange == order.Exchange {
+			exp.held += p.Quantity
+		}
+	}
+
+	if order.Side == models.OrderSideSell && order.Product == models.ProductCNC {
+		holdings, err := g.Broker.GetHoldings(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetching holdings: %w", err)
+		}
+		for _, h := range holdings {
+			if h.Symbol == order.Symbol {
+				exp.held += h.Quantity
+			}
+		}
+	}
+
+	delta := order.Quantity
+	if order.Side == models.OrderSideSell {
+		delta = -delta
+	}
+	exp.after = exp.held + delta
+	exp.reducing = exp.held != 0 && abs(exp.after) < abs(exp.held) && exp.after*exp.held >= 0
+	return exp, nil
+}
+
+// CheckOrder applies the pre-trade limits to order without placing it.
+// The kill switch is not consulted.
+func (g *RiskGate) CheckOrder(ctx context.Context, order *models.Order) error {
+	reject := func(check, format string, args ...interface{}) error {
+		return &RiskGateError{Check: check, Symbol: order.Symbol, Reason: fmt.Sprintf(format, args...)}
+	}
+	if order.Quantity <= 0 {
+		return reject(CheckQuantity, "quantity must be positive")
+	}
+
+	exp, err := g.exposure(ctx, order)
+	if err != nil {
+		return reject(CheckMarketData, "%v", err)
+	}
+	held, after, open, unrealized, reducing := exp.held, exp.after, exp.open, exp.unrealized, exp.reducing
+
+	ltp, limit, err := g.marketPrice(ctx, order)
+	if err != nil {
+		// A stale feed must not trap an exit; new risk needs a price
+		if !reducing {
+			return reject(CheckMarketData, "no quote: %v", err)
+		}
+	}
+
+	if !reducing {
+		price := orderPrice(order, ltp)
+		if g.cfg.MaxOrderValue > 0 && price*float64(order.Quantity) > g.cfg.MaxOrderValue {
+			return reject(CheckOrderValue, "order value %.2f exceeds limit %.2f",
+				price*float64(order.Quantity), g.cfg.MaxOrderValue)
+		}
+		if g.cfg.MaxQuantityPerSymbol > 0 && abs(after) > g.cfg.MaxQuantityPerSymbol {
+			return reject(CheckQuantity, "position of %d would exceed %d per symbol",
+				abs(after), g.cfg.MaxQuantityPerSymbol)
+		}
+		if g.cfg.MaxConcurrentPositions > 0 && held == 0 && open >= g.cfg.MaxConcurrentPositions {
+			return reject(CheckPositions, "%d positions open, limit is %d", open, g.cfg.MaxConcurrentPositions)
+		}
+		if g.cfg.DailyLossLimit > 0 {
+			loss, err := g.dailyLoss(ctx, unrealized)
+			if err != nil {
+				return reject(CheckDailyLoss, "restoring daily loss: %v", err)
+			}
+			if loss >= g.cfg.DailyLossLimit {
+				return reject(CheckDailyLoss, "day's loss %.2f has reached limit %.2f", loss, g.cfg.DailyLossLimit)
+			}
+		}
+	}
+
+	if ltp <= 0 {
+		return nil
+	}
+
+	if g.cfg.PriceBandPercent > 0 && order.Price > 0 && hasLimitPrice(order.Type) {
+		dev := math.Abs(order.Price-ltp) / ltp * 100
+		if dev > g.cfg.PriceBandPercent {
+			return reject(CheckPriceBand, "price %.2f is %.1f%% from LTP %.2f (band %.1f%%)",
+				order.Price, dev, ltp, g.cfg.PriceBandPercent)
+		}
+	}
+
+	if limit != nil {
+		for _, p := range []float64{order.Price, order.TriggerPrice} {
+			if p > 0 && p > limit.UpperLimit {
+				return reject(CheckCircuit, "price %.2f exceeds upper circuit limit %.2f", p, limit.UpperLimit)
+			}
+			if p > 0 && p < limit.LowerLimit {
+				return reject(CheckCircuit, "price %.2f below lower circuit limit %.2f", p, limit.LowerLimit)
+			}
+		}
+		// Exits may queue in a locked stock; new risk may not
+		if !reducing {
+			if order.Side == models.OrderSideBuy && limit.Status == CircuitUpperHit {
+				return reject(CheckCircuit, "locked at upper circuit %.2f", limit.UpperLimit)
+			}
+			if order.Side == models.OrderSideSell && limit.Status == CircuitLowerHit {
+				return reject(CheckCircuit, "locked at lower circuit %.2f", limit.LowerLimit)
+			}
+		}
+	}
+
+	return nil
+}
+
+// marketPrice returns the order symbol's LTP and, on cash exchanges, its
+// circuit limits, which come from the same quote.
+func (g *RiskGate) marketPrice(ctx context.Context, order *models.Order) (float64, *CircuitLimit, error) {
+	if order.Exchange == models.NSE || order.Exchange == models.BSE {
+		limit, err := g.circuits.FetchCircuitLimits(ctx, order.Symbol, order.Exchange)
+		if err != nil {
+			return 0, nil, err
+		}
+		return limit.LTP, limit, nil
+	}
+
+	quote, err := g.Broker.GetQuote(ctx, fmt.Sprintf("%s:%s", order.Exchange, order.Symbol))
+	if err != nil {
+		return 0, nil, err
+	}
+	return quote.LTP, nil, nil
+}
+
+// dailyLoss returns the day's realized loss plus the open positions' net
+// unrealized loss.
+func (g *RiskGate) dailyLoss(ctx context.Context, unrealized float64) (float64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.riskLoaded {
+		if err := g.risk.Load(ctx); err != nil {
+			return 0, err
+		}
+		g.riskLoaded = true
+	}
+
+	loss := g.risk.Counters().Loss
+	if unrealized < 0 {
+		loss += -unrealized
+	}
+	return loss, nil
+}
+
+// orderPrice is the price an order is valued at: its limit price, else its
+// trigger, else the LTP.
+func orderPrice(order *models.Order, ltp float64) float64 {
+	if order.Price > 0 && hasLimitPrice(order.Type) {
+		return order.Price
+	}
+	if order.TriggerPrice > 0 && ltp <= 0 {
+		return order.TriggerPrice
+	}
+	return ltp
+}
+
+func hasLimitPrice(t models.OrderType) bool {
+	return t == models.OrderTypeLimit || t == models.OrderTypeStopLoss
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,240 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/broker"
+	"zerodha-trader/internal/config"
+	apperrors "zerodha-trader/internal/errors"
+	"zerodha-trader/internal/models"
+	"zerodha-trader/internal/resilience"
+)
+
+// gateBroker serves fixed positions and quotes and records placed orders.
+// Methods the gate does not use panic through the nil embedded interface.
+type gateBroker struct {
+	broker.Broker
+	positions []models.Position
+	holdings  []models.Holding
+	quote     models.Quote
+	placed    []models.Order
+	modified  []models.Order
+}
+
+func (b *gateBroker) GetPositions(ctx context.Context) ([]models.Position, error) {
+	return b.positions, nil
+}
+
+func (b *gateBroker) GetHoldings(ctx context.Context) ([]models.Holding, error) {
+	return b.holdings, nil
+}
+
+func (b *gateBroker) ModifyOrder(ctx context.Context, orderID string, order *models.Order) error {
+	b.modified = append(b.modified, *order)
+	return nil
+}
+
+func (b *gateBroker) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	q := b.quote
+	return &q, nil
+}
+
+func (b *gateBroker) PlaceOrder(ctx context.Context, order *models.Order) (*broker.OrderResult, error) {
+	b.placed = append(b.placed, *order)
+	return &broker.OrderResult{OrderID: "1"}, nil
+}
+
+func TestRiskGate(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.RiskConfig{
+		MaxOrderValue:          100000,
+		MaxQuantityPerSymbol:   500,
+		MaxConcurrentPositions: 2,
+		DailyLossLimit:         5000,
+		PriceBandPercent:       5,
+	}
+	held := []models.Position{
+		{Symbol: "INFY", Exchange: models.NSE, Quantity: 400, PnL: -1000},
+		{Symbol: "TCS", Exchange: models.NSE, Quantity: 10, PnL: 200},
+	}
+	order := func(symbol string, side models.OrderSide, qty int, price float64) *models.Order {
+		o := &models.Order{Symbol: symbol, Exchange: models.NSE, Side: side, Type: models.OrderTypeMarket, Quantity: qty}
+		if price > 0 {
+			o.Type, o.Price = models.OrderTypeLimit, price
+		}
+		return o
+	}
+
+	cnc := func(o *models.Order) *models.Order {
+		o.Product = models.ProductCNC
+		return o
+	}
+	holdings := []models.Holding{{Symbol: "HDFC", Quantity: 800}}
+	losing := []models.Position{
+		{Symbol: "INFY", Exchange: models.NSE, Quantity: 400, PnL: -6000},
+		{Symbol: "TCS", Exchange: models.NSE, Quantity: 10},
+	}
+
+	tests := []struct {
+		name      string
+		positions []models.Position
+		holdings  []models.Holding
+		ltp       float64
+		order     *models.Order
+		check     string
+	}{
+		{"within limits", held[:1], nil, 100, order("INFY", models.OrderSideBuy, 50, 0), ""},
+		{"order value at limit price", held[:1], nil, 100, order("INFY", models.OrderSideBuy, 50, 2500), CheckOrderValue},
+		{"order value at LTP", nil, nil, 3000, order("INFY", models.OrderSideBuy, 50, 0), CheckOrderValue},
+		{"quantity per symbol", held[:1], nil, 100, order("INFY", models.OrderSideBuy, 200, 0), CheckQuantity},
+		{"open positions", held, nil, 100, order("SBIN", models.OrderSideBuy, 10, 0), CheckPositions},
+		{"adding to a held symbol", held, nil, 100, order("TCS", models.OrderSideBuy, 10, 0), ""},
+		{"fat finger", held[:1], nil, 100, order("INFY", models.OrderSideSell, 10, 80), CheckPriceBand},
+		{"daily loss", []models.Position{{Symbol: "INFY", Exchange: models.NSE, Quantity: 400, PnL: -6000}}, nil, 100,
+			order("SBIN", models.OrderSideBuy, 10, 0), CheckDailyLoss},
+		{"exit past daily loss", []models.Position{{Symbol: "INFY", Exchange: models.NSE, Quantity: 400, PnL: -6000}}, nil, 100,
+			order("INFY", models.OrderSideSell, 400, 0), ""},
+		{"reversal is not an exit", held[:1], nil, 100, order("INFY", models.OrderSideSell, 1000, 0), CheckQuantity},
+		{"selling holdings past every limit", losing, holdings, 100, cnc(order("HDFC", models.OrderSideSell, 800, 0)), ""},
+		{"selling more than held", nil, holdings, 100, cnc(order("HDFC", models.OrderSideSell, 1600, 0)), CheckOrderValue},
+		{"intraday sell ignores holdings", held, holdings, 100, order("HDFC", models.OrderSideSell, 10, 0), CheckPositions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &gateBroker{positions: tt.positions, holdings: tt.holdings, quote: models.Quote{LTP: tt.ltp, Close: tt.ltp}}
+			gate := NewRiskGate(b, cfg, nil)
+
+			_, err := gate.PlaceOrder(ctx, tt.order)
+			if tt.check == "" {
+				if err != nil {
+					t.Fatalf("PlaceOrder() error = %v", err)
+				}
+				if len(b.placed) != 1 {
+					t.Fatalf("placed %d orders, want 1", len(b.placed))
+				}
+				return
+			}
+
+			var gateErr *RiskGateError
+			if !errors.As(err, &gateErr) || gateErr.Check != tt.check {
+				t.Fatalf("PlaceOrder() error = %v, want %s", err, tt.check)
+			}
+			if !errors.Is(err, apperrors.ErrOrderRejected) {
+				t.Errorf("error does not wrap ErrOrderRejected")
+			}
+			if len(b.placed) != 0 {
+				t.Errorf("blocked order reached the broker")
+			}
+		})
+	}
+}
+
+func TestRiskGateKillSwitch(t *testing.T) {
+	ctx := context.Background()
+	b := &gateBroker{quote: models.Quote{LTP: 100, Close: 100}}
+	gate := NewRiskGate(b, config.RiskConfig{}, nil)
+	buy := &models.Order{Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy, Type: models.OrderTypeMarket, Quantity: 1}
+
+	if err := gate.SetKillSwitch(ctx, true, "test"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := gate.PlaceOrder(ctx, buy)
+	var gateErr *RiskGateError
+	if !errors.As(err, &gateErr) || gateErr.Check != CheckKillSwitch {
+		t.Fatalf("PlaceOrder() with kill switch on: error = %v", err)
+	}
+
+	if err := gate.SetKillSwitch(ctx, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gate.PlaceOrder(ctx, buy); err != nil {
+		t.Fatalf("PlaceOrder() with kill switch off: error = %v", err)
+	}
+
+	if UnwrapBroker(gate) != b {
+		t.Errorf("UnwrapBroker() did not return the inner broker")
+	}
+}
+
+func TestRiskGateKillSwitchLetsExitsThrough(t *testing.T) {
+	ctx := context.Background()
+	b := &gateBroker{
+		positions: []models.Position{{Symbol: "INFY", Exchange: models.NSE, Product: models.ProductMIS, Quantity: 100}},
+		holdings:  []models.Holding{{Symbol: "TCS", Quantity: 20}},
+		quote:     models.Quote{LTP: 100, Close: 100},
+	}
+	gate := NewRiskGate(b, config.RiskConfig{}, nil)
+	if err := gate.SetKillSwitch(ctx, true, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	exit := &models.Order{Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideSell, Type: models.OrderTypeMarket,
+		Product: models.ProductMIS, Quantity: 100}
+	if _, err := gate.PlaceOrder(ctx, exit); err != nil {
+		t.Errorf("exit blocked by kill switch: %v", err)
+	}
+
+	trail := &models.Order{Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideSell, Type: models.OrderTypeStopLossM,
+		Product: models.ProductMIS, Quantity: 100, TriggerPrice: 98}
+	if err := gate.ModifyOrder(ctx, "sl-1", trail); err != nil {
+		t.Errorf("stop-loss modification blocked by kill switch: %v", err)
+	}
+
+	sellHolding := &models.Order{Symbol: "TCS", Exchange: models.NSE, Side: models.OrderSideSell, Type: models.OrderTypeMarket,
+		Product: models.ProductCNC, Quantity: 20}
+	if _, err := gate.PlaceOrder(ctx, sellHolding); err != nil {
+		t.Errorf("holding sale blocked by kill switch: %v", err)
+	}
+
+	for _, o := range []*models.Order{
+		{Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideBuy, Type: models.OrderTypeMarket, Product: models.ProductMIS, Quantity: 10},
+		{Symbol: "INFY", Exchange: models.NSE, Side: models.OrderSideSell, Type: models.OrderTypeMarket, Product: models.ProductMIS, Quantity: 150},
+	} {
+		_, err := gate.PlaceOrder(ctx, o)
+		var gateErr *RiskGateError
+		if !errors.As(err, &gateErr) || gateErr.Check != CheckKillSwitch {
+			t.Errorf("%s %d with kill switch on: error = %v", o.Side, o.Quantity, err)
+		}
+	}
+
+	if len(b.placed) != 2 || len(b.modified) != 1 {
+		t.Errorf("placed %d and modified %d orders, want 2 and 1", len(b.placed), len(b.modified))
+	}
+}
+
+func TestRiskGateChecksTriggeredPaperGTTs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, resilience.IndiaLocation)
+	paper := broker.NewPaperBroker(broker.PaperBrokerConfig{InitialBalance: 1000000, DisableCharges: true, Clock: func() time.Time { return now }})
+	paper.UpdatePrice("INFY", 1500)
+	gate := NewRiskGate(paper, config.RiskConfig{}, nil)
+	paper.SetOrderRouter(gate.PlaceOrder)
+
+	if _, err := gate.PlaceGTT(ctx, &models.GTTOrder{
+		Symbol: "INFY", Exchange: models.NSE, TriggerType: "single", TriggerPrice: 1600,
+		Orders: []models.GTTOrderLeg{{Side: models.OrderSideBuy, Type: models.OrderTypeLimit, Product: models.ProductMIS, Quantity: 10, Price: 1601}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The switch is engaged after the GTT was accepted; its leg still adds risk
+	if err := gate.SetKillSwitch(ctx, true, "test"); err != nil {
+		t.Fatal(err)
+	}
+	paper.ProcessTick(models.Tick{Symbol: "INFY", LTP: 1605, Timestamp: now})
+
+	gtts, _ := paper.GetGTTs(ctx)
+	if len(gtts) != 1 || gtts[0].Status != "TRIGGERED" || gtts[0].OrderID != "" {
+		t.Fatalf("GTTs = %+v, want one triggered with no order", gtts)
+	}
+	if orders, _ := paper.GetOrders(ctx); len(orders) != 0 {
+		t.Errorf("triggered leg bypassed the kill switch: %+v", orders)
+	}
+	if positions, _ := paper.GetPositions(ctx); len(positions) != 0 {
+		t.Errorf("positions = %+v, want none", positions)
+	}
+}
@@ -2,6 +2,7 @@
 package trading
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -40,10 +41,15 @@ type PreOpenOrderBook struct {
 	LastUpdated     time.Time
 }
 
+// DefaultDayBoundary is when a new market day starts for daily risk
+// counters: the pre-open session.
+const DefaultDayBoundary = "09:00"
+
 // SessionManager manages market session detection and order placement rules.
 type SessionManager struct {
 	location *time.Location
 	holidays map[string]bool // Date string -> is holiday
+	dayStart time.Duration   // Offset of the market-day boundary from midnight
 }
 
 // NewSessionManager creates a new session manager.
@@ -52,7 +58,33 @@ func NewSessionManager() *SessionManager {
 	return &SessionManager{
 		location: loc,
 		holidays: make(map[string]bool),
+		dayStart: 9 * time.Hour,
+	}
+}
+
+// SetDayBoundary sets the IST time of day, as "HH:MM", at which a new market
+// day starts. An empty clock restores DefaultDayBoundary.
+func (m *SessionManager) SetDayBoundary(clock string) error {
+	if clock == "" {
+		clock = DefaultDayBoundary
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return fmt.Errorf("invalid day boundary %q: want HH:MM", clock)
+	}
+	m.dayStart = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	return nil
+}
+
+// MarketDayStart returns the start of the market day containing t: the most
+// recent day boundary at or before t. Daily risk counters reset there.
+func (m *SessionManager) MarketDayStart(t time.Time) time.Time {
+	t = t.In(m.location)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, m.location).Add(m.dayStart)
+	if t.Before(start) {
+		start = start.AddDate(0, 0, -1)
 	}
+	return start
 }
 
 // AddHoliday adds a market holiday.
@@ -225,8 +257,18 @@ func (m *SessionManager) GetTimeToSessionStart(targetSession MarketSession) time
 
 // GetMISSquareOffTime returns the MIS square-off time.
 func (m *SessionManager) GetMISSquareOffTime() time.Time {
-	now := time.Now().In(m.location)
-	return timeAt(now, 15, 15) // MIS square-off at 3:15 PM
+	return m.MISSquareOffAt(time.Now())
+}
+
+// MISSquareOffAt returns the MIS square-off time on the trading day of t.
+func (m *SessionManager) MISSquareOffAt(t time.Time) time.Time {
+	return timeAt(t.In(m.location), 15, 15) // MIS square-off at 3:15 PM
+}
+
+// SameTradingDay reports whether a and b fall on the same exchange calendar day.
+func (m *SessionManager) SameTradingDay(a, b time.Time) bool {
+	a, b = a.In(m.location), b.In(m.location)
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
 }
 
 // GetTimeToMISSquareOff returns duration until MIS square-off.
@@ -0,0 +1,494 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"zerodha-trader/internal/analysis/indicators"
+	"zerodha-trader/internal/models"
+)
+
+// DefaultStrategy is used when a backtest config does not name a strategy.
+const DefaultStrategy = "sma_crossover"
+
+// Signal values emitted by strategies.
+const (
+	SignalBuy  = "BUY"
+	SignalSell = "SELL"
+	SignalHold = "HOLD"
+)
+
+// ParamType is the type of a strategy parameter.
+type ParamType string
+
+const (
+	ParamInt   ParamType = "int"
+	ParamFloat ParamType = "float"
+)
+
+// ParamSpec describes a tunable strategy parameter.
+type ParamSpec struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Type        ParamType `json:"type"`
+	Default     float64   `json:"default"`
+	Min         float64   `json:"min"`
+	Max         float64   `json:"max"`
+	// Step is the suggested increment when searching the parameter space.
+	Step float64 `json:"step"`
+}
+
+// Params holds resolved strategy parameter values keyed by name.
+type Params map[string]float64
+
+// Int returns the named parameter as an int.
+func (p Params) Int(name string) int {
+	return int(math.Round(p[name]))
+}
+
+// Float returns the named parameter as a float64.
+func (p Params) Float(name string) float64 {
+	return p[name]
+}
+
+// Strategy generates trading signals for the backtest engine.
+// Implementations declare their parameters so callers can validate and tune them,
+// and build indicator series through the supplied indicators.Engine.
+type Strategy interface {
+	// Name returns the unique registry key of the strategy.
+	Name() string
+	// Description returns a one-line summary of the strategy.
+	Description() string
+	// Params returns the parameters accepted by the strategy.
+	Params() []ParamSpec
+	// Prepare computes the indicators needed for the candles and returns a
+	// signal generator. Params have already been validated against Params().
+	Prepare(ctx context.Context, engine *indicators.Engine, candles []models.Candle, params Params) (SignalGenerator, error)
+}
+
+// MultiTimeframeStrategy is implemented by strategies that read a higher
+// timeframe as a filter while stepping on the backtest timeframe.
+type MultiTimeframeStrategy interface {
+	Strategy
+	// PrepareMultiTimeframe is used instead of Prepare when the backtest
+	// config sets a higher timeframe.
+	PrepareMultiTimeframe(ctx context.Context, engine *indicators.Engine, candles []models.Candle, higher *HigherTimeframe, params Params) (SignalGenerator, error)
+}
+
+// ResolveParams applies defaults to the given raw parameters and validates them
+// against the strategy's declared parameter specs.
+// Raw values may be ints, floats or numeric strings.
+func ResolveParams(s Strategy, raw map[string]interface{}) (Params, error) {
+	specs := s.Params()
+	resolved := make(Params, len(specs))
+	known := make(map[string]ParamSpec, len(specs))
+
+	for _, spec := range specs {
+		resolved[spec.Name] = spec.Default
+		known[spec.Name] = spec
+	}
+
+	for name, value := range raw {
+		spec, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("strategy %s has no parameter %q", s.Name(), name)
+		}
+
+		v, err := paramFloat(value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		if spec.Type == ParamInt && v != math.Trunc(v) {
+			return nil, fmt.Errorf("parameter %s must be an integer, got %v", name, v)
+		}
+		if v < spec.Min || v > spec.Max {
+			return nil, fmt.Errorf("parameter %s must be between %v and %v, got %v", name, spec.Min, spec.Max, v)
+		}
+		resolved[name] = v
+	}
+
+	return resolved, nil
+}
+
+// paramFloat converts a raw parameter value to float64.
+func paramFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// StrategyRegistry holds the strategies available to the backtest engine.
+type StrategyRegistry struct {
+	mu         sync.RWMutex
+	strategies map[string]Strategy
+}
+
+// NewStrategyRegistry creates an empty strategy registry.
+func NewStrategyRegistry() *StrategyRegistry {
+	return &StrategyRegistry{
+		strategies: make(map[string]Strategy),
+	}
+}
+
+// Register adds a strategy to the registry.
+func (r *StrategyRegistry) Register(s Strategy) error {
+	name := strings.ToLower(s.Name())
+	if name == "" {
+		return fmt.Errorf("strategy name is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.strategies[name]; exists {
+		return fmt.Errorf("strategy %s already registered", name)
+	}
+	r.strategies[name] = s
+	return nil
+}
+
+// Get returns the strategy registered under name.
+func (r *StrategyRegistry) Get(name string) (Strategy, error) {
+	r.mu.RLock()
+	s, ok := r.strategies[strings.ToLower(name)]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q (available: %s)", name, strings.Join(r.Names(), ", "))
+	}
+	return s, nil
+}
+
+// Names returns the registered strategy names in sorted order.
+func (r *StrategyRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.strategies))
+	for name := range r.strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns the registered strategies sorted by name.
+func (r *StrategyRegistry) List() []Strategy {
+	names := r.Names()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Strategy, 0, len(names))
+	for _, name := range names {
+		list = append(list, r.strategies[name])
+	}
+	return list
+}
+
+var defaultStrategies = NewStrategyRegistry()
+
+func init() {
+	for _, s := range []Strategy{
+		&SMACrossoverStrategy{},
+		&EMACrossoverStrategy{},
+		&RSIReversalStrategy{},
+		&MACDCrossoverStrategy{},
+		&TrendFilteredMomentumStrategy{},
+	} {
+		if err := defaultStrategies.Register(s); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// DefaultStrategyRegistry returns the registry holding the built-in strategies.
+func DefaultStrategyRegistry() *StrategyRegistry {
+	return defaultStrategies
+}
+
+// RegisterStrategy adds a strategy to the default registry.
+func RegisterStrategy(s Strategy) error {
+	return defaultStrategies.Register(s)
+}
+
+// crossover reports whether a crossed above b between index-1 and index.
+func crossover(a, b []float64, index int) bool {
+	return a[index-1] <= b[index-1] && a[index] > b[index]
+}
+
+// crossunder reports whether a crossed below b between index-1 and index.
+func crossunder(a, b []float64, index int) bool {
+	return a[index-1] >= b[index-1] && a[index] < b[index]
+}
+
+// SMACrossoverStrategy buys when the short SMA crosses above the long SMA and
+// sells on the opposite cross.
+type SMACrossoverStrategy struct{}
+
+func (s *SMACrossoverStrategy) Name() string { return "sma_crossover" }
+
+func (s *SMACrossoverStrategy) Description() string {
+	return "Short/long simple moving average crossover"
+}
+
+func (s *SMACrossoverStrategy) Params() []ParamSpec {
+	return []ParamSpec{
+		{Name: "short_period", Description: "Short SMA period", Type: ParamInt, Default: 10, Min: 2, Max: 100, Step: 1},
+		{Name: "long_period", Description: "Long SMA period", Type: ParamInt, Default: 20, Min: 5, Max: 250, Step: 5},
+	}
+}
+
+func (s *SMACrossoverStrategy) Prepare(ctx context.Context, engine *indicators.Engine, candles []models.Candle, params Params) (SignalGenerator, error) {
+	shortPeriod, longPeriod := params.Int("short_period"), params.Int("long_period")
+	if shortPeriod >= longPeriod {
+		return nil, fmt.Errorf("short_period must be less than long_period")
+	}
+
+	short := indicators.NewSMA(shortPeriod)
+	long := indicators.NewSMA(longPeriod)
+	engine.RegisterIndicator(short)
+	engine.RegisterIndicator(long)
+
+	shortSMA, err := engine.Calculate(ctx, short.Name(), candles)
+	if err != nil {
+		return nil, err
+	}
+	longSMA, err := engine.Calculate(ctx, long.Name(), candles)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(_ []models.Candle, index int) (string, float64) {
+		if index < longPeriod {
+			return SignalHold, 0
+		}
+		if crossover(shortSMA, longSMA, index) {
+			return SignalBuy, 70
+		}
+		if crossunder(shortSMA, longSMA, index) {
+			return SignalSell, 70
+		}
+		return SignalHold, 0
+	}, nil
+}
+
+// EMACrossoverStrategy is a momentum strategy that trades fast/slow EMA crosses.
+type EMACrossoverStrategy struct{}
+
+func (s *EMACrossoverStrategy) Name() string { return "momentum" }
+
+func (s *EMACrossoverStrategy) Description() string {
+	return "Fast/slow exponential moving average momentum crossover"
+}
+
+func (s *EMACrossoverStrategy) Params() []ParamSpec {
+	return []ParamSpec{
+		{Name: "fast_period", Description: "Fast EMA period", Type: ParamInt, Default: 9, Min: 2, Max: 100, Step: 1},
+		{Name: "slow_period", Description: "Slow EMA period", Type: ParamInt, Default: 21, Min: 5, Max: 250, Step: 1},
+	}
+}
+
+func (s *EMACrossoverStrategy) Prepare(ctx context.Context, engine *indicators.Engine, candles []models.Candle, params Params) (SignalGenerator, error) {
+	fastPeriod, slowPeriod := params.Int("fast_period"), params.Int("slow_period")
+	if fastPeriod >= slowPeriod {
+		return nil, fmt.Errorf("fast_period must be less than slow_period")
+	}
+
+	fast := indicators.NewEMA(fastPeriod)
+	slow := indicators.NewEMA(slowPeriod)
+	engine.RegisterIndicator(fast)
+	engine.RegisterIndicator(slow)
+
+	fastEMA, err := engine.Calculate(ctx, fast.Name(), candles)
+	if err != nil {
+		return nil, err
+	}
+	slowEMA, err := engine.Calculate(ctx, slow.Name(), candles)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(_ []models.Candle, index int) (string, float64) {
+		if index < slowPeriod {
+			return SignalHold, 0
+		}
+		if crossover(fastEMA, slowEMA, index) {
+			return SignalBuy, 70
+		}
+		if crossunder(fastEMA, slowEMA, index) {
+			return SignalSell, 70
+		}
+		return SignalHold, 0
+	}, nil
+}
+
+// RSIReversalStrategy buys when RSI recovers from oversold and sells when it
+// falls back from overbought.
+type RSIReversalStrategy struct{}
+
+func (s *RSIReversalStrategy) Name() string { return "rsi_oversold" }
+
+func (s *RSIReversalStrategy) Description() string {
+	return "RSI oversold/overbought reversal"
+}
+
+func (s *RSIReversalStrategy) Params() []ParamSpec {
+	return []ParamSpec{
+		{Name: "period", Description: "RSI period", Type: ParamInt, Default: 14, Min: 2, Max: 50, Step: 1},
+		{Name: "oversold", Description: "Oversold threshold", Type: ParamFloat, Default: 30, Min: 5, Max: 50, Step: 5},
+		{Name: "overbought", Description: "Overbought threshold", Type: ParamFloat, Default: 70, Min: 50, Max: 95, Step: 5},
+	}
+}
+
+func (s *RSIReversalStrategy) Prepare(ctx context.Context, engine *indicators.Engine, candles []models.Candle, params Params) (SignalGenerator, error) {
+	period := params.Int("period")
+	oversold, overbought := params.Float("oversold"), params.Float("overbought")
+
+	ind := indicators.NewRSI(period)
+	engine.RegisterIndicator(ind)
+
+	rsi, err := engine.Calculate(ctx, ind.Name(), candles)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(_ []models.Candle, index int) (string, float64) {
+		if index < period+1 {
+			return SignalHold, 0
+		}
+		// Buy when RSI crosses above oversold
+		if rsi[index-1] <= oversold && rsi[index] > oversold {
+			return SignalBuy, 65
+		}
+		// Sell when RSI crosses below overbought
+		if rsi[index-1] >= overbought && rsi[index] < overbought {
+			return SignalSell, 65
+		}
+		return SignalHold, 0
+	}, nil
+}
+
+// MACDCrossoverStrategy trades MACD line crosses of the signal line.
+type MACDCrossoverStrategy struct{}
+
+func (s *MACDCrossoverStrategy) Name() string { return "macd" }
+
+func (s *MACDCrossoverStrategy) Description() string {
+	return "MACD/signal line crossover"
+}
+
+func (s *MACDCrossoverStrategy) Params() []ParamSpec {
+	return []ParamSpec{
+		{Name: "fast_period", Description: "Fast EMA period", Type: ParamInt, Default: 12, Min: 2, Max: 50, Step: 1},
+		{Name: "slow_period", Description: "Slow EMA period", Type: ParamInt, Default: 26, Min: 5, Max: 100, Step: 1},
+		{Name: "signal_period", Description: "Signal EMA period", Type: ParamInt, Default: 9, Min: 2, Max: 50, Step: 1},
+	}
+}
+
+func (s *MACDCrossoverStrategy) Prepare(ctx context.Context, engine *indicators.Engine, candles []models.Candle, params Params) (SignalGenerator, error) {
+	fastPeriod, slowPeriod, signalPeriod := params.Int("fast_period"), params.Int("slow_period"), params.Int("signal_period")
+	if fastPeriod >= slowPeriod {
+		return nil, fmt.Errorf("fast_period must be less than slow_period")
+	}
+
+	ind := indicators.NewMACD(fastPeriod, slowPeriod, signalPeriod)
+	engine.RegisterMultiIndicator(ind)
+
+	values, err := engine.CalculateMulti(ctx, ind.Name(), candles)
+	if err != nil {
+		return nil, err
+	}
+	macd, signal := values["macd"], values["signal"]
+	warmup := ind.Period()
+
+	return func(_ []models.Candle, index int) (string, float64) {
+		if index < warmup {
+			return SignalHold, 0
+		}
+		if crossover(macd, signal, index) {
+			return SignalBuy, 75
+		}
+		if crossunder(macd, signal, index) {
+			return SignalSell, 75
+		}
+		return SignalHold, 0
+	}, nil
+}
+
+// TrendFilteredMomentumStrategy trades fast/slow EMA crosses on the backtest
+// timeframe and only takes longs while the higher timeframe closes above its EMA.
+type TrendFilteredMomentumStrategy struct {
+	EMACrossoverStrategy
+}
+
+func (s *TrendFilteredMomentumStrategy) Name() string { return "mtf_momentum" }
+
+func (s *TrendFilteredMomentumStrategy) Description() string {
+	return "EMA momentum crossover filtered by higher timeframe trend (requires a higher timeframe)"
+}
+
+func (s *TrendFilteredMomentumStrategy) Params() []ParamSpec {
+	return append(s.EMACrossoverStrategy.Params(),
+		ParamSpec{Name: "trend_period", Description: "Higher timeframe trend EMA period", Type: ParamInt, Default: 20, Min: 2, Max: 200, Step: 1},
+	)
+}
+
+func (s *TrendFilteredMomentumStrategy) Prepare(ctx context.Context, engine *indicators.Engine, candles []models.Candle, params Params) (SignalGenerator, error) {
+	return nil, fmt.Errorf("strategy %s requires a higher timeframe", s.Name())
+}
+
+func (s *TrendFilteredMomentumStrategy) PrepareMultiTimeframe(ctx context.Context, engine *indicators.Engine, candles []models.Candle, higher *HigherTimeframe, params Params) (SignalGenerator, error) {
+	momentum, err := s.EMACrossoverStrategy.Prepare(ctx, engine, candles, params)
+	if err != nil {
+		return nil, err
+	}
+
+	trendPeriod := params.Int("trend_period")
+	if len(higher.Candles) < trendPeriod {
+		return nil, fmt.Errorf("insufficient %s data: need %d candles, got %d", higher.Timeframe, trendPeriod, len(higher.Candles))
+	}
+	trendEMA := indicators.CalculateEMA(closes(higher.Candles), trendPeriod)
+
+	return func(c []models.Candle, index int) (string, float64) {
+		signal, confidence := momentum(c, index)
+		if signal != SignalBuy {
+			return signal, confidence
+		}
+
+		h := higher.Index(index)
+		if h < trendPeriod-1 || higher.Candles[h].Close <= trendEMA[h] {
+			return SignalHold, 0
+		}
+		return signal, confidence + 5
+	}, nil
+}
+
+// closes returns the close prices of candles.
+func closes(candles []models.Candle) []float64 {
+	values := make([]float64, len(candles))
+	for i, c := range candles {
+		values[i] = c.Close
+	}
+	return values
+}
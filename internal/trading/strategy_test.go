@@ -0,0 +1,157 @@
+package trading
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"zerodha-trader/internal/analysis/indicators"
+	"zerodha-trader/internal/models"
+)
+
+// alternatingStrategy buys and sells on a fixed cadence so trades are deterministic.
+type alternatingStrategy struct{}
+
+func (s *alternatingStrategy) Name() string        { return "alternating" }
+func (s *alternatingStrategy) Description() string { return "Buys and sells every N bars" }
+
+func (s *alternatingStrategy) Params() []ParamSpec {
+	return []ParamSpec{{Name: "every", Type: ParamInt, Default: 5, Min: 1, Max: 50, Step: 1}}
+}
+
+func (s *alternatingStrategy) Prepare(_ context.Context, _ *indicators.Engine, _ []models.Candle, params Params) (SignalGenerator, error) {
+	every := params.Int("every")
+	return func(_ []models.Candle, index int) (string, float64) {
+		switch (index / every) % 2 {
+		case 0:
+			return SignalBuy, 80
+		default:
+			return SignalSell, 80
+		}
+	}, nil
+}
+
+func testCandles(n int) []models.Candle {
+	candles := make([]models.Candle, n)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range candles {
+		price := 100 + float64(i%10)
+		candles[i] = models.Candle{
+			Timestamp: start.AddDate(0, 0, i),
+			Open:      price,
+			High:      price + 1,
+			Low:       price - 1,
+			Close:     price,
+			Volume:    1000,
+		}
+	}
+	return candles
+}
+
+func TestStrategyRegistry_BuiltinsRegistered(t *testing.T) {
+	for _, name := range []string{"sma_crossover", "momentum", "rsi_oversold", "macd"} {
+		if _, err := DefaultStrategyRegistry().Get(name); err != nil {
+			t.Errorf("expected built-in strategy %s: %v", name, err)
+		}
+	}
+
+	if _, err := DefaultStrategyRegistry().Get("does_not_exist"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestStrategyRegistry_RejectsDuplicate(t *testing.T) {
+	registry := NewStrategyRegistry()
+	if err := registry.Register(&alternatingStrategy{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := registry.Register(&alternatingStrategy{}); err == nil {
+		t.Error("expected duplicate registration to fail")
+	}
+}
+
+func TestResolveParams(t *testing.T) {
+	s := &SMACrossoverStrategy{}
+
+	params, err := ResolveParams(s, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params.Int("short_period") != 10 || params.Int("long_period") != 20 {
+		t.Errorf("expected defaults, got %v", params)
+	}
+
+	params, err = ResolveParams(s, map[string]interface{}{"short_period": "5", "long_period": 30})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params.Int("short_period") != 5 || params.Int("long_period") != 30 {
+		t.Errorf("expected overrides, got %v", params)
+	}
+
+	invalid := []map[string]interface{}{
+		{"unknown": 1},
+		{"short_period": 2.5},
+		{"long_period": 1000},
+		{"short_period": "abc"},
+	}
+	for _, raw := range invalid {
+		if _, err := ResolveParams(s, raw); err == nil {
+			t.Errorf("expected error for %v", raw)
+		}
+	}
+}
+
+func TestBacktestEngine_RunsRegisteredStrategy(t *testing.T) {
+	registry := NewStrategyRegistry()
+	if err := registry.Register(&alternatingStrategy{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	engine := NewBacktestEngine(nil)
+	engine.SetStrategyRegistry(registry)
+
+	candles := testCandles(100)
+	config := BacktestConfig{
+		Symbol:         "TEST",
+		StartDate:      candles[0].Timestamp,
+		EndDate:        candles[len(candles)-1].Timestamp,
+		InitialCapital: 100000,
+		Strategy:       "alternating",
+		Parameters:     map[string]interface{}{"every": 10},
+	}
+
+	result, err := engine.RunCandles(context.Background(), config, candles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TotalTrades == 0 {
+		t.Error("expected trades from registered strategy")
+	}
+
+	config.Strategy = "sma_crossover"
+	if _, err := engine.RunCandles(context.Background(), config, candles); err == nil {
+		t.Error("expected error for strategy missing from the engine's registry")
+	}
+}
+
+func TestBuiltinStrategies_Run(t *testing.T) {
+	engine := NewBacktestEngine(nil)
+	candles := testCandles(200)
+
+	for _, s := range DefaultStrategyRegistry().List() {
+		if _, ok := s.(MultiTimeframeStrategy); ok {
+			continue
+		}
+		_, err := engine.RunCandles(context.Background(), BacktestConfig{
+			Symbol:         "TEST",
+			StartDate:      candles[0].Timestamp,
+			EndDate:        candles[len(candles)-1].Timestamp,
+			InitialCapital: 100000,
+			Strategy:       s.Name(),
+		}, candles)
+		if err != nil {
+			t.Errorf("strategy %s: %v", s.Name(), err)
+		}
+	}
+}
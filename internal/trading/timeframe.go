@@ -0,0 +1,100 @@
+package trading
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"zerodha-trader/internal/models"
+)
+
+// timeframeDurations maps the candle timeframes used across the app to bar lengths.
+var timeframeDurations = map[string]time.Duration{
+	"1min":  time.Minute,
+	"5min":  5 * time.Minute,
+	"15min": 15 * time.Minute,
+	"30min": 30 * time.Minute,
+	"1hour": time.Hour,
+	"1day":  24 * time.Hour,
+}
+
+// TimeframeDuration returns the bar length of a timeframe such as "5min" or "1day".
+func TimeframeDuration(timeframe string) (time.Duration, error) {
+	d, ok := timeframeDurations[timeframe]
+	if !ok {
+		return 0, fmt.Errorf("unsupported timeframe %q", timeframe)
+	}
+	return d, nil
+}
+
+// IsIntradayTimeframe reports whether bars of the timeframe are shorter than a session.
+func IsIntradayTimeframe(timeframe string) bool {
+	d, err := TimeframeDuration(timeframe)
+	return err == nil && d < 24*time.Hour
+}
+
+// HigherTimeframe exposes higher timeframe candles to a strategy stepping on a
+// lower timeframe. Only bars that have closed by the end of the current lower
+// bar are visible, so filters cannot look ahead.
+type HigherTimeframe struct {
+	Timeframe string
+	Candles   []models.Candle
+
+	// closed[i] is the index of the last higher bar closed at lower bar i, or -1.
+	closed []int
+}
+
+// NewHigherTimeframe aligns higher timeframe candles to the lower timeframe candles.
+func NewHigherTimeframe(lowerTimeframe string, lower []models.Candle, higherTimeframe string, higher []models.Candle) (*HigherTimeframe, error) {
+	lowerDur, err := TimeframeDuration(lowerTimeframe)
+	if err != nil {
+		return nil, err
+	}
+	higherDur, err := TimeframeDuration(higherTimeframe)
+	if err != nil {
+		return nil, err
+	}
+	if higherDur <= lowerDur {
+		return nil, fmt.Errorf("higher timeframe %s must be longer than %s", higherTimeframe, lowerTimeframe)
+	}
+
+	sorted := make([]models.Candle, len(higher))
+	copy(sorted, higher)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	closed := make([]int, len(lower))
+	j := -1
+	for i, c := range lower {
+		barClose := c.Timestamp.Add(lowerDur)
+		for j+1 < len(sorted) && !sorted[j+1].Timestamp.Add(higherDur).After(barClose) {
+			j++
+		}
+		closed[i] = j
+	}
+
+	return &HigherTimeframe{
+		Timeframe: higherTimeframe,
+		Candles:   sorted,
+		closed:    closed,
+	}, nil
+}
+
+// Index returns the index into Candles of the last higher bar closed at lower
+// bar i, or -1 if none has closed yet.
+func (h *HigherTimeframe) Index(i int) int {
+	if h == nil || i < 0 || i >= len(h.closed) {
+		return -1
+	}
+	return h.closed[i]
+}
+
+// At returns the last higher bar closed at lower bar i.
+func (h *HigherTimeframe) At(i int) (models.Candle, bool) {
+	idx := h.Index(i)
+	if idx < 0 {
+		return models.Candle{}, false
+	}
+	return h.Candles[idx], true
+}
@@ -59,6 +59,7 @@ type PortfolioAnalyzer interface {
 	GetPortfolioGreeks(ctx context.Context) (*PortfolioGreeks, error)
 	GetPortfolioBeta(ctx context.Context) (float64, error)
 	GetVaR(ctx context.Context, confidence float64) (float64, error)
+	ComputeVaR(ctx context.Context, req VaRRequest) (*VaRResult, error)
 	SuggestHedges(ctx context.Context) ([]HedgeSuggestion, error)
 }
 
@@ -81,6 +82,7 @@ type PortfolioGreeks struct {
 	Gamma float64
 	Theta float64
 	Vega  float64
+	Rho   float64
 }
 
 // HedgeSuggestion represents a hedging suggestion.
@@ -108,6 +110,21 @@ type BacktestConfig struct {
 	Parameters    map[string]interface{}
 	Slippage      float64
 	Commission    float64
+	// Timeframe is the candle timeframe to step on (e.g. "5min"); defaults to "1day".
+	Timeframe string
+	// HigherTimeframe is an optional longer timeframe exposed to strategies as a filter.
+	HigherTimeframe string
+	// Product defaults to MIS for intraday timeframes and CNC otherwise.
+	// MIS positions are squared off at the cutoff and never carried overnight.
+	Product models.ProductType
+	// AllowShort lets SELL signals open short positions (MIS only).
+	AllowShort bool
+	// Exchange selects the exchange charge schedule; defaults to NSE.
+	// Brokerage and statutory charges apply on top of Commission.
+	Exchange models.Exchange
+	// TradeFrom treats earlier candles as indicator warmup only; no trades are
+	// taken and no equity is recorded before it.
+	TradeFrom time.Time
 }
 
 // BacktestResult represents backtesting results.
@@ -123,6 +140,9 @@ type BacktestResult struct {
 	AvgWin          float64
 	AvgLoss         float64
 	ProfitFactor    float64
+	GrossPnL        float64
+	TotalCharges    float64
+	NetPnL          float64
 	EquityCurve     []EquityPoint
 	Trades          []BacktestTrade
 }
@@ -142,6 +162,12 @@ type BacktestTrade struct {
 	EntryPrice float64
 	ExitPrice  float64
 	Quantity   int
+	// GrossPnL is the price P&L before commission and charges.
+	GrossPnL float64
+	// Charges is commission plus brokerage and statutory charges for both legs.
+	Charges float64
+	// PnL is the net P&L after charges.
 	PnL        float64
 	PnLPercent float64
+	ExitReason string
 }